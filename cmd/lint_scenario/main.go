@@ -0,0 +1,119 @@
+// Command lint_scenario validates scenarios before they are imported, with the
+// checks of POST /api/v1/scenarios/lint. Each argument is a KillerCoda-style
+// scenario directory (holding an index.json) or a JSON file in the
+// /scenarios/import-json format. It needs no database.
+//
+// Feature names are only checked against a catalog when one is given, since
+// the terminal feature catalog lives on tt-backend.
+//
+// Usage:
+//
+//	go run ./cmd/lint_scenario ./scenarios/linux-basics
+//	go run ./cmd/lint_scenario --features network,docker ./scenarios/*/
+//	go run ./cmd/lint_scenario --json scenario.json       # machine-readable report
+//
+// It exits with status 1 when any scenario has errors; warnings alone do not
+// fail it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/services"
+)
+
+func main() {
+	features := flag.String("features", "", "Comma-separated feature keys of the terminal catalog. Default: features are not checked.")
+	asJSON := flag.Bool("json", false, "Print the reports as JSON")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: lint_scenario [--features a,b] [--json] <scenario dir or .json>...")
+		os.Exit(2)
+	}
+
+	var catalog func() ([]string, error)
+	if *features != "" {
+		var keys []string
+		for _, key := range strings.Split(*features, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		catalog = func() ([]string, error) { return keys, nil }
+	}
+	linter := services.NewScenarioLinter(nil, catalog)
+
+	reports := make(map[string]*dto.ScenarioLintReport)
+	failed := false
+	for _, target := range flag.Args() {
+		report, err := lint(linter, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lint_scenario: %s: %v\n", target, err)
+			failed = true
+			continue
+		}
+		reports[target] = report
+		if !report.Valid {
+			failed = true
+		}
+		if !*asJSON {
+			printReport(target, report)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "lint_scenario: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// lint runs the linter on a scenario directory or JSON file.
+func lint(linter *services.ScenarioLinter, target string) (*dto.ScenarioLintReport, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return linter.LintDirectory(target)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		return nil, err
+	}
+	var input dto.SeedScenarioInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return linter.LintSeedInput(input), nil
+}
+
+func printReport(target string, report *dto.ScenarioLintReport) {
+	fmt.Printf("%s: %d error(s), %d warning(s)\n", target, report.Errors, report.Warnings)
+	for _, issue := range report.Issues {
+		where := "scenario"
+		if issue.Step > 0 {
+			where = fmt.Sprintf("step %d", issue.Step)
+			if issue.StepTitle != "" {
+				where += fmt.Sprintf(" (%s)", issue.StepTitle)
+			}
+		}
+		if issue.Field != "" {
+			where += " " + issue.Field
+		}
+		fmt.Printf("  %-7s %s: %s [%s]\n", issue.Severity, where, issue.Message, issue.Code)
+	}
+}
@@ -0,0 +1,86 @@
+// Command migrate_project_files moves scenario ProjectFile content (scripts,
+// markdown, base64 images) out of the project_files table and into the
+// storage backend configured by SCENARIO_STORAGE_BACKEND. It is safe to run
+// multiple times and while the server is up: rows already moved are skipped,
+// and a row edited during the run is left for the next one.
+//
+// Usage:
+//
+//	go run ./cmd/migrate_project_files                    # dry-run (default)
+//	go run ./cmd/migrate_project_files --only image       # images only
+//	go run ./cmd/migrate_project_files --apply            # commit changes
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	sqldb "soli/formations/src/db"
+	"soli/formations/src/scenarios/services"
+	"soli/formations/src/scenarios/storage"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "Commit changes. Default is dry-run.")
+	envFile := flag.String("env", ".env", "Path to .env file holding POSTGRES_* and SCENARIO_STORAGE_* variables")
+	only := flag.String("only", "", "Comma-separated content types to migrate (script,markdown,text,image). Default: all.")
+	batch := flag.Int("batch", 100, "Rows loaded per query")
+	flag.Parse()
+
+	if err := godotenv.Load(*envFile); err != nil {
+		log.Printf("migrate_project_files: %v (continuing with process environment)", err)
+	}
+
+	target, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("migrate_project_files: %v", err)
+	}
+	if target.Type() == storage.TypeDatabase {
+		log.Fatalf("migrate_project_files: SCENARIO_STORAGE_BACKEND selects the database; set it to filesystem or s3")
+	}
+
+	sqldb.InitDBConnection(*envFile)
+	if sqldb.DB == nil {
+		log.Fatalf("migrate_project_files: database connection not initialised")
+	}
+
+	var contentTypes []string
+	for _, ct := range strings.Split(*only, ",") {
+		if ct = strings.TrimSpace(ct); ct != "" {
+			contentTypes = append(contentTypes, ct)
+		}
+	}
+
+	mode := "DRY-RUN"
+	if *apply {
+		mode = "APPLY"
+	}
+	fmt.Printf("[migrate_project_files] mode=%s target=%s\n", mode, target.Type())
+
+	report, err := services.MigrateProjectFileStorage(sqldb.DB, target, services.ProjectFileMigrationOptions{
+		Apply:        *apply,
+		ContentTypes: contentTypes,
+		BatchSize:    *batch,
+	})
+	if err != nil {
+		log.Fatalf("migrate_project_files: %v", err)
+	}
+
+	for _, f := range report.Failures {
+		fmt.Printf("[file: %s id=%s] FAILED: %s\n", f.Name, f.FileID, f.Reason)
+	}
+	fmt.Printf("[migrate_project_files] total=%d migrated=%d would_migrate=%d skipped=%d failed=%d bytes=%d\n",
+		report.Total, report.Migrated, report.WouldMigrate, report.Skipped, len(report.Failures), report.Bytes)
+
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+	if !*apply && report.WouldMigrate > 0 {
+		fmt.Println("[migrate_project_files] dry-run complete — pass --apply to commit")
+	}
+}
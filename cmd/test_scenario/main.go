@@ -0,0 +1,117 @@
+// Command test_scenario plays a scenario end to end in a local container, the
+// way a learner who knows the answers would: each step's background script
+// provisions it, its solution script solves it and its verify script must then
+// pass. The scenario is a KillerCoda-style directory (holding an index.json,
+// solutions in the steps' extensions.json sidecars) or a JSON file in the
+// /scenarios/import-json format. It needs neither a database nor tt-backend.
+//
+// Usage:
+//
+//	go run ./cmd/test_scenario --image ubuntu:24.04 ./scenarios/linux-basics
+//	go run ./cmd/test_scenario --runtime podman --image debian:12 scenario.json
+//	go run ./cmd/test_scenario --container my-box scenario.json   # an already running container
+//	go run ./cmd/test_scenario --json --image ubuntu:24.04 ./scenarios/linux-basics
+//
+// Without --container, a container is started from --image and removed once
+// the run is over, unless --keep is given. It exits with status 1 when a step
+// fails.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/services"
+)
+
+func main() {
+	runtime := flag.String("runtime", "docker", "Container CLI: docker or podman")
+	image := flag.String("image", "", "Image to start the test container from")
+	container := flag.String("container", "", "Play the scenario in this running container instead of starting one")
+	keep := flag.Bool("keep", false, "Do not remove the started container once the run is over")
+	asJSON := flag.Bool("json", false, "Print the report as JSON")
+	flag.Parse()
+
+	if flag.NArg() != 1 || (*image == "" && *container == "") {
+		fmt.Fprintln(os.Stderr, "usage: test_scenario (--image <image> | --container <name>) [--runtime docker|podman] [--keep] [--json] <scenario dir or .json>")
+		os.Exit(2)
+	}
+
+	scenario, err := services.LoadScenarioSource(flag.Arg(0))
+	if err != nil {
+		fail("%s: %v", flag.Arg(0), err)
+	}
+
+	containerID := *container
+	if containerID == "" {
+		out, err := exec.Command(*runtime, "run", "-d", "--rm", *image, "sleep", "infinity").Output()
+		if err != nil {
+			fail("failed to start a container from %s: %v", *image, err)
+		}
+		containerID = strings.TrimSpace(string(out))
+		if !*keep {
+			defer func() { _ = exec.Command(*runtime, "rm", "-f", containerID).Run() }()
+		} else {
+			fmt.Fprintf(os.Stderr, "test_scenario: container %s is kept\n", containerID)
+		}
+	}
+
+	runner := services.NewScenarioTestRunner(services.NewContainerCLIVerificationService(*runtime))
+	report, err := runner.Run(scenario, containerID)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fail("%v", err)
+		}
+	} else {
+		printReport(scenario.Title, report)
+	}
+	if !report.Passed {
+		// os.Exit skips deferred calls: remove the container first.
+		if *container == "" && !*keep {
+			_ = exec.Command(*runtime, "rm", "-f", containerID).Run()
+		}
+		os.Exit(1)
+	}
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "test_scenario: "+format+"\n", args...)
+	os.Exit(2)
+}
+
+func printReport(title string, report *dto.ScenarioTestReport) {
+	result := "PASSED"
+	if !report.Passed {
+		result = "FAILED"
+	}
+	fmt.Printf("%s: %s\n", title, result)
+	for _, step := range report.Steps {
+		line := fmt.Sprintf("  %-8s step %d (%s)", step.Status, step.Step, step.Title)
+		if step.Phase != "" {
+			line += " at " + step.Phase
+		}
+		if step.Message != "" {
+			line += ": " + step.Message
+		}
+		fmt.Println(line)
+		if step.VerifyPassedBeforeSolution {
+			fmt.Println("           warning: the verify script passed before the solution ran")
+		}
+		if step.Status == services.ScenarioTestFailed && step.Output != "" {
+			for _, out := range strings.Split(strings.TrimRight(step.Output, "\n"), "\n") {
+				fmt.Println("           | " + out)
+			}
+		}
+	}
+}
@@ -30,7 +30,13 @@ import (
 	impersonationController "soli/formations/src/auth/routes/impersonationRoutes"
 	passwordResetController "soli/formations/src/auth/routes/passwordResetRoutes"
 	adminUsersController "soli/formations/src/admin/routes/adminUsersRoutes"
+	"soli/formations/src/observability"
 	observabilityController "soli/formations/src/observability/routes"
+	ltiController "soli/formations/src/lti/routes"
+	webhookController "soli/formations/src/webhooks/routes"
+	webhookServices "soli/formations/src/webhooks/services"
+	certificateController "soli/formations/src/certificates/routes"
+	learningPathController "soli/formations/src/learningPaths/routes"
 userController "soli/formations/src/auth/routes/usersRoutes"
 	permissionReferenceRoutes "soli/formations/src/auth/routes/permissionReferenceRoutes"
 	securityAdminController "soli/formations/src/auth/routes/securityAdminRoutes"
@@ -54,6 +60,7 @@ userController "soli/formations/src/auth/routes/usersRoutes"
 	terminalServices "soli/formations/src/terminalTrainer/services"
 	scenarioHooks "soli/formations/src/scenarios/hooks"
 	scenarioController "soli/formations/src/scenarios/routes"
+	scenarioStorage "soli/formations/src/scenarios/storage"
 	versionController "soli/formations/src/version"
 	sshClientController "soli/formations/src/webSsh/routes/sshClientRoutes"
 
@@ -87,15 +94,29 @@ func main() {
 	shutdownCtx, shutdownCancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer shutdownCancel()
 
+	// OpenTelemetry tracing, exported over OTLP when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set; a no-op otherwise.
+	shutdownTracing, err := observability.InitTracing(shutdownCtx)
+	if err != nil {
+		log.Fatalf("tracing setup failed: %v", err)
+	}
+
 	// Initialize Casdoor connection
 	casdoor.InitCasdoorConnection("", envFile)
 
 	// Initialize database connection
 	sqldb.InitDBConnection(envFile)
+	if err := observability.RegisterGormTracing(sqldb.DB); err != nil {
+		log.Fatalf("gorm tracing setup failed: %v", err)
+	}
 
 	// Perform all database migrations
 	initialization.AutoMigrateAll(sqldb.DB)
 
+	// Select the backend new scenario file content is written to
+	// (SCENARIO_STORAGE_BACKEND); falls back to the database on misconfiguration
+	scenarioStorage.InitFromEnv()
+
 	// Initialize Casdoor enforcer
 	casdoor.InitCasdoorEnforcer(sqldb.DB, "")
 
@@ -122,6 +143,13 @@ func main() {
 	// block, after http.Server.Shutdown drains in-flight requests.
 	log.Println("✅ Stripe sync worker started")
 
+	// Start the WebhookDeliveryWorker: sends queued organization webhook
+	// deliveries, retrying with backoff. Same single-goroutine shape as the
+	// Stripe worker; shut down alongside it below.
+	webhookWorker := webhookServices.NewWebhookDeliveryWorker(webhookServices.NewWebhookDeliveryQueue(sqldb.DB), nil)
+	webhookWorker.Start(shutdownCtx)
+	log.Println("✅ Webhook delivery worker started")
+
 	// Setup development data (test users, default subscription plans)
 	initialization.InitDevelopmentData(sqldb.DB)
 
@@ -144,6 +172,10 @@ func main() {
 	impersonationController.RegisterImpersonationPermissions(casdoor.Enforcer)
 	adminUsersController.RegisterPermissions(casdoor.Enforcer)
 	observabilityController.RegisterPermissions(casdoor.Enforcer)
+	ltiController.RegisterPermissions(casdoor.Enforcer)
+	webhookController.RegisterPermissions(casdoor.Enforcer)
+	certificateController.RegisterPermissions(casdoor.Enforcer)
+	learningPathController.RegisterPermissions(casdoor.Enforcer)
 	log.Println("✅ All permissions setup completed")
 
 	// Register Layer 2 enforcement handlers (business logic authorization)
@@ -188,6 +220,10 @@ func main() {
 	cron.StartAuditLogCleanupJob(sqldb.DB) // Start audit log cleanup (retention management)
 	cron.StartEmailVerificationCleanupJob(sqldb.DB)    // Clean up expired email verification tokens
 	cron.StartScenarioSessionCleanupJob(sqldb.DB)      // Abandon zombie scenario sessions with dead terminals
+	cron.StartExamExpiryJob(sqldb.DB)                  // Grade exam sessions past their time limit and stop their terminals
+	cron.StartLtiGradePassbackJob(sqldb.DB)            // Send scenario grades back to LTI platforms
+	cron.StartAssignmentDeadlineReminderJob(sqldb.DB)  // Email learners whose assignment deadline is near
+	cron.StartTerminalFleetJob(sqldb.DB)               // Launch and stop scheduled terminal fleets
 
 	// Background job: close idle impersonation sessions every minute. Mirrors
 	// the safety net described in src/auth/services/impersonationService.go.
@@ -213,6 +249,7 @@ func main() {
 
 	// Initialize Gin router
 	r := gin.Default()
+	r.Use(observability.TracingMiddleware())
 
 	// Setup CORS middleware - SECURE CONFIGURATION
 	allowedOrigins := config.InitAllowedOrigins()
@@ -279,6 +316,11 @@ userController.UsersRoutes(apiGroup, &config.Configuration{}, sqldb.DB)
 	impersonationController.ImpersonationRoutes(apiGroup, sqldb.DB, impersonationSvc, impersonationValidator)
 	adminUsersController.RegisterRoutes(apiGroup, sqldb.DB)
 	observabilityController.RegisterRoutes(apiGroup, sqldb.DB)
+	observabilityController.RegisterMetricsRoute(r, sqldb.DB)
+	ltiController.RegisterRoutes(apiGroup, sqldb.DB)
+	webhookController.RegisterRoutes(apiGroup, sqldb.DB)
+	certificateController.RegisterRoutes(apiGroup, sqldb.DB)
+	learningPathController.RegisterRoutes(apiGroup, sqldb.DB)
 
 	// Initialize payment routes
 	payment.InitPaymentRoutes(apiGroup, &config.Configuration{}, sqldb.DB)
@@ -346,6 +388,13 @@ userController.UsersRoutes(apiGroup, &config.Configuration{}, sqldb.DB)
 	httpShutdownCancel()
 
 	stripeSyncWorker.Shutdown(shutdownStepTimeout)
+	webhookWorker.Shutdown(shutdownStepTimeout)
+
+	tracingShutdownCtx, tracingShutdownCancel := context.WithTimeout(context.Background(), shutdownStepTimeout)
+	if err := shutdownTracing(tracingShutdownCtx); err != nil {
+		log.Printf("tracing shutdown error: %v", err)
+	}
+	tracingShutdownCancel()
 
 	log.Println("shutdown complete")
 }
@@ -3,8 +3,10 @@ package casdoor
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"soli/formations/src/auth/interfaces"
+	"soli/formations/src/observability"
 
 	"github.com/casbin/casbin/v2"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
@@ -37,6 +39,9 @@ func InitCasdoorConnection(basePath string, fileName string) {
 	JwtPublicKey := string(b)
 
 	casdoorsdk.InitConfig(casdoorEndPoint, casdoorClientId, casdoorClientsecret, JwtPublicKey, casdoorOrganizationName, casdoorApplicationName)
+	// Traced like the other outgoing calls: user lookups sit on the path of
+	// most authenticated requests.
+	casdoorsdk.SetHttpClient(&http.Client{Transport: observability.NewTracingTransport(nil, "Casdoor")})
 }
 
 func InitCasdoorEnforcer(db *gorm.DB, basePath string) {
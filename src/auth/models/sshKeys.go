@@ -1,8 +1,6 @@
 package models
 
 import (
-	"strings"
-
 	"gorm.io/gorm"
 
 	"soli/formations/src/utils/crypto"
@@ -16,28 +14,11 @@ type SshKey struct {
 }
 
 // BeforeSave encrypts PrivateKey before writing to the database.
-// It is idempotent: already-encrypted values (prefixed with "enc::v1:") are
-// left unchanged to avoid double-encryption on updates.
 func (k *SshKey) BeforeSave(tx *gorm.DB) error {
-	if k.PrivateKey != "" && !strings.HasPrefix(k.PrivateKey, "enc::v1:") {
-		encrypted, err := crypto.Encrypt(k.PrivateKey)
-		if err != nil {
-			return err
-		}
-		k.PrivateKey = encrypted
-	}
-	return nil
+	return crypto.EncryptField(&k.PrivateKey)
 }
 
-// AfterFind decrypts PrivateKey after loading from the database so that
-// callers always work with plaintext.
+// AfterFind decrypts PrivateKey after loading from the database.
 func (k *SshKey) AfterFind(tx *gorm.DB) error {
-	if strings.HasPrefix(k.PrivateKey, "enc::v1:") {
-		decrypted, err := crypto.Decrypt(k.PrivateKey)
-		if err != nil {
-			return err
-		}
-		k.PrivateKey = decrypted
-	}
-	return nil
+	return crypto.DecryptField(&k.PrivateKey)
 }
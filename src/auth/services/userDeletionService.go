@@ -195,10 +195,11 @@ func (s *userDeletionService) anonymizeTerminalUserID(tx *gorm.DB, userID string
 }
 
 // deleteScenarioSessions deletes the user's scenario sessions together with
-// their step-progress and flag rows. Children are removed explicitly rather
-// than relying on the DB-level OnDelete:CASCADE, because SQLite only enforces
-// foreign keys when `PRAGMA foreign_keys = ON` is active on the executing
-// connection — doing it in code keeps the behavior identical across dialects.
+// their step-progress, flag and flag-attempt rows. Children are removed
+// explicitly rather than relying on the DB-level OnDelete:CASCADE, because
+// SQLite only enforces foreign keys when `PRAGMA foreign_keys = ON` is active on
+// the executing connection — doing it in code keeps the behavior identical
+// across dialects.
 func (s *userDeletionService) deleteScenarioSessions(tx *gorm.DB, userID string) error {
 	var sessionIDs []uuid.UUID
 	if err := tx.Model(&scenarioModels.ScenarioSession{}).
@@ -216,6 +217,9 @@ func (s *userDeletionService) deleteScenarioSessions(tx *gorm.DB, userID string)
 	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&scenarioModels.ScenarioFlag{}).Error; err != nil {
 		return fmt.Errorf("failed to delete scenario flags: %w", err)
 	}
+	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&scenarioModels.ScenarioFlagAttempt{}).Error; err != nil {
+		return fmt.Errorf("failed to delete scenario flag attempts: %w", err)
+	}
 	if err := tx.Where("id IN ?", sessionIDs).Delete(&scenarioModels.ScenarioSession{}).Error; err != nil {
 		return fmt.Errorf("failed to delete scenario sessions: %w", err)
 	}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevokeCertificateInput revokes a certificate. The reason is published in
+// the issuer's revocation list.
+type RevokeCertificateInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+// CertificateVerification is the public view of a certificate, for whoever
+// scans its QR code: what it attests, and whether it still does.
+type CertificateVerification struct {
+	ID uuid.UUID `json:"id"`
+	// Status is "valid" or "revoked". A certificate whose credential does not
+	// verify is "invalid".
+	Status           string     `json:"status"`
+	SignatureValid   bool       `json:"signature_valid"`
+	Kind             string     `json:"kind"`
+	RecipientName    string     `json:"recipient_name"`
+	Title            string     `json:"title"`
+	OrganizationName string     `json:"organization_name,omitempty"`
+	Grade            *float64   `json:"grade,omitempty"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	CredentialURL    string     `json:"credential_url"`
+	IssuerURL        string     `json:"issuer_url"`
+}
+
+// RevocationList is the 1EdTechRevocationList every credential's
+// credentialStatus points to.
+type RevocationList struct {
+	ID                 string              `json:"id"`
+	Issuer             string              `json:"issuer"`
+	RevokedCredentials []RevokedCredential `json:"revokedCredentials"`
+}
+
+// RevokedCredential is one entry of the revocation list.
+type RevokedCredential struct {
+	ID               string `json:"id"`
+	RevocationReason string `json:"revocationReason,omitempty"`
+}
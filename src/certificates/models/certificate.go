@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"soli/formations/src/utils/crypto"
+)
+
+// Certificate kinds — what the learner completed.
+const (
+	CertificateKindScenario = "scenario" // A scenario session
+	CertificateKindGroup    = "group"    // Every scenario assigned to a class group
+)
+
+// Certificate is a certificate of completion issued to a learner. The
+// recipient, title and organization are copied at issue time: the certificate
+// attests what was true then, and renaming the scenario or the organization
+// later must not change what a funding body already received.
+//
+// Credential is the signed Open Badges 3.0 credential exactly as issued; the
+// PDF and the verification endpoint are derived from the row, the signature
+// from Credential.
+type Certificate struct {
+	entityManagementModels.BaseModel
+	Kind   string `gorm:"type:varchar(20);not null" json:"kind"`
+	UserID string `gorm:"type:varchar(255);not null;index" json:"user_id"`
+	// IssueKey makes issuance idempotent: "session:<id>" or
+	// "group:<id>:<user id>". A learner gets one certificate per session and
+	// one per group, however many times completion is reported.
+	IssueKey       string     `gorm:"type:varchar(255);not null;uniqueIndex" json:"-"`
+	RecipientName  string     `gorm:"type:varchar(255);not null" json:"recipient_name"`
+	RecipientEmail string     `gorm:"type:varchar(255)" json:"recipient_email,omitempty"`
+	ScenarioID     *uuid.UUID `gorm:"type:uuid;index" json:"scenario_id,omitempty"`
+	SessionID      *uuid.UUID `gorm:"type:uuid;index" json:"session_id,omitempty"`
+	GroupID        *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	// OrganizationID is the organization the learner trained with, whose
+	// branding the certificate carries. Nil for learners training on their own.
+	OrganizationID   *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	OrganizationName string     `gorm:"type:varchar(255)" json:"organization_name,omitempty"`
+	Title            string     `gorm:"type:varchar(255);not null" json:"title"`
+	Grade            *float64   `gorm:"type:decimal(5,2)" json:"grade,omitempty"`
+	IssuedAt         time.Time  `gorm:"not null" json:"issued_at"`
+
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokedByID      string     `gorm:"type:varchar(255)" json:"revoked_by_id,omitempty"`
+	RevocationReason string     `gorm:"type:text" json:"revocation_reason,omitempty"`
+
+	Credential string `gorm:"type:text;not null" json:"-"`
+}
+
+func (Certificate) TableName() string {
+	return "certificates"
+}
+
+// IsRevoked reports whether the certificate was revoked.
+func (c *Certificate) IsRevoked() bool {
+	return c.RevokedAt != nil
+}
+
+// CertificateSigningKey is an Ed25519 key OCF signs credentials with.
+// Generated on first use and kept: a credential stays verifiable for as long
+// as the key its proof names is published in the issuer profile, so keys are
+// never deleted, only superseded by a newer one.
+type CertificateSigningKey struct {
+	ID  uint   `gorm:"primarykey" json:"-"`
+	Kid string `gorm:"type:varchar(64);not null;uniqueIndex" json:"kid"`
+	// PrivateKeySeed is the base64 RFC 8032 seed of the key.
+	PrivateKeySeed string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (CertificateSigningKey) TableName() string {
+	return "certificate_signing_keys"
+}
+
+// BeforeSave encrypts PrivateKeySeed before writing to the database.
+func (k *CertificateSigningKey) BeforeSave(tx *gorm.DB) error {
+	return crypto.EncryptField(&k.PrivateKeySeed)
+}
+
+// AfterFind decrypts PrivateKeySeed after loading from the database.
+func (k *CertificateSigningKey) AfterFind(tx *gorm.DB) error {
+	return crypto.DecryptField(&k.PrivateKeySeed)
+}
@@ -0,0 +1,337 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/certificates/dto"
+	"soli/formations/src/certificates/models"
+	"soli/formations/src/certificates/services"
+)
+
+// CertificateController serves certificates of completion: to their
+// recipients, to the organizations that issued them, and publicly to
+// whoever verifies one.
+type CertificateController struct {
+	service *services.CertificateService
+}
+
+// NewCertificateController creates a new certificate controller.
+func NewCertificateController(service *services.CertificateService) *CertificateController {
+	return &CertificateController{service: service}
+}
+
+// ListMyCertificates godoc
+// @Summary List my certificates
+// @Description Returns the caller's certificates of completion, newest first.
+// @Tags certificates
+// @Produce json
+// @Success 200 {array} models.Certificate
+// @Failure 500 {object} map[string]string
+// @Router /certificates [get]
+// @Security BearerAuth
+func (cc *CertificateController) ListMyCertificates(c *gin.Context) {
+	certs, err := cc.service.ListForUser(c.GetString("userId"))
+	if err != nil {
+		slog.Error("failed to list certificates", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list certificates"})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// ListOrganizationCertificates godoc
+// @Summary List an organization's certificates
+// @Description Returns the certificates issued under the organization's branding, newest first.
+// @Tags certificates
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} models.Certificate
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /organizations/{id}/certificates [get]
+// @Security BearerAuth
+func (cc *CertificateController) ListOrganizationCertificates(c *gin.Context) {
+	orgID, ok := parseID(c, "id", "invalid organization ID")
+	if !ok {
+		return
+	}
+	certs, err := cc.service.ListForOrganization(orgID)
+	if err != nil {
+		slog.Error("failed to list organization certificates", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list certificates"})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// GetCertificate godoc
+// @Summary Get a certificate
+// @Description Returns a certificate to its recipient, or to a manager of the organization or group it was issued under.
+// @Tags certificates
+// @Produce json
+// @Param id path string true "Certificate ID"
+// @Success 200 {object} models.Certificate
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /certificates/{id} [get]
+// @Security BearerAuth
+func (cc *CertificateController) GetCertificate(c *gin.Context) {
+	cert, ok := cc.loadViewableCertificate(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// DownloadCertificatePDF godoc
+// @Summary Download a certificate as PDF
+// @Description Renders the certificate as a printable PDF carrying the organization's branding and a
+// @Description QR code pointing to its public verification URL.
+// @Tags certificates
+// @Produce application/pdf
+// @Param id path string true "Certificate ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /certificates/{id}/pdf [get]
+// @Security BearerAuth
+func (cc *CertificateController) DownloadCertificatePDF(c *gin.Context) {
+	cert, ok := cc.loadViewableCertificate(c)
+	if !ok {
+		return
+	}
+	pdf, err := cc.service.RenderPDF(cert)
+	if err != nil {
+		slog.Error("failed to render certificate", "certificate_id", cert.ID, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render certificate"})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="certificate-`+cert.ID.String()+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// VerifyCertificate godoc
+// @Summary Verify a certificate
+// @Description Public: what the certificate attests, whether its credential's signature verifies and
+// @Description whether it was revoked. The URL a certificate's QR code points to.
+// @Tags certificates
+// @Produce json
+// @Param id path string true "Certificate ID"
+// @Success 200 {object} dto.CertificateVerification
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /certificates/{id}/verify [get]
+func (cc *CertificateController) VerifyCertificate(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid certificate ID")
+	if !ok {
+		return
+	}
+	verification, err := cc.service.Verify(id)
+	if err != nil {
+		writeCertificateError(c, err, "failed to verify certificate")
+		return
+	}
+	c.JSON(http.StatusOK, verification)
+}
+
+// GetCredential godoc
+// @Summary Get a certificate's verifiable credential
+// @Description Public: the Open Badges 3.0 credential (a W3C Verifiable Credential) exactly as issued,
+// @Description secured with an eddsa-jcs-2022 Data Integrity proof verifiable against the issuer profile.
+// @Tags certificates
+// @Produce json
+// @Param id path string true "Certificate ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /certificates/{id}/credential [get]
+func (cc *CertificateController) GetCredential(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid certificate ID")
+	if !ok {
+		return
+	}
+	cert, err := cc.service.GetCertificate(id)
+	if err != nil {
+		writeCertificateError(c, err, "failed to load certificate")
+		return
+	}
+	c.Data(http.StatusOK, "application/vc+ld+json", []byte(cert.Credential))
+}
+
+// GetIssuerProfile godoc
+// @Summary Get the certificate issuer profile
+// @Description Public: the Open Badges issuer Profile, publishing every key credentials were signed with.
+// @Tags certificates
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /certificates/issuer [get]
+func (cc *CertificateController) GetIssuerProfile(c *gin.Context) {
+	profile, err := cc.service.IssuerProfile()
+	if err != nil {
+		slog.Error("failed to build issuer profile", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load issuer profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetRevocationList godoc
+// @Summary Get the certificate revocation list
+// @Description Public: the 1EdTechRevocationList every credential's status points to.
+// @Tags certificates
+// @Produce json
+// @Success 200 {object} dto.RevocationList
+// @Failure 500 {object} map[string]string
+// @Router /certificates/issuer/revocations [get]
+func (cc *CertificateController) GetRevocationList(c *gin.Context) {
+	list, err := cc.service.RevocationList()
+	if err != nil {
+		slog.Error("failed to build revocation list", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load revocation list"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RevokeCertificate godoc
+// @Summary Revoke a certificate
+// @Description Revokes a certificate. Its verification then reports it revoked, with the reason, and it
+// @Description joins the issuer's revocation list. Managers of the organization or group it was issued
+// @Description under, and administrators, may revoke.
+// @Tags certificates
+// @Accept json
+// @Produce json
+// @Param id path string true "Certificate ID"
+// @Param body body dto.RevokeCertificateInput true "Reason"
+// @Success 200 {object} models.Certificate
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /certificates/{id}/revoke [post]
+// @Security BearerAuth
+func (cc *CertificateController) RevokeCertificate(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid certificate ID")
+	if !ok {
+		return
+	}
+	var input dto.RevokeCertificateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	cert, err := cc.service.Revoke(id, c.GetString("userId"), access.IsAdmin(c.GetStringSlice("userRoles")), input.Reason)
+	if err != nil {
+		writeCertificateError(c, err, "failed to revoke certificate")
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// ClaimSessionCertificate godoc
+// @Summary Claim a session's certificate
+// @Description Issues the certificate of one of the caller's completed sessions, or returns it if it
+// @Description was issued on completion.
+// @Tags certificates
+// @Produce json
+// @Param sessionId path string true "Scenario session ID"
+// @Success 200 {object} models.Certificate
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /certificates/sessions/{sessionId} [post]
+// @Security BearerAuth
+func (cc *CertificateController) ClaimSessionCertificate(c *gin.Context) {
+	sessionID, ok := parseID(c, "sessionId", "invalid session ID")
+	if !ok {
+		return
+	}
+	cert, err := cc.service.ClaimSessionCertificate(sessionID, c.GetString("userId"))
+	if err != nil {
+		writeCertificateError(c, err, "failed to issue certificate")
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// ClaimGroupCertificate godoc
+// @Summary Claim a class group's certificate
+// @Description Issues the caller's certificate for a class group once they have completed every scenario
+// @Description assigned to it, or returns it if it was already issued.
+// @Tags certificates
+// @Produce json
+// @Param groupId path string true "Class group ID"
+// @Success 200 {object} models.Certificate
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /certificates/groups/{groupId} [post]
+// @Security BearerAuth
+func (cc *CertificateController) ClaimGroupCertificate(c *gin.Context) {
+	groupID, ok := parseID(c, "groupId", "invalid group ID")
+	if !ok {
+		return
+	}
+	cert, err := cc.service.IssueForGroup(groupID, c.GetString("userId"))
+	if err != nil {
+		writeCertificateError(c, err, "failed to issue certificate")
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// loadViewableCertificate loads the certificate named by the :id parameter,
+// answering the request itself when the caller may not see it.
+func (cc *CertificateController) loadViewableCertificate(c *gin.Context) (*models.Certificate, bool) {
+	id, ok := parseID(c, "id", "invalid certificate ID")
+	if !ok {
+		return nil, false
+	}
+	cert, err := cc.service.GetCertificate(id)
+	if err != nil {
+		writeCertificateError(c, err, "failed to load certificate")
+		return nil, false
+	}
+	allowed, err := cc.service.CanView(cert, c.GetString("userId"), access.IsAdmin(c.GetStringSlice("userRoles")))
+	if err != nil {
+		writeCertificateError(c, err, "failed to load certificate")
+		return nil, false
+	}
+	if !allowed {
+		writeCertificateError(c, services.ErrCertificateAccessDenied, "")
+		return nil, false
+	}
+	return cert, true
+}
+
+func parseID(c *gin.Context, param, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeCertificateError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrCertificateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "certificate not found"})
+	case errors.Is(err, services.ErrCertificateAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCertificateAlreadyRevoked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotEligible):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		slog.Error(message, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	auth "soli/formations/src/auth"
+	"soli/formations/src/certificates/services"
+)
+
+// RegisterRoutes wires the certificate endpoints.
+//
+// verify, credential and the issuer profile and revocation list are what a
+// funding body or a badge wallet checks a certificate with, without an OCF
+// account, so they carry no AuthManagement. Everything else needs a
+// signed-in user.
+func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	mw := auth.NewAuthMiddleware(db)
+	controller := NewCertificateController(services.NewCertificateService(db, services.IssuerFromEnv()))
+
+	certificates := router.Group("/certificates")
+	certificates.GET("/issuer", controller.GetIssuerProfile)
+	certificates.GET("/issuer/revocations", controller.GetRevocationList)
+	certificates.GET("/:id/verify", controller.VerifyCertificate)
+	certificates.GET("/:id/credential", controller.GetCredential)
+
+	certificates.GET("", mw.AuthManagement(), controller.ListMyCertificates)
+	certificates.GET("/:id", mw.AuthManagement(), controller.GetCertificate)
+	certificates.GET("/:id/pdf", mw.AuthManagement(), controller.DownloadCertificatePDF)
+	certificates.POST("/:id/revoke", mw.AuthManagement(), controller.RevokeCertificate)
+	certificates.POST("/sessions/:sessionId", mw.AuthManagement(), controller.ClaimSessionCertificate)
+	certificates.POST("/groups/:groupId", mw.AuthManagement(), controller.ClaimGroupCertificate)
+
+	router.GET("/organizations/:id/certificates", mw.AuthManagement(), controller.ListOrganizationCertificates)
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"log"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/interfaces"
+)
+
+// RegisterPermissions registers the Casbin policies and RouteRegistry entries
+// of the certificate endpoints. The verification endpoints are declared
+// without a gateway policy: they are mounted without AuthManagement.
+func RegisterPermissions(enforcer interfaces.EnforcerInterface) {
+	log.Println("=== Registering certificate permissions ===")
+
+	access.RegisterEnforced(enforcer, "Certificates",
+		access.RoutePermission{
+			Path: "/api/v1/certificates/issuer", Method: "GET", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Certificate issuer profile and signing keys",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/issuer/revocations", Method: "GET", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Revoked certificate credentials",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/:id/verify", Method: "GET", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Verify a certificate (public, the target of its QR code)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/:id/credential", Method: "GET", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "A certificate's signed Open Badges credential (public)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "List the caller's certificates",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/:id", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "View a certificate (recipient, or manager of its organization or group)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/:id/pdf", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Download a certificate as PDF (recipient, or manager of its organization or group)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/:id/revoke", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Revoke a certificate (manager of its organization or group)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/sessions/:sessionId", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Claim the certificate of the caller's completed session",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/certificates/groups/:groupId", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Claim the caller's certificate for a completed class group",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/certificates", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "List the certificates issued under an organization",
+		},
+	)
+
+	log.Println("=== Certificate permissions registered ===")
+}
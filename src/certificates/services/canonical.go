@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// canonicalJSON serializes a JSON value per the JSON Canonicalization Scheme
+// (RFC 8785), the form eddsa-jcs-2022 signs: object members sorted by key, no
+// whitespace, ECMAScript string and number formatting. Two serializations of
+// the same credential, however their keys were ordered, sign identically.
+func canonicalJSON(value any) ([]byte, error) {
+	// A round trip turns structs, typed maps and slices into the generic
+	// JSON types the writer below handles.
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %s: %w", v, err)
+		}
+		number, err := canonicalNumber(f)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case string:
+		writeCanonicalString(buf, v)
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// RFC 8785 orders keys by their UTF-16 code units.
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, key)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported JSON value %T", value)
+	}
+	return nil
+}
+
+// writeCanonicalString escapes like ECMAScript's JSON.stringify: only the
+// quote, the backslash and control characters, with short forms where they
+// exist.
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// canonicalNumber formats f like ECMAScript's Number.prototype.toString.
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("%v is not a JSON number", f)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		if f == math.Trunc(f) {
+			return new(big.Float).SetFloat64(f).Text('f', 0), nil
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	// Exponent form: Go writes "1e+21" and "1e-07", ECMAScript "1e+21" and
+	// "1e-7".
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(s, "e")
+	sign := exponent[0]
+	exponent = strings.TrimLeft(exponent[1:], "0")
+	return mantissa + "e" + string(sign) + exponent, nil
+}
+
+func lessUTF16(a, b string) bool {
+	for a != "" && b != "" {
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			if ka, kb := utf16Key(ra), utf16Key(rb); ka != kb {
+				return ka < kb
+			}
+			return ra < rb
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return a == "" && b != ""
+}
+
+// utf16Key orders runes by their first UTF-16 code unit: supplementary
+// characters, encoded as surrogates, sort before U+E000..U+FFFF.
+func utf16Key(r rune) rune {
+	if r >= 0x10000 {
+		return 0xD800 + (r-0x10000)>>10
+	}
+	return r
+}
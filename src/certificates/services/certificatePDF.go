@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+
+	"soli/formations/src/certificates/models"
+	orgModels "soli/formations/src/organizations/models"
+)
+
+// Organization metadata keys a certificate's branding is read from. Both are
+// optional: without them the certificate carries the organization's name in
+// the default color.
+const (
+	// BrandingLogoKey holds the logo as a PNG or JPEG data URL
+	// ("data:image/png;base64,...").
+	BrandingLogoKey = "certificate_logo"
+	// BrandingColorKey holds the accent color as "#rrggbb".
+	BrandingColorKey = "certificate_color"
+)
+
+var defaultAccent = [3]int{0x1f, 0x3a, 0x5f}
+
+// certificateBranding is how a certificate looks for its organization.
+type certificateBranding struct {
+	accent    [3]int
+	logo      []byte
+	logoType  string // "PNG" or "JPG"
+	publisher string
+}
+
+// RenderPDF renders a certificate as a one-page A4 landscape PDF, with a QR
+// code pointing to its public verification URL.
+func (s *CertificateService) RenderPDF(cert *models.Certificate) ([]byte, error) {
+	branding := s.branding(cert)
+	accent := branding.accent
+
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetCreationDate(cert.IssuedAt)
+	pdf.SetModificationDate(cert.IssuedAt)
+	pdf.SetTitle("Certificate of completion - "+cert.Title, true)
+	pdf.SetAuthor(branding.publisher, true)
+	pdf.SetCreator(s.issuer.Name, true)
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, pageHeight := pdf.GetPageSize()
+
+	pdf.SetDrawColor(accent[0], accent[1], accent[2])
+	pdf.SetLineWidth(1.5)
+	pdf.Rect(10, 10, pageWidth-20, pageHeight-20, "D")
+	pdf.SetLineWidth(0.3)
+	pdf.Rect(14, 14, pageWidth-28, pageHeight-28, "D")
+
+	centered := func(y float64, font string, style string, size float64, color [3]int, text string) {
+		pdf.SetFont(font, style, size)
+		pdf.SetTextColor(color[0], color[1], color[2])
+		pdf.SetXY(20, y)
+		pdf.CellFormat(pageWidth-40, size*0.5, tr(text), "", 0, "C", false, 0, "")
+	}
+	dark := [3]int{0x22, 0x22, 0x22}
+	grey := [3]int{0x66, 0x66, 0x66}
+
+	if branding.logo != nil {
+		options := gofpdf.ImageOptions{ImageType: branding.logoType, ReadDpi: false}
+		info := pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(branding.logo))
+		if pdf.Err() {
+			// A logo that does not decode leaves the certificate unbranded
+			// rather than unavailable.
+			slog.Warn("invalid certificate logo", "organization_id", cert.OrganizationID, "err", pdf.Error())
+			pdf.ClearError()
+		} else if info != nil && info.Height() > 0 {
+			height := 18.0
+			width := height * info.Width() / info.Height()
+			pdf.ImageOptions("logo", (pageWidth-width)/2, 22, width, height, false, options, 0, "")
+		}
+	} else if branding.publisher != "" {
+		centered(28, "Helvetica", "B", 16, accent, branding.publisher)
+	}
+
+	centered(50, "Helvetica", "B", 30, accent, "CERTIFICATE OF COMPLETION")
+	centered(70, "Helvetica", "", 14, grey, "This certifies that")
+	centered(82, "Helvetica", "B", 28, dark, cert.RecipientName)
+	if cert.Kind == models.CertificateKindGroup {
+		centered(100, "Helvetica", "", 14, grey, "has completed every hands-on scenario of")
+	} else {
+		centered(100, "Helvetica", "", 14, grey, "has successfully completed the hands-on scenario")
+	}
+	centered(112, "Helvetica", "B", 20, dark, cert.Title)
+	if cert.Grade != nil {
+		centered(126, "Helvetica", "", 14, grey, fmt.Sprintf("with a grade of %s%%", formatGrade(roundGrade(*cert.Grade))))
+	}
+	if cert.IsRevoked() {
+		centered(138, "Helvetica", "B", 16, [3]int{0xc0, 0x1c, 0x1c}, "REVOKED on "+cert.RevokedAt.Format("2 January 2006"))
+	}
+
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.SetTextColor(dark[0], dark[1], dark[2])
+	pdf.SetXY(28, 158)
+	pdf.CellFormat(150, 6, tr("Issued on "+cert.IssuedAt.Format("2 January 2006")), "", 2, "L", false, 0, "")
+	if branding.publisher != "" {
+		pdf.CellFormat(150, 6, tr("by "+branding.publisher), "", 2, "L", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(grey[0], grey[1], grey[2])
+	pdf.SetXY(28, 176)
+	pdf.CellFormat(180, 4, "Certificate "+cert.ID.String(), "", 2, "L", false, 0, "")
+	pdf.CellFormat(180, 4, tr("Verify at "+s.issuer.VerifyURL(cert.ID.String())), "", 2, "L", false, 0, "")
+
+	const qrSize = 34.0
+	if err := drawQRCode(pdf, s.issuer.VerifyURL(cert.ID.String()), pageWidth-28-qrSize, 150, qrSize); err != nil {
+		return nil, err
+	}
+	pdf.SetXY(pageWidth-28-qrSize, 150+qrSize+1)
+	pdf.CellFormat(qrSize, 4, "Scan to verify", "", 0, "C", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render certificate: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawQRCode draws content as a QR code of the given size, in vector form.
+func drawQRCode(pdf *gofpdf.Fpdf, content string, x, y, size float64) error {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+	module := size / float64(len(bitmap))
+	pdf.SetFillColor(0, 0, 0)
+	for row, line := range bitmap {
+		for col, dark := range line {
+			if dark {
+				pdf.Rect(x+float64(col)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+	return nil
+}
+
+// branding reads the certificate organization's branding. The publisher is
+// the name recorded at issue time; logo and color are read at render time, so
+// an organization that rebrands gets new PDFs with its new look.
+func (s *CertificateService) branding(cert *models.Certificate) certificateBranding {
+	branding := certificateBranding{accent: defaultAccent, publisher: cert.OrganizationName}
+	if cert.OrganizationID == nil {
+		return branding
+	}
+	var org orgModels.Organization
+	if err := s.db.Select("id", "metadata").First(&org, "id = ?", *cert.OrganizationID).Error; err != nil {
+		return branding
+	}
+	if color, ok := org.Metadata[BrandingColorKey].(string); ok {
+		if rgb, ok := parseHexColor(color); ok {
+			branding.accent = rgb
+		}
+	}
+	if logo, ok := org.Metadata[BrandingLogoKey].(string); ok {
+		branding.logo, branding.logoType = parseImageDataURL(logo)
+	}
+	return branding
+}
+
+func parseHexColor(color string) ([3]int, bool) {
+	color = strings.TrimPrefix(color, "#")
+	if len(color) != 6 {
+		return [3]int{}, false
+	}
+	value, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return [3]int{}, false
+	}
+	return [3]int{int(value >> 16), int(value >> 8 & 0xff), int(value & 0xff)}, true
+}
+
+// parseImageDataURL decodes a base64 PNG or JPEG data URL.
+func parseImageDataURL(dataURL string) ([]byte, string) {
+	header, payload, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, ""
+	}
+	var imageType string
+	switch strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64") {
+	case "image/png":
+		imageType = "PNG"
+	case "image/jpeg", "image/jpg":
+		imageType = "JPG"
+	default:
+		return nil, ""
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ""
+	}
+	return data, imageType
+}
+
+// roundGrade keeps one decimal, as grades are shown everywhere else.
+func roundGrade(grade float64) float64 {
+	value, _ := strconv.ParseFloat(strconv.FormatFloat(grade, 'f', 1, 64), 64)
+	return value
+}
@@ -0,0 +1,533 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/certificates/dto"
+	"soli/formations/src/certificates/models"
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+var (
+	ErrCertificateNotFound       = errors.New("certificate not found")
+	ErrCertificateAccessDenied   = errors.New("not allowed to manage this certificate")
+	ErrCertificateAlreadyRevoked = errors.New("certificate already revoked")
+	// ErrNotEligible is returned when what a certificate attests did not
+	// happen: the session is not completed, or assignments of the group are
+	// left.
+	ErrNotEligible = errors.New("not eligible for a certificate")
+)
+
+// Verification statuses.
+const (
+	VerificationValid   = "valid"
+	VerificationRevoked = "revoked"
+	VerificationInvalid = "invalid"
+)
+
+// LookupCasdoorUser resolves the recipient's name and email. A variable so
+// tests can issue certificates without a Casdoor server.
+var LookupCasdoorUser = casdoorsdk.GetUserByUserId
+
+// CertificateService issues, serves and revokes certificates of completion.
+type CertificateService struct {
+	db      *gorm.DB
+	issuer  Issuer
+	keys    *signingKeyStore
+	members *access.GormMembershipChecker
+}
+
+// NewCertificateService creates a certificate service signing as issuer.
+func NewCertificateService(db *gorm.DB, issuer Issuer) *CertificateService {
+	return &CertificateService{
+		db:      db,
+		issuer:  issuer,
+		keys:    &signingKeyStore{db: db},
+		members: access.NewGormMembershipChecker(db),
+	}
+}
+
+// Issuer returns who the service signs as.
+func (s *CertificateService) Issuer() Issuer {
+	return s.issuer
+}
+
+// IssueForCompletedSession issues the certificates a session completion
+// earns: the session's own, and the certificate of every class group whose
+// assignments the learner has now all completed. Called once the completing
+// transaction has committed; failures are logged, and the learner can claim
+// a missing certificate later.
+func IssueForCompletedSession(db *gorm.DB, sessionID uuid.UUID) {
+	s := NewCertificateService(db, IssuerFromEnv())
+	cert, err := s.IssueForSession(sessionID)
+	if err != nil {
+		if !errors.Is(err, ErrNotEligible) {
+			slog.Warn("failed to issue session certificate", "session_id", sessionID, "err", err)
+		}
+		return
+	}
+	for _, groupID := range s.assignedGroupIDs(cert.UserID, *cert.ScenarioID) {
+		if _, err := s.IssueForGroup(groupID, cert.UserID); err != nil && !errors.Is(err, ErrNotEligible) {
+			slog.Warn("failed to issue group certificate", "group_id", groupID, "user_id", cert.UserID, "err", err)
+		}
+	}
+}
+
+// IssueForSession issues the certificate of a completed session, or returns
+// the one already issued.
+func (s *CertificateService) IssueForSession(sessionID uuid.UUID) (*models.Certificate, error) {
+	issueKey := "session:" + sessionID.String()
+	if existing, err := s.findByIssueKey(issueKey); existing != nil || err != nil {
+		return existing, err
+	}
+
+	var session scenarioModels.ScenarioSession
+	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotEligible
+		}
+		return nil, err
+	}
+	if session.Status != "completed" || session.IsPreview {
+		return nil, ErrNotEligible
+	}
+	var scenario scenarioModels.Scenario
+	if err := s.db.Unscoped().Select("id", "title", "organization_id").First(&scenario, "id = ?", session.ScenarioID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scenario: %w", err)
+	}
+
+	cert := &models.Certificate{
+		Kind:           models.CertificateKindScenario,
+		UserID:         session.UserID,
+		IssueKey:       issueKey,
+		ScenarioID:     &session.ScenarioID,
+		SessionID:      &session.ID,
+		OrganizationID: s.sessionOrganization(&session, &scenario),
+		Title:          scenario.Title,
+		Grade:          session.Grade,
+	}
+	return s.issue(cert, "urn:uuid:"+scenario.ID.String())
+}
+
+// IssueForGroup issues a learner's certificate for a class group once they
+// have completed a session of every scenario actively assigned to it, or
+// returns the one already issued. The grade is the average of the learner's
+// best grade on each scenario.
+func (s *CertificateService) IssueForGroup(groupID uuid.UUID, userID string) (*models.Certificate, error) {
+	issueKey := "group:" + groupID.String() + ":" + userID
+	if existing, err := s.findByIssueKey(issueKey); existing != nil || err != nil {
+		return existing, err
+	}
+
+	var group groupModels.ClassGroup
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotEligible
+		}
+		return nil, err
+	}
+	isMember, err := s.members.CheckGroupRole(groupID.String(), userID, access.RoleMember)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotEligible
+	}
+
+	var scenarioIDs []uuid.UUID
+	if err := s.db.Model(&scenarioModels.ScenarioAssignment{}).
+		Where("group_id = ? AND scope = ? AND is_active = ?", groupID, "group", true).
+		Distinct().Pluck("scenario_id", &scenarioIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(scenarioIDs) == 0 {
+		return nil, ErrNotEligible
+	}
+
+	var sessions []scenarioModels.ScenarioSession
+	if err := s.db.Select("scenario_id", "grade").
+		Where("user_id = ? AND scenario_id IN ? AND status = ? AND is_preview = ?", userID, scenarioIDs, "completed", false).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	best := make(map[uuid.UUID]*float64, len(scenarioIDs))
+	for _, session := range sessions {
+		current, seen := best[session.ScenarioID]
+		if !seen || (session.Grade != nil && (current == nil || *session.Grade > *current)) {
+			best[session.ScenarioID] = session.Grade
+		}
+	}
+	if len(best) < len(scenarioIDs) {
+		return nil, ErrNotEligible
+	}
+	var total float64
+	graded := 0
+	for _, grade := range best {
+		if grade != nil {
+			total += *grade
+			graded++
+		}
+	}
+	var grade *float64
+	if graded > 0 {
+		average := total / float64(graded)
+		grade = &average
+	}
+
+	title := group.DisplayName
+	if title == "" {
+		title = group.Name
+	}
+	cert := &models.Certificate{
+		Kind:           models.CertificateKindGroup,
+		UserID:         userID,
+		IssueKey:       issueKey,
+		GroupID:        &group.ID,
+		OrganizationID: group.OrganizationID,
+		Title:          title,
+		Grade:          grade,
+	}
+	return s.issue(cert, "urn:uuid:"+group.ID.String())
+}
+
+// issue completes cert with its recipient, organization and signed
+// credential, and stores it.
+func (s *CertificateService) issue(cert *models.Certificate, achievementID string) (*models.Certificate, error) {
+	user, err := LookupCasdoorUser(cert.UserID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("failed to look up recipient %s: %v", cert.UserID, err)
+	}
+	cert.RecipientName = user.DisplayName
+	if cert.RecipientName == "" {
+		cert.RecipientName = user.Name
+	}
+	cert.RecipientEmail = user.Email
+
+	if cert.OrganizationID != nil {
+		var org orgModels.Organization
+		if err := s.db.Select("id", "name", "display_name").First(&org, "id = ?", *cert.OrganizationID).Error; err == nil {
+			cert.OrganizationName = org.DisplayName
+			if cert.OrganizationName == "" {
+				cert.OrganizationName = org.Name
+			}
+		} else {
+			cert.OrganizationID = nil
+		}
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		id = uuid.New()
+	}
+	cert.ID = id
+	cert.IssuedAt = time.Now().UTC().Truncate(time.Second)
+
+	credential, err := s.issuer.buildCredential(cert, achievementID)
+	if err != nil {
+		return nil, err
+	}
+	kid, key, err := s.keys.current()
+	if err != nil {
+		return nil, err
+	}
+	if err := signCredential(credential, key, s.issuer.VerificationMethod(kid), cert.IssuedAt); err != nil {
+		return nil, fmt.Errorf("failed to sign credential: %w", err)
+	}
+	raw, err := json.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+	cert.Credential = string(raw)
+
+	if err := s.db.Create(cert).Error; err != nil {
+		// Completion can be reported twice at once; the unique issue key lets
+		// only one certificate through, and the loser returns it.
+		if existing, findErr := s.findByIssueKey(cert.IssueKey); existing != nil && findErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to store certificate: %w", err)
+	}
+	webhookServices.Emit(s.db, cert.OrganizationID, webhookServices.EventCertificateIssued, s.eventData(cert))
+	return cert, nil
+}
+
+func (s *CertificateService) findByIssueKey(issueKey string) (*models.Certificate, error) {
+	var cert models.Certificate
+	err := s.db.Where("issue_key = ?", issueKey).First(&cert).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// sessionOrganization picks the organization whose branding a session's
+// certificate carries: the one that assigned the scenario — through the
+// session's assignment, one of the learner's groups or their
+// organization — or else the scenario's own organization if the learner
+// belongs to it. Nil for a learner training on their own.
+func (s *CertificateService) sessionOrganization(session *scenarioModels.ScenarioSession, scenario *scenarioModels.Scenario) *uuid.UUID {
+	var assignment scenarioModels.ScenarioAssignment
+	query := s.db.Select("organization_id", "group_id")
+	if session.AssignmentID != nil {
+		query = query.Where("id = ?", *session.AssignmentID)
+	} else {
+		groupIDs := s.db.Model(&groupModels.GroupMember{}).Select("group_id").
+			Where("user_id = ? AND is_active = ?", session.UserID, true)
+		orgIDs := s.db.Model(&orgModels.OrganizationMember{}).Select("organization_id").
+			Where("user_id = ? AND is_active = ?", session.UserID, true)
+		query = query.Where("scenario_id = ? AND is_active = ?", session.ScenarioID, true).
+			Where("(scope = 'group' AND group_id IN (?)) OR (scope = 'org' AND organization_id IN (?))", groupIDs, orgIDs).
+			Order("created_at ASC")
+	}
+	if err := query.First(&assignment).Error; err == nil {
+		if assignment.OrganizationID != nil {
+			return assignment.OrganizationID
+		}
+		if assignment.GroupID != nil {
+			var group groupModels.ClassGroup
+			if err := s.db.Select("id", "organization_id").First(&group, "id = ?", *assignment.GroupID).Error; err == nil && group.OrganizationID != nil {
+				return group.OrganizationID
+			}
+		}
+	}
+
+	if scenario.OrganizationID != nil {
+		if isMember, err := s.members.CheckOrgRole(scenario.OrganizationID.String(), session.UserID, access.RoleMember); err == nil && isMember {
+			return scenario.OrganizationID
+		}
+	}
+	return nil
+}
+
+// assignedGroupIDs returns the learner's active groups the scenario is
+// actively assigned to.
+func (s *CertificateService) assignedGroupIDs(userID string, scenarioID uuid.UUID) []uuid.UUID {
+	var groupIDs []uuid.UUID
+	memberOf := s.db.Model(&groupModels.GroupMember{}).Select("group_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+	if err := s.db.Model(&scenarioModels.ScenarioAssignment{}).
+		Where("scenario_id = ? AND scope = ? AND is_active = ? AND group_id IN (?)", scenarioID, "group", true, memberOf).
+		Distinct().Pluck("group_id", &groupIDs).Error; err != nil {
+		slog.Warn("failed to list assigned groups", "user_id", userID, "scenario_id", scenarioID, "err", err)
+	}
+	return groupIDs
+}
+
+// ClaimSessionCertificate issues the certificate of one of the caller's
+// completed sessions, if it was not issued on completion.
+func (s *CertificateService) ClaimSessionCertificate(sessionID uuid.UUID, userID string) (*models.Certificate, error) {
+	var session scenarioModels.ScenarioSession
+	if err := s.db.Select("id", "user_id").First(&session, "id = ?", sessionID).Error; err != nil || session.UserID != userID {
+		return nil, ErrNotEligible
+	}
+	return s.IssueForSession(sessionID)
+}
+
+// GetCertificate loads a certificate.
+func (s *CertificateService) GetCertificate(id uuid.UUID) (*models.Certificate, error) {
+	var cert models.Certificate
+	if err := s.db.First(&cert, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCertificateNotFound
+		}
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// ListForUser returns a learner's certificates, newest first.
+func (s *CertificateService) ListForUser(userID string) ([]models.Certificate, error) {
+	var certs []models.Certificate
+	err := s.db.Where("user_id = ?", userID).Order("issued_at DESC").Find(&certs).Error
+	return certs, err
+}
+
+// ListForOrganization returns the certificates issued under an
+// organization's branding, newest first.
+func (s *CertificateService) ListForOrganization(orgID uuid.UUID) ([]models.Certificate, error) {
+	var certs []models.Certificate
+	err := s.db.Where("organization_id = ?", orgID).Order("issued_at DESC").Find(&certs).Error
+	return certs, err
+}
+
+// CanView reports whether a user may see a certificate in full and download
+// its PDF: its recipient, and whoever may revoke it.
+func (s *CertificateService) CanView(cert *models.Certificate, userID string, isAdmin bool) (bool, error) {
+	if cert.UserID == userID {
+		return true, nil
+	}
+	return s.CanManage(cert, userID, isAdmin)
+}
+
+// CanManage reports whether a user may revoke a certificate: an
+// administrator, a manager of the organization it was issued under, or a
+// manager of the group it was issued for.
+func (s *CertificateService) CanManage(cert *models.Certificate, userID string, isAdmin bool) (bool, error) {
+	if isAdmin {
+		return true, nil
+	}
+	if cert.OrganizationID != nil {
+		ok, err := s.members.CheckOrgRole(cert.OrganizationID.String(), userID, access.RoleManager)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	if cert.GroupID != nil {
+		return s.members.CheckGroupRole(cert.GroupID.String(), userID, access.RoleManager)
+	}
+	return false, nil
+}
+
+// Revoke revokes a certificate. It stays verifiable, and verifiers are told
+// it was revoked and why.
+func (s *CertificateService) Revoke(id uuid.UUID, userID string, isAdmin bool, reason string) (*models.Certificate, error) {
+	cert, err := s.GetCertificate(id)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := s.CanManage(cert, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrCertificateAccessDenied
+	}
+	if cert.IsRevoked() {
+		return nil, ErrCertificateAlreadyRevoked
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.Certificate{}).
+		Where("id = ? AND revoked_at IS NULL", cert.ID).
+		Updates(map[string]any{"revoked_at": now, "revoked_by_id": userID, "revocation_reason": reason})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCertificateAlreadyRevoked
+	}
+	cert.RevokedAt, cert.RevokedByID, cert.RevocationReason = &now, userID, reason
+	webhookServices.Emit(s.db, cert.OrganizationID, webhookServices.EventCertificateRevoked, s.eventData(cert))
+	return cert, nil
+}
+
+// Verify checks a certificate's credential signature and revocation status.
+func (s *CertificateService) Verify(id uuid.UUID) (*dto.CertificateVerification, error) {
+	cert, err := s.GetCertificate(id)
+	if err != nil {
+		return nil, err
+	}
+	verification := &dto.CertificateVerification{
+		ID:               cert.ID,
+		Kind:             cert.Kind,
+		RecipientName:    cert.RecipientName,
+		Title:            cert.Title,
+		OrganizationName: cert.OrganizationName,
+		Grade:            cert.Grade,
+		IssuedAt:         cert.IssuedAt,
+		RevokedAt:        cert.RevokedAt,
+		RevocationReason: cert.RevocationReason,
+		CredentialURL:    s.issuer.CredentialURL(cert.ID.String()),
+		IssuerURL:        s.issuer.ProfileURL(),
+	}
+
+	var credential map[string]any
+	if err := json.Unmarshal([]byte(cert.Credential), &credential); err != nil {
+		slog.Warn("stored credential is not JSON", "certificate_id", cert.ID, "err", err)
+	} else if _, keys, err := s.keys.publicKeys(); err != nil {
+		return nil, err
+	} else if err := verifyCredential(credential, keys); err != nil {
+		slog.Warn("certificate credential does not verify", "certificate_id", cert.ID, "err", err)
+	} else {
+		verification.SignatureValid = true
+	}
+
+	switch {
+	case !verification.SignatureValid:
+		verification.Status = VerificationInvalid
+	case cert.IsRevoked():
+		verification.Status = VerificationRevoked
+	default:
+		verification.Status = VerificationValid
+	}
+	return verification, nil
+}
+
+// IssuerProfile returns the issuer Profile publishing the signing keys.
+func (s *CertificateService) IssuerProfile() (map[string]any, error) {
+	stored, keys, err := s.keys.publicKeys()
+	if err != nil {
+		return nil, err
+	}
+	return s.issuer.issuerProfile(stored, keys), nil
+}
+
+// RevocationList returns the issuer's list of revoked credentials.
+func (s *CertificateService) RevocationList() (*dto.RevocationList, error) {
+	var revoked []models.Certificate
+	if err := s.db.Select("id", "revocation_reason").
+		Where("revoked_at IS NOT NULL").Order("revoked_at ASC").
+		Find(&revoked).Error; err != nil {
+		return nil, err
+	}
+	list := &dto.RevocationList{
+		ID:                 s.issuer.RevocationListURL(),
+		Issuer:             s.issuer.ProfileURL(),
+		RevokedCredentials: make([]dto.RevokedCredential, 0, len(revoked)),
+	}
+	for _, cert := range revoked {
+		list.RevokedCredentials = append(list.RevokedCredentials, dto.RevokedCredential{
+			ID:               s.issuer.CredentialURL(cert.ID.String()),
+			RevocationReason: cert.RevocationReason,
+		})
+	}
+	return list, nil
+}
+
+// certificateEventData is the "data" of the certificate webhooks.
+type certificateEventData struct {
+	CertificateID    uuid.UUID  `json:"certificate_id"`
+	Kind             string     `json:"kind"`
+	UserID           string     `json:"user_id"`
+	ScenarioID       *uuid.UUID `json:"scenario_id,omitempty"`
+	SessionID        *uuid.UUID `json:"session_id,omitempty"`
+	GroupID          *uuid.UUID `json:"group_id,omitempty"`
+	Title            string     `json:"title"`
+	Grade            *float64   `json:"grade,omitempty"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	VerifyURL        string     `json:"verify_url"`
+}
+
+func (s *CertificateService) eventData(cert *models.Certificate) certificateEventData {
+	return certificateEventData{
+		CertificateID:    cert.ID,
+		Kind:             cert.Kind,
+		UserID:           cert.UserID,
+		ScenarioID:       cert.ScenarioID,
+		SessionID:        cert.SessionID,
+		GroupID:          cert.GroupID,
+		Title:            cert.Title,
+		Grade:            cert.Grade,
+		IssuedAt:         cert.IssuedAt,
+		RevokedAt:        cert.RevokedAt,
+		RevocationReason: cert.RevocationReason,
+		VerifyURL:        s.issuer.VerifyURL(cert.ID.String()),
+	}
+}
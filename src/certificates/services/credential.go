@@ -0,0 +1,190 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"soli/formations/src/certificates/models"
+)
+
+// JSON-LD contexts of an Open Badges 3.0 credential.
+var credentialContexts = []any{
+	"https://www.w3.org/ns/credentials/v2",
+	"https://purl.imsglobal.org/spec/ob/v3p0/context-3.0.3.json",
+}
+
+// revocationListType is the credentialStatus type Open Badges 3.0 defines for
+// an issuer-published list of revoked credentials.
+const revocationListType = "1EdTechRevocationList"
+
+// Issuer is who signs certificates: the public base URL of this API, which
+// credential, profile and verification URLs are built on, and the name
+// verifiers see.
+type Issuer struct {
+	BaseURL string
+	Name    string
+}
+
+// IssuerFromEnv reads CERTIFICATE_BASE_URL and CERTIFICATE_ISSUER_NAME.
+func IssuerFromEnv() Issuer {
+	name := os.Getenv("CERTIFICATE_ISSUER_NAME")
+	if name == "" {
+		name = "OCF"
+	}
+	return Issuer{BaseURL: strings.TrimRight(os.Getenv("CERTIFICATE_BASE_URL"), "/"), Name: name}
+}
+
+// ProfileURL is the issuer profile, which publishes the signing keys.
+func (i Issuer) ProfileURL() string {
+	return i.BaseURL + "/api/v1/certificates/issuer"
+}
+
+// RevocationListURL is the list every credential's status points to.
+func (i Issuer) RevocationListURL() string {
+	return i.ProfileURL() + "/revocations"
+}
+
+// CredentialURL identifies a certificate's credential, and serves it.
+func (i Issuer) CredentialURL(certificateID string) string {
+	return i.BaseURL + "/api/v1/certificates/" + certificateID + "/credential"
+}
+
+// VerifyURL is the public page a certificate's QR code points to.
+func (i Issuer) VerifyURL(certificateID string) string {
+	return i.BaseURL + "/api/v1/certificates/" + certificateID + "/verify"
+}
+
+// VerificationMethod names a signing key in the issuer profile.
+func (i Issuer) VerificationMethod(kid string) string {
+	return i.ProfileURL() + "#key-" + kid
+}
+
+// buildCredential renders a certificate as an unsigned OpenBadgeCredential.
+// The recipient is identified by name, and by a salted hash of their email
+// that lets a verifier who knows the address confirm it without the
+// credential disclosing it.
+func (i Issuer) buildCredential(cert *models.Certificate, achievementID string) (map[string]any, error) {
+	identifiers := []any{map[string]any{
+		"type":         "IdentityObject",
+		"identityType": "name",
+		"hashed":       false,
+		"identityHash": cert.RecipientName,
+	}}
+	if cert.RecipientEmail != "" {
+		salt := make([]byte, 8)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		saltHex := hex.EncodeToString(salt)
+		hash := sha256.Sum256([]byte(strings.ToLower(cert.RecipientEmail) + saltHex))
+		identifiers = append(identifiers, map[string]any{
+			"type":         "IdentityObject",
+			"identityType": "emailAddress",
+			"hashed":       true,
+			"salt":         saltHex,
+			"identityHash": "sha256$" + hex.EncodeToString(hash[:]),
+		})
+	}
+
+	achievement := map[string]any{
+		"id":              achievementID,
+		"type":            []any{"Achievement"},
+		"achievementType": "Certificate",
+		"name":            cert.Title,
+		"description":     achievementDescription(cert),
+		"criteria":        map[string]any{"narrative": achievementCriteria(cert)},
+	}
+	if cert.OrganizationID != nil {
+		achievement["creator"] = map[string]any{
+			"id":   "urn:uuid:" + cert.OrganizationID.String(),
+			"type": []any{"Profile"},
+			"name": cert.OrganizationName,
+		}
+	}
+	subject := map[string]any{
+		"type":        []any{"AchievementSubject"},
+		"identifier":  identifiers,
+		"achievement": achievement,
+	}
+	if cert.Grade != nil {
+		resultID := achievementID + "#grade"
+		achievement["resultDescription"] = []any{map[string]any{
+			"id":         resultID,
+			"type":       []any{"ResultDescription"},
+			"name":       "Grade",
+			"resultType": "Percent",
+		}}
+		subject["result"] = []any{map[string]any{
+			"type":              []any{"Result"},
+			"resultDescription": resultID,
+			"value":             formatGrade(*cert.Grade),
+		}}
+	}
+
+	certificateID := cert.ID.String()
+	return map[string]any{
+		"@context":          credentialContexts,
+		"id":                i.CredentialURL(certificateID),
+		"type":              []any{"VerifiableCredential", "OpenBadgeCredential"},
+		"name":              cert.Title,
+		"issuer":            map[string]any{"id": i.ProfileURL(), "type": []any{"Profile"}, "name": i.Name},
+		"validFrom":         cert.IssuedAt.UTC().Format(time.RFC3339),
+		"credentialSubject": subject,
+		"credentialStatus": map[string]any{
+			"id":   i.RevocationListURL(),
+			"type": revocationListType,
+		},
+	}, nil
+}
+
+func achievementDescription(cert *models.Certificate) string {
+	if cert.Kind == models.CertificateKindGroup {
+		return fmt.Sprintf("Completed every hands-on scenario assigned to %s.", cert.Title)
+	}
+	return fmt.Sprintf("Completed the hands-on scenario %s.", cert.Title)
+}
+
+func achievementCriteria(cert *models.Certificate) string {
+	if cert.Kind == models.CertificateKindGroup {
+		return "The learner completed every scenario assigned to the class, each in a live environment checked step by step."
+	}
+	return "The learner completed every step of the scenario in a live environment checked step by step."
+}
+
+// formatGrade renders a 0-100 grade without trailing zeros: "87.5", "100".
+func formatGrade(grade float64) string {
+	return strconv.FormatFloat(grade, 'f', -1, 64)
+}
+
+// issuerProfile renders the issuer Profile, publishing every signing key as a
+// Multikey verification method.
+func (i Issuer) issuerProfile(keys []models.CertificateSigningKey, publicKeys map[string]ed25519.PublicKey) map[string]any {
+	methods := make([]any, 0, len(keys))
+	ids := make([]any, 0, len(keys))
+	for _, k := range keys {
+		id := i.VerificationMethod(k.Kid)
+		methods = append(methods, map[string]any{
+			"id":                 id,
+			"type":               "Multikey",
+			"controller":         i.ProfileURL(),
+			"publicKeyMultibase": multikey(publicKeys[k.Kid]),
+		})
+		ids = append(ids, id)
+	}
+	return map[string]any{
+		"@context":           append(append([]any{}, credentialContexts...), "https://w3id.org/security/multikey/v1"),
+		"id":                 i.ProfileURL(),
+		"type":               []any{"Profile"},
+		"name":               i.Name,
+		"url":                i.BaseURL,
+		"verificationMethod": methods,
+		"assertionMethod":    ids,
+	}
+}
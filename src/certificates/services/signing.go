@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"soli/formations/src/certificates/models"
+)
+
+// Credentials are secured with a W3C Data Integrity proof using the
+// eddsa-jcs-2022 cryptosuite: an Ed25519 signature over the SHA-256 hashes of
+// the JCS-canonical proof options and document. Any verifier implementing the
+// suite can check a credential with nothing but the issuer profile.
+const (
+	proofType        = "DataIntegrityProof"
+	proofCryptosuite = "eddsa-jcs-2022"
+	proofPurpose     = "assertionMethod"
+)
+
+// ErrInvalidProof is returned when a credential's proof does not verify.
+var ErrInvalidProof = errors.New("invalid credential proof")
+
+// signingKeyStore loads the current signing key, creating it the first time,
+// and every key by kid for verification.
+type signingKeyStore struct {
+	db  *gorm.DB
+	mu  sync.Mutex
+	kid string
+	key ed25519.PrivateKey
+}
+
+func (s *signingKeyStore) current() (string, ed25519.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		return s.kid, s.key, nil
+	}
+
+	var stored models.CertificateSigningKey
+	err := s.db.Order("id DESC").First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stored, err = s.create()
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load certificate signing key: %w", err)
+	}
+	key, err := privateKeyFromSeed(stored)
+	if err != nil {
+		return "", nil, err
+	}
+	s.kid, s.key = stored.Kid, key
+	return s.kid, s.key, nil
+}
+
+func (s *signingKeyStore) create() (models.CertificateSigningKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return models.CertificateSigningKey{}, err
+	}
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return models.CertificateSigningKey{}, err
+	}
+	seed := base64.StdEncoding.EncodeToString(key.Seed())
+	stored := models.CertificateSigningKey{Kid: hex.EncodeToString(kid), PrivateKeySeed: seed}
+	if err := s.db.Create(&stored).Error; err != nil {
+		return models.CertificateSigningKey{}, err
+	}
+	// BeforeSave encrypted the field in place.
+	stored.PrivateKeySeed = seed
+	return stored, nil
+}
+
+// publicKeys returns every signing key ever used, oldest first.
+func (s *signingKeyStore) publicKeys() ([]models.CertificateSigningKey, map[string]ed25519.PublicKey, error) {
+	// The issuer profile must list at least the key the next credential will
+	// be signed with.
+	if _, _, err := s.current(); err != nil {
+		return nil, nil, err
+	}
+	var stored []models.CertificateSigningKey
+	if err := s.db.Order("id ASC").Find(&stored).Error; err != nil {
+		return nil, nil, err
+	}
+	keys := make(map[string]ed25519.PublicKey, len(stored))
+	for _, k := range stored {
+		key, err := privateKeyFromSeed(k)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[k.Kid] = key.Public().(ed25519.PublicKey)
+	}
+	return stored, keys, nil
+}
+
+func privateKeyFromSeed(stored models.CertificateSigningKey) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(stored.PrivateKeySeed)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("certificate signing key %s is corrupt", stored.Kid)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// signCredential adds a proof to credential, signed with key and naming
+// verificationMethod.
+func signCredential(credential map[string]any, key ed25519.PrivateKey, verificationMethod string, created time.Time) error {
+	proof := map[string]any{
+		"type":               proofType,
+		"cryptosuite":        proofCryptosuite,
+		"created":            created.UTC().Format(time.RFC3339),
+		"verificationMethod": verificationMethod,
+		"proofPurpose":       proofPurpose,
+	}
+	hash, err := proofHash(credential, proof)
+	if err != nil {
+		return err
+	}
+	proof["proofValue"] = "z" + base58Encode(ed25519.Sign(key, hash))
+	credential["proof"] = proof
+	return nil
+}
+
+// verifyCredential checks the proof of a credential against keys, indexed by
+// kid. The key is found by the "#key-<kid>" fragment of the proof's
+// verification method, so credentials stay verifiable if the base URL
+// changes.
+func verifyCredential(credential map[string]any, keys map[string]ed25519.PublicKey) error {
+	proof, ok := credential["proof"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%w: no proof", ErrInvalidProof)
+	}
+	if proof["type"] != proofType || proof["cryptosuite"] != proofCryptosuite {
+		return fmt.Errorf("%w: unsupported proof %v/%v", ErrInvalidProof, proof["type"], proof["cryptosuite"])
+	}
+	method, _ := proof["verificationMethod"].(string)
+	_, kid, _ := strings.Cut(method, "#key-")
+	key, ok := keys[kid]
+	if !ok {
+		return fmt.Errorf("%w: unknown verification method %q", ErrInvalidProof, method)
+	}
+	value, _ := proof["proofValue"].(string)
+	signature, err := base58Decode(strings.TrimPrefix(value, "z"))
+	if !strings.HasPrefix(value, "z") || err != nil {
+		return fmt.Errorf("%w: malformed proof value", ErrInvalidProof)
+	}
+
+	document := make(map[string]any, len(credential))
+	for k, v := range credential {
+		if k != "proof" {
+			document[k] = v
+		}
+	}
+	options := make(map[string]any, len(proof))
+	for k, v := range proof {
+		if k != "proofValue" {
+			options[k] = v
+		}
+	}
+	hash, err := proofHash(document, options)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, hash, signature) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// proofHash is the eddsa-jcs-2022 hash data: the hash of the canonical proof
+// options, carrying the document's @context, followed by the hash of the
+// canonical document.
+func proofHash(document, options map[string]any) ([]byte, error) {
+	config := make(map[string]any, len(options)+1)
+	for k, v := range options {
+		config[k] = v
+	}
+	config["@context"] = document["@context"]
+
+	canonicalConfig, err := canonicalJSON(config)
+	if err != nil {
+		return nil, err
+	}
+	canonicalDocument, err := canonicalJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	configHash := sha256.Sum256(canonicalConfig)
+	documentHash := sha256.Sum256(canonicalDocument)
+	return append(configHash[:], documentHash[:]...), nil
+}
+
+// multikey renders an Ed25519 public key as a Multikey publicKeyMultibase:
+// base58btc of the ed25519-pub multicodec prefix and the key.
+func multikey(key ed25519.PublicKey) string {
+	return "z" + base58Encode(append([]byte{0xed, 0x01}, key...))
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, r := range s {
+		digit := strings.IndexRune(base58Alphabet, r)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	decoded := n.Bytes()
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), decoded...), nil
+}
@@ -0,0 +1,44 @@
+package cron
+
+import (
+	"log"
+	"time"
+
+	emailServices "soli/formations/src/email/services"
+	"soli/formations/src/scenarios/services"
+
+	"gorm.io/gorm"
+)
+
+// StartAssignmentDeadlineReminderJob starts a background job that emails
+// learners whose assignment deadline is coming, as set by each assignment's
+// reminder_hours_before. Runs every 15 minutes.
+func StartAssignmentDeadlineReminderJob(db *gorm.DB) {
+	reminderService := services.NewAssignmentReminderService(db, emailServices.NewEmailServiceWithDB(db))
+
+	ticker := time.NewTicker(15 * time.Minute)
+
+	log.Println("✅ Assignment deadline reminder job started (runs every 15 minutes)")
+
+	// Run immediately on startup
+	sendAssignmentReminders(reminderService)
+
+	// Then run on schedule
+	go func() {
+		for range ticker.C {
+			sendAssignmentReminders(reminderService)
+		}
+	}()
+}
+
+func sendAssignmentReminders(reminderService *services.AssignmentReminderService) {
+	count, err := reminderService.SendDueReminders(time.Now())
+	if err != nil {
+		log.Printf("❌ [ASSIGNMENT REMINDERS] Failed to send deadline reminders: %v", err)
+		return
+	}
+
+	if count > 0 {
+		log.Printf("📧 [ASSIGNMENT REMINDERS] Sent %d assignment deadline reminders", count)
+	}
+}
@@ -0,0 +1,49 @@
+package cron
+
+import (
+	"log"
+	"time"
+
+	"soli/formations/src/scenarios/services"
+	terminalServices "soli/formations/src/terminalTrainer/services"
+
+	"gorm.io/gorm"
+)
+
+// StartExamExpiryJob starts a background job that finalises exam sessions
+// whose time limit has passed: grades them and stops their terminal. Learner
+// actions enforce the limit on their own; this catches the learner who simply
+// stopped working. Runs every minute, so an exam overruns by at most that.
+func StartExamExpiryJob(db *gorm.DB) {
+	terminalService := terminalServices.NewTerminalTrainerService(db)
+	sessionService := services.NewScenarioSessionService(db, services.NewFlagService(), services.NewVerificationService())
+	sessionService.SetTerminalStopFunc(func(terminalSessionID string) error {
+		return terminalService.StopSession(terminalSessionID)
+	})
+
+	ticker := time.NewTicker(1 * time.Minute)
+
+	log.Println("✅ Exam expiry job started (runs every minute)")
+
+	// Run immediately on startup
+	finalizeExpiredExams(sessionService)
+
+	// Then run on schedule
+	go func() {
+		for range ticker.C {
+			finalizeExpiredExams(sessionService)
+		}
+	}()
+}
+
+func finalizeExpiredExams(sessionService *services.ScenarioSessionService) {
+	count, err := sessionService.FinalizeExpiredExams()
+	if err != nil {
+		log.Printf("❌ [EXAM EXPIRY] Failed to finalize expired exams: %v", err)
+		return
+	}
+
+	if count > 0 {
+		log.Printf("⏱️ [EXAM EXPIRY] Finalized %d exam sessions past their time limit", count)
+	}
+}
@@ -0,0 +1,48 @@
+package cron
+
+import (
+	"log"
+	"time"
+
+	ltiServices "soli/formations/src/lti/services"
+
+	"gorm.io/gorm"
+)
+
+// StartLtiGradePassbackJob starts a background job that sends the grades of
+// LTI-launched scenario sessions back to their platform through Assignment
+// and Grade Services, and purges expired launch states and deep linking
+// requests. A failed post is retried on the next run. Runs every 5 minutes.
+func StartLtiGradePassbackJob(db *gorm.DB) {
+	ltiService := ltiServices.NewLtiService(db, ltiServices.NewCasdoorAccountProvisioner(db), ltiServices.ToolURLFromEnv())
+
+	ticker := time.NewTicker(5 * time.Minute)
+
+	log.Println("✅ LTI grade passback job started (runs every 5 minutes)")
+
+	// Run immediately on startup
+	syncLtiGrades(ltiService)
+
+	// Then run on schedule
+	go func() {
+		for range ticker.C {
+			syncLtiGrades(ltiService)
+		}
+	}()
+}
+
+func syncLtiGrades(ltiService *ltiServices.LtiService) {
+	if err := ltiService.PurgeExpired(); err != nil {
+		log.Printf("❌ [LTI] Failed to purge expired launch states: %v", err)
+	}
+
+	count, err := ltiService.SyncGrades()
+	if err != nil {
+		log.Printf("❌ [LTI] Failed to sync grades: %v", err)
+		return
+	}
+
+	if count > 0 {
+		log.Printf("📤 [LTI] Sent %d grades to LTI platforms", count)
+	}
+}
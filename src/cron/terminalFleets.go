@@ -0,0 +1,42 @@
+package cron
+
+import (
+	"log"
+	"time"
+
+	terminalServices "soli/formations/src/terminalTrainer/services"
+
+	"gorm.io/gorm"
+)
+
+// StartTerminalFleetJob starts a background job that launches scheduled
+// terminal fleets once their start time has come, carries on with launches a
+// restart interrupted, and stops fleets at their stop time. Runs every minute.
+// A fleet's staggered launch runs inside the job, so the first run is not
+// made on the startup path either.
+func StartTerminalFleetJob(db *gorm.DB) {
+	fleetService := terminalServices.NewTerminalFleetService(db, terminalServices.NewTerminalTrainerService(db))
+
+	ticker := time.NewTicker(1 * time.Minute)
+
+	log.Println("✅ Terminal fleet job started (runs every minute)")
+
+	go func() {
+		runTerminalFleets(fleetService)
+		for range ticker.C {
+			runTerminalFleets(fleetService)
+		}
+	}()
+}
+
+func runTerminalFleets(fleetService terminalServices.TerminalFleetService) {
+	launched, stopped, err := fleetService.RunDue(time.Now())
+	if err != nil {
+		log.Printf("❌ [TERMINAL FLEETS] Failed to run scheduled fleets: %v", err)
+		return
+	}
+
+	if launched > 0 || stopped > 0 {
+		log.Printf("🖥️ [TERMINAL FLEETS] Launched %d and stopped %d terminal fleets", launched, stopped)
+	}
+}
@@ -72,6 +72,28 @@ func InitDefaultTemplates(db *gorm.DB) {
 			IsActive: true,
 			IsSystem: true,
 		},
+		{
+			Name:        "assignment_deadline_reminder",
+			DisplayName: "Assignment Deadline Reminder",
+			Description: "Email sent to learners who have not completed an assignment as its deadline approaches",
+			Subject:     "Reminder: {{.ScenarioTitle}} is due in {{.HoursLeft}} hours",
+			HTMLBody:    getAssignmentDeadlineReminderTemplate(),
+			Variables: func() string {
+				vars := []models.EmailTemplateVariable{
+					{Name: "UserName", Description: "Learner's display name", Example: "John Doe"},
+					{Name: "ScenarioTitle", Description: "Title of the assigned scenario", Example: "Linux permissions"},
+					{Name: "Deadline", Description: "The learner's deadline, in UTC", Example: "2026-03-14 18:00 UTC"},
+					{Name: "HoursLeft", Description: "Hours until the deadline", Example: "24"},
+					{Name: "LateWorkNote", Description: "What happens to work completed after the deadline", Example: "Work completed after the deadline is accepted but marked as late."},
+					{Name: "ScenarioLink", Description: "URL of the scenario", Example: "https://example.com/scenarios/123"},
+					{Name: "PlatformName", Description: "Name of the platform", Example: "OCF Platform"},
+				}
+				data, _ := json.Marshal(vars)
+				return string(data)
+			}(),
+			IsActive: true,
+			IsSystem: true,
+		},
 	}
 
 	for _, template := range templates {
@@ -500,3 +522,111 @@ func getEmailVerificationTemplate() string {
 </body>
 </html>`
 }
+
+// getAssignmentDeadlineReminderTemplate returns the HTML template for assignment deadline reminders
+func getAssignmentDeadlineReminderTemplate() string {
+	return `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Assignment Deadline Reminder</title>
+    <style>
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            background-color: #f5f5f5;
+        }
+        .container {
+            max-width: 600px;
+            margin: 40px auto;
+            background-color: #ffffff;
+            border-radius: 8px;
+            overflow: hidden;
+            box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
+        }
+        .header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            padding: 40px 30px;
+            text-align: center;
+        }
+        .header h1 {
+            font-size: 28px;
+            font-weight: 600;
+        }
+        .content {
+            padding: 40px 30px;
+        }
+        .content p {
+            margin-bottom: 20px;
+            color: #333333;
+            font-size: 16px;
+        }
+        .deadline-box {
+            background-color: #fff8e1;
+            border-left: 4px solid #ffb300;
+            padding: 15px 20px;
+            margin: 25px 0;
+            font-size: 16px;
+        }
+        .button-container {
+            text-align: center;
+            margin: 35px 0;
+        }
+        .button {
+            display: inline-block;
+            padding: 14px 32px;
+            background-color: #667eea;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: #ffffff;
+            text-decoration: none;
+            border-radius: 6px;
+            font-weight: 600;
+            font-size: 16px;
+        }
+        .footer {
+            background-color: #f8f9fa;
+            padding: 25px 30px;
+            text-align: center;
+            border-top: 1px solid #e9ecef;
+        }
+        .footer p {
+            color: #495057;
+            font-size: 13px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Deadline Approaching</h1>
+        </div>
+        <div class="content">
+            <p>Hi {{.UserName}},</p>
+            <p>You have not completed <strong>{{.ScenarioTitle}}</strong> yet, and it is due in {{.HoursLeft}} hours.</p>
+
+            <div class="deadline-box">
+                Deadline: <strong>{{.Deadline}}</strong>
+            </div>
+
+            <p>{{.LateWorkNote}}</p>
+
+            <div class="button-container">
+                <a href="{{.ScenarioLink}}" class="button">Open the Scenario</a>
+            </div>
+        </div>
+        <div class="footer">
+            <p>© 2026 {{.PlatformName}}. All rights reserved.</p>
+            <p>This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>`
+}
@@ -7,6 +7,10 @@ import (
 	"sort"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"soli/formations/src/observability"
 )
 
 type hookRegistry struct {
@@ -123,7 +127,22 @@ func (hr *hookRegistry) ExecuteHooks(ctx *HookContext) error {
 		}
 
 		// Exécuter le hook
-		if err := hook.Execute(ctx); err != nil {
+		_, span := observability.StartSpan(ctx.Context, "hook "+hook.GetName(),
+			attribute.String("hook.name", hook.GetName()),
+			attribute.String("hook.entity", ctx.EntityName),
+			attribute.String("hook.type", string(ctx.HookType)),
+		)
+		start := time.Now()
+		err := hook.Execute(ctx)
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		observability.HookDuration.Observe(time.Since(start).Seconds(),
+			hook.GetName(), ctx.EntityName, string(ctx.HookType), result)
+		observability.EndSpan(span, err)
+
+		if err != nil {
 			log.Printf("❌ Hook '%s' failed: %v", hook.GetName(), err)
 
 			// Before-hooks are synchronous and gate the write: a validation or
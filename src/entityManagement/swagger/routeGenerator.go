@@ -11,6 +11,7 @@ import (
 	ems "soli/formations/src/entityManagement/entityManagementService"
 	entityManagementInterfaces "soli/formations/src/entityManagement/interfaces"
 	controller "soli/formations/src/entityManagement/routes"
+	"soli/formations/src/observability"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -68,8 +69,8 @@ func (srg *SwaggerRouteGenerator) registerActionRoutes(router *gin.RouterGroup,
 	basePath := ems.ResourceBasePath(entityName)
 
 	for _, action := range actions {
-		handlers := make([]gin.HandlerFunc, 0, len(action.Middlewares)+2)
-		handlers = append(handlers, authMiddleware)
+		handlers := make([]gin.HandlerFunc, 0, len(action.Middlewares)+3)
+		handlers = append(handlers, observability.InstrumentRoute(entityName), authMiddleware)
 
 		// The plan-gating chain runs BETWEEN the auth middleware and the action's
 		// own middlewares/handler, so plan resolution sees the authenticated user
@@ -98,7 +99,7 @@ func (srg *SwaggerRouteGenerator) registerActionRoutes(router *gin.RouterGroup,
 func (srg *SwaggerRouteGenerator) registerEntityRoutes(router *gin.RouterGroup, entityName string, config *entityManagementInterfaces.EntitySwaggerConfig, authMiddleware gin.HandlerFunc, identifyMiddleware gin.HandlerFunc) {
 	// Déterminer le path de base (pluriel, en minuscules)
 	basePath := ems.ResourceBasePath(entityName)
-	entityGroup := router.Group(basePath)
+	entityGroup := router.Group(basePath, observability.InstrumentRoute(entityName))
 
 	log.Printf("📚 Registering documented routes for %s at %s", entityName, basePath)
 
@@ -15,15 +15,19 @@ import (
 	"soli/formations/src/auth/casdoor"
 	authModels "soli/formations/src/auth/models"
 	scenarioServices "soli/formations/src/scenarios/services"
+	certificateModels "soli/formations/src/certificates/models"
 	configModels "soli/formations/src/configuration/models"
 	courseModels "soli/formations/src/courses/models"
 	emailModels "soli/formations/src/email/models"
 	groupModels "soli/formations/src/groups/models"
+	learningPathModels "soli/formations/src/learningPaths/models"
+	ltiModels "soli/formations/src/lti/models"
 	organizationModels "soli/formations/src/organizations/models"
 	paymentModels "soli/formations/src/payment/models"
 	paymentServices "soli/formations/src/payment/services"
 	scenarioModels "soli/formations/src/scenarios/models"
 	terminalModels "soli/formations/src/terminalTrainer/models"
+	webhookModels "soli/formations/src/webhooks/models"
 	testtools "soli/formations/tests/testTools"
 )
 
@@ -144,6 +148,10 @@ func AutoMigrateAll(db *gorm.DB) {
 	// Terminal entities
 	db.AutoMigrate(&terminalModels.Terminal{})
 	db.AutoMigrate(&terminalModels.UserTerminalKey{})
+	db.AutoMigrate(&terminalModels.TerminalFleet{})
+	db.AutoMigrate(&terminalModels.TerminalFleetMember{})
+	db.AutoMigrate(&terminalModels.TerminalSnapshot{})
+	db.AutoMigrate(&terminalModels.TerminalTeamMember{})
 	// MR !239 (SSOT consolidation): the legacy `status` column on `terminals`
 	// was a parallel field that drifted from `state` and caused zombie-resume
 	// and dashboard banner bugs. The model field is gone; this drops the
@@ -166,12 +174,23 @@ func AutoMigrateAll(db *gorm.DB) {
 	db.AutoMigrate(&scenarioModels.ScenarioSession{})
 	db.AutoMigrate(&scenarioModels.ScenarioStepProgress{})
 	db.AutoMigrate(&scenarioModels.ScenarioFlag{})
+	db.AutoMigrate(&scenarioModels.ScenarioFlagAttempt{})
 	db.AutoMigrate(&scenarioModels.ScenarioAssignment{})
+	db.AutoMigrate(&scenarioModels.ScenarioAssignmentExtension{})
+	db.AutoMigrate(&scenarioModels.ScenarioAssignmentReminder{})
 	db.AutoMigrate(&scenarioModels.ScenarioInstanceType{})
+	db.AutoMigrate(&scenarioModels.ScenarioNode{})
+	db.AutoMigrate(&scenarioModels.ScenarioSessionNode{})
 	db.AutoMigrate(&scenarioModels.ScenarioStepQuestion{})
+	db.AutoMigrate(&scenarioModels.ScenarioStepTransition{})
+	db.AutoMigrate(&scenarioModels.ScenarioRevision{})
+	db.AutoMigrate(&scenarioModels.ScenarioGitSource{})
+	db.AutoMigrate(&scenarioModels.ScenarioGitSync{})
+	db.AutoMigrate(&scenarioModels.ScenarioReview{})
 
 	// Scenario indexes
 	scenarioModels.MigrateUniqueActiveSessionIndex(db)
+	scenarioModels.MigrateCatalogSearchIndex(db)
 
 	// Payment entities
 	db.AutoMigrate(&paymentModels.SubscriptionPlan{})
@@ -222,6 +241,26 @@ func AutoMigrateAll(db *gorm.DB) {
 	// Audit logging entities (compliance & security)
 	db.AutoMigrate(&auditModels.AuditLog{})
 
+	// LTI 1.3 tool provider entities
+	db.AutoMigrate(&ltiModels.LtiPlatform{})
+	db.AutoMigrate(&ltiModels.LtiToolKey{})
+	db.AutoMigrate(&ltiModels.LtiLaunchState{})
+	db.AutoMigrate(&ltiModels.LtiUserLink{})
+	db.AutoMigrate(&ltiModels.LtiContextLink{})
+	db.AutoMigrate(&ltiModels.LtiResourceLink{})
+	db.AutoMigrate(&ltiModels.LtiGradeLink{})
+	db.AutoMigrate(&ltiModels.LtiDeepLinkRequest{})
+
+	// Outbound organization webhooks
+	db.AutoMigrate(&webhookModels.WebhookSubscription{})
+	db.AutoMigrate(&webhookModels.WebhookDelivery{})
+	db.AutoMigrate(&webhookModels.WebhookDeliveryAttempt{})
+	db.AutoMigrate(&certificateModels.Certificate{})
+	db.AutoMigrate(&certificateModels.CertificateSigningKey{})
+	db.AutoMigrate(&learningPathModels.LearningPath{})
+	db.AutoMigrate(&learningPathModels.LearningPathItem{})
+	db.AutoMigrate(&learningPathModels.ChapterCompletion{})
+
 	// Harmonize group roles: admin → manager, assistant → member
 	migrateGroupRoles(db)
 
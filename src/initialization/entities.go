@@ -51,11 +51,13 @@ func RegisterEntities() {
 	scenarioRegistration.RegisterScenarioStep(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioStepHint(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioStepQuestion(ems.GlobalEntityRegistrationService)
+	scenarioRegistration.RegisterScenarioStepTransition(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioSession(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioStepProgress(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioFlag(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioAssignment(ems.GlobalEntityRegistrationService)
 	scenarioRegistration.RegisterScenarioInstanceType(ems.GlobalEntityRegistrationService)
+	scenarioRegistration.RegisterScenarioNode(ems.GlobalEntityRegistrationService)
 
 	configRegistration.RegisterFeature(ems.GlobalEntityRegistrationService)
 
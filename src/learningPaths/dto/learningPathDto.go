@@ -0,0 +1,97 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Progress statuses of a learning path item.
+const (
+	ItemStatusCompleted  = "completed"
+	ItemStatusInProgress = "in_progress" // A session is running, or every attempt fell short of the grade
+	ItemStatusAvailable  = "available"   // Unlocked, not started
+	ItemStatusLocked     = "locked"      // An earlier item is not completed yet
+)
+
+// Statuses of an assignment with a deadline, for the learner.
+const (
+	DeadlineStatusNotStarted = "not_started"
+	DeadlineStatusInProgress = "in_progress"
+)
+
+// LearningPathItemInput is one item of a learning path, in path order.
+type LearningPathItemInput struct {
+	Kind       string     `json:"kind" binding:"required,oneof=scenario chapter"`
+	ScenarioID *uuid.UUID `json:"scenario_id"`
+	ChapterID  *uuid.UUID `json:"chapter_id"`
+	CourseID   *uuid.UUID `json:"course_id"`
+	MinGrade   *float64   `json:"min_grade" binding:"omitempty,min=0,max=100"`
+}
+
+// LearningPathInput creates a learning path, or replaces one entirely.
+type LearningPathInput struct {
+	Title       string                  `json:"title" binding:"required,max=255"`
+	Description string                  `json:"description"`
+	GroupID     *uuid.UUID              `json:"group_id"`
+	Items       []LearningPathItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// LearningPathItemProgress is where a learner stands on one path item.
+type LearningPathItemProgress struct {
+	ItemID     uuid.UUID  `json:"item_id"`
+	Position   int        `json:"position"`
+	Kind       string     `json:"kind"`
+	ScenarioID *uuid.UUID `json:"scenario_id,omitempty"`
+	ChapterID  *uuid.UUID `json:"chapter_id,omitempty"`
+	CourseID   *uuid.UUID `json:"course_id,omitempty"`
+	Title      string     `json:"title"`
+	MinGrade   *float64   `json:"min_grade,omitempty"`
+	Status     string     `json:"status"`
+	// BestGrade, Attempts and ActiveSessionID describe the learner's
+	// sessions of a scenario item.
+	BestGrade       *float64   `json:"best_grade,omitempty"`
+	Attempts        int        `json:"attempts"`
+	ActiveSessionID *uuid.UUID `json:"active_session_id,omitempty"`
+	// TimeSpentSeconds adds up the learner's sessions, running ones until now.
+	TimeSpentSeconds int64      `json:"time_spent_seconds"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+}
+
+// LearningPathProgress is where a learner stands on a learning path.
+type LearningPathProgress struct {
+	PathID           uuid.UUID                  `json:"path_id"`
+	Title            string                     `json:"title"`
+	Description      string                     `json:"description,omitempty"`
+	OrganizationID   uuid.UUID                  `json:"organization_id"`
+	GroupID          *uuid.UUID                 `json:"group_id,omitempty"`
+	UserID           string                     `json:"user_id"`
+	CompletedItems   int                        `json:"completed_items"`
+	TotalItems       int                        `json:"total_items"`
+	Percent          float64                    `json:"percent"`
+	TimeSpentSeconds int64                      `json:"time_spent_seconds"`
+	NextItemID       *uuid.UUID                 `json:"next_item_id,omitempty"` // The first item left to complete
+	Items            []LearningPathItemProgress `json:"items"`
+}
+
+// UpcomingDeadline is an assignment of the learner's with a deadline they
+// have not completed yet.
+type UpcomingDeadline struct {
+	AssignmentID   uuid.UUID  `json:"assignment_id"`
+	ScenarioID     uuid.UUID  `json:"scenario_id"`
+	ScenarioTitle  string     `json:"scenario_title"`
+	GroupID        *uuid.UUID `json:"group_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	ExamMode       bool       `json:"exam_mode,omitempty"`
+	StartDate      *time.Time `json:"start_date,omitempty"`
+	Deadline       time.Time  `json:"deadline"`
+	Status         string     `json:"status"`
+	Overdue        bool       `json:"overdue"`
+}
+
+// LearnerDashboard gathers what a learner has to do: the learning paths they
+// follow and their next deadlines.
+type LearnerDashboard struct {
+	Paths     []LearningPathProgress `json:"paths"`
+	Deadlines []UpcomingDeadline     `json:"deadlines"`
+}
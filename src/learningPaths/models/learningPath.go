@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+)
+
+// Learning path item kinds.
+const (
+	ItemKindScenario = "scenario" // A hands-on scenario, completed by a session
+	ItemKindChapter  = "chapter"  // A course chapter, marked as read by the learner
+)
+
+// LearningPath is an ordered sequence of scenarios and course chapters an
+// organization sets its learners. Every item is a prerequisite of the items
+// after it: an item unlocks once all earlier ones are completed.
+//
+// A path with a GroupID is followed by the members of that class group; one
+// without is followed by every member of the organization.
+type LearningPath struct {
+	entityManagementModels.BaseModel
+	OrganizationID uuid.UUID          `gorm:"type:uuid;not null;index" json:"organization_id"`
+	GroupID        *uuid.UUID         `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	Title          string             `gorm:"type:varchar(255);not null" json:"title"`
+	Description    string             `gorm:"type:text" json:"description,omitempty"`
+	CreatedByID    string             `gorm:"type:varchar(255);not null" json:"created_by_id"`
+	Items          []LearningPathItem `gorm:"foreignKey:PathID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"items,omitempty"`
+}
+
+func (LearningPath) TableName() string {
+	return "learning_paths"
+}
+
+// LearningPathItem is one step of a learning path: a scenario or a course
+// chapter, depending on Kind.
+type LearningPathItem struct {
+	entityManagementModels.BaseModel
+	PathID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"path_id"`
+	Position   int        `gorm:"not null" json:"position"`
+	Kind       string     `gorm:"type:varchar(20);not null" json:"kind"`
+	ScenarioID *uuid.UUID `gorm:"type:uuid;index" json:"scenario_id,omitempty"`
+	ChapterID  *uuid.UUID `gorm:"type:uuid;index" json:"chapter_id,omitempty"`
+	// CourseID is the course the chapter is read in, so that learners can be
+	// sent to it. Optional: a chapter may belong to several courses.
+	CourseID *uuid.UUID `gorm:"type:uuid" json:"course_id,omitempty"`
+	// MinGrade is the grade, out of 100, a scenario session must reach for
+	// the item to count as completed. Nil: any completed session does.
+	MinGrade *float64 `gorm:"type:decimal(5,2)" json:"min_grade,omitempty"`
+}
+
+func (LearningPathItem) TableName() string {
+	return "learning_path_items"
+}
+
+// ChapterCompletion records that a learner read a course chapter. It is kept
+// per chapter rather than per path item, so a chapter read once counts in
+// every path it appears in.
+type ChapterCompletion struct {
+	entityManagementModels.BaseModel
+	UserID      string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_chapter_completion_user" json:"user_id"`
+	ChapterID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_chapter_completion_user" json:"chapter_id"`
+	CompletedAt time.Time `gorm:"not null" json:"completed_at"`
+}
+
+func (ChapterCompletion) TableName() string {
+	return "chapter_completions"
+}
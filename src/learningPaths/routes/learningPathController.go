@@ -0,0 +1,337 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/learningPaths/dto"
+	"soli/formations/src/learningPaths/services"
+)
+
+// LearningPathController serves learning paths: their definition to the
+// organizations that build them, and progress and deadlines to learners.
+type LearningPathController struct {
+	service *services.LearningPathService
+}
+
+// NewLearningPathController creates a new learning path controller.
+func NewLearningPathController(service *services.LearningPathService) *LearningPathController {
+	return &LearningPathController{service: service}
+}
+
+// ListOrganizationPaths godoc
+// @Summary List an organization's learning paths
+// @Description Returns the organization's learning paths with their items, by title.
+// @Tags learning-paths
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} models.LearningPath
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /organizations/{id}/learning-paths [get]
+// @Security BearerAuth
+func (lc *LearningPathController) ListOrganizationPaths(c *gin.Context) {
+	orgID, ok := parseID(c, "id", "invalid organization ID")
+	if !ok {
+		return
+	}
+	paths, err := lc.service.ListForOrganization(orgID)
+	if err != nil {
+		writeLearningPathError(c, err, "failed to list learning paths")
+		return
+	}
+	c.JSON(http.StatusOK, paths)
+}
+
+// CreatePath godoc
+// @Summary Create a learning path
+// @Description Creates an ordered learning path of scenarios and course chapters in the organization. Each item unlocks once the earlier ones are completed; a scenario item with a min_grade is completed by a session reaching that grade.
+// @Tags learning-paths
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param body body dto.LearningPathInput true "Learning path"
+// @Success 201 {object} models.LearningPath
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /organizations/{id}/learning-paths [post]
+// @Security BearerAuth
+func (lc *LearningPathController) CreatePath(c *gin.Context) {
+	orgID, ok := parseID(c, "id", "invalid organization ID")
+	if !ok {
+		return
+	}
+	var input dto.LearningPathInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	path, err := lc.service.Create(orgID, c.GetString("userId"), input)
+	if err != nil {
+		writeLearningPathError(c, err, "failed to create learning path")
+		return
+	}
+	c.JSON(http.StatusCreated, path)
+}
+
+// GetPath godoc
+// @Summary Get a learning path
+// @Description Returns a learning path with its items, to its learners and managers.
+// @Tags learning-paths
+// @Produce json
+// @Param id path string true "Learning path ID"
+// @Success 200 {object} models.LearningPath
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /learning-paths/{id} [get]
+// @Security BearerAuth
+func (lc *LearningPathController) GetPath(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid learning path ID")
+	if !ok {
+		return
+	}
+	path, err := lc.service.Get(id, c.GetString("userId"), isAdmin(c))
+	if err != nil {
+		writeLearningPathError(c, err, "failed to load learning path")
+		return
+	}
+	c.JSON(http.StatusOK, path)
+}
+
+// UpdatePath godoc
+// @Summary Replace a learning path
+// @Description Replaces a learning path's title, description, group and items. Learners keep the progress they made.
+// @Tags learning-paths
+// @Accept json
+// @Produce json
+// @Param id path string true "Learning path ID"
+// @Param body body dto.LearningPathInput true "Learning path"
+// @Success 200 {object} models.LearningPath
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /learning-paths/{id} [put]
+// @Security BearerAuth
+func (lc *LearningPathController) UpdatePath(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid learning path ID")
+	if !ok {
+		return
+	}
+	var input dto.LearningPathInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	path, err := lc.service.Update(id, c.GetString("userId"), isAdmin(c), input)
+	if err != nil {
+		writeLearningPathError(c, err, "failed to update learning path")
+		return
+	}
+	c.JSON(http.StatusOK, path)
+}
+
+// DeletePath godoc
+// @Summary Delete a learning path
+// @Tags learning-paths
+// @Param id path string true "Learning path ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /learning-paths/{id} [delete]
+// @Security BearerAuth
+func (lc *LearningPathController) DeletePath(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid learning path ID")
+	if !ok {
+		return
+	}
+	if err := lc.service.Delete(id, c.GetString("userId"), isAdmin(c)); err != nil {
+		writeLearningPathError(c, err, "failed to delete learning path")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListMyPaths godoc
+// @Summary List my learning paths
+// @Description Returns the caller's progress on every learning path they follow, as a member of its organization or class group.
+// @Tags learning-paths
+// @Produce json
+// @Success 200 {array} dto.LearningPathProgress
+// @Failure 500 {object} map[string]string
+// @Router /learning-paths [get]
+// @Security BearerAuth
+func (lc *LearningPathController) ListMyPaths(c *gin.Context) {
+	paths, err := lc.service.LearnerProgress(c.GetString("userId"))
+	if err != nil {
+		writeLearningPathError(c, err, "failed to list learning paths")
+		return
+	}
+	c.JSON(http.StatusOK, paths)
+}
+
+// GetMyProgress godoc
+// @Summary Get my progress on a learning path
+// @Description Returns the status of each item of the path for the caller — completed, in progress, available or locked — with their best grade, attempts and time spent.
+// @Tags learning-paths
+// @Produce json
+// @Param id path string true "Learning path ID"
+// @Success 200 {object} dto.LearningPathProgress
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /learning-paths/{id}/progress [get]
+// @Security BearerAuth
+func (lc *LearningPathController) GetMyProgress(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid learning path ID")
+	if !ok {
+		return
+	}
+	userID := c.GetString("userId")
+	progress, err := lc.service.Progress(id, userID, userID, isAdmin(c))
+	if err != nil {
+		writeLearningPathError(c, err, "failed to compute progress")
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// GetLearnerProgress godoc
+// @Summary Get a learner's progress on a learning path
+// @Description Returns a learner's progress on the path, to the managers of its organization or group.
+// @Tags learning-paths
+// @Produce json
+// @Param id path string true "Learning path ID"
+// @Param userId path string true "Learner's user ID"
+// @Success 200 {object} dto.LearningPathProgress
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /learning-paths/{id}/learners/{userId}/progress [get]
+// @Security BearerAuth
+func (lc *LearningPathController) GetLearnerProgress(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid learning path ID")
+	if !ok {
+		return
+	}
+	progress, err := lc.service.Progress(id, c.Param("userId"), c.GetString("userId"), isAdmin(c))
+	if err != nil {
+		writeLearningPathError(c, err, "failed to compute progress")
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// CompleteChapter godoc
+// @Summary Mark a chapter of a learning path as read
+// @Description Records that the caller read the chapter of a chapter item. The item must be unlocked.
+// @Tags learning-paths
+// @Produce json
+// @Param id path string true "Learning path ID"
+// @Param itemId path string true "Item ID"
+// @Success 200 {object} dto.LearningPathProgress
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /learning-paths/{id}/items/{itemId}/complete [post]
+// @Security BearerAuth
+func (lc *LearningPathController) CompleteChapter(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid learning path ID")
+	if !ok {
+		return
+	}
+	itemID, ok := parseID(c, "itemId", "invalid item ID")
+	if !ok {
+		return
+	}
+	progress, err := lc.service.CompleteChapter(id, itemID, c.GetString("userId"))
+	if err != nil {
+		writeLearningPathError(c, err, "failed to complete chapter")
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// GetMyDashboard godoc
+// @Summary Get my learner dashboard
+// @Description Returns the caller's progress on the learning paths they follow and the deadlines of their assignments over the next 30 days, overdue ones included.
+// @Tags learning-paths
+// @Produce json
+// @Success 200 {object} dto.LearnerDashboard
+// @Failure 500 {object} map[string]string
+// @Router /learner/dashboard [get]
+// @Security BearerAuth
+func (lc *LearningPathController) GetMyDashboard(c *gin.Context) {
+	dashboard, err := lc.service.Dashboard(c.GetString("userId"))
+	if err != nil {
+		writeLearningPathError(c, err, "failed to load dashboard")
+		return
+	}
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// ListMyDeadlines godoc
+// @Summary List my upcoming deadlines
+// @Description Returns the caller's assignments not completed yet whose deadline falls within the window, soonest first. Deadlines missed within the window are flagged overdue.
+// @Tags learning-paths
+// @Produce json
+// @Param days query int false "Days ahead and back to look (default 30, at most 365)"
+// @Success 200 {array} dto.UpcomingDeadline
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /learner/deadlines [get]
+// @Security BearerAuth
+func (lc *LearningPathController) ListMyDeadlines(c *gin.Context) {
+	days := 0
+	if raw := c.Query("days"); raw != "" {
+		var err error
+		days, err = strconv.Atoi(raw)
+		if err != nil || days < 1 || days > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+	}
+	deadlines, err := lc.service.UpcomingDeadlines(c.GetString("userId"), days)
+	if err != nil {
+		writeLearningPathError(c, err, "failed to list deadlines")
+		return
+	}
+	c.JSON(http.StatusOK, deadlines)
+}
+
+func isAdmin(c *gin.Context) bool {
+	return access.IsAdmin(c.GetStringSlice("userRoles"))
+}
+
+func parseID(c *gin.Context, param, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeLearningPathError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPathNotFound), errors.Is(err, services.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPathAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPath), errors.Is(err, services.ErrItemNotChapter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrItemLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		slog.Error(message, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	auth "soli/formations/src/auth"
+	"soli/formations/src/learningPaths/services"
+)
+
+// RegisterRoutes wires the learning path and learner dashboard endpoints.
+func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	mw := auth.NewAuthMiddleware(db)
+	controller := NewLearningPathController(services.NewLearningPathService(db))
+
+	paths := router.Group("/learning-paths", mw.AuthManagement())
+	paths.GET("", controller.ListMyPaths)
+	paths.GET("/:id", controller.GetPath)
+	paths.PUT("/:id", controller.UpdatePath)
+	paths.DELETE("/:id", controller.DeletePath)
+	paths.GET("/:id/progress", controller.GetMyProgress)
+	paths.GET("/:id/learners/:userId/progress", controller.GetLearnerProgress)
+	paths.POST("/:id/items/:itemId/complete", controller.CompleteChapter)
+
+	learner := router.Group("/learner", mw.AuthManagement())
+	learner.GET("/dashboard", controller.GetMyDashboard)
+	learner.GET("/deadlines", controller.ListMyDeadlines)
+
+	router.GET("/organizations/:id/learning-paths", mw.AuthManagement(), controller.ListOrganizationPaths)
+	router.POST("/organizations/:id/learning-paths", mw.AuthManagement(), controller.CreatePath)
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"log"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/interfaces"
+)
+
+// RegisterPermissions registers the Casbin policies and RouteRegistry entries
+// of the learning path and learner dashboard endpoints.
+func RegisterPermissions(enforcer interfaces.EnforcerInterface) {
+	log.Println("=== Registering learning path permissions ===")
+
+	access.RegisterEnforced(enforcer, "Learning paths",
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "List the caller's learning paths with their progress",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths/:id", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "View a learning path (its learners and managers)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths/:id", Method: "PUT",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Replace a learning path (manager of its organization or group)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths/:id", Method: "DELETE",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Delete a learning path (manager of its organization or group)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths/:id/progress", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "The caller's progress on a learning path they follow",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths/:id/learners/:userId/progress", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "A learner's progress on a learning path (manager of its organization or group)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learning-paths/:id/items/:itemId/complete", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Mark a chapter of a learning path as read",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learner/dashboard", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "The caller's learning paths and upcoming deadlines",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/learner/deadlines", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "The caller's upcoming assignment deadlines",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/learning-paths", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "List an organization's learning paths",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/learning-paths", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "Create a learning path in an organization",
+		},
+	)
+
+	log.Println("=== Learning path permissions registered ===")
+}
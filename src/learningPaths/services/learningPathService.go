@@ -0,0 +1,679 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	courseModels "soli/formations/src/courses/models"
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/learningPaths/dto"
+	"soli/formations/src/learningPaths/models"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+var (
+	ErrPathNotFound     = errors.New("learning path not found")
+	ErrPathAccessDenied = errors.New("not allowed to access this learning path")
+	ErrInvalidPath      = errors.New("invalid learning path")
+	ErrItemNotFound     = errors.New("learning path item not found")
+	ErrItemNotChapter   = errors.New("only chapter items are marked as completed by hand")
+	ErrItemLocked       = errors.New("complete the earlier items of the path first")
+)
+
+// DefaultDeadlineWindowDays is how far ahead, and how far back for overdue
+// ones, deadlines are listed when the caller does not say.
+const DefaultDeadlineWindowDays = 30
+
+// LearningPathService manages learning paths and computes where learners
+// stand on them.
+type LearningPathService struct {
+	db      *gorm.DB
+	members *access.GormMembershipChecker
+}
+
+// NewLearningPathService creates a learning path service.
+func NewLearningPathService(db *gorm.DB) *LearningPathService {
+	return &LearningPathService{db: db, members: access.NewGormMembershipChecker(db)}
+}
+
+// Create creates a learning path in an organization.
+func (s *LearningPathService) Create(orgID uuid.UUID, userID string, input dto.LearningPathInput) (*models.LearningPath, error) {
+	if err := s.validate(orgID, input); err != nil {
+		return nil, err
+	}
+	path := &models.LearningPath{
+		OrganizationID: orgID,
+		GroupID:        input.GroupID,
+		Title:          input.Title,
+		Description:    input.Description,
+		CreatedByID:    userID,
+		Items:          buildItems(input.Items),
+	}
+	path.OwnerIDs = append(path.OwnerIDs, userID)
+	if err := s.db.Create(path).Error; err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// Update replaces a learning path's title, group and items. Chapter
+// completions are kept: they belong to the chapters, not to the items.
+func (s *LearningPathService) Update(id uuid.UUID, userID string, isAdmin bool, input dto.LearningPathInput) (*models.LearningPath, error) {
+	path, err := s.loadManageable(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(path.OrganizationID, input); err != nil {
+		return nil, err
+	}
+
+	items := buildItems(input.Items)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("path_id = ?", path.ID).Delete(&models.LearningPathItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(path).Select("group_id", "title", "description").Updates(map[string]any{
+			"group_id":    input.GroupID,
+			"title":       input.Title,
+			"description": input.Description,
+		}).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].PathID = path.ID
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.load(id)
+}
+
+// Delete deletes a learning path.
+func (s *LearningPathService) Delete(id uuid.UUID, userID string, isAdmin bool) error {
+	path, err := s.loadManageable(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("path_id = ?", path.ID).Delete(&models.LearningPathItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(path).Error
+	})
+}
+
+// Get returns a learning path to someone who follows or manages it.
+func (s *LearningPathService) Get(id uuid.UUID, userID string, isAdmin bool) (*models.LearningPath, error) {
+	path, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	follows, err := s.follows(path, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !follows {
+		manages, err := s.canManage(path, userID, isAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if !manages {
+			return nil, ErrPathAccessDenied
+		}
+	}
+	return path, nil
+}
+
+// ListForOrganization returns an organization's learning paths, by title.
+func (s *LearningPathService) ListForOrganization(orgID uuid.UUID) ([]models.LearningPath, error) {
+	var paths []models.LearningPath
+	err := s.db.Preload("Items", orderedItems).
+		Where("organization_id = ?", orgID).
+		Order("title ASC").Find(&paths).Error
+	return paths, err
+}
+
+// ListForLearner returns the learning paths a user follows: those of their
+// organizations without a group, and those of their class groups.
+func (s *LearningPathService) ListForLearner(userID string) ([]models.LearningPath, error) {
+	orgIDs := s.db.Model(&orgModels.OrganizationMember{}).Select("organization_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+	groupIDs := s.db.Model(&groupModels.GroupMember{}).Select("group_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	var paths []models.LearningPath
+	err := s.db.Preload("Items", orderedItems).
+		Where("(group_id IS NULL AND organization_id IN (?)) OR group_id IN (?)", orgIDs, groupIDs).
+		Order("title ASC").Find(&paths).Error
+	return paths, err
+}
+
+// Progress returns where a learner stands on a learning path. Learners see
+// their own progress on the paths they follow; the path's managers see any
+// learner's.
+func (s *LearningPathService) Progress(id uuid.UUID, learnerID, viewerID string, isAdmin bool) (*dto.LearningPathProgress, error) {
+	path, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if learnerID == viewerID {
+		follows, err := s.follows(path, learnerID)
+		if err != nil {
+			return nil, err
+		}
+		if !follows {
+			return nil, ErrPathAccessDenied
+		}
+	} else {
+		manages, err := s.canManage(path, viewerID, isAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if !manages {
+			return nil, ErrPathAccessDenied
+		}
+	}
+	return s.progress(path, learnerID, time.Now())
+}
+
+// CompleteChapter marks a chapter item of a path as read by the learner. The
+// chapter must be unlocked: every earlier item completed.
+func (s *LearningPathService) CompleteChapter(id, itemID uuid.UUID, userID string) (*dto.LearningPathProgress, error) {
+	path, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	follows, err := s.follows(path, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !follows {
+		return nil, ErrPathAccessDenied
+	}
+
+	now := time.Now()
+	progress, err := s.progress(path, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	var item *dto.LearningPathItemProgress
+	for i := range progress.Items {
+		if progress.Items[i].ItemID == itemID {
+			item = &progress.Items[i]
+		}
+	}
+	switch {
+	case item == nil:
+		return nil, ErrItemNotFound
+	case item.Kind != models.ItemKindChapter:
+		return nil, ErrItemNotChapter
+	case item.Status == dto.ItemStatusCompleted:
+		return progress, nil
+	case item.Status == dto.ItemStatusLocked:
+		return nil, ErrItemLocked
+	}
+
+	completion := &models.ChapterCompletion{UserID: userID, ChapterID: *item.ChapterID, CompletedAt: now}
+	completion.OwnerIDs = append(completion.OwnerIDs, userID)
+	if err := s.db.Create(completion).Error; err != nil {
+		return nil, err
+	}
+	return s.progress(path, userID, now)
+}
+
+// LearnerProgress returns a learner's progress on every path they follow.
+func (s *LearningPathService) LearnerProgress(userID string) ([]dto.LearningPathProgress, error) {
+	return s.learnerProgress(userID, time.Now())
+}
+
+// Dashboard returns a learner's progress on every path they follow and their
+// upcoming deadlines.
+func (s *LearningPathService) Dashboard(userID string) (*dto.LearnerDashboard, error) {
+	now := time.Now()
+	paths, err := s.learnerProgress(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	deadlines, err := s.upcomingDeadlines(userID, DefaultDeadlineWindowDays, now)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LearnerDashboard{Paths: paths, Deadlines: deadlines}, nil
+}
+
+func (s *LearningPathService) learnerProgress(userID string, now time.Time) ([]dto.LearningPathProgress, error) {
+	paths, err := s.ListForLearner(userID)
+	if err != nil {
+		return nil, err
+	}
+	progress := make([]dto.LearningPathProgress, 0, len(paths))
+	for i := range paths {
+		p, err := s.progress(&paths[i], userID, now)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, *p)
+	}
+	return progress, nil
+}
+
+// UpcomingDeadlines lists the active assignments of a learner's groups and
+// organizations due within the given number of days that the learner has not
+// completed, soonest first, at the learner's own deadline if they were
+// granted an extension. Deadlines missed as many days back are listed too,
+// flagged overdue.
+func (s *LearningPathService) UpcomingDeadlines(userID string, days int) ([]dto.UpcomingDeadline, error) {
+	if days <= 0 {
+		days = DefaultDeadlineWindowDays
+	}
+	return s.upcomingDeadlines(userID, days, time.Now())
+}
+
+func (s *LearningPathService) upcomingDeadlines(userID string, days int, now time.Time) ([]dto.UpcomingDeadline, error) {
+	window := time.Duration(days) * 24 * time.Hour
+	groupIDs := s.db.Model(&groupModels.GroupMember{}).Select("group_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+	orgIDs := s.db.Model(&orgModels.OrganizationMember{}).Select("organization_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	extendedIDs := s.db.Model(&scenarioModels.ScenarioAssignmentExtension{}).Select("assignment_id").
+		Where("user_id = ?", userID)
+
+	var candidates []scenarioModels.ScenarioAssignment
+	if err := s.db.Preload("Scenario").
+		Where("is_active = ?", true).
+		Where("(deadline IS NOT NULL AND deadline BETWEEN ? AND ?) OR id IN (?)", now.Add(-window), now.Add(window), extendedIDs).
+		Where("(scope = 'group' AND group_id IN (?)) OR (scope = 'org' AND organization_id IN (?))", groupIDs, orgIDs).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []dto.UpcomingDeadline{}, nil
+	}
+
+	// The learner's extensions replace the deadlines of their assignments.
+	var extensions []scenarioModels.ScenarioAssignmentExtension
+	if err := s.db.Where("user_id = ?", userID).Find(&extensions).Error; err != nil {
+		return nil, err
+	}
+	extended := make(map[uuid.UUID]time.Time, len(extensions))
+	for _, e := range extensions {
+		extended[e.AssignmentID] = e.Deadline
+	}
+	var assignments []scenarioModels.ScenarioAssignment
+	for _, a := range candidates {
+		if deadline, ok := extended[a.ID]; ok {
+			a.Deadline = &deadline
+		}
+		if a.Deadline != nil && !a.Deadline.Before(now.Add(-window)) && !a.Deadline.After(now.Add(window)) {
+			assignments = append(assignments, a)
+		}
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].Deadline.Before(*assignments[j].Deadline)
+	})
+	if len(assignments) == 0 {
+		return []dto.UpcomingDeadline{}, nil
+	}
+
+	scenarioIDs := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		scenarioIDs = append(scenarioIDs, a.ScenarioID)
+	}
+	sessions, err := s.learnerSessions(userID, scenarioIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	deadlines := []dto.UpcomingDeadline{}
+	seen := map[uuid.UUID]bool{}
+	for _, a := range assignments {
+		// A scenario assigned to both a group and the organization is listed
+		// once, at its earliest deadline.
+		if seen[a.ScenarioID] {
+			continue
+		}
+		status := dto.DeadlineStatusNotStarted
+		completed := false
+		for _, session := range sessions[a.ScenarioID] {
+			switch session.Status {
+			case "completed":
+				completed = true
+			case "active", "provisioning":
+				status = dto.DeadlineStatusInProgress
+			}
+		}
+		if completed {
+			continue
+		}
+		seen[a.ScenarioID] = true
+		deadlines = append(deadlines, dto.UpcomingDeadline{
+			AssignmentID:   a.ID,
+			ScenarioID:     a.ScenarioID,
+			ScenarioTitle:  a.Scenario.Title,
+			GroupID:        a.GroupID,
+			OrganizationID: a.OrganizationID,
+			ExamMode:       a.ExamMode,
+			StartDate:      a.StartDate,
+			Deadline:       *a.Deadline,
+			Status:         status,
+			Overdue:        a.Deadline.Before(now),
+		})
+	}
+	return deadlines, nil
+}
+
+// progress computes a learner's status on every item of a path. Items are
+// completed by a finished session reaching their grade, or for chapters by
+// the learner marking them as read; an item is locked while any earlier one
+// is not completed. Work done before the path existed counts.
+func (s *LearningPathService) progress(path *models.LearningPath, userID string, now time.Time) (*dto.LearningPathProgress, error) {
+	var scenarioIDs, chapterIDs []uuid.UUID
+	for _, item := range path.Items {
+		if item.ScenarioID != nil {
+			scenarioIDs = append(scenarioIDs, *item.ScenarioID)
+		}
+		if item.ChapterID != nil {
+			chapterIDs = append(chapterIDs, *item.ChapterID)
+		}
+	}
+
+	scenarioTitles := map[uuid.UUID]string{}
+	if len(scenarioIDs) > 0 {
+		var scenarios []scenarioModels.Scenario
+		if err := s.db.Select("id", "title").Where("id IN ?", scenarioIDs).Find(&scenarios).Error; err != nil {
+			return nil, err
+		}
+		for _, sc := range scenarios {
+			scenarioTitles[sc.ID] = sc.Title
+		}
+	}
+	sessions, err := s.learnerSessions(userID, scenarioIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	chapterTitles := map[uuid.UUID]string{}
+	completions := map[uuid.UUID]time.Time{}
+	if len(chapterIDs) > 0 {
+		var chapters []courseModels.Chapter
+		if err := s.db.Select("id", "title").Where("id IN ?", chapterIDs).Find(&chapters).Error; err != nil {
+			return nil, err
+		}
+		for _, ch := range chapters {
+			chapterTitles[ch.ID] = ch.Title
+		}
+		var done []models.ChapterCompletion
+		if err := s.db.Where("user_id = ? AND chapter_id IN ?", userID, chapterIDs).Find(&done).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range done {
+			completions[c.ChapterID] = c.CompletedAt
+		}
+	}
+
+	progress := &dto.LearningPathProgress{
+		PathID:         path.ID,
+		Title:          path.Title,
+		Description:    path.Description,
+		OrganizationID: path.OrganizationID,
+		GroupID:        path.GroupID,
+		UserID:         userID,
+		TotalItems:     len(path.Items),
+		Items:          make([]dto.LearningPathItemProgress, 0, len(path.Items)),
+	}
+	unlocked := true
+	for _, item := range path.Items {
+		p := dto.LearningPathItemProgress{
+			ItemID:     item.ID,
+			Position:   item.Position,
+			Kind:       item.Kind,
+			ScenarioID: item.ScenarioID,
+			ChapterID:  item.ChapterID,
+			CourseID:   item.CourseID,
+			MinGrade:   item.MinGrade,
+		}
+		started := false
+		switch item.Kind {
+		case models.ItemKindScenario:
+			p.Title = scenarioTitles[*item.ScenarioID]
+			started = scenarioProgress(&p, sessions[*item.ScenarioID], now)
+		case models.ItemKindChapter:
+			p.Title = chapterTitles[*item.ChapterID]
+			if at, ok := completions[*item.ChapterID]; ok {
+				p.CompletedAt = &at
+			}
+		}
+
+		switch {
+		case p.CompletedAt != nil:
+			p.Status = dto.ItemStatusCompleted
+			progress.CompletedItems++
+		case !unlocked:
+			p.Status = dto.ItemStatusLocked
+		case started:
+			p.Status = dto.ItemStatusInProgress
+		default:
+			p.Status = dto.ItemStatusAvailable
+		}
+		if p.Status != dto.ItemStatusCompleted {
+			if progress.NextItemID == nil {
+				id := item.ID
+				progress.NextItemID = &id
+			}
+			unlocked = false
+		}
+		progress.TimeSpentSeconds += p.TimeSpentSeconds
+		progress.Items = append(progress.Items, p)
+	}
+	if progress.TotalItems > 0 {
+		progress.Percent = float64(progress.CompletedItems) * 100 / float64(progress.TotalItems)
+	}
+	return progress, nil
+}
+
+// scenarioProgress fills in a scenario item from the learner's sessions of
+// the scenario and reports whether the learner has started it. The item is
+// completed by the earliest finished session reaching its grade; a session
+// without a grade only completes items without one.
+func scenarioProgress(p *dto.LearningPathItemProgress, sessions []scenarioModels.ScenarioSession, now time.Time) bool {
+	var elapsed time.Duration
+	for _, session := range sessions {
+		p.Attempts++
+		elapsed += sessionDuration(&session, now)
+		switch session.Status {
+		case "active", "provisioning":
+			id := session.ID
+			p.ActiveSessionID = &id
+		case "completed":
+			if session.Grade != nil && (p.BestGrade == nil || *session.Grade > *p.BestGrade) {
+				grade := *session.Grade
+				p.BestGrade = &grade
+			}
+			passed := p.MinGrade == nil || (session.Grade != nil && *session.Grade >= *p.MinGrade)
+			if passed && session.CompletedAt != nil && (p.CompletedAt == nil || session.CompletedAt.Before(*p.CompletedAt)) {
+				at := *session.CompletedAt
+				p.CompletedAt = &at
+			}
+		}
+	}
+	p.TimeSpentSeconds = int64(elapsed / time.Second)
+	return p.Attempts > 0
+}
+
+// sessionDuration is how long a session ran: until it completed, until now
+// if it still runs, or until it was last updated if it was abandoned.
+func sessionDuration(session *scenarioModels.ScenarioSession, now time.Time) time.Duration {
+	end := session.UpdatedAt
+	switch {
+	case session.CompletedAt != nil:
+		end = *session.CompletedAt
+	case session.Status == "active" || session.Status == "provisioning":
+		end = now
+	}
+	if end.Before(session.StartedAt) {
+		return 0
+	}
+	return end.Sub(session.StartedAt)
+}
+
+// learnerSessions returns a learner's sessions of the scenarios, previews
+// excepted, grouped by scenario in start order.
+func (s *LearningPathService) learnerSessions(userID string, scenarioIDs []uuid.UUID) (map[uuid.UUID][]scenarioModels.ScenarioSession, error) {
+	byScenario := map[uuid.UUID][]scenarioModels.ScenarioSession{}
+	if len(scenarioIDs) == 0 {
+		return byScenario, nil
+	}
+	var sessions []scenarioModels.ScenarioSession
+	if err := s.db.Where("user_id = ? AND scenario_id IN ? AND is_preview = ?", userID, scenarioIDs, false).
+		Order("started_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		byScenario[session.ScenarioID] = append(byScenario[session.ScenarioID], session)
+	}
+	return byScenario, nil
+}
+
+// validate checks a path's group belongs to the organization and its items
+// name existing content of the right kind: scenarios of the organization or
+// public ones, and chapters.
+func (s *LearningPathService) validate(orgID uuid.UUID, input dto.LearningPathInput) error {
+	if input.GroupID != nil {
+		var group groupModels.ClassGroup
+		if err := s.db.Select("id", "organization_id").First(&group, "id = ?", *input.GroupID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: group not found", ErrInvalidPath)
+			}
+			return err
+		}
+		if group.OrganizationID == nil || *group.OrganizationID != orgID {
+			return fmt.Errorf("%w: the group does not belong to the organization", ErrInvalidPath)
+		}
+	}
+
+	for i, item := range input.Items {
+		switch item.Kind {
+		case models.ItemKindScenario:
+			if item.ScenarioID == nil || item.ChapterID != nil || item.CourseID != nil {
+				return fmt.Errorf("%w: item %d: a scenario item names a scenario_id only", ErrInvalidPath, i+1)
+			}
+			var scenario scenarioModels.Scenario
+			err := s.db.Select("id", "organization_id", "is_public").First(&scenario, "id = ?", *item.ScenarioID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: item %d: scenario not found", ErrInvalidPath, i+1)
+			}
+			if err != nil {
+				return err
+			}
+			if !scenario.IsPublic && (scenario.OrganizationID == nil || *scenario.OrganizationID != orgID) {
+				return fmt.Errorf("%w: item %d: the scenario is neither public nor the organization's", ErrInvalidPath, i+1)
+			}
+		case models.ItemKindChapter:
+			if item.ChapterID == nil || item.ScenarioID != nil || item.MinGrade != nil {
+				return fmt.Errorf("%w: item %d: a chapter item names a chapter_id and takes no min_grade", ErrInvalidPath, i+1)
+			}
+			var count int64
+			if err := s.db.Model(&courseModels.Chapter{}).Where("id = ?", *item.ChapterID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return fmt.Errorf("%w: item %d: chapter not found", ErrInvalidPath, i+1)
+			}
+			if item.CourseID != nil {
+				if err := s.db.Table("course_chapters").
+					Where("course_id = ? AND chapter_id = ?", *item.CourseID, *item.ChapterID).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					return fmt.Errorf("%w: item %d: the chapter is not part of the course", ErrInvalidPath, i+1)
+				}
+			}
+		default:
+			return fmt.Errorf("%w: item %d: unknown kind %q", ErrInvalidPath, i+1, item.Kind)
+		}
+	}
+	return nil
+}
+
+// follows reports whether a user follows a path: as a member of its group,
+// or of its organization when it has no group.
+func (s *LearningPathService) follows(path *models.LearningPath, userID string) (bool, error) {
+	if path.GroupID != nil {
+		return s.members.CheckGroupRole(path.GroupID.String(), userID, access.RoleMember)
+	}
+	return s.members.CheckOrgRole(path.OrganizationID.String(), userID, access.RoleMember)
+}
+
+// canManage reports whether a user may edit a path and see its learners'
+// progress: an administrator, a manager of its organization, or a manager of
+// its group.
+func (s *LearningPathService) canManage(path *models.LearningPath, userID string, isAdmin bool) (bool, error) {
+	if isAdmin {
+		return true, nil
+	}
+	ok, err := s.members.CheckOrgRole(path.OrganizationID.String(), userID, access.RoleManager)
+	if err != nil || ok {
+		return ok, err
+	}
+	if path.GroupID != nil {
+		return s.members.CheckGroupRole(path.GroupID.String(), userID, access.RoleManager)
+	}
+	return false, nil
+}
+
+func (s *LearningPathService) loadManageable(id uuid.UUID, userID string, isAdmin bool) (*models.LearningPath, error) {
+	path, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	manages, err := s.canManage(path, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !manages {
+		return nil, ErrPathAccessDenied
+	}
+	return path, nil
+}
+
+func (s *LearningPathService) load(id uuid.UUID) (*models.LearningPath, error) {
+	var path models.LearningPath
+	if err := s.db.Preload("Items", orderedItems).First(&path, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPathNotFound
+		}
+		return nil, err
+	}
+	return &path, nil
+}
+
+func orderedItems(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// buildItems turns input items into path items, numbered from 1 in order.
+func buildItems(inputs []dto.LearningPathItemInput) []models.LearningPathItem {
+	items := make([]models.LearningPathItem, 0, len(inputs))
+	for i, input := range inputs {
+		items = append(items, models.LearningPathItem{
+			Position:   i + 1,
+			Kind:       input.Kind,
+			ScenarioID: input.ScenarioID,
+			ChapterID:  input.ChapterID,
+			CourseID:   input.CourseID,
+			MinGrade:   input.MinGrade,
+		})
+	}
+	return items
+}
@@ -0,0 +1,129 @@
+// Package liveprogress is the in-process event bus behind the teacher live
+// class view stream. Scenario sessions and terminal lifecycle code publish a
+// small Event whenever a learner's standing may have changed; each open
+// stream subscribes, keeps the events of its own class and recomputes only
+// the learners they name.
+//
+// The bus is per process. With several API replicas a stream only hears the
+// replica it is connected to, which is why streams also resynchronise on a
+// timer: the bus makes updates fast, the resync keeps them correct.
+package liveprogress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event kinds.
+const (
+	KindSessionStarted   = "session_started"
+	KindSessionEnded     = "session_ended"
+	KindStepProgress     = "step_progress"
+	KindVerifyAttempt    = "verify_attempt"
+	KindFlagSubmission   = "flag_submission"
+	KindHintRevealed     = "hint_revealed"
+	KindQuizSubmitted    = "quiz_submitted"
+	KindTerminalPresence = "terminal_presence"
+)
+
+// subscriptionBuffer is how many events a subscriber may fall behind by before
+// further events are dropped for it (and it is marked lagged).
+const subscriptionBuffer = 256
+
+// Event says that something about a learner changed. It names who, not what
+// the new state is: subscribers reload the state themselves.
+type Event struct {
+	Kind   string
+	UserID string
+	// ScenarioID and SessionID are uuid.Nil for terminal events.
+	ScenarioID uuid.UUID
+	SessionID  uuid.UUID
+	At         time.Time
+}
+
+// Bus fans events out to its subscribers. Publish never blocks: a subscriber
+// that does not keep up loses events and is told so through TakeLagged.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBus creates an empty bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Default is the process-wide bus the services publish to.
+var Default = NewBus()
+
+// Publish sends e to every subscriber of Default.
+func Publish(e Event) { Default.Publish(e) }
+
+// Subscribe subscribes to Default.
+func Subscribe() *Subscription { return Default.Subscribe(subscriptionBuffer) }
+
+// HasSubscribers reports whether anyone listens to Default, so publishers can
+// skip the lookups an event needs when no stream is open.
+func HasSubscribers() bool { return Default.SubscriberCount() > 0 }
+
+// Publish sends e to every subscriber without waiting for any of them.
+func (b *Bus) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		select {
+		case s.ch <- e:
+		default:
+			s.lagged.Store(true)
+		}
+	}
+}
+
+// Subscribe returns a subscription buffering up to buffer events.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, ch: ch, bus: b}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// SubscriberCount returns the number of open subscriptions.
+func (b *Bus) SubscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Subscription receives the bus's events on C until Close.
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	bus    *Bus
+	lagged atomic.Bool
+	once   sync.Once
+}
+
+// TakeLagged reports whether events were dropped for this subscription since
+// the last call, and clears the mark.
+func (s *Subscription) TakeLagged() bool {
+	return s.lagged.Swap(false)
+}
+
+// Close unsubscribes and closes C. Safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		close(s.ch)
+		s.bus.mu.Unlock()
+	})
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreatePlatformInput registers an LMS as an LTI 1.3 platform. The values are
+// those the LMS displays when the tool is added to it.
+type CreatePlatformInput struct {
+	Name           string     `json:"name" binding:"required"`
+	Issuer         string     `json:"issuer" binding:"required,url"`
+	ClientID       string     `json:"client_id" binding:"required"`
+	DeploymentID   string     `json:"deployment_id" binding:"required"`
+	AuthLoginURL   string     `json:"auth_login_url" binding:"required,url"`
+	AuthTokenURL   string     `json:"auth_token_url" binding:"omitempty,url"`
+	KeySetURL      string     `json:"key_set_url" binding:"required,url"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	// OwnerUserID owns the class-groups created for the platform's courses.
+	// Defaults to the administrator registering the platform.
+	OwnerUserID string `json:"owner_user_id,omitempty"`
+}
+
+// UpdatePlatformInput changes a registration. Nil fields are left as they are.
+type UpdatePlatformInput struct {
+	Name         *string `json:"name,omitempty"`
+	DeploymentID *string `json:"deployment_id,omitempty"`
+	AuthLoginURL *string `json:"auth_login_url,omitempty" binding:"omitempty,url"`
+	AuthTokenURL *string `json:"auth_token_url,omitempty" binding:"omitempty,url"`
+	KeySetURL    *string `json:"key_set_url,omitempty" binding:"omitempty,url"`
+	IsActive     *bool   `json:"is_active,omitempty"`
+}
+
+// PlatformOutput is a registered platform.
+type PlatformOutput struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Issuer         string     `json:"issuer"`
+	ClientID       string     `json:"client_id"`
+	DeploymentID   string     `json:"deployment_id"`
+	AuthLoginURL   string     `json:"auth_login_url"`
+	AuthTokenURL   string     `json:"auth_token_url,omitempty"`
+	KeySetURL      string     `json:"key_set_url"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	OwnerUserID    string     `json:"owner_user_id"`
+	IsActive       bool       `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ToolConfigurationOutput is what an LMS administrator enters when adding OCF
+// as an external tool.
+type ToolConfigurationOutput struct {
+	LoginURL       string `json:"login_url"`
+	LaunchURL      string `json:"launch_url"`
+	DeepLinkingURL string `json:"deep_linking_url"`
+	KeySetURL      string `json:"key_set_url"`
+}
+
+// CompleteDeepLinkInput is the instructor's pick in a deep linking flow.
+type CompleteDeepLinkInput struct {
+	ScenarioID uuid.UUID `json:"scenario_id" binding:"required"`
+}
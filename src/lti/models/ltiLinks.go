@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LtiLaunchState is one OIDC login in flight: issued when the platform
+// initiates the login, consumed by the launch that follows. Single use, so an
+// id_token cannot be replayed.
+type LtiLaunchState struct {
+	State      string    `gorm:"type:varchar(64);primarykey"`
+	Nonce      string    `gorm:"type:varchar(64);not null"`
+	PlatformID uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+}
+
+func (LtiLaunchState) TableName() string {
+	return "lti_launch_states"
+}
+
+// LtiUserLink maps a platform user (the id_token "sub") to an OCF user.
+type LtiUserLink struct {
+	ID         uuid.UUID `gorm:"type:uuid;primarykey" json:"id"`
+	PlatformID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_lti_user_subject" json:"platform_id"`
+	Subject    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_lti_user_subject" json:"subject"`
+	UserID     string    `gorm:"type:varchar(255);not null;index" json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (LtiUserLink) TableName() string {
+	return "lti_user_links"
+}
+
+// LtiContextLink maps a platform course (the LTI context) to the class-group
+// its learners are enrolled in.
+type LtiContextLink struct {
+	ID         uuid.UUID `gorm:"type:uuid;primarykey" json:"id"`
+	PlatformID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_lti_context" json:"platform_id"`
+	ContextID  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_lti_context" json:"context_id"`
+	GroupID    uuid.UUID `gorm:"type:uuid;not null;index" json:"group_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (LtiContextLink) TableName() string {
+	return "lti_context_links"
+}
+
+// LtiResourceLink is one placement of a scenario in a platform course, as
+// created by deep linking. LineItemURL is the gradebook column grades are
+// passed back to; empty when the platform did not offer AGS.
+type LtiResourceLink struct {
+	ID             uuid.UUID `gorm:"type:uuid;primarykey" json:"id"`
+	PlatformID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_lti_resource_link" json:"platform_id"`
+	ResourceLinkID string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_lti_resource_link" json:"resource_link_id"`
+	ScenarioID     uuid.UUID `gorm:"type:uuid;not null;index" json:"scenario_id"`
+	GroupID        uuid.UUID `gorm:"type:uuid;not null;index" json:"group_id"`
+	LineItemURL    string    `gorm:"type:varchar(1024)" json:"line_item_url,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (LtiResourceLink) TableName() string {
+	return "lti_resource_links"
+}
+
+// LtiGradeLink records that a learner launched a resource link, and which of
+// their grades was last passed back for it. The passback job sends a grade
+// when the learner's latest completed session differs from SyncedSessionID.
+type LtiGradeLink struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primarykey" json:"id"`
+	ResourceLinkID  uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_lti_grade_link" json:"resource_link_id"`
+	UserID          string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_lti_grade_link" json:"user_id"`
+	Subject         string     `gorm:"type:varchar(255);not null" json:"subject"`
+	SyncedSessionID *uuid.UUID `gorm:"type:uuid" json:"synced_session_id,omitempty"`
+	SyncedGrade     *float64   `json:"synced_grade,omitempty"`
+	SyncedAt        *time.Time `json:"synced_at,omitempty"`
+	LastError       string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (LtiGradeLink) TableName() string {
+	return "lti_grade_links"
+}
+
+// LtiDeepLinkRequest is a deep linking launch waiting for the instructor to
+// pick a scenario. The platform's return URL and opaque data are echoed back
+// in the signed response.
+type LtiDeepLinkRequest struct {
+	ID           uuid.UUID `gorm:"type:uuid;primarykey" json:"id"`
+	PlatformID   uuid.UUID `gorm:"type:uuid;not null" json:"platform_id"`
+	UserID       string    `gorm:"type:varchar(255);not null" json:"user_id"`
+	DeploymentID string    `gorm:"type:varchar(255);not null" json:"deployment_id"`
+	ReturnURL    string    `gorm:"type:varchar(1024);not null" json:"return_url"`
+	Data         string    `gorm:"type:text" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+}
+
+func (LtiDeepLinkRequest) TableName() string {
+	return "lti_deep_link_requests"
+}
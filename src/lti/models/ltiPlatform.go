@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"soli/formations/src/utils/crypto"
+)
+
+// LtiPlatform is an LMS (Moodle, Canvas, ...) registered as an LTI 1.3
+// platform. The values are the ones the LMS shows when the tool is added to
+// it; a launch from an issuer/client pair that is not registered is refused.
+type LtiPlatform struct {
+	entityManagementModels.BaseModel
+	Name string `gorm:"type:varchar(255);not null" json:"name"`
+	// Issuer is the platform's "iss" claim, e.g. https://moodle.example.edu.
+	Issuer   string `gorm:"type:varchar(512);not null;uniqueIndex:idx_lti_platform_client" json:"issuer"`
+	ClientID string `gorm:"type:varchar(255);not null;uniqueIndex:idx_lti_platform_client" json:"client_id"`
+	// DeploymentID is the deployment the tool was installed as on the platform.
+	DeploymentID string `gorm:"type:varchar(255);not null" json:"deployment_id"`
+	// AuthLoginURL is the platform's OIDC authorization endpoint.
+	AuthLoginURL string `gorm:"type:varchar(1024);not null" json:"auth_login_url"`
+	// AuthTokenURL is the platform's OAuth2 token endpoint, used for Assignment
+	// and Grade Services.
+	AuthTokenURL string `gorm:"type:varchar(1024)" json:"auth_token_url"`
+	// KeySetURL is the platform's public JWKS, against which id_tokens are checked.
+	KeySetURL string `gorm:"type:varchar(1024);not null" json:"key_set_url"`
+	// OrganizationID is the organization class-groups created for this
+	// platform's courses belong to.
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	// OwnerUserID owns the class-groups created for this platform's courses.
+	OwnerUserID string `gorm:"type:varchar(255);not null" json:"owner_user_id"`
+	IsActive    bool   `gorm:"default:true" json:"is_active"`
+}
+
+func (LtiPlatform) TableName() string {
+	return "lti_platforms"
+}
+
+// LtiToolKey is the RSA key OCF signs its own LTI messages with (deep linking
+// responses, AGS client assertions). Generated on first use and kept, so the
+// public half published at /lti/jwks stays valid across restarts.
+type LtiToolKey struct {
+	ID            uint      `gorm:"primarykey" json:"-"`
+	Kid           string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"kid"`
+	PrivateKeyPEM string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (LtiToolKey) TableName() string {
+	return "lti_tool_keys"
+}
+
+// BeforeSave encrypts PrivateKeyPEM before writing to the database.
+func (k *LtiToolKey) BeforeSave(tx *gorm.DB) error {
+	return crypto.EncryptField(&k.PrivateKeyPEM)
+}
+
+// AfterFind decrypts PrivateKeyPEM after loading from the database.
+func (k *LtiToolKey) AfterFind(tx *gorm.DB) error {
+	return crypto.DecryptField(&k.PrivateKeyPEM)
+}
@@ -0,0 +1,317 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"soli/formations/src/lti/dto"
+	"soli/formations/src/lti/services"
+)
+
+// LtiController serves the LTI 1.3 tool endpoints.
+type LtiController struct {
+	service *services.LtiService
+}
+
+// NewLtiController creates a new LTI controller.
+func NewLtiController(service *services.LtiService) *LtiController {
+	return &LtiController{service: service}
+}
+
+// Login godoc
+// @Summary LTI 1.3 OIDC login initiation
+// @Description Called by the LMS to start a launch. Redirects to the platform's authorization
+// @Description endpoint with a fresh state and nonce. Parameters come as query string (GET) or form (POST).
+// @Tags lti
+// @Param iss query string true "Platform issuer"
+// @Param login_hint query string true "Opaque user hint"
+// @Param target_link_uri query string false "Launch target"
+// @Param lti_message_hint query string false "Opaque message hint"
+// @Param client_id query string false "Client ID of the tool on the platform"
+// @Success 302
+// @Failure 400 {object} map[string]string
+// @Router /lti/login [get]
+func (lc *LtiController) Login(c *gin.Context) {
+	var req services.LoginRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid login request"})
+		return
+	}
+
+	redirect, err := lc.service.InitiateLogin(req)
+	if err != nil {
+		writeLaunchError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// Launch godoc
+// @Summary LTI 1.3 launch
+// @Description Receives the id_token the platform form-posts after login. A resource link
+// @Description launch signs the user in and redirects to the scenario in the frontend; a deep
+// @Description linking launch redirects the instructor to the scenario picker. The OCF access
+// @Description token is passed in the URL fragment, which browsers never send to a server.
+// @Tags lti
+// @Accept x-www-form-urlencoded
+// @Param id_token formData string true "Signed launch message"
+// @Param state formData string true "State issued at login"
+// @Success 303
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /lti/launch [post]
+func (lc *LtiController) Launch(c *gin.Context) {
+	result, err := lc.service.Launch(c.PostForm("state"), c.PostForm("id_token"))
+	if err != nil {
+		writeLaunchError(c, err)
+		return
+	}
+
+	fragment := url.Values{}
+	fragment.Set("access_token", result.AccessToken)
+	fragment.Set("user_id", result.UserID)
+	if result.GroupID != nil {
+		fragment.Set("group_id", result.GroupID.String())
+	}
+	path := "/lti/launch"
+	if result.DeepLinkID != nil {
+		path = "/lti/deep-link"
+		fragment.Set("deep_link_id", result.DeepLinkID.String())
+	}
+	if result.ScenarioID != nil {
+		fragment.Set("scenario_id", result.ScenarioID.String())
+	}
+	c.Redirect(http.StatusSeeOther, strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")+path+"#"+fragment.Encode())
+}
+
+func writeLaunchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownPlatform):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown LTI platform"})
+	case errors.Is(err, services.ErrInvalidLaunch):
+		slog.Warn("rejected LTI launch", "err", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		slog.Error("LTI launch failed", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "LTI launch failed"})
+	}
+}
+
+// GetJWKS godoc
+// @Summary LTI tool public keys
+// @Description The key set platforms verify OCF's deep linking responses and AGS client assertions with.
+// @Tags lti
+// @Produce json
+// @Success 200 {object} services.JWKS
+// @Failure 500 {object} map[string]string
+// @Router /lti/jwks [get]
+func (lc *LtiController) GetJWKS(c *gin.Context) {
+	jwks, err := lc.service.ToolJWKS()
+	if err != nil {
+		slog.Error("failed to load LTI tool key", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tool keys"})
+		return
+	}
+	c.JSON(http.StatusOK, jwks)
+}
+
+// ListDeepLinkScenarios godoc
+// @Summary List scenarios placeable in an LTI course
+// @Description Returns the scenarios the instructor of a pending deep linking request may place:
+// @Description public ones, their own, and those of the platform's organization.
+// @Tags lti
+// @Produce json
+// @Param id path string true "Deep linking request ID"
+// @Success 200 {array} services.DeepLinkScenario
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /lti/deep-links/{id}/scenarios [get]
+// @Security BearerAuth
+func (lc *LtiController) ListDeepLinkScenarios(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deep linking request ID"})
+		return
+	}
+	scenarios, err := lc.service.ListDeepLinkScenarios(requestID, c.GetString("userId"))
+	if err != nil {
+		writeDeepLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, scenarios)
+}
+
+// CompleteDeepLink godoc
+// @Summary Place a scenario in an LTI course
+// @Description Signs the deep linking response for the chosen scenario. The frontend form-posts
+// @Description the returned jwt, as field JWT, to return_url.
+// @Tags lti
+// @Accept json
+// @Produce json
+// @Param id path string true "Deep linking request ID"
+// @Param body body dto.CompleteDeepLinkInput true "Chosen scenario"
+// @Success 200 {object} services.DeepLinkResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /lti/deep-links/{id} [post]
+// @Security BearerAuth
+func (lc *LtiController) CompleteDeepLink(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deep linking request ID"})
+		return
+	}
+	var input dto.CompleteDeepLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	response, err := lc.service.CompleteDeepLink(requestID, c.GetString("userId"), input.ScenarioID)
+	if err != nil {
+		writeDeepLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func writeDeepLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDeepLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "deep linking request not found"})
+	case errors.Is(err, services.ErrInvalidLaunch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		slog.Error("LTI deep linking failed", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "deep linking failed"})
+	}
+}
+
+// GetToolConfiguration godoc
+// @Summary LTI tool configuration
+// @Description The URLs an LMS administrator enters when adding OCF as an LTI 1.3 tool (admin only).
+// @Tags lti
+// @Produce json
+// @Success 200 {object} dto.ToolConfigurationOutput
+// @Router /lti/tool-configuration [get]
+// @Security BearerAuth
+func (lc *LtiController) GetToolConfiguration(c *gin.Context) {
+	c.JSON(http.StatusOK, lc.service.ToolConfiguration())
+}
+
+// ListPlatforms godoc
+// @Summary List LTI platforms
+// @Description Returns every registered LTI 1.3 platform (admin only).
+// @Tags lti
+// @Produce json
+// @Success 200 {array} dto.PlatformOutput
+// @Failure 500 {object} map[string]string
+// @Router /lti/platforms [get]
+// @Security BearerAuth
+func (lc *LtiController) ListPlatforms(c *gin.Context) {
+	platforms, err := lc.service.ListPlatforms()
+	if err != nil {
+		slog.Error("failed to list LTI platforms", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list LTI platforms"})
+		return
+	}
+	c.JSON(http.StatusOK, platforms)
+}
+
+// CreatePlatform godoc
+// @Summary Register an LTI platform
+// @Description Registers an LMS as an LTI 1.3 platform (admin only).
+// @Tags lti
+// @Accept json
+// @Produce json
+// @Param body body dto.CreatePlatformInput true "Platform registration"
+// @Success 201 {object} dto.PlatformOutput
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /lti/platforms [post]
+// @Security BearerAuth
+func (lc *LtiController) CreatePlatform(c *gin.Context) {
+	var input dto.CreatePlatformInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	platform, err := lc.service.CreatePlatform(input, c.GetString("userId"))
+	if err != nil {
+		slog.Error("failed to register LTI platform", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register LTI platform"})
+		return
+	}
+	c.JSON(http.StatusCreated, platform)
+}
+
+// UpdatePlatform godoc
+// @Summary Update an LTI platform
+// @Description Changes a platform registration, e.g. to disable it (admin only).
+// @Tags lti
+// @Accept json
+// @Produce json
+// @Param id path string true "Platform ID"
+// @Param body body dto.UpdatePlatformInput true "Fields to change"
+// @Success 200 {object} dto.PlatformOutput
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /lti/platforms/{id} [patch]
+// @Security BearerAuth
+func (lc *LtiController) UpdatePlatform(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid platform ID"})
+		return
+	}
+	var input dto.UpdatePlatformInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	platform, err := lc.service.UpdatePlatform(id, input)
+	if err != nil {
+		if errors.Is(err, services.ErrPlatformNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "platform not found"})
+			return
+		}
+		slog.Error("failed to update LTI platform", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update LTI platform"})
+		return
+	}
+	c.JSON(http.StatusOK, platform)
+}
+
+// DeletePlatform godoc
+// @Summary Delete an LTI platform
+// @Description Removes a platform registration. Courses and accounts it created are kept (admin only).
+// @Tags lti
+// @Param id path string true "Platform ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /lti/platforms/{id} [delete]
+// @Security BearerAuth
+func (lc *LtiController) DeletePlatform(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid platform ID"})
+		return
+	}
+	if err := lc.service.DeletePlatform(id); err != nil {
+		if errors.Is(err, services.ErrPlatformNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "platform not found"})
+			return
+		}
+		slog.Error("failed to delete LTI platform", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete LTI platform"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
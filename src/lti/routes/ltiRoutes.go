@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	auth "soli/formations/src/auth"
+	"soli/formations/src/lti/services"
+)
+
+// RegisterRoutes wires the LTI 1.3 tool endpoints.
+//
+// login, launch and jwks are called by the LMS and the learner's browser
+// without an OCF session, so they carry no AuthManagement: a launch is
+// authenticated by the platform's signature on its id_token. Everything else
+// needs a signed-in user.
+func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	mw := auth.NewAuthMiddleware(db)
+	service := services.NewLtiService(db, services.NewCasdoorAccountProvisioner(db), services.ToolURLFromEnv())
+	controller := NewLtiController(service)
+
+	lti := router.Group("/lti")
+	lti.GET("/login", controller.Login)
+	lti.POST("/login", controller.Login)
+	lti.POST("/launch", controller.Launch)
+	lti.GET("/jwks", controller.GetJWKS)
+
+	lti.GET("/deep-links/:id/scenarios", mw.AuthManagement(), controller.ListDeepLinkScenarios)
+	lti.POST("/deep-links/:id", mw.AuthManagement(), controller.CompleteDeepLink)
+
+	lti.GET("/tool-configuration", mw.AuthManagement(), controller.GetToolConfiguration)
+	lti.GET("/platforms", mw.AuthManagement(), controller.ListPlatforms)
+	lti.POST("/platforms", mw.AuthManagement(), controller.CreatePlatform)
+	lti.PATCH("/platforms/:id", mw.AuthManagement(), controller.UpdatePlatform)
+	lti.DELETE("/platforms/:id", mw.AuthManagement(), controller.DeletePlatform)
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"log"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/interfaces"
+)
+
+// RegisterPermissions registers the Casbin policies and RouteRegistry entries
+// of the LTI endpoints. The platform-facing endpoints are declared without a
+// gateway policy: they are mounted without AuthManagement.
+func RegisterPermissions(enforcer interfaces.EnforcerInterface) {
+	log.Println("=== Registering LTI permissions ===")
+
+	access.RegisterEnforced(enforcer, "LTI",
+		access.RoutePermission{
+			Path: "/api/v1/lti/login", Method: "GET", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "LTI 1.3 OIDC login initiation (called by the LMS)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/login", Method: "POST", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "LTI 1.3 OIDC login initiation (called by the LMS)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/launch", Method: "POST", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "LTI 1.3 launch, authenticated by the platform-signed id_token",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/jwks", Method: "GET", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "LTI tool public key set",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/deep-links/:id/scenarios", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "List scenarios placeable in the caller's pending LTI deep linking request",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/deep-links/:id", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Place a scenario in an LTI course (caller's own deep linking request)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/tool-configuration", Method: "GET",
+			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
+			Description: "View the URLs to register OCF as an LTI tool (admin only)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/platforms", Method: "GET",
+			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
+			Description: "List registered LTI platforms (admin only)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/platforms", Method: "POST",
+			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
+			Description: "Register an LTI platform (admin only)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/platforms/:id", Method: "PATCH",
+			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
+			Description: "Update an LTI platform (admin only)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/lti/platforms/:id", Method: "DELETE",
+			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
+			Description: "Delete an LTI platform (admin only)",
+		},
+	)
+
+	log.Println("=== LTI permissions registered ===")
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"gorm.io/gorm"
+
+	authController "soli/formations/src/auth"
+	"soli/formations/src/auth/casdoor"
+	authServices "soli/formations/src/auth/services"
+	"soli/formations/src/lti/models"
+	organizationServices "soli/formations/src/organizations/services"
+	"soli/formations/src/utils"
+)
+
+// casdoorAccounts provisions LTI users as Casdoor accounts.
+//
+// An LTI account has no password its owner knows: the platform vouches for the
+// user on every launch. To sign them in, a fresh random password is set and
+// immediately used for the password grant, the same grant /auth/login uses.
+// Accounts created here are marked lti_managed, and only those are ever
+// signed in this way.
+type casdoorAccounts struct {
+	db        *gorm.DB
+	passwords authServices.PasswordService
+}
+
+// NewCasdoorAccountProvisioner returns the production AccountProvisioner.
+func NewCasdoorAccountProvisioner(db *gorm.DB) AccountProvisioner {
+	return &casdoorAccounts{db: db, passwords: authServices.NewPasswordService()}
+}
+
+func (c *casdoorAccounts) CreateAccount(platform *models.LtiPlatform, identity LaunchIdentity) (string, error) {
+	// The Casdoor name must be stable and unique per platform user; the
+	// subject itself may be long or contain characters Casdoor refuses.
+	digest := sha256.Sum256([]byte(platform.ID.String() + "|" + identity.Subject))
+	name := "lti-" + hex.EncodeToString(digest[:])[:20]
+
+	password, err := randomToken(24)
+	if err != nil {
+		return "", err
+	}
+	displayName := identity.Name
+	if displayName == "" {
+		displayName = name
+	}
+
+	// An email already held by another account is left off rather than shared:
+	// that account stays its owner's, and /auth/login resolves users by email.
+	email := identity.Email
+	if email != "" {
+		if existing, err := casdoorsdk.GetUserByEmail(email); err == nil && existing != nil && existing.Name != "" {
+			email = ""
+		}
+	}
+
+	user := casdoorsdk.User{
+		Name:              name,
+		DisplayName:       displayName,
+		Email:             email,
+		Password:          password,
+		SignupApplication: "ocf",
+		// The platform has verified the address; there is no inbox round trip
+		// an LMS user could complete anyway.
+		EmailVerified: email != "",
+		Properties: map[string]string{
+			"lti_managed":  "true",
+			"lti_platform": platform.ID.String(),
+		},
+	}
+	user.CreatedTime = casdoorsdk.GetCurrentTime()
+	if _, err := casdoorsdk.AddUser(&user); err != nil {
+		return "", fmt.Errorf("failed to create Casdoor user: %w", err)
+	}
+	created, err := casdoorsdk.GetUser(name)
+	if err != nil || created == nil {
+		return "", fmt.Errorf("failed to load created Casdoor user %s: %v", name, err)
+	}
+
+	opts := utils.DefaultPermissionOptions()
+	opts.WarnOnError = true
+	if err := utils.AddGroupingPolicy(casdoor.Enforcer, created.Id, "member", opts); err != nil {
+		utils.Warn("Could not add role member to LTI user %s: %v", created.Id, err)
+	}
+	orgService := organizationServices.NewOrganizationService(c.db)
+	if _, err := orgService.CreatePersonalOrganization(created.Id, created.DisplayName); err != nil {
+		utils.Warn("Could not create personal organization for LTI user %s: %v", created.Id, err)
+	}
+	return created.Id, nil
+}
+
+func (c *casdoorAccounts) IssueAccessToken(userID string) (string, error) {
+	user, err := casdoorsdk.GetUserByUserId(userID)
+	if err != nil || user == nil {
+		return "", fmt.Errorf("failed to load user %s: %v", userID, err)
+	}
+	if user.Properties["lti_managed"] != "true" {
+		// Never reset the password of an account someone signs into directly.
+		return "", fmt.Errorf("user %s is not an LTI-managed account", userID)
+	}
+
+	password, err := randomToken(24)
+	if err != nil {
+		return "", err
+	}
+	if err := c.passwords.SetUserPassword(userID, "", password); err != nil {
+		return "", fmt.Errorf("failed to rotate LTI credential: %w", err)
+	}
+	resp, err := authController.LoginToCasdoor(user, password)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("casdoor did not issue a token (HTTP %d)", resp.StatusCode)
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Message types OCF accepts or sends.
+const (
+	MessageTypeResourceLink        = "LtiResourceLinkRequest"
+	MessageTypeDeepLinkingRequest  = "LtiDeepLinkingRequest"
+	MessageTypeDeepLinkingResponse = "LtiDeepLinkingResponse"
+	ltiVersion                     = "1.3.0"
+)
+
+// AGS scope OCF asks for to post scores.
+const scopeScore = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+
+// customScenarioID is the custom parameter a deep-linked resource carries, so
+// the first launch of the placement knows which scenario it is.
+const customScenarioID = "scenario_id"
+
+// LaunchClaims is the id_token of an LTI 1.3 launch. The specification
+// namespaces every LTI claim with a URL.
+type LaunchClaims struct {
+	jwt.RegisteredClaims
+	Nonce      string `json:"nonce"`
+	Azp        string `json:"azp,omitempty"`
+	Name       string `json:"name,omitempty"`
+	GivenName  string `json:"given_name,omitempty"`
+	FamilyName string `json:"family_name,omitempty"`
+	Email      string `json:"email,omitempty"`
+
+	MessageType   string             `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version       string             `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID  string             `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkURI string             `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri,omitempty"`
+	ResourceLink  *ResourceLinkClaim `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link,omitempty"`
+	Context       *ContextClaim      `json:"https://purl.imsglobal.org/spec/lti/claim/context,omitempty"`
+	Roles         []string           `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+	Custom        map[string]string  `json:"https://purl.imsglobal.org/spec/lti/claim/custom,omitempty"`
+	AGSEndpoint   *AGSEndpointClaim  `json:"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint,omitempty"`
+
+	DeepLinkingSettings *DeepLinkingSettingsClaim `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings,omitempty"`
+}
+
+// ResourceLinkClaim identifies the placement that was launched.
+type ResourceLinkClaim struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// ContextClaim identifies the course the launch came from.
+type ContextClaim struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// AGSEndpointClaim is the Assignment and Grade Services endpoint of a launch.
+type AGSEndpointClaim struct {
+	Scope     []string `json:"scope"`
+	LineItems string   `json:"lineitems,omitempty"`
+	LineItem  string   `json:"lineitem,omitempty"`
+}
+
+// DeepLinkingSettingsClaim tells the tool where to send the selection back.
+type DeepLinkingSettingsClaim struct {
+	ReturnURL                string   `json:"deep_link_return_url"`
+	AcceptTypes              []string `json:"accept_types"`
+	AcceptPresentationTarget []string `json:"accept_presentation_document_targets,omitempty"`
+	Data                     string   `json:"data,omitempty"`
+}
+
+// isInstructor reports whether the launching user teaches the course. Both the
+// full context role URIs and the short names LTI 1.3 still tolerates count.
+func (c *LaunchClaims) isInstructor() bool {
+	for _, role := range c.Roles {
+		name := role
+		if i := strings.LastIndex(role, "#"); i >= 0 {
+			if !strings.Contains(role, "/membership") {
+				// Institution and system roles say nothing about this course.
+				continue
+			}
+			name = role[i+1:]
+		}
+		switch name {
+		case "Instructor", "Administrator", "ContentDeveloper", "TeachingAssistant":
+			return true
+		}
+	}
+	return false
+}
+
+// displayName is the best name the platform gave for the user.
+func (c *LaunchClaims) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return strings.TrimSpace(c.GivenName + " " + c.FamilyName)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/lti/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+// DeepLinkScenario is a scenario an instructor may place in their course.
+type DeepLinkScenario struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Difficulty  string    `json:"difficulty,omitempty"`
+}
+
+// DeepLinkResponse is the signed selection to form-post back to the platform.
+type DeepLinkResponse struct {
+	ReturnURL string `json:"return_url"`
+	JWT       string `json:"jwt"`
+}
+
+// pendingDeepLink loads a deep linking request of userID that is still open.
+func (s *LtiService) pendingDeepLink(requestID uuid.UUID, userID string) (*models.LtiDeepLinkRequest, error) {
+	var request models.LtiDeepLinkRequest
+	err := s.db.Where("id = ? AND user_id = ? AND expires_at > ?", requestID, userID, time.Now()).First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeepLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deep linking request: %w", err)
+	}
+	return &request, nil
+}
+
+// placeableScenarios is what an instructor on this platform may place: public
+// scenarios, their own, and those of the organization the platform belongs to.
+func (s *LtiService) placeableScenarios(platformID uuid.UUID, userID string) *gorm.DB {
+	var platform models.LtiPlatform
+	query := s.db.Model(&scenarioModels.Scenario{}).Scopes(scenarioModels.NotArchived)
+	if err := s.db.Where("id = ?", platformID).First(&platform).Error; err == nil && platform.OrganizationID != nil {
+		return query.Where("is_public = ? OR created_by_id = ? OR organization_id = ?", true, userID, *platform.OrganizationID)
+	}
+	return query.Where("is_public = ? OR created_by_id = ?", true, userID)
+}
+
+// ListDeepLinkScenarios returns the scenarios the instructor may pick from.
+func (s *LtiService) ListDeepLinkScenarios(requestID uuid.UUID, userID string) ([]DeepLinkScenario, error) {
+	request, err := s.pendingDeepLink(requestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var scenarios []scenarioModels.Scenario
+	if err := s.placeableScenarios(request.PlatformID, userID).Order("title ASC").Find(&scenarios).Error; err != nil {
+		return nil, fmt.Errorf("failed to list scenarios: %w", err)
+	}
+	items := make([]DeepLinkScenario, 0, len(scenarios))
+	for _, sc := range scenarios {
+		items = append(items, DeepLinkScenario{ID: sc.ID, Title: sc.Title, Description: sc.Description, Difficulty: sc.Difficulty})
+	}
+	return items, nil
+}
+
+// deepLinkingResponseClaims is the LtiDeepLinkingResponse message.
+type deepLinkingResponseClaims struct {
+	jwt.RegisteredClaims
+	Nonce        string           `json:"nonce"`
+	MessageType  string           `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version      string           `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID string           `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	ContentItems []map[string]any `json:"https://purl.imsglobal.org/spec/lti-dl/claim/content_items"`
+	Data         string           `json:"https://purl.imsglobal.org/spec/lti-dl/claim/data,omitempty"`
+}
+
+// CompleteDeepLink places scenarioID in the instructor's course: it signs an
+// LtiDeepLinkingResponse with one resource link, graded out of 100, whose
+// launches carry the scenario ID. The request is used up.
+func (s *LtiService) CompleteDeepLink(requestID uuid.UUID, userID string, scenarioID uuid.UUID) (*DeepLinkResponse, error) {
+	request, err := s.pendingDeepLink(requestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var scenario scenarioModels.Scenario
+	if err := s.placeableScenarios(request.PlatformID, userID).Where("id = ?", scenarioID).First(&scenario).Error; err != nil {
+		return nil, fmt.Errorf("%w: scenario %s cannot be placed", ErrInvalidLaunch, scenarioID)
+	}
+	var platform models.LtiPlatform
+	if err := s.db.Where("id = ?", request.PlatformID).First(&platform).Error; err != nil {
+		return nil, ErrUnknownPlatform
+	}
+	kid, key, err := s.toolKeys.get()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims := deepLinkingResponseClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    platform.ClientID,
+			Audience:  jwt.ClaimStrings{platform.Issuer},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:        nonce,
+		MessageType:  MessageTypeDeepLinkingResponse,
+		Version:      ltiVersion,
+		DeploymentID: request.DeploymentID,
+		ContentItems: []map[string]any{{
+			"type":  "ltiResourceLink",
+			"title": scenario.Title,
+			"text":  scenario.Description,
+			"url":   s.LaunchURL(),
+			"custom": map[string]string{
+				customScenarioID: scenario.ID.String(),
+			},
+			"lineItem": map[string]any{
+				"scoreMaximum": 100,
+				"label":        scenario.Title,
+				"resourceId":   scenario.ID.String(),
+			},
+		}},
+		Data: request.Data,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign deep linking response: %w", err)
+	}
+
+	if err := s.db.Delete(request).Error; err != nil {
+		return nil, fmt.Errorf("failed to close deep linking request: %w", err)
+	}
+	return &DeepLinkResponse{ReturnURL: request.ReturnURL, JWT: signed}, nil
+}
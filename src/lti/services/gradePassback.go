@@ -0,0 +1,263 @@
+package services
+
+// gradePassback.go — Assignment and Grade Services (AGS) score publishing.
+//
+// A learner launching a graded placement gets an LtiGradeLink. SyncGrades,
+// run on a timer, looks for links whose learner has a completed session newer
+// than the one last sent, and posts its grade to the placement's line item.
+// A failed post leaves the link as it was, so the next run retries it.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+
+	"soli/formations/src/lti/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+// agsToken is an access token for a platform's AGS endpoints.
+type agsToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// agsTokenCache keeps one access token per platform until shortly before it
+// expires, so a passback run does not ask the platform for one per grade.
+type agsTokenCache struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]agsToken
+}
+
+// accessToken returns a token for platform, requesting one with the OAuth2
+// client credentials grant and a signed client assertion if none is cached.
+func (s *LtiService) accessToken(platform *models.LtiPlatform) (string, error) {
+	s.tokens.mu.Lock()
+	cached, ok := s.tokens.tokens[platform.ID]
+	s.tokens.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+	if platform.AuthTokenURL == "" {
+		return "", fmt.Errorf("platform %s has no auth_token_url", platform.ID)
+	}
+
+	kid, key, err := s.toolKeys.get()
+	if err != nil {
+		return "", err
+	}
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer:    platform.ClientID,
+		Subject:   platform.ClientID,
+		Audience:  jwt.ClaimStrings{platform.AuthTokenURL},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		ID:        jti,
+	})
+	assertion.Header["kid"] = kid
+	signed, err := assertion.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign client assertion: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {signed},
+		"scope":                 {scopeScore},
+	}
+	resp, err := s.client.PostForm(platform.AuthTokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request AGS token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("AGS token request returned HTTP %d: %s", resp.StatusCode, body)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("invalid AGS token response")
+	}
+
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = time.Hour
+	}
+	s.tokens.mu.Lock()
+	// Renew a minute early so a token never expires between cache and use.
+	s.tokens.tokens[platform.ID] = agsToken{value: token.AccessToken, expiresAt: now.Add(lifetime - time.Minute)}
+	s.tokens.mu.Unlock()
+	return token.AccessToken, nil
+}
+
+// scoresURL is the line item's scores endpoint: "/scores" appended to its
+// path, before any query string.
+func scoresURL(lineItemURL string) (string, error) {
+	u, err := url.Parse(lineItemURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/scores"
+	return u.String(), nil
+}
+
+// agsScore is the body of a score publish.
+type agsScore struct {
+	UserID           string  `json:"userId"`
+	ScoreGiven       float64 `json:"scoreGiven"`
+	ScoreMaximum     float64 `json:"scoreMaximum"`
+	ActivityProgress string  `json:"activityProgress"`
+	GradingProgress  string  `json:"gradingProgress"`
+	Timestamp        string  `json:"timestamp"`
+}
+
+func (s *LtiService) postScore(platform *models.LtiPlatform, lineItemURL string, score agsScore) error {
+	token, err := s.accessToken(platform)
+	if err != nil {
+		return err
+	}
+	target, err := scoresURL(lineItemURL)
+	if err != nil {
+		return fmt.Errorf("invalid line item URL: %w", err)
+	}
+	body, err := json.Marshal(score)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.ims.lis.v1.score+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post score: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			// A revoked token: drop it so the next attempt asks for a new one.
+			s.tokens.mu.Lock()
+			delete(s.tokens.tokens, platform.ID)
+			s.tokens.mu.Unlock()
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("score publish returned HTTP %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// pendingGrade is a grade link whose learner has a completed session not yet
+// passed back.
+type pendingGrade struct {
+	GradeLinkID uuid.UUID
+	Subject     string
+	PlatformID  uuid.UUID
+	LineItemURL string
+	SessionID   uuid.UUID
+	Grade       float64
+	CompletedAt time.Time
+}
+
+// SyncGrades passes back every grade not yet sent and returns how many were.
+// Each learner's latest completed session counts, so a retake replaces the
+// grade in the LMS like it replaces it in OCF.
+func (s *LtiService) SyncGrades() (int, error) {
+	var links []struct {
+		models.LtiGradeLink
+		PlatformID  uuid.UUID
+		ScenarioID  uuid.UUID
+		LineItemURL string
+	}
+	if err := s.db.Table("lti_grade_links gl").
+		Select("gl.*, rl.platform_id, rl.scenario_id, rl.line_item_url").
+		Joins("JOIN lti_resource_links rl ON rl.id = gl.resource_link_id").
+		Where("rl.line_item_url <> ''").
+		Scan(&links).Error; err != nil {
+		return 0, fmt.Errorf("failed to load grade links: %w", err)
+	}
+
+	var pending []pendingGrade
+	for _, link := range links {
+		var session scenarioModels.ScenarioSession
+		err := s.db.Where("user_id = ? AND scenario_id = ? AND status = ? AND grade IS NOT NULL AND is_preview = ?",
+			link.UserID, link.ScenarioID, "completed", false).
+			Order("completed_at DESC").First(&session).Error
+		if err != nil || session.CompletedAt == nil {
+			continue
+		}
+		if link.SyncedSessionID != nil && *link.SyncedSessionID == session.ID {
+			continue
+		}
+		pending = append(pending, pendingGrade{
+			GradeLinkID: link.ID,
+			Subject:     link.Subject,
+			PlatformID:  link.PlatformID,
+			LineItemURL: link.LineItemURL,
+			SessionID:   session.ID,
+			Grade:       *session.Grade,
+			CompletedAt: *session.CompletedAt,
+		})
+	}
+
+	platforms := map[uuid.UUID]*models.LtiPlatform{}
+	sent := 0
+	for _, p := range pending {
+		platform, ok := platforms[p.PlatformID]
+		if !ok {
+			platform = &models.LtiPlatform{}
+			if err := s.db.Where("id = ?", p.PlatformID).First(platform).Error; err != nil {
+				slog.Error("LTI grade passback: platform not found", "platform_id", p.PlatformID, "err", err)
+				continue
+			}
+			platforms[p.PlatformID] = platform
+		}
+
+		err := s.postScore(platform, p.LineItemURL, agsScore{
+			UserID:           p.Subject,
+			ScoreGiven:       p.Grade,
+			ScoreMaximum:     100,
+			ActivityProgress: "Completed",
+			GradingProgress:  "FullyGraded",
+			Timestamp:        p.CompletedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			slog.Warn("LTI grade passback failed", "grade_link_id", p.GradeLinkID, "session_id", p.SessionID, "err", err)
+			s.db.Model(&models.LtiGradeLink{}).Where("id = ?", p.GradeLinkID).Update("last_error", err.Error())
+			continue
+		}
+
+		now := time.Now()
+		if err := s.db.Model(&models.LtiGradeLink{}).Where("id = ?", p.GradeLinkID).Updates(map[string]any{
+			"synced_session_id": p.SessionID,
+			"synced_grade":      p.Grade,
+			"synced_at":         now,
+			"last_error":        "",
+		}).Error; err != nil {
+			slog.Error("LTI grade passback: failed to record sync", "grade_link_id", p.GradeLinkID, "err", err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
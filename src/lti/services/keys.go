@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"soli/formations/src/lti/models"
+)
+
+// JWK is one RSA public key in JSON Web Key form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK renders key as a signing JWK.
+func PublicJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Alg: "RS256",
+		Use: "sig",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func (k JWK) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// toolKeyStore loads OCF's own signing key, creating it the first time.
+type toolKeyStore struct {
+	db  *gorm.DB
+	mu  sync.Mutex
+	kid string
+	key *rsa.PrivateKey
+}
+
+func (s *toolKeyStore) get() (string, *rsa.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		return s.kid, s.key, nil
+	}
+
+	var stored models.LtiToolKey
+	err := s.db.Order("id ASC").First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stored, err = s.create()
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load LTI tool key: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(stored.PrivateKeyPEM))
+	if block == nil {
+		return "", nil, fmt.Errorf("LTI tool key %s is not PEM encoded", stored.Kid)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse LTI tool key %s: %w", stored.Kid, err)
+	}
+	s.kid, s.key = stored.Kid, key
+	return s.kid, s.key, nil
+}
+
+func (s *toolKeyStore) create() (models.LtiToolKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return models.LtiToolKey{}, err
+	}
+	kid, err := randomToken(8)
+	if err != nil {
+		return models.LtiToolKey{}, err
+	}
+	privateKeyPEM := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+	stored := models.LtiToolKey{Kid: kid, PrivateKeyPEM: privateKeyPEM}
+	if err := s.db.Create(&stored).Error; err != nil {
+		return models.LtiToolKey{}, err
+	}
+	// BeforeSave encrypted the field in place.
+	stored.PrivateKeyPEM = privateKeyPEM
+	return stored, nil
+}
+
+// platformKeySetTTL is how long a platform's JWKS is trusted before it is
+// fetched again. An unknown kid triggers an early refetch, so a platform that
+// rotates its key is picked up on the next launch.
+const platformKeySetTTL = 10 * time.Minute
+
+type cachedKeySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// platformKeySets fetches and caches platform JWKS by URL.
+type platformKeySets struct {
+	client *http.Client
+	mu     sync.Mutex
+	sets   map[string]cachedKeySet
+}
+
+func (p *platformKeySets) key(url, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	cached, ok := p.sets[url]
+	p.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < platformKeySetTTL {
+		if key, found := cached.keys[kid]; found {
+			return key, nil
+		}
+	}
+
+	keys, err := p.fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.sets[url] = cachedKeySet{keys: keys, fetchedAt: time.Now()}
+	p.mu.Unlock()
+
+	key, found := keys[kid]
+	if !found {
+		return nil, fmt.Errorf("platform key set has no key %q", kid)
+	}
+	return key, nil
+}
+
+func (p *platformKeySets) fetch(url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch platform key set: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("platform key set returned HTTP %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid platform key set: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func randomToken(bytes int) (string, error) {
+	b := make([]byte, bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
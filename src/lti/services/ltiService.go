@@ -0,0 +1,614 @@
+package services
+
+// ltiService.go — OCF as an LTI 1.3 tool.
+//
+// A launch is three requests. The platform calls InitiateLogin; OCF answers
+// with a redirect to the platform's authorization endpoint carrying a fresh
+// state and nonce. The platform then form-posts a signed id_token to Launch,
+// which checks it against the platform's published keys, maps the course to a
+// class-group and the user to an OCF account, and hands back an OCF access
+// token for the frontend. Grades travel back later, through gradePassback.go.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	groupServices "soli/formations/src/groups/services"
+	"soli/formations/src/lti/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+// ErrUnknownPlatform is returned for a login or launch from an issuer/client
+// pair that is not registered, or whose registration is disabled.
+var ErrUnknownPlatform = errors.New("unknown LTI platform")
+
+// ErrInvalidLaunch is returned for a launch whose state, token or claims do not
+// pass validation. The wrapped message says which check failed.
+var ErrInvalidLaunch = errors.New("invalid LTI launch")
+
+// ErrDeepLinkNotFound is returned for a deep linking request that does not
+// exist, has expired, or belongs to another user.
+var ErrDeepLinkNotFound = errors.New("deep linking request not found")
+
+// launchStateTTL bounds the time between login initiation and launch. The
+// platform redirects straight back, so a few minutes is generous.
+const launchStateTTL = 10 * time.Minute
+
+// deepLinkTTL bounds the time an instructor has to pick a scenario.
+const deepLinkTTL = time.Hour
+
+// idTokenMaxAge bounds the age of a launch id_token. The platform signs it on
+// the redirect back to the tool, so it is never older than the login it
+// answers.
+const idTokenMaxAge = launchStateTTL
+
+// LaunchIdentity is the platform user behind a launch, as given to the
+// AccountProvisioner when an OCF account must be created for them.
+type LaunchIdentity struct {
+	Subject string
+	Name    string
+	Email   string
+}
+
+// AccountProvisioner creates OCF accounts for platform users and signs them in.
+// The production implementation talks to Casdoor; tests substitute a stub.
+type AccountProvisioner interface {
+	// CreateAccount creates the OCF account of a platform user launching for
+	// the first time and returns its user ID.
+	CreateAccount(platform *models.LtiPlatform, identity LaunchIdentity) (string, error)
+	// IssueAccessToken returns an OCF access token for userID.
+	IssueAccessToken(userID string) (string, error)
+}
+
+// LaunchResult is what the frontend needs after a successful launch.
+type LaunchResult struct {
+	MessageType string
+	UserID      string
+	AccessToken string
+	GroupID     *uuid.UUID
+	// ScenarioID is set for a resource link launch.
+	ScenarioID *uuid.UUID
+	// DeepLinkID is set for a deep linking launch: the pending request the
+	// instructor completes by picking a scenario.
+	DeepLinkID *uuid.UUID
+}
+
+// LtiService implements the tool side of LTI 1.3.
+type LtiService struct {
+	db       *gorm.DB
+	accounts AccountProvisioner
+	groups   groupServices.GroupService
+	toolURL  string
+	client   *http.Client
+	toolKeys *toolKeyStore
+	keySets  *platformKeySets
+	tokens   *agsTokenCache
+}
+
+// NewLtiService builds the LTI service. toolURL is the public base URL of this
+// API (LTI_TOOL_URL); the launch and JWKS URLs registered on the platform are
+// derived from it.
+func NewLtiService(db *gorm.DB, accounts AccountProvisioner, toolURL string) *LtiService {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &LtiService{
+		db:       db,
+		accounts: accounts,
+		groups:   groupServices.NewGroupService(db),
+		toolURL:  strings.TrimRight(toolURL, "/"),
+		client:   client,
+		toolKeys: &toolKeyStore{db: db},
+		keySets:  &platformKeySets{client: client, sets: map[string]cachedKeySet{}},
+		tokens:   &agsTokenCache{tokens: map[uuid.UUID]agsToken{}},
+	}
+}
+
+// ToolURLFromEnv returns LTI_TOOL_URL, the public base URL of this API.
+func ToolURLFromEnv() string {
+	return os.Getenv("LTI_TOOL_URL")
+}
+
+// LaunchURL is the redirect_uri and deep linking target registered on platforms.
+func (s *LtiService) LaunchURL() string {
+	return s.toolURL + "/api/v1/lti/launch"
+}
+
+// ToolJWKS is the public key set platforms verify OCF's messages with.
+func (s *LtiService) ToolJWKS() (*JWKS, error) {
+	kid, key, err := s.toolKeys.get()
+	if err != nil {
+		return nil, err
+	}
+	return &JWKS{Keys: []JWK{PublicJWK(kid, &key.PublicKey)}}, nil
+}
+
+// LoginRequest holds the parameters of an OIDC third-party login initiation.
+type LoginRequest struct {
+	Issuer          string `form:"iss"`
+	LoginHint       string `form:"login_hint"`
+	TargetLinkURI   string `form:"target_link_uri"`
+	LtiMessageHint  string `form:"lti_message_hint"`
+	ClientID        string `form:"client_id"`
+	LtiDeploymentID string `form:"lti_deployment_id"`
+}
+
+// InitiateLogin records a new state/nonce pair and returns the URL of the
+// platform's authorization endpoint the browser must be sent to.
+func (s *LtiService) InitiateLogin(req LoginRequest) (string, error) {
+	if req.Issuer == "" || req.LoginHint == "" {
+		return "", fmt.Errorf("%w: iss and login_hint are required", ErrInvalidLaunch)
+	}
+	platform, err := s.findPlatform(req.Issuer, req.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	stateValue, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	state := models.LtiLaunchState{
+		State:      stateValue,
+		Nonce:      nonce,
+		PlatformID: platform.ID,
+		ExpiresAt:  time.Now().Add(launchStateTTL),
+	}
+	if err := s.db.Create(&state).Error; err != nil {
+		return "", fmt.Errorf("failed to record launch state: %w", err)
+	}
+
+	authURL, err := url.Parse(platform.AuthLoginURL)
+	if err != nil {
+		return "", fmt.Errorf("platform %s has an invalid auth_login_url: %w", platform.ID, err)
+	}
+	query := authURL.Query()
+	query.Set("scope", "openid")
+	query.Set("response_type", "id_token")
+	query.Set("response_mode", "form_post")
+	query.Set("prompt", "none")
+	query.Set("client_id", platform.ClientID)
+	query.Set("redirect_uri", s.LaunchURL())
+	query.Set("login_hint", req.LoginHint)
+	query.Set("state", state.State)
+	query.Set("nonce", state.Nonce)
+	if req.LtiMessageHint != "" {
+		query.Set("lti_message_hint", req.LtiMessageHint)
+	}
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// findPlatform resolves an issuer (and the client ID, when the platform sent
+// one) to an active registration. Without a client ID the issuer must be
+// unambiguous.
+func (s *LtiService) findPlatform(issuer, clientID string) (*models.LtiPlatform, error) {
+	query := s.db.Where("issuer = ? AND is_active = ?", issuer, true)
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	var platforms []models.LtiPlatform
+	if err := query.Limit(2).Find(&platforms).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up platform: %w", err)
+	}
+	if len(platforms) != 1 {
+		return nil, ErrUnknownPlatform
+	}
+	return &platforms[0], nil
+}
+
+// Launch validates the id_token posted by the platform and carries out the
+// launch it describes.
+func (s *LtiService) Launch(stateValue, idToken string) (*LaunchResult, error) {
+	platform, claims, err := s.validateLaunch(stateValue, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	switch claims.MessageType {
+	case MessageTypeResourceLink:
+		return s.launchResourceLink(platform, claims)
+	case MessageTypeDeepLinkingRequest:
+		return s.launchDeepLinking(platform, claims)
+	}
+	return nil, fmt.Errorf("%w: unsupported message type %q", ErrInvalidLaunch, claims.MessageType)
+}
+
+// validateLaunch consumes the state and checks the id_token: signature against
+// the platform's key set, then issuer, audience, nonce, deployment and version.
+// The JWT parser only checks exp and iat when they are present, so their
+// presence and the token's age are checked here.
+func (s *LtiService) validateLaunch(stateValue, idToken string) (*models.LtiPlatform, *LaunchClaims, error) {
+	if stateValue == "" || idToken == "" {
+		return nil, nil, fmt.Errorf("%w: state and id_token are required", ErrInvalidLaunch)
+	}
+
+	var state models.LtiLaunchState
+	if err := s.db.Where("state = ?", stateValue).First(&state).Error; err != nil {
+		return nil, nil, fmt.Errorf("%w: unknown or already used state", ErrInvalidLaunch)
+	}
+	// Single use: of two launches racing on one state, only the one whose
+	// delete removed the row goes on.
+	consumed := s.db.Where("state = ?", stateValue).Delete(&models.LtiLaunchState{})
+	if consumed.Error != nil {
+		return nil, nil, fmt.Errorf("failed to consume launch state: %w", consumed.Error)
+	}
+	if consumed.RowsAffected == 0 {
+		return nil, nil, fmt.Errorf("%w: unknown or already used state", ErrInvalidLaunch)
+	}
+	if time.Now().After(state.ExpiresAt) {
+		return nil, nil, fmt.Errorf("%w: state has expired", ErrInvalidLaunch)
+	}
+
+	var platform models.LtiPlatform
+	if err := s.db.Where("id = ? AND is_active = ?", state.PlatformID, true).First(&platform).Error; err != nil {
+		return nil, nil, ErrUnknownPlatform
+	}
+
+	claims := &LaunchClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.keySets.key(platform.KeySetURL, kid)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidLaunch, err)
+	}
+
+	switch {
+	case claims.ExpiresAt == nil || claims.IssuedAt == nil:
+		return nil, nil, fmt.Errorf("%w: token must carry exp and iat", ErrInvalidLaunch)
+	case time.Since(claims.IssuedAt.Time) > idTokenMaxAge:
+		return nil, nil, fmt.Errorf("%w: token was issued too long ago", ErrInvalidLaunch)
+	case claims.Issuer != platform.Issuer:
+		return nil, nil, fmt.Errorf("%w: issuer does not match the platform", ErrInvalidLaunch)
+	case !claims.VerifyAudience(platform.ClientID, true):
+		return nil, nil, fmt.Errorf("%w: token is not addressed to this tool", ErrInvalidLaunch)
+	case len(claims.Audience) > 1 && claims.Azp != platform.ClientID:
+		return nil, nil, fmt.Errorf("%w: azp does not match the client ID", ErrInvalidLaunch)
+	case claims.Nonce != state.Nonce:
+		return nil, nil, fmt.Errorf("%w: nonce does not match", ErrInvalidLaunch)
+	case claims.DeploymentID != platform.DeploymentID:
+		return nil, nil, fmt.Errorf("%w: unknown deployment", ErrInvalidLaunch)
+	case claims.Version != ltiVersion:
+		return nil, nil, fmt.Errorf("%w: unsupported LTI version %q", ErrInvalidLaunch, claims.Version)
+	case claims.Subject == "":
+		return nil, nil, fmt.Errorf("%w: anonymous launches are not supported", ErrInvalidLaunch)
+	}
+	return &platform, claims, nil
+}
+
+// launchResourceLink opens a placed scenario: enrols the user in the course's
+// class-group, makes sure the scenario is assigned to it, and, for a learner on
+// a graded placement, registers them for grade passback.
+func (s *LtiService) launchResourceLink(platform *models.LtiPlatform, claims *LaunchClaims) (*LaunchResult, error) {
+	if claims.ResourceLink == nil || claims.ResourceLink.ID == "" {
+		return nil, fmt.Errorf("%w: resource link launch without a resource link", ErrInvalidLaunch)
+	}
+	if claims.Context == nil || claims.Context.ID == "" {
+		return nil, fmt.Errorf("%w: resource link launch without a course context", ErrInvalidLaunch)
+	}
+
+	userID, err := s.resolveUser(platform, claims)
+	if err != nil {
+		return nil, err
+	}
+	groupID, err := s.enrolInContext(platform, claims, userID)
+	if err != nil {
+		return nil, err
+	}
+	link, err := s.resolveResourceLink(platform, claims, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureAssignment(platform, link); err != nil {
+		return nil, err
+	}
+	if !claims.isInstructor() && link.LineItemURL != "" {
+		if err := s.registerForGrading(link, userID, claims.Subject); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := s.accounts.IssueAccessToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the user in: %w", err)
+	}
+	return &LaunchResult{
+		MessageType: MessageTypeResourceLink,
+		UserID:      userID,
+		AccessToken: token,
+		GroupID:     &groupID,
+		ScenarioID:  &link.ScenarioID,
+	}, nil
+}
+
+// launchDeepLinking starts a placement: the instructor is signed in and sent to
+// pick a scenario, which CompleteDeepLink returns to the platform.
+func (s *LtiService) launchDeepLinking(platform *models.LtiPlatform, claims *LaunchClaims) (*LaunchResult, error) {
+	if claims.DeepLinkingSettings == nil || claims.DeepLinkingSettings.ReturnURL == "" {
+		return nil, fmt.Errorf("%w: deep linking launch without a return URL", ErrInvalidLaunch)
+	}
+	if !acceptsResourceLinks(claims.DeepLinkingSettings.AcceptTypes) {
+		return nil, fmt.Errorf("%w: platform does not accept LTI resource links here", ErrInvalidLaunch)
+	}
+	if !claims.isInstructor() {
+		return nil, fmt.Errorf("%w: only instructors can place scenarios", ErrInvalidLaunch)
+	}
+
+	userID, err := s.resolveUser(platform, claims)
+	if err != nil {
+		return nil, err
+	}
+	var groupID *uuid.UUID
+	if claims.Context != nil && claims.Context.ID != "" {
+		id, err := s.enrolInContext(platform, claims, userID)
+		if err != nil {
+			return nil, err
+		}
+		groupID = &id
+	}
+
+	request := models.LtiDeepLinkRequest{
+		ID:           uuid.New(),
+		PlatformID:   platform.ID,
+		UserID:       userID,
+		DeploymentID: claims.DeploymentID,
+		ReturnURL:    claims.DeepLinkingSettings.ReturnURL,
+		Data:         claims.DeepLinkingSettings.Data,
+		ExpiresAt:    time.Now().Add(deepLinkTTL),
+	}
+	if err := s.db.Create(&request).Error; err != nil {
+		return nil, fmt.Errorf("failed to record deep linking request: %w", err)
+	}
+
+	token, err := s.accounts.IssueAccessToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the user in: %w", err)
+	}
+	return &LaunchResult{
+		MessageType: MessageTypeDeepLinkingRequest,
+		UserID:      userID,
+		AccessToken: token,
+		GroupID:     groupID,
+		DeepLinkID:  &request.ID,
+	}, nil
+}
+
+func acceptsResourceLinks(acceptTypes []string) bool {
+	for _, t := range acceptTypes {
+		if t == "ltiResourceLink" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveUser returns the OCF account linked to the platform user, creating
+// both on their first launch.
+//
+// Accounts are linked by (platform, sub) only, never by email: an email claim
+// is whatever the platform says it is, and matching on it would let any
+// registered platform sign in as an existing OCF user.
+func (s *LtiService) resolveUser(platform *models.LtiPlatform, claims *LaunchClaims) (string, error) {
+	var link models.LtiUserLink
+	err := s.db.Where("platform_id = ? AND subject = ?", platform.ID, claims.Subject).First(&link).Error
+	if err == nil {
+		return link.UserID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("failed to look up LTI user: %w", err)
+	}
+
+	userID, err := s.accounts.CreateAccount(platform, LaunchIdentity{
+		Subject: claims.Subject,
+		Name:    claims.displayName(),
+		Email:   claims.Email,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create account for LTI user: %w", err)
+	}
+	link = models.LtiUserLink{ID: uuid.New(), PlatformID: platform.ID, Subject: claims.Subject, UserID: userID}
+	if err := s.db.Create(&link).Error; err != nil {
+		return "", fmt.Errorf("failed to link LTI user: %w", err)
+	}
+	return userID, nil
+}
+
+// enrolInContext returns the class-group of the launch's course, creating it on
+// the course's first launch, and makes the user an active member of it: a
+// manager if they teach the course, a member otherwise.
+func (s *LtiService) enrolInContext(platform *models.LtiPlatform, claims *LaunchClaims, userID string) (uuid.UUID, error) {
+	groupID, err := s.contextGroup(platform, claims.Context)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	role := groupModels.GroupMemberRoleMember
+	if claims.isInstructor() {
+		role = groupModels.GroupMemberRoleManager
+	}
+
+	var member groupModels.GroupMember
+	err = s.db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := s.groups.EnrolMember(groupID, userID, role, platform.OwnerUserID); err != nil {
+			return uuid.Nil, err
+		}
+	case err != nil:
+		return uuid.Nil, fmt.Errorf("failed to look up group membership: %w", err)
+	case !member.IsActive || (role == groupModels.GroupMemberRoleManager && member.Role == groupModels.GroupMemberRoleMember):
+		// The platform is the roster's source of truth: a learner promoted to
+		// instructor there is promoted here. Demotions are left to OCF staff,
+		// so a mis-set platform role cannot strip a teacher of their class.
+		updates := map[string]any{"is_active": true}
+		if member.Role == groupModels.GroupMemberRoleMember {
+			updates["role"] = role
+		}
+		if err := s.db.Model(&member).Updates(updates).Error; err != nil {
+			return uuid.Nil, fmt.Errorf("failed to update group membership: %w", err)
+		}
+	}
+	return groupID, nil
+}
+
+func (s *LtiService) contextGroup(platform *models.LtiPlatform, context *ContextClaim) (uuid.UUID, error) {
+	var link models.LtiContextLink
+	err := s.db.Where("platform_id = ? AND context_id = ?", platform.ID, context.ID).First(&link).Error
+	if err == nil {
+		return link.GroupID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, fmt.Errorf("failed to look up LTI context: %w", err)
+	}
+
+	displayName := context.Title
+	if displayName == "" {
+		displayName = context.Label
+	}
+	if displayName == "" {
+		displayName = context.ID
+	}
+	group := groupModels.ClassGroup{
+		Name:           fmt.Sprintf("lti-%s-%s", platform.ID.String()[:8], context.ID),
+		DisplayName:    displayName,
+		Description:    fmt.Sprintf("Course imported from %s", platform.Name),
+		OwnerUserID:    platform.OwnerUserID,
+		OrganizationID: platform.OrganizationID,
+		MaxMembers:     0,
+		IsActive:       true,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Metadata").Create(&group).Error; err != nil {
+			return err
+		}
+		link = models.LtiContextLink{ID: uuid.New(), PlatformID: platform.ID, ContextID: context.ID, GroupID: group.ID}
+		return tx.Create(&link).Error
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create class-group for LTI context: %w", err)
+	}
+	return group.ID, nil
+}
+
+// resolveResourceLink returns the placement launched, recording it on its
+// first launch from the scenario_id custom parameter deep linking set on it.
+// That parameter can be edited in the LMS, so the scenario it names must be
+// one deep linking would have offered the launching user.
+// The line item URL is refreshed on every launch: platforms may recreate it.
+func (s *LtiService) resolveResourceLink(platform *models.LtiPlatform, claims *LaunchClaims, userID string, groupID uuid.UUID) (*models.LtiResourceLink, error) {
+	lineItemURL := ""
+	if claims.AGSEndpoint != nil && claims.AGSEndpoint.LineItem != "" && hasScope(claims.AGSEndpoint.Scope, scopeScore) {
+		lineItemURL = claims.AGSEndpoint.LineItem
+	}
+
+	var link models.LtiResourceLink
+	err := s.db.Where("platform_id = ? AND resource_link_id = ?", platform.ID, claims.ResourceLink.ID).First(&link).Error
+	if err == nil {
+		if lineItemURL != "" && lineItemURL != link.LineItemURL {
+			if err := s.db.Model(&link).Update("line_item_url", lineItemURL).Error; err != nil {
+				return nil, fmt.Errorf("failed to update line item: %w", err)
+			}
+		}
+		return &link, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to look up resource link: %w", err)
+	}
+
+	scenarioID, err := uuid.Parse(claims.Custom[customScenarioID])
+	if err != nil {
+		return nil, fmt.Errorf("%w: resource link does not name a scenario", ErrInvalidLaunch)
+	}
+	var scenario scenarioModels.Scenario
+	if err := s.placeableScenarios(platform.ID, userID).Where("id = ?", scenarioID).First(&scenario).Error; err != nil {
+		return nil, fmt.Errorf("%w: scenario %s is not available", ErrInvalidLaunch, scenarioID)
+	}
+
+	link = models.LtiResourceLink{
+		ID:             uuid.New(),
+		PlatformID:     platform.ID,
+		ResourceLinkID: claims.ResourceLink.ID,
+		ScenarioID:     scenario.ID,
+		GroupID:        groupID,
+		LineItemURL:    lineItemURL,
+	}
+	if err := s.db.Create(&link).Error; err != nil {
+		return nil, fmt.Errorf("failed to record resource link: %w", err)
+	}
+	return &link, nil
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureAssignment assigns the placed scenario to the course's class-group, so
+// the teacher dashboard and gradebook see LTI learners like any other class.
+func (s *LtiService) ensureAssignment(platform *models.LtiPlatform, link *models.LtiResourceLink) error {
+	var count int64
+	if err := s.db.Model(&scenarioModels.ScenarioAssignment{}).
+		Where("scenario_id = ? AND group_id = ? AND scope = ? AND is_active = ?", link.ScenarioID, link.GroupID, "group", true).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check scenario assignment: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	groupID := link.GroupID
+	assignment := scenarioModels.ScenarioAssignment{
+		ScenarioID:  link.ScenarioID,
+		GroupID:     &groupID,
+		Scope:       "group",
+		CreatedByID: platform.OwnerUserID,
+		IsActive:    true,
+	}
+	if err := s.db.Create(&assignment).Error; err != nil {
+		return fmt.Errorf("failed to assign scenario to LTI course: %w", err)
+	}
+	return nil
+}
+
+func (s *LtiService) registerForGrading(link *models.LtiResourceLink, userID, subject string) error {
+	var existing models.LtiGradeLink
+	err := s.db.Where("resource_link_id = ? AND user_id = ?", link.ID, userID).First(&existing).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to look up grade link: %w", err)
+	}
+	gradeLink := models.LtiGradeLink{ID: uuid.New(), ResourceLinkID: link.ID, UserID: userID, Subject: subject}
+	if err := s.db.Create(&gradeLink).Error; err != nil {
+		return fmt.Errorf("failed to register for grade passback: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired removes launch states and deep linking requests past their
+// expiry. Run by the passback job, which already ticks.
+func (s *LtiService) PurgeExpired() error {
+	now := time.Now()
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.LtiLaunchState{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("expires_at < ?", now).Delete(&models.LtiDeepLinkRequest{}).Error
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/lti/dto"
+	"soli/formations/src/lti/models"
+)
+
+// ErrPlatformNotFound is returned for an unknown platform ID.
+var ErrPlatformNotFound = errors.New("LTI platform not found")
+
+func platformToOutput(p *models.LtiPlatform) dto.PlatformOutput {
+	return dto.PlatformOutput{
+		ID:             p.ID,
+		Name:           p.Name,
+		Issuer:         p.Issuer,
+		ClientID:       p.ClientID,
+		DeploymentID:   p.DeploymentID,
+		AuthLoginURL:   p.AuthLoginURL,
+		AuthTokenURL:   p.AuthTokenURL,
+		KeySetURL:      p.KeySetURL,
+		OrganizationID: p.OrganizationID,
+		OwnerUserID:    p.OwnerUserID,
+		IsActive:       p.IsActive,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+// ToolConfiguration returns the URLs to enter on the platform side.
+func (s *LtiService) ToolConfiguration() dto.ToolConfigurationOutput {
+	return dto.ToolConfigurationOutput{
+		LoginURL:       s.toolURL + "/api/v1/lti/login",
+		LaunchURL:      s.LaunchURL(),
+		DeepLinkingURL: s.LaunchURL(),
+		KeySetURL:      s.toolURL + "/api/v1/lti/jwks",
+	}
+}
+
+// ListPlatforms returns every registered platform.
+func (s *LtiService) ListPlatforms() ([]dto.PlatformOutput, error) {
+	var platforms []models.LtiPlatform
+	if err := s.db.Order("name ASC").Find(&platforms).Error; err != nil {
+		return nil, fmt.Errorf("failed to list LTI platforms: %w", err)
+	}
+	outputs := make([]dto.PlatformOutput, 0, len(platforms))
+	for i := range platforms {
+		outputs = append(outputs, platformToOutput(&platforms[i]))
+	}
+	return outputs, nil
+}
+
+// CreatePlatform registers a platform. createdBy owns the class-groups of its
+// courses unless the input names another owner.
+func (s *LtiService) CreatePlatform(input dto.CreatePlatformInput, createdBy string) (*dto.PlatformOutput, error) {
+	owner := input.OwnerUserID
+	if owner == "" {
+		owner = createdBy
+	}
+	platform := models.LtiPlatform{
+		Name:           input.Name,
+		Issuer:         input.Issuer,
+		ClientID:       input.ClientID,
+		DeploymentID:   input.DeploymentID,
+		AuthLoginURL:   input.AuthLoginURL,
+		AuthTokenURL:   input.AuthTokenURL,
+		KeySetURL:      input.KeySetURL,
+		OrganizationID: input.OrganizationID,
+		OwnerUserID:    owner,
+		IsActive:       true,
+	}
+	if err := s.db.Create(&platform).Error; err != nil {
+		return nil, fmt.Errorf("failed to register LTI platform: %w", err)
+	}
+	output := platformToOutput(&platform)
+	return &output, nil
+}
+
+// UpdatePlatform applies the non-nil fields of input to a platform.
+func (s *LtiService) UpdatePlatform(id uuid.UUID, input dto.UpdatePlatformInput) (*dto.PlatformOutput, error) {
+	var platform models.LtiPlatform
+	if err := s.db.Where("id = ?", id).First(&platform).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlatformNotFound
+		}
+		return nil, err
+	}
+
+	updates := map[string]any{}
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.DeploymentID != nil {
+		updates["deployment_id"] = *input.DeploymentID
+	}
+	if input.AuthLoginURL != nil {
+		updates["auth_login_url"] = *input.AuthLoginURL
+	}
+	if input.AuthTokenURL != nil {
+		updates["auth_token_url"] = *input.AuthTokenURL
+	}
+	if input.KeySetURL != nil {
+		updates["key_set_url"] = *input.KeySetURL
+	}
+	if input.IsActive != nil {
+		updates["is_active"] = *input.IsActive
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(&platform).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update LTI platform: %w", err)
+		}
+		if err := s.db.Where("id = ?", id).First(&platform).Error; err != nil {
+			return nil, err
+		}
+	}
+	output := platformToOutput(&platform)
+	return &output, nil
+}
+
+// DeletePlatform removes a registration. The class-groups and accounts its
+// launches created are kept: they hold learners' work.
+func (s *LtiService) DeletePlatform(id uuid.UUID) error {
+	result := s.db.Unscoped().Where("id = ?", id).Delete(&models.LtiPlatform{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete LTI platform: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPlatformNotFound
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package observability
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPRequestDuration is the latency of the generic entity routes, per entity,
+// method, route template and status code.
+var HTTPRequestDuration = NewHistogramVec(
+	"ocf_http_request_duration_seconds",
+	"Latency of the generic entity routes.",
+	DurationBuckets, "entity", "method", "route", "status",
+)
+
+// HookDuration is the execution time of entity hooks run by the hook registry.
+var HookDuration = NewHistogramVec(
+	"ocf_hook_duration_seconds",
+	"Execution time of entity hooks.",
+	DurationBuckets, "hook", "entity", "hook_type", "result",
+)
+
+// InstrumentRoute records the latency of the routes of entity. The route label
+// is gin's route template (/api/v1/courses/:id), never the raw path, so the
+// number of series stays bounded by the number of routes.
+func InstrumentRoute(entity string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(),
+			entity, c.Request.Method, c.FullPath(), strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// WritePrometheus writes the in-process metrics: the Metrics counters and the
+// latency histograms. Gauges read from the database are written by the
+// /metrics handler, which has the db handle.
+func WritePrometheus(w io.Writer) {
+	m := Metrics
+
+	stripeOps := func(op string, success, failure uint64) []Sample {
+		return []Sample{
+			{Labels: []Label{{"operation", op}, {"result", "success"}}, Value: float64(success)},
+			{Labels: []Label{{"operation", op}, {"result", "failure"}}, Value: float64(failure)},
+		}
+	}
+	var ops []Sample
+	ops = append(ops, stripeOps("create", m.StripeCreateSuccess.Load(), m.StripeCreateFailure.Load())...)
+	ops = append(ops, stripeOps("update", m.StripeUpdateSuccess.Load(), m.StripeUpdateFailure.Load())...)
+	ops = append(ops, stripeOps("archive", m.StripeArchiveSuccess.Load(), m.StripeArchiveFailure.Load())...)
+	WriteFamily(w, "ocf_stripe_sync_operations_total", "counter",
+		"Stripe product sync operations, by operation and result.", ops...)
+	writeCounter(w, "ocf_stripe_sync_panics_total",
+		"Panics recovered in the Stripe sync worker.", m.StripeSyncPanic.Load())
+	writeCounter(w, "ocf_stripe_sync_queue_retries_total",
+		"Stripe sync queue rows scheduled for another attempt.", m.StripeQueueRetry.Load())
+	writeCounter(w, "ocf_stripe_sync_queue_exhausted_total",
+		"Stripe sync queue rows that ran out of attempts.", m.StripeQueueExhausted.Load())
+	WriteFamily(w, "ocf_stripe_sync_queue_depth", "gauge",
+		"Stripe sync queue rows still pending after the worker's last pass.",
+		Sample{Value: float64(m.StripeQueuePendingDepth.Load())})
+
+	writeCounter(w, "ocf_scenario_setup_panics_total",
+		"Panics recovered during scenario session setup.", m.ScenarioSetupPanic.Load())
+	writeCounter(w, "ocf_scenario_setup_failures_total",
+		"Scenario sessions moved to setup_failed.", m.ScenarioSetupFailed.Load())
+	writeCounter(w, "ocf_terminal_stop_on_cleanup_failures_total",
+		"Terminals that could not be stopped when their scenario session was cleaned up.", m.TerminalStopOnCleanupFailure.Load())
+	writeCounter(w, "ocf_scenario_effects_unsupported_total",
+		"Scenarios with step banners started on an image without tte.", m.ScenarioEffectsUnsupported.Load())
+	writeCounter(w, "ocf_scenario_step_provisioning_failures_total",
+		"Failed mid-scenario step provisionings.", m.ScenarioStepProvisioningFailed.Load())
+	writeCounter(w, "ocf_scenario_step_provisioning_panics_total",
+		"Panics recovered during mid-scenario step provisioning.", m.ScenarioStepProvisioningPanic.Load())
+
+	WriteFamily(w, "ocf_webhook_delivery_attempts_total", "counter",
+		"Outbound webhook delivery attempts, by result.",
+		Sample{Labels: []Label{{"result", "success"}}, Value: float64(m.WebhookDeliverySuccess.Load())},
+		Sample{Labels: []Label{{"result", "failure"}}, Value: float64(m.WebhookDeliveryFailure.Load())})
+	writeCounter(w, "ocf_webhook_deliveries_dead_total",
+		"Outbound webhook deliveries that ran out of attempts.", m.WebhookDeliveryDead.Load())
+	WriteFamily(w, "ocf_webhook_queue_depth", "gauge",
+		"Outbound webhook deliveries still pending after the worker's last pass.",
+		Sample{Value: float64(m.WebhookQueuePendingDepth.Load())})
+
+	HTTPRequestDuration.Write(w)
+	HookDuration.Write(w)
+}
+
+func writeCounter(w io.Writer, name, help string, value uint64) {
+	WriteFamily(w, name, "counter", help, Sample{Value: float64(value)})
+}
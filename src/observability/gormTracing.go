@@ -0,0 +1,85 @@
+package observability
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "observability:span"
+
+// RegisterGormTracing adds a span around every statement db runs, named after
+// the operation and table ("gorm.query terminals").
+//
+// A statement is traced only when its context already carries a span, i.e.
+// when it was issued through db.WithContext(ctx) inside a traced request or
+// job: untraced background queries would otherwise each become a one-span
+// trace and drown the useful ones. The SQL is recorded without its bound
+// values.
+func RegisterGormTracing(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, op := range []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	} {
+		if err := op.before("observability:before_"+op.name, startGormSpan(op.name)); err != nil {
+			return err
+		}
+		if err := op.after("observability:after_"+op.name, endGormSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startGormSpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+		name := "gorm." + operation
+		if tx.Statement.Table != "" {
+			name += " " + tx.Statement.Table
+		}
+		_, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameKey.String(tx.Dialector.Name()),
+				semconv.DBCollectionName(tx.Statement.Table),
+				semconv.DBOperationName(operation),
+			),
+		)
+		tx.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endGormSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+		attribute.Int64("db.response.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && tx.Error != gorm.ErrRecordNotFound {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+	span.End()
+}
@@ -42,6 +42,15 @@ type observabilityMetrics struct {
 	// These were previously silent: the sync branch swallowed its error.
 	ScenarioStepProvisioningFailed atomic.Uint64
 	ScenarioStepProvisioningPanic  atomic.Uint64
+
+	// Outbound organization webhooks
+	// - Success / Failure: one per delivery attempt
+	// - Dead: bumped when a delivery runs out of attempts
+	// - PendingDepth: gauge updated by the worker after each pass
+	WebhookDeliverySuccess   atomic.Uint64
+	WebhookDeliveryFailure   atomic.Uint64
+	WebhookDeliveryDead      atomic.Uint64
+	WebhookQueuePendingDepth atomic.Uint64
 }
 
 // Metrics is the global observability metrics singleton, accessed by both
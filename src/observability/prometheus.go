@@ -0,0 +1,173 @@
+package observability
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prometheus text exposition (format 0.0.4, which OpenMetrics scrapers also
+// accept). Written by hand rather than through client_golang: the counters
+// already live in Metrics, and the format for counters, gauges and
+// fixed-bucket histograms is a few lines of text.
+//
+// Counters restart from zero with the process; Prometheus' rate() and
+// increase() treat a drop as a reset, so alerts stay correct across deploys.
+
+// PrometheusContentType is the Content-Type of the /metrics response.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DurationBuckets are the upper bounds, in seconds, of the latency histograms:
+// from a cached read to a slow Stripe or tt-backend round trip.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Label is one name="value" pair of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one value of a metric family.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// WriteFamily writes a counter or gauge family: its HELP and TYPE lines, then
+// one line per sample.
+func WriteFamily(w io.Writer, name, metricType, help string, samples ...Sample) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, metricType)
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(s.Labels), formatValue(s.Value))
+	}
+}
+
+// HistogramVec is a histogram partitioned by label values, with the same
+// buckets for every series.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // per bucket, not cumulative
+	sum         float64
+	count       uint64
+}
+
+// NewHistogramVec returns an empty histogram family named name, partitioned by
+// labelNames.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labelNames,
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+}
+
+// Observe records value in the series of labelValues, given in the order of
+// the label names.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Reset drops every series. Used by tests.
+func (h *HistogramVec) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.series = make(map[string]*histogramSeries)
+}
+
+// Write writes the family in exposition format, series sorted by labels so
+// successive scrapes diff cleanly.
+func (h *HistogramVec) Write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, escapeHelp(h.help), h.name)
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+		labels := make([]Label, len(h.labels), len(h.labels)+1)
+		for i, name := range h.labels {
+			labels[i] = Label{Name: name, Value: s.labelValues[i]}
+		}
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			le := append(labels, Label{Name: "le", Value: formatValue(bound)})
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(le), cumulative)
+		}
+		inf := append(labels, Label{Name: "le", Value: "+Inf"})
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(inf), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(labels), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(labels), s.count)
+	}
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Name + `="` + escapeLabelValue(l.Value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string { return labelValueEscaper.Replace(v) }
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(v string) string { return helpEscaper.Replace(v) }
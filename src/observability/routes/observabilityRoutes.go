@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"log"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
@@ -17,3 +19,16 @@ func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB) {
 	admin := router.Group("/admin")
 	admin.GET("/observability-metrics", mw.AuthManagement(), NewObservabilityHandler())
 }
+
+// RegisterMetricsRoute mounts the Prometheus /metrics endpoint at the root of
+// the server, outside /api/v1 where scrapers expect it and where neither
+// AuthManagement nor Layer 2 applies. With no METRICS_BEARER_TOKEN set the
+// endpoint is not mounted at all.
+func RegisterMetricsRoute(router *gin.Engine, db *gorm.DB) {
+	token := MetricsTokenFromEnv()
+	if token == "" {
+		log.Println("METRICS_BEARER_TOKEN not set: /metrics is disabled")
+		return
+	}
+	router.GET("/metrics", NewPrometheusHandler(db, token))
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"soli/formations/src/observability"
+	terminalModels "soli/formations/src/terminalTrainer/models"
+)
+
+// MetricsTokenFromEnv returns the bearer token Prometheus must present to
+// scrape /metrics (METRICS_BEARER_TOKEN). Empty means the endpoint is off.
+func MetricsTokenFromEnv() string {
+	return strings.TrimSpace(os.Getenv("METRICS_BEARER_TOKEN"))
+}
+
+// NewPrometheusHandler returns the /metrics handler: the observability
+// counters and histograms, plus gauges read from the database at scrape time.
+//
+// Scrapers cannot log in through Casdoor, so the endpoint is authenticated by
+// a static bearer token instead of AuthManagement, compared in constant time.
+func NewPrometheusHandler(db *gorm.DB, token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+			return
+		}
+
+		var buf bytes.Buffer
+		observability.WritePrometheus(&buf)
+		writeDatabaseGauges(&buf, db)
+		c.Data(http.StatusOK, observability.PrometheusContentType, buf.Bytes())
+	}
+}
+
+// writeDatabaseGauges writes the gauges that are state, not events: running
+// terminals per backend and scenario sessions per status. A failed query
+// drops its family from this scrape rather than failing the whole response —
+// the counters are still worth having when the database is the problem.
+func writeDatabaseGauges(buf *bytes.Buffer, db *gorm.DB) {
+	var terminals []struct {
+		Backend string
+		Count   int64
+	}
+	if err := db.Table("terminals").Scopes(terminalModels.RunningDisplayScope).
+		Select("terminals.backend AS backend, COUNT(*) AS count").
+		Group("terminals.backend").
+		Scan(&terminals).Error; err != nil {
+		slog.Error("metrics: failed to count active terminals", "err", err)
+	} else {
+		samples := make([]observability.Sample, 0, len(terminals))
+		for _, row := range terminals {
+			backend := row.Backend
+			if backend == "" {
+				backend = "default"
+			}
+			samples = append(samples, observability.Sample{
+				Labels: []observability.Label{{Name: "backend", Value: backend}},
+				Value:  float64(row.Count),
+			})
+		}
+		observability.WriteFamily(buf, "ocf_terminals_active", "gauge",
+			"Running terminals, by backend.", samples...)
+	}
+
+	var sessions []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Table("scenario_sessions").
+		Select("status, COUNT(*) AS count").
+		Where("deleted_at IS NULL").
+		Group("status").
+		Scan(&sessions).Error; err != nil {
+		slog.Error("metrics: failed to count scenario sessions", "err", err)
+	} else {
+		samples := make([]observability.Sample, 0, len(sessions))
+		for _, row := range sessions {
+			samples = append(samples, observability.Sample{
+				Labels: []observability.Label{{Name: "status", Value: row.Status}},
+				Value:  float64(row.Count),
+			})
+		}
+		observability.WriteFamily(buf, "ocf_scenario_sessions", "gauge",
+			"Scenario sessions, by status.", samples...)
+	}
+}
@@ -0,0 +1,173 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetry tracing. Spans are created through the global tracer
+// provider, which is a no-op until InitTracing installs an exporting one, so
+// instrumented code costs nothing when tracing is off.
+//
+// Much of ocf-core does not thread a context.Context (services take IDs, not
+// contexts), so a span only nests under the request span where a context is
+// available: the gin middleware, the worker client, HookContext.Context, a
+// gorm statement's context. tt-backend calls and container execs made without
+// one start their own trace, tagged with the session they belong to.
+
+const tracerName = "soli/formations"
+
+// Tracer returns the tracer of ocf-core, resolved through the global provider
+// at each call so a provider installed later (or by a test) is picked up.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// TracingEnabled reports whether an OTLP endpoint is configured, through the
+// standard OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_TRACES_ENDPOINT.
+func TracingEnabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// InitTracing installs an OTLP/HTTP exporting tracer provider when an endpoint
+// is configured, and returns the function flushing it on shutdown. The
+// exporter reads the standard OTEL_EXPORTER_OTLP_* variables (endpoint,
+// headers, TLS); OTEL_SERVICE_NAME defaults to "ocf-core".
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	if !TracingEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	tp := NewTracerProvider(sdktrace.WithBatcher(exporter))
+	InstallTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider returns a tracer provider describing this service, with
+// the given span processors (a batcher in production, a syncer over an
+// in-memory exporter in tests).
+func NewTracerProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "ocf-core"
+	}
+	res := resource.NewSchemaless(semconv.ServiceName(serviceName))
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(res)}, opts...)...)
+}
+
+// InstallTracerProvider makes tp the global provider, with W3C trace context
+// propagation so traces continue across the frontend, ocf-core and tt-backend.
+func InstallTracerProvider(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// StartSpan starts an internal span under ctx; a nil ctx starts a new trace.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err, if any, on span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracingMiddleware starts a server span per request, continuing the caller's
+// trace when it sends a traceparent header. The span is named after gin's
+// route template, and the request carries its context to the handlers.
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+	}
+}
+
+// tracingTransport starts a client span around each outgoing request and
+// propagates the trace to the callee.
+type tracingTransport struct {
+	base    http.RoundTripper
+	service string
+}
+
+// NewTracingTransport wraps base (http.DefaultTransport when nil) so every
+// request to service is traced.
+func NewTracingTransport(base http.RoundTripper, service string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{base: base, service: service}
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(req.Context(), t.service+" "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			// Path only: query strings may carry tokens.
+			semconv.URLPath(req.URL.Path),
+			attribute.String("peer.service", t.service),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -600,6 +600,7 @@ func (s *importService) addUserToOrganization(userID string, orgID uuid.UUID) er
 	if err := s.db.Create(&newMember).Error; err != nil {
 		return fmt.Errorf("failed to add user to organization: %w", err)
 	}
+	publishMemberAdded(s.db, &newMember)
 
 	// Add Casbin permission for organization
 	opts := utils.DefaultPermissionOptions()
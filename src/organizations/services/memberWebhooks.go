@@ -0,0 +1,28 @@
+package services
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"soli/formations/src/organizations/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+// memberEventData is the "data" of the organization_member.added webhook.
+type memberEventData struct {
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invited_by,omitempty"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// publishMemberAdded raises the organization_member.added webhook.
+func publishMemberAdded(db *gorm.DB, member *models.OrganizationMember) {
+	webhookServices.Emit(db, &member.OrganizationID, webhookServices.EventOrganizationMemberAdded, memberEventData{
+		UserID:    member.UserID,
+		Role:      string(member.Role),
+		InvitedBy: member.InvitedBy,
+		JoinedAt:  member.JoinedAt,
+	})
+}
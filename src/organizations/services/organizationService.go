@@ -406,6 +406,7 @@ func (os *organizationService) AddMembersToOrganization(orgID uuid.UUID, request
 		}
 
 		utils.Info("User %s added to organization %s with role %s", userID, orgID, role)
+		publishMemberAdded(os.db, member)
 	}
 
 	return nil
@@ -398,6 +398,7 @@ func (s *bulkLicenseService) AssignLicense(batchID uuid.UUID, requestingUserID s
 	if batch.GroupID != nil {
 		s.autoAddUserToGroup(*batch.GroupID, batch.PurchaserUserID, targetUserID)
 	}
+	s.publishLicenseAssigned(batch, &assignedLicense, requestingUserID)
 
 	return &assignedLicense, nil
 }
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"github.com/google/uuid"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/payment/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+// licenseEventData is the "data" of the license.assigned webhook.
+type licenseEventData struct {
+	LicenseID  uuid.UUID  `json:"license_id"`
+	BatchID    uuid.UUID  `json:"batch_id"`
+	PlanID     uuid.UUID  `json:"plan_id"`
+	UserID     string     `json:"user_id"`
+	AssignedBy string     `json:"assigned_by"`
+	GroupID    *uuid.UUID `json:"group_id,omitempty"`
+}
+
+// publishLicenseAssigned raises the license.assigned webhook. A batch belongs
+// to an organization only through its linked group, so a batch without one
+// raises nothing.
+func (s *bulkLicenseService) publishLicenseAssigned(batch *models.SubscriptionBatch, license *models.UserSubscription, assignedBy string) {
+	if batch.GroupID == nil {
+		return
+	}
+	var group groupModels.ClassGroup
+	if err := s.db.Select("id", "organization_id").First(&group, "id = ?", *batch.GroupID).Error; err != nil {
+		return
+	}
+	webhookServices.Emit(s.db, group.OrganizationID, webhookServices.EventLicenseAssigned, licenseEventData{
+		LicenseID:  license.ID,
+		BatchID:    batch.ID,
+		PlanID:     license.SubscriptionPlanID,
+		UserID:     license.UserID,
+		AssignedBy: assignedBy,
+		GroupID:    batch.GroupID,
+	})
+}
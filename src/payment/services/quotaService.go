@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 
+	"soli/formations/src/observability"
 	"soli/formations/src/payment/catalog"
 	paymentDto "soli/formations/src/payment/dto"
 	"soli/formations/src/payment/models"
@@ -13,6 +14,7 @@ import (
 	"soli/formations/src/utils"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -139,6 +141,10 @@ type QuotaService interface {
 	// BudgetEnforcement carries the BudgetCheck verdict plus the summed
 	// UsedCPU/UsedMemMB, so callers can construct their own rejection
 	// error (e.g. the hook's ErrBudgetExhausted) without re-querying.
+	//
+	// requestedCPU/requestedMemMB are the whole request: a multi-node
+	// scenario passes the sum of its nodes, so it is admitted or refused as
+	// one, never with only some of its machines fitting.
 	EnforceBudgetTx(tx *gorm.DB, userID string, orgID *uuid.UUID, plan *models.SubscriptionPlan, requestedCPU, requestedMemMB int) (*BudgetEnforcement, error)
 }
 
@@ -625,20 +631,26 @@ func acquireScopeAdvisoryLock(tx *gorm.DB, userID string, orgID *uuid.UUID) erro
 	if !supportsRowLock(tx) {
 		return nil
 	}
-	scopeKey := userID
+	scopeKey, scopeKind := userID, "user"
 	if orgID != nil {
-		scopeKey = orgID.String()
+		scopeKey, scopeKind = orgID.String(), "organization"
 	}
+	// The wait on this lock is the cost of a concurrent launch in the same
+	// scope, which is why it gets a span of its own.
+	ctx, span := observability.StartSpan(tx.Statement.Context, "quota.advisory_lock",
+		attribute.String("quota.scope", scopeKind))
 	// hashtext(text) returns int4, so the single-arg pg_advisory_xact_lock
 	// collapses the key into 32-bit advisory space — two distinct scopes may
 	// rarely collide onto the same lock. That only ever over-serializes (a
 	// false sharing makes two unrelated scopes wait on each other); it never
 	// lets a real conflict through, so it is correctness-safe. Chosen over a
 	// 64-bit key (hashtextextended / two-arg int4,int4) for simplicity.
-	return tx.Exec(
+	err := tx.WithContext(ctx).Exec(
 		"SELECT pg_advisory_xact_lock(hashtext(?))",
 		"terminal_budget:"+scopeKey,
 	).Error
+	observability.EndSpan(span, err)
+	return err
 }
 
 // lockAndSumActiveResources sums the CPU + RAM footprint of the rows that
@@ -15,6 +15,17 @@ type CreateScenarioAssignmentInput struct {
 	StartDate      *time.Time `json:"start_date,omitempty" mapstructure:"start_date"`
 	Deadline       *time.Time `json:"deadline,omitempty" mapstructure:"deadline"`
 	IsActive       bool       `json:"is_active,omitempty" mapstructure:"is_active"`
+
+	// Exam mode requires StartDate, which opens the start window, and a time limit.
+	ExamMode         bool       `json:"exam_mode,omitempty" mapstructure:"exam_mode"`
+	TimeLimitMinutes int        `json:"time_limit_minutes,omitempty" mapstructure:"time_limit_minutes" binding:"omitempty,min=1"`
+	StartWindowEnd   *time.Time `json:"start_window_end,omitempty" mapstructure:"start_window_end"`
+
+	// Late policy: block (default), penalty or flag. A penalty policy requires
+	// a percentage to take off per day late.
+	LatePolicy               string  `json:"late_policy,omitempty" mapstructure:"late_policy" binding:"omitempty,oneof=block penalty flag"`
+	LatePenaltyPercentPerDay float64 `json:"late_penalty_percent_per_day,omitempty" mapstructure:"late_penalty_percent_per_day" binding:"omitempty,gt=0,max=100"`
+	ReminderHoursBefore      int     `json:"reminder_hours_before,omitempty" mapstructure:"reminder_hours_before" binding:"omitempty,min=1,max=720"`
 }
 
 // EditScenarioAssignmentInput - DTO for editing a scenario assignment (partial updates)
@@ -22,6 +33,14 @@ type EditScenarioAssignmentInput struct {
 	StartDate *time.Time `json:"start_date,omitempty" mapstructure:"start_date"`
 	Deadline  *time.Time `json:"deadline,omitempty" mapstructure:"deadline"`
 	IsActive  *bool      `json:"is_active,omitempty" mapstructure:"is_active"`
+
+	ExamMode         *bool      `json:"exam_mode,omitempty" mapstructure:"exam_mode"`
+	TimeLimitMinutes *int       `json:"time_limit_minutes,omitempty" mapstructure:"time_limit_minutes" binding:"omitempty,min=1"`
+	StartWindowEnd   *time.Time `json:"start_window_end,omitempty" mapstructure:"start_window_end"`
+
+	LatePolicy               *string  `json:"late_policy,omitempty" mapstructure:"late_policy" binding:"omitempty,oneof=block penalty flag"`
+	LatePenaltyPercentPerDay *float64 `json:"late_penalty_percent_per_day,omitempty" mapstructure:"late_penalty_percent_per_day" binding:"omitempty,gt=0,max=100"`
+	ReminderHoursBefore      *int     `json:"reminder_hours_before,omitempty" mapstructure:"reminder_hours_before" binding:"omitempty,min=0,max=720"`
 }
 
 // ScenarioAssignmentOutput - DTO for scenario assignment responses
@@ -38,4 +57,19 @@ type ScenarioAssignmentOutput struct {
 	CreatedAt      time.Time       `json:"created_at"`
 	UpdatedAt      time.Time       `json:"updated_at"`
 	Scenario       *ScenarioOutput `json:"scenario,omitempty"`
+
+	ExamMode         bool       `json:"exam_mode"`
+	TimeLimitMinutes int        `json:"time_limit_minutes,omitempty"`
+	StartWindowEnd   *time.Time `json:"start_window_end,omitempty"`
+
+	LatePolicy               string  `json:"late_policy"`
+	LatePenaltyPercentPerDay float64 `json:"late_penalty_percent_per_day,omitempty"`
+	ReminderHoursBefore      int     `json:"reminder_hours_before,omitempty"`
+}
+
+// GrantAssignmentExtensionInput sets a learner's own deadline for an
+// assignment.
+type GrantAssignmentExtensionInput struct {
+	Deadline time.Time `json:"deadline" binding:"required"`
+	Reason   string    `json:"reason" binding:"max=1000"`
 }
@@ -0,0 +1,111 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Catalog sort orders.
+const (
+	CatalogSortRelevance = "relevance" // best text match first; "recent" without a query
+	CatalogSortRating    = "rating"
+	CatalogSortPopular   = "popular" // most learners first
+	CatalogSortRecent    = "recent"
+)
+
+// CatalogSearchInput filters the public scenario catalog. Features and
+// SupportedFeatures are comma-separated feature names.
+type CatalogSearchInput struct {
+	Query      string `form:"q"`
+	Difficulty string `form:"difficulty"`
+	OsType     string `form:"os_type"`
+	// MaxMinutes keeps the scenarios estimated to take at most that long;
+	// scenarios without a parsable estimate are left out when it is set.
+	MaxMinutes int `form:"max_minutes"`
+	// Features keeps the scenarios requiring every listed feature.
+	Features string `form:"features"`
+	// SupportedFeatures keeps the scenarios requiring no feature beyond the
+	// listed ones, e.g. those a plan can run.
+	SupportedFeatures *string `form:"supported_features"`
+	Sort              string  `form:"sort"`
+	Limit             int     `form:"limit"`
+	Offset            int     `form:"offset"`
+}
+
+// CatalogRatingSummary aggregates a scenario's reviews.
+type CatalogRatingSummary struct {
+	Average *float64 `json:"average,omitempty"`
+	Count   int      `json:"count"`
+	// Distribution counts the reviews per star, index 0 holding the 1-star
+	// ones. Only filled in for a single scenario.
+	Distribution []int `json:"distribution,omitempty"`
+}
+
+// CatalogUsageStats describes how much a scenario is used.
+type CatalogUsageStats struct {
+	Sessions       int      `json:"sessions"`
+	Learners       int      `json:"learners"`
+	Completions    int      `json:"completions"`
+	CompletionRate float64  `json:"completion_rate"` // share of learners who completed it, 0..100
+	AverageGrade   *float64 `json:"average_grade,omitempty"`
+	Forks          int      `json:"forks"`
+}
+
+// CatalogScenario is a scenario as listed in the catalog.
+type CatalogScenario struct {
+	ID               uuid.UUID            `json:"id"`
+	Name             string               `json:"name"`
+	Title            string               `json:"title"`
+	Description      string               `json:"description,omitempty"`
+	Objectives       string               `json:"objectives,omitempty"`
+	Difficulty       string               `json:"difficulty,omitempty"`
+	EstimatedTime    string               `json:"estimated_time,omitempty"`
+	EstimatedMinutes *int                 `json:"estimated_minutes,omitempty"`
+	OsType           string               `json:"os_type,omitempty"`
+	RequiredFeatures []string             `json:"required_features,omitempty"`
+	OrganizationID   *uuid.UUID           `json:"organization_id,omitempty"`
+	ForkedFromID     *uuid.UUID           `json:"forked_from_id,omitempty"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	Rating           CatalogRatingSummary `json:"rating"`
+	Usage            CatalogUsageStats    `json:"usage"`
+}
+
+// CatalogPage is one page of catalog search results.
+type CatalogPage struct {
+	Items  []CatalogScenario `json:"items"`
+	Total  int               `json:"total"`
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+}
+
+// CatalogScenarioDetail is a catalog scenario with its prerequisites and
+// latest reviews.
+type CatalogScenarioDetail struct {
+	CatalogScenario
+	Prerequisites string                 `json:"prerequisites,omitempty"`
+	StepCount     int                    `json:"step_count"`
+	RecentReviews []ScenarioReviewOutput `json:"recent_reviews"`
+}
+
+// UpsertScenarioReviewInput rates a scenario.
+type UpsertScenarioReviewInput struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+// ScenarioReviewOutput is a learner's review of a scenario.
+type ScenarioReviewOutput struct {
+	ID         uuid.UUID `json:"id"`
+	ScenarioID uuid.UUID `json:"scenario_id"`
+	UserID     string    `json:"user_id"`
+	Rating     int       `json:"rating"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ForkScenarioInput names the catalog scenario to fork into an organization.
+type ForkScenarioInput struct {
+	ScenarioID uuid.UUID `json:"scenario_id" binding:"required"`
+}
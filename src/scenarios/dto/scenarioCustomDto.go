@@ -73,9 +73,13 @@ type StepProvisioningStatus struct {
 
 // VerifyStepResponse - DTO for verify step results
 type VerifyStepResponse struct {
-	Passed   bool   `json:"passed"`
-	Output   string `json:"output,omitempty"`
-	NextStep *int   `json:"next_step,omitempty"`
+	Passed bool   `json:"passed"`
+	Output string `json:"output,omitempty"`
+	// NextStep is the order the session moved to. It can be set alongside
+	// Passed=false when a verify_exit_code transition routed the failed
+	// attempt to a remediation step; the step left is then recorded as
+	// failed and earns no credit.
+	NextStep *int `json:"next_step,omitempty"`
 	StepProvisioningStatus
 }
 
@@ -117,23 +121,34 @@ type CurrentStepResponse struct {
 	// arithmetic on StepOrder renders "Étape 3 / 2" on 1-based scenarios.
 	Position int `json:"position"`
 	// StepOrders lists every step's Order in display order, so a client can
-	// map a display position back to the order it must navigate to.
-	StepOrders            []int                 `json:"step_orders,omitempty"`
-	TotalSteps            int                   `json:"total_steps"`
-	Title                 string                `json:"title"`
-	Text                  string                `json:"text,omitempty"`
-	Hint                  string                `json:"hint,omitempty"`
-	HintsTotalCount       int                   `json:"hints_total_count"`
-	HintsRevealed         int                   `json:"hints_revealed"`
+	// map a display position back to the order it must navigate to. Steps
+	// the session's routing skipped are not listed, and TotalSteps counts
+	// the same list.
+	StepOrders      []int  `json:"step_orders,omitempty"`
+	TotalSteps      int    `json:"total_steps"`
+	Title           string `json:"title"`
+	Text            string `json:"text,omitempty"`
+	Hint            string `json:"hint,omitempty"`
+	HintsTotalCount int    `json:"hints_total_count"`
+	HintsRevealed   int    `json:"hints_revealed"`
+	// HintPenalties is the PenaltyPercent of each hint, by level, so the
+	// learner sees what a hint costs before revealing it. Omitted when every
+	// hint is free.
+	HintPenalties         []float64             `json:"hint_penalties,omitempty"`
 	Status                string                `json:"status"`
 	HasFlag               bool                  `json:"has_flag"`
 	StepType              string                `json:"step_type"`
 	TextContent           string                `json:"text_content,omitempty"`
 	Questions             []CurrentStepQuestion `json:"questions,omitempty"`
 	ShowImmediateFeedback bool                  `json:"show_immediate_feedback"`
+	// Exam sessions withhold hints and carry the server-enforced cut-off, so
+	// the client can show a countdown.
+	ExamMode  bool       `json:"exam_mode,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
-// CurrentStepQuestion - sanitized public DTO for a quiz question.
+// CurrentStepQuestion - sanitized public DTO for a quiz question. Options are
+// in the session's own order.
 // CRITICAL: this DTO MUST NOT include CorrectAnswer or Explanation — those
 // would leak the answer to the student. Post-submission results expose them
 // via QuizQuestionResult.
@@ -145,7 +160,8 @@ type CurrentStepQuestion struct {
 	Options      string    `json:"options,omitempty"`
 }
 
-// SubmitQuizInput - DTO for submitting a quiz answer set
+// SubmitQuizInput - DTO for submitting a quiz answer set. Multi-select and
+// ordering answers are JSON arrays of the chosen options, encoded as a string.
 type SubmitQuizInput struct {
 	Answers map[uuid.UUID]string `json:"answers" binding:"required"`
 }
@@ -179,6 +195,21 @@ type RevealHintResponse struct {
 	Level   int    `json:"level"`
 	Content string `json:"content"`
 	Total   int    `json:"total"`
+	// PenaltyPercent is what this hint costs, as a share of the step's credit.
+	PenaltyPercent float64 `json:"penalty_percent"`
+	// StepCreditRemaining is the share of the step's credit (0..100) still
+	// available after the hints revealed so far.
+	StepCreditRemaining float64 `json:"step_credit_remaining"`
+	// MaxAchievableGrade is the best grade (0..100) the session can still
+	// reach, counting every unfinished step as passed.
+	MaxAchievableGrade float64 `json:"max_achievable_grade"`
+}
+
+// SubmitExamResponse - response after a learner ends an exam early
+type SubmitExamResponse struct {
+	SessionID   uuid.UUID  `json:"session_id"`
+	Grade       *float64   `json:"grade,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
 // SeedScenarioInput - DTO for seeding a scenario with inline content (admin/testing)
@@ -211,51 +242,79 @@ type SeedScenarioInput struct {
 	RequiredFeatures []string `json:"required_features,omitempty"`
 	// BuildFeatures names features held only while the container is
 	// provisioned, then removed. Same meaning as the archive importer's field.
-	BuildFeatures []string        `json:"build_features,omitempty"`
-	Steps         []SeedStepInput `json:"steps" binding:"required,min=1"`
+	BuildFeatures []string `json:"build_features,omitempty"`
+	// Nodes declare a multi-machine topology, the first node being the
+	// primary one. Leave empty for a scenario that runs on one machine.
+	Nodes []ScenarioNodeSpec `json:"nodes,omitempty" binding:"omitempty,dive"`
+	Steps []SeedStepInput    `json:"steps" binding:"required,min=1"`
 }
 
 // SeedQuestionInput - DTO for a quiz question inside a SeedStepInput
 type SeedQuestionInput struct {
-	Order         int    `json:"order"`
-	QuestionText  string `json:"question_text"`
-	QuestionType  string `json:"question_type"`
-	Options       string `json:"options,omitempty"`
-	CorrectAnswer string `json:"correct_answer,omitempty"`
-	Explanation   string `json:"explanation,omitempty"`
-	Points        int    `json:"points,omitempty"`
+	Order         int     `json:"order"`
+	QuestionText  string  `json:"question_text"`
+	QuestionType  string  `json:"question_type"`
+	Options       string  `json:"options,omitempty"`
+	CorrectAnswer string  `json:"correct_answer,omitempty"`
+	Explanation   string  `json:"explanation,omitempty"`
+	Points        int     `json:"points,omitempty"`
+	Tolerance     float64 `json:"tolerance,omitempty"`
+	Difficulty    string  `json:"difficulty,omitempty"`
+	Topic         string  `json:"topic,omitempty"`
+}
+
+// StepTransitionSpec - a step transition as it travels in seed input and JSON
+// export. TargetStep is the 1-based position of the target step in the
+// scenario's step list, 0 to end the scenario; orders are not used because
+// they are not preserved across export and re-import.
+type StepTransitionSpec struct {
+	Priority   int     `json:"priority,omitempty"`
+	Condition  string  `json:"condition"`
+	Threshold  float64 `json:"threshold,omitempty"`
+	Value      string  `json:"value,omitempty"`
+	TargetStep int     `json:"target_step"`
 }
 
 // SeedStepInput - DTO for a single step in a seed scenario
 type SeedStepInput struct {
-	Title                    string              `json:"title" binding:"required,max=1000"`
-	StepType                 string              `json:"step_type,omitempty"`
-	ShowImmediateFeedback    bool                `json:"show_immediate_feedback,omitempty"`
-	TextContent              string              `json:"text_content" binding:"max=65536"`
-	HintContent              string              `json:"hint_content" binding:"max=65536"`
-	VerifyScript             string              `json:"verify_script"`
-	BackgroundScript         string              `json:"background_script"`
-	ForegroundScript         string              `json:"foreground_script"`
-	IntroEffect              string              `json:"intro_effect,omitempty"`
-	IntroText                string              `json:"intro_text,omitempty" binding:"max=500"`
-	OutroEffect              string              `json:"outro_effect,omitempty"`
-	OutroText                string              `json:"outro_text,omitempty" binding:"max=500"`
-	BackgroundTimeoutSeconds int                 `json:"background_timeout_seconds,omitempty"`
-	BackgroundAsync          bool                `json:"background_async,omitempty"`
-	HasFlag                  bool                `json:"has_flag"`
-	FlagPath                 string              `json:"flag_path"`
-	Questions                []SeedQuestionInput `json:"questions,omitempty"`
+	Title                 string `json:"title" binding:"required,max=1000"`
+	StepType              string `json:"step_type,omitempty"`
+	ShowImmediateFeedback bool   `json:"show_immediate_feedback,omitempty"`
+	TextContent           string `json:"text_content" binding:"max=65536"`
+	HintContent           string `json:"hint_content" binding:"max=65536"`
+	// HintPenalties is the PenaltyPercent of each hint split from
+	// HintContent, by level. Missing entries are free.
+	HintPenalties            []float64            `json:"hint_penalties,omitempty" binding:"omitempty,dive,min=0,max=100"`
+	VerifyScript             string               `json:"verify_script"`
+	BackgroundScript         string               `json:"background_script"`
+	ForegroundScript         string               `json:"foreground_script"`
+	SolutionScript           string               `json:"solution_script,omitempty"`
+	IntroEffect              string               `json:"intro_effect,omitempty"`
+	IntroText                string               `json:"intro_text,omitempty" binding:"max=500"`
+	OutroEffect              string               `json:"outro_effect,omitempty"`
+	OutroText                string               `json:"outro_text,omitempty" binding:"max=500"`
+	BackgroundTimeoutSeconds int                  `json:"background_timeout_seconds,omitempty"`
+	BackgroundAsync          bool                 `json:"background_async,omitempty"`
+	TargetNode               string               `json:"target_node,omitempty" binding:"max=63"`
+	HasFlag                  bool                 `json:"has_flag"`
+	FlagPath                 string               `json:"flag_path"`
+	Questions                []SeedQuestionInput  `json:"questions,omitempty"`
+	QuizDrawCount            int                  `json:"quiz_draw_count,omitempty" binding:"min=0"`
+	Transitions              []StepTransitionSpec `json:"transitions,omitempty"`
 }
 
 // ScenarioExportStepQuestionOutput — quiz question shape inside a scenario export
 type ScenarioExportStepQuestionOutput struct {
-	Order         int    `json:"order"`
-	QuestionText  string `json:"question_text"`
-	QuestionType  string `json:"question_type"`
-	Options       string `json:"options,omitempty"`
-	CorrectAnswer string `json:"correct_answer,omitempty"`
-	Explanation   string `json:"explanation,omitempty"`
-	Points        int    `json:"points,omitempty"`
+	Order         int     `json:"order"`
+	QuestionText  string  `json:"question_text"`
+	QuestionType  string  `json:"question_type"`
+	Options       string  `json:"options,omitempty"`
+	CorrectAnswer string  `json:"correct_answer,omitempty"`
+	Explanation   string  `json:"explanation,omitempty"`
+	Points        int     `json:"points,omitempty"`
+	Tolerance     float64 `json:"tolerance,omitempty"`
+	Difficulty    string  `json:"difficulty,omitempty"`
+	Topic         string  `json:"topic,omitempty"`
 }
 
 // ScenarioExportStepOutput — full step data including scripts (for export only)
@@ -266,19 +325,24 @@ type ScenarioExportStepOutput struct {
 	ShowImmediateFeedback    bool                               `json:"show_immediate_feedback,omitempty"`
 	TextContent              string                             `json:"text_content,omitempty"`
 	HintContent              string                             `json:"hint_content,omitempty"`
+	HintPenalties            []float64                          `json:"hint_penalties,omitempty"`
 	VerifyScript             string                             `json:"verify_script,omitempty"`
 	BackgroundScript         string                             `json:"background_script,omitempty"`
 	ForegroundScript         string                             `json:"foreground_script,omitempty"`
+	SolutionScript           string                             `json:"solution_script,omitempty"`
 	IntroEffect              string                             `json:"intro_effect,omitempty"`
 	IntroText                string                             `json:"intro_text,omitempty"`
 	OutroEffect              string                             `json:"outro_effect,omitempty"`
 	OutroText                string                             `json:"outro_text,omitempty"`
 	BackgroundTimeoutSeconds int                                `json:"background_timeout_seconds,omitempty"`
 	BackgroundAsync          bool                               `json:"background_async,omitempty"`
+	TargetNode               string                             `json:"target_node,omitempty"`
 	HasFlag                  bool                               `json:"has_flag"`
 	FlagPath                 string                             `json:"flag_path,omitempty"`
 	FlagLevel                int                                `json:"flag_level,omitempty"`
 	Questions                []ScenarioExportStepQuestionOutput `json:"questions,omitempty"`
+	QuizDrawCount            int                                `json:"quiz_draw_count,omitempty"`
+	Transitions              []StepTransitionSpec               `json:"transitions,omitempty"`
 }
 
 // ScenarioExportOutput — full scenario data for JSON export/re-import
@@ -297,6 +361,7 @@ type ScenarioExportOutput struct {
 	IntroText        string                     `json:"intro_text,omitempty"`
 	FinishText       string                     `json:"finish_text,omitempty"`
 	SetupScript      string                     `json:"setup_script,omitempty"`
+	Nodes            []ScenarioNodeSpec         `json:"nodes,omitempty"`
 	Steps            []ScenarioExportStepOutput `json:"steps"`
 }
 
@@ -383,3 +448,25 @@ type MySessionResponse struct {
 	CompletedAt       *time.Time `json:"completed_at,omitempty"`
 	TerminalSessionID *string    `json:"terminal_session_id,omitempty"`
 }
+
+// ShareScenarioSessionInput - DTO for turning a learner's run into a team run:
+// the sub-group and/or classmates who join the lead's terminal.
+type ShareScenarioSessionInput struct {
+	GroupID *uuid.UUID `json:"group_id,omitempty"`
+	UserIDs []string   `json:"user_ids,omitempty"`
+}
+
+// ScenarioTeamMemberOutput - one learner of a team run, with the session that
+// records the run for them (the lead session for its owner).
+type ScenarioTeamMemberOutput struct {
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// ScenarioTeamResponse - DTO for a team run
+type ScenarioTeamResponse struct {
+	SessionID         uuid.UUID                  `json:"session_id"`
+	TerminalSessionID string                     `json:"terminal_session_id"`
+	Members           []ScenarioTeamMemberOutput `json:"members"`
+}
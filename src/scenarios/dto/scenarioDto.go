@@ -24,6 +24,7 @@ type CreateScenarioInput struct {
 	FlagsEnabled     bool       `json:"flags_enabled,omitempty" mapstructure:"flags_enabled"`
 	AllowedFlagPaths string     `json:"allowed_flag_paths,omitempty" mapstructure:"allowed_flag_paths"`
 	CrashTraps     bool       `json:"crash_traps,omitempty" mapstructure:"crash_traps"`
+	SnapshotSteps  bool       `json:"snapshot_steps,omitempty" mapstructure:"snapshot_steps"`
 	Objectives     string     `json:"objectives,omitempty" mapstructure:"objectives" binding:"omitempty,max=5000"`
 	Prerequisites  string     `json:"prerequisites,omitempty" mapstructure:"prerequisites" binding:"omitempty,max=5000"`
 	IntroText      string     `json:"intro_text,omitempty" mapstructure:"intro_text"`
@@ -55,6 +56,7 @@ type EditScenarioInput struct {
 	FlagsEnabled     *bool      `json:"flags_enabled,omitempty" mapstructure:"flags_enabled"`
 	AllowedFlagPaths *string    `json:"allowed_flag_paths,omitempty" mapstructure:"allowed_flag_paths"`
 	CrashTraps     *bool      `json:"crash_traps,omitempty" mapstructure:"crash_traps"`
+	SnapshotSteps  *bool      `json:"snapshot_steps,omitempty" mapstructure:"snapshot_steps"`
 	Objectives     *string    `json:"objectives,omitempty" mapstructure:"objectives" binding:"omitempty,max=5000"`
 	Prerequisites  *string    `json:"prerequisites,omitempty" mapstructure:"prerequisites" binding:"omitempty,max=5000"`
 	IntroText      *string    `json:"intro_text,omitempty" mapstructure:"intro_text"`
@@ -83,9 +85,12 @@ type ScenarioOutput struct {
 	GitRepository  string             `json:"git_repository,omitempty"`
 	GitBranch      string             `json:"git_branch"`
 	SourcePath     string             `json:"source_path,omitempty"`
+	GitSourceID    *uuid.UUID         `json:"git_source_id,omitempty"`
+	GitCommitSHA   string             `json:"git_commit_sha,omitempty"`
 	FlagsEnabled     bool               `json:"flags_enabled"`
 	AllowedFlagPaths string             `json:"allowed_flag_paths,omitempty"`
 	CrashTraps     bool               `json:"crash_traps"`
+	SnapshotSteps  bool               `json:"snapshot_steps"`
 	Objectives     string             `json:"objectives,omitempty"`
 	Prerequisites  string             `json:"prerequisites,omitempty"`
 	IntroText      string             `json:"intro_text,omitempty"`
@@ -98,10 +103,16 @@ type ScenarioOutput struct {
 	IntroFileID    *uuid.UUID         `json:"intro_file_id,omitempty"`
 	FinishFileID   *uuid.UUID         `json:"finish_file_id,omitempty"`
 	ArchivedAt     *time.Time         `json:"archived_at,omitempty"`
+	// PublishedRevisionID is the revision new sessions start on; nil until
+	// the scenario is first published, when sessions run its draft.
+	PublishedRevisionID *uuid.UUID `json:"published_revision_id,omitempty"`
+	// ForkedFromID is the catalog scenario this one was forked from.
+	ForkedFromID *uuid.UUID `json:"forked_from_id,omitempty"`
 	CreatedAt      time.Time          `json:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at"`
 	Steps                  []ScenarioStepOutput          `json:"steps,omitempty"`
 	CompatibleInstanceTypes []ScenarioInstanceTypeOutput `json:"compatible_instance_types,omitempty"`
+	Nodes                  []ScenarioNodeOutput          `json:"nodes,omitempty"`
 }
 
 // ScenarioInstanceType DTOs
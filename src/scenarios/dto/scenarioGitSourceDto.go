@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"soli/formations/src/scenarios/models"
+
+	"github.com/google/uuid"
+)
+
+// CreateScenarioGitSourceInput registers a repository an organization's
+// scenarios are synced from.
+type CreateScenarioGitSourceInput struct {
+	// RepositoryURL is an HTTPS URL, or an SSH one (ssh://… or git@host:path)
+	// pulled with SshKeyID.
+	RepositoryURL string `json:"repository_url" binding:"required"`
+	// Branch defaults to main.
+	Branch string `json:"branch,omitempty"`
+	// SourcePath limits the sync to a directory of the repository.
+	SourcePath string     `json:"source_path,omitempty"`
+	SshKeyID   *uuid.UUID `json:"ssh_key_id,omitempty"`
+}
+
+// ScenarioGitSourceWithSecret is the registration response: the only time the
+// webhook secret is shown.
+type ScenarioGitSourceWithSecret struct {
+	models.ScenarioGitSource
+	WebhookSecret string `json:"webhook_secret"`
+	// WebhookPath is where the Git host should send push events.
+	WebhookPath string `json:"webhook_path"`
+}
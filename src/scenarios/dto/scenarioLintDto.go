@@ -0,0 +1,27 @@
+package dto
+
+// ScenarioLintReport is the outcome of a dry-run validation of a scenario:
+// everything that would make it fail or misbehave for learners, without
+// importing it.
+type ScenarioLintReport struct {
+	// Valid is false when any issue is an error.
+	Valid    bool                `json:"valid"`
+	Errors   int                 `json:"errors"`
+	Warnings int                 `json:"warnings"`
+	Issues   []ScenarioLintIssue `json:"issues"`
+}
+
+// ScenarioLintIssue is one problem found in a scenario.
+type ScenarioLintIssue struct {
+	// Severity is "error" for a scenario learners cannot complete as authored,
+	// "warning" for one that runs but likely not as intended.
+	Severity string `json:"severity"`
+	// Code identifies the check, e.g. "flag_not_placed".
+	Code string `json:"code"`
+	// Step is the 1-based position of the step at fault; 0 for the scenario.
+	Step      int    `json:"step,omitempty"`
+	StepTitle string `json:"step_title,omitempty"`
+	// Field names what is at fault, e.g. "flag_path" or "questions[2]".
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
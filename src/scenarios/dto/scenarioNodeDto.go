@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateScenarioNodeInput - DTO for adding a node to a scenario's topology
+type CreateScenarioNodeInput struct {
+	ScenarioID       uuid.UUID `json:"scenario_id" mapstructure:"scenario_id" binding:"required"`
+	Name             string    `json:"name" mapstructure:"name" binding:"required,max=63"`
+	Order            int       `json:"order,omitempty" mapstructure:"order"`
+	Distribution     string    `json:"distribution,omitempty" mapstructure:"distribution"`
+	Size             string    `json:"size,omitempty" mapstructure:"size"`
+	Hostname         string    `json:"hostname,omitempty" mapstructure:"hostname" binding:"max=63"`
+	RequiredFeatures string    `json:"required_features,omitempty" mapstructure:"required_features"`
+}
+
+// EditScenarioNodeInput - DTO for editing a scenario node (partial updates)
+type EditScenarioNodeInput struct {
+	Name             *string `json:"name,omitempty" mapstructure:"name" binding:"omitempty,max=63"`
+	Order            *int    `json:"order,omitempty" mapstructure:"order"`
+	Distribution     *string `json:"distribution,omitempty" mapstructure:"distribution"`
+	Size             *string `json:"size,omitempty" mapstructure:"size"`
+	Hostname         *string `json:"hostname,omitempty" mapstructure:"hostname" binding:"omitempty,max=63"`
+	RequiredFeatures *string `json:"required_features,omitempty" mapstructure:"required_features"`
+}
+
+// ScenarioNodeOutput - DTO for scenario node responses
+type ScenarioNodeOutput struct {
+	ID               uuid.UUID `json:"id"`
+	ScenarioID       uuid.UUID `json:"scenario_id"`
+	Name             string    `json:"name"`
+	Order            int       `json:"order"`
+	Distribution     string    `json:"distribution,omitempty"`
+	Size             string    `json:"size,omitempty"`
+	Hostname         string    `json:"hostname,omitempty"`
+	RequiredFeatures string    `json:"required_features,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ScenarioNodeSpec - a topology node as it travels in seed input, JSON export
+// and the archive importer's OCF extension. Declaration order is the node
+// order: the first node is the primary one.
+type ScenarioNodeSpec struct {
+	Name             string   `json:"name" binding:"required,max=63"`
+	Distribution     string   `json:"distribution,omitempty"`
+	Size             string   `json:"size,omitempty"`
+	Hostname         string   `json:"hostname,omitempty" binding:"max=63"`
+	RequiredFeatures []string `json:"required_features,omitempty"`
+}
+
+// ScenarioSessionNodeOutput - one machine of a running session's topology,
+// for the learner's console switcher
+type ScenarioSessionNodeOutput struct {
+	Name              string `json:"name"`
+	Hostname          string `json:"hostname,omitempty"`
+	TerminalSessionID string `json:"terminal_session_id"`
+	IsPrimary         bool   `json:"is_primary"`
+}
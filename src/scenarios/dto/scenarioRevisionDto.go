@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PublishScenarioRevisionInput is the body of POST /scenarios/:id/revisions.
+type PublishScenarioRevisionInput struct {
+	Note string `json:"note,omitempty" binding:"max=2000"`
+}
+
+// ScenarioRevisionOutput describes a published revision of a scenario.
+type ScenarioRevisionOutput struct {
+	ID            uuid.UUID `json:"id"`
+	Number        int       `json:"number"`
+	Note          string    `json:"note,omitempty"`
+	PublishedByID string    `json:"published_by_id"`
+	PublishedAt   time.Time `json:"published_at"`
+	StepCount     int64     `json:"step_count"`
+	// SessionCount is how many sessions were started on this revision.
+	SessionCount int64 `json:"session_count"`
+	// Current marks the revision new sessions start on.
+	Current bool `json:"current"`
+}
+
+// ScenarioRevisionDiff lists what changed between two versions of a scenario.
+// From and To are a revision number or "draft".
+type ScenarioRevisionDiff struct {
+	From               string                     `json:"from"`
+	To                 string                     `json:"to"`
+	SetupScriptChanged bool                       `json:"setup_script_changed"`
+	Steps              []ScenarioRevisionStepDiff `json:"steps"`
+}
+
+// ScenarioRevisionStepDiff is one step that differs between two versions.
+// Steps are matched by Order, which is how sessions address them.
+type ScenarioRevisionStepDiff struct {
+	Order int    `json:"order"`
+	Title string `json:"title"`
+	// Change is "added", "removed" or "changed".
+	Change string `json:"change"`
+	// Fields names what changed on a "changed" step, e.g. "text", "questions".
+	Fields []string `json:"fields,omitempty"`
+}
@@ -18,12 +18,15 @@ type CreateScenarioStepInput struct {
 	VerifyScript       string     `json:"verify_script,omitempty" mapstructure:"verify_script"`
 	BackgroundScript   string     `json:"background_script,omitempty" mapstructure:"background_script"`
 	ForegroundScript   string     `json:"foreground_script,omitempty" mapstructure:"foreground_script"`
+	SolutionScript     string     `json:"solution_script,omitempty" mapstructure:"solution_script"`
 	IntroEffect        string     `json:"intro_effect,omitempty" mapstructure:"intro_effect"`
 	IntroText          string     `json:"intro_text,omitempty" mapstructure:"intro_text" binding:"max=500"`
 	OutroEffect        string     `json:"outro_effect,omitempty" mapstructure:"outro_effect"`
 	OutroText          string     `json:"outro_text,omitempty" mapstructure:"outro_text" binding:"max=500"`
 	BackgroundTimeoutSeconds int  `json:"background_timeout_seconds,omitempty" mapstructure:"background_timeout_seconds"`
 	BackgroundAsync    bool       `json:"background_async,omitempty" mapstructure:"background_async"`
+	TargetNode         string     `json:"target_node,omitempty" mapstructure:"target_node" binding:"max=63"`
+	QuizDrawCount      int        `json:"quiz_draw_count,omitempty" mapstructure:"quiz_draw_count" binding:"min=0"`
 	HasFlag            bool       `json:"has_flag,omitempty" mapstructure:"has_flag"`
 	FlagPath           string     `json:"flag_path,omitempty" mapstructure:"flag_path"`
 	FlagLevel          int        `json:"flag_level,omitempty" mapstructure:"flag_level"`
@@ -45,12 +48,15 @@ type EditScenarioStepInput struct {
 	VerifyScript       *string    `json:"verify_script,omitempty" mapstructure:"verify_script"`
 	BackgroundScript   *string    `json:"background_script,omitempty" mapstructure:"background_script"`
 	ForegroundScript   *string    `json:"foreground_script,omitempty" mapstructure:"foreground_script"`
+	SolutionScript     *string    `json:"solution_script,omitempty" mapstructure:"solution_script"`
 	IntroEffect        *string    `json:"intro_effect,omitempty" mapstructure:"intro_effect"`
 	IntroText          *string    `json:"intro_text,omitempty" mapstructure:"intro_text" binding:"omitempty,max=500"`
 	OutroEffect        *string    `json:"outro_effect,omitempty" mapstructure:"outro_effect"`
 	OutroText          *string    `json:"outro_text,omitempty" mapstructure:"outro_text" binding:"omitempty,max=500"`
 	BackgroundTimeoutSeconds *int `json:"background_timeout_seconds,omitempty" mapstructure:"background_timeout_seconds"`
 	BackgroundAsync    *bool      `json:"background_async,omitempty" mapstructure:"background_async"`
+	TargetNode         *string    `json:"target_node,omitempty" mapstructure:"target_node" binding:"omitempty,max=63"`
+	QuizDrawCount      *int       `json:"quiz_draw_count,omitempty" mapstructure:"quiz_draw_count" binding:"omitempty,min=0"`
 	HasFlag            *bool      `json:"has_flag,omitempty" mapstructure:"has_flag"`
 	FlagPath           *string    `json:"flag_path,omitempty" mapstructure:"flag_path"`
 	FlagLevel          *int       `json:"flag_level,omitempty" mapstructure:"flag_level"`
@@ -75,12 +81,15 @@ type ScenarioStepOutput struct {
 	VerifyScript       string     `json:"verify_script,omitempty"`
 	BackgroundScript   string     `json:"background_script,omitempty"`
 	ForegroundScript   string     `json:"foreground_script,omitempty"`
+	SolutionScript     string     `json:"solution_script,omitempty"`
 	IntroEffect        string     `json:"intro_effect,omitempty"`
 	IntroText          string     `json:"intro_text,omitempty"`
 	OutroEffect        string     `json:"outro_effect,omitempty"`
 	OutroText          string     `json:"outro_text,omitempty"`
 	BackgroundTimeoutSeconds int  `json:"background_timeout_seconds,omitempty"`
 	BackgroundAsync    bool       `json:"background_async,omitempty"`
+	TargetNode         string     `json:"target_node,omitempty"`
+	QuizDrawCount      int        `json:"quiz_draw_count,omitempty"`
 	HasFlag            bool       `json:"has_flag"`
 	FlagPath           string     `json:"flag_path,omitempty"`
 	FlagLevel          int        `json:"flag_level"`
@@ -89,6 +98,9 @@ type ScenarioStepOutput struct {
 	ForegroundScriptID *uuid.UUID `json:"foreground_script_id,omitempty"`
 	TextFileID         *uuid.UUID `json:"text_file_id,omitempty"`
 	HintFileID         *uuid.UUID `json:"hint_file_id,omitempty"`
+	// RevisionID is set on the copies of the step held by a published
+	// revision, which cannot be edited; it is nil on the draft.
+	RevisionID *uuid.UUID `json:"revision_id,omitempty"`
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 	Questions          []ScenarioStepQuestionOutput `json:"questions,omitempty"`
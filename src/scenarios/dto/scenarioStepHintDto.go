@@ -11,20 +11,24 @@ type CreateScenarioStepHintInput struct {
 	StepID  uuid.UUID `json:"step_id" mapstructure:"step_id" binding:"required"`
 	Level   int       `json:"level" mapstructure:"level" binding:"required,min=1,max=5"`
 	Content string    `json:"content" mapstructure:"content" binding:"required"`
+	// PenaltyPercent is the share of the step's credit lost by revealing the hint.
+	PenaltyPercent float64 `json:"penalty_percent,omitempty" mapstructure:"penalty_percent" binding:"min=0,max=100"`
 }
 
 // EditScenarioStepHintInput - DTO for editing a scenario step hint (partial updates)
 type EditScenarioStepHintInput struct {
-	Level   *int    `json:"level,omitempty" mapstructure:"level"`
-	Content *string `json:"content,omitempty" mapstructure:"content"`
+	Level          *int     `json:"level,omitempty" mapstructure:"level"`
+	Content        *string  `json:"content,omitempty" mapstructure:"content"`
+	PenaltyPercent *float64 `json:"penalty_percent,omitempty" mapstructure:"penalty_percent" binding:"omitempty,min=0,max=100"`
 }
 
 // ScenarioStepHintOutput - DTO for scenario step hint responses
 type ScenarioStepHintOutput struct {
-	ID        uuid.UUID `json:"id"`
-	StepID    uuid.UUID `json:"step_id"`
-	Level     int       `json:"level"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uuid.UUID `json:"id"`
+	StepID         uuid.UUID `json:"step_id"`
+	Level          int       `json:"level"`
+	Content        string    `json:"content"`
+	PenaltyPercent float64   `json:"penalty_percent"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -16,17 +16,23 @@ type CreateScenarioStepQuestionInput struct {
 	CorrectAnswer string    `json:"correct_answer,omitempty" mapstructure:"correct_answer"`
 	Explanation   string    `json:"explanation,omitempty" mapstructure:"explanation"`
 	Points        int       `json:"points,omitempty" mapstructure:"points"`
+	Tolerance     float64   `json:"tolerance,omitempty" mapstructure:"tolerance" binding:"min=0"`
+	Difficulty    string    `json:"difficulty,omitempty" mapstructure:"difficulty" binding:"max=20"`
+	Topic         string    `json:"topic,omitempty" mapstructure:"topic" binding:"max=100"`
 }
 
 // EditScenarioStepQuestionInput - DTO for editing a scenario step question (partial updates)
 type EditScenarioStepQuestionInput struct {
-	Order         *int    `json:"order,omitempty" mapstructure:"order"`
-	QuestionText  *string `json:"question_text,omitempty" mapstructure:"question_text"`
-	QuestionType  *string `json:"question_type,omitempty" mapstructure:"question_type"`
-	Options       *string `json:"options,omitempty" mapstructure:"options"`
-	CorrectAnswer *string `json:"correct_answer,omitempty" mapstructure:"correct_answer"`
-	Explanation   *string `json:"explanation,omitempty" mapstructure:"explanation"`
-	Points        *int    `json:"points,omitempty" mapstructure:"points"`
+	Order         *int     `json:"order,omitempty" mapstructure:"order"`
+	QuestionText  *string  `json:"question_text,omitempty" mapstructure:"question_text"`
+	QuestionType  *string  `json:"question_type,omitempty" mapstructure:"question_type"`
+	Options       *string  `json:"options,omitempty" mapstructure:"options"`
+	CorrectAnswer *string  `json:"correct_answer,omitempty" mapstructure:"correct_answer"`
+	Explanation   *string  `json:"explanation,omitempty" mapstructure:"explanation"`
+	Points        *int     `json:"points,omitempty" mapstructure:"points"`
+	Tolerance     *float64 `json:"tolerance,omitempty" mapstructure:"tolerance" binding:"omitempty,min=0"`
+	Difficulty    *string  `json:"difficulty,omitempty" mapstructure:"difficulty" binding:"omitempty,max=20"`
+	Topic         *string  `json:"topic,omitempty" mapstructure:"topic" binding:"omitempty,max=100"`
 }
 
 // ScenarioStepQuestionOutput - DTO for scenario step question responses (admin-only entity).
@@ -41,6 +47,9 @@ type ScenarioStepQuestionOutput struct {
 	CorrectAnswer string    `json:"correct_answer,omitempty"`
 	Explanation   string    `json:"explanation,omitempty"`
 	Points        int       `json:"points"`
+	Tolerance     float64   `json:"tolerance,omitempty"`
+	Difficulty    string    `json:"difficulty,omitempty"`
+	Topic         string    `json:"topic,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
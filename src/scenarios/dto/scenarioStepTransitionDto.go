@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateScenarioStepTransitionInput - DTO for creating a new scenario step transition
+type CreateScenarioStepTransitionInput struct {
+	StepID          uuid.UUID `json:"step_id" mapstructure:"step_id" binding:"required"`
+	Priority        int       `json:"priority,omitempty" mapstructure:"priority"`
+	Condition       string    `json:"condition" mapstructure:"condition" binding:"required,oneof=always quiz_score_below quiz_score_at_least flag verify_exit_code"`
+	Threshold       float64   `json:"threshold,omitempty" mapstructure:"threshold"`
+	Value           string    `json:"value,omitempty" mapstructure:"value"`
+	TargetStepOrder *int      `json:"target_step_order" mapstructure:"target_step_order"` // nil ends the scenario
+}
+
+// EditScenarioStepTransitionInput - DTO for editing a scenario step transition (partial updates).
+// A transition cannot be re-pointed at "end of scenario" by PATCH since a nil
+// TargetStepOrder means "unchanged"; delete and recreate it instead.
+type EditScenarioStepTransitionInput struct {
+	Priority        *int     `json:"priority,omitempty" mapstructure:"priority"`
+	Condition       *string  `json:"condition,omitempty" mapstructure:"condition" binding:"omitempty,oneof=always quiz_score_below quiz_score_at_least flag verify_exit_code"`
+	Threshold       *float64 `json:"threshold,omitempty" mapstructure:"threshold"`
+	Value           *string  `json:"value,omitempty" mapstructure:"value"`
+	TargetStepOrder *int     `json:"target_step_order,omitempty" mapstructure:"target_step_order"`
+}
+
+// ScenarioStepTransitionOutput - DTO for scenario step transition responses.
+// Value is a flag for flag transitions, so it is stripped for users who
+// cannot manage the parent scenario (see scenarioStepTransitionRedactor).
+type ScenarioStepTransitionOutput struct {
+	ID              uuid.UUID `json:"id"`
+	StepID          uuid.UUID `json:"step_id"`
+	Priority        int       `json:"priority"`
+	Condition       string    `json:"condition"`
+	Threshold       float64   `json:"threshold,omitempty"`
+	Value           string    `json:"value,omitempty"`
+	TargetStepOrder *int      `json:"target_step_order"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
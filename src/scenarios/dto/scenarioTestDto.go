@@ -0,0 +1,29 @@
+package dto
+
+// ScenarioTestReport is the outcome of playing a scenario end to end with the
+// scenario test runner.
+type ScenarioTestReport struct {
+	// Passed is false when any step failed.
+	Passed bool                     `json:"passed"`
+	Steps  []ScenarioTestStepResult `json:"steps"`
+}
+
+// ScenarioTestStepResult is what became of one step.
+type ScenarioTestStepResult struct {
+	// Step is the 1-based position of the step.
+	Step     int    `json:"step"`
+	Title    string `json:"title"`
+	StepType string `json:"step_type"`
+	// Status is "passed", "failed", "untested" (no solution script to play)
+	// or "skipped" (not played: a quiz step, or a step after a failure).
+	Status string `json:"status"`
+	// Phase names what failed: "setup", "background", "flag", "solution" or
+	// "verify".
+	Phase    string `json:"phase,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// VerifyPassedBeforeSolution flags a verify script that already passed
+	// before the solution ran, and so likely checks nothing.
+	VerifyPassedBeforeSolution bool `json:"verify_passed_before_solution,omitempty"`
+}
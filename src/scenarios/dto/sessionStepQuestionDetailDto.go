@@ -7,7 +7,8 @@ import "github.com/google/uuid"
 // answer so trainers can see HOW the student answered. This DTO is only ever
 // embedded in the teacher-side SessionStepDetail response, which is gated by
 // Layer 2 GroupRole(manager) — learners never reach the route that serializes
-// this type.
+// this type. On a step drawing from a question pool it lists only the
+// questions the session was asked.
 type SessionStepQuestionDetail struct {
 	ID            uuid.UUID `json:"id"`
 	Order         int       `json:"order"`
@@ -19,4 +20,6 @@ type SessionStepQuestionDetail struct {
 	IsCorrect     bool      `json:"is_correct"`
 	Points        int       `json:"points"`
 	Explanation   string    `json:"explanation,omitempty"`
+	Difficulty    string    `json:"difficulty,omitempty"`
+	Topic         string    `json:"topic,omitempty"`
 }
@@ -1,6 +1,7 @@
 package scenarioRegistration
 
 import (
+	"log/slog"
 	"net/http"
 
 	authModels "soli/formations/src/auth/models"
@@ -8,6 +9,8 @@ import (
 	entityManagementInterfaces "soli/formations/src/entityManagement/interfaces"
 	"soli/formations/src/scenarios/dto"
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+	"soli/formations/src/scenarios/storage"
 )
 
 func RegisterProjectFile(service *ems.EntityRegistrationService) {
@@ -17,12 +20,18 @@ func RegisterProjectFile(service *ems.EntityRegistrationService) {
 		entityManagementInterfaces.TypedEntityRegistration[models.ProjectFile, dto.CreateProjectFileInput, dto.EditProjectFileInput, dto.ProjectFileOutput]{
 			Converters: entityManagementInterfaces.TypedEntityConverters[models.ProjectFile, dto.CreateProjectFileInput, dto.EditProjectFileInput, dto.ProjectFileOutput]{
 				ModelToDto: func(model *models.ProjectFile) (dto.ProjectFileOutput, error) {
+					// A missing object must not fail a whole list page; the
+					// row is still listed, just without its content.
+					content, err := services.LoadProjectFileContent(model)
+					if err != nil {
+						slog.Warn("project file content unavailable", "id", model.ID, "storage_type", model.StorageType, "err", err)
+					}
 					return dto.ProjectFileOutput{
 						ID:          model.ID,
 						Name:        model.Name,
 						RelPath:     model.RelPath,
 						ContentType: model.ContentType,
-						Content:     model.Content,
+						Content:     content,
 						StorageType: model.StorageType,
 						StorageRef:  model.StorageRef,
 						SizeBytes:   model.SizeBytes,
@@ -54,6 +63,13 @@ func RegisterProjectFile(service *ems.EntityRegistrationService) {
 					}
 					if input.Content != nil {
 						updates["content"] = *input.Content
+						// Content written through the API lives in the row. Without
+						// this, a file already moved to object storage would keep
+						// serving its old object and silently ignore the edit.
+						if input.StorageType == nil {
+							updates["storage_type"] = storage.TypeDatabase
+							updates["storage_ref"] = ""
+						}
 					}
 					if input.StorageType != nil {
 						updates["storage_type"] = *input.StorageType
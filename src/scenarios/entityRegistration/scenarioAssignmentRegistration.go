@@ -29,6 +29,14 @@ func RegisterScenarioAssignment(service *ems.EntityRegistrationService) {
 						IsActive:       model.IsActive,
 						CreatedAt:      model.CreatedAt,
 						UpdatedAt:      model.UpdatedAt,
+
+						ExamMode:         model.ExamMode,
+						TimeLimitMinutes: model.TimeLimitMinutes,
+						StartWindowEnd:   model.StartWindowEnd,
+
+						LatePolicy:               model.LatePolicy,
+						LatePenaltyPercentPerDay: model.LatePenaltyPercentPerDay,
+						ReminderHoursBefore:      model.ReminderHoursBefore,
 					}
 
 					if model.Scenario.ID.String() != "00000000-0000-0000-0000-000000000000" {
@@ -61,6 +69,14 @@ func RegisterScenarioAssignment(service *ems.EntityRegistrationService) {
 						StartDate:      input.StartDate,
 						Deadline:       input.Deadline,
 						IsActive:       input.IsActive,
+
+						ExamMode:         input.ExamMode,
+						TimeLimitMinutes: input.TimeLimitMinutes,
+						StartWindowEnd:   input.StartWindowEnd,
+
+						LatePolicy:               input.LatePolicy,
+						LatePenaltyPercentPerDay: input.LatePenaltyPercentPerDay,
+						ReminderHoursBefore:      input.ReminderHoursBefore,
 					}
 				},
 				DtoToMap: func(input dto.EditScenarioAssignmentInput) map[string]any {
@@ -74,6 +90,24 @@ func RegisterScenarioAssignment(service *ems.EntityRegistrationService) {
 					if input.IsActive != nil {
 						updates["is_active"] = *input.IsActive
 					}
+					if input.ExamMode != nil {
+						updates["exam_mode"] = *input.ExamMode
+					}
+					if input.TimeLimitMinutes != nil {
+						updates["time_limit_minutes"] = *input.TimeLimitMinutes
+					}
+					if input.StartWindowEnd != nil {
+						updates["start_window_end"] = *input.StartWindowEnd
+					}
+					if input.LatePolicy != nil {
+						updates["late_policy"] = *input.LatePolicy
+					}
+					if input.LatePenaltyPercentPerDay != nil {
+						updates["late_penalty_percent_per_day"] = *input.LatePenaltyPercentPerDay
+					}
+					if input.ReminderHoursBefore != nil {
+						updates["reminder_hours_before"] = *input.ReminderHoursBefore
+					}
 					return updates
 				},
 			},
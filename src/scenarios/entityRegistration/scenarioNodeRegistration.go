@@ -0,0 +1,119 @@
+package scenarioRegistration
+
+import (
+	"net/http"
+
+	authModels "soli/formations/src/auth/models"
+	ems "soli/formations/src/entityManagement/entityManagementService"
+	entityManagementInterfaces "soli/formations/src/entityManagement/interfaces"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// RegisterScenarioNode registers the machines of multi-node scenario
+// topologies. Like compatible instance types they describe the scenario's
+// environment rather than its content, so they are not revisioned: a change
+// applies to the next launch of any revision.
+func RegisterScenarioNode(service *ems.EntityRegistrationService) {
+	ems.RegisterTypedEntity[models.ScenarioNode, dto.CreateScenarioNodeInput, dto.EditScenarioNodeInput, dto.ScenarioNodeOutput](
+		service,
+		"ScenarioNode",
+		entityManagementInterfaces.TypedEntityRegistration[models.ScenarioNode, dto.CreateScenarioNodeInput, dto.EditScenarioNodeInput, dto.ScenarioNodeOutput]{
+			Converters: entityManagementInterfaces.TypedEntityConverters[models.ScenarioNode, dto.CreateScenarioNodeInput, dto.EditScenarioNodeInput, dto.ScenarioNodeOutput]{
+				ModelToDto: func(model *models.ScenarioNode) (dto.ScenarioNodeOutput, error) {
+					return scenarioNodeOutput(model), nil
+				},
+				DtoToModel: func(input dto.CreateScenarioNodeInput) *models.ScenarioNode {
+					return &models.ScenarioNode{
+						ScenarioID:       input.ScenarioID,
+						Name:             input.Name,
+						Order:            input.Order,
+						Distribution:     input.Distribution,
+						Size:             input.Size,
+						Hostname:         input.Hostname,
+						RequiredFeatures: input.RequiredFeatures,
+					}
+				},
+				DtoToMap: func(input dto.EditScenarioNodeInput) map[string]any {
+					updates := make(map[string]any)
+					if input.Name != nil {
+						updates["name"] = *input.Name
+					}
+					if input.Order != nil {
+						updates["order"] = *input.Order
+					}
+					if input.Distribution != nil {
+						updates["distribution"] = *input.Distribution
+					}
+					if input.Size != nil {
+						updates["size"] = *input.Size
+					}
+					if input.Hostname != nil {
+						updates["hostname"] = *input.Hostname
+					}
+					if input.RequiredFeatures != nil {
+						updates["required_features"] = *input.RequiredFeatures
+					}
+					return updates
+				},
+			},
+			Roles: entityManagementInterfaces.EntityRoles{
+				Roles: map[string]string{
+					string(authModels.Member): "(" + http.MethodGet + ")",
+					string(authModels.Admin):  "(" + http.MethodGet + "|" + http.MethodPost + "|" + http.MethodPatch + "|" + http.MethodDelete + ")",
+				},
+			},
+			SwaggerConfig: &entityManagementInterfaces.EntitySwaggerConfig{
+				Tag:        "scenario-nodes",
+				EntityName: "ScenarioNode",
+				GetAll: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "List all scenario nodes",
+					Description: "Retrieve the machines of multi-node scenario topologies",
+					Tags:        []string{"scenario-nodes"},
+					Security:    true,
+				},
+				GetOne: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Get a scenario node",
+					Description: "Retrieve a specific scenario node by ID",
+					Tags:        []string{"scenario-nodes"},
+					Security:    true,
+				},
+				Create: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Create a scenario node",
+					Description: "Add a machine to a scenario's topology",
+					Tags:        []string{"scenario-nodes"},
+					Security:    true,
+				},
+				Update: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Update a scenario node",
+					Description: "Update an existing scenario node",
+					Tags:        []string{"scenario-nodes"},
+					Security:    true,
+				},
+				Delete: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Delete a scenario node",
+					Description: "Delete a scenario node",
+					Tags:        []string{"scenario-nodes"},
+					Security:    true,
+				},
+			},
+		},
+	)
+}
+
+// scenarioNodeOutput is shared with the scenario's own output, which embeds
+// its nodes.
+func scenarioNodeOutput(model *models.ScenarioNode) dto.ScenarioNodeOutput {
+	return dto.ScenarioNodeOutput{
+		ID:               model.ID,
+		ScenarioID:       model.ScenarioID,
+		Name:             model.Name,
+		Order:            model.Order,
+		Distribution:     model.Distribution,
+		Size:             model.Size,
+		Hostname:         model.Hostname,
+		RequiredFeatures: model.RequiredFeatures,
+		CreatedAt:        model.CreatedAt,
+		UpdatedAt:        model.UpdatedAt,
+	}
+}
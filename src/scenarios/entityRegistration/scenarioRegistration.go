@@ -33,9 +33,12 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 						GitRepository:  model.GitRepository,
 						GitBranch:      model.GitBranch,
 						SourcePath:     model.SourcePath,
+						GitSourceID:    model.GitSourceID,
+						GitCommitSHA:   model.GitCommitSHA,
 						FlagsEnabled:     model.FlagsEnabled,
 						AllowedFlagPaths: model.AllowedFlagPaths,
 						CrashTraps:     model.CrashTraps,
+						SnapshotSteps:  model.SnapshotSteps,
 						Objectives:     model.Objectives,
 						Prerequisites:  model.Prerequisites,
 						IntroText:      model.IntroText,
@@ -48,6 +51,8 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 						IntroFileID:    model.IntroFileID,
 						FinishFileID:   model.FinishFileID,
 						ArchivedAt:     model.ArchivedAt,
+						PublishedRevisionID: model.PublishedRevisionID,
+						ForkedFromID:        model.ForkedFromID,
 						CreatedAt:      model.CreatedAt,
 						UpdatedAt:      model.UpdatedAt,
 					}
@@ -55,6 +60,11 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 					if len(model.Steps) > 0 {
 						steps := make([]dto.ScenarioStepOutput, 0, len(model.Steps))
 						for _, step := range model.Steps {
+							// The copies held by published revisions are
+							// not part of the scenario being edited.
+							if step.RevisionID != nil {
+								continue
+							}
 							stepDto := dto.ScenarioStepOutput{
 								ID:                 step.ID,
 								ScenarioID:         step.ScenarioID,
@@ -62,6 +72,7 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 								Title:              step.Title,
 								StepType:           step.StepType,
 								ShowImmediateFeedback: step.ShowImmediateFeedback,
+								QuizDrawCount:      step.QuizDrawCount,
 								TextContent:        step.TextContent,
 								HintContent:        step.HintContent,
 								HasFlag:            step.HasFlag,
@@ -97,6 +108,9 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 										CorrectAnswer: q.CorrectAnswer,
 										Explanation:   q.Explanation,
 										Points:        q.Points,
+										Tolerance:     q.Tolerance,
+										Difficulty:    q.Difficulty,
+										Topic:         q.Topic,
 										CreatedAt:     q.CreatedAt,
 										UpdatedAt:     q.UpdatedAt,
 									})
@@ -124,6 +138,14 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 						output.CompatibleInstanceTypes = types
 					}
 
+					if len(model.Nodes) > 0 {
+						nodes := make([]dto.ScenarioNodeOutput, 0, len(model.Nodes))
+						for _, n := range model.Nodes {
+							nodes = append(nodes, scenarioNodeOutput(&n))
+						}
+						output.Nodes = nodes
+					}
+
 					return output, nil
 				},
 				DtoToModel: func(input dto.CreateScenarioInput) *models.Scenario {
@@ -144,6 +166,7 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 						FlagsEnabled:     input.FlagsEnabled,
 						AllowedFlagPaths: input.AllowedFlagPaths,
 						CrashTraps:     input.CrashTraps,
+						SnapshotSteps:  input.SnapshotSteps,
 						Objectives:     input.Objectives,
 						Prerequisites:  input.Prerequisites,
 						IntroText:      input.IntroText,
@@ -207,6 +230,9 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 					if input.CrashTraps != nil {
 						updates["crash_traps"] = *input.CrashTraps
 					}
+					if input.SnapshotSteps != nil {
+						updates["snapshot_steps"] = *input.SnapshotSteps
+					}
 					if input.Objectives != nil {
 						updates["objectives"] = *input.Objectives
 					}
@@ -240,8 +266,8 @@ func RegisterScenario(service *ems.EntityRegistrationService) {
 					return updates
 				},
 			},
-			SubEntities: []any{models.ScenarioStep{}, models.ScenarioInstanceType{}},
-			DefaultIncludes: []string{"Steps.Questions", "CompatibleInstanceTypes"},
+			SubEntities: []any{models.ScenarioStep{}, models.ScenarioInstanceType{}, models.ScenarioNode{}},
+			DefaultIncludes: []string{"Steps.Questions", "CompatibleInstanceTypes", "Nodes"},
 			Roles: entityManagementInterfaces.EntityRoles{
 				// Member can GET / PATCH / DELETE — the ScenarioAuthorizationHook
 				// gates writes to scenarios the user can manage (creator /
@@ -18,19 +18,21 @@ func RegisterScenarioStepHint(service *ems.EntityRegistrationService) {
 			Converters: entityManagementInterfaces.TypedEntityConverters[models.ScenarioStepHint, dto.CreateScenarioStepHintInput, dto.EditScenarioStepHintInput, dto.ScenarioStepHintOutput]{
 				ModelToDto: func(model *models.ScenarioStepHint) (dto.ScenarioStepHintOutput, error) {
 					return dto.ScenarioStepHintOutput{
-						ID:        model.ID,
-						StepID:    model.StepID,
-						Level:     model.Level,
-						Content:   model.Content,
-						CreatedAt: model.CreatedAt,
-						UpdatedAt: model.UpdatedAt,
+						ID:             model.ID,
+						StepID:         model.StepID,
+						Level:          model.Level,
+						Content:        model.Content,
+						PenaltyPercent: model.PenaltyPercent,
+						CreatedAt:      model.CreatedAt,
+						UpdatedAt:      model.UpdatedAt,
 					}, nil
 				},
 				DtoToModel: func(input dto.CreateScenarioStepHintInput) *models.ScenarioStepHint {
 					return &models.ScenarioStepHint{
-						StepID:  input.StepID,
-						Level:   input.Level,
-						Content: input.Content,
+						StepID:         input.StepID,
+						Level:          input.Level,
+						Content:        input.Content,
+						PenaltyPercent: input.PenaltyPercent,
 					}
 				},
 				DtoToMap: func(input dto.EditScenarioStepHintInput) map[string]any {
@@ -41,6 +43,9 @@ func RegisterScenarioStepHint(service *ems.EntityRegistrationService) {
 					if input.Content != nil {
 						updates["content"] = *input.Content
 					}
+					if input.PenaltyPercent != nil {
+						updates["penalty_percent"] = *input.PenaltyPercent
+					}
 					return updates
 				},
 			},
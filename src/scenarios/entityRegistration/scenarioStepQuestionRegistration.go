@@ -27,6 +27,9 @@ func RegisterScenarioStepQuestion(service *ems.EntityRegistrationService) {
 						CorrectAnswer: model.CorrectAnswer,
 						Explanation:   model.Explanation,
 						Points:        model.Points,
+						Tolerance:     model.Tolerance,
+						Difficulty:    model.Difficulty,
+						Topic:         model.Topic,
 						CreatedAt:     model.CreatedAt,
 						UpdatedAt:     model.UpdatedAt,
 					}, nil
@@ -45,6 +48,9 @@ func RegisterScenarioStepQuestion(service *ems.EntityRegistrationService) {
 						CorrectAnswer: input.CorrectAnswer,
 						Explanation:   input.Explanation,
 						Points:        points,
+						Tolerance:     input.Tolerance,
+						Difficulty:    input.Difficulty,
+						Topic:         input.Topic,
 					}
 				},
 				DtoToMap: func(input dto.EditScenarioStepQuestionInput) map[string]any {
@@ -70,6 +76,15 @@ func RegisterScenarioStepQuestion(service *ems.EntityRegistrationService) {
 					if input.Points != nil {
 						updates["points"] = *input.Points
 					}
+					if input.Tolerance != nil {
+						updates["tolerance"] = *input.Tolerance
+					}
+					if input.Difficulty != nil {
+						updates["difficulty"] = *input.Difficulty
+					}
+					if input.Topic != nil {
+						updates["topic"] = *input.Topic
+					}
 					return updates
 				},
 			},
@@ -99,6 +99,7 @@ func stripScenarioStepDto(out *dto.ScenarioStepOutput) {
 	out.VerifyScript = ""
 	out.BackgroundScript = ""
 	out.ForegroundScript = ""
+	out.SolutionScript = ""
 	out.FlagPath = ""
 	out.FlagLevel = 0
 	out.VerifyScriptID = nil
@@ -30,12 +30,15 @@ func RegisterScenarioStep(service *ems.EntityRegistrationService) {
 						VerifyScript:       model.VerifyScript,
 						BackgroundScript:   model.BackgroundScript,
 						ForegroundScript:   model.ForegroundScript,
+						SolutionScript:     model.SolutionScript,
 						IntroEffect:        model.IntroEffect,
 						IntroText:          model.IntroText,
 						OutroEffect:        model.OutroEffect,
 						OutroText:          model.OutroText,
 						BackgroundTimeoutSeconds: model.BackgroundTimeoutSeconds,
 						BackgroundAsync:    model.BackgroundAsync,
+						TargetNode:         model.TargetNode,
+						QuizDrawCount:      model.QuizDrawCount,
 						HasFlag:            model.HasFlag,
 						FlagPath:           model.FlagPath,
 						FlagLevel:          model.FlagLevel,
@@ -44,6 +47,7 @@ func RegisterScenarioStep(service *ems.EntityRegistrationService) {
 						ForegroundScriptID: model.ForegroundScriptID,
 						TextFileID:         model.TextFileID,
 						HintFileID:         model.HintFileID,
+						RevisionID:         model.RevisionID,
 						CreatedAt:          model.CreatedAt,
 						UpdatedAt:          model.UpdatedAt,
 					}
@@ -68,6 +72,9 @@ func RegisterScenarioStep(service *ems.EntityRegistrationService) {
 								CorrectAnswer: q.CorrectAnswer,
 								Explanation:   q.Explanation,
 								Points:        q.Points,
+								Tolerance:     q.Tolerance,
+								Difficulty:    q.Difficulty,
+								Topic:         q.Topic,
 								CreatedAt:     q.CreatedAt,
 								UpdatedAt:     q.UpdatedAt,
 							})
@@ -92,12 +99,15 @@ func RegisterScenarioStep(service *ems.EntityRegistrationService) {
 						VerifyScript:       input.VerifyScript,
 						BackgroundScript:   input.BackgroundScript,
 						ForegroundScript:   input.ForegroundScript,
+						SolutionScript:     input.SolutionScript,
 						IntroEffect:        input.IntroEffect,
 						IntroText:          input.IntroText,
 						OutroEffect:        input.OutroEffect,
 						OutroText:          input.OutroText,
 						BackgroundTimeoutSeconds: input.BackgroundTimeoutSeconds,
 						BackgroundAsync:    input.BackgroundAsync,
+						TargetNode:         input.TargetNode,
+						QuizDrawCount:      input.QuizDrawCount,
 						HasFlag:            input.HasFlag,
 						FlagPath:           input.FlagPath,
 						FlagLevel:          input.FlagLevel,
@@ -137,6 +147,9 @@ func RegisterScenarioStep(service *ems.EntityRegistrationService) {
 					if input.ForegroundScript != nil {
 						updates["foreground_script"] = *input.ForegroundScript
 					}
+					if input.SolutionScript != nil {
+						updates["solution_script"] = *input.SolutionScript
+					}
 					if input.IntroEffect != nil {
 						updates["intro_effect"] = *input.IntroEffect
 					}
@@ -155,6 +168,12 @@ func RegisterScenarioStep(service *ems.EntityRegistrationService) {
 					if input.BackgroundAsync != nil {
 						updates["background_async"] = *input.BackgroundAsync
 					}
+					if input.TargetNode != nil {
+						updates["target_node"] = *input.TargetNode
+					}
+					if input.QuizDrawCount != nil {
+						updates["quiz_draw_count"] = *input.QuizDrawCount
+					}
 					if input.HasFlag != nil {
 						updates["has_flag"] = *input.HasFlag
 					}
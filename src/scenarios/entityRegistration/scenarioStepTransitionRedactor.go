@@ -0,0 +1,69 @@
+package scenarioRegistration
+
+import (
+	"fmt"
+
+	"soli/formations/src/auth/access"
+	groupServices "soli/formations/src/groups/services"
+	"soli/formations/src/scenarios/dto"
+	scenarioHooks "soli/formations/src/scenarios/hooks"
+	"soli/formations/src/scenarios/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scenarioStepTransitionRedactor strips Value from a
+// ScenarioStepTransitionOutput DTO when the requesting user is NOT authorized
+// to manage the parent scenario. Same transitive chain as
+// scenarioStepQuestionRedactor:
+//
+//	transition.StepID → ScenarioStep.ScenarioID → Scenario → CanManageScenario
+func scenarioStepTransitionRedactor(c *gin.Context, dtoPtr any, db *gorm.DB) error {
+	wrapper, ok := dtoPtr.(*any)
+	if !ok {
+		return nil
+	}
+	output, ok := (*wrapper).(dto.ScenarioStepTransitionOutput)
+	if !ok {
+		return nil
+	}
+
+	if access.IsAdmin(readRoles(c)) {
+		return nil
+	}
+
+	userID := c.GetString("userId")
+	if userID == "" || db == nil {
+		output.Value = ""
+		*wrapper = output
+		return nil
+	}
+
+	var step models.ScenarioStep
+	if err := db.Where("id = ?", output.StepID).First(&step).Error; err != nil {
+		output.Value = ""
+		*wrapper = output
+		return nil
+	}
+
+	var scenario models.Scenario
+	if err := db.Where("id = ?", step.ScenarioID).First(&scenario).Error; err != nil {
+		output.Value = ""
+		*wrapper = output
+		return nil
+	}
+
+	groupSvc := groupServices.NewGroupService(db)
+	allowed, err := scenarioHooks.CanManageScenario(db, groupSvc, &scenario, userID)
+	if err != nil {
+		return fmt.Errorf("scenarioStepTransitionRedactor: check manage permission: %w", err)
+	}
+	if allowed {
+		return nil
+	}
+
+	output.Value = ""
+	*wrapper = output
+	return nil
+}
@@ -0,0 +1,111 @@
+package scenarioRegistration
+
+import (
+	"net/http"
+
+	authModels "soli/formations/src/auth/models"
+	ems "soli/formations/src/entityManagement/entityManagementService"
+	entityManagementInterfaces "soli/formations/src/entityManagement/interfaces"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+func RegisterScenarioStepTransition(service *ems.EntityRegistrationService) {
+	ems.RegisterTypedEntity[models.ScenarioStepTransition, dto.CreateScenarioStepTransitionInput, dto.EditScenarioStepTransitionInput, dto.ScenarioStepTransitionOutput](
+		service,
+		"ScenarioStepTransition",
+		entityManagementInterfaces.TypedEntityRegistration[models.ScenarioStepTransition, dto.CreateScenarioStepTransitionInput, dto.EditScenarioStepTransitionInput, dto.ScenarioStepTransitionOutput]{
+			Converters: entityManagementInterfaces.TypedEntityConverters[models.ScenarioStepTransition, dto.CreateScenarioStepTransitionInput, dto.EditScenarioStepTransitionInput, dto.ScenarioStepTransitionOutput]{
+				ModelToDto: func(model *models.ScenarioStepTransition) (dto.ScenarioStepTransitionOutput, error) {
+					return dto.ScenarioStepTransitionOutput{
+						ID:              model.ID,
+						StepID:          model.StepID,
+						Priority:        model.Priority,
+						Condition:       model.Condition,
+						Threshold:       model.Threshold,
+						Value:           model.Value,
+						TargetStepOrder: model.TargetStepOrder,
+						CreatedAt:       model.CreatedAt,
+						UpdatedAt:       model.UpdatedAt,
+					}, nil
+				},
+				DtoToModel: func(input dto.CreateScenarioStepTransitionInput) *models.ScenarioStepTransition {
+					return &models.ScenarioStepTransition{
+						StepID:          input.StepID,
+						Priority:        input.Priority,
+						Condition:       input.Condition,
+						Threshold:       input.Threshold,
+						Value:           input.Value,
+						TargetStepOrder: input.TargetStepOrder,
+					}
+				},
+				DtoToMap: func(input dto.EditScenarioStepTransitionInput) map[string]any {
+					updates := make(map[string]any)
+					if input.Priority != nil {
+						updates["priority"] = *input.Priority
+					}
+					if input.Condition != nil {
+						updates["condition"] = *input.Condition
+					}
+					if input.Threshold != nil {
+						updates["threshold"] = *input.Threshold
+					}
+					if input.Value != nil {
+						updates["value"] = *input.Value
+					}
+					if input.TargetStepOrder != nil {
+						updates["target_step_order"] = *input.TargetStepOrder
+					}
+					return updates
+				},
+			},
+			Roles: entityManagementInterfaces.EntityRoles{
+				Roles: map[string]string{
+					// Members may CRUD step transitions; the ScenarioStepTransition
+					// AuthorizationHook gates write operations transitively via
+					// the parent scenario, like step questions.
+					string(authModels.Member): "(" + http.MethodGet + "|" + http.MethodPost + "|" + http.MethodPatch + "|" + http.MethodDelete + ")",
+					string(authModels.Admin):  "(" + http.MethodGet + "|" + http.MethodPost + "|" + http.MethodPatch + "|" + http.MethodDelete + ")",
+				},
+			},
+			SwaggerConfig: &entityManagementInterfaces.EntitySwaggerConfig{
+				Tag:        "scenario-step-transitions",
+				EntityName: "ScenarioStepTransition",
+				GetAll: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "List all scenario step transitions",
+					Description: "Retrieve all conditional transitions between scenario steps",
+					Tags:        []string{"scenario-step-transitions"},
+					Security:    true,
+				},
+				GetOne: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Get a scenario step transition",
+					Description: "Retrieve a specific scenario step transition by ID",
+					Tags:        []string{"scenario-step-transitions"},
+					Security:    true,
+				},
+				Create: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Create a scenario step transition",
+					Description: "Route learners leaving a step to another step when a condition holds",
+					Tags:        []string{"scenario-step-transitions"},
+					Security:    true,
+				},
+				Update: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Update a scenario step transition",
+					Description: "Update an existing scenario step transition",
+					Tags:        []string{"scenario-step-transitions"},
+					Security:    true,
+				},
+				Delete: &entityManagementInterfaces.SwaggerOperation{
+					Summary:     "Delete a scenario step transition",
+					Description: "Delete a scenario step transition",
+					Tags:        []string{"scenario-step-transitions"},
+					Security:    true,
+				},
+			},
+		},
+	)
+
+	// A flag transition's Value is an accepted answer, so it is hidden from
+	// anyone who cannot manage the scenario, like a question's CorrectAnswer.
+	service.RegisterDtoRedactor("ScenarioStepTransition", scenarioStepTransitionRedactor)
+}
@@ -51,5 +51,21 @@ func InitScenarioHooks(db *gorm.DB) {
 		log.Println("Scenario step question authorization hook registered")
 	}
 
+	// Hook for validating step transitions and verifying parent-scenario authorship before writing them
+	stepTransitionAuthorizationHook := NewScenarioStepTransitionAuthorizationHook(db)
+	if err := hooks.GlobalHookRegistry.RegisterHook(stepTransitionAuthorizationHook); err != nil {
+		log.Printf("Failed to register scenario step transition authorization hook: %v", err)
+	} else {
+		log.Println("Scenario step transition authorization hook registered")
+	}
+
+	// Hooks refusing any change to the steps of a published revision
+	for _, revisionHook := range NewRevisionImmutabilityHooks(db) {
+		if err := hooks.GlobalHookRegistry.RegisterHook(revisionHook); err != nil {
+			log.Printf("Failed to register %s hook: %v", revisionHook.GetName(), err)
+		}
+	}
+	log.Println("Revision immutability hooks registered")
+
 	log.Println("Scenario hooks initialization complete")
 }
@@ -3,11 +3,13 @@ package scenarioHooks
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	"soli/formations/src/entityManagement/hooks"
 	groupServices "soli/formations/src/groups/services"
 	orgModels "soli/formations/src/organizations/models"
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
 	"soli/formations/src/utils"
 
 	"github.com/google/uuid"
@@ -67,6 +69,17 @@ func (h *ScenarioAssignmentAuthorizationHook) Execute(ctx *hooks.HookContext) er
 }
 
 func (h *ScenarioAssignmentAuthorizationHook) handleBeforeCreate(ctx *hooks.HookContext) error {
+	// Exam settings are validated for everyone: an admin can misconfigure an
+	// exam as easily as a trainer.
+	if assignment, ok := ctx.NewEntity.(*models.ScenarioAssignment); ok {
+		if err := services.ValidateExamAssignment(assignment); err != nil {
+			return err
+		}
+		if err := services.ValidateLatePolicy(assignment); err != nil {
+			return err
+		}
+	}
+
 	// Admin bypasses all authorization checks
 	if ctx.IsAdmin() {
 		if assignment, ok := ctx.NewEntity.(*models.ScenarioAssignment); ok && ctx.UserID != "" {
@@ -111,17 +124,30 @@ func (h *ScenarioAssignmentAuthorizationHook) handleBeforeCreate(ctx *hooks.Hook
 }
 
 func (h *ScenarioAssignmentAuthorizationHook) handleBeforeUpdate(ctx *hooks.HookContext) error {
-	// Admin bypasses all authorization checks
-	if ctx.IsAdmin() {
-		return nil
-	}
-
 	// OldEntity contains the existing assignment loaded by the service
 	assignment, ok := ctx.OldEntity.(*models.ScenarioAssignment)
 	if !ok {
 		return fmt.Errorf("expected *models.ScenarioAssignment in OldEntity, got %T", ctx.OldEntity)
 	}
 
+	// Validate the assignment as it will be saved: a PATCH that only turns on
+	// exam mode must still find a start date and time limit.
+	if updates, ok := ctx.NewEntity.(map[string]any); ok {
+		updated := *assignment
+		applyAssignmentUpdates(&updated, updates)
+		if err := services.ValidateExamAssignment(&updated); err != nil {
+			return err
+		}
+		if err := services.ValidateLatePolicy(&updated); err != nil {
+			return err
+		}
+	}
+
+	// Admin bypasses all authorization checks
+	if ctx.IsAdmin() {
+		return nil
+	}
+
 	// Check group-level authorization for group-scoped assignments
 	if assignment.GroupID != nil && ctx.UserID != "" {
 		canManage, err := h.groupService.CanUserManageGroup(*assignment.GroupID, ctx.UserID)
@@ -236,6 +262,32 @@ func (h *ScenarioAssignmentAuthorizationHook) handleAfterDelete(ctx *hooks.HookC
 	return nil
 }
 
+// applyAssignmentUpdates overlays a PATCH map (as built by the registration's
+// DtoToMap) onto an assignment so the result can be validated as a whole.
+func applyAssignmentUpdates(a *models.ScenarioAssignment, updates map[string]any) {
+	if v, ok := updates["start_date"].(time.Time); ok {
+		a.StartDate = &v
+	}
+	if v, ok := updates["deadline"].(time.Time); ok {
+		a.Deadline = &v
+	}
+	if v, ok := updates["exam_mode"].(bool); ok {
+		a.ExamMode = v
+	}
+	if v, ok := updates["time_limit_minutes"].(int); ok {
+		a.TimeLimitMinutes = v
+	}
+	if v, ok := updates["start_window_end"].(time.Time); ok {
+		a.StartWindowEnd = &v
+	}
+	if v, ok := updates["late_policy"].(string); ok {
+		a.LatePolicy = v
+	}
+	if v, ok := updates["late_penalty_percent_per_day"].(float64); ok {
+		a.LatePenaltyPercentPerDay = v
+	}
+}
+
 // canUserManageOrg checks if a user is a manager or owner of the given organization.
 func (h *ScenarioAssignmentAuthorizationHook) canUserManageOrg(orgID uuid.UUID, userID string) (bool, error) {
 	var orgMember orgModels.OrganizationMember
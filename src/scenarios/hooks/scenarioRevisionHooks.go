@@ -0,0 +1,114 @@
+package scenarioHooks
+
+import (
+	"fmt"
+
+	"soli/formations/src/entityManagement/hooks"
+	"soli/formations/src/scenarios/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// RevisionImmutabilityHook
+// =============================================================================
+
+// RevisionImmutabilityHook refuses every create, update and delete that would
+// change a published revision of a scenario: its steps, or the hints,
+// questions and transitions under them. Sessions pinned to a revision rely on
+// it never changing, so the rule holds for admins too — authors change the
+// draft and publish again, or roll back.
+//
+// One instance guards one entity; stepOf extracts the step an entity belongs
+// to, or the step itself.
+type RevisionImmutabilityHook struct {
+	db         *gorm.DB
+	entityName string
+	stepOf     func(db *gorm.DB, entity any) (*models.ScenarioStep, error)
+	enabled    bool
+	priority   int
+}
+
+// NewRevisionImmutabilityHooks builds the hooks guarding published revisions,
+// one per entity making up a step.
+func NewRevisionImmutabilityHooks(db *gorm.DB) []hooks.Hook {
+	return []hooks.Hook{
+		newRevisionImmutabilityHook(db, "ScenarioStep", func(db *gorm.DB, entity any) (*models.ScenarioStep, error) {
+			step, ok := entity.(*models.ScenarioStep)
+			if !ok {
+				return nil, fmt.Errorf("expected *models.ScenarioStep, got %T", entity)
+			}
+			return step, nil
+		}),
+		newRevisionImmutabilityHook(db, "ScenarioStepHint", func(db *gorm.DB, entity any) (*models.ScenarioStep, error) {
+			hint, ok := entity.(*models.ScenarioStepHint)
+			if !ok {
+				return nil, fmt.Errorf("expected *models.ScenarioStepHint, got %T", entity)
+			}
+			return loadParentStep(db, hint.StepID)
+		}),
+		newRevisionImmutabilityHook(db, "ScenarioStepQuestion", func(db *gorm.DB, entity any) (*models.ScenarioStep, error) {
+			question, ok := entity.(*models.ScenarioStepQuestion)
+			if !ok {
+				return nil, fmt.Errorf("expected *models.ScenarioStepQuestion, got %T", entity)
+			}
+			return loadParentStep(db, question.StepID)
+		}),
+		newRevisionImmutabilityHook(db, "ScenarioStepTransition", func(db *gorm.DB, entity any) (*models.ScenarioStep, error) {
+			transition, ok := entity.(*models.ScenarioStepTransition)
+			if !ok {
+				return nil, fmt.Errorf("expected *models.ScenarioStepTransition, got %T", entity)
+			}
+			return loadParentStep(db, transition.StepID)
+		}),
+	}
+}
+
+func newRevisionImmutabilityHook(db *gorm.DB, entityName string, stepOf func(*gorm.DB, any) (*models.ScenarioStep, error)) *RevisionImmutabilityHook {
+	return &RevisionImmutabilityHook{
+		db:         db,
+		entityName: entityName,
+		stepOf:     stepOf,
+		enabled:    true,
+		// Before the authorization hooks: there is nothing to authorize.
+		priority: 5,
+	}
+}
+
+func (h *RevisionImmutabilityHook) GetName() string {
+	return "revision_immutability_" + h.entityName
+}
+func (h *RevisionImmutabilityHook) GetEntityName() string { return h.entityName }
+func (h *RevisionImmutabilityHook) IsEnabled() bool       { return h.enabled }
+func (h *RevisionImmutabilityHook) GetPriority() int      { return h.priority }
+func (h *RevisionImmutabilityHook) GetHookTypes() []hooks.HookType {
+	return []hooks.HookType{hooks.BeforeCreate, hooks.BeforeUpdate, hooks.BeforeDelete}
+}
+
+func (h *RevisionImmutabilityHook) Execute(ctx *hooks.HookContext) error {
+	entity := ctx.NewEntity
+	if ctx.HookType == hooks.BeforeUpdate {
+		entity = ctx.OldEntity
+	}
+	step, err := h.stepOf(h.db, entity)
+	if err != nil {
+		// A step that does not exist cannot be part of a revision; the
+		// authorization hooks report it.
+		return nil
+	}
+	if step.RevisionID != nil {
+		return fmt.Errorf("scenario step %s belongs to a published revision and cannot be changed: edit the draft and publish it", step.ID)
+	}
+	return nil
+}
+
+// loadParentStep loads the step a child entity belongs to. Unlike loadStepByID
+// it accepts a nil ID, which the authorization hooks reject with a better
+// message.
+func loadParentStep(db *gorm.DB, stepID uuid.UUID) (*models.ScenarioStep, error) {
+	if stepID == uuid.Nil {
+		return nil, fmt.Errorf("step_id is required")
+	}
+	return loadStepByID(db, stepID)
+}
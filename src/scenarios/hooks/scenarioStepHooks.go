@@ -7,6 +7,7 @@ import (
 	groupServices "soli/formations/src/groups/services"
 	orgModels "soli/formations/src/organizations/models"
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
 	"soli/formations/src/utils"
 
 	"github.com/google/uuid"
@@ -220,6 +221,27 @@ func (h *ScenarioStepQuestionAuthorizationHook) GetHookTypes() []hooks.HookType
 }
 
 func (h *ScenarioStepQuestionAuthorizationHook) Execute(ctx *hooks.HookContext) error {
+	// Validate before the admin bypass, as transitions do: a question whose
+	// answer cannot be graded is wrong for everyone.
+	switch ctx.HookType {
+	case hooks.BeforeCreate:
+		if question, ok := ctx.NewEntity.(*models.ScenarioStepQuestion); ok {
+			if err := services.ValidateQuizQuestion(question); err != nil {
+				return err
+			}
+		}
+	case hooks.BeforeUpdate:
+		if old, ok := ctx.OldEntity.(*models.ScenarioStepQuestion); ok {
+			updated := *old
+			if updates, ok := ctx.NewEntity.(map[string]any); ok {
+				applyQuestionUpdates(&updated, updates)
+			}
+			if err := services.ValidateQuizQuestion(&updated); err != nil {
+				return err
+			}
+		}
+	}
+
 	if ctx.IsAdmin() {
 		return nil
 	}
@@ -283,3 +305,157 @@ func (h *ScenarioStepQuestionAuthorizationHook) checkExisting(ctx *hooks.HookCon
 	}
 	return nil
 }
+
+// =============================================================================
+// ScenarioStepTransitionAuthorizationHook
+// =============================================================================
+
+// ScenarioStepTransitionAuthorizationHook gates create/update/delete on
+// ScenarioStepTransition through the parent scenario, like questions. It also
+// validates the transition on create and update, admins included: a
+// transition whose condition the session engine cannot evaluate, or whose
+// target is not a step of the same scenario, would silently never fire.
+type ScenarioStepTransitionAuthorizationHook struct {
+	db           *gorm.DB
+	groupService groupServices.GroupService
+	enabled      bool
+	priority     int
+}
+
+// NewScenarioStepTransitionAuthorizationHook builds a new transition authorization hook.
+func NewScenarioStepTransitionAuthorizationHook(db *gorm.DB) hooks.Hook {
+	return &ScenarioStepTransitionAuthorizationHook{
+		db:           db,
+		groupService: groupServices.NewGroupService(db),
+		enabled:      true,
+		priority:     10,
+	}
+}
+
+func (h *ScenarioStepTransitionAuthorizationHook) GetName() string {
+	return "scenario_step_transition_authorization"
+}
+func (h *ScenarioStepTransitionAuthorizationHook) GetEntityName() string {
+	return "ScenarioStepTransition"
+}
+func (h *ScenarioStepTransitionAuthorizationHook) IsEnabled() bool { return h.enabled }
+func (h *ScenarioStepTransitionAuthorizationHook) GetPriority() int { return h.priority }
+func (h *ScenarioStepTransitionAuthorizationHook) GetHookTypes() []hooks.HookType {
+	return []hooks.HookType{hooks.BeforeCreate, hooks.BeforeUpdate, hooks.BeforeDelete}
+}
+
+func (h *ScenarioStepTransitionAuthorizationHook) Execute(ctx *hooks.HookContext) error {
+	switch ctx.HookType {
+	case hooks.BeforeCreate:
+		transition, ok := ctx.NewEntity.(*models.ScenarioStepTransition)
+		if !ok {
+			return fmt.Errorf("expected *models.ScenarioStepTransition in NewEntity, got %T", ctx.NewEntity)
+		}
+		if transition.StepID == uuid.Nil {
+			return fmt.Errorf("step_id is required to create a transition")
+		}
+		return h.check(ctx, transition, "add transitions to", "scenario step")
+	case hooks.BeforeUpdate:
+		old, ok := ctx.OldEntity.(*models.ScenarioStepTransition)
+		if !ok {
+			return fmt.Errorf("expected *models.ScenarioStepTransition, got %T", ctx.OldEntity)
+		}
+		updated := *old
+		if updates, ok := ctx.NewEntity.(map[string]any); ok {
+			applyTransitionUpdates(&updated, updates)
+		}
+		return h.check(ctx, &updated, "update", "scenario step transition")
+	case hooks.BeforeDelete:
+		transition, ok := ctx.NewEntity.(*models.ScenarioStepTransition)
+		if !ok {
+			return fmt.Errorf("expected *models.ScenarioStepTransition, got %T", ctx.NewEntity)
+		}
+		if ctx.IsAdmin() {
+			return nil
+		}
+		step, err := loadStepByID(h.db, transition.StepID)
+		if err != nil {
+			return err
+		}
+		return h.checkManage(ctx, step, "delete", "scenario step transition")
+	}
+	return nil
+}
+
+// check validates transition as it will be saved, then authorizes the caller.
+func (h *ScenarioStepTransitionAuthorizationHook) check(ctx *hooks.HookContext, transition *models.ScenarioStepTransition, action, entityLabel string) error {
+	if err := services.ValidateStepTransition(transition.Condition, transition.Threshold, transition.Value); err != nil {
+		return err
+	}
+	step, err := loadStepByID(h.db, transition.StepID)
+	if err != nil {
+		return err
+	}
+	if transition.TargetStepOrder != nil {
+		var count int64
+		if err := h.db.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).
+			Where("scenario_id = ? AND \"order\" = ?", step.ScenarioID, *transition.TargetStepOrder).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check transition target: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("transition target step %d does not exist in this scenario", *transition.TargetStepOrder)
+		}
+	}
+	if ctx.IsAdmin() {
+		return nil
+	}
+	return h.checkManage(ctx, step, action, entityLabel)
+}
+
+func (h *ScenarioStepTransitionAuthorizationHook) checkManage(ctx *hooks.HookContext, step *models.ScenarioStep, action, entityLabel string) error {
+	scenario, err := loadScenarioByID(h.db, step.ScenarioID)
+	if err != nil {
+		return err
+	}
+	allowed, err := CanManageScenario(h.db, h.groupService, scenario, ctx.UserID)
+	if err != nil {
+		return fmt.Errorf("permission check failed: %w", err)
+	}
+	if !allowed {
+		return utils.PermissionDeniedError(action, entityLabel)
+	}
+	return nil
+}
+
+// applyTransitionUpdates overlays a PATCH map (as built by the registration's
+// DtoToMap) onto a transition so the result can be validated as a whole.
+func applyTransitionUpdates(t *models.ScenarioStepTransition, updates map[string]any) {
+	if v, ok := updates["priority"].(int); ok {
+		t.Priority = v
+	}
+	if v, ok := updates["condition"].(string); ok {
+		t.Condition = v
+	}
+	if v, ok := updates["threshold"].(float64); ok {
+		t.Threshold = v
+	}
+	if v, ok := updates["value"].(string); ok {
+		t.Value = v
+	}
+	if v, ok := updates["target_step_order"].(int); ok {
+		t.TargetStepOrder = &v
+	}
+}
+
+// applyQuestionUpdates overlays a PATCH map (as built by the registration's
+// DtoToMap) onto a question so the result can be validated as a whole.
+func applyQuestionUpdates(q *models.ScenarioStepQuestion, updates map[string]any) {
+	if v, ok := updates["question_type"].(string); ok {
+		q.QuestionType = v
+	}
+	if v, ok := updates["options"].(string); ok {
+		q.Options = v
+	}
+	if v, ok := updates["correct_answer"].(string); ok {
+		q.CorrectAnswer = v
+	}
+	if v, ok := updates["tolerance"].(float64); ok {
+		q.Tolerance = v
+	}
+}
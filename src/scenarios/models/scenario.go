@@ -26,10 +26,18 @@ type Scenario struct {
 	GitRepository  string     `gorm:"type:varchar(1000)" json:"git_repository,omitempty"`
 	GitBranch      string     `gorm:"type:varchar(255);default:'main'" json:"git_branch"`
 	SourcePath     string     `gorm:"type:varchar(1000)" json:"source_path,omitempty"`
+	// GitSourceID is the ScenarioGitSource the scenario is synced from, and
+	// GitCommitSHA the commit its draft was last imported from.
+	GitSourceID    *uuid.UUID `gorm:"type:uuid;index" json:"git_source_id,omitempty" mapstructure:"git_source_id"`
+	GitCommitSHA   string     `gorm:"type:varchar(64)" json:"git_commit_sha,omitempty" mapstructure:"git_commit_sha"`
 	FlagsEnabled   bool       `gorm:"default:false" json:"flags_enabled"`
 	FlagSecret       string     `gorm:"type:varchar(500)" json:"-"` // never exposed in API
 	AllowedFlagPaths string     `gorm:"type:text" json:"allowed_flag_paths,omitempty" mapstructure:"allowed_flag_paths"` // comma-separated allowed path prefixes; empty = defaults
 	CrashTraps     bool       `gorm:"default:false" json:"crash_traps"`
+	// SnapshotSteps snapshots the learner's terminal once each step's setup
+	// has run, so reprovisioning the step restores that point instead of
+	// re-running its scripts. Needs a persistent session.
+	SnapshotSteps  bool       `gorm:"default:false" json:"snapshot_steps" mapstructure:"snapshot_steps"`
 	Objectives     string     `gorm:"type:text" json:"objectives,omitempty"`
 	Prerequisites  string     `gorm:"type:text" json:"prerequisites,omitempty"`
 	IntroText      string     `gorm:"type:text" json:"intro_text,omitempty"`
@@ -49,10 +57,19 @@ type Scenario struct {
 	FinishFileID   *uuid.UUID `gorm:"type:uuid;index" json:"finish_file_id,omitempty" mapstructure:"finish_file_id"`
 	// ArchivedAt retires the scenario without deleting it — see NotArchived.
 	ArchivedAt     *time.Time `gorm:"index" json:"archived_at,omitempty" mapstructure:"archived_at"`
+	// PublishedRevisionID is the revision new sessions start on. Nil until the
+	// scenario is first published; sessions then run the draft.
+	PublishedRevisionID *uuid.UUID `gorm:"type:uuid" json:"published_revision_id,omitempty" mapstructure:"published_revision_id"`
+	// ForkedFromID is the catalog scenario this one was forked from, kept as
+	// provenance. Nil for an original scenario.
+	ForkedFromID *uuid.UUID `gorm:"type:uuid;index" json:"forked_from_id,omitempty" mapstructure:"forked_from_id"`
 
 	// Relations
 	Steps                  []ScenarioStep         `gorm:"foreignKey:ScenarioID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"steps,omitempty"`
 	CompatibleInstanceTypes []ScenarioInstanceType `gorm:"foreignKey:ScenarioID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"compatible_instance_types,omitempty"`
+	// Nodes declare a multi-machine topology; see ScenarioNode. Empty for a
+	// scenario that runs on a single machine.
+	Nodes []ScenarioNode `gorm:"foreignKey:ScenarioID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"nodes,omitempty"`
 }
 
 // Implement interfaces for entity management system
@@ -1,11 +1,20 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	entityManagementModels "soli/formations/src/entityManagement/models"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Late policies: what an assignment allows once its deadline has passed.
+const (
+	LatePolicyBlock   = "block"   // No session may be started
+	LatePolicyPenalty = "penalty" // Sessions completed late lose a share of their grade per day
+	LatePolicyFlag    = "flag"    // Sessions completed late are marked, their grade untouched
 )
 
 // ScenarioAssignment represents an assignment of a scenario to a group or organization
@@ -20,6 +29,25 @@ type ScenarioAssignment struct {
 	Deadline       *time.Time `json:"deadline,omitempty" mapstructure:"deadline"`
 	IsActive       bool       `gorm:"default:true" json:"is_active" mapstructure:"is_active"`
 
+	// Exam mode turns the assignment into a graded practical exam: it can only
+	// be started between StartDate and StartWindowEnd, each session is cut off
+	// TimeLimitMinutes after it starts (or at Deadline, if sooner), hints are
+	// withheld, and a learner gets a single attempt.
+	ExamMode         bool       `gorm:"default:false" json:"exam_mode" mapstructure:"exam_mode"`
+	TimeLimitMinutes int        `gorm:"default:0" json:"time_limit_minutes,omitempty" mapstructure:"time_limit_minutes"`
+	StartWindowEnd   *time.Time `json:"start_window_end,omitempty" mapstructure:"start_window_end"` // nil: startable until Deadline
+
+	// LatePolicy applies once Deadline — or a learner's extension of it — has
+	// passed. "block", the default, is how assignments always behaved. Under
+	// "penalty", a session completed late loses LatePenaltyPercentPerDay
+	// percent of its grade per day or part of a day late, down to zero. Exams
+	// keep their own start window and time limit.
+	LatePolicy               string  `gorm:"type:varchar(20);default:'block'" json:"late_policy" mapstructure:"late_policy"`
+	LatePenaltyPercentPerDay float64 `gorm:"default:0" json:"late_penalty_percent_per_day,omitempty" mapstructure:"late_penalty_percent_per_day"`
+	// ReminderHoursBefore emails the learners who have not completed the
+	// scenario that many hours before their deadline. 0 sends no reminder.
+	ReminderHoursBefore int `gorm:"default:0" json:"reminder_hours_before,omitempty" mapstructure:"reminder_hours_before"`
+
 	// Relations
 	Scenario Scenario `gorm:"foreignKey:ScenarioID" json:"scenario,omitempty"`
 }
@@ -35,3 +63,19 @@ func (s ScenarioAssignment) GetReferenceObject() string {
 func (ScenarioAssignment) TableName() string {
 	return "scenario_assignments"
 }
+
+// OpenAssignmentsFor keeps the active assignments userID may start a session
+// of at now: past their start date, and before their deadline — the
+// learner's extended one, if they were granted an extension — unless they
+// accept late work. table names the assignments table as the query refers
+// to it, for queries joining it under an alias.
+func OpenAssignmentsFor(table, userID string, now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(fmt.Sprintf(`%[1]s.is_active = ? AND (%[1]s.start_date IS NULL OR %[1]s.start_date <= ?)
+			AND (%[1]s.late_policy IN ? OR COALESCE(COALESCE(
+				(SELECT ext.deadline FROM scenario_assignment_extensions ext
+					WHERE ext.assignment_id = %[1]s.id AND ext.user_id = ? AND ext.deleted_at IS NULL),
+				%[1]s.deadline) > ?, true))`, table),
+			true, now, []string{LatePolicyPenalty, LatePolicyFlag}, userID, now)
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+)
+
+// ScenarioAssignmentExtension moves one learner's deadline for an
+// assignment. It replaces the assignment's deadline for that learner, whether
+// later or earlier, in every check: starting sessions, grading them late, and
+// reminders.
+type ScenarioAssignmentExtension struct {
+	entityManagementModels.BaseModel
+	AssignmentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_assignment_extension_user" json:"assignment_id"`
+	UserID       string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_assignment_extension_user" json:"user_id"`
+	Deadline     time.Time `gorm:"not null" json:"deadline"`
+	Reason       string    `gorm:"type:text" json:"reason,omitempty"`
+	GrantedByID  string    `gorm:"type:varchar(255);not null" json:"granted_by_id"`
+}
+
+func (ScenarioAssignmentExtension) TableName() string {
+	return "scenario_assignment_extensions"
+}
+
+// ScenarioAssignmentReminder records a deadline reminder sent to a learner.
+// Keyed by the deadline it announced, so that a learner whose deadline moves
+// is reminded again of the new one, and never twice of the same.
+type ScenarioAssignmentReminder struct {
+	entityManagementModels.BaseModel
+	AssignmentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_assignment_reminder" json:"assignment_id"`
+	UserID       string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_assignment_reminder" json:"user_id"`
+	Deadline     time.Time `gorm:"not null;uniqueIndex:idx_assignment_reminder" json:"deadline"`
+	SentAt       time.Time `gorm:"not null" json:"sent_at"`
+}
+
+func (ScenarioAssignmentReminder) TableName() string {
+	return "scenario_assignment_reminders"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// CatalogSearchDocument is the text the catalog's full-text search matches,
+// as a Postgres tsvector. The 'simple' configuration neither stems nor drops
+// stop words, so French and English scenarios are searched alike.
+const CatalogSearchDocument = `to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, '') || ' ' || coalesce(objectives, ''))`
+
+// MigrateCatalogSearchIndex creates the GIN index backing the catalog's
+// full-text search. Postgres only: other databases search without an index.
+func MigrateCatalogSearchIndex(db *gorm.DB) {
+	if db.Dialector.Name() != "postgres" {
+		return
+	}
+	sql := `CREATE INDEX IF NOT EXISTS idx_scenarios_catalog_search ON scenarios USING GIN (` + CatalogSearchDocument + `)`
+	if err := db.Exec(sql).Error; err != nil {
+		fmt.Printf("MigrateCatalogSearchIndex: failed to create index: %v\n", err)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScenarioFlagAttempt records one wrong flag submission. ScenarioFlag keeps only
+// the latest submission of a step, so a learner who first pastes a classmate's
+// flag and then finds their own would leave no trace; the integrity report
+// (teacherIntegrityService.go) reads this log instead.
+type ScenarioFlagAttempt struct {
+	entityManagementModels.BaseModel
+	SessionID     uuid.UUID `gorm:"type:uuid;not null;index" json:"session_id"`
+	StepOrder     int       `gorm:"not null" json:"step_order"`
+	SubmittedFlag string    `gorm:"type:varchar(500);index" json:"-"`
+	SubmittedAt   time.Time `gorm:"not null" json:"submitted_at"`
+}
+
+// TableName specifies the table name
+func (ScenarioFlagAttempt) TableName() string {
+	return "scenario_flag_attempts"
+}
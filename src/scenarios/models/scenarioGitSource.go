@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"soli/formations/src/utils/crypto"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScenarioGitSync status values — outcome of one pull of a ScenarioGitSource.
+const (
+	GitSyncStatusRunning   = "running"   // The repository is being pulled and imported
+	GitSyncStatusSucceeded = "succeeded" // Every scenario found was imported or unchanged
+	GitSyncStatusPartial   = "partial"   // Some scenarios failed validation; the others were imported
+	GitSyncStatusFailed    = "failed"    // The repository could not be pulled; nothing was imported
+)
+
+// ScenarioGitSync triggers — what started a sync.
+const (
+	GitSyncTriggerManual  = "manual"
+	GitSyncTriggerWebhook = "webhook"
+)
+
+// Per-scenario outcomes recorded in ScenarioGitSync.Results.
+const (
+	GitSyncScenarioImported  = "imported"  // Content changed; published as a new revision
+	GitSyncScenarioUnchanged = "unchanged" // Same content as the scenario's current revision
+	GitSyncScenarioFailed    = "failed"    // Invalid; the scenario was left as it was
+)
+
+// ScenarioGitSource is a Git repository an organization keeps its scenarios
+// in. Every directory of the repository holding an index.json (under
+// SourcePath) is a scenario; a sync clones Branch, re-imports each one and
+// publishes it as a new revision when its content changed.
+//
+// SSH repositories are pulled with SshKeyID, a key of the user who registered
+// the source. WebhookSecret authenticates the push webhook of the Git host; it
+// is returned once, when the source is registered, never serialized
+// afterwards, and encrypted at rest.
+type ScenarioGitSource struct {
+	entityManagementModels.BaseModel
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	RepositoryURL  string     `gorm:"type:varchar(1000);not null" json:"repository_url"`
+	Branch         string     `gorm:"type:varchar(255);not null;default:'main'" json:"branch"`
+	SourcePath     string     `gorm:"type:varchar(1000)" json:"source_path,omitempty"`
+	SshKeyID       *uuid.UUID `gorm:"type:uuid" json:"ssh_key_id,omitempty"`
+	WebhookSecret  string     `gorm:"type:varchar(255);not null" json:"-"`
+	CreatedByID    string     `gorm:"type:varchar(255);not null" json:"created_by_id"`
+
+	// Outcome of the latest sync, for listings.
+	LastSyncedAt   *time.Time `json:"last_synced_at,omitempty"`
+	LastCommitSHA  string     `gorm:"type:varchar(64)" json:"last_commit_sha,omitempty"`
+	LastSyncStatus string     `gorm:"type:varchar(20)" json:"last_sync_status,omitempty"`
+	LastSyncError  string     `gorm:"type:text" json:"last_sync_error,omitempty"`
+}
+
+func (ScenarioGitSource) TableName() string {
+	return "scenario_git_sources"
+}
+
+// BeforeSave encrypts WebhookSecret before writing to the database.
+func (s *ScenarioGitSource) BeforeSave(tx *gorm.DB) error {
+	return crypto.EncryptField(&s.WebhookSecret)
+}
+
+// AfterFind decrypts WebhookSecret after loading from the database.
+func (s *ScenarioGitSource) AfterFind(tx *gorm.DB) error {
+	return crypto.DecryptField(&s.WebhookSecret)
+}
+
+// ScenarioGitSync is the log of one sync of a ScenarioGitSource: the commit it
+// pulled and what became of each scenario the repository holds.
+type ScenarioGitSync struct {
+	entityManagementModels.BaseModel
+	SourceID   uuid.UUID               `gorm:"type:uuid;not null;index" json:"source_id"`
+	Trigger    string                  `gorm:"type:varchar(20);not null" json:"trigger"`
+	Status     string                  `gorm:"type:varchar(20);not null" json:"status"`
+	CommitSHA  string                  `gorm:"type:varchar(64)" json:"commit_sha,omitempty"`
+	StartedAt  time.Time               `json:"started_at"`
+	FinishedAt *time.Time              `json:"finished_at,omitempty"`
+	Error      string                  `gorm:"type:text" json:"error,omitempty"`
+	Results    []ScenarioGitSyncResult `gorm:"serializer:json" json:"results"`
+}
+
+func (ScenarioGitSync) TableName() string {
+	return "scenario_git_syncs"
+}
+
+// ScenarioGitSyncResult is what a sync did with one scenario directory.
+type ScenarioGitSyncResult struct {
+	// Path is the scenario's directory, relative to the repository root.
+	Path           string     `json:"path"`
+	Name           string     `json:"name,omitempty"`
+	ScenarioID     *uuid.UUID `json:"scenario_id,omitempty"`
+	Status         string     `json:"status"`
+	RevisionNumber *int       `json:"revision_number,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
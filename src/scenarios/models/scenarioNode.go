@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+)
+
+// ScenarioNode is one machine of a multi-node scenario: a client, a server, a
+// router, an attacker and its target. A scenario with nodes launches one
+// terminal per node, all on a private network of their own, in place of the
+// single machine its InstanceType describes. The node with the lowest Order is
+// the primary one: the terminal the session is bound to, and where steps that
+// name no node run.
+type ScenarioNode struct {
+	entityManagementModels.BaseModel
+	ScenarioID uuid.UUID `gorm:"type:uuid;not null;index" json:"scenario_id" mapstructure:"scenario_id"`
+	// Name is how steps target the node (ScenarioStep.TargetNode) and how the
+	// learner's console switcher labels it. Unique within the scenario.
+	Name  string `gorm:"type:varchar(63);not null" json:"name" mapstructure:"name"`
+	Order int    `gorm:"default:0" json:"order" mapstructure:"order"`
+	// Distribution names the image the node runs. Empty matches on the
+	// scenario's OsType, like a single-machine scenario without compatible
+	// instance types.
+	Distribution string `gorm:"type:varchar(255)" json:"distribution,omitempty" mapstructure:"distribution"`
+	// Size is a catalog size key; empty takes the distribution's default.
+	Size string `gorm:"type:varchar(50)" json:"size,omitempty" mapstructure:"size"`
+	// Hostname defaults to Name, which is what the other nodes reach it by.
+	Hostname         string `gorm:"type:varchar(63)" json:"hostname,omitempty" mapstructure:"hostname"`
+	RequiredFeatures string `gorm:"type:text" json:"required_features,omitempty" mapstructure:"required_features"`
+}
+
+// Implement interfaces for entity management system
+func (n ScenarioNode) GetBaseModel() entityManagementModels.BaseModel {
+	return n.BaseModel
+}
+
+func (n ScenarioNode) GetReferenceObject() string {
+	return "ScenarioNode"
+}
+
+// EffectiveHostname is the node's hostname on the scenario network.
+func (n ScenarioNode) EffectiveHostname() string {
+	if n.Hostname != "" {
+		return n.Hostname
+	}
+	return n.Name
+}
+
+// GetRequiredFeatures parses the RequiredFeatures JSON array field
+func (n ScenarioNode) GetRequiredFeatures() ([]string, error) {
+	if n.RequiredFeatures == "" {
+		return nil, nil
+	}
+	var features []string
+	if err := json.Unmarshal([]byte(n.RequiredFeatures), &features); err != nil {
+		return nil, fmt.Errorf("invalid required_features format for node %q (must be JSON array): %w", n.Name, err)
+	}
+	return features, nil
+}
+
+// GetFeaturesMap returns the node's required features as a map[string]bool
+// for composed sessions.
+func (n ScenarioNode) GetFeaturesMap() (map[string]bool, error) {
+	return featureNamesToMap(n.GetRequiredFeatures())
+}
+
+// TableName specifies the table name
+func (ScenarioNode) TableName() string {
+	return "scenario_nodes"
+}
+
+// ScenarioSessionNode records which terminal runs each node of a session's
+// topology. The primary node's terminal is also the session's own
+// TerminalSessionID. A session of a single-machine scenario has no rows.
+type ScenarioSessionNode struct {
+	entityManagementModels.BaseModel
+	SessionID         uuid.UUID `gorm:"type:uuid;not null;index" json:"session_id"`
+	Name              string    `gorm:"type:varchar(63);not null" json:"name"`
+	Hostname          string    `gorm:"type:varchar(63)" json:"hostname,omitempty"`
+	TerminalSessionID string    `gorm:"type:varchar(255);not null;index" json:"terminal_session_id"`
+	IsPrimary         bool      `gorm:"default:false" json:"is_primary"`
+}
+
+// TableName specifies the table name
+func (ScenarioSessionNode) TableName() string {
+	return "scenario_session_nodes"
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+)
+
+// Bounds of ScenarioReview.Rating.
+const (
+	ReviewRatingMin = 1
+	ReviewRatingMax = 5
+)
+
+// ScenarioReview is a learner's rating of a catalog scenario, with an
+// optional comment. Only learners who completed the scenario may review it,
+// once: reviewing again replaces their review.
+type ScenarioReview struct {
+	entityManagementModels.BaseModel
+	ScenarioID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_scenario_review_user,priority:1" json:"scenario_id"`
+	UserID     string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_scenario_review_user,priority:2" json:"user_id"`
+	Rating     int       `gorm:"not null" json:"rating"`
+	Comment    string    `gorm:"type:text" json:"comment,omitempty"`
+}
+
+func (ScenarioReview) TableName() string {
+	return "scenario_reviews"
+}
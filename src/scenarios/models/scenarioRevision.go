@@ -0,0 +1,92 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScenarioRevision is a published, immutable version of a scenario's content.
+//
+// The scenario's own steps are its draft: the editor changes them in place.
+// Publishing copies the draft — steps with their hints, questions and
+// transitions — into step rows that carry the revision's ID, with every script
+// and text resolved from its project file and inlined, so later edits to the
+// draft or to the files cannot reach the copy. Sessions record the revision
+// they started on and run it to the end.
+type ScenarioRevision struct {
+	entityManagementModels.BaseModel
+	ScenarioID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_scenario_revision_number" json:"scenario_id"`
+	// Number counts the scenario's revisions from 1.
+	Number        int    `gorm:"not null;uniqueIndex:idx_scenario_revision_number" json:"number"`
+	Note          string `gorm:"type:text" json:"note,omitempty"`
+	PublishedByID string `gorm:"type:varchar(255)" json:"published_by_id"`
+	// SetupScript is the scenario's setup script as published, resolved.
+	SetupScript string `gorm:"type:text" json:"-"`
+
+	Steps []ScenarioStep `gorm:"foreignKey:RevisionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (r ScenarioRevision) GetBaseModel() entityManagementModels.BaseModel {
+	return r.BaseModel
+}
+
+func (r ScenarioRevision) GetReferenceObject() string {
+	return "ScenarioRevision"
+}
+
+func (ScenarioRevision) TableName() string {
+	return "scenario_revisions"
+}
+
+// DraftSteps restricts a step query to the scenario's draft, leaving out the
+// copies held by its published revisions.
+//
+// Scenario.Steps holds both, so every path that reads a scenario's steps for
+// authoring — the editor, exports, duplicates, imports, listings — goes
+// through this scope, and every path that runs a session goes through
+// RevisionSteps with the session's revision.
+func DraftSteps(db *gorm.DB) *gorm.DB {
+	return db.Where("scenario_steps.revision_id IS NULL")
+}
+
+// RevisionSteps restricts a step query to the steps of a revision, or to the
+// draft when revisionID is nil: sessions started before the scenario was
+// first published run the draft, as every session did before revisions.
+func RevisionSteps(revisionID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	if revisionID == nil {
+		return DraftSteps
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("scenario_steps.revision_id = ?", *revisionID)
+	}
+}
+
+// PublishedSteps restricts a step query to the version of each scenario new
+// sessions start on: its published revision, or its draft until it has one.
+// Views that describe a scenario as a whole, rather than one session's run of
+// it, read this version.
+func PublishedSteps(db *gorm.DB) *gorm.DB {
+	return db.Where(`COALESCE(scenario_steps.revision_id, scenario_steps.scenario_id) = COALESCE(
+		(SELECT scenarios.published_revision_id FROM scenarios WHERE scenarios.id = scenario_steps.scenario_id),
+		scenario_steps.scenario_id)`)
+}
+
+// VersionKey identifies the version of its scenario a step belongs to: its
+// revision, or its scenario for a draft step. Loaders that batch the steps of
+// several sessions key them by it and look them up by the session's.
+func (s ScenarioStep) VersionKey() uuid.UUID {
+	if s.RevisionID != nil {
+		return *s.RevisionID
+	}
+	return s.ScenarioID
+}
+
+// VersionKey is the ScenarioStep.VersionKey of the steps the session runs.
+func (s ScenarioSession) VersionKey() uuid.UUID {
+	if s.RevisionID != nil {
+		return *s.RevisionID
+	}
+	return s.ScenarioID
+}
@@ -24,6 +24,40 @@ type ScenarioSession struct {
 	TrainerID         *string    `gorm:"type:varchar(255)" json:"trainer_id,omitempty" mapstructure:"trainer_id"`
 	IsPreview         bool       `gorm:"default:false" json:"is_preview,omitempty" mapstructure:"is_preview"`
 
+	// AssignmentID is the assignment the session was started under, whose
+	// deadline and late policy apply to it. Nil for a public scenario run
+	// outside any assignment.
+	//
+	// Exam sessions. ExamMode and ExpiresAt are copied from the assignment at
+	// start so editing the assignment cannot extend or cut short an exam
+	// already running. TimedOut marks a session finalised by the time limit
+	// rather than submitted by the learner.
+	AssignmentID *uuid.UUID `gorm:"type:uuid;index" json:"assignment_id,omitempty"`
+	ExamMode     bool       `gorm:"default:false" json:"exam_mode,omitempty"`
+	ExpiresAt    *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	TimedOut     bool       `gorm:"default:false" json:"timed_out,omitempty"`
+
+	// Late completion of an assignment. SubmittedLate marks a session
+	// completed after the learner's deadline; under a penalty late policy,
+	// LatePenaltyPercent is the share of the grade it lost and
+	// GradeBeforePenalty the grade it had earned.
+	SubmittedLate      bool     `gorm:"default:false" json:"submitted_late,omitempty" mapstructure:"submitted_late"`
+	LatePenaltyPercent float64  `gorm:"default:0" json:"late_penalty_percent,omitempty" mapstructure:"late_penalty_percent"`
+	GradeBeforePenalty *float64 `gorm:"type:decimal(5,2)" json:"grade_before_penalty,omitempty" mapstructure:"grade_before_penalty"`
+
+	// RevisionID pins the session to the revision of the scenario it started
+	// on, so that editing or republishing the scenario does not change the
+	// content under a learner mid-run. Nil runs the draft: the scenario was not
+	// published when the session started, or the session is a preview.
+	RevisionID *uuid.UUID `gorm:"type:uuid;index" json:"revision_id,omitempty" mapstructure:"revision_id"`
+
+	// TeamLeadSessionID marks a team member's session on a shared terminal:
+	// the session of the learner whose terminal it is. The team works through
+	// the lead session; a member's session mirrors its progress, status and
+	// grade so that each learner has the run in their own record and
+	// gradebook. Nil for a learner's own run, including a team lead's.
+	TeamLeadSessionID *uuid.UUID `gorm:"type:uuid;index" json:"team_lead_session_id,omitempty" mapstructure:"team_lead_session_id"`
+
 	// Relations
 	StepProgress []ScenarioStepProgress `gorm:"foreignKey:SessionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"step_progress,omitempty"`
 	Flags        []ScenarioFlag         `gorm:"foreignKey:SessionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"flags,omitempty"`
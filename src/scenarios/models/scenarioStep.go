@@ -28,6 +28,10 @@ type ScenarioStep struct {
 	// not opened their terminal simply misses it; the level itself is already
 	// provisioned by the time this runs, so nothing is left unsolvable.
 	ForegroundScript string `gorm:"type:text" json:"-"`
+	// SolutionScript solves the step the way a learner would. It is never run
+	// in a learner's session: the scenario test runner plays it between the
+	// background and verify scripts to check the step can be passed.
+	SolutionScript string `gorm:"type:text" json:"-"`
 	// Intro/outro banners. The trainer picks an effect by name and types a
 	// line; the engine turns that into an ocf-banner call in the container, so
 	// nobody has to write shell to get one. Empty effect or empty text means no
@@ -48,7 +52,10 @@ type ScenarioStep struct {
 	// and into a background goroutine, moving the session to "provisioning"
 	// until it finishes. Long timeouts imply it; this flag opts a step in
 	// regardless of its timeout.
-	BackgroundAsync    bool                   `gorm:"default:false" json:"background_async,omitempty" mapstructure:"background_async"`
+	BackgroundAsync bool `gorm:"default:false" json:"background_async,omitempty" mapstructure:"background_async"`
+	// TargetNode names the ScenarioNode this step's scripts and flag run on,
+	// in a multi-node scenario. Empty means the primary node.
+	TargetNode         string                 `gorm:"type:varchar(63)" json:"target_node,omitempty" mapstructure:"target_node"`
 	HasFlag            bool                   `gorm:"default:false" json:"has_flag"`
 	FlagPath           string                 `gorm:"type:varchar(500)" json:"flag_path,omitempty"` // where to place the flag file in the container
 	FlagLevel          int                    `gorm:"default:0" json:"flag_level"`
@@ -59,6 +66,15 @@ type ScenarioStep struct {
 	HintFileID         *uuid.UUID             `gorm:"type:uuid;index" json:"hint_file_id,omitempty" mapstructure:"hint_file_id"`
 	Hints              []ScenarioStepHint     `gorm:"foreignKey:StepID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"hints,omitempty"`
 	Questions          []ScenarioStepQuestion `gorm:"foreignKey:StepID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"questions,omitempty"`
+	// Transitions make the scenario non-linear: see ScenarioStepTransition.
+	// A step without any advances to the next Order.
+	Transitions []ScenarioStepTransition `gorm:"foreignKey:StepID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"transitions,omitempty"`
+	// QuizDrawCount makes a quiz step's Questions a pool: each session is
+	// asked this many of them, drawn per session. 0 asks them all.
+	QuizDrawCount int `gorm:"default:0" json:"quiz_draw_count,omitempty" mapstructure:"quiz_draw_count"`
+	// RevisionID is set on the copies of a step held by a published
+	// ScenarioRevision, which are never edited. The draft's steps have none.
+	RevisionID *uuid.UUID `gorm:"type:uuid;index" json:"revision_id,omitempty" mapstructure:"revision_id"`
 }
 
 // Implement interfaces for entity management system
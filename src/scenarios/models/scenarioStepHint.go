@@ -12,6 +12,10 @@ type ScenarioStepHint struct {
 	StepID  uuid.UUID `gorm:"type:uuid;not null;index" json:"step_id"`
 	Level   int       `gorm:"not null" json:"level"`
 	Content string    `gorm:"type:text;not null" json:"content"`
+	// PenaltyPercent is the share of the step's credit (0..100) a learner
+	// gives up by revealing this hint. The penalties of every hint revealed on
+	// a step add up, capped at the step's whole credit.
+	PenaltyPercent float64 `gorm:"not null;default:0" json:"penalty_percent"`
 }
 
 func (s ScenarioStepHint) GetBaseModel() entityManagementModels.BaseModel {
@@ -12,7 +12,7 @@ type ScenarioStepProgress struct {
 	entityManagementModels.BaseModel
 	SessionID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"session_id"`
 	StepOrder        int        `gorm:"not null" json:"step_order"`
-	Status           string     `gorm:"type:varchar(50);default:'locked'" json:"status"` // locked, active, completed, skipped
+	Status           string     `gorm:"type:varchar(50);default:'locked'" json:"status"` // locked, active, completed, failed, skipped
 	VerifyAttempts   int        `gorm:"default:0" json:"verify_attempts"`
 	HintsRevealed    int        `gorm:"default:0" json:"hints_revealed"`
 	CompletedAt      *time.Time `json:"completed_at,omitempty"`
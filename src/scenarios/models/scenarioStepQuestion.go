@@ -6,17 +6,49 @@ import (
 	"github.com/google/uuid"
 )
 
-// ScenarioStepQuestion represents a quiz question within a scenario step
+// Question types. The answer a learner submits is always a string; the types
+// differ in how it is compared with CorrectAnswer.
+const (
+	// QuestionTypeMultipleChoice, QuestionTypeTrueFalse and
+	// QuestionTypeFreeText match the answer exactly. Options is a JSON array
+	// for multiple choice, and CorrectAnswer one of its entries.
+	QuestionTypeMultipleChoice = "multiple_choice"
+	QuestionTypeTrueFalse      = "true_false"
+	QuestionTypeFreeText       = "free_text"
+	// QuestionTypeMultiSelect asks for every correct entry of Options. The
+	// answer and CorrectAnswer are JSON arrays, compared as sets.
+	QuestionTypeMultiSelect = "multi_select"
+	// QuestionTypeOrdering asks to put Options in order. The answer and
+	// CorrectAnswer are JSON arrays, compared entry by entry. Options may be
+	// left empty: the learner is then shown CorrectAnswer's entries.
+	QuestionTypeOrdering = "ordering"
+	// QuestionTypeNumeric accepts any number within Tolerance of CorrectAnswer.
+	QuestionTypeNumeric = "numeric"
+	// QuestionTypeRegex accepts free text matching CorrectAnswer, a regular
+	// expression applied to the whole (trimmed) answer.
+	QuestionTypeRegex = "regex"
+)
+
+// ScenarioStepQuestion represents a quiz question within a scenario step.
+// A step's questions are its pool: when the step sets QuizDrawCount, each
+// session is asked a subset of them.
 type ScenarioStepQuestion struct {
 	entityManagementModels.BaseModel
 	StepID        uuid.UUID `gorm:"type:uuid;not null;index" json:"step_id"`
 	Order         int       `gorm:"not null" json:"order"`
 	QuestionText  string    `gorm:"type:text;not null" json:"question_text"`
-	QuestionType  string    `gorm:"type:varchar(50);not null" json:"question_type"` // multiple_choice, free_text, true_false
-	Options       string    `gorm:"type:text" json:"options,omitempty"`             // JSON array for multiple choice
+	QuestionType  string    `gorm:"type:varchar(50);not null" json:"question_type"` // see the QuestionType constants
+	Options       string    `gorm:"type:text" json:"options,omitempty"`             // JSON array for choice and ordering questions
 	CorrectAnswer string    `gorm:"type:text" json:"-"`                             // hidden from API
 	Explanation   string    `gorm:"type:text" json:"explanation,omitempty"`
 	Points        int       `gorm:"default:1" json:"points"`
+	// Tolerance is the largest accepted distance from CorrectAnswer for a
+	// numeric question. Ignored by the other types.
+	Tolerance float64 `gorm:"default:0" json:"tolerance,omitempty"`
+	// Difficulty and Topic tag the question within its pool. A draw spreads
+	// across topics, so a session is not asked only about one of them.
+	Difficulty string `gorm:"type:varchar(20)" json:"difficulty,omitempty"`
+	Topic      string `gorm:"type:varchar(100)" json:"topic,omitempty"`
 }
 
 func (s ScenarioStepQuestion) GetBaseModel() entityManagementModels.BaseModel {
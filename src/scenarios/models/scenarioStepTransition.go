@@ -0,0 +1,57 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+)
+
+// Transition conditions. A step's transitions are evaluated in Priority order
+// when the learner leaves it; the first one whose condition holds decides
+// where they go, and a step with no matching transition falls through to the
+// next step by Order, as every step did before transitions existed.
+const (
+	// TransitionAlways matches unconditionally. Used to send a remediation
+	// step back to the step it remediates, or to end the scenario early.
+	TransitionAlways = "always"
+	// TransitionQuizScoreBelow matches a quiz step whose score is < Threshold.
+	TransitionQuizScoreBelow = "quiz_score_below"
+	// TransitionQuizScoreAtLeast matches a quiz step whose score is >= Threshold.
+	TransitionQuizScoreAtLeast = "quiz_score_at_least"
+	// TransitionFlag matches when the submitted flag equals Value. Value is an
+	// alternative answer accepted in addition to the step's own flag, so a
+	// scenario can plant a decoy that routes the learner elsewhere.
+	TransitionFlag = "flag"
+	// TransitionVerifyExitCode matches when the verify script exits with the
+	// code in Value. Unlike the other conditions it also applies to a failed
+	// verification, which is how a diagnostic step routes a learner to the
+	// remediation for the specific mistake it detected.
+	TransitionVerifyExitCode = "verify_exit_code"
+)
+
+// ScenarioStepTransition routes a learner from one step to another based on
+// how they completed it.
+type ScenarioStepTransition struct {
+	entityManagementModels.BaseModel
+	StepID    uuid.UUID `gorm:"type:uuid;not null;index" json:"step_id"`
+	Priority  int       `gorm:"default:0" json:"priority"`
+	Condition string    `gorm:"type:varchar(50);not null" json:"condition"`
+	Threshold float64   `gorm:"default:0" json:"threshold,omitempty"` // quiz conditions, in [0, 1]
+	Value     string    `gorm:"type:varchar(1000)" json:"-"`          // flag or exit code; hidden from API (a flag is an answer)
+	// TargetStepOrder is the Order of the step to route to. Nil ends the
+	// scenario, which a main path needs when remediation steps are placed
+	// after its last step.
+	TargetStepOrder *int `json:"target_step_order"`
+}
+
+func (s ScenarioStepTransition) GetBaseModel() entityManagementModels.BaseModel {
+	return s.BaseModel
+}
+
+func (s ScenarioStepTransition) GetReferenceObject() string {
+	return "ScenarioStepTransition"
+}
+
+func (ScenarioStepTransition) TableName() string {
+	return "scenario_step_transitions"
+}
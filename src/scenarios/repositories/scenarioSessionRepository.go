@@ -20,6 +20,14 @@ type SessionAggregate struct {
 	CompletedAt *time.Time
 }
 
+// StepHintUsage is the projection hint analytics aggregates over: one row per
+// step progress row of a session SessionAggregate would count.
+type StepHintUsage struct {
+	StepOrder     int
+	Status        string
+	HintsRevealed int
+}
+
 // ScenarioSessionRepository is the (deliberately narrow) query surface the
 // teacher dashboard needs from scenario sessions. Extend it method by method
 // as callers migrate off inline SQL; don't widen it speculatively.
@@ -29,6 +37,9 @@ type ScenarioSessionRepository interface {
 	// of the group. Soft-deleted sessions are excluded by GORM's default
 	// scope on the model.
 	GetSessionAggregatesForGroupScenario(groupID, scenarioID uuid.UUID) ([]SessionAggregate, error)
+	// GetStepHintUsageForGroupScenario returns the hint projection of the
+	// step progress rows of those same sessions.
+	GetStepHintUsageForGroupScenario(groupID, scenarioID uuid.UUID) ([]StepHintUsage, error)
 }
 
 type scenarioSessionRepository struct {
@@ -51,3 +62,18 @@ func (r *scenarioSessionRepository) GetSessionAggregatesForGroupScenario(groupID
 		Scan(&rows).Error
 	return rows, err
 }
+
+func (r *scenarioSessionRepository) GetStepHintUsageForGroupScenario(groupID, scenarioID uuid.UUID) ([]StepHintUsage, error) {
+	var rows []StepHintUsage
+	err := r.db.Model(&models.ScenarioStepProgress{}).
+		Select("scenario_step_progress.step_order", "scenario_step_progress.status",
+			"scenario_step_progress.hints_revealed").
+		Joins("JOIN scenario_sessions ON scenario_sessions.id = scenario_step_progress.session_id"+
+			" AND scenario_sessions.deleted_at IS NULL").
+		Joins("JOIN group_members ON group_members.user_id = scenario_sessions.user_id"+
+			" AND group_members.group_id = ? AND group_members.is_active = ?", groupID, true).
+		Where("scenario_sessions.scenario_id = ?", scenarioID).
+		Where("scenario_sessions.is_preview = ?", false).
+		Scan(&rows).Error
+	return rows, err
+}
@@ -44,6 +44,11 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
 			Description: "Get session flags (must own the session)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/scenario-sessions/:id/nodes", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
+			Description: "List the machines of a multi-node session (must own the session)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/scenario-sessions/:id/current-step", Method: "GET",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
@@ -69,6 +74,11 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
 			Description: "Submit quiz answers for a scenario session step (must own the session)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/scenario-sessions/:id/submit-exam", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
+			Description: "Hand in an exam before its time limit (must own the session)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/scenario-sessions/:id/steps/:stepOrder/hints/:level/reveal", Method: "POST",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
@@ -79,6 +89,11 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
 			Description: "Abandon a session (must own the session)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/scenario-sessions/:id/team", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
+			Description: "Play a session as a team on its shared terminal (must own the session)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/scenario-sessions/:id/reprovision-step", Method: "POST",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.EntityOwner, Entity: "ScenarioSession", Field: "UserID"},
@@ -115,11 +130,21 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
 			Description: "View the merged per-learner live class view (presence + scenario position + results)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/teacher/groups/:groupId/live-progress/stream", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
+			Description: "Stream the live class view as Server-Sent Events (pushed on learner progress and presence changes)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/teacher/groups/:groupId/assignments-progress", Method: "GET",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
 			Description: "View per-scenario assignment progress for a group",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/teacher/groups/:groupId/gradebook", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
+			Description: "Export a group's gradebook (JSON, CSV, XLSX or Moodle grade import)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/teacher/groups/:groupId/scenarios/:scenarioId/results", Method: "GET",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
@@ -130,6 +155,11 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
 			Description: "View scenario analytics for a group",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/teacher/groups/:groupId/scenarios/:scenarioId/integrity", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
+			Description: "View the flag-sharing and similar-history report of a group's assignment",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/teacher/groups/:groupId/sessions/:sessionId/detail", Method: "GET",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
@@ -145,6 +175,11 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
 			Description: "View terminal command history for a student's scenario session (proxies to tt-backend)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/teacher/groups/:groupId/sessions/:sessionId/recording", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
+			Description: "Replay the terminal recording of a student's scenario session, subject to recording consent",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/teacher/groups/:groupId/scenarios/:scenarioId/bulk-start", Method: "POST",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "groupId", MinRole: "manager"},
@@ -220,6 +255,89 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
 			Description: "Duplicate a scenario within an organization",
 		},
+		// Organization Git repositories of scenarios
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-git-sources", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "List the Git repositories an organization's scenarios are synced from",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-git-sources", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "Register a Git repository of scenarios for an organization",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-git-sources/:sourceId", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "Get one of an organization's Git repositories of scenarios",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-git-sources/:sourceId", Method: "DELETE",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "Stop syncing an organization's scenarios from a Git repository",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-git-sources/:sourceId/sync", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "Sync an organization's scenarios from a Git repository now",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-git-sources/:sourceId/syncs", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "List the syncs of an organization's Git repository of scenarios",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenario-git-sources/:id/webhook", Method: "POST", NoGateway: true,
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Receive a Git host's push webhook (no auth: verified with the source's webhook secret)",
+		},
+		// Per-learner deadline extensions of assignments
+		access.RoutePermission{
+			Path: "/api/v1/scenario-assignments/:id/extensions", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "List an assignment's deadline extensions (manager of its group or organization)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenario-assignments/:id/extensions/:userId", Method: "PUT",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Grant a learner a deadline extension (manager of the assignment's group or organization)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenario-assignments/:id/extensions/:userId", Method: "DELETE",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Revoke a learner's deadline extension (manager of the assignment's group or organization)",
+		},
+		// Public scenario catalog
+		access.RoutePermission{
+			Path: "/api/v1/catalog/scenarios", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Search the catalog of public scenarios",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/catalog/scenarios/:id", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Get a catalog scenario with its ratings and usage statistics",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/catalog/scenarios/:id/reviews", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "List the reviews of a catalog scenario",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/catalog/scenarios/:id/reviews", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Review a catalog scenario (service verifies the caller completed it)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/catalog/scenarios/:id/reviews/:reviewId", Method: "DELETE",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Delete a review (service verifies the caller wrote it, or is an admin)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/scenario-forks", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"},
+			Description: "Fork a catalog scenario into an organization",
+		},
 		// Admin scenario routes
 		access.RoutePermission{
 			Path: "/api/v1/scenarios/import", Method: "POST",
@@ -251,6 +369,16 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
 			Description: "Import scenarios from JSON (admin only)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/scenarios/lint", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.Public},
+			Description: "Validate a scenario in the JSON import format without importing it",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenarios/:id/lint", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Validate a scenario's draft (controller verifies CanManageScenario: creator, org manager, group manager, or admin)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/scenarios/:id/archive", Method: "POST",
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
@@ -261,6 +389,26 @@ func RegisterScenarioPermissions(enforcer interfaces.EnforcerInterface) {
 			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
 			Description: "Unarchive a scenario (controller verifies CanManageScenario: creator, org manager, group manager, or admin)",
 		},
+		access.RoutePermission{
+			Path: "/api/v1/scenarios/:id/revisions", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "List a scenario's published revisions (controller verifies CanManageScenario: creator, org manager, group manager, or admin)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenarios/:id/revisions", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Publish a scenario's draft as a new revision (controller verifies CanManageScenario: creator, org manager, group manager, or admin)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenarios/:id/revisions/diff", Method: "GET",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Compare two versions of a scenario (controller verifies CanManageScenario: creator, org manager, group manager, or admin)",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/scenarios/:id/revisions/:number/rollback", Method: "POST",
+			Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped},
+			Description: "Roll a scenario's draft back to a revision (controller verifies CanManageScenario: creator, org manager, group manager, or admin)",
+		},
 		access.RoutePermission{
 			Path: "/api/v1/scenarios/:id/duplicate", Method: "POST",
 			Role: access.RoleAdministrator, Access: access.AccessRule{Type: access.AdminOnly},
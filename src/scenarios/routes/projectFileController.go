@@ -13,6 +13,7 @@ import (
 
 	"soli/formations/src/auth/errors"
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
 )
 
 type projectFileController struct {
@@ -72,9 +73,19 @@ func (c *projectFileController) GetContent(ctx *gin.Context) {
 		return
 	}
 
+	content, err := services.LoadProjectFileContent(&file)
+	if err != nil {
+		slog.Error("failed to load project file content", "id", fileID, "storage_type", file.StorageType, "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to retrieve file",
+		})
+		return
+	}
+
 	// Images are stored as base64 — decode and serve with proper MIME type
 	if file.ContentType == "image" {
-		data, err := base64.StdEncoding.DecodeString(file.Content)
+		data, err := base64.StdEncoding.DecodeString(content)
 		if err != nil {
 			slog.Error("failed to decode image content", "id", fileID, "err", err)
 			ctx.JSON(http.StatusInternalServerError, &errors.APIError{
@@ -99,7 +110,7 @@ func (c *projectFileController) GetContent(ctx *gin.Context) {
 		contentType = "text/x-shellscript; charset=utf-8"
 	}
 
-	ctx.Data(http.StatusOK, contentType, []byte(file.Content))
+	ctx.Data(http.StatusOK, contentType, []byte(content))
 }
 
 // projectFileListItem is a DTO for the by-scenario list (metadata only, no content).
@@ -137,7 +148,7 @@ func (c *projectFileController) GetByScenario(ctx *gin.Context) {
 	// Load scenario with steps
 	var scenario models.Scenario
 	if err := c.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
+		return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 	}).First(&scenario, "id = ?", scenarioID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			ctx.JSON(http.StatusNotFound, &errors.APIError{
@@ -398,7 +409,17 @@ func (c *projectFileController) GetImage(ctx *gin.Context) {
 		return
 	}
 
-	data, err := base64.StdEncoding.DecodeString(file.Content)
+	content, err := services.LoadProjectFileContent(&file)
+	if err != nil {
+		slog.Error("failed to load image content", "id", file.ID, "storage_type", file.StorageType, "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to retrieve image",
+		})
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(content)
 	if err != nil {
 		slog.Error("failed to decode image content", "id", file.ID, "err", err)
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
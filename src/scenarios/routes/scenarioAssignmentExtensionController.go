@@ -0,0 +1,143 @@
+package scenarioController
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/services"
+)
+
+// scenarioAssignmentExtensionController lets an assignment's managers move
+// one learner's deadline.
+type scenarioAssignmentExtensionController struct {
+	scenarioControllerBase
+	extensionService *services.AssignmentExtensionService
+}
+
+func NewScenarioAssignmentExtensionController(db *gorm.DB) *scenarioAssignmentExtensionController {
+	return &scenarioAssignmentExtensionController{
+		scenarioControllerBase: scenarioControllerBase{db: db},
+		extensionService:       services.NewAssignmentExtensionService(db),
+	}
+}
+
+// ListExtensions godoc
+// @Summary List an assignment's deadline extensions
+// @Description Returns the learners granted their own deadline for the assignment, to its group or organization managers.
+// @Tags scenario-assignments
+// @Produce json
+// @Param id path string true "Assignment ID"
+// @Success 200 {array} models.ScenarioAssignmentExtension
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenario-assignments/{id}/extensions [get]
+// @Security BearerAuth
+func (ec *scenarioAssignmentExtensionController) ListExtensions(ctx *gin.Context) {
+	assignmentID, ok := parseUUIDParam(ctx, "id", "Invalid assignment ID")
+	if !ok {
+		return
+	}
+	extensions, err := ec.extensionService.List(assignmentID, ctx.GetString("userId"), ec.hasAdminRole(ctx))
+	if err != nil {
+		ec.respondExtensionError(ctx, err, "Failed to list extensions")
+		return
+	}
+	ctx.JSON(http.StatusOK, extensions)
+}
+
+// GrantExtension godoc
+// @Summary Grant a learner a deadline extension
+// @Description Sets the learner's own deadline for the assignment, replacing any extension they had. It applies wherever the deadline does: starting sessions, late grading and reminders.
+// @Tags scenario-assignments
+// @Accept json
+// @Produce json
+// @Param id path string true "Assignment ID"
+// @Param userId path string true "Learner's user ID"
+// @Param body body dto.GrantAssignmentExtensionInput true "New deadline"
+// @Success 200 {object} models.ScenarioAssignmentExtension
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenario-assignments/{id}/extensions/{userId} [put]
+// @Security BearerAuth
+func (ec *scenarioAssignmentExtensionController) GrantExtension(ctx *gin.Context) {
+	assignmentID, ok := parseUUIDParam(ctx, "id", "Invalid assignment ID")
+	if !ok {
+		return
+	}
+	var input dto.GrantAssignmentExtensionInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	extension, err := ec.extensionService.Grant(assignmentID, ctx.Param("userId"), input.Deadline, input.Reason,
+		ctx.GetString("userId"), ec.hasAdminRole(ctx))
+	if err != nil {
+		ec.respondExtensionError(ctx, err, "Failed to grant the extension")
+		return
+	}
+	ctx.JSON(http.StatusOK, extension)
+}
+
+// RevokeExtension godoc
+// @Summary Revoke a learner's deadline extension
+// @Description The assignment's own deadline applies to the learner again.
+// @Tags scenario-assignments
+// @Param id path string true "Assignment ID"
+// @Param userId path string true "Learner's user ID"
+// @Success 204
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenario-assignments/{id}/extensions/{userId} [delete]
+// @Security BearerAuth
+func (ec *scenarioAssignmentExtensionController) RevokeExtension(ctx *gin.Context) {
+	assignmentID, ok := parseUUIDParam(ctx, "id", "Invalid assignment ID")
+	if !ok {
+		return
+	}
+	if err := ec.extensionService.Revoke(assignmentID, ctx.Param("userId"), ctx.GetString("userId"), ec.hasAdminRole(ctx)); err != nil {
+		ec.respondExtensionError(ctx, err, "Failed to revoke the extension")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ec *scenarioAssignmentExtensionController) respondExtensionError(ctx *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: "Assignment not found",
+		})
+	case stderrors.Is(err, services.ErrExtensionNotFound):
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: err.Error(),
+		})
+	case stderrors.Is(err, services.ErrExtensionNotAllowed):
+		ctx.JSON(http.StatusForbidden, &errors.APIError{
+			ErrorCode:    http.StatusForbidden,
+			ErrorMessage: err.Error(),
+		})
+	default:
+		slog.Error("assignment extension operation failed", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: message,
+		})
+	}
+}
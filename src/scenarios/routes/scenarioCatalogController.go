@@ -0,0 +1,249 @@
+package scenarioController
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/services"
+)
+
+// scenarioCatalogController serves the public scenario catalog: search,
+// ratings and reviews, and forks into an organization.
+type scenarioCatalogController struct {
+	scenarioControllerBase
+	catalogService *services.ScenarioCatalogService
+}
+
+func NewScenarioCatalogController(db *gorm.DB) *scenarioCatalogController {
+	return &scenarioCatalogController{
+		scenarioControllerBase: scenarioControllerBase{db: db},
+		catalogService:         services.NewScenarioCatalogService(db),
+	}
+}
+
+// SearchCatalog godoc
+// @Summary Search the scenario catalog
+// @Description Searches the public scenarios by text over their title, description and objectives, with filters. Each result carries its rating and usage statistics.
+// @Tags scenario-catalog
+// @Produce json
+// @Param q query string false "Text to search for"
+// @Param difficulty query string false "Difficulty (beginner, intermediate, advanced)"
+// @Param os_type query string false "OS type (deb, rpm, apk, pacman)"
+// @Param max_minutes query int false "Longest estimated time, in minutes"
+// @Param features query string false "Comma-separated features every result requires"
+// @Param supported_features query string false "Comma-separated features; results require no other"
+// @Param sort query string false "relevance (default with q), recent (default), rating or popular"
+// @Param limit query int false "Page size (default 20, at most 100)"
+// @Param offset query int false "Results to skip"
+// @Success 200 {object} dto.CatalogPage
+// @Failure 400 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /catalog/scenarios [get]
+// @Security BearerAuth
+func (cc *scenarioCatalogController) SearchCatalog(ctx *gin.Context) {
+	var input dto.CatalogSearchInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	switch input.Sort {
+	case "", dto.CatalogSortRelevance, dto.CatalogSortRecent, dto.CatalogSortRating, dto.CatalogSortPopular:
+	default:
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: "sort must be relevance, recent, rating or popular",
+		})
+		return
+	}
+
+	page, err := cc.catalogService.Search(input)
+	if err != nil {
+		cc.respondCatalogError(ctx, err, "Failed to search the catalog")
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// GetCatalogScenario godoc
+// @Summary Get a catalog scenario
+// @Description Returns a public scenario with its rating distribution, usage statistics and latest reviews.
+// @Tags scenario-catalog
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Success 200 {object} dto.CatalogScenarioDetail
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /catalog/scenarios/{id} [get]
+// @Security BearerAuth
+func (cc *scenarioCatalogController) GetCatalogScenario(ctx *gin.Context) {
+	scenarioID, ok := parseUUIDParam(ctx, "id", "Invalid scenario ID")
+	if !ok {
+		return
+	}
+	detail, err := cc.catalogService.Get(scenarioID)
+	if err != nil {
+		cc.respondCatalogError(ctx, err, "Failed to load the scenario")
+		return
+	}
+	ctx.JSON(http.StatusOK, detail)
+}
+
+// ListReviews godoc
+// @Summary List a catalog scenario's reviews
+// @Description Returns the reviews of a public scenario, most recently written first.
+// @Tags scenario-catalog
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Param limit query int false "Page size (default 20, at most 100)"
+// @Param offset query int false "Reviews to skip"
+// @Success 200 {array} dto.ScenarioReviewOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /catalog/scenarios/{id}/reviews [get]
+// @Security BearerAuth
+func (cc *scenarioCatalogController) ListReviews(ctx *gin.Context) {
+	scenarioID, ok := parseUUIDParam(ctx, "id", "Invalid scenario ID")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	reviews, err := cc.catalogService.ListReviews(scenarioID, limit, offset)
+	if err != nil {
+		cc.respondCatalogError(ctx, err, "Failed to list reviews")
+		return
+	}
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// ReviewScenario godoc
+// @Summary Review a catalog scenario
+// @Description Rates a public scenario from 1 to 5 stars, with an optional comment. Only learners who completed the scenario may review it; reviewing again replaces the previous review.
+// @Tags scenario-catalog
+// @Accept json
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Param body body dto.UpsertScenarioReviewInput true "Review"
+// @Success 200 {object} dto.ScenarioReviewOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /catalog/scenarios/{id}/reviews [post]
+// @Security BearerAuth
+func (cc *scenarioCatalogController) ReviewScenario(ctx *gin.Context) {
+	scenarioID, ok := parseUUIDParam(ctx, "id", "Invalid scenario ID")
+	if !ok {
+		return
+	}
+	var input dto.UpsertScenarioReviewInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	review, err := cc.catalogService.UpsertReview(scenarioID, ctx.GetString("userId"), input)
+	if err != nil {
+		cc.respondCatalogError(ctx, err, "Failed to save the review")
+		return
+	}
+	ctx.JSON(http.StatusOK, review)
+}
+
+// DeleteReview godoc
+// @Summary Delete a review
+// @Description Deletes a review of a catalog scenario. Its author and administrators may delete it.
+// @Tags scenario-catalog
+// @Param id path string true "Scenario ID"
+// @Param reviewId path string true "Review ID"
+// @Success 204
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /catalog/scenarios/{id}/reviews/{reviewId} [delete]
+// @Security BearerAuth
+func (cc *scenarioCatalogController) DeleteReview(ctx *gin.Context) {
+	scenarioID, ok := parseUUIDParam(ctx, "id", "Invalid scenario ID")
+	if !ok {
+		return
+	}
+	reviewID, ok := parseUUIDParam(ctx, "reviewId", "Invalid review ID")
+	if !ok {
+		return
+	}
+	if err := cc.catalogService.DeleteReview(scenarioID, reviewID, ctx.GetString("userId"), cc.hasAdminRole(ctx)); err != nil {
+		cc.respondCatalogError(ctx, err, "Failed to delete the review")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ForkScenario godoc
+// @Summary Fork a catalog scenario into an organization
+// @Description Copies a public scenario, with its steps and files, into the organization as a private scenario that keeps a link to the scenario it was forked from.
+// @Tags scenario-catalog
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param body body dto.ForkScenarioInput true "Scenario to fork"
+// @Success 201 {object} dto.ScenarioOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /organizations/{id}/scenario-forks [post]
+// @Security BearerAuth
+func (cc *scenarioCatalogController) ForkScenario(ctx *gin.Context) {
+	orgID, ok := parseUUIDParam(ctx, "id", "Invalid organization ID")
+	if !ok {
+		return
+	}
+	var input dto.ForkScenarioInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	fork, err := cc.catalogService.Fork(input.ScenarioID, orgID, ctx.GetString("userId"))
+	if err != nil {
+		cc.respondCatalogError(ctx, err, "Failed to fork the scenario")
+		return
+	}
+	ctx.JSON(http.StatusCreated, cc.buildScenarioOutput(fork))
+}
+
+func (cc *scenarioCatalogController) respondCatalogError(ctx *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, services.ErrCatalogScenarioNotFound), stderrors.Is(err, services.ErrReviewNotFound):
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: err.Error(),
+		})
+	case stderrors.Is(err, services.ErrReviewNotAllowed):
+		ctx.JSON(http.StatusForbidden, &errors.APIError{
+			ErrorCode:    http.StatusForbidden,
+			ErrorMessage: err.Error(),
+		})
+	default:
+		slog.Error("scenario catalog operation failed", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: message,
+		})
+	}
+}
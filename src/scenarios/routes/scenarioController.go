@@ -17,6 +17,7 @@ import (
 	"soli/formations/src/scenarios/models"
 	"soli/formations/src/scenarios/services"
 	"soli/formations/src/scenarios/utils"
+	terminalServices "soli/formations/src/terminalTrainer/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -38,6 +39,12 @@ type ScenarioController interface {
 	DuplicateScenario(ctx *gin.Context)
 	ArchiveScenario(ctx *gin.Context)
 	UnarchiveScenario(ctx *gin.Context)
+	ListRevisions(ctx *gin.Context)
+	PublishRevision(ctx *gin.Context)
+	DiffRevisions(ctx *gin.Context)
+	RollbackRevision(ctx *gin.Context)
+	LintScenarioInput(ctx *gin.Context)
+	LintScenario(ctx *gin.Context)
 }
 
 type scenarioController struct {
@@ -48,6 +55,8 @@ type scenarioController struct {
 	duplicateService *services.ScenarioDuplicateService
 	groupService     groupServices.GroupService
 	sessionService   *services.ScenarioSessionService
+	revisionService  *services.ScenarioRevisionService
+	linter           *services.ScenarioLinter
 }
 
 // NewScenarioController creates a new scenario controller with its service dependencies
@@ -60,6 +69,8 @@ func NewScenarioController(db *gorm.DB) ScenarioController {
 		duplicateService:       services.NewScenarioDuplicateService(db),
 		groupService:           groupServices.NewGroupService(db),
 		sessionService:         services.NewScenarioSessionService(db, services.NewFlagService(), services.NewVerificationService()),
+		revisionService:        services.NewScenarioRevisionService(db),
+		linter:                 services.NewScenarioLinter(db, catalogFeatureKeys(terminalServices.NewTerminalTrainerService(db))),
 	}
 }
 
@@ -121,13 +132,20 @@ func (sc *scenarioController) GetSessionByTerminal(ctx *gin.Context) {
 		return
 	}
 
+	// On a shared terminal, a team member gets the team's run under their
+	// own session.
 	userID := ctx.GetString("userId")
+	ownID := session.ID
 	if session.UserID != userID {
-		ctx.JSON(http.StatusForbidden, &errors.APIError{
-			ErrorCode:    http.StatusForbidden,
-			ErrorMessage: "You do not own this session",
-		})
-		return
+		member, err := sc.sessionService.TeamMemberSession(session.ID, userID)
+		if err != nil {
+			ctx.JSON(http.StatusForbidden, &errors.APIError{
+				ErrorCode:    http.StatusForbidden,
+				ErrorMessage: "You do not own this session",
+			})
+			return
+		}
+		ownID = member.ID
 	}
 
 	terminalSessionID := ""
@@ -135,9 +153,9 @@ func (sc *scenarioController) GetSessionByTerminal(ctx *gin.Context) {
 		terminalSessionID = *session.TerminalSessionID
 	}
 	ctx.JSON(http.StatusOK, dto.SessionResponse{
-		ID:                session.ID.String(),
+		ID:                ownID.String(),
 		ScenarioID:        session.ScenarioID.String(),
-		UserID:            session.UserID,
+		UserID:            userID,
 		TrainerID:         session.TrainerID,
 		TerminalSessionID: terminalSessionID,
 		CurrentStep:       session.CurrentStep,
@@ -159,8 +177,18 @@ func (sc *scenarioController) GetSessionByTerminal(ctx *gin.Context) {
 // @Router /scenario-sessions/{id}/info [get]
 // @Security BearerAuth
 func (sc *scenarioController) GetSessionInfo(ctx *gin.Context) {
-	session, err := sc.getSessionIfOwned(ctx)
+	own, err := sc.getOwnSession(ctx)
+	if err != nil {
+		return
+	}
+	// A team member sees the team's run under their own session ID, the one
+	// their requests carry.
+	session, err := sc.sessionService.TeamLeadSession(own)
 	if err != nil {
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: "Session not found",
+		})
 		return
 	}
 
@@ -169,9 +197,9 @@ func (sc *scenarioController) GetSessionInfo(ctx *gin.Context) {
 		terminalSessionID = *session.TerminalSessionID
 	}
 	ctx.JSON(http.StatusOK, dto.SessionResponse{
-		ID:                         session.ID.String(),
+		ID:                         own.ID.String(),
 		ScenarioID:                 session.ScenarioID.String(),
-		UserID:                     session.UserID,
+		UserID:                     own.UserID,
 		TrainerID:                  session.TrainerID,
 		TerminalSessionID:          terminalSessionID,
 		CurrentStep:                session.CurrentStep,
@@ -358,7 +386,7 @@ func (sc *scenarioController) UploadScenario(ctx *gin.Context) {
 	// Reload with steps
 	var loaded models.Scenario
 	if err := sc.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
+		return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 	}).First(&loaded, "id = ?", scenario.ID).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
 			ErrorCode:    http.StatusInternalServerError,
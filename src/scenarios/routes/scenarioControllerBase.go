@@ -22,7 +22,28 @@ type scenarioControllerBase struct {
 }
 
 // getSessionIfOwned loads a session by ID and checks that the authenticated user owns it.
+// A team member's session resolves to the team's lead session: the run the
+// whole team plays through on the shared terminal.
 func (b *scenarioControllerBase) getSessionIfOwned(ctx *gin.Context) (*models.ScenarioSession, error) {
+	session, err := b.getOwnSession(ctx)
+	if err != nil || session.TeamLeadSessionID == nil {
+		return session, err
+	}
+
+	var lead models.ScenarioSession
+	if err := b.db.First(&lead, "id = ?", *session.TeamLeadSessionID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: "Session not found",
+		})
+		return nil, err
+	}
+	return &lead, nil
+}
+
+// getOwnSession is getSessionIfOwned without the team resolution, for the
+// actions a team member takes on their own record of the run.
+func (b *scenarioControllerBase) getOwnSession(ctx *gin.Context) (*models.ScenarioSession, error) {
 	sessionID, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, &errors.APIError{
@@ -85,25 +106,32 @@ func (b *scenarioControllerBase) hasAdminRole(ctx *gin.Context) bool {
 // buildScenarioOutput converts a Scenario model to a ScenarioOutput DTO
 func (b *scenarioControllerBase) buildScenarioOutput(scenario *models.Scenario) dto.ScenarioOutput {
 	output := dto.ScenarioOutput{
-		ID:               scenario.ID,
-		Name:             scenario.Name,
-		Title:            scenario.Title,
-		Description:      scenario.Description,
-		Difficulty:       scenario.Difficulty,
-		EstimatedTime:    scenario.EstimatedTime,
-		InstanceType:     scenario.InstanceType,
-		OsType:           scenario.OsType,
-		SourceType:       scenario.SourceType,
-		FlagsEnabled:     scenario.FlagsEnabled,
-		AllowedFlagPaths: scenario.AllowedFlagPaths,
-		CrashTraps:       scenario.CrashTraps,
-		IntroText:        scenario.IntroText,
-		FinishText:       scenario.FinishText,
-		CreatedByID:      scenario.CreatedByID,
-		OrganizationID:   scenario.OrganizationID,
-		ArchivedAt:       scenario.ArchivedAt,
-		CreatedAt:        scenario.CreatedAt,
-		UpdatedAt:        scenario.UpdatedAt,
+		ID:                  scenario.ID,
+		Name:                scenario.Name,
+		Title:               scenario.Title,
+		Description:         scenario.Description,
+		Difficulty:          scenario.Difficulty,
+		EstimatedTime:       scenario.EstimatedTime,
+		InstanceType:        scenario.InstanceType,
+		OsType:              scenario.OsType,
+		SourceType:          scenario.SourceType,
+		GitRepository:       scenario.GitRepository,
+		GitBranch:           scenario.GitBranch,
+		GitSourceID:         scenario.GitSourceID,
+		GitCommitSHA:        scenario.GitCommitSHA,
+		FlagsEnabled:        scenario.FlagsEnabled,
+		AllowedFlagPaths:    scenario.AllowedFlagPaths,
+		CrashTraps:          scenario.CrashTraps,
+		SnapshotSteps:       scenario.SnapshotSteps,
+		IntroText:           scenario.IntroText,
+		FinishText:          scenario.FinishText,
+		CreatedByID:         scenario.CreatedByID,
+		OrganizationID:      scenario.OrganizationID,
+		ArchivedAt:          scenario.ArchivedAt,
+		PublishedRevisionID: scenario.PublishedRevisionID,
+		ForkedFromID:        scenario.ForkedFromID,
+		CreatedAt:           scenario.CreatedAt,
+		UpdatedAt:           scenario.UpdatedAt,
 	}
 	if len(scenario.Steps) > 0 {
 		steps := make([]dto.ScenarioStepOutput, 0, len(scenario.Steps))
@@ -0,0 +1,302 @@
+package scenarioController
+
+import (
+	stderrors "errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// maxPushWebhookBody bounds the push payloads read; GitHub caps its own at 25 MB.
+const maxPushWebhookBody = 25 << 20
+
+// gitSyncHistoryLimit is how many past syncs ListGitSyncs returns.
+const gitSyncHistoryLimit = 50
+
+type scenarioGitSourceController struct {
+	gitSyncService *services.ScenarioGitSyncService
+}
+
+func NewScenarioGitSourceController(db *gorm.DB) *scenarioGitSourceController {
+	return &scenarioGitSourceController{gitSyncService: services.NewScenarioGitSyncService(db)}
+}
+
+// CreateGitSource godoc
+// @Summary Register a Git repository of scenarios
+// @Description Registers a repository the organization's scenarios are synced from: every directory holding an index.json is imported as a scenario. SSH repositories need one of the caller's SSH keys. The response carries the push webhook's secret; it is not shown again.
+// @Tags scenario-git-sources
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param body body dto.CreateScenarioGitSourceInput true "Repository"
+// @Success 201 {object} dto.ScenarioGitSourceWithSecret
+// @Failure 400 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /organizations/{id}/scenario-git-sources [post]
+// @Security BearerAuth
+func (gc *scenarioGitSourceController) CreateGitSource(ctx *gin.Context) {
+	orgID, ok := parseUUIDParam(ctx, "id", "Invalid organization ID")
+	if !ok {
+		return
+	}
+	var input dto.CreateScenarioGitSourceInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	if err := services.ValidateRepositoryURL(input.RepositoryURL); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	source, err := gc.gitSyncService.RegisterSource(orgID, ctx.GetString("userId"), input)
+	if err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to register repository")
+		return
+	}
+	ctx.JSON(http.StatusCreated, dto.ScenarioGitSourceWithSecret{
+		ScenarioGitSource: *source,
+		WebhookSecret:     source.WebhookSecret,
+		WebhookPath:       "/api/v1/scenario-git-sources/" + source.ID.String() + "/webhook",
+	})
+}
+
+// ListGitSources godoc
+// @Summary List an organization's Git repositories of scenarios
+// @Description Returns the repositories the organization's scenarios are synced from, with the outcome of their latest sync. Webhook secrets are not included.
+// @Tags scenario-git-sources
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} models.ScenarioGitSource
+// @Failure 400 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /organizations/{id}/scenario-git-sources [get]
+// @Security BearerAuth
+func (gc *scenarioGitSourceController) ListGitSources(ctx *gin.Context) {
+	orgID, ok := parseUUIDParam(ctx, "id", "Invalid organization ID")
+	if !ok {
+		return
+	}
+	sources, err := gc.gitSyncService.ListSources(orgID)
+	if err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to list repositories")
+		return
+	}
+	ctx.JSON(http.StatusOK, sources)
+}
+
+// GetGitSource godoc
+// @Summary Get a Git repository of scenarios
+// @Tags scenario-git-sources
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param sourceId path string true "Source ID"
+// @Success 200 {object} models.ScenarioGitSource
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Router /organizations/{id}/scenario-git-sources/{sourceId} [get]
+// @Security BearerAuth
+func (gc *scenarioGitSourceController) GetGitSource(ctx *gin.Context) {
+	source := gc.loadSource(ctx)
+	if source == nil {
+		return
+	}
+	ctx.JSON(http.StatusOK, source)
+}
+
+// DeleteGitSource godoc
+// @Summary Stop syncing a Git repository
+// @Description Deletes the source and its sync history. The scenarios it synced are kept as they are.
+// @Tags scenario-git-sources
+// @Param id path string true "Organization ID"
+// @Param sourceId path string true "Source ID"
+// @Success 204
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /organizations/{id}/scenario-git-sources/{sourceId} [delete]
+// @Security BearerAuth
+func (gc *scenarioGitSourceController) DeleteGitSource(ctx *gin.Context) {
+	source := gc.loadSource(ctx)
+	if source == nil {
+		return
+	}
+	if err := gc.gitSyncService.DeleteSource(source.OrganizationID, source.ID); err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to delete repository")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// SyncGitSource godoc
+// @Summary Sync a Git repository now
+// @Description Pulls the repository and re-imports its scenarios, publishing a new revision of each one that changed. A scenario failing validation is reported in the results and left as it was. A repository that cannot be pulled yields a sync with status "failed".
+// @Tags scenario-git-sources
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param sourceId path string true "Source ID"
+// @Success 200 {object} models.ScenarioGitSync
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 409 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /organizations/{id}/scenario-git-sources/{sourceId}/sync [post]
+// @Security BearerAuth
+func (gc *scenarioGitSourceController) SyncGitSource(ctx *gin.Context) {
+	source := gc.loadSource(ctx)
+	if source == nil {
+		return
+	}
+	run, err := gc.gitSyncService.Sync(source.ID, models.GitSyncTriggerManual)
+	if err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to sync repository")
+		return
+	}
+	ctx.JSON(http.StatusOK, run)
+}
+
+// ListGitSyncs godoc
+// @Summary List a Git repository's syncs
+// @Description Returns the latest syncs of the repository, newest first, each with the commit pulled and what became of every scenario.
+// @Tags scenario-git-sources
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param sourceId path string true "Source ID"
+// @Success 200 {array} models.ScenarioGitSync
+// @Failure 400 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /organizations/{id}/scenario-git-sources/{sourceId}/syncs [get]
+// @Security BearerAuth
+func (gc *scenarioGitSourceController) ListGitSyncs(ctx *gin.Context) {
+	source := gc.loadSource(ctx)
+	if source == nil {
+		return
+	}
+	syncs, err := gc.gitSyncService.ListSyncs(source.ID, gitSyncHistoryLimit)
+	if err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to list syncs")
+		return
+	}
+	ctx.JSON(http.StatusOK, syncs)
+}
+
+// HandlePushWebhook godoc
+// @Summary Receive a Git host's push webhook
+// @Description Syncs the repository in the background after a push to its branch. The request is authenticated with the source's webhook secret: GitHub's X-Hub-Signature-256, Gitea's or Forgejo's X-Gitea-Signature, or GitLab's X-Gitlab-Token. Pushes to other branches and tags are ignored.
+// @Tags scenario-git-sources
+// @Accept json
+// @Produce json
+// @Param id path string true "Source ID"
+// @Success 202 {object} map[string]string
+// @Failure 401 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Router /scenario-git-sources/{id}/webhook [post]
+func (gc *scenarioGitSourceController) HandlePushWebhook(ctx *gin.Context) {
+	sourceID, ok := parseUUIDParam(ctx, "id", "Invalid source ID")
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPushWebhookBody))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: "Failed to read payload",
+		})
+		return
+	}
+	source, err := gc.gitSyncService.LoadSource(sourceID)
+	if err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to load repository")
+		return
+	}
+	if err := services.VerifyPushWebhook(source.WebhookSecret, ctx.Request.Header, body); err != nil {
+		ctx.JSON(http.StatusUnauthorized, &errors.APIError{
+			ErrorCode:    http.StatusUnauthorized,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	if branch, ok := services.PushedBranch(body); !ok || (branch != "" && branch != source.Branch) {
+		ctx.JSON(http.StatusAccepted, gin.H{"status": "ignored"})
+		return
+	}
+	gc.gitSyncService.SyncInBackground(source.ID, models.GitSyncTriggerWebhook)
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+}
+
+// loadSource loads the source named by the path, scoped to the organization
+// in it, writing the error response when it cannot.
+func (gc *scenarioGitSourceController) loadSource(ctx *gin.Context) *models.ScenarioGitSource {
+	orgID, ok := parseUUIDParam(ctx, "id", "Invalid organization ID")
+	if !ok {
+		return nil
+	}
+	sourceID, ok := parseUUIDParam(ctx, "sourceId", "Invalid source ID")
+	if !ok {
+		return nil
+	}
+	source, err := gc.gitSyncService.GetSource(orgID, sourceID)
+	if err != nil {
+		gc.respondGitSyncError(ctx, err, "Failed to load repository")
+		return nil
+	}
+	return source
+}
+
+// respondGitSyncError maps the git sync service's errors to responses.
+func (gc *scenarioGitSourceController) respondGitSyncError(ctx *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, services.ErrGitSourceNotFound):
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: err.Error(),
+		})
+	case stderrors.Is(err, services.ErrSSHKeyRequired), stderrors.Is(err, services.ErrSSHKeyNotFound):
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+	case stderrors.Is(err, services.ErrGitSyncInProgress):
+		ctx.JSON(http.StatusConflict, &errors.APIError{
+			ErrorCode:    http.StatusConflict,
+			ErrorMessage: err.Error(),
+		})
+	default:
+		slog.Error("scenario git sync operation failed", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: message,
+		})
+	}
+}
+
+// parseUUIDParam parses a UUID path parameter, answering 400 with message
+// when it is not one.
+func parseUUIDParam(ctx *gin.Context, name, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param(name))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: message,
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -60,6 +60,16 @@ func NewScenarioLaunchController(db *gorm.DB) *scenarioLaunchController {
 		return terminalService.BuildComplete(terminalSessionID)
 	})
 
+	// Step snapshots, for scenarios that take them: taken as the learner's
+	// storage, the scenario's snapshot of a step replacing any earlier one.
+	sessionService.SetTerminalSnapshotFuncs(
+		func(terminalSessionID, name string, stepOrder int) error {
+			_, err := terminalService.ReplaceSnapshot(terminalSessionID, name, "", &stepOrder)
+			return err
+		},
+		terminalService.RestoreSnapshot,
+	)
+
 	// Subscribe to SIGKILLed console shells so a crash trap can end the run.
 	// terminalTrainer publishes the event rather than calling us directly: it
 	// is the lower layer (scenarios imports it, not the reverse), so this is
@@ -193,8 +203,8 @@ func (sc *scenarioLaunchController) StartScenario(ctx *gin.Context) {
 		var count int64
 		if len(groupIDs) > 0 {
 			if err := sc.db.Model(&models.ScenarioAssignment{}).
-				Where("scenario_id = ? AND group_id IN ? AND scope = ? AND is_active = true AND (deadline IS NULL OR deadline > ?) AND (start_date IS NULL OR start_date <= ?)",
-					scenarioID, groupIDs, "group", time.Now(), time.Now()).
+				Scopes(models.OpenAssignmentsFor("scenario_assignments", userID, time.Now())).
+				Where("scenario_id = ? AND group_id IN ? AND scope = ?", scenarioID, groupIDs, "group").
 				Count(&count).Error; err != nil {
 				slog.Error("failed to check group scenario assignment", "err", err)
 				ctx.JSON(http.StatusInternalServerError, &errors.APIError{
@@ -220,8 +230,8 @@ func (sc *scenarioLaunchController) StartScenario(ctx *gin.Context) {
 			}
 			if len(orgIDs) > 0 {
 				if err := sc.db.Model(&models.ScenarioAssignment{}).
-					Where("scenario_id = ? AND organization_id IN ? AND scope = ? AND is_active = true AND (deadline IS NULL OR deadline > ?) AND (start_date IS NULL OR start_date <= ?)",
-						scenarioID, orgIDs, "org", time.Now(), time.Now()).
+					Scopes(models.OpenAssignmentsFor("scenario_assignments", userID, time.Now())).
+					Where("scenario_id = ? AND organization_id IN ? AND scope = ?", scenarioID, orgIDs, "org").
 					Count(&count).Error; err != nil {
 					slog.Error("failed to check org scenario assignment", "err", err)
 					ctx.JSON(http.StatusInternalServerError, &errors.APIError{
@@ -255,6 +265,9 @@ func (sc *scenarioLaunchController) StartScenario(ctx *gin.Context) {
 			})
 			return
 		}
+		if writeLaunchRejection(ctx, err) {
+			return
+		}
 		slog.Error("failed to start scenario", "err", err)
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
 			ErrorCode:    http.StatusInternalServerError,
@@ -389,7 +402,7 @@ func (sc *scenarioLaunchController) GetAvailableScenarios(ctx *gin.Context) {
 				Scopes(models.NotArchived).
 				Preload("CompatibleInstanceTypes").
 				Joins("JOIN scenario_assignments sa ON sa.scenario_id = scenarios.id").
-				Where("sa.is_active = true AND (sa.deadline IS NULL OR sa.deadline > ?) AND (sa.start_date IS NULL OR sa.start_date <= ?)", now, now).
+				Scopes(models.OpenAssignmentsFor("sa", userID, now)).
 				Where(combined, args...)
 
 			if err := query.Find(&scenarios).Error; err != nil {
@@ -658,8 +671,47 @@ const (
 	blockReasonDeclaredImageUnavail = "declared_image_unavailable"
 	blockReasonBudgetExhausted      = "budget_exhausted"
 	blockReasonSessionExists        = "session_exists"
+	blockReasonExamNotOpen          = "exam_not_open"
+	blockReasonExamTaken            = "exam_taken"
+	blockReasonAssignmentNotStarted = "assignment_not_started"
+	blockReasonAssignmentClosed     = "assignment_closed"
 )
 
+// writeLaunchRejection answers a launch refused by the exam or assignment
+// deadline rules with the reason the launcher needs to explain it, and
+// reports whether err was one.
+func writeLaunchRejection(ctx *gin.Context, err error) bool {
+	switch {
+	case stderrors.Is(err, services.ErrAssignmentNotStarted):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error_code":    http.StatusForbidden,
+			"error_message": "This assignment has not started yet.",
+			"reason":        blockReasonAssignmentNotStarted,
+		})
+	case stderrors.Is(err, services.ErrAssignmentClosed):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error_code":    http.StatusForbidden,
+			"error_message": "The deadline of this assignment has passed.",
+			"reason":        blockReasonAssignmentClosed,
+		})
+	case stderrors.Is(err, services.ErrExamNotOpen):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error_code":    http.StatusForbidden,
+			"error_message": "This exam is not open for starting.",
+			"reason":        blockReasonExamNotOpen,
+		})
+	case stderrors.Is(err, services.ErrExamAlreadyTaken):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error_code":    http.StatusConflict,
+			"error_message": "You have already taken this exam.",
+			"reason":        blockReasonExamTaken,
+		})
+	default:
+		return false
+	}
+	return true
+}
+
 func resolveDistribution(scenario models.Scenario, distributions []terminalDto.TTDistribution, sizes []terminalDto.TTSize) (distName string, size string, features map[string]bool, err error) {
 	requiredFeatures, featErr := scenario.GetRequiredFeatures()
 	if featErr != nil {
@@ -759,25 +811,6 @@ func (sc *scenarioLaunchController) resolveScenarioBackendAndDistribution(
 	scenario models.Scenario,
 	orgID *uuid.UUID,
 ) (backend string, distName string, size string, features map[string]bool, err error) {
-	// Determine candidate backends
-	var candidateBackends []string
-	if orgID != nil {
-		var org orgModels.Organization
-		if err := sc.db.First(&org, "id = ?", *orgID).Error; err == nil {
-			if org.DefaultBackend != "" {
-				candidateBackends = append(candidateBackends, org.DefaultBackend)
-			}
-			for _, b := range org.AllowedBackends {
-				if b != org.DefaultBackend {
-					candidateBackends = append(candidateBackends, b)
-				}
-			}
-		}
-	}
-	if len(candidateBackends) == 0 {
-		candidateBackends = []string{""} // system default
-	}
-
 	// Fetch the size catalog once (cached 60s in the service) so resolveDistribution
 	// can apply launch-time fallback for scenarios with unknown InstanceType values
 	// (typos, stale imports, keys from another tt-backend instance). On fetch
@@ -791,7 +824,7 @@ func (sc *scenarioLaunchController) resolveScenarioBackendAndDistribution(
 
 	// Try each candidate backend
 	var lastErr error
-	for _, b := range candidateBackends {
+	for _, b := range sc.candidateBackends(orgID) {
 		distributions, distErr := sc.terminalService.GetDistributions(b)
 		if distErr != nil {
 			lastErr = distErr
@@ -808,6 +841,30 @@ func (sc *scenarioLaunchController) resolveScenarioBackendAndDistribution(
 	return "", "", "", nil, fmt.Errorf("no compatible distribution on any backend: %v", lastErr)
 }
 
+// candidateBackends lists the backends a launch may use, in the order they
+// are tried: the organization's default, then its other allowed backends,
+// or the system default ("") when the organization sets none.
+func (sc *scenarioLaunchController) candidateBackends(orgID *uuid.UUID) []string {
+	var candidates []string
+	if orgID != nil {
+		var org orgModels.Organization
+		if err := sc.db.First(&org, "id = ?", *orgID).Error; err == nil {
+			if org.DefaultBackend != "" {
+				candidates = append(candidates, org.DefaultBackend)
+			}
+			for _, b := range org.AllowedBackends {
+				if b != org.DefaultBackend {
+					candidates = append(candidates, b)
+				}
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = []string{""} // system default
+	}
+	return candidates
+}
+
 // LaunchScenario godoc
 // @Summary Launch a scenario with auto-provisioned terminal
 // @Description Creates a terminal session and starts a scenario session in one call
@@ -851,9 +908,9 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 		return
 	}
 
-	// Load scenario with CompatibleInstanceTypes and Steps
+	// Load scenario with CompatibleInstanceTypes, Nodes and Steps
 	var scenario models.Scenario
-	if err := sc.db.Preload("CompatibleInstanceTypes").Preload("Steps").First(&scenario, scenarioID).Error; err != nil {
+	if err := sc.db.Preload("CompatibleInstanceTypes").Preload("Nodes").Preload("Steps", models.PublishedSteps).First(&scenario, scenarioID).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, &errors.APIError{
 			ErrorCode:    http.StatusNotFound,
 			ErrorMessage: "Scenario not found",
@@ -867,6 +924,21 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 
 	// Check assignment access: admin OR user has group/org assignment
 	if !sc.hasAdminRole(ctx) {
+		// Outside the dates of the learner's assignments, say so rather than
+		// deny access without a reason.
+		if !scenario.IsPublic {
+			if err := sc.sessionService.CheckAssignmentWindow(userID, scenarioID); err != nil {
+				if writeLaunchRejection(ctx, err) {
+					return
+				}
+				slog.Error("failed to check assignment dates", "err", err)
+				ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+					ErrorCode:    http.StatusInternalServerError,
+					ErrorMessage: "Failed to verify access",
+				})
+				return
+			}
+		}
 		hasAccess, err := sc.checkScenarioAccess(userID, scenarioID)
 		if err != nil {
 			slog.Error("failed to check scenario access", "err", err)
@@ -885,6 +957,20 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 		}
 	}
 
+	// Exam rules are checked before a terminal exists: StartScenario enforces
+	// them too, but only after the container has been provisioned.
+	if err := sc.sessionService.CheckExamEligibility(userID, scenarioID); err != nil {
+		if writeLaunchRejection(ctx, err) {
+			return
+		}
+		slog.Error("failed to check exam eligibility", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to verify access",
+		})
+		return
+	}
+
 	// Read org context from middleware (set by InjectOrgContext)
 	var orgID *uuid.UUID
 	if orgCtx, exists := ctx.Get("org_context_id"); exists {
@@ -895,8 +981,17 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 		}
 	}
 
-	// Resolve backend + distribution using org-aware logic
-	backend, distName, size, features, distErr := sc.resolveScenarioBackendAndDistribution(scenario, orgID)
+	// Resolve backend + distribution using org-aware logic. A multi-node
+	// scenario resolves each of its nodes instead of the scenario's machine.
+	var backend, distName, size string
+	var features map[string]bool
+	var topology []topologyNodeLaunch
+	var distErr error
+	if len(scenario.Nodes) > 0 {
+		backend, topology, distErr = sc.resolveScenarioTopology(scenario, orgID)
+	} else {
+		backend, distName, size, features, distErr = sc.resolveScenarioBackendAndDistribution(scenario, orgID)
+	}
 	if distErr != nil {
 		slog.Error("no compatible distribution for scenario", "scenario", scenario.Name, "err", distErr)
 		ctx.JSON(http.StatusConflict, &errors.APIError{
@@ -915,8 +1010,17 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 	// a size in the request body. Mirrors commit 951b69c (resume path).
 	if planVal, exists := ctx.Get("subscription_plan"); exists && planVal != nil {
 		if plan, ok := planVal.(*paymentModels.SubscriptionPlan); ok {
-			if terminalServices.EnforceLaunchCapacity(ctx, plan, size, sc.terminalService) {
-				return
+			launchSizes := []string{size}
+			if len(topology) > 0 {
+				launchSizes = launchSizes[:0]
+				for _, n := range topology {
+					launchSizes = append(launchSizes, n.Size)
+				}
+			}
+			for _, s := range launchSizes {
+				if terminalServices.EnforceLaunchCapacity(ctx, plan, s, sc.terminalService) {
+					return
+				}
 			}
 		}
 	}
@@ -987,7 +1091,14 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 	// ephemeral; plan-allows-persistence → persistent; else empty default).
 	composedInput.PersistenceMode = terminalServices.ResolveScenarioPersistenceMode(scenario.CrashTraps, plan)
 
-	terminalResp, termErr := sc.terminalService.StartComposedSession(userID, composedInput, plan)
+	var terminalResp *terminalDto.TerminalSessionResponse
+	var nodes []services.TopologyNode
+	var termErr error
+	if len(topology) > 0 {
+		terminalResp, nodes, termErr = sc.startScenarioTopology(userID, composedInput, topology, plan)
+	} else {
+		terminalResp, termErr = sc.terminalService.StartComposedSession(userID, composedInput, plan)
+	}
 	if termErr != nil {
 		slog.Error("failed to create terminal session for scenario", "scenario", scenario.Name, "userID", userID, "err", termErr)
 		// Budget exhaustion answers the same structured 403 the terminal
@@ -1004,7 +1115,13 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 	}
 
 	// Create scenario session
-	session, startErr := sc.sessionService.StartScenario(userID, scenarioID, terminalResp.SessionID)
+	var session *models.ScenarioSession
+	var startErr error
+	if len(nodes) > 0 {
+		session, startErr = sc.sessionService.StartScenarioOnTopology(userID, scenarioID, nodes)
+	} else {
+		session, startErr = sc.sessionService.StartScenario(userID, scenarioID, terminalResp.SessionID)
+	}
 	if startErr != nil {
 		if stderrors.Is(startErr, services.ErrActiveSessionExists) {
 			ctx.JSON(http.StatusConflict, gin.H{
@@ -1014,6 +1131,9 @@ func (sc *scenarioLaunchController) LaunchScenario(ctx *gin.Context) {
 			})
 			return
 		}
+		if writeLaunchRejection(ctx, startErr) {
+			return
+		}
 		slog.Error("failed to start scenario session", "userID", userID, "scenarioID", scenarioID, "err", startErr)
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
 			ErrorCode:    http.StatusInternalServerError,
@@ -1090,12 +1210,12 @@ func (sc *scenarioLaunchController) checkScenarioAccess(userID string, scenarioI
 		return false, nil
 	}
 
-	now := time.Now()
 	combined := strings.Join(conditions, " OR ")
 
 	var count int64
 	if err := sc.db.Model(&models.ScenarioAssignment{}).
-		Where("scenario_id = ? AND is_active = true AND (deadline IS NULL OR deadline > ?) AND (start_date IS NULL OR start_date <= ?)", args[0], now, now).
+		Scopes(models.OpenAssignmentsFor("scenario_assignments", userID, time.Now())).
+		Where("scenario_id = ?", args[0]).
 		Where(combined, args[1:]...).
 		Count(&count).Error; err != nil {
 		return false, err
@@ -1117,9 +1237,9 @@ func (sc *scenarioLaunchController) PreviewScenario(ctx *gin.Context) {
 
 	userID := ctx.GetString("userId")
 
-	// Load scenario with CompatibleInstanceTypes and Steps
+	// Load scenario with CompatibleInstanceTypes, Nodes and Steps
 	var scenario models.Scenario
-	if err := sc.db.Preload("CompatibleInstanceTypes").Preload("Steps").First(&scenario, scenarioID).Error; err != nil {
+	if err := sc.db.Preload("CompatibleInstanceTypes").Preload("Nodes").Preload("Steps", models.PublishedSteps).First(&scenario, scenarioID).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, &errors.APIError{
 			ErrorCode:    http.StatusNotFound,
 			ErrorMessage: "Scenario not found",
@@ -1174,8 +1294,17 @@ func (sc *scenarioLaunchController) PreviewScenario(ctx *gin.Context) {
 		previewSizes = nil
 	}
 
-	// Find a compatible distribution for the scenario
-	distName, size, features, distErr := resolveDistribution(scenario, distributions, previewSizes)
+	// Find a compatible distribution for the scenario, or for each of its
+	// nodes
+	var distName, size string
+	var features map[string]bool
+	var topology []topologyNodeLaunch
+	var distErr error
+	if len(scenario.Nodes) > 0 {
+		topology, distErr = resolveTopologyNodes(scenario, distributions, previewSizes)
+	} else {
+		distName, size, features, distErr = resolveDistribution(scenario, distributions, previewSizes)
+	}
 	if distErr != nil {
 		slog.Error("no compatible distribution for scenario preview", "scenario", scenario.Name, "err", distErr)
 		ctx.JSON(http.StatusConflict, &errors.APIError{
@@ -1266,7 +1395,15 @@ func (sc *scenarioLaunchController) PreviewScenario(ctx *gin.Context) {
 	// Persistence: SSOT lives in ResolveScenarioPersistenceMode (shared with LaunchScenario).
 	composedInput.PersistenceMode = terminalServices.ResolveScenarioPersistenceMode(scenario.CrashTraps, planResult.Plan)
 
-	terminalResp, termErr := sc.terminalService.StartComposedSession(userID, composedInput, planResult.Plan)
+	var terminalResp *terminalDto.TerminalSessionResponse
+	var termErr error
+	if len(topology) > 0 {
+		var nodes []services.TopologyNode
+		terminalResp, nodes, termErr = sc.startScenarioTopology(userID, composedInput, topology, planResult.Plan)
+		previewOpts = append(previewOpts, services.WithPreviewTopology(nodes))
+	} else {
+		terminalResp, termErr = sc.terminalService.StartComposedSession(userID, composedInput, planResult.Plan)
+	}
 	if termErr != nil {
 		slog.Error("failed to create terminal session for scenario preview", "scenario", scenario.Name, "userID", userID, "err", termErr)
 		if httperrors.WriteBudgetRejection(ctx, termErr, userID) {
@@ -0,0 +1,81 @@
+package scenarioController
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/scenarios/dto"
+	terminalServices "soli/formations/src/terminalTrainer/services"
+)
+
+// LintScenarioInput godoc
+// @Summary Validate a scenario before importing it
+// @Description Dry-runs the checks learners would otherwise hit at play time on a scenario in the JSON import format: flags that are never placed or land outside the allowed paths, scripts without a shebang, quizzes that cannot be passed, transitions to missing steps, features no terminal offers. Nothing is stored.
+// @Tags scenarios
+// @Accept json
+// @Produce json
+// @Param body body dto.SeedScenarioInput true "Scenario data"
+// @Success 200 {object} dto.ScenarioLintReport
+// @Failure 400 {object} errors.APIError
+// @Router /scenarios/lint [post]
+// @Security BearerAuth
+func (sc *scenarioController) LintScenarioInput(ctx *gin.Context) {
+	var input dto.SeedScenarioInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, sc.linter.LintSeedInput(input))
+}
+
+// LintScenario godoc
+// @Summary Validate a scenario's draft
+// @Description Runs the scenario linter on the scenario's draft, the content its next revision would publish.
+// @Tags scenarios
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Success 200 {object} dto.ScenarioLintReport
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenarios/{id}/lint [get]
+// @Security BearerAuth
+func (sc *scenarioController) LintScenario(ctx *gin.Context) {
+	scenario := sc.loadManageableScenario(ctx)
+	if scenario == nil {
+		return
+	}
+
+	report, err := sc.linter.LintStoredScenario(scenario)
+	if err != nil {
+		slog.Error("failed to lint scenario", "scenario_id", scenario.ID, "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to lint scenario",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// catalogFeatureKeys adapts the terminal feature catalog to the linter.
+func catalogFeatureKeys(terminalService terminalServices.TerminalTrainerService) func() ([]string, error) {
+	return func() ([]string, error) {
+		features, err := terminalService.GetCatalogFeatures()
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, len(features))
+		for i, feature := range features {
+			keys[i] = feature.Key
+		}
+		return keys, nil
+	}
+}
@@ -378,7 +378,7 @@ func (sc *scenarioManagementController) GroupUploadScenario(ctx *gin.Context) {
 	// Reload with steps
 	var loaded models.Scenario
 	if err := sc.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
+		return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 	}).First(&loaded, "id = ?", scenario.ID).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
 			ErrorCode:    http.StatusInternalServerError,
@@ -429,7 +429,7 @@ func (sc *scenarioManagementController) OrgListScenarios(ctx *gin.Context) {
 	}
 
 	var scenarios []models.Scenario
-	if err := sc.db.Where("organization_id = ?", orgID).Preload("Steps").Find(&scenarios).Error; err != nil {
+	if err := sc.db.Where("organization_id = ?", orgID).Preload("Steps", models.DraftSteps).Find(&scenarios).Error; err != nil {
 		slog.Error("failed to list org scenarios", "err", err)
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
 			ErrorCode:    http.StatusInternalServerError,
@@ -843,7 +843,7 @@ func (sc *scenarioManagementController) OrgUploadScenario(ctx *gin.Context) {
 	// Reload with steps
 	var loaded models.Scenario
 	if err := sc.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
+		return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 	}).First(&loaded, "id = ?", scenario.ID).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
 			ErrorCode:    http.StatusInternalServerError,
@@ -1048,7 +1048,7 @@ func (sc *scenarioManagementController) ListGroupAvailableScenarios(ctx *gin.Con
 		var orgScenarios []models.Scenario
 		if err := sc.db.Scopes(models.NotArchived).
 			Where("organization_id = ?", group.OrganizationID).
-			Preload("Steps").
+			Preload("Steps", models.PublishedSteps).
 			Find(&orgScenarios).Error; err != nil {
 			slog.Error("failed to fetch org scenarios", "err", err)
 			ctx.JSON(http.StatusInternalServerError, &errors.APIError{
@@ -1069,7 +1069,7 @@ func (sc *scenarioManagementController) ListGroupAvailableScenarios(ctx *gin.Con
 	var groupAssignments []models.ScenarioAssignment
 	if err := sc.db.Where("group_id = ? AND scope = ? AND is_active = true",
 		groupID, "group").
-		Preload("Scenario", models.NotArchived).Preload("Scenario.Steps").
+		Preload("Scenario", models.NotArchived).Preload("Scenario.Steps", models.PublishedSteps).
 		Find(&groupAssignments).Error; err != nil {
 		slog.Error("failed to fetch group scenario assignments", "err", err)
 		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
@@ -11,6 +11,7 @@ import (
 	"soli/formations/src/scenarios/dto"
 	"soli/formations/src/scenarios/models"
 	"soli/formations/src/scenarios/services"
+	terminalDto "soli/formations/src/terminalTrainer/dto"
 	terminalServices "soli/formations/src/terminalTrainer/services"
 
 	"github.com/gin-gonic/gin"
@@ -28,8 +29,11 @@ type ScenarioProgressController interface {
 	SubmitQuiz(ctx *gin.Context)
 	RevealHint(ctx *gin.Context)
 	AbandonSession(ctx *gin.Context)
+	SubmitExam(ctx *gin.Context)
 	ReprovisionStep(ctx *gin.Context)
 	GetSessionFlags(ctx *gin.Context)
+	GetSessionNodes(ctx *gin.Context)
+	ShareSession(ctx *gin.Context)
 }
 
 type scenarioProgressController struct {
@@ -63,6 +67,16 @@ func NewScenarioProgressControllerWithTerminalService(db *gorm.DB, terminalServi
 		return terminalService.BuildComplete(terminalSessionID)
 	})
 
+	// Step snapshots, for scenarios that take them: taken as the learner's
+	// storage, the scenario's snapshot of a step replacing any earlier one.
+	sessionService.SetTerminalSnapshotFuncs(
+		func(terminalSessionID, name string, stepOrder int) error {
+			_, err := terminalService.ReplaceSnapshot(terminalSessionID, name, "", &stepOrder)
+			return err
+		},
+		terminalService.RestoreSnapshot,
+	)
+
 	return &scenarioProgressController{
 		scenarioControllerBase: scenarioControllerBase{db: db},
 		sessionService:         sessionService,
@@ -294,7 +308,7 @@ func (pc *scenarioProgressController) SubmitQuiz(ctx *gin.Context) {
 
 // RevealHint godoc
 // @Summary Reveal a progressive hint
-// @Description Reveal a progressive hint for a specific step in a scenario session. Hints must be revealed sequentially.
+// @Description Reveal a progressive hint for a specific step in a scenario session. Hints must be revealed sequentially. A hint with a penalty reduces the credit of its step; the response reports what the learner can still earn.
 // @Tags scenario-sessions
 // @Produce json
 // @Param id path string true "Session ID"
@@ -335,6 +349,13 @@ func (pc *scenarioProgressController) RevealHint(ctx *gin.Context) {
 		if pc.abortIfSessionNotActive(ctx, err) {
 			return
 		}
+		if stderrors.Is(err, services.ErrHintsDisabled) {
+			ctx.JSON(http.StatusForbidden, &errors.APIError{
+				ErrorCode:    http.StatusForbidden,
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
 		slog.Error("failed to reveal hint", "err", err)
 		ctx.JSON(http.StatusBadRequest, &errors.APIError{
 			ErrorCode:    http.StatusBadRequest,
@@ -359,11 +380,31 @@ func (pc *scenarioProgressController) RevealHint(ctx *gin.Context) {
 // @Router /scenario-sessions/{id}/abandon [post]
 // @Security BearerAuth
 func (pc *scenarioProgressController) AbandonSession(ctx *gin.Context) {
-	session, err := pc.getSessionIfOwned(ctx)
+	session, err := pc.getOwnSession(ctx)
 	if err != nil {
 		return
 	}
 
+	// A team member abandoning only leaves the team: the run and the
+	// terminal are the lead's and carry on without them.
+	if session.TeamLeadSessionID != nil {
+		if err := pc.sessionService.LeaveTeamSession(session.ID); err != nil {
+			slog.Error("failed to leave team session", "err", err)
+			ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+				ErrorCode:    http.StatusInternalServerError,
+				ErrorMessage: "Failed to abandon session",
+			})
+			return
+		}
+		if session.TerminalSessionID != nil && *session.TerminalSessionID != "" {
+			if _, err := pc.terminalService.RemoveTerminalTeamMember(*session.TerminalSessionID, session.UserID); err != nil {
+				slog.Warn("failed to detach the learner from the team terminal", "terminal_session_id", *session.TerminalSessionID, "err", err)
+			}
+		}
+		ctx.JSON(http.StatusOK, dto.MessageResponse{Message: "Session abandoned"})
+		return
+	}
+
 	err = pc.sessionService.AbandonSession(session.ID)
 	if err != nil {
 		slog.Error("failed to abandon session", "err", err)
@@ -382,15 +423,58 @@ func (pc *scenarioProgressController) AbandonSession(ctx *gin.Context) {
 	// or the learner's allowance drains one abandoned run at a time until
 	// nothing will launch and the catalogue simply reports every scenario as
 	// unavailable, with nothing pointing at the cause.
-	if session.TerminalSessionID != nil && *session.TerminalSessionID != "" {
-		if delErr := pc.terminalService.DeleteSession(*session.TerminalSessionID); delErr != nil {
-			slog.Warn("failed to delete terminal session on abandon", "terminal_session_id", *session.TerminalSessionID, "err", delErr)
+	// A multi-node scenario's other machines go with it.
+	for _, terminalID := range pc.sessionTerminals(session) {
+		if delErr := pc.terminalService.DeleteSession(terminalID); delErr != nil {
+			slog.Warn("failed to delete terminal session on abandon", "terminal_session_id", terminalID, "err", delErr)
 		}
 	}
 
 	ctx.JSON(http.StatusOK, dto.MessageResponse{Message: "Session abandoned"})
 }
 
+// SubmitExam godoc
+// @Summary Hand in an exam
+// @Description End an exam session before its time limit. The session is graded immediately, its terminal is stopped, and the exam cannot be started again.
+// @Tags scenario-sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} dto.SubmitExamResponse
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 409 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenario-sessions/{id}/submit-exam [post]
+// @Security BearerAuth
+func (pc *scenarioProgressController) SubmitExam(ctx *gin.Context) {
+	session, err := pc.getSessionIfOwned(ctx)
+	if err != nil {
+		return
+	}
+
+	result, err := pc.sessionService.SubmitExam(session.ID)
+	if err != nil {
+		if pc.abortIfSessionNotActive(ctx, err) {
+			return
+		}
+		if stderrors.Is(err, services.ErrNotAnExam) {
+			ctx.JSON(http.StatusBadRequest, &errors.APIError{
+				ErrorCode:    http.StatusBadRequest,
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+		slog.Error("failed to submit exam", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to submit exam",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
 // ReprovisionStep godoc
 // @Summary Re-run the current step's setup
 // @Description Re-run the current step's background script in the learner's container. Available to the session owner when the session is active or its setup failed. Step scripts are expected to be idempotent; pass force to make the script redo work it already marked as done.
@@ -454,7 +538,10 @@ func (pc *scenarioProgressController) GetSessionFlags(ctx *gin.Context) {
 	// number on its own — see services.StepPosition. Without this the flags
 	// list labelled the first level "0".
 	var steps []models.ScenarioStep
-	pc.db.Where("scenario_id = ?", session.ScenarioID).Order("\"order\" asc").Find(&steps)
+	pc.db.Scopes(models.RevisionSteps(session.RevisionID)).
+		Where("scenario_id = ?", session.ScenarioID).Order("\"order\" asc").Find(&steps)
+	var progress []models.ScenarioStepProgress
+	pc.db.Where("session_id = ?", session.ID).Find(&progress)
 
 	type flagResponse struct {
 		StepOrder int `json:"step_order"`
@@ -470,7 +557,7 @@ func (pc *scenarioProgressController) GetSessionFlags(ctx *gin.Context) {
 		if f.SubmittedFlag != nil {
 			result = append(result, flagResponse{
 				StepOrder:   f.StepOrder,
-				Position:    services.StepPosition(steps, f.StepOrder),
+				Position:    services.SessionStepPosition(steps, progress, f.StepOrder),
 				Flag:        *f.SubmittedFlag,
 				SubmittedAt: f.SubmittedAt,
 			})
@@ -479,3 +566,170 @@ func (pc *scenarioProgressController) GetSessionFlags(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, result)
 }
+
+// GetSessionNodes godoc
+// @Summary List the machines of a session
+// @Description Lists the nodes of a multi-node scenario run with their terminals, primary first, for the console switcher. A single-machine run has none.
+// @Tags scenario-sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {array} dto.ScenarioSessionNodeOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenario-sessions/{id}/nodes [get]
+// @Security BearerAuth
+func (pc *scenarioProgressController) GetSessionNodes(ctx *gin.Context) {
+	session, err := pc.getSessionIfOwned(ctx)
+	if err != nil {
+		return
+	}
+
+	nodes, err := pc.sessionService.SessionNodes(session.ID)
+	if err != nil {
+		slog.Error("failed to list session nodes", "session_id", session.ID, "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to list session nodes",
+		})
+		return
+	}
+
+	result := make([]dto.ScenarioSessionNodeOutput, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, dto.ScenarioSessionNodeOutput{
+			Name:              node.Name,
+			Hostname:          node.Hostname,
+			TerminalSessionID: node.TerminalSessionID,
+			IsPrimary:         node.IsPrimary,
+		})
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// sessionTerminals lists every terminal a session runs on: its own, and the
+// other nodes' for a multi-node scenario.
+func (pc *scenarioProgressController) sessionTerminals(session *models.ScenarioSession) []string {
+	if session.TerminalSessionID == nil || *session.TerminalSessionID == "" {
+		return nil
+	}
+	terminals := []string{*session.TerminalSessionID}
+	nodes, err := pc.sessionService.SessionNodes(session.ID)
+	if err != nil {
+		slog.Warn("failed to list session nodes", "session_id", session.ID, "err", err)
+		return terminals
+	}
+	for _, node := range nodes {
+		if !node.IsPrimary {
+			terminals = append(terminals, node.TerminalSessionID)
+		}
+	}
+	return terminals
+}
+
+// ShareSession godoc
+// @Summary Play a scenario as a team
+// @Description Shares the session's terminal with a sub-group the learner belongs to and/or classmates, who join as navigators, and gives each of them a session mirroring this one. The team plays through this session; its progress, status and grade are every member's.
+// @Tags scenario-sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param body body dto.ShareScenarioSessionInput true "Sub-group and/or learners"
+// @Success 200 {object} dto.ScenarioTeamResponse
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 409 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenario-sessions/{id}/team [post]
+// @Security BearerAuth
+func (pc *scenarioProgressController) ShareSession(ctx *gin.Context) {
+	session, err := pc.getOwnSession(ctx)
+	if err != nil {
+		return
+	}
+	if session.TeamLeadSessionID != nil || session.TerminalSessionID == nil || *session.TerminalSessionID == "" {
+		ctx.JSON(http.StatusConflict, &errors.APIError{
+			ErrorCode:    http.StatusConflict,
+			ErrorMessage: services.ErrTeamLeadNotRunning.Error(),
+		})
+		return
+	}
+
+	var input dto.ShareScenarioSessionInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	team, err := pc.terminalService.ShareTerminal(*session.TerminalSessionID, terminalDto.ShareTerminalInput{
+		GroupID: input.GroupID,
+		UserIDs: input.UserIDs,
+	}, session.UserID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case stderrors.Is(err, terminalServices.ErrTeamNoMembers):
+			status = http.StatusBadRequest
+		case stderrors.Is(err, terminalServices.ErrTeamNotClassmate),
+			stderrors.Is(err, terminalServices.ErrTeamGroupNotOwners):
+			status = http.StatusForbidden
+		}
+		slog.Warn("failed to share the session's terminal", "session_id", session.ID, "err", err)
+		ctx.JSON(status, &errors.APIError{
+			ErrorCode:    status,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+	// The team plays every machine of a multi-node scenario, not only the
+	// one the session is bound to. The primary terminal's share has already
+	// vetted the team, so a failure on another node only costs the team its
+	// console there.
+	for _, terminalID := range pc.sessionTerminals(session)[1:] {
+		if _, err := pc.terminalService.ShareTerminal(terminalID, terminalDto.ShareTerminalInput{
+			GroupID: input.GroupID,
+			UserIDs: input.UserIDs,
+		}, session.UserID); err != nil {
+			slog.Warn("failed to share a node's terminal with the team", "session_id", session.ID, "terminal_session_id", terminalID, "err", err)
+		}
+	}
+
+	userIDs := make([]string, 0, len(team.Members))
+	for _, member := range team.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	memberSessions, err := pc.sessionService.JoinTeamSession(session.ID, userIDs)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if stderrors.Is(err, services.ErrTeamLeadNotRunning) || stderrors.Is(err, services.ErrTeamMemberBusy) {
+			status = http.StatusConflict
+		}
+		slog.Warn("failed to start the team's sessions", "session_id", session.ID, "err", err)
+		ctx.JSON(status, &errors.APIError{
+			ErrorCode:    status,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	response := dto.ScenarioTeamResponse{
+		SessionID:         session.ID,
+		TerminalSessionID: *session.TerminalSessionID,
+		Members:           make([]dto.ScenarioTeamMemberOutput, 0, len(team.Members)),
+	}
+	for _, member := range team.Members {
+		sessionID := session.ID
+		if member.UserID != session.UserID {
+			sessionID = memberSessions[member.UserID]
+		}
+		response.Members = append(response.Members, dto.ScenarioTeamMemberOutput{
+			UserID:    member.UserID,
+			Role:      member.Role,
+			SessionID: sessionID,
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,198 @@
+package scenarioController
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListRevisions godoc
+// @Summary List a scenario's published revisions
+// @Description Returns the scenario's revisions, newest first, with how many sessions each was run by. The current revision is the one new sessions start on.
+// @Tags scenarios
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Success 200 {array} dto.ScenarioRevisionOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenarios/{id}/revisions [get]
+// @Security BearerAuth
+func (sc *scenarioController) ListRevisions(ctx *gin.Context) {
+	scenario := sc.loadManageableScenario(ctx)
+	if scenario == nil {
+		return
+	}
+
+	revisions, err := sc.revisionService.ListRevisions(scenario.ID)
+	if err != nil {
+		slog.Error("failed to list scenario revisions", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: "Failed to list revisions",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+// PublishRevision godoc
+// @Summary Publish a scenario's draft
+// @Description Freezes the scenario's steps, hints, questions and scripts as a new immutable revision that new sessions start on. Sessions already running keep the revision they started on.
+// @Tags scenarios
+// @Accept json
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Param body body dto.PublishScenarioRevisionInput false "Revision note"
+// @Success 201 {object} dto.ScenarioRevisionOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 409 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenarios/{id}/revisions [post]
+// @Security BearerAuth
+func (sc *scenarioController) PublishRevision(ctx *gin.Context) {
+	scenario := sc.loadManageableScenario(ctx)
+	if scenario == nil {
+		return
+	}
+
+	var input dto.PublishScenarioRevisionInput
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, &errors.APIError{
+				ErrorCode:    http.StatusBadRequest,
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+	}
+
+	revision, err := sc.revisionService.Publish(scenario.ID, ctx.GetString("userId"), input.Note)
+	if err != nil {
+		sc.respondRevisionError(ctx, err, "Failed to publish scenario")
+		return
+	}
+	sc.respondWithRevision(ctx, http.StatusCreated, scenario.ID, revision)
+}
+
+// DiffRevisions godoc
+// @Summary Compare two versions of a scenario
+// @Description Lists the steps added, removed or changed between two versions, each a revision number or "draft". from defaults to the current revision and to to the draft, which previews what publishing would change.
+// @Tags scenarios
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Param from query string false "Revision number or draft"
+// @Param to query string false "Revision number or draft"
+// @Success 200 {object} dto.ScenarioRevisionDiff
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenarios/{id}/revisions/diff [get]
+// @Security BearerAuth
+func (sc *scenarioController) DiffRevisions(ctx *gin.Context) {
+	scenario := sc.loadManageableScenario(ctx)
+	if scenario == nil {
+		return
+	}
+
+	diff, err := sc.revisionService.Diff(scenario.ID, ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		sc.respondRevisionError(ctx, err, "Failed to compare revisions")
+		return
+	}
+	ctx.JSON(http.StatusOK, diff)
+}
+
+// RollbackRevision godoc
+// @Summary Roll a scenario back to a revision
+// @Description Replaces the scenario's draft with the content of a published revision and makes that revision the one new sessions start on. No revision is deleted.
+// @Tags scenarios
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} dto.ScenarioRevisionOutput
+// @Failure 400 {object} errors.APIError
+// @Failure 403 {object} errors.APIError
+// @Failure 404 {object} errors.APIError
+// @Failure 500 {object} errors.APIError
+// @Router /scenarios/{id}/revisions/{number}/rollback [post]
+// @Security BearerAuth
+func (sc *scenarioController) RollbackRevision(ctx *gin.Context) {
+	scenario := sc.loadManageableScenario(ctx)
+	if scenario == nil {
+		return
+	}
+	number, err := strconv.Atoi(ctx.Param("number"))
+	if err != nil || number < 1 {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: "Invalid revision number",
+		})
+		return
+	}
+
+	revision, err := sc.revisionService.Rollback(scenario.ID, number)
+	if err != nil {
+		sc.respondRevisionError(ctx, err, "Failed to roll back scenario")
+		return
+	}
+	sc.respondWithRevision(ctx, http.StatusOK, scenario.ID, revision)
+}
+
+// respondWithRevision answers with revision as ListRevisions lists it.
+func (sc *scenarioController) respondWithRevision(ctx *gin.Context, status int, scenarioID uuid.UUID, revision *models.ScenarioRevision) {
+	revisions, err := sc.revisionService.ListRevisions(scenarioID)
+	if err == nil {
+		for _, r := range revisions {
+			if r.ID == revision.ID {
+				ctx.JSON(status, r)
+				return
+			}
+		}
+	}
+	slog.Error("failed to reload scenario revision", "err", err, "revision_id", revision.ID)
+	ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+		ErrorCode:    http.StatusInternalServerError,
+		ErrorMessage: "Failed to load revision",
+	})
+}
+
+// respondRevisionError maps the revision service's errors to responses.
+func (sc *scenarioController) respondRevisionError(ctx *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, services.ErrRevisionNotFound):
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: err.Error(),
+		})
+	case stderrors.Is(err, services.ErrNoStepsToPublish):
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+	case stderrors.Is(err, services.ErrNothingToPublish):
+		ctx.JSON(http.StatusConflict, &errors.APIError{
+			ErrorCode:    http.StatusConflict,
+			ErrorMessage: err.Error(),
+		})
+	default:
+		slog.Error("scenario revision operation failed", "err", err)
+		ctx.JSON(http.StatusInternalServerError, &errors.APIError{
+			ErrorCode:    http.StatusInternalServerError,
+			ErrorMessage: message,
+		})
+	}
+}
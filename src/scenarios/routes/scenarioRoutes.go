@@ -28,10 +28,16 @@ func ScenarioRoutes(router *gin.RouterGroup, _ *config.Configuration, db *gorm.D
 	scenarioRoutes.GET("/:id/export", middleware.AuthManagement(), controller.ExportScenario)
 	scenarioRoutes.POST("/export", middleware.AuthManagement(), controller.ExportScenarios)
 	scenarioRoutes.POST("/import-json", middleware.AuthManagement(), controller.ImportJSON)
+	scenarioRoutes.POST("/lint", middleware.AuthManagement(), controller.LintScenarioInput)
+	scenarioRoutes.GET("/:id/lint", middleware.AuthManagement(), controller.LintScenario)
 	scenarioRoutes.POST("/:id/duplicate", middleware.AuthManagement(), controller.DuplicateScenario)
 	scenarioRoutes.POST("/:id/preview", middleware.AuthManagement(), launchController.PreviewScenario)
 	scenarioRoutes.POST("/:id/archive", middleware.AuthManagement(), controller.ArchiveScenario)
 	scenarioRoutes.POST("/:id/unarchive", middleware.AuthManagement(), controller.UnarchiveScenario)
+	scenarioRoutes.GET("/:id/revisions", middleware.AuthManagement(), controller.ListRevisions)
+	scenarioRoutes.POST("/:id/revisions", middleware.AuthManagement(), controller.PublishRevision)
+	scenarioRoutes.GET("/:id/revisions/diff", middleware.AuthManagement(), controller.DiffRevisions)
+	scenarioRoutes.POST("/:id/revisions/:number/rollback", middleware.AuthManagement(), controller.RollbackRevision)
 
 	// Session routes (students)
 	rateLimiter := scenarioMiddleware.PerUserRateLimit()
@@ -49,6 +55,7 @@ func ScenarioRoutes(router *gin.RouterGroup, _ *config.Configuration, db *gorm.D
 	sessionRoutes.GET("/by-terminal/:terminalId", middleware.AuthManagement(), controller.GetSessionByTerminal)
 	sessionRoutes.GET("/:id/info", middleware.AuthManagement(), controller.GetSessionInfo)
 	sessionRoutes.GET("/:id/flags", middleware.AuthManagement(), progressController.GetSessionFlags)
+	sessionRoutes.GET("/:id/nodes", middleware.AuthManagement(), progressController.GetSessionNodes)
 	sessionRoutes.GET("/:id/current-step", middleware.AuthManagement(), progressController.GetCurrentStep)
 	sessionRoutes.GET("/:id/step/:stepOrder", middleware.AuthManagement(), progressController.GetStepByOrder)
 	sessionRoutes.POST("/:id/verify", middleware.AuthManagement(), rateLimiter, progressController.VerifyStep)
@@ -56,6 +63,8 @@ func ScenarioRoutes(router *gin.RouterGroup, _ *config.Configuration, db *gorm.D
 	sessionRoutes.POST("/:id/submit-quiz", middleware.AuthManagement(), rateLimiter, progressController.SubmitQuiz)
 	sessionRoutes.POST("/:id/steps/:stepOrder/hints/:level/reveal", middleware.AuthManagement(), progressController.RevealHint)
 	sessionRoutes.POST("/:id/abandon", middleware.AuthManagement(), progressController.AbandonSession)
+	sessionRoutes.POST("/:id/team", middleware.AuthManagement(), progressController.ShareSession)
+	sessionRoutes.POST("/:id/submit-exam", middleware.AuthManagement(), progressController.SubmitExam)
 	sessionRoutes.POST("/:id/reprovision-step", middleware.AuthManagement(), rateLimiter, progressController.ReprovisionStep)
 	// Budget enforcement is performed inside LaunchScenario via
 	// QuotaService.CheckBudget; no middleware-level slot counter is needed.
@@ -98,6 +107,35 @@ func ScenarioRoutes(router *gin.RouterGroup, _ *config.Configuration, db *gorm.D
 	orgScenarioRoutes.DELETE("/:scenarioId", middleware.AuthManagement(), managementController.OrgDeleteScenario)
 	orgScenarioRoutes.POST("/:scenarioId/duplicate", middleware.AuthManagement(), managementController.OrgDuplicateScenario)
 
+	// Organization Git repositories of scenarios (org managers)
+	gitSourceCtrl := NewScenarioGitSourceController(db)
+	orgGitSourceRoutes := router.Group("/organizations/:id/scenario-git-sources")
+	orgGitSourceRoutes.GET("", middleware.AuthManagement(), gitSourceCtrl.ListGitSources)
+	orgGitSourceRoutes.POST("", middleware.AuthManagement(), gitSourceCtrl.CreateGitSource)
+	orgGitSourceRoutes.GET("/:sourceId", middleware.AuthManagement(), gitSourceCtrl.GetGitSource)
+	orgGitSourceRoutes.DELETE("/:sourceId", middleware.AuthManagement(), gitSourceCtrl.DeleteGitSource)
+	orgGitSourceRoutes.POST("/:sourceId/sync", middleware.AuthManagement(), gitSourceCtrl.SyncGitSource)
+	orgGitSourceRoutes.GET("/:sourceId/syncs", middleware.AuthManagement(), gitSourceCtrl.ListGitSyncs)
+	// Push webhook: no auth, the Git host signs it with the source's secret
+	router.POST("/scenario-git-sources/:id/webhook", gitSourceCtrl.HandlePushWebhook)
+
+	// Public scenario catalog
+	catalogCtrl := NewScenarioCatalogController(db)
+	catalogRoutes := router.Group("/catalog/scenarios")
+	catalogRoutes.GET("", middleware.AuthManagement(), catalogCtrl.SearchCatalog)
+	catalogRoutes.GET("/:id", middleware.AuthManagement(), catalogCtrl.GetCatalogScenario)
+	catalogRoutes.GET("/:id/reviews", middleware.AuthManagement(), catalogCtrl.ListReviews)
+	catalogRoutes.POST("/:id/reviews", middleware.AuthManagement(), catalogCtrl.ReviewScenario)
+	catalogRoutes.DELETE("/:id/reviews/:reviewId", middleware.AuthManagement(), catalogCtrl.DeleteReview)
+	router.POST("/organizations/:id/scenario-forks", middleware.AuthManagement(), catalogCtrl.ForkScenario)
+
+	// Per-learner deadline extensions of assignments (group/org managers)
+	extensionCtrl := NewScenarioAssignmentExtensionController(db)
+	extensionRoutes := router.Group("/scenario-assignments/:id/extensions")
+	extensionRoutes.GET("", middleware.AuthManagement(), extensionCtrl.ListExtensions)
+	extensionRoutes.PUT("/:userId", middleware.AuthManagement(), extensionCtrl.GrantExtension)
+	extensionRoutes.DELETE("/:userId", middleware.AuthManagement(), extensionCtrl.RevokeExtension)
+
 	// ProjectFile custom routes
 	projectFileCtrl := NewProjectFileController(db)
 	projectFileRoutes := router.Group("/project-files")
@@ -112,12 +150,16 @@ func ScenarioRoutes(router *gin.RouterGroup, _ *config.Configuration, db *gorm.D
 	teacherRoutes.GET("/groups", middleware.AuthManagement(), teacherCtrl.GetMyGroups)
 	teacherRoutes.GET("/groups/:groupId/activity", middleware.AuthManagement(), teacherCtrl.GetGroupActivity)
 	teacherRoutes.GET("/groups/:groupId/live-progress", middleware.AuthManagement(), teacherCtrl.GetGroupLiveProgress)
+	teacherRoutes.GET("/groups/:groupId/live-progress/stream", middleware.AuthManagement(), teacherCtrl.StreamGroupLiveProgress)
 	teacherRoutes.GET("/groups/:groupId/assignments-progress", middleware.AuthManagement(), teacherCtrl.GetGroupAssignmentsProgress)
+	teacherRoutes.GET("/groups/:groupId/gradebook", middleware.AuthManagement(), teacherCtrl.GetGroupGradebook)
 	teacherRoutes.GET("/groups/:groupId/scenarios/:scenarioId/results", middleware.AuthManagement(), teacherCtrl.GetScenarioResults)
 	teacherRoutes.GET("/groups/:groupId/scenarios/:scenarioId/analytics", middleware.AuthManagement(), teacherCtrl.GetScenarioAnalytics)
+	teacherRoutes.GET("/groups/:groupId/scenarios/:scenarioId/integrity", middleware.AuthManagement(), teacherCtrl.GetScenarioIntegrityReport)
 	teacherRoutes.GET("/groups/:groupId/sessions/:sessionId/detail", middleware.AuthManagement(), teacherCtrl.GetSessionDetail)
 	teacherRoutes.POST("/groups/:groupId/sessions/details", middleware.AuthManagement(), teacherCtrl.GetSessionDetailsBulk)
 	teacherRoutes.GET("/groups/:groupId/sessions/:sessionId/commands", middleware.AuthManagement(), teacherCtrl.GetSessionCommands)
+	teacherRoutes.GET("/groups/:groupId/sessions/:sessionId/recording", middleware.AuthManagement(), teacherCtrl.GetSessionRecording)
 	teacherRoutes.POST("/groups/:groupId/scenarios/:scenarioId/bulk-start", middleware.AuthManagement(), teacherCtrl.BulkStartScenario)
 	teacherRoutes.POST("/groups/:groupId/scenarios/:scenarioId/reset-sessions", middleware.AuthManagement(), teacherCtrl.ResetGroupScenarioSessions)
 }
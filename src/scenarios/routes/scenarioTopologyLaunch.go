@@ -0,0 +1,153 @@
+package scenarioController
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+	terminalDto "soli/formations/src/terminalTrainer/dto"
+
+	"github.com/google/uuid"
+)
+
+// topologyNodeLaunch is one node of a multi-node scenario once resolved
+// against a backend's catalog.
+type topologyNodeLaunch struct {
+	Node         models.ScenarioNode
+	Distribution string
+	Size         string
+	Features     map[string]bool
+}
+
+// nodeScenario presents a node as the single-machine scenario
+// resolveDistribution knows how to place: the node's distribution is its
+// only declared image, its size and features stand in for the scenario's.
+// The OS family stays the scenario's, so a node naming no distribution lands
+// on the same kind of system as the rest of the scenario.
+//
+// Step banners are drawn on the session's terminal, so only the primary node
+// carries the steps scenarioFeatures derives the renderer from.
+func nodeScenario(scenario models.Scenario, node models.ScenarioNode, primary bool) models.Scenario {
+	ns := models.Scenario{
+		Name:             scenario.Name + "/" + node.Name,
+		InstanceType:     node.Size,
+		OsType:           scenario.OsType,
+		RequiredFeatures: node.RequiredFeatures,
+	}
+	ns.ID = scenario.ID
+	if primary {
+		ns.Steps = scenario.Steps
+	}
+	if node.Distribution != "" {
+		ns.CompatibleInstanceTypes = []models.ScenarioInstanceType{{InstanceType: node.Distribution}}
+	}
+	return ns
+}
+
+// resolveTopologyNodes places every node of a scenario on one backend's
+// distributions. A node that cannot be placed fails the whole topology: a
+// scenario missing one of its machines cannot be played.
+func resolveTopologyNodes(scenario models.Scenario, distributions []terminalDto.TTDistribution, sizes []terminalDto.TTSize) ([]topologyNodeLaunch, error) {
+	nodes := services.SortNodesByOrder(scenario.Nodes)
+	resolved := make([]topologyNodeLaunch, 0, len(nodes))
+	for i, node := range nodes {
+		distName, size, features, err := resolveDistribution(nodeScenario(scenario, node, i == 0), distributions, sizes)
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", node.Name, err)
+		}
+		resolved = append(resolved, topologyNodeLaunch{
+			Node:         node,
+			Distribution: distName,
+			Size:         size,
+			Features:     features,
+		})
+	}
+	return resolved, nil
+}
+
+// resolveScenarioTopology is resolveScenarioBackendAndDistribution for a
+// multi-node scenario. All nodes must resolve on the same backend, since
+// their private network does not span backends.
+func (sc *scenarioLaunchController) resolveScenarioTopology(scenario models.Scenario, orgID *uuid.UUID) (string, []topologyNodeLaunch, error) {
+	sizes, sizesErr := sc.terminalService.GetCatalogSizes()
+	if sizesErr != nil {
+		slog.Warn("failed to fetch sizes catalog, scenario size fallback disabled", "err", sizesErr)
+		sizes = nil
+	}
+
+	var lastErr error
+	for _, b := range sc.candidateBackends(orgID) {
+		distributions, distErr := sc.terminalService.GetDistributions(b)
+		if distErr != nil {
+			lastErr = distErr
+			continue
+		}
+		nodes, resolveErr := resolveTopologyNodes(scenario, distributions, sizes)
+		if resolveErr != nil {
+			lastErr = resolveErr
+			continue
+		}
+		return b, nodes, nil
+	}
+	return "", nil, fmt.Errorf("no backend can host the whole topology: %v", lastErr)
+}
+
+// topologyNetworkName names the private network of one run of a topology.
+// Short enough for the bridge name limits of the container backends.
+func topologyNetworkName() string {
+	return "scn-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:11]
+}
+
+// startScenarioTopology starts one terminal per node, each a copy of base
+// with the node's machine and hostname, all on a network of their own. It
+// returns the primary node's terminal, which the session is bound to, and the
+// topology for the session service.
+func (sc *scenarioLaunchController) startScenarioTopology(userID string, base terminalDto.CreateComposedSessionInput, nodes []topologyNodeLaunch, plan any) (*terminalDto.TerminalSessionResponse, []services.TopologyNode, error) {
+	network := topologyNetworkName()
+	inputs := make([]terminalDto.CreateComposedSessionInput, 0, len(nodes))
+	for _, n := range nodes {
+		input := base
+		input.Distribution = n.Distribution
+		input.Size = n.Size
+		input.Features = n.Features
+		input.Hostname = n.Node.EffectiveHostname()
+		input.Name = fmt.Sprintf("%s-%s", base.Name, n.Node.Name)
+		input.PrivateNetwork = network
+		inputs = append(inputs, input)
+	}
+
+	responses, err := sc.terminalService.StartComposedTopology(userID, inputs, plan)
+	if err == nil && len(responses) != len(nodes) {
+		err = fmt.Errorf("terminal service started %d of %d nodes", len(responses), len(nodes))
+	}
+	if err != nil {
+		sc.stopTopologyNodes(responses)
+		return nil, nil, err
+	}
+
+	topology := make([]services.TopologyNode, 0, len(nodes))
+	for i, n := range nodes {
+		topology = append(topology, services.TopologyNode{
+			Name:              n.Node.Name,
+			Hostname:          n.Node.EffectiveHostname(),
+			TerminalSessionID: responses[i].SessionID,
+		})
+	}
+	return responses[0], topology, nil
+}
+
+// stopTopologyNodes stops the nodes of a topology that failed to start as a
+// whole. Nothing references them once the launch is refused, so left running
+// they would hold their budget until they expire.
+func (sc *scenarioLaunchController) stopTopologyNodes(responses []*terminalDto.TerminalSessionResponse) {
+	for _, resp := range responses {
+		if resp == nil {
+			continue
+		}
+		if err := sc.terminalService.StopSession(resp.SessionID); err != nil {
+			slog.Warn("failed to stop node of a topology that failed to start", "terminal_session_id", resp.SessionID, "err", err)
+		}
+	}
+}
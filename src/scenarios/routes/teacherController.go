@@ -1,7 +1,9 @@
 package scenarioController
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -126,6 +128,130 @@ func (tc *TeacherController) GetGroupLiveProgress(c *gin.Context) {
 	c.JSON(http.StatusOK, rows)
 }
 
+// StreamGroupLiveProgress godoc
+// @Summary Stream the per-learner live view of a class
+// @Description Server-Sent Events form of live-progress. The first event, "snapshot", carries every
+// @Description row; each "progress" event then carries only the rows that changed (in full) and the
+// @Description learners that left the class ("removed"). Rows are pushed when a learner's step
+// @Description progress, verify attempts, flag submissions, hints or terminal presence change, and
+// @Description the whole class is resynchronised every minute. current_step_elapsed_seconds is as of
+// @Description generated_at. A "ping" event with no data is sent every 25 seconds.
+// @Tags scenario-teacher
+// @Produce text/event-stream
+// @Param groupId path string true "Group ID (UUID)"
+// @Success 200 {object} services.LiveProgressMessage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /teacher/groups/{groupId}/live-progress/stream [get]
+// @Security BearerAuth
+func (tc *TeacherController) StreamGroupLiveProgress(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// nginx buffers proxied responses unless told otherwise.
+	header.Set("X-Accel-Buffering", "no")
+
+	started := false
+	emit := func(event string, payload any) error {
+		if !started {
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if event == services.LiveProgressEventPing {
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return err
+			}
+		} else {
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, data); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if err := tc.dashboardService.StreamGroupLiveProgress(c.Request.Context(), groupID, emit); err != nil {
+		if !started {
+			slog.Error("failed to start group live progress stream", "err", err)
+			header.Del("Content-Type")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group live progress"})
+			return
+		}
+		slog.Debug("group live progress stream closed", "group_id", groupID, "err", err)
+	}
+}
+
+// GetGroupGradebook godoc
+// @Summary Export a class gradebook
+// @Description Returns one row per active learner of the group with, for every active
+// @Description assignment, the status, final grade, completion time, time spent, hints used,
+// @Description verify attempts and quiz score. format=json (default) returns the structure;
+// @Description csv and xlsx return a spreadsheet; moodle returns a CSV in the layout of
+// @Description Moodle's "Import grades from CSV" (learners matched on email address).
+// @Tags scenario-teacher
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param groupId path string true "Group ID (UUID)"
+// @Param format query string false "json, csv, xlsx or moodle (default json)"
+// @Success 200 {object} services.Gradebook
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /teacher/groups/{groupId}/gradebook [get]
+// @Security BearerAuth
+func (tc *TeacherController) GetGroupGradebook(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format == "json" {
+		gradebook, err := tc.dashboardService.GetGroupGradebook(groupID)
+		if err != nil {
+			tc.writeGradebookError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gradebook)
+		return
+	}
+
+	body, contentType, filename, err := tc.dashboardService.ExportGroupGradebook(groupID, format)
+	if err != nil {
+		tc.writeGradebookError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, body)
+}
+
+func (tc *TeacherController) writeGradebookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedGradebookFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, csv, xlsx, moodle"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+	default:
+		slog.Error("failed to build gradebook", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build gradebook"})
+	}
+}
+
 // GetGroupAssignmentsProgress godoc
 // @Summary Get group assignments progress
 // @Description Returns one progress summary per scenario assigned to a group, aggregating active members' non-preview sessions (total, completed, average grade)
@@ -214,7 +340,7 @@ func (tc *TeacherController) GetScenarioResults(c *gin.Context) {
 
 // GetScenarioAnalytics godoc
 // @Summary Get scenario analytics
-// @Description Returns aggregate analytics (completion rate, avg grade, avg time) for a scenario within a group
+// @Description Returns aggregate analytics (completion rate, avg grade, avg time, per-step hint usage and cost) for a scenario within a group
 // @Tags scenario-teacher
 // @Produce json
 // @Param groupId path string true "Group ID (UUID)"
@@ -248,6 +374,47 @@ func (tc *TeacherController) GetScenarioAnalytics(c *gin.Context) {
 	c.JSON(http.StatusOK, analytics)
 }
 
+// GetScenarioIntegrityReport godoc
+// @Summary Get scenario integrity report
+// @Description Flags learners of a group who submitted a flag generated for another session, or whose command histories overlap beyond the scenario's expected solution. Findings are leads for the teacher, not verdicts.
+// @Tags scenario-teacher
+// @Produce json
+// @Param groupId path string true "Group ID (UUID)"
+// @Param scenarioId path string true "Scenario ID (UUID)"
+// @Success 200 {object} services.IntegrityReport
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /teacher/groups/{groupId}/scenarios/{scenarioId}/integrity [get]
+// @Security BearerAuth
+func (tc *TeacherController) GetScenarioIntegrityReport(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	scenarioID, err := uuid.Parse(c.Param("scenarioId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scenario ID"})
+		return
+	}
+
+	report, err := tc.dashboardService.GetScenarioIntegrityReport(groupID, scenarioID)
+	if err != nil {
+		if err == services.ErrScenarioNotAssignedToGroup {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scenario not assigned to this group"})
+			return
+		}
+		slog.Error("failed to get scenario integrity report", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scenario integrity report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // GetSessionDetail godoc
 // @Summary Get session detail
 // @Description Returns step-by-step progress for a specific session within a group
@@ -414,6 +581,74 @@ func (tc *TeacherController) GetSessionCommands(c *gin.Context) {
 	c.Data(http.StatusOK, contentType, body)
 }
 
+// GetSessionRecording godoc
+// @Summary Replay a session's terminal recording
+// @Description Returns the terminal recording of a scenario session as asciicast v2, with a marker at the start of each step.
+// @Description With step, the replay is cut to the time the learner spent on that step (0-based step order).
+// @Description Group managers (and platform admins) only, and only when the learner's recording consent is covered by the group or organization policy or their own acknowledgement.
+// @Tags scenario-teacher
+// @Produce application/x-asciicast
+// @Param groupId path string true "Group ID (UUID)"
+// @Param sessionId path string true "Scenario session ID (UUID)"
+// @Param step query int false "Step order to cut the replay to"
+// @Success 200 {string} string "asciicast v2 recording"
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /teacher/groups/{groupId}/sessions/{sessionId}/recording [get]
+// @Security BearerAuth
+func (tc *TeacherController) GetSessionRecording(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("groupId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	var stepOrder *int
+	if stepStr := c.Query("step"); stepStr != "" {
+		v, err := strconv.Atoi(stepStr)
+		if err != nil || v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step parameter"})
+			return
+		}
+		stepOrder = &v
+	}
+
+	body, err := tc.dashboardService.GetSessionRecording(groupID, sessionID, stepOrder)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSessionNotFound),
+			errors.Is(err, services.ErrSessionNotInGroup),
+			errors.Is(err, services.ErrScenarioNotAssignedToGroup):
+			// Don't leak existence — return 404 for all three
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		case errors.Is(err, services.ErrSessionHasNoTerminal):
+			c.JSON(http.StatusNotFound, gin.H{"error": "session has no terminal yet"})
+		case errors.Is(err, services.ErrStepNotInSession):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRecordingConsentMissing):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, ttServices.ErrRecordingExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			slog.Error("failed to get session recording", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session recording"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.cast\"", sessionID))
+	c.Data(http.StatusOK, "application/x-asciicast", body)
+}
+
 // BulkStartRequest is the request body for bulk starting a scenario
 type BulkStartRequest struct {
 	InstanceType           string `json:"instance_type"`
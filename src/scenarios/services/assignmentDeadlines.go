@@ -0,0 +1,328 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	groupServices "soli/formations/src/groups/services"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/models"
+)
+
+// ErrAssignmentNotStarted is returned when a learner starts a scenario whose
+// assignments to them have not opened yet.
+var ErrAssignmentNotStarted = errors.New("assignment has not started yet")
+
+// ErrAssignmentClosed is returned when a learner starts a scenario whose
+// assignments to them are past their deadline and accept no late work.
+var ErrAssignmentClosed = errors.New("assignment deadline has passed")
+
+// ErrExtensionNotAllowed is returned when someone who does not manage an
+// assignment grants or revokes an extension of it.
+var ErrExtensionNotAllowed = errors.New("only the assignment's group or organization managers manage its extensions")
+
+// ErrExtensionNotFound is returned when revoking an extension the learner
+// does not have.
+var ErrExtensionNotFound = errors.New("extension not found")
+
+// ValidateLatePolicy checks an assignment's late policy settings.
+func ValidateLatePolicy(a *models.ScenarioAssignment) error {
+	switch a.LatePolicy {
+	case "", models.LatePolicyBlock, models.LatePolicyFlag:
+	case models.LatePolicyPenalty:
+		if a.LatePenaltyPercentPerDay <= 0 || a.LatePenaltyPercentPerDay > 100 {
+			return fmt.Errorf("a penalty late policy requires late_penalty_percent_per_day between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("late_policy must be block, penalty or flag")
+	}
+	if a.ReminderHoursBefore < 0 {
+		return fmt.Errorf("reminder_hours_before cannot be negative")
+	}
+	return nil
+}
+
+// learnerAssignments queries the active assignments of a scenario to userID,
+// through their groups and organizations. Nil when the learner belongs to
+// neither.
+func learnerAssignments(db *gorm.DB, userID string, scenarioID uuid.UUID) (*gorm.DB, error) {
+	var groupIDs []uuid.UUID
+	if err := db.Model(&groupModels.GroupMember{}).
+		Where("user_id = ? AND is_active = true", userID).
+		Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load group membership: %w", err)
+	}
+	var orgIDs []uuid.UUID
+	if err := db.Model(&orgModels.OrganizationMember{}).
+		Where("user_id = ? AND is_active = true", userID).
+		Pluck("organization_id", &orgIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load organization membership: %w", err)
+	}
+	if len(groupIDs) == 0 && len(orgIDs) == 0 {
+		return nil, nil
+	}
+
+	query := db.Model(&models.ScenarioAssignment{}).
+		Where("scenario_id = ? AND is_active = true", scenarioID)
+	switch {
+	case len(groupIDs) > 0 && len(orgIDs) > 0:
+		query = query.Where("(scope = 'group' AND group_id IN ?) OR (scope = 'org' AND organization_id IN ?)", groupIDs, orgIDs)
+	case len(groupIDs) > 0:
+		query = query.Where("scope = 'group' AND group_id IN ?", groupIDs)
+	default:
+		query = query.Where("scope = 'org' AND organization_id IN ?", orgIDs)
+	}
+	return query, nil
+}
+
+// applyExtension replaces the assignment's deadline with the learner's
+// extension of it, if they were granted one.
+func applyExtension(db *gorm.DB, a *models.ScenarioAssignment, userID string) error {
+	var extension models.ScenarioAssignmentExtension
+	err := db.Where("assignment_id = ? AND user_id = ?", a.ID, userID).First(&extension).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load deadline extension: %w", err)
+	}
+	deadline := extension.Deadline
+	a.Deadline = &deadline
+	return nil
+}
+
+// findOpenAssignment returns the assignment a learner's new session of the
+// scenario runs under. Of the learner's non-exam assignments of it, the ones
+// open at now are candidates, and the most lenient wins: the one with the
+// latest deadline, or none at all. Its Deadline is the learner's own.
+//
+// When the learner has assignments but none is open, the error says why:
+// ErrAssignmentNotStarted if one will open later, ErrAssignmentClosed
+// otherwise. Nil and no error when the scenario is not assigned to them.
+func findOpenAssignment(db *gorm.DB, userID string, scenarioID uuid.UUID, now time.Time) (*models.ScenarioAssignment, error) {
+	query, err := learnerAssignments(db, userID, scenarioID)
+	if err != nil || query == nil {
+		return nil, err
+	}
+	var assignments []models.ScenarioAssignment
+	if err := query.Where("exam_mode = ?", false).Order("created_at ASC").Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load assignments: %w", err)
+	}
+
+	var open *models.ScenarioAssignment
+	notStarted := false
+	for i := range assignments {
+		a := &assignments[i]
+		if err := applyExtension(db, a, userID); err != nil {
+			return nil, err
+		}
+		switch {
+		case a.StartDate != nil && now.Before(*a.StartDate):
+			notStarted = true
+		case a.Deadline != nil && !now.Before(*a.Deadline) && latePolicy(a) == models.LatePolicyBlock:
+		default:
+			if open == nil || laterDeadline(a.Deadline, open.Deadline) {
+				open = a
+			}
+		}
+	}
+	switch {
+	case open != nil:
+		return open, nil
+	case notStarted:
+		return nil, ErrAssignmentNotStarted
+	case len(assignments) > 0:
+		return nil, ErrAssignmentClosed
+	}
+	return nil, nil
+}
+
+// isAssignmentWindowError reports whether err refuses a session for being
+// outside the learner's assignments of the scenario.
+func isAssignmentWindowError(err error) bool {
+	return errors.Is(err, ErrAssignmentNotStarted) || errors.Is(err, ErrAssignmentClosed)
+}
+
+// CheckAssignmentWindow refuses a launch the assignment dates would refuse,
+// without creating anything, so that LaunchScenario can tell a learner why
+// before provisioning a terminal. Exams are left to CheckExamEligibility.
+func (s *ScenarioSessionService) CheckAssignmentWindow(userID string, scenarioID uuid.UUID) error {
+	exam, err := findExamAssignment(s.db, userID, scenarioID)
+	if err != nil || exam != nil {
+		return err
+	}
+	_, err = findOpenAssignment(s.db, userID, scenarioID, time.Now())
+	return err
+}
+
+// laterDeadline reports whether deadline a is later than b, no deadline being
+// the latest of all.
+func laterDeadline(a, b *time.Time) bool {
+	if b == nil {
+		return false
+	}
+	return a == nil || a.After(*b)
+}
+
+// latePolicy is an assignment's late policy, assignments saved before late
+// policies existed blocking as they always did.
+func latePolicy(a *models.ScenarioAssignment) string {
+	if a.LatePolicy == "" {
+		return models.LatePolicyBlock
+	}
+	return a.LatePolicy
+}
+
+// LatePenaltyPercent is the share of the grade, in percent, a session
+// completed at completedAt loses under the assignment's penalty: its
+// percentage per day or part of a day past the deadline, at most 100.
+func LatePenaltyPercent(a *models.ScenarioAssignment, deadline, completedAt time.Time) float64 {
+	if latePolicy(a) != models.LatePolicyPenalty || !completedAt.After(deadline) {
+		return 0
+	}
+	days := math.Ceil(completedAt.Sub(deadline).Hours() / 24)
+	return math.Min(100, days*a.LatePenaltyPercentPerDay)
+}
+
+// completionUpdates are the column updates completing a session with grade at
+// now. A session of an assignment completed after the learner's deadline is
+// marked late and, under a penalty policy, loses its share of the grade; the
+// grade earned is kept alongside.
+func completionUpdates(db *gorm.DB, session *models.ScenarioSession, grade float64, now time.Time) (map[string]any, error) {
+	updates := map[string]any{
+		"status":       "completed",
+		"completed_at": now,
+		"grade":        grade,
+	}
+	if session.AssignmentID == nil {
+		return updates, nil
+	}
+	var assignment models.ScenarioAssignment
+	err := db.First(&assignment, "id = ?", *session.AssignmentID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return updates, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the session's assignment: %w", err)
+	}
+	if err := applyExtension(db, &assignment, session.UserID); err != nil {
+		return nil, err
+	}
+	if assignment.Deadline == nil || !now.After(*assignment.Deadline) {
+		return updates, nil
+	}
+
+	updates["submitted_late"] = true
+	if penalty := LatePenaltyPercent(&assignment, *assignment.Deadline, now); penalty > 0 {
+		updates["late_penalty_percent"] = penalty
+		updates["grade_before_penalty"] = grade
+		updates["grade"] = math.Round(grade*(100-penalty)) / 100
+	}
+	return updates, nil
+}
+
+// AssignmentExtensionService grants learners their own deadline for an
+// assignment.
+type AssignmentExtensionService struct {
+	db           *gorm.DB
+	groupService groupServices.GroupService
+}
+
+func NewAssignmentExtensionService(db *gorm.DB) *AssignmentExtensionService {
+	return &AssignmentExtensionService{db: db, groupService: groupServices.NewGroupService(db)}
+}
+
+// List returns an assignment's extensions, by learner.
+func (s *AssignmentExtensionService) List(assignmentID uuid.UUID, userID string, isAdmin bool) ([]models.ScenarioAssignmentExtension, error) {
+	if _, err := s.loadManageable(assignmentID, userID, isAdmin); err != nil {
+		return nil, err
+	}
+	var extensions []models.ScenarioAssignmentExtension
+	err := s.db.Where("assignment_id = ?", assignmentID).Order("user_id ASC").Find(&extensions).Error
+	return extensions, err
+}
+
+// Grant sets learnerID's deadline for the assignment, replacing any
+// extension they had.
+func (s *AssignmentExtensionService) Grant(assignmentID uuid.UUID, learnerID string, deadline time.Time, reason, userID string, isAdmin bool) (*models.ScenarioAssignmentExtension, error) {
+	if _, err := s.loadManageable(assignmentID, userID, isAdmin); err != nil {
+		return nil, err
+	}
+	var extension models.ScenarioAssignmentExtension
+	err := s.db.Where("assignment_id = ? AND user_id = ?", assignmentID, learnerID).First(&extension).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		extension = models.ScenarioAssignmentExtension{
+			AssignmentID: assignmentID,
+			UserID:       learnerID,
+			Deadline:     deadline,
+			Reason:       reason,
+			GrantedByID:  userID,
+		}
+		extension.OwnerIDs = append(extension.OwnerIDs, userID)
+		err = s.db.Create(&extension).Error
+	case err == nil:
+		extension.Deadline, extension.Reason, extension.GrantedByID = deadline, reason, userID
+		err = s.db.Model(&extension).Select("deadline", "reason", "granted_by_id").Updates(&extension).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &extension, nil
+}
+
+// Revoke removes learnerID's extension: the assignment's deadline applies to
+// them again.
+func (s *AssignmentExtensionService) Revoke(assignmentID uuid.UUID, learnerID, userID string, isAdmin bool) error {
+	if _, err := s.loadManageable(assignmentID, userID, isAdmin); err != nil {
+		return err
+	}
+	result := s.db.Unscoped().Where("assignment_id = ? AND user_id = ?", assignmentID, learnerID).
+		Delete(&models.ScenarioAssignmentExtension{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrExtensionNotFound
+	}
+	return nil
+}
+
+// loadManageable loads an assignment its managers may grant extensions of:
+// those who may edit it, as the assignment authorization hook decides.
+func (s *AssignmentExtensionService) loadManageable(assignmentID uuid.UUID, userID string, isAdmin bool) (*models.ScenarioAssignment, error) {
+	var assignment models.ScenarioAssignment
+	if err := s.db.First(&assignment, "id = ?", assignmentID).Error; err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		return &assignment, nil
+	}
+	if assignment.GroupID != nil {
+		canManage, err := s.groupService.CanUserManageGroup(*assignment.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !canManage {
+			return nil, ErrExtensionNotAllowed
+		}
+	}
+	if assignment.OrganizationID != nil {
+		var member orgModels.OrganizationMember
+		err := s.db.Where("organization_id = ? AND user_id = ? AND is_active = ?", *assignment.OrganizationID, userID, true).
+			First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !member.IsManager()) {
+			return nil, ErrExtensionNotAllowed
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &assignment, nil
+}
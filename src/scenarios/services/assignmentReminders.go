@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	emailServices "soli/formations/src/email/services"
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/models"
+)
+
+// AssignmentReminderTemplate is the email template of deadline reminders.
+const AssignmentReminderTemplate = "assignment_deadline_reminder"
+
+// LookupReminderRecipient resolves a learner's name and email. A variable so
+// tests can send reminders without a Casdoor server.
+var LookupReminderRecipient = casdoorsdk.GetUserByUserId
+
+// AssignmentReminderService emails learners whose assignment deadline is
+// coming and who have not completed the scenario yet.
+type AssignmentReminderService struct {
+	db           *gorm.DB
+	emailService emailServices.EmailService
+}
+
+func NewAssignmentReminderService(db *gorm.DB, emailService emailServices.EmailService) *AssignmentReminderService {
+	return &AssignmentReminderService{db: db, emailService: emailService}
+}
+
+// SendDueReminders emails every learner whose deadline for an assignment
+// with reminders falls within its reminder_hours_before of now. Each
+// deadline is announced once per learner: the reminder is recorded before
+// the email goes out, and forgotten again if it fails so the next run
+// retries. Returns the number of reminders sent.
+func (s *AssignmentReminderService) SendDueReminders(now time.Time) (int, error) {
+	var assignments []models.ScenarioAssignment
+	if err := s.db.Preload("Scenario").
+		Where("is_active = ? AND reminder_hours_before > 0", true).
+		Where(`(deadline > ? OR EXISTS (SELECT 1 FROM scenario_assignment_extensions ext
+			WHERE ext.assignment_id = scenario_assignments.id AND ext.deadline > ? AND ext.deleted_at IS NULL))`, now, now).
+		Find(&assignments).Error; err != nil {
+		return 0, fmt.Errorf("failed to load assignments with reminders: %w", err)
+	}
+
+	sent := 0
+	for i := range assignments {
+		n, err := s.remindAssignment(&assignments[i], now)
+		if err != nil {
+			return sent, err
+		}
+		sent += n
+	}
+	return sent, nil
+}
+
+// remindAssignment sends the reminders due for one assignment.
+func (s *AssignmentReminderService) remindAssignment(a *models.ScenarioAssignment, now time.Time) (int, error) {
+	learnerIDs, err := s.assignmentLearners(a)
+	if err != nil {
+		return 0, err
+	}
+	window := time.Duration(a.ReminderHoursBefore) * time.Hour
+
+	sent := 0
+	for _, learnerID := range learnerIDs {
+		learnerAssignment := *a
+		if err := applyExtension(s.db, &learnerAssignment, learnerID); err != nil {
+			return sent, err
+		}
+		deadline := learnerAssignment.Deadline
+		if deadline == nil || !deadline.After(now) || deadline.Sub(now) > window {
+			continue
+		}
+
+		var completed int64
+		if err := s.db.Model(&models.ScenarioSession{}).
+			Where("scenario_id = ? AND user_id = ? AND status = ?", a.ScenarioID, learnerID, "completed").
+			Count(&completed).Error; err != nil {
+			return sent, fmt.Errorf("failed to check completion: %w", err)
+		}
+		if completed > 0 {
+			continue
+		}
+
+		reminder := models.ScenarioAssignmentReminder{
+			AssignmentID: a.ID,
+			UserID:       learnerID,
+			Deadline:     *deadline,
+			SentAt:       now,
+		}
+		// The unique index makes the claim: a reminder already recorded for
+		// this deadline, by this run or a concurrent one, is not sent again.
+		claim := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+		if claim.Error != nil {
+			return sent, fmt.Errorf("failed to record reminder: %w", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		if err := s.sendReminder(a, learnerID, *deadline, now); err != nil {
+			slog.Warn("failed to send assignment deadline reminder",
+				"assignment_id", a.ID, "user_id", learnerID, "err", err)
+			s.db.Unscoped().Delete(&reminder)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// assignmentLearners lists the learners an assignment is for: the learners
+// of its group, or the members of its organization.
+func (s *AssignmentReminderService) assignmentLearners(a *models.ScenarioAssignment) ([]string, error) {
+	var userIDs []string
+	var err error
+	switch {
+	case a.GroupID != nil:
+		err = s.db.Model(&groupModels.GroupMember{}).
+			Scopes(groupModels.LearnerRoleScope("group_members")).
+			Where("group_id = ? AND is_active = ?", *a.GroupID, true).
+			Distinct().Pluck("user_id", &userIDs).Error
+	case a.OrganizationID != nil:
+		err = s.db.Model(&orgModels.OrganizationMember{}).
+			Where("organization_id = ? AND role = ? AND is_active = ?", *a.OrganizationID, orgModels.OrgRoleMember, true).
+			Distinct().Pluck("user_id", &userIDs).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the assignment's learners: %w", err)
+	}
+	return userIDs, nil
+}
+
+func (s *AssignmentReminderService) sendReminder(a *models.ScenarioAssignment, learnerID string, deadline, now time.Time) error {
+	user, err := LookupReminderRecipient(learnerID)
+	if err != nil || user == nil {
+		return fmt.Errorf("failed to look up learner: %v", err)
+	}
+	if user.Email == "" {
+		return fmt.Errorf("learner has no email address")
+	}
+	name := user.DisplayName
+	if name == "" {
+		name = user.Name
+	}
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:4000"
+	}
+
+	return s.emailService.SendTemplatedEmail(user.Email, AssignmentReminderTemplate, map[string]interface{}{
+		"UserName":      name,
+		"ScenarioTitle": a.Scenario.Title,
+		"Deadline":      deadline.UTC().Format("2006-01-02 15:04 MST"),
+		"HoursLeft":     fmt.Sprintf("%.0f", deadline.Sub(now).Hours()),
+		"LateWorkNote":  lateWorkNote(a),
+		"ScenarioLink":  fmt.Sprintf("%s/scenarios/%s", frontendURL, a.ScenarioID),
+		"PlatformName":  "OCF Platform",
+	})
+}
+
+// lateWorkNote tells the learner what happens to work completed after the
+// deadline.
+func lateWorkNote(a *models.ScenarioAssignment) string {
+	switch latePolicy(a) {
+	case models.LatePolicyPenalty:
+		return fmt.Sprintf("Work completed after the deadline loses %g%% of its grade per day late.", a.LatePenaltyPercentPerDay)
+	case models.LatePolicyFlag:
+		return "Work completed after the deadline is accepted but marked as late."
+	default:
+		return "The scenario can no longer be started once the deadline has passed."
+	}
+}
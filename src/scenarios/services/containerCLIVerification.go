@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"soli/formations/src/scenarios/models"
+)
+
+// ContainerCLIVerificationService stands in for tt-backend outside of the
+// platform: it runs the engine's commands in a local container through the
+// docker or podman CLI, the session ID being the container's name or ID. The
+// scenario test runner plays scenarios against it.
+type ContainerCLIVerificationService struct {
+	// runtime is the CLI binary, "docker" or "podman" (or anything taking
+	// their exec arguments).
+	runtime string
+}
+
+// NewContainerCLIVerificationService creates a verification service running
+// commands with the given container CLI.
+func NewContainerCLIVerificationService(runtime string) *ContainerCLIVerificationService {
+	return &ContainerCLIVerificationService{runtime: runtime}
+}
+
+// ExecInContainer runs command in the container with `exec -i`. env values are
+// passed through the CLI's own environment with bare `-e NAME` flags, so that
+// like with tt-backend they never appear on a command line.
+func (s *ContainerCLIVerificationService) ExecInContainer(containerID string, command []string, env map[string]string, timeout int) (exitCode int, stdout string, stderr string, err error) {
+	return s.exec(containerID, command, env, nil, timeout)
+}
+
+// PushFile writes content to targetPath in the container, creating its
+// directory, then applies mode.
+func (s *ContainerCLIVerificationService) PushFile(containerID string, targetPath string, content string, mode string) error {
+	const push = `mkdir -p "$(dirname "$1")" && cat > "$1" && chmod "$2" "$1"`
+	exitCode, _, stderr, err := s.exec(containerID, []string{"/bin/sh", "-c", push, "sh", targetPath, mode}, nil, []byte(content), 30)
+	if err != nil {
+		return fmt.Errorf("push file to container failed: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("push file to container failed: exit code %d: %s", exitCode, stderr)
+	}
+	return nil
+}
+
+// WriteToConsole always returns ErrNoLiveConsole: nobody is attached to a
+// local test container, and foreground scripts are skipped as they are for a
+// learner who has not opened their terminal.
+func (s *ContainerCLIVerificationService) WriteToConsole(containerID string, text string) error {
+	return ErrNoLiveConsole
+}
+
+// VerifyStep runs the step's verify script; see VerificationService.VerifyStep.
+func (s *ContainerCLIVerificationService) VerifyStep(containerID string, step *models.ScenarioStep) (passed bool, output string, err error) {
+	exitCode, output, err := runVerifyScript(s, containerID, step)
+	if err != nil {
+		return false, "", err
+	}
+	return exitCode == 0, output, nil
+}
+
+// VerifyStepExitCode runs the step's verify script and reports its exit code.
+func (s *ContainerCLIVerificationService) VerifyStepExitCode(containerID string, step *models.ScenarioStep) (exitCode int, output string, err error) {
+	return runVerifyScript(s, containerID, step)
+}
+
+func (s *ContainerCLIVerificationService) exec(containerID string, command []string, env map[string]string, stdin []byte, timeout int) (int, string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	args := []string{"exec", "-i"}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmdEnv := os.Environ()
+	for _, name := range names {
+		args = append(args, "-e", name)
+		cmdEnv = append(cmdEnv, name+"="+env[name])
+	}
+	args = append(args, containerID)
+	args = append(args, command...)
+
+	cmd := exec.CommandContext(ctx, s.runtime, args...)
+	cmd.Env = cmdEnv
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return -1, stdout.String(), stderr.String(), fmt.Errorf("exec in container timed out after %ds", timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), stdout.String(), stderr.String(), nil
+	}
+	if err != nil {
+		return -1, "", "", fmt.Errorf("exec in container failed: %w", err)
+	}
+	return 0, stdout.String(), stderr.String(), nil
+}
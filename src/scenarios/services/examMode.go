@@ -0,0 +1,268 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/liveprogress"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// ErrExamNotOpen is returned when a learner launches an exam outside its start
+// window. The window is enforced at launch only: a session started inside it
+// runs to its own time limit.
+var ErrExamNotOpen = errors.New("exam is not open for starting")
+
+// ErrExamAlreadyTaken is returned when a learner launches an exam they have
+// already sat. An exam allows a single attempt; a run lost to a setup failure
+// does not count, since the learner never had a working environment.
+var ErrExamAlreadyTaken = errors.New("exam has already been taken")
+
+// ErrExamTimeUp is returned for an action on an exam session whose time limit
+// has passed. It wraps ErrSessionNotActive so every controller that already
+// answers 409 for a finished session answers the same here.
+var ErrExamTimeUp = fmt.Errorf("%w: the exam time limit has been reached", ErrSessionNotActive)
+
+// ErrHintsDisabled is returned when a hint is requested during an exam.
+var ErrHintsDisabled = errors.New("hints are disabled during an exam")
+
+// ErrNotAnExam is returned by SubmitExam for a session that is not an exam.
+var ErrNotAnExam = errors.New("session is not an exam")
+
+// examLiveStatuses are the statuses an exam session can be finalised from.
+// setup_failed is left out on purpose: the learner never had an environment to
+// be graded on, and that run does not use up their attempt.
+var examLiveStatuses = []string{statusActive, statusInProgress, statusProvisioning}
+
+// ValidateExamAssignment checks the settings exam mode depends on. An exam
+// without a start date has no window, and one without a time limit would never
+// be finalised by the server.
+func ValidateExamAssignment(a *models.ScenarioAssignment) error {
+	if !a.ExamMode {
+		return nil
+	}
+	if a.TimeLimitMinutes <= 0 {
+		return fmt.Errorf("exam mode requires a positive time_limit_minutes")
+	}
+	if a.StartDate == nil {
+		return fmt.Errorf("exam mode requires a start_date")
+	}
+	if a.StartWindowEnd != nil && !a.StartWindowEnd.After(*a.StartDate) {
+		return fmt.Errorf("start_window_end must be after start_date")
+	}
+	if a.Deadline != nil && !a.Deadline.After(*a.StartDate) {
+		return fmt.Errorf("deadline must be after start_date")
+	}
+	return nil
+}
+
+// examWindowOpen reports whether an exam may be started at now.
+func examWindowOpen(a *models.ScenarioAssignment, now time.Time) bool {
+	if a.StartDate != nil && now.Before(*a.StartDate) {
+		return false
+	}
+	end := a.StartWindowEnd
+	if end == nil {
+		end = a.Deadline
+	}
+	return end == nil || now.Before(*end)
+}
+
+// examExpiry is when a session started at startedAt must be cut off: after the
+// time limit, or at the assignment deadline if that comes first.
+func examExpiry(a *models.ScenarioAssignment, startedAt time.Time) time.Time {
+	expiresAt := startedAt.Add(time.Duration(a.TimeLimitMinutes) * time.Minute)
+	if a.Deadline != nil && a.Deadline.Before(expiresAt) {
+		expiresAt = *a.Deadline
+	}
+	return expiresAt
+}
+
+// findExamAssignment returns the active exam assignment that applies to this
+// learner for this scenario, through one of their groups or organisations, or
+// nil when they are not sitting it as an exam.
+func findExamAssignment(db *gorm.DB, userID string, scenarioID uuid.UUID) (*models.ScenarioAssignment, error) {
+	query, err := learnerAssignments(db, userID, scenarioID)
+	if err != nil || query == nil {
+		return nil, err
+	}
+
+	var assignment models.ScenarioAssignment
+	err = query.Where("exam_mode = true").Order("start_date DESC").First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check exam assignment: %w", err)
+	}
+	// The learner's extension moves the end of their exam window too.
+	if err := applyExtension(db, &assignment, userID); err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// checkExamStart is the single answer to "may this learner start this exam
+// now". A session still in a blocking status is left to the one-run rule, which
+// offers to resume it; anything else but a setup failure is a spent attempt.
+func checkExamStart(db *gorm.DB, a *models.ScenarioAssignment, userID string, now time.Time) error {
+	if !examWindowOpen(a, now) {
+		return ErrExamNotOpen
+	}
+	var attempts int64
+	if err := db.Model(&models.ScenarioSession{}).
+		Where("assignment_id = ? AND user_id = ? AND status NOT IN ?", a.ID, userID, blockingSessionStatuses).
+		Count(&attempts).Error; err != nil {
+		return fmt.Errorf("failed to check exam attempts: %w", err)
+	}
+	if attempts > 0 {
+		return ErrExamAlreadyTaken
+	}
+	return nil
+}
+
+// CheckExamEligibility refuses a launch the exam rules would refuse, without
+// creating anything. LaunchScenario calls it before provisioning a terminal, so
+// a learner outside the window or past their attempt is not handed a container
+// StartScenario would then reject. StartScenario applies the same check again
+// inside its transaction.
+func (s *ScenarioSessionService) CheckExamEligibility(userID string, scenarioID uuid.UUID) error {
+	exam, err := findExamAssignment(s.db, userID, scenarioID)
+	if err != nil || exam == nil {
+		return err
+	}
+	return checkExamStart(s.db, exam, userID, time.Now())
+}
+
+// enforceExamTimeLimit finalises an exam session whose time is up and reports
+// it as ErrExamTimeUp. The expiry job does the same on a timer; checking here
+// too means no answer is accepted in the minute between two runs.
+func (s *ScenarioSessionService) enforceExamTimeLimit(session *models.ScenarioSession) error {
+	if !session.ExamMode || session.ExpiresAt == nil || time.Now().Before(*session.ExpiresAt) {
+		return nil
+	}
+	if _, err := s.finalizeExam(session.ID, true); err != nil {
+		return err
+	}
+	return ErrExamTimeUp
+}
+
+// finalizeExam completes an exam session, records its grade and stops its
+// terminal. The status update is guarded, so a learner submitting while the
+// expiry job fires grades the session once. finalized is false when the session
+// had already ended.
+func (s *ScenarioSessionService) finalizeExam(sessionID uuid.UUID, timedOut bool) (finalized bool, err error) {
+	grade, err := ComputeWeightedGrade(s.db, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to compute exam grade: %w", err)
+	}
+
+	var session models.ScenarioSession
+	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		return false, fmt.Errorf("session not found: %w", err)
+	}
+	now := time.Now()
+	updates, err := completionUpdates(s.db, &session, grade, now)
+	if err != nil {
+		return false, err
+	}
+	updates["timed_out"] = timedOut
+	result := s.db.Model(&models.ScenarioSession{}).
+		Where("id = ? AND status IN ?", sessionID, examLiveStatuses).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to finalize exam: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	s.syncTeamSessionsNow(sessionID)
+	if session.TerminalSessionID != nil {
+		s.tryStopTerminal(*session.TerminalSessionID, sessionID)
+	}
+	slog.Info("exam finalized", "session_id", sessionID, "grade", grade, "timed_out", timedOut)
+	s.publishSessionCompleted(sessionID)
+	s.notifyLiveProgress(sessionID, liveprogress.KindSessionEnded)
+	return true, nil
+}
+
+// SubmitExam ends an exam session before its time limit at the learner's
+// request. The grade is final: the learner cannot start the exam again.
+func (s *ScenarioSessionService) SubmitExam(sessionID uuid.UUID) (*dto.SubmitExamResponse, error) {
+	var session models.ScenarioSession
+	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		return nil, fmt.Errorf("session not found: %w", err)
+	}
+	if !session.ExamMode {
+		return nil, ErrNotAnExam
+	}
+	if err := requireActiveSession(&session); err != nil {
+		return nil, err
+	}
+	if err := s.enforceExamTimeLimit(&session); err != nil {
+		return nil, err
+	}
+
+	finalized, err := s.finalizeExam(sessionID, false)
+	if err != nil {
+		return nil, err
+	}
+	if !finalized {
+		return nil, fmt.Errorf("%w: session already ended", ErrSessionNotActive)
+	}
+
+	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload session: %w", err)
+	}
+	return &dto.SubmitExamResponse{
+		SessionID:   session.ID,
+		Grade:       session.Grade,
+		CompletedAt: session.CompletedAt,
+	}, nil
+}
+
+// FinalizeExpiredExams finalises every exam session whose time limit has
+// passed. Run periodically by the exam expiry job, so an exam ends on time even
+// when the learner has stopped interacting with it.
+func (s *ScenarioSessionService) FinalizeExpiredExams() (int, error) {
+	var sessionIDs []uuid.UUID
+	if err := s.db.Model(&models.ScenarioSession{}).
+		Where("exam_mode = ? AND expires_at <= ? AND status IN ?", true, time.Now(), examLiveStatuses).
+		Where("team_lead_session_id IS NULL"). // a team's exam ends with its lead's
+		Pluck("id", &sessionIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired exams: %w", err)
+	}
+
+	finalized := 0
+	for _, id := range sessionIDs {
+		ok, err := s.finalizeExam(id, true)
+		if err != nil {
+			slog.Error("failed to finalize expired exam", "session_id", id, "err", err)
+			continue
+		}
+		if ok {
+			finalized++
+		}
+	}
+	return finalized, nil
+}
+
+// withholdExamHints strips hint content from a step shown during an exam.
+func withholdExamHints(session *models.ScenarioSession, response *dto.CurrentStepResponse) {
+	if !session.ExamMode {
+		return
+	}
+	response.Hint = ""
+	response.HintsTotalCount = 0
+	response.HintsRevealed = 0
+	response.HintPenalties = nil
+	response.ExamMode = true
+	response.ExpiresAt = session.ExpiresAt
+}
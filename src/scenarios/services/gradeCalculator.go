@@ -35,7 +35,8 @@ func ComputeWeightedGrade(db *gorm.DB, sessionID uuid.UUID) (float64, error) {
 	}
 
 	var steps []models.ScenarioStep
-	if err := db.Where("scenario_id = ?", session.ScenarioID).
+	if err := db.Scopes(models.RevisionSteps(session.RevisionID)).Preload("Hints").
+		Where("scenario_id = ?", session.ScenarioID).
 		Order("\"order\" ASC").
 		Find(&steps).Error; err != nil {
 		return 0, fmt.Errorf("failed to load scenario steps: %w", err)
@@ -57,20 +58,21 @@ func ComputeWeightedGrade(db *gorm.DB, sessionID uuid.UUID) (float64, error) {
 //	                     else 0.0
 //	quiz               → progress.QuizScore (0 if nil)
 //
+// Each weight is then reduced by the penalties of the hints revealed on the
+// step (see HintCreditFactor), read from step.Hints: callers that want the
+// penalties applied must load the steps' hints.
+//
 // Legacy rows with an empty step_type are treated as "terminal" so older
-// sessions keep their grades.
+// sessions keep their grades. Steps the session's routing skipped are left out
+// of the average entirely: a learner who tested out of a remediation branch is
+// not marked down for not playing it.
 //
 // `currentStepOverride`, when non-nil, overrides the matching step's quiz
 // score. This is used by SubmitQuiz to score the just-submitted final step
 // before its DB row is reloaded into the in-memory progress slice.
 //
-// Returns 0 when len(steps) == 0.
+// Returns 0 when no step counts.
 func ComputeWeightedGradeFromLoaded(steps []models.ScenarioStep, progress []models.ScenarioStepProgress, currentStepOverride *QuizScoreOverride) float64 {
-	totalSteps := len(steps)
-	if totalSteps == 0 {
-		return 0
-	}
-
 	// Index progress by step_order for O(1) lookup.
 	progressByOrder := make(map[int]models.ScenarioStepProgress, len(progress))
 	for _, p := range progress {
@@ -78,29 +80,122 @@ func ComputeWeightedGradeFromLoaded(steps []models.ScenarioStep, progress []mode
 	}
 
 	var sum float64
+	totalSteps := 0
 	for _, step := range steps {
 		stepType := normalizeStepType(step.StepType)
 		p, hasProgress := progressByOrder[step.Order]
+		if hasProgress && p.Status == "skipped" {
+			continue
+		}
+		totalSteps++
 
+		factor := HintCreditFactor(step.Hints, p.HintsRevealed)
 		switch stepType {
 		case "quiz":
 			// Apply override if it targets this step.
 			if currentStepOverride != nil && currentStepOverride.StepOrder == step.Order {
-				sum += currentStepOverride.QuizScore
+				sum += currentStepOverride.QuizScore * factor
 				continue
 			}
 			if hasProgress && p.QuizScore != nil {
-				sum += *p.QuizScore
+				sum += *p.QuizScore * factor
 			}
 			// nil score (not yet submitted) counts as 0.
 		default:
 			// terminal / flag / info — full credit if completed, else 0.
 			if hasProgress && p.Status == "completed" {
-				sum += 1.0
+				sum += factor
 			}
 		}
 	}
 
+	if totalSteps == 0 {
+		return 0
+	}
+	return (sum / float64(totalSteps)) * 100.0
+}
+
+// attachStepHints loads the hints of steps in one query and sets each step's
+// Hints, so ComputeWeightedGradeFromLoaded can apply hint penalties to steps
+// loaded without them.
+func attachStepHints(db *gorm.DB, steps []models.ScenarioStep) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(steps))
+	for i := range steps {
+		ids[i] = steps[i].ID
+	}
+	var hints []models.ScenarioStepHint
+	if err := db.Where("step_id IN ?", ids).Order("level ASC").Find(&hints).Error; err != nil {
+		return fmt.Errorf("failed to load step hints: %w", err)
+	}
+	byStep := make(map[uuid.UUID][]models.ScenarioStepHint, len(steps))
+	for _, hint := range hints {
+		byStep[hint.StepID] = append(byStep[hint.StepID], hint)
+	}
+	for i := range steps {
+		steps[i].Hints = byStep[steps[i].ID]
+	}
+	return nil
+}
+
+// HintPenaltyPercent returns the share of a step's credit (0..100) given up by
+// revealing its first `revealed` hints: the sum of their PenaltyPercent,
+// capped at 100. Hints are matched by level, so the order of `hints` does not
+// matter.
+func HintPenaltyPercent(hints []models.ScenarioStepHint, revealed int) float64 {
+	var penalty float64
+	for _, hint := range hints {
+		if hint.Level >= 1 && hint.Level <= revealed && hint.PenaltyPercent > 0 {
+			penalty += hint.PenaltyPercent
+		}
+	}
+	return math.Min(penalty, 100)
+}
+
+// HintCreditFactor is the multiplier (0..1) applied to a step's credit once
+// its first `revealed` hints have been revealed.
+func HintCreditFactor(hints []models.ScenarioStepHint, revealed int) float64 {
+	return 1 - HintPenaltyPercent(hints, revealed)/100
+}
+
+// MaxAchievableGradeFromLoaded is the best grade (0..100) a session can still
+// reach: finished steps keep the credit they earned, every step not finished
+// yet counts as passed — with full marks on a quiz — less the penalties of the
+// hints already revealed on it. Skipped steps are left out, as in
+// ComputeWeightedGradeFromLoaded.
+func MaxAchievableGradeFromLoaded(steps []models.ScenarioStep, progress []models.ScenarioStepProgress) float64 {
+	progressByOrder := make(map[int]models.ScenarioStepProgress, len(progress))
+	for _, p := range progress {
+		progressByOrder[p.StepOrder] = p
+	}
+
+	var sum float64
+	totalSteps := 0
+	for _, step := range steps {
+		p, hasProgress := progressByOrder[step.Order]
+		if hasProgress && p.Status == "skipped" {
+			continue
+		}
+		totalSteps++
+
+		factor := HintCreditFactor(step.Hints, p.HintsRevealed)
+		switch {
+		case hasProgress && p.Status == "completed" && normalizeStepType(step.StepType) == "quiz":
+			if p.QuizScore != nil {
+				sum += *p.QuizScore * factor
+			}
+		case hasProgress && p.Status == "failed":
+			// Routed away from after a failed attempt: no credit to gain.
+		default:
+			sum += factor
+		}
+	}
+
+	if totalSteps == 0 {
+		return 0
+	}
 	return (sum / float64(totalSteps)) * 100.0
 }
 
@@ -120,7 +215,8 @@ func ComputeWeightedGradeFromLoaded(steps []models.ScenarioStep, progress []mode
 //   - terminal/info steps: ignored entirely.
 //
 // Denominator semantics:
-//   - quiz step: + questionCountByStepID[step.ID]
+//   - quiz step: + questionCountByStepID[step.ID], or the step's
+//     QuizDrawCount when it draws fewer questions from its pool
 //   - flag-bearing step (StepType=="flag" OR HasFlag): + 1
 //   - terminal/info steps: ignored entirely.
 //
@@ -152,7 +248,7 @@ func ComputeCorrectCountsFromLoaded(
 		stepType := normalizeStepType(step.StepType)
 		switch {
 		case stepType == "quiz":
-			n := questionCountByStepID[step.ID]
+			n := QuizDrawSize(step.QuizDrawCount, questionCountByStepID[step.ID])
 			if n == 0 {
 				continue
 			}
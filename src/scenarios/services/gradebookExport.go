@@ -0,0 +1,268 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Gradebook export formats accepted by ExportGroupGradebook.
+const (
+	GradebookFormatCSV    = "csv"
+	GradebookFormatXLSX   = "xlsx"
+	GradebookFormatMoodle = "moodle"
+)
+
+// ErrUnsupportedGradebookFormat is returned for a format not listed above.
+var ErrUnsupportedGradebookFormat = errors.New("unsupported gradebook format")
+
+// ExportGroupGradebook renders the gradebook of groupID as a downloadable file.
+// Returns the body, its content type and a suggested file name.
+func (s *TeacherDashboardService) ExportGroupGradebook(groupID uuid.UUID, format string) ([]byte, string, string, error) {
+	if format != GradebookFormatCSV && format != GradebookFormatXLSX && format != GradebookFormatMoodle {
+		return nil, "", "", fmt.Errorf("%w: %q", ErrUnsupportedGradebookFormat, format)
+	}
+
+	gradebook, err := s.GetGroupGradebook(groupID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var buf bytes.Buffer
+	var contentType, suffix string
+	switch format {
+	case GradebookFormatCSV:
+		err = writeCSV(&buf, gradebookTable(gradebook))
+		contentType, suffix = "text/csv", ".csv"
+	case GradebookFormatXLSX:
+		err = writeXLSX(&buf, "Gradebook", gradebookTable(gradebook))
+		contentType, suffix = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"
+	case GradebookFormatMoodle:
+		err = writeCSV(&buf, moodleGradeTable(gradebook))
+		contentType, suffix = "text/csv", "-moodle.csv"
+	}
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to render gradebook: %w", err)
+	}
+
+	filename := fmt.Sprintf("gradebook-%s-%s%s", gradebookFileSlug(gradebook.GroupName), gradebook.GeneratedAt.Format("2006-01-02"), suffix)
+	return buf.Bytes(), contentType, filename, nil
+}
+
+// tableCell is one spreadsheet cell. Numbers are kept apart from text so the
+// XLSX writer can store them as numbers a teacher can sum and sort.
+type tableCell struct {
+	text    string
+	number  float64
+	numeric bool
+}
+
+func textCell(s string) tableCell { return tableCell{text: s} }
+
+func numberCell(f float64) tableCell {
+	return tableCell{text: strconv.FormatFloat(f, 'f', -1, 64), number: f, numeric: true}
+}
+
+func optionalNumberCell(f *float64) tableCell {
+	if f == nil {
+		return textCell("")
+	}
+	return numberCell(roundGrade(*f))
+}
+
+func roundGrade(f float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'f', 2, 64), 64)
+	return rounded
+}
+
+// gradebookTable is the full gradebook as a wide table: identity columns, then
+// one group of result columns per assignment. CSV and XLSX share it.
+func gradebookTable(g *Gradebook) [][]tableCell {
+	header := []tableCell{textCell("User ID"), textCell("Name"), textCell("Email")}
+	for _, a := range g.Assignments {
+		for _, column := range []string{"Status", "Grade (%)", "Completed at", "Time spent (s)", "Hints used", "Verify attempts", "Quiz score (%)"} {
+			header = append(header, textCell(a.ScenarioTitle+" - "+column))
+		}
+	}
+
+	table := [][]tableCell{header}
+	for _, learner := range g.Learners {
+		row := []tableCell{textCell(learner.UserID), textCell(learner.UserName), textCell(learner.UserEmail)}
+		for _, r := range learner.Results {
+			completedAt := ""
+			if r.CompletedAt != nil {
+				completedAt = r.CompletedAt.UTC().Format(time.RFC3339)
+			}
+			timeSpent := textCell("")
+			if r.TimeSpentSeconds != nil {
+				timeSpent = numberCell(float64(*r.TimeSpentSeconds))
+			}
+			row = append(row,
+				textCell(r.Status),
+				optionalNumberCell(r.Grade),
+				textCell(completedAt),
+				timeSpent,
+				numberCell(float64(r.HintsUsed)),
+				numberCell(float64(r.VerifyAttempts)),
+				optionalNumberCell(r.QuizScore),
+			)
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+// moodleGradeTable is the gradebook in the layout Moodle's "Import grades from
+// CSV" expects: learners matched on their email address, one column per grade
+// item named after the scenario, grades out of 100. A learner with no email
+// cannot be matched and would fail the whole import, so they are left out; a
+// learner without a final grade gets an empty cell, which Moodle skips.
+func moodleGradeTable(g *Gradebook) [][]tableCell {
+	header := []tableCell{textCell("Email address")}
+	for _, a := range g.Assignments {
+		header = append(header, textCell(a.ScenarioTitle))
+	}
+
+	table := [][]tableCell{header}
+	for _, learner := range g.Learners {
+		if learner.UserEmail == "" {
+			continue
+		}
+		row := []tableCell{textCell(learner.UserEmail)}
+		for _, r := range learner.Results {
+			row = append(row, optionalNumberCell(r.Grade))
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+// writeCSV writes table as CSV. Text that a spreadsheet would read as a
+// formula is quoted with a leading apostrophe: names and titles are typed by
+// learners and authors, and the file is opened by a teacher.
+func writeCSV(w io.Writer, table [][]tableCell) error {
+	writer := csv.NewWriter(w)
+	for _, row := range table {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = cell.text
+			if !cell.numeric && cell.text != "" && strings.ContainsRune("=+-@", rune(cell.text[0])) {
+				record[i] = "'" + cell.text
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// gradebookFileSlug reduces a group name to something safe in a file name.
+func gradebookFileSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '-'
+	}, name)
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "group"
+	}
+	return slug
+}
+
+// writeXLSX writes table as a single-sheet workbook.
+//
+// Hand-rolled on archive/zip rather than pulled from a spreadsheet library: a
+// gradebook needs one sheet of plain values, which is five small XML parts, and
+// that does not justify a dependency. Strings are stored inline, so there is no
+// shared-strings table to keep consistent.
+func writeXLSX(w io.Writer, sheetName string, table [][]tableCell) error {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(table)},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func xlsxSheet(table [][]tableCell) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range table {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxColumnName(c) + strconv.Itoa(r+1)
+			switch {
+			case cell.numeric:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			case cell.text != "":
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xmlEscape(cell.text))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxColumnName converts a 0-based column index to its letters (0 → A, 26 → AA).
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// hintPenalties returns the PenaltyPercent of a step's hints by level, or nil
+// when every hint is free so the step response keeps its usual shape.
+func (s *ScenarioSessionService) hintPenalties(stepID uuid.UUID) []float64 {
+	var hints []models.ScenarioStepHint
+	if err := s.db.Select("level", "penalty_percent").
+		Where("step_id = ?", stepID).Order("level ASC").Find(&hints).Error; err != nil {
+		slog.Warn("failed to load hint penalties", "step_id", stepID, "err", err)
+		return nil
+	}
+	return hintPenaltyList(hints)
+}
+
+// hintPenaltyList lists the PenaltyPercent of hints ordered by level, or nil
+// when every hint is free.
+func hintPenaltyList(hints []models.ScenarioStepHint) []float64 {
+	penalties := make([]float64, len(hints))
+	charged := false
+	for i, hint := range hints {
+		penalties[i] = hint.PenaltyPercent
+		charged = charged || hint.PenaltyPercent > 0
+	}
+	if !charged {
+		return nil
+	}
+	return penalties
+}
+
+// fillHintScoreOutlook sets what the learner still stands to earn on the
+// response of a hint just revealed: the share of the step's credit left and
+// the best grade the session can still reach. Best-effort: a failed lookup
+// leaves the fields as they are.
+func (s *ScenarioSessionService) fillHintScoreOutlook(session *models.ScenarioSession, stepOrder int, response *dto.RevealHintResponse) {
+	var steps []models.ScenarioStep
+	if err := s.db.Scopes(models.RevisionSteps(session.RevisionID)).Preload("Hints").
+		Where("scenario_id = ?", session.ScenarioID).
+		Order("\"order\" ASC").
+		Find(&steps).Error; err != nil {
+		slog.Warn("failed to load steps for hint outlook", "session_id", session.ID, "err", err)
+		return
+	}
+	var progress []models.ScenarioStepProgress
+	if err := s.db.Where("session_id = ?", session.ID).Find(&progress).Error; err != nil {
+		slog.Warn("failed to load progress for hint outlook", "session_id", session.ID, "err", err)
+		return
+	}
+
+	for _, step := range steps {
+		if step.Order != stepOrder {
+			continue
+		}
+		for _, p := range progress {
+			if p.StepOrder == stepOrder {
+				response.StepCreditRemaining = HintCreditFactor(step.Hints, p.HintsRevealed) * 100
+				break
+			}
+		}
+		break
+	}
+	response.MaxAchievableGrade = MaxAchievableGradeFromLoaded(steps, progress)
+}
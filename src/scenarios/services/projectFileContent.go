@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/storage"
+)
+
+// newProjectFile builds a ProjectFile whose content is written to store. A
+// database store keeps the content in the row as before; any other store
+// leaves Content empty and records where the object lives.
+//
+// SizeBytes is left to the caller when already known (images record their
+// decoded size, not the length of the base64 text), otherwise it is the
+// content length.
+func newProjectFile(store storage.FileStorage, file models.ProjectFile, content string) (models.ProjectFile, error) {
+	if err := putProjectFileContent(store, &file, content); err != nil {
+		return file, err
+	}
+	if file.SizeBytes == 0 {
+		file.SizeBytes = int64(len(content))
+	}
+	return file, nil
+}
+
+// putProjectFileContent stores content through store and points file at it.
+func putProjectFileContent(store storage.FileStorage, file *models.ProjectFile, content string) error {
+	if store == nil || store.Type() == storage.TypeDatabase {
+		file.Content = content
+		file.StorageType = storage.TypeDatabase
+		file.StorageRef = ""
+		return nil
+	}
+	ref, err := store.Store(content)
+	if err != nil {
+		return fmt.Errorf("failed to store %s content: %w", file.Name, err)
+	}
+	file.Content = ""
+	file.StorageType = store.Type()
+	file.StorageRef = ref
+	return nil
+}
+
+// LoadProjectFileContent returns a file's content, reading it from the backend
+// the row says it was written to.
+func LoadProjectFileContent(file *models.ProjectFile) (string, error) {
+	if file.StorageType == "" || file.StorageType == storage.TypeDatabase {
+		return file.Content, nil
+	}
+	store, err := storage.ForType(file.StorageType)
+	if err != nil {
+		return "", err
+	}
+	return store.Retrieve(file.StorageRef)
+}
+
+// releaseProjectFileContent deletes the stored objects behind files that have
+// just been removed, skipping any object another live row still points at.
+//
+// Objects are content-addressed, so two scenarios carrying the same script — a
+// duplicate, or two imports of one archive — share a single object. Deleting
+// it because one of them went away would break the other. Called after the
+// deleting transaction commits: storage is not transactional, and an object
+// removed for a rollback that then restores its row cannot be brought back.
+//
+// Best-effort. A failure leaves an orphaned object, which costs space and
+// nothing else.
+func releaseProjectFileContent(db *gorm.DB, files []models.ProjectFile) {
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if f.StorageType == "" || f.StorageType == storage.TypeDatabase || f.StorageRef == "" {
+			continue
+		}
+		key := f.StorageType + ":" + f.StorageRef
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var refs int64
+		if err := db.Model(&models.ProjectFile{}).
+			Where("storage_type = ? AND storage_ref = ?", f.StorageType, f.StorageRef).
+			Count(&refs).Error; err != nil {
+			slog.Warn("could not count references to stored object, keeping it",
+				"storage_type", f.StorageType, "ref", f.StorageRef, "err", err)
+			continue
+		}
+		if refs > 0 {
+			continue
+		}
+
+		store, err := storage.ForType(f.StorageType)
+		if err != nil {
+			slog.Warn("stored object orphaned: backend not configured",
+				"storage_type", f.StorageType, "ref", f.StorageRef, "err", err)
+			continue
+		}
+		if err := store.Delete(f.StorageRef); err != nil {
+			slog.Warn("failed to delete orphaned stored object",
+				"storage_type", f.StorageType, "ref", f.StorageRef, "err", err)
+		}
+	}
+}
+
+// loadProjectFilesByID loads the rows about to be deleted so their stored
+// objects can be released once the deletion commits.
+func loadProjectFilesByID(tx *gorm.DB, ids []uuid.UUID) []models.ProjectFile {
+	if len(ids) == 0 {
+		return nil
+	}
+	var files []models.ProjectFile
+	tx.Select("id, storage_type, storage_ref").Where("id IN ?", ids).Find(&files)
+	return files
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/storage"
+)
+
+// ProjectFileMigrationOptions controls MigrateProjectFileStorage.
+type ProjectFileMigrationOptions struct {
+	// Apply commits changes. False is a dry run that only reports.
+	Apply bool
+	// ContentTypes restricts the run to these ProjectFile content types
+	// (e.g. just "image", the rows that weigh the most). Empty means all.
+	ContentTypes []string
+	// BatchSize is the number of rows loaded per query. Defaults to 100.
+	BatchSize int
+}
+
+// ProjectFileMigrationFailure records a row the migration could not move.
+type ProjectFileMigrationFailure struct {
+	FileID uuid.UUID
+	Name   string
+	Reason string
+}
+
+// ProjectFileMigrationReport summarises a MigrateProjectFileStorage run.
+type ProjectFileMigrationReport struct {
+	Target       string
+	Total        int
+	Migrated     int
+	WouldMigrate int
+	Skipped      int // modified concurrently; picked up by the next run
+	Bytes        int64
+	Failures     []ProjectFileMigrationFailure
+}
+
+// MigrateProjectFileStorage moves ProjectFile content still held in the
+// database into target. Safe to run repeatedly and while the server is up:
+// only rows still marked "database" are considered, and each row is switched
+// over with a conditional update, so a file edited mid-run keeps its edit and
+// is simply left for the next run.
+//
+// A row's content is cleared only after the stored object has been read back
+// and matched — the database copy is the only copy until then.
+func MigrateProjectFileStorage(db *gorm.DB, target storage.FileStorage, opts ProjectFileMigrationOptions) (*ProjectFileMigrationReport, error) {
+	if target == nil || target.Type() == storage.TypeDatabase {
+		return nil, fmt.Errorf("migration target must be an external storage backend, got %q", storageTypeOf(target))
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	report := &ProjectFileMigrationReport{Target: target.Type()}
+
+	// Keyset pagination on id: rows migrated in one batch drop out of the
+	// filter, so an offset would skip rows; the cursor does not care.
+	var cursor uuid.UUID
+	for {
+		query := db.Model(&models.ProjectFile{}).
+			Where("(storage_type = ? OR storage_type = '' OR storage_type IS NULL)", storage.TypeDatabase).
+			Where("id > ?", cursor).
+			Order("id ASC").
+			Limit(batchSize)
+		if len(opts.ContentTypes) > 0 {
+			query = query.Where("content_type IN ?", opts.ContentTypes)
+		}
+
+		var batch []models.ProjectFile
+		if err := query.Find(&batch).Error; err != nil {
+			return report, fmt.Errorf("failed to load project files: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		cursor = batch[len(batch)-1].ID
+
+		for i := range batch {
+			file := &batch[i]
+			report.Total++
+			if !opts.Apply {
+				report.WouldMigrate++
+				report.Bytes += int64(len(file.Content))
+				continue
+			}
+
+			moved, err := migrateProjectFile(db, target, file)
+			if err != nil {
+				report.Failures = append(report.Failures, ProjectFileMigrationFailure{
+					FileID: file.ID,
+					Name:   file.Name,
+					Reason: err.Error(),
+				})
+				continue
+			}
+			if !moved {
+				report.Skipped++
+				continue
+			}
+			report.Migrated++
+			report.Bytes += int64(len(file.Content))
+		}
+	}
+
+	return report, nil
+}
+
+// migrateProjectFile stores one row's content in target and switches the row
+// over. Returns false when the row changed since it was loaded.
+func migrateProjectFile(db *gorm.DB, target storage.FileStorage, file *models.ProjectFile) (bool, error) {
+	ref, err := target.Store(file.Content)
+	if err != nil {
+		return false, err
+	}
+	stored, err := target.Retrieve(ref)
+	if err != nil {
+		return false, fmt.Errorf("read-back failed: %w", err)
+	}
+	if stored != file.Content {
+		return false, fmt.Errorf("read-back mismatch for object %s", ref)
+	}
+
+	updates := map[string]any{
+		"content":      "",
+		"storage_type": target.Type(),
+		"storage_ref":  ref,
+	}
+	if file.SizeBytes == 0 {
+		updates["size_bytes"] = int64(len(file.Content))
+	}
+	result := db.Model(&models.ProjectFile{}).
+		Where("id = ? AND updated_at = ?", file.ID, file.UpdatedAt).
+		Where("(storage_type = ? OR storage_type = '' OR storage_type IS NULL)", storage.TypeDatabase).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update row: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func storageTypeOf(fs storage.FileStorage) string {
+	if fs == nil {
+		return ""
+	}
+	return fs.Type()
+}
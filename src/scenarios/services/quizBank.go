@@ -0,0 +1,248 @@
+package services
+
+// quizBank.go — a quiz step's questions as a pool. A step with QuizDrawCount
+// asks each session a subset of its questions, and choice questions show their
+// options in a per-session order, so that a class cannot pass the answers
+// around as "the third option of question two".
+//
+// Draws are not stored. Like the flags of FlagService they are derived from
+// the session ID, so GetCurrentStep, SubmitQuiz and the teacher views all
+// compute the same subset and the same option order independently.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"soli/formations/src/scenarios/models"
+)
+
+// quizDrawKey domain-separates quiz draws from every other use of a session ID.
+var quizDrawKey = []byte("ocf-quiz-draw")
+
+// ValidateQuizQuestion checks that a question's answer fields can be graded
+// for its type. Shared by every path that creates questions, like
+// ValidateStepTransition. Types it does not know are left alone: they predate
+// this check and are graded by exact match.
+func ValidateQuizQuestion(q *models.ScenarioStepQuestion) error {
+	switch q.QuestionType {
+	case models.QuestionTypeMultiSelect, models.QuestionTypeOrdering:
+		correct, err := parseAnswerList(q.CorrectAnswer)
+		if err != nil || len(correct) == 0 {
+			return fmt.Errorf("%s question requires correct_answer as a non-empty JSON array of strings", q.QuestionType)
+		}
+		if q.Options == "" {
+			if q.QuestionType == models.QuestionTypeMultiSelect {
+				return fmt.Errorf("%s question requires options", q.QuestionType)
+			}
+			return nil
+		}
+		options, err := parseAnswerList(q.Options)
+		if err != nil {
+			return fmt.Errorf("%s question requires options as a JSON array of strings", q.QuestionType)
+		}
+		for _, entry := range correct {
+			if !slices.Contains(options, entry) {
+				return fmt.Errorf("%s question: correct answer %q is not one of the options", q.QuestionType, entry)
+			}
+		}
+		if q.QuestionType == models.QuestionTypeOrdering && len(options) != len(correct) {
+			return fmt.Errorf("ordering question: correct_answer must order every option")
+		}
+	case models.QuestionTypeNumeric:
+		if _, err := parseNumber(q.CorrectAnswer); err != nil {
+			return fmt.Errorf("numeric question requires a number as correct_answer, got %q", q.CorrectAnswer)
+		}
+		if q.Tolerance < 0 {
+			return fmt.Errorf("numeric question tolerance must not be negative, got %v", q.Tolerance)
+		}
+	case models.QuestionTypeRegex:
+		if q.CorrectAnswer == "" {
+			return fmt.Errorf("regex question requires a pattern as correct_answer")
+		}
+		if _, err := compileAnswerPattern(q.CorrectAnswer); err != nil {
+			return fmt.Errorf("regex question: invalid pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// GradeQuizAnswer reports whether submitted answers q. The exact-match types
+// compare in constant time, as SubmitQuiz always has.
+func GradeQuizAnswer(q models.ScenarioStepQuestion, submitted string) bool {
+	switch q.QuestionType {
+	case models.QuestionTypeMultiSelect:
+		got, err1 := parseTrimmedAnswerList(submitted)
+		want, err2 := parseTrimmedAnswerList(q.CorrectAnswer)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return sameSet(got, want)
+	case models.QuestionTypeOrdering:
+		got, err1 := parseTrimmedAnswerList(submitted)
+		want, err2 := parseTrimmedAnswerList(q.CorrectAnswer)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return slices.Equal(got, want)
+	case models.QuestionTypeNumeric:
+		got, err1 := parseNumber(submitted)
+		want, err2 := parseNumber(q.CorrectAnswer)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		// The epsilon keeps 0.1+0.2 within a tolerance of 0 of 0.3.
+		return math.Abs(got-want) <= q.Tolerance+1e-9
+	case models.QuestionTypeRegex:
+		re, err := compileAnswerPattern(q.CorrectAnswer)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(strings.TrimSpace(submitted))
+	}
+	return subtle.ConstantTimeCompare([]byte(submitted), []byte(q.CorrectAnswer)) == 1
+}
+
+// QuizDrawSize is how many of a pool of poolSize questions a session is asked.
+func QuizDrawSize(drawCount, poolSize int) int {
+	if drawCount <= 0 || drawCount > poolSize {
+		return poolSize
+	}
+	return drawCount
+}
+
+// DrawQuizQuestions returns the questions of pool a session is asked on a
+// step, in pool order. The draw depends only on the session and the step, so
+// it is the same on every call. When topics are set it takes from each in
+// turn before taking a second question from any, so a session covers as many
+// topics as its draw size allows.
+func DrawQuizQuestions(pool []models.ScenarioStepQuestion, drawCount int, sessionID, stepID uuid.UUID) []models.ScenarioStepQuestion {
+	size := QuizDrawSize(drawCount, len(pool))
+	if size == len(pool) {
+		return pool
+	}
+
+	rng := quizRand(sessionID, stepID)
+	byTopic := make(map[string][]int)
+	var topics []string
+	for i, q := range pool {
+		if _, ok := byTopic[q.Topic]; !ok {
+			topics = append(topics, q.Topic)
+		}
+		byTopic[q.Topic] = append(byTopic[q.Topic], i)
+	}
+	rng.Shuffle(len(topics), func(i, j int) { topics[i], topics[j] = topics[j], topics[i] })
+	for _, topic := range topics {
+		indexes := byTopic[topic]
+		rng.Shuffle(len(indexes), func(i, j int) { indexes[i], indexes[j] = indexes[j], indexes[i] })
+	}
+
+	picked := make([]int, 0, size)
+	for round := 0; len(picked) < size; round++ {
+		for _, topic := range topics {
+			if indexes := byTopic[topic]; round < len(indexes) && len(picked) < size {
+				picked = append(picked, indexes[round])
+			}
+		}
+	}
+	slices.Sort(picked)
+
+	drawn := make([]models.ScenarioStepQuestion, len(picked))
+	for i, index := range picked {
+		drawn[i] = pool[index]
+	}
+	return drawn
+}
+
+// ShuffledQuestionOptions returns the options of q in the order a session
+// sees them. Choice and ordering questions are shuffled per session; other
+// questions, and options that are not a JSON array, are returned unchanged.
+func ShuffledQuestionOptions(q models.ScenarioStepQuestion, sessionID uuid.UUID) string {
+	raw := q.Options
+	switch q.QuestionType {
+	case models.QuestionTypeMultipleChoice, models.QuestionTypeMultiSelect:
+	case models.QuestionTypeOrdering:
+		if raw == "" {
+			raw = q.CorrectAnswer
+		}
+	default:
+		return q.Options
+	}
+	options, err := parseAnswerList(raw)
+	if err != nil || len(options) < 2 {
+		return raw
+	}
+	rng := quizRand(sessionID, q.ID)
+	rng.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	// An ordering question must not be shown already solved.
+	if q.QuestionType == models.QuestionTypeOrdering {
+		if correct, err := parseAnswerList(q.CorrectAnswer); err == nil && slices.Equal(options, correct) {
+			options[0], options[1] = options[1], options[0]
+		}
+	}
+	shuffled, err := json.Marshal(options)
+	if err != nil {
+		return raw
+	}
+	return string(shuffled)
+}
+
+// quizRand is the random source of one (session, item) pair.
+func quizRand(sessionID, itemID uuid.UUID) *rand.Rand {
+	mac := hmac.New(sha256.New, quizDrawKey)
+	mac.Write(sessionID[:])
+	mac.Write(itemID[:])
+	sum := mac.Sum(nil)
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+}
+
+func parseAnswerList(raw string) ([]string, error) {
+	var list []string
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// parseTrimmedAnswerList is parseAnswerList for grading, where surrounding
+// whitespace is not part of an answer.
+func parseTrimmedAnswerList(raw string) ([]string, error) {
+	list, err := parseAnswerList(raw)
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list, err
+}
+
+func parseNumber(raw string) (float64, error) {
+	// Accept a decimal comma: learners type the number the way they write it.
+	n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(raw), ",", "."), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("not a number: %q", raw)
+	}
+	return n, nil
+}
+
+// compileAnswerPattern anchors pattern so it has to match the whole answer.
+func compileAnswerPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+func sameSet(a, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// ErrCatalogScenarioNotFound is returned for a scenario that is not in the
+// catalog: missing, private or archived.
+var ErrCatalogScenarioNotFound = errors.New("scenario not found in the catalog")
+
+// ErrReviewNotAllowed is returned when a learner reviews a scenario they
+// have not completed.
+var ErrReviewNotAllowed = errors.New("only learners who completed this scenario can review it")
+
+// ErrReviewNotFound is returned for a review that does not exist on the scenario.
+var ErrReviewNotFound = errors.New("review not found")
+
+const (
+	catalogDefaultLimit = 20
+	catalogMaxLimit     = 100
+)
+
+// estimatedTimePart matches one amount of an estimated time: "1h", "30 min",
+// "1.5 hours".
+var estimatedTimePart = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(hours?|heures?|hrs|hr|h|minutes?|mins|min|mn|m)?`)
+
+// ScenarioCatalogService is the public scenario catalog: search over the
+// public scenarios, their ratings and usage, learner reviews, and forks into
+// an organization.
+type ScenarioCatalogService struct {
+	db               *gorm.DB
+	duplicateService *ScenarioDuplicateService
+}
+
+func NewScenarioCatalogService(db *gorm.DB) *ScenarioCatalogService {
+	return &ScenarioCatalogService{db: db, duplicateService: NewScenarioDuplicateService(db)}
+}
+
+// catalogScope restricts a scenario query to the catalog: public scenarios
+// that are not archived.
+func catalogScope(db *gorm.DB) *gorm.DB {
+	return db.Scopes(models.NotArchived).Where("scenarios.is_public = ?", true)
+}
+
+// catalogRank is the text-match rank of a search result.
+type catalogRank struct {
+	ID   uuid.UUID
+	Rank float64
+}
+
+// Search returns one page of the catalog scenarios matching input.
+func (s *ScenarioCatalogService) Search(input dto.CatalogSearchInput) (*dto.CatalogPage, error) {
+	query := s.db.Model(&models.Scenario{}).Scopes(catalogScope)
+	if input.Difficulty != "" {
+		query = query.Where("LOWER(difficulty) = ?", strings.ToLower(input.Difficulty))
+	}
+	if input.OsType != "" {
+		query = query.Where("LOWER(os_type) = ?", strings.ToLower(input.OsType))
+	}
+	text := strings.TrimSpace(input.Query)
+	rank := "0"
+	if text != "" {
+		query, rank = s.matchText(query, text)
+	}
+
+	var scenarios []models.Scenario
+	if err := query.Select("id", "estimated_time", "required_features", "updated_at").Find(&scenarios).Error; err != nil {
+		return nil, fmt.Errorf("failed to search catalog: %w", err)
+	}
+	ranks := map[uuid.UUID]float64{}
+	if text != "" && len(scenarios) > 0 {
+		var ranked []catalogRank
+		ids := scenarioIDs(scenarios)
+		if err := s.db.Model(&models.Scenario{}).Select("id, "+rank+" AS rank", text).
+			Where("id IN ?", ids).Scan(&ranked).Error; err != nil {
+			return nil, fmt.Errorf("failed to rank catalog results: %w", err)
+		}
+		for _, r := range ranked {
+			ranks[r.ID] = r.Rank
+		}
+	}
+
+	required := splitFeatures(input.Features)
+	var supported []string
+	if input.SupportedFeatures != nil {
+		supported = splitFeatures(*input.SupportedFeatures)
+	}
+	matches := scenarios[:0]
+	for _, scenario := range scenarios {
+		if input.MaxMinutes > 0 {
+			minutes, ok := ParseEstimatedMinutes(scenario.EstimatedTime)
+			if !ok || minutes > input.MaxMinutes {
+				continue
+			}
+		}
+		features, _ := scenario.GetRequiredFeatures()
+		if !containsAll(features, required) {
+			continue
+		}
+		if input.SupportedFeatures != nil && !containsAll(supported, features) {
+			continue
+		}
+		matches = append(matches, scenario)
+	}
+
+	ids := scenarioIDs(matches)
+	ratings, err := s.ratingSummaries(ids)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.usageStats(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	order := input.Sort
+	if order == "" || (order == dto.CatalogSortRelevance && text == "") {
+		order = dto.CatalogSortRecent
+		if text != "" {
+			order = dto.CatalogSortRelevance
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch order {
+		case dto.CatalogSortRelevance:
+			if ranks[a.ID] != ranks[b.ID] {
+				return ranks[a.ID] > ranks[b.ID]
+			}
+		case dto.CatalogSortRating:
+			ra, rb := ratings[a.ID], ratings[b.ID]
+			if (ra.Average == nil) != (rb.Average == nil) {
+				return ra.Average != nil
+			}
+			if ra.Average != nil && *ra.Average != *rb.Average {
+				return *ra.Average > *rb.Average
+			}
+			if ra.Count != rb.Count {
+				return ra.Count > rb.Count
+			}
+		case dto.CatalogSortPopular:
+			if usage[a.ID].Learners != usage[b.ID].Learners {
+				return usage[a.ID].Learners > usage[b.ID].Learners
+			}
+		}
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = catalogDefaultLimit
+	}
+	limit = min(limit, catalogMaxLimit)
+	offset := max(input.Offset, 0)
+	page := &dto.CatalogPage{Items: []dto.CatalogScenario{}, Total: len(matches), Limit: limit, Offset: offset}
+	if offset >= len(matches) {
+		return page, nil
+	}
+	pageIDs := scenarioIDs(matches[offset:min(offset+limit, len(matches))])
+
+	var full []models.Scenario
+	if err := s.db.Where("id IN ?", pageIDs).Find(&full).Error; err != nil {
+		return nil, fmt.Errorf("failed to load catalog scenarios: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.Scenario, len(full))
+	for i := range full {
+		byID[full[i].ID] = &full[i]
+	}
+	for _, id := range pageIDs {
+		if scenario, ok := byID[id]; ok {
+			page.Items = append(page.Items, catalogScenarioOutput(scenario, ratings[id], usage[id]))
+		}
+	}
+	return page, nil
+}
+
+// matchText restricts query to the scenarios whose title, description or
+// objectives match text, and returns the SQL expression ranking them (taking
+// text as its single argument). Postgres uses its full-text search; other
+// databases require every word to appear and rank title matches first.
+func (s *ScenarioCatalogService) matchText(query *gorm.DB, text string) (*gorm.DB, string) {
+	if s.db.Dialector.Name() == "postgres" {
+		return query.Where(models.CatalogSearchDocument+" @@ plainto_tsquery('simple', ?)", text),
+			"ts_rank(" + models.CatalogSearchDocument + ", plainto_tsquery('simple', ?))"
+	}
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		pattern := "%" + word + "%"
+		query = query.Where("(LOWER(title) LIKE ? OR LOWER(COALESCE(description, '')) LIKE ? OR LOWER(COALESCE(objectives, '')) LIKE ?)",
+			pattern, pattern, pattern)
+	}
+	return query, "CASE WHEN LOWER(title) LIKE '%' || LOWER(?) || '%' THEN 1 ELSE 0 END"
+}
+
+// Get returns a catalog scenario with its rating distribution and latest
+// reviews.
+func (s *ScenarioCatalogService) Get(scenarioID uuid.UUID) (*dto.CatalogScenarioDetail, error) {
+	scenario, err := s.catalogScenario(scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := s.ratingSummaries([]uuid.UUID{scenario.ID})
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.usageStats([]uuid.UUID{scenario.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	rating := ratings[scenario.ID]
+	var perStar []struct {
+		Rating int
+		Count  int
+	}
+	if err := s.db.Model(&models.ScenarioReview{}).Select("rating, COUNT(*) AS count").
+		Where("scenario_id = ?", scenario.ID).Group("rating").Scan(&perStar).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rating distribution: %w", err)
+	}
+	rating.Distribution = make([]int, models.ReviewRatingMax)
+	for _, star := range perStar {
+		if star.Rating >= models.ReviewRatingMin && star.Rating <= models.ReviewRatingMax {
+			rating.Distribution[star.Rating-1] = star.Count
+		}
+	}
+
+	var stepCount int64
+	if err := s.db.Model(&models.ScenarioStep{}).Scopes(models.RevisionSteps(scenario.PublishedRevisionID)).
+		Where("scenario_id = ?", scenario.ID).Count(&stepCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count steps: %w", err)
+	}
+	reviews, err := s.ListReviews(scenario.ID, 5, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CatalogScenarioDetail{
+		CatalogScenario: catalogScenarioOutput(scenario, rating, usage[scenario.ID]),
+		Prerequisites:   scenario.Prerequisites,
+		StepCount:       int(stepCount),
+		RecentReviews:   reviews,
+	}, nil
+}
+
+// ListReviews returns a catalog scenario's reviews, most recently written first.
+func (s *ScenarioCatalogService) ListReviews(scenarioID uuid.UUID, limit, offset int) ([]dto.ScenarioReviewOutput, error) {
+	if _, err := s.catalogScenario(scenarioID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = catalogDefaultLimit
+	}
+	var reviews []models.ScenarioReview
+	if err := s.db.Where("scenario_id = ?", scenarioID).
+		Order("updated_at DESC").Limit(min(limit, catalogMaxLimit)).Offset(max(offset, 0)).
+		Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	outputs := make([]dto.ScenarioReviewOutput, 0, len(reviews))
+	for i := range reviews {
+		outputs = append(outputs, reviewOutput(&reviews[i]))
+	}
+	return outputs, nil
+}
+
+// UpsertReview records a learner's review of a catalog scenario, replacing
+// their previous one. The learner must have completed the scenario.
+func (s *ScenarioCatalogService) UpsertReview(scenarioID uuid.UUID, userID string, input dto.UpsertScenarioReviewInput) (*dto.ScenarioReviewOutput, error) {
+	if input.Rating < models.ReviewRatingMin || input.Rating > models.ReviewRatingMax {
+		return nil, fmt.Errorf("rating must be between %d and %d", models.ReviewRatingMin, models.ReviewRatingMax)
+	}
+	if _, err := s.catalogScenario(scenarioID); err != nil {
+		return nil, err
+	}
+	var completed int64
+	if err := s.db.Model(&models.ScenarioSession{}).
+		Where("scenario_id = ? AND user_id = ? AND status = ? AND is_preview = ?", scenarioID, userID, "completed", false).
+		Count(&completed).Error; err != nil {
+		return nil, err
+	}
+	if completed == 0 {
+		return nil, ErrReviewNotAllowed
+	}
+
+	var review models.ScenarioReview
+	err := s.db.Where("scenario_id = ? AND user_id = ?", scenarioID, userID).First(&review).Error
+	switch {
+	case err == nil:
+		if err := s.db.Model(&review).Updates(map[string]any{"rating": input.Rating, "comment": input.Comment}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update review: %w", err)
+		}
+		review.Rating, review.Comment = input.Rating, input.Comment
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		review = models.ScenarioReview{ScenarioID: scenarioID, UserID: userID, Rating: input.Rating, Comment: input.Comment}
+		if err := s.db.Create(&review).Error; err != nil {
+			return nil, fmt.Errorf("failed to save review: %w", err)
+		}
+	default:
+		return nil, err
+	}
+	output := reviewOutput(&review)
+	return &output, nil
+}
+
+// DeleteReview removes a review. Its author and administrators may delete it.
+func (s *ScenarioCatalogService) DeleteReview(scenarioID, reviewID uuid.UUID, userID string, isAdmin bool) error {
+	var review models.ScenarioReview
+	if err := s.db.Where("id = ? AND scenario_id = ?", reviewID, scenarioID).First(&review).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReviewNotFound
+		}
+		return err
+	}
+	if review.UserID != userID && !isAdmin {
+		return ErrReviewNotFound
+	}
+	// Deleted for good, so that the learner can review the scenario again.
+	return s.db.Unscoped().Delete(&review).Error
+}
+
+// Fork copies a catalog scenario into an organization, as a private scenario
+// that records the scenario it was forked from.
+func (s *ScenarioCatalogService) Fork(sourceID, orgID uuid.UUID, userID string) (*models.Scenario, error) {
+	source, err := s.catalogScenario(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	fork, err := s.duplicateService.DuplicateScenario(source.ID, userID, &orgID)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]any{"title": source.Title, "is_public": false, "forked_from_id": source.ID}
+	if err := s.db.Model(fork).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to record fork provenance: %w", err)
+	}
+	fork.Title, fork.IsPublic, fork.ForkedFromID = source.Title, false, &source.ID
+	return fork, nil
+}
+
+func (s *ScenarioCatalogService) catalogScenario(scenarioID uuid.UUID) (*models.Scenario, error) {
+	var scenario models.Scenario
+	if err := s.db.Scopes(catalogScope).First(&scenario, "scenarios.id = ?", scenarioID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCatalogScenarioNotFound
+		}
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// ratingSummaries returns the average rating and review count of scenarios.
+func (s *ScenarioCatalogService) ratingSummaries(ids []uuid.UUID) (map[uuid.UUID]dto.CatalogRatingSummary, error) {
+	summaries := make(map[uuid.UUID]dto.CatalogRatingSummary, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+	var rows []struct {
+		ScenarioID uuid.UUID
+		Average    float64
+		Count      int
+	}
+	if err := s.db.Model(&models.ScenarioReview{}).
+		Select("scenario_id, AVG(rating) AS average, COUNT(*) AS count").
+		Where("scenario_id IN ?", ids).Group("scenario_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load ratings: %w", err)
+	}
+	for _, row := range rows {
+		average := roundTo(row.Average, 2)
+		summaries[row.ScenarioID] = dto.CatalogRatingSummary{Average: &average, Count: row.Count}
+	}
+	return summaries, nil
+}
+
+// usageStats returns how many learners ran, completed and forked scenarios.
+// Previews are not counted.
+func (s *ScenarioCatalogService) usageStats(ids []uuid.UUID) (map[uuid.UUID]dto.CatalogUsageStats, error) {
+	stats := make(map[uuid.UUID]dto.CatalogUsageStats, len(ids))
+	if len(ids) == 0 {
+		return stats, nil
+	}
+	var sessions []struct {
+		ScenarioID   uuid.UUID
+		Sessions     int
+		Learners     int
+		Completions  int
+		AverageGrade *float64
+	}
+	if err := s.db.Model(&models.ScenarioSession{}).
+		Select(`scenario_id, COUNT(*) AS sessions, COUNT(DISTINCT user_id) AS learners,
+			COUNT(DISTINCT CASE WHEN status = 'completed' THEN user_id END) AS completions,
+			AVG(CASE WHEN status = 'completed' THEN grade END) AS average_grade`).
+		Where("scenario_id IN ? AND is_preview = ?", ids, false).
+		Group("scenario_id").Scan(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load usage statistics: %w", err)
+	}
+	for _, row := range sessions {
+		usage := dto.CatalogUsageStats{Sessions: row.Sessions, Learners: row.Learners, Completions: row.Completions}
+		if row.Learners > 0 {
+			usage.CompletionRate = roundTo(float64(row.Completions)*100/float64(row.Learners), 1)
+		}
+		if row.AverageGrade != nil {
+			grade := roundTo(*row.AverageGrade, 1)
+			usage.AverageGrade = &grade
+		}
+		stats[row.ScenarioID] = usage
+	}
+
+	var forks []struct {
+		ForkedFromID uuid.UUID
+		Count        int
+	}
+	if err := s.db.Model(&models.Scenario{}).Select("forked_from_id, COUNT(*) AS count").
+		Where("forked_from_id IN ?", ids).Group("forked_from_id").Scan(&forks).Error; err != nil {
+		return nil, fmt.Errorf("failed to count forks: %w", err)
+	}
+	for _, row := range forks {
+		usage := stats[row.ForkedFromID]
+		usage.Forks = row.Count
+		stats[row.ForkedFromID] = usage
+	}
+	return stats, nil
+}
+
+// ParseEstimatedMinutes reads a scenario's free-form estimated time ("30m",
+// "1h30", "45 minutes", "1.5 hours") as minutes. A number without a unit
+// counts as minutes.
+func ParseEstimatedMinutes(estimate string) (int, bool) {
+	parts := estimatedTimePart.FindAllStringSubmatch(strings.ToLower(estimate), -1)
+	if len(parts) == 0 {
+		return 0, false
+	}
+	var minutes float64
+	for _, part := range parts {
+		amount, err := strconv.ParseFloat(strings.Replace(part[1], ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+		if strings.HasPrefix(part[2], "h") {
+			minutes += amount * 60
+		} else {
+			minutes += amount
+		}
+	}
+	return int(minutes + 0.5), true
+}
+
+func catalogScenarioOutput(scenario *models.Scenario, rating dto.CatalogRatingSummary, usage dto.CatalogUsageStats) dto.CatalogScenario {
+	features, _ := scenario.GetRequiredFeatures()
+	output := dto.CatalogScenario{
+		ID:               scenario.ID,
+		Name:             scenario.Name,
+		Title:            scenario.Title,
+		Description:      scenario.Description,
+		Objectives:       scenario.Objectives,
+		Difficulty:       scenario.Difficulty,
+		EstimatedTime:    scenario.EstimatedTime,
+		OsType:           scenario.OsType,
+		RequiredFeatures: features,
+		OrganizationID:   scenario.OrganizationID,
+		ForkedFromID:     scenario.ForkedFromID,
+		UpdatedAt:        scenario.UpdatedAt,
+		Rating:           rating,
+		Usage:            usage,
+	}
+	if minutes, ok := ParseEstimatedMinutes(scenario.EstimatedTime); ok {
+		output.EstimatedMinutes = &minutes
+	}
+	return output
+}
+
+func reviewOutput(review *models.ScenarioReview) dto.ScenarioReviewOutput {
+	return dto.ScenarioReviewOutput{
+		ID:         review.ID,
+		ScenarioID: review.ScenarioID,
+		UserID:     review.UserID,
+		Rating:     review.Rating,
+		Comment:    review.Comment,
+		CreatedAt:  review.CreatedAt,
+		UpdatedAt:  review.UpdatedAt,
+	}
+}
+
+func scenarioIDs(scenarios []models.Scenario) []uuid.UUID {
+	ids := make([]uuid.UUID, len(scenarios))
+	for i := range scenarios {
+		ids[i] = scenarios[i].ID
+	}
+	return ids
+}
+
+func splitFeatures(list string) []string {
+	var features []string
+	for _, feature := range strings.Split(list, ",") {
+		if feature = strings.TrimSpace(feature); feature != "" {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// containsAll reports whether every element of subset is in set.
+func containsAll(set, subset []string) bool {
+	for _, element := range subset {
+		if !slices.Contains(set, element) {
+			return false
+		}
+	}
+	return true
+}
+
+func roundTo(value float64, decimals int) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', decimals, 64), 64)
+	return rounded
+}
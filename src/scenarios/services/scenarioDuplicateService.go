@@ -9,21 +9,29 @@ import (
 	"gorm.io/gorm"
 
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/storage"
 	"soli/formations/src/scenarios/utils"
 )
 
 // ScenarioDuplicateService handles deep-copying a scenario with all relations
 type ScenarioDuplicateService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	store storage.FileStorage
 }
 
-// NewScenarioDuplicateService creates a new duplicate service
+// NewScenarioDuplicateService creates a new duplicate service writing copied
+// file content to the configured storage backend
 func NewScenarioDuplicateService(db *gorm.DB) *ScenarioDuplicateService {
-	return &ScenarioDuplicateService{db: db}
+	return &ScenarioDuplicateService{db: db, store: storage.Active()}
+}
+
+// SetFileStorage overrides the backend copied file content is written to
+func (s *ScenarioDuplicateService) SetFileStorage(store storage.FileStorage) {
+	s.store = store
 }
 
 // DuplicateScenario creates a deep copy of the source scenario including Steps,
-// Hints, quiz Questions, CompatibleInstanceTypes, and ProjectFiles. FK
+// Hints, quiz Questions, Transitions, CompatibleInstanceTypes, Nodes, and ProjectFiles. FK
 // references (script IDs) on steps and scenario are remapped to the newly
 // created ProjectFile copies.
 //
@@ -39,7 +47,7 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 	var source models.Scenario
 	if err := s.db.
 		Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("\"order\" ASC")
+			return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 		}).
 		Preload("Steps.Hints", func(db *gorm.DB) *gorm.DB {
 			return db.Order("level ASC")
@@ -47,7 +55,11 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 		Preload("Steps.Questions", func(db *gorm.DB) *gorm.DB {
 			return db.Order("\"order\" ASC")
 		}).
+		Preload("Steps.Transitions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("priority ASC, created_at ASC")
+		}).
 		Preload("CompatibleInstanceTypes").
+		Preload("Nodes").
 		First(&source, "id = ?", sourceID).Error; err != nil {
 		return nil, fmt.Errorf("scenario not found: %w", err)
 	}
@@ -104,6 +116,8 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 			InstanceType:   source.InstanceType,
 			Hostname:       source.Hostname,
 			OsType:         source.OsType,
+			RequiredFeatures: source.RequiredFeatures,
+			BuildFeatures:    source.BuildFeatures,
 			SourceType:     source.SourceType,
 			GitRepository:  source.GitRepository,
 			GitBranch:      source.GitBranch,
@@ -112,6 +126,7 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 			AllowedFlagPaths: source.AllowedFlagPaths,
 			FlagSecret:       flagSecret,
 			CrashTraps:     source.CrashTraps,
+			SnapshotSteps:  source.SnapshotSteps,
 			Objectives:     source.Objectives,
 			Prerequisites:  source.Prerequisites,
 			IntroText:      source.IntroText,
@@ -127,9 +142,12 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 		}
 
 		// 2. Copy ProjectFiles (map old ID -> new ID)
-		// NOTE: StorageRef is shallow-copied. If S3-backed storage is introduced,
-		// duplication must either copy the S3 object or implement reference counting
-		// to prevent a delete of one copy from breaking the other's reference.
+		// Externally stored content is content-addressed, so the copy shares the
+		// source's object: StorageRef is copied as-is, and the object is only
+		// deleted once no row references it (see releaseProjectFileContent).
+		// Content still held in the database is written to the configured
+		// backend instead, so duplicating a scenario does not add another
+		// copy of its images to the database.
 		fileIDMap := make(map[uuid.UUID]uuid.UUID) // oldID -> newID
 		for _, srcFile := range sourceFiles {
 			newFile := models.ProjectFile{
@@ -142,6 +160,11 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 				StorageRef:  srcFile.StorageRef,
 				SizeBytes:   srcFile.SizeBytes,
 			}
+			if srcFile.StorageType == "" || srcFile.StorageType == storage.TypeDatabase {
+				if err := putProjectFileContent(s.store, &newFile, srcFile.Content); err != nil {
+					return err
+				}
+			}
 			// Image files get linked to the new scenario via ScenarioID
 			if srcFile.ScenarioID != nil {
 				newFile.ScenarioID = &newScenario.ID
@@ -188,8 +211,10 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 				VerifyScript:             srcStep.VerifyScript,
 				BackgroundScript:         srcStep.BackgroundScript,
 				ForegroundScript:         srcStep.ForegroundScript,
+				SolutionScript:           srcStep.SolutionScript,
 				BackgroundTimeoutSeconds: srcStep.BackgroundTimeoutSeconds,
 				BackgroundAsync:          srcStep.BackgroundAsync,
+				TargetNode:               srcStep.TargetNode,
 				IntroEffect:              srcStep.IntroEffect,
 				IntroText:                srcStep.IntroText,
 				OutroEffect:              srcStep.OutroEffect,
@@ -197,6 +222,7 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 				HasFlag:                  srcStep.HasFlag,
 				FlagPath:                 srcStep.FlagPath,
 				FlagLevel:                srcStep.FlagLevel,
+				QuizDrawCount:            srcStep.QuizDrawCount,
 			}
 
 			// Remap step-level FK refs
@@ -233,9 +259,10 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 			// 5. Copy Hints (linked to new step ID)
 			for _, srcHint := range srcStep.Hints {
 				newHint := models.ScenarioStepHint{
-					StepID:  newStep.ID,
-					Level:   srcHint.Level,
-					Content: srcHint.Content,
+					StepID:         newStep.ID,
+					Level:          srcHint.Level,
+					Content:        srcHint.Content,
+					PenaltyPercent: srcHint.PenaltyPercent,
 				}
 				if err := tx.Create(&newHint).Error; err != nil {
 					return fmt.Errorf("failed to create hint copy: %w", err)
@@ -254,11 +281,29 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 					CorrectAnswer: srcQuestion.CorrectAnswer,
 					Explanation:   srcQuestion.Explanation,
 					Points:        srcQuestion.Points,
+					Tolerance:     srcQuestion.Tolerance,
+					Difficulty:    srcQuestion.Difficulty,
+					Topic:         srcQuestion.Topic,
 				}
 				if err := tx.Create(&newQuestion).Error; err != nil {
 					return fmt.Errorf("failed to create question copy: %w", err)
 				}
 			}
+
+			// 6b. Copy Transitions. Step orders are kept, so targets need no remapping.
+			for _, srcTransition := range srcStep.Transitions {
+				newTransition := models.ScenarioStepTransition{
+					StepID:          newStep.ID,
+					Priority:        srcTransition.Priority,
+					Condition:       srcTransition.Condition,
+					Threshold:       srcTransition.Threshold,
+					Value:           srcTransition.Value,
+					TargetStepOrder: srcTransition.TargetStepOrder,
+				}
+				if err := tx.Create(&newTransition).Error; err != nil {
+					return fmt.Errorf("failed to create transition copy: %w", err)
+				}
+			}
 		}
 
 		// 7. Copy CompatibleInstanceTypes
@@ -274,6 +319,22 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 			}
 		}
 
+		// 8. Copy Nodes
+		for _, srcNode := range source.Nodes {
+			newNode := models.ScenarioNode{
+				ScenarioID:       newScenario.ID,
+				Name:             srcNode.Name,
+				Order:            srcNode.Order,
+				Distribution:     srcNode.Distribution,
+				Size:             srcNode.Size,
+				Hostname:         srcNode.Hostname,
+				RequiredFeatures: srcNode.RequiredFeatures,
+			}
+			if err := tx.Create(&newNode).Error; err != nil {
+				return fmt.Errorf("failed to create node copy: %w", err)
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -284,7 +345,7 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 	var result models.Scenario
 	if err := s.db.
 		Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("\"order\" ASC")
+			return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 		}).
 		Preload("Steps.Hints", func(db *gorm.DB) *gorm.DB {
 			return db.Order("level ASC")
@@ -292,7 +353,11 @@ func (s *ScenarioDuplicateService) DuplicateScenario(sourceID uuid.UUID, userID
 		Preload("Steps.Questions", func(db *gorm.DB) *gorm.DB {
 			return db.Order("\"order\" ASC")
 		}).
+		Preload("Steps.Transitions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("priority ASC, created_at ASC")
+		}).
 		Preload("CompatibleInstanceTypes").
+		Preload("Nodes").
 		First(&result, "id = ?", newScenario.ID).Error; err != nil {
 		return nil, fmt.Errorf("failed to reload duplicated scenario: %w", err)
 	}
@@ -3,9 +3,12 @@ package services
 import (
 	"archive/zip"
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"path"
+	"strings"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -34,9 +37,12 @@ func NewScenarioExportService(db *gorm.DB) *ScenarioExportService {
 func withExportAssociations(db *gorm.DB) *gorm.DB {
 	byOrder := func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }
 	return db.
-		Preload("Steps", byOrder).
+		Preload("Steps", models.DraftSteps, byOrder).
 		Preload("Steps.Questions", byOrder).
-		Preload("CompatibleInstanceTypes")
+		Preload("Steps.Transitions", func(db *gorm.DB) *gorm.DB { return db.Order("priority ASC, created_at ASC") }).
+		Preload("Steps.Hints", func(db *gorm.DB) *gorm.DB { return db.Order("level ASC") }).
+		Preload("CompatibleInstanceTypes").
+		Preload("Nodes")
 }
 
 // ExportAsJSON loads a scenario with steps and returns the export DTO
@@ -110,6 +116,9 @@ func (s *ScenarioExportService) buildExportOutput(scenario *models.Scenario) *dt
 					CorrectAnswer: q.CorrectAnswer,
 					Explanation:   q.Explanation,
 					Points:        q.Points,
+					Tolerance:     q.Tolerance,
+					Difficulty:    q.Difficulty,
+					Topic:         q.Topic,
 				})
 			}
 		}
@@ -121,19 +130,24 @@ func (s *ScenarioExportService) buildExportOutput(scenario *models.Scenario) *dt
 			ShowImmediateFeedback: step.ShowImmediateFeedback,
 			TextContent:           ResolveScriptContent(s.db, step.TextFileID, step.TextContent),
 			HintContent:           ResolveScriptContent(s.db, step.HintFileID, step.HintContent),
+			HintPenalties:         hintPenaltyList(step.Hints),
 			VerifyScript:          ResolveScriptContent(s.db, step.VerifyScriptID, step.VerifyScript),
 			BackgroundScript:      ResolveScriptContent(s.db, step.BackgroundScriptID, step.BackgroundScript),
 			ForegroundScript:      ResolveScriptContent(s.db, step.ForegroundScriptID, step.ForegroundScript),
+			SolutionScript:        step.SolutionScript,
 			IntroEffect:           step.IntroEffect,
 			IntroText:             step.IntroText,
 			OutroEffect:           step.OutroEffect,
 			OutroText:             step.OutroText,
 			BackgroundTimeoutSeconds: step.BackgroundTimeoutSeconds,
 			BackgroundAsync:       step.BackgroundAsync,
+			TargetNode:            step.TargetNode,
 			HasFlag:               step.HasFlag,
 			FlagPath:              step.FlagPath,
 			FlagLevel:             step.FlagLevel,
 			Questions:             questions,
+			QuizDrawCount:         step.QuizDrawCount,
+			Transitions:           transitionSpecs(step.Transitions, scenario.Steps),
 		})
 	}
 
@@ -151,6 +165,7 @@ func (s *ScenarioExportService) buildExportOutput(scenario *models.Scenario) *dt
 		IntroText:     introText,
 		FinishText:    finishText,
 		SetupScript:   setupScript,
+		Nodes:         ScenarioNodeSpecs(scenario.Nodes),
 		Steps:         steps,
 	}
 }
@@ -234,7 +249,13 @@ func (s *ScenarioExportService) buildArchive(scenario *models.Scenario) ([]byte,
 		// compatibility (index.json schema) is preserved. Only write when
 		// the step carries non-default OCF data.
 		if needsStepExtensions(&step) {
-			sidecar := buildStepExtensions(&step)
+			sidecar := buildStepExtensions(&step, scenario.Steps)
+			if step.SolutionScript != "" {
+				sidecar.Solution = stepDir + "/solution.sh"
+				if err := addFileToZip(w, sidecar.Solution, []byte(step.SolutionScript)); err != nil {
+					return nil, err
+				}
+			}
 			sidecarBytes, err := json.MarshalIndent(sidecar, "", "  ")
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal step %d extensions.json: %w", i+1, err)
@@ -245,6 +266,10 @@ func (s *ScenarioExportService) buildArchive(scenario *models.Scenario) ([]byte,
 		}
 	}
 
+	if err := s.addImagesToZip(w, scenario.ID); err != nil {
+		return nil, err
+	}
+
 	if err := w.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close zip writer: %w", err)
 	}
@@ -289,6 +314,7 @@ func (s *ScenarioExportService) buildKillerCodaIndex(scenario *models.Scenario)
 		hintContent := ResolveScriptContent(s.db, step.HintFileID, step.HintContent)
 		if hintContent != "" {
 			kcStep.Hint = resolveRelPath(s.db, step.HintFileID, stepDir+"/hint.md")
+			kcStep.HintPenalties = hintPenaltyList(step.Hints)
 		}
 		verifyScript := ResolveScriptContent(s.db, step.VerifyScriptID, step.VerifyScript)
 		if verifyScript != "" {
@@ -316,6 +342,7 @@ func (s *ScenarioExportService) buildKillerCodaIndex(scenario *models.Scenario)
 		}
 		kcStep.BackgroundTimeoutSeconds = step.BackgroundTimeoutSeconds
 		kcStep.BackgroundAsync = step.BackgroundAsync
+		kcStep.TargetNode = step.TargetNode
 
 		details.Steps = append(details.Steps, kcStep)
 	}
@@ -348,14 +375,17 @@ func (s *ScenarioExportService) buildKillerCodaIndex(scenario *models.Scenario)
 		requiredFeatures = nil
 	}
 
+	nodes := ScenarioNodeSpecs(scenario.Nodes)
+
 	if scenario.FlagsEnabled || scenario.CrashTraps ||
-		len(declaredImages) > 0 || len(requiredFeatures) > 0 {
+		len(declaredImages) > 0 || len(requiredFeatures) > 0 || len(nodes) > 0 {
 		index.Extensions = &KillerCodaExtensions{
 			OCF: &KillerCodaOCF{
 				Flags:                   scenario.FlagsEnabled,
 				CrashTraps:              scenario.CrashTraps,
 				CompatibleInstanceTypes: declaredImages,
 				RequiredFeatures:        requiredFeatures,
+				Nodes:                   nodes,
 			},
 		}
 	}
@@ -363,6 +393,38 @@ func (s *ScenarioExportService) buildKillerCodaIndex(scenario *models.Scenario)
 	return index
 }
 
+// addImagesToZip writes the scenario's image assets at the paths the markdown
+// references them by, so the archive re-imports with its images instead of
+// with broken links. Images are read from whichever backend holds them; one
+// that cannot be read fails the export rather than producing an archive that
+// quietly lacks it.
+func (s *ScenarioExportService) addImagesToZip(w *zip.Writer, scenarioID uuid.UUID) error {
+	var images []models.ProjectFile
+	if err := s.db.Where("scenario_id = ? AND content_type = ?", scenarioID, "image").
+		Order("rel_path ASC").Find(&images).Error; err != nil {
+		return fmt.Errorf("failed to load scenario images: %w", err)
+	}
+
+	for i := range images {
+		relPath := path.Clean(images[i].RelPath)
+		if images[i].RelPath == "" || path.IsAbs(relPath) || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		encoded, err := LoadProjectFileContent(&images[i])
+		if err != nil {
+			return fmt.Errorf("failed to load image %s: %w", relPath, err)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("failed to decode image %s: %w", relPath, err)
+		}
+		if err := addFileToZip(w, relPath, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // resolveRelPath returns the RelPath from a ProjectFile if fileID is non-nil and the file
 // has a non-empty RelPath, otherwise returns the fallback path.
 func resolveRelPath(db *gorm.DB, fileID *uuid.UUID, fallback string) string {
@@ -383,10 +445,10 @@ func resolveRelPath(db *gorm.DB, fileID *uuid.UUID, fallback string) string {
 // the legacy KillerCoda index.json schema (and therefore needs a sidecar file).
 // The on-disk payload type (stepExtensions) is defined alongside the importer.
 func needsStepExtensions(step *models.ScenarioStep) bool {
-	if len(step.Questions) > 0 {
+	if len(step.Questions) > 0 || len(step.Transitions) > 0 || step.QuizDrawCount > 0 {
 		return true
 	}
-	if step.ShowImmediateFeedback {
+	if step.ShowImmediateFeedback || step.SolutionScript != "" {
 		return true
 	}
 	if step.StepType != "" && step.StepType != "terminal" {
@@ -397,7 +459,8 @@ func needsStepExtensions(step *models.ScenarioStep) bool {
 
 // buildStepExtensions converts a step's extension fields into the sidecar payload.
 // The returned type is shared with the importer so the on-disk JSON shape is symmetric.
-func buildStepExtensions(step *models.ScenarioStep) *stepExtensions {
+// steps is the scenario's full step list, which transition targets are positions in.
+func buildStepExtensions(step *models.ScenarioStep, steps []models.ScenarioStep) *stepExtensions {
 	stepType := step.StepType
 	if stepType == "" {
 		stepType = "terminal"
@@ -415,6 +478,9 @@ func buildStepExtensions(step *models.ScenarioStep) *stepExtensions {
 				CorrectAnswer: q.CorrectAnswer,
 				Explanation:   q.Explanation,
 				Points:        q.Points,
+				Tolerance:     q.Tolerance,
+				Difficulty:    q.Difficulty,
+				Topic:         q.Topic,
 			})
 		}
 	}
@@ -423,6 +489,8 @@ func buildStepExtensions(step *models.ScenarioStep) *stepExtensions {
 		StepType:              stepType,
 		ShowImmediateFeedback: step.ShowImmediateFeedback,
 		Questions:             questions,
+		QuizDrawCount:         step.QuizDrawCount,
+		Transitions:           transitionSpecs(step.Transitions, steps),
 	}
 }
 
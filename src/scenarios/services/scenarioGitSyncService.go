@@ -0,0 +1,527 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	stdssh "golang.org/x/crypto/ssh"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	authModels "soli/formations/src/auth/models"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+// ErrGitSourceNotFound is returned for a source that does not exist in the
+// organization.
+var ErrGitSourceNotFound = errors.New("scenario git source not found")
+
+// ErrGitSyncInProgress is returned by Sync while the source is being synced.
+var ErrGitSyncInProgress = errors.New("a sync of this repository is already running")
+
+// ErrInvalidRepositoryURL is returned by ValidateRepositoryURL.
+var ErrInvalidRepositoryURL = errors.New("repository URL must be https://…, ssh://… or user@host:path")
+
+// ErrSSHKeyRequired is returned when an SSH repository is registered without a key.
+var ErrSSHKeyRequired = errors.New("an SSH repository needs one of your SSH keys")
+
+// ErrSSHKeyNotFound is returned for an SSH key the user does not own.
+var ErrSSHKeyNotFound = errors.New("SSH key not found")
+
+// scpLikeURL matches the user@host:path form of an SSH repository URL.
+var scpLikeURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/].*$`)
+
+// gitSyncStates holds the per-source sync state, shared by every service
+// instance so that a manual sync and a webhook never pull the same source at
+// once.
+var gitSyncStates sync.Map // uuid.UUID → *gitSyncState
+
+type gitSyncState struct {
+	mu      sync.Mutex
+	running bool
+	pending bool // a push arrived while running: sync again once done
+}
+
+// start marks the source as syncing. It reports false when a sync is already
+// running, after asking it to run again if queue is set.
+func (st *gitSyncState) start(queue bool) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.running {
+		st.pending = st.pending || queue
+		return false
+	}
+	st.running = true
+	return true
+}
+
+// finish ends a sync. It reports true, leaving the source marked as syncing,
+// when another sync was queued meanwhile.
+func (st *gitSyncState) finish() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.pending {
+		st.pending = false
+		return true
+	}
+	st.running = false
+	return false
+}
+
+func gitSyncStateFor(sourceID uuid.UUID) *gitSyncState {
+	st, _ := gitSyncStates.LoadOrStore(sourceID, &gitSyncState{})
+	return st.(*gitSyncState)
+}
+
+// ScenarioGitSyncService keeps an organization's scenarios in sync with the
+// Git repositories they are authored in.
+//
+// A sync clones the source's branch, imports every scenario directory with
+// the importer — the same upsert by name as an upload — and publishes each as
+// a new revision. Publishing an unchanged draft is refused by the revision
+// service, which is how a sync tells unchanged scenarios apart. A scenario
+// failing validation is reported and left as it was; the others still sync.
+type ScenarioGitSyncService struct {
+	db        *gorm.DB
+	importer  *ScenarioImporterService
+	revisions *ScenarioRevisionService
+}
+
+// NewScenarioGitSyncService creates a git sync service.
+func NewScenarioGitSyncService(db *gorm.DB) *ScenarioGitSyncService {
+	return &ScenarioGitSyncService{
+		db:        db,
+		importer:  NewScenarioImporterService(db),
+		revisions: NewScenarioRevisionService(db),
+	}
+}
+
+// ValidateRepositoryURL accepts the remote repository URLs a source may be
+// registered with. Local paths and file:// URLs are refused: they would let a
+// user import files off the server.
+func ValidateRepositoryURL(repositoryURL string) error {
+	if scpLikeURL.MatchString(repositoryURL) {
+		return nil
+	}
+	u, err := url.Parse(repositoryURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "ssh") {
+		return ErrInvalidRepositoryURL
+	}
+	return nil
+}
+
+func isSSHRepositoryURL(repositoryURL string) bool {
+	return strings.HasPrefix(repositoryURL, "ssh://") || scpLikeURL.MatchString(repositoryURL)
+}
+
+// RegisterSource registers a repository for the organization. The repository
+// URL is not checked here: callers taking it from a user validate it with
+// ValidateRepositoryURL first. The returned source carries its webhook secret.
+func (s *ScenarioGitSyncService) RegisterSource(orgID uuid.UUID, userID string, input dto.CreateScenarioGitSourceInput) (*models.ScenarioGitSource, error) {
+	if input.SshKeyID != nil {
+		if _, err := s.loadSSHKey(*input.SshKeyID, userID); err != nil {
+			return nil, err
+		}
+	} else if isSSHRepositoryURL(input.RepositoryURL) {
+		return nil, ErrSSHKeyRequired
+	}
+	secret, err := webhookServices.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	branch := strings.TrimSpace(input.Branch)
+	if branch == "" {
+		branch = "main"
+	}
+	source := &models.ScenarioGitSource{
+		OrganizationID: orgID,
+		RepositoryURL:  strings.TrimSpace(input.RepositoryURL),
+		Branch:         branch,
+		SourcePath:     cleanSourcePath(input.SourcePath),
+		SshKeyID:       input.SshKeyID,
+		WebhookSecret:  secret,
+		CreatedByID:    userID,
+	}
+	if err := s.db.Create(source).Error; err != nil {
+		return nil, fmt.Errorf("failed to save git source: %w", err)
+	}
+	// BeforeSave encrypted the field in place.
+	source.WebhookSecret = secret
+	return source, nil
+}
+
+// cleanSourcePath normalizes a path inside the repository; "" is its root.
+// Cleaning it as an absolute path drops any ".." that would leave it.
+func cleanSourcePath(sourcePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.TrimSpace(sourcePath)), "/")
+}
+
+// loadSSHKey loads one of userID's SSH keys.
+func (s *ScenarioGitSyncService) loadSSHKey(keyID uuid.UUID, userID string) (*authModels.SshKey, error) {
+	var key authModels.SshKey
+	if err := s.db.First(&key, "id = ?", keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSSHKeyNotFound
+		}
+		return nil, err
+	}
+	if !slices.Contains(key.OwnerIDs, userID) {
+		return nil, ErrSSHKeyNotFound
+	}
+	return &key, nil
+}
+
+// ListSources returns the organization's sources, newest first.
+func (s *ScenarioGitSyncService) ListSources(orgID uuid.UUID) ([]models.ScenarioGitSource, error) {
+	var sources []models.ScenarioGitSource
+	err := s.db.Where("organization_id = ?", orgID).Order("created_at DESC").Find(&sources).Error
+	return sources, err
+}
+
+// GetSource loads one of the organization's sources.
+func (s *ScenarioGitSyncService) GetSource(orgID, sourceID uuid.UUID) (*models.ScenarioGitSource, error) {
+	var source models.ScenarioGitSource
+	if err := s.db.Where("organization_id = ?", orgID).First(&source, "id = ?", sourceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGitSourceNotFound
+		}
+		return nil, err
+	}
+	return &source, nil
+}
+
+// LoadSource loads a source by ID alone, for the push webhook.
+func (s *ScenarioGitSyncService) LoadSource(sourceID uuid.UUID) (*models.ScenarioGitSource, error) {
+	var source models.ScenarioGitSource
+	if err := s.db.First(&source, "id = ?", sourceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGitSourceNotFound
+		}
+		return nil, err
+	}
+	return &source, nil
+}
+
+// DeleteSource stops syncing a repository. Its scenarios are kept, with the
+// content and revisions they had; they are simply no longer synced.
+func (s *ScenarioGitSyncService) DeleteSource(orgID, sourceID uuid.UUID) error {
+	source, err := s.GetSource(orgID, sourceID)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Scenario{}).Where("git_source_id = ?", source.ID).
+			Update("git_source_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("source_id = ?", source.ID).Delete(&models.ScenarioGitSync{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(source).Error
+	})
+}
+
+// ListSyncs returns the source's latest syncs, newest first.
+func (s *ScenarioGitSyncService) ListSyncs(sourceID uuid.UUID, limit int) ([]models.ScenarioGitSync, error) {
+	var syncs []models.ScenarioGitSync
+	err := s.db.Where("source_id = ?", sourceID).Order("started_at DESC").Limit(limit).Find(&syncs).Error
+	return syncs, err
+}
+
+// Sync pulls the source now and waits for the result. It returns
+// ErrGitSyncInProgress while another sync of the source is running. A pull
+// that fails is not an error: it is recorded in the returned sync.
+func (s *ScenarioGitSyncService) Sync(sourceID uuid.UUID, trigger string) (*models.ScenarioGitSync, error) {
+	state := gitSyncStateFor(sourceID)
+	if !state.start(false) {
+		return nil, ErrGitSyncInProgress
+	}
+	run, err := s.sync(sourceID, trigger)
+	if state.finish() {
+		// A push arrived meanwhile: pull it too.
+		go s.drain(sourceID, state, models.GitSyncTriggerWebhook)
+	}
+	return run, err
+}
+
+// SyncInBackground pulls the source without waiting. A sync requested while
+// one is running runs again after it, so the latest push is never missed.
+func (s *ScenarioGitSyncService) SyncInBackground(sourceID uuid.UUID, trigger string) {
+	state := gitSyncStateFor(sourceID)
+	if !state.start(true) {
+		return
+	}
+	go s.drain(sourceID, state, trigger)
+}
+
+func (s *ScenarioGitSyncService) drain(sourceID uuid.UUID, state *gitSyncState, trigger string) {
+	for {
+		if _, err := s.sync(sourceID, trigger); err != nil {
+			slog.Error("git sync failed", "err", err, "source_id", sourceID)
+		}
+		if !state.finish() {
+			return
+		}
+	}
+}
+
+// sync runs one sync of the source; the caller holds its sync state.
+func (s *ScenarioGitSyncService) sync(sourceID uuid.UUID, trigger string) (*models.ScenarioGitSync, error) {
+	source, err := s.LoadSource(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	run := &models.ScenarioGitSync{
+		SourceID:  source.ID,
+		Trigger:   trigger,
+		Status:    models.GitSyncStatusRunning,
+		StartedAt: time.Now(),
+		Results:   []models.ScenarioGitSyncResult{},
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record git sync: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "ocf-git-sync-*")
+	if err != nil {
+		return s.finishSync(source, run, err)
+	}
+	defer os.RemoveAll(workDir)
+
+	sha, err := s.clone(source, workDir)
+	if err != nil {
+		return s.finishSync(source, run, err)
+	}
+	run.CommitSHA = sha
+
+	root := filepath.Join(workDir, filepath.FromSlash(source.SourcePath))
+	dirs, err := findScenarioDirs(root)
+	if err != nil {
+		return s.finishSync(source, run, fmt.Errorf("source path %q: %w", source.SourcePath, err))
+	}
+	claimed := make(map[string]string, len(dirs)) // scenario name → path declaring it
+	for _, dir := range dirs {
+		rel, _ := filepath.Rel(workDir, dir)
+		run.Results = append(run.Results, s.syncScenario(source, sha, dir, filepath.ToSlash(rel), claimed))
+	}
+	return s.finishSync(source, run, nil)
+}
+
+// clone checks the source's branch out into dir and returns its commit SHA.
+func (s *ScenarioGitSyncService) clone(source *models.ScenarioGitSource, dir string) (string, error) {
+	options := &git.CloneOptions{
+		URL:           source.RepositoryURL,
+		ReferenceName: plumbing.NewBranchReferenceName(source.Branch),
+		SingleBranch:  true,
+	}
+	if source.SshKeyID != nil {
+		key, err := s.loadSSHKey(*source.SshKeyID, source.CreatedByID)
+		if err != nil {
+			return "", fmt.Errorf("SSH key of the source: %w", err)
+		}
+		auth, err := ssh.NewPublicKeys("git", []byte(key.PrivateKey), "")
+		if err != nil {
+			return "", fmt.Errorf("SSH key of the source: %w", err)
+		}
+		auth.HostKeyCallback = stdssh.InsecureIgnoreHostKey()
+		options.Auth = auth
+	}
+
+	repo, err := git.PlainClone(dir, false, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to pull %s: %w", source.Branch, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the pulled commit: %w", err)
+	}
+	// The importer reads whatever the index points to; a symlink in the
+	// repository could point it at any file of the server.
+	if err := removeSymlinks(dir); err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+func removeSymlinks(root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return os.Remove(p)
+		}
+		return nil
+	})
+}
+
+// findScenarioDirs returns the directories under root holding an index.json.
+// A scenario's own subdirectories are not searched.
+func findScenarioDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info, err := os.Stat(filepath.Join(p, "index.json")); err == nil && info.Mode().IsRegular() {
+			dirs = append(dirs, p)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// syncScenario imports and publishes the scenario in dir.
+func (s *ScenarioGitSyncService) syncScenario(source *models.ScenarioGitSource, sha, dir, rel string, claimed map[string]string) models.ScenarioGitSyncResult {
+	result := models.ScenarioGitSyncResult{Path: rel}
+	fail := func(err error) models.ScenarioGitSyncResult {
+		result.Status = models.GitSyncScenarioFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	name, err := s.validateScenarioDir(source, dir)
+	if err != nil {
+		return fail(err)
+	}
+	result.Name = name
+	if other, ok := claimed[name]; ok {
+		return fail(fmt.Errorf("scenario %q is also declared in %s", name, other))
+	}
+	claimed[name] = rel
+
+	var existing models.Scenario
+	err = s.db.Where("name = ? AND organization_id = ?", name, source.OrganizationID).First(&existing).Error
+	if err == nil && (existing.GitSourceID == nil || *existing.GitSourceID != source.ID) {
+		return fail(fmt.Errorf("the organization already has a scenario named %q that is not synced from this repository", name))
+	}
+
+	scenario, err := s.importer.ImportFromDirectory(dir, source.CreatedByID, &source.OrganizationID, "git")
+	if err != nil {
+		return fail(err)
+	}
+	result.ScenarioID = &scenario.ID
+	if err := s.db.Model(scenario).Updates(map[string]any{
+		"source_type":    "git",
+		"git_repository": source.RepositoryURL,
+		"git_branch":     source.Branch,
+		"source_path":    rel,
+		"git_source_id":  source.ID,
+		"git_commit_sha": sha,
+	}).Error; err != nil {
+		return fail(fmt.Errorf("failed to record the commit: %w", err))
+	}
+
+	revision, err := s.revisions.Publish(scenario.ID, source.CreatedByID, "Synced from "+shortSHA(sha))
+	switch {
+	case errors.Is(err, ErrNothingToPublish):
+		result.Status = models.GitSyncScenarioUnchanged
+		var current models.ScenarioRevision
+		if s.db.Joins("JOIN scenarios ON scenarios.published_revision_id = scenario_revisions.id").
+			Where("scenarios.id = ?", scenario.ID).First(&current).Error == nil {
+			result.RevisionNumber = &current.Number
+		}
+	case err != nil:
+		return fail(err)
+	default:
+		result.Status = models.GitSyncScenarioImported
+		result.RevisionNumber = &revision.Number
+	}
+	return result
+}
+
+// validateScenarioDir checks the scenario in dir the way an import would,
+// before anything is written, and returns its name.
+func (s *ScenarioGitSyncService) validateScenarioDir(source *models.ScenarioGitSource, dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read index.json: %w", err)
+	}
+	index, err := s.importer.ParseIndexJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("index.json: %w", err)
+	}
+	if strings.TrimSpace(index.Title) == "" {
+		return "", errors.New("index.json: title is required")
+	}
+	if len(index.Details.Steps) == 0 {
+		return "", errors.New("index.json: the scenario has no steps")
+	}
+	scenario, err := s.importer.BuildScenarioFromIndex(index, dir, source.CreatedByID, &source.OrganizationID, "git")
+	if err != nil {
+		return "", err
+	}
+	return scenario.Name, nil
+}
+
+// finishSync records the outcome of run: pullErr when the repository could
+// not be pulled, else the per-scenario results.
+func (s *ScenarioGitSyncService) finishSync(source *models.ScenarioGitSource, run *models.ScenarioGitSync, pullErr error) (*models.ScenarioGitSync, error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	switch {
+	case pullErr != nil:
+		run.Status = models.GitSyncStatusFailed
+		run.Error = pullErr.Error()
+	default:
+		run.Status = models.GitSyncStatusSucceeded
+		failed := 0
+		for _, r := range run.Results {
+			if r.Status == models.GitSyncScenarioFailed {
+				failed++
+			}
+		}
+		if failed > 0 {
+			run.Status = models.GitSyncStatusPartial
+			run.Error = fmt.Sprintf("%d of %d scenarios failed to sync", failed, len(run.Results))
+		}
+	}
+	if err := s.db.Save(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record git sync: %w", err)
+	}
+
+	updates := map[string]any{
+		"last_synced_at":   now,
+		"last_sync_status": run.Status,
+		"last_sync_error":  run.Error,
+	}
+	if run.CommitSHA != "" {
+		updates["last_commit_sha"] = run.CommitSHA
+	}
+	if err := s.db.Model(source).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update git source: %w", err)
+	}
+	return run, nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Headers Git hosts authenticate their push webhooks with.
+const (
+	GitHubSignatureHeader = "X-Hub-Signature-256" // "sha256=<hex HMAC-SHA256 of the body>"
+	GiteaSignatureHeader  = "X-Gitea-Signature"   // "<hex HMAC-SHA256 of the body>", also sent by Forgejo
+	GitLabTokenHeader     = "X-Gitlab-Token"      // the secret itself
+)
+
+// ErrInvalidPushSignature is returned by VerifyPushWebhook for a push that
+// does not prove knowledge of the source's secret.
+var ErrInvalidPushSignature = errors.New("invalid push webhook signature")
+
+// VerifyPushWebhook authenticates a push webhook with the source's secret, in
+// whichever form the Git host sent it.
+func VerifyPushWebhook(secret string, header http.Header, body []byte) error {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	switch {
+	case header.Get(GitHubSignatureHeader) != "":
+		signature, ok := strings.CutPrefix(header.Get(GitHubSignatureHeader), "sha256=")
+		if ok && hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	case header.Get(GiteaSignatureHeader) != "":
+		if hmac.Equal([]byte(header.Get(GiteaSignatureHeader)), []byte(expected)) {
+			return nil
+		}
+	case header.Get(GitLabTokenHeader) != "":
+		if hmac.Equal([]byte(header.Get(GitLabTokenHeader)), []byte(secret)) {
+			return nil
+		}
+	}
+	return ErrInvalidPushSignature
+}
+
+// PushedBranch returns the branch a push event is about, or "" for an event
+// naming no ref — a host's ping, or a bare trigger. ok is false for a push to
+// something other than a branch, such as a tag.
+func PushedBranch(body []byte) (branch string, ok bool) {
+	var event struct {
+		Ref string `json:"ref"`
+	}
+	if json.Unmarshal(body, &event) != nil || event.Ref == "" {
+		return "", true
+	}
+	return strings.CutPrefix(event.Ref, "refs/heads/")
+}
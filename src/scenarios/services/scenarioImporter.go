@@ -17,7 +17,9 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"soli/formations/src/scenarios/dto"
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/storage"
 	"soli/formations/src/scenarios/utils"
 )
 
@@ -72,6 +74,10 @@ type KillerCodaStep struct {
 	// step's setup off the advance request.
 	BackgroundTimeoutSeconds int  `json:"background_timeout_seconds,omitempty"`
 	BackgroundAsync          bool `json:"background_async,omitempty"`
+	// OCF extension: the node of a multi-node scenario the step's scripts run on.
+	TargetNode string `json:"target_node,omitempty"`
+	// OCF extension: PenaltyPercent of each hint split from hint.md, by level.
+	HintPenalties []float64 `json:"hint_penalties,omitempty"`
 }
 
 // KillerCodaBackend describes the backend image to use
@@ -106,6 +112,10 @@ type KillerCodaOCF struct {
 	// needs without granting the learner a session's worth of egress, and
 	// without being locked out of every plan that has no internet access.
 	BuildFeatures    []string `json:"build_features,omitempty"`
+	// Nodes declares a multi-node topology: one machine per entry, the first
+	// being the primary one, all on a private network of their own. Steps
+	// pick theirs with target_node.
+	Nodes []dto.ScenarioNodeSpec `json:"nodes,omitempty"`
 }
 
 // KillerCodaAssets describes files to copy into the environment
@@ -122,12 +132,19 @@ type KillerCodaAsset struct {
 
 // ScenarioImporterService handles importing scenarios from KillerCoda-compatible directories
 type ScenarioImporterService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	store storage.FileStorage
 }
 
-// NewScenarioImporterService creates a new importer service
+// NewScenarioImporterService creates a new importer service writing file
+// content to the configured storage backend
 func NewScenarioImporterService(db *gorm.DB) *ScenarioImporterService {
-	return &ScenarioImporterService{db: db}
+	return &ScenarioImporterService{db: db, store: storage.Active()}
+}
+
+// SetFileStorage overrides the backend imported file content is written to
+func (s *ScenarioImporterService) SetFileStorage(store storage.FileStorage) {
+	s.store = store
 }
 
 // ImportFromDirectory parses a local directory containing a KillerCoda-format scenario.
@@ -171,9 +188,11 @@ func (s *ScenarioImporterService) ImportFromDirectory(dirPath string, createdByI
 			scenario.FlagSecret = existing.FlagSecret // preserve flag secret
 		}
 
+		var oldFiles []models.ProjectFile
 		err = s.db.Transaction(func(tx *gorm.DB) error {
 			// Collect old ProjectFile IDs from scenario and steps
 			oldFileIDs := collectProjectFileIDs(tx, existing.ID)
+			oldFiles = loadProjectFilesByID(tx, oldFileIDs)
 
 			// Null out scenario-level FKs before deleting files
 			if err := tx.Model(&existing).Updates(map[string]any{
@@ -199,18 +218,24 @@ func (s *ScenarioImporterService) ImportFromDirectory(dirPath string, createdByI
 
 			// Delete old hints before steps (soft-delete won't cascade)
 			if err := tx.Where("step_id IN (?)",
-				tx.Model(&models.ScenarioStep{}).Select("id").Where("scenario_id = ?", existing.ID),
+				tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).Select("id").Where("scenario_id = ?", existing.ID),
 			).Delete(&models.ScenarioStepHint{}).Error; err != nil {
 				return fmt.Errorf("failed to delete old hints: %w", err)
 			}
 			// Delete old quiz questions before steps (soft-delete won't cascade)
 			if err := tx.Where("step_id IN (?)",
-				tx.Model(&models.ScenarioStep{}).Select("id").Where("scenario_id = ?", existing.ID),
+				tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).Select("id").Where("scenario_id = ?", existing.ID),
 			).Delete(&models.ScenarioStepQuestion{}).Error; err != nil {
 				return fmt.Errorf("failed to delete old questions: %w", err)
 			}
+			// Delete old transitions before steps (soft-delete won't cascade)
+			if err := tx.Where("step_id IN (?)",
+				tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).Select("id").Where("scenario_id = ?", existing.ID),
+			).Delete(&models.ScenarioStepTransition{}).Error; err != nil {
+				return fmt.Errorf("failed to delete old transitions: %w", err)
+			}
 			// Delete old steps
-			if err := tx.Where("scenario_id = ?", existing.ID).Delete(&models.ScenarioStep{}).Error; err != nil {
+			if err := tx.Scopes(models.DraftSteps).Where("scenario_id = ?", existing.ID).Delete(&models.ScenarioStep{}).Error; err != nil {
 				return fmt.Errorf("failed to delete old steps: %w", err)
 			}
 			// Delete old ProjectFiles
@@ -235,6 +260,9 @@ func (s *ScenarioImporterService) ImportFromDirectory(dirPath string, createdByI
 					return fmt.Errorf("failed to create compatible instance type: %w", err)
 				}
 			}
+			if err := replaceScenarioNodes(tx, existing.ID, scenario.Nodes); err != nil {
+				return err
+			}
 
 			// Create new steps
 			for i := range scenario.Steps {
@@ -245,12 +273,12 @@ func (s *ScenarioImporterService) ImportFromDirectory(dirPath string, createdByI
 			}
 
 			// Create ProjectFiles for scenario and steps (dual-write)
-			if err := createProjectFilesForScenario(tx, &existing, scenario, stepRelPaths); err != nil {
+			if err := createProjectFilesForScenario(tx, s.store, &existing, scenario, stepRelPaths); err != nil {
 				return err
 			}
 
 			// Import images referenced in markdown content
-			if err := importScenarioImages(tx, existing.ID, dirPath, index, scenario); err != nil {
+			if err := importScenarioImages(tx, s.store, existing.ID, dirPath, index, scenario); err != nil {
 				return err
 			}
 
@@ -259,10 +287,11 @@ func (s *ScenarioImporterService) ImportFromDirectory(dirPath string, createdByI
 		if err != nil {
 			return nil, err
 		}
+		releaseProjectFileContent(s.db, oldFiles)
 
 		// Reload
 		if err := s.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("\"order\" ASC")
+			return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 		}).Preload("Steps.Hints", func(db *gorm.DB) *gorm.DB {
 			return db.Order("level ASC")
 		}).First(&existing, "id = ?", existing.ID).Error; err != nil {
@@ -278,12 +307,12 @@ func (s *ScenarioImporterService) ImportFromDirectory(dirPath string, createdByI
 		}
 
 		// Create ProjectFiles for scenario and steps (dual-write)
-		if err := createProjectFilesForScenario(tx, scenario, scenario, stepRelPaths); err != nil {
+		if err := createProjectFilesForScenario(tx, s.store, scenario, scenario, stepRelPaths); err != nil {
 			return err
 		}
 
 		// Import images referenced in markdown content
-		if err := importScenarioImages(tx, scenario.ID, dirPath, index, scenario); err != nil {
+		if err := importScenarioImages(tx, s.store, scenario.ID, dirPath, index, scenario); err != nil {
 			return err
 		}
 
@@ -313,6 +342,7 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 	flagsEnabled := false
 	crashTraps := false
 	var compatibleInstanceTypes []models.ScenarioInstanceType
+	var nodes []models.ScenarioNode
 	requiredFeatures := ""
 	buildFeatures := ""
 	if index.Extensions != nil && index.Extensions.OCF != nil {
@@ -320,6 +350,12 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 		crashTraps = index.Extensions.OCF.CrashTraps
 		compatibleInstanceTypes = BuildCompatibleInstanceTypes(index.Extensions.OCF.CompatibleInstanceTypes)
 
+		var nodesErr error
+		nodes, nodesErr = BuildScenarioNodes(index.Extensions.OCF.Nodes)
+		if nodesErr != nil {
+			return nil, fmt.Errorf("invalid nodes in index.json: %w", nodesErr)
+		}
+
 		var featErr error
 		requiredFeatures, featErr = EncodeRequiredFeatures(index.Extensions.OCF.RequiredFeatures)
 		if featErr != nil {
@@ -379,10 +415,14 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 		CreatedByID:    createdByID,
 		OrganizationID: orgID,
 		CompatibleInstanceTypes: compatibleInstanceTypes,
+		Nodes:                   nodes,
 	}
 
 	// Build steps
 	steps := make([]models.ScenarioStep, 0, len(index.Details.Steps))
+	// Transitions target steps by position, so they are resolved once every
+	// step exists. Keyed by step Order.
+	transitionSpecsByOrder := make(map[int][]dto.StepTransitionSpec)
 	for i, kcStep := range index.Details.Steps {
 		// Per-step has_flag override: if specified, use it; otherwise fall back to scenario-level flagsEnabled
 		stepHasFlag := flagsEnabled
@@ -404,6 +444,7 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 			OutroText:        kcStep.OutroText,
 			BackgroundTimeoutSeconds: kcStep.BackgroundTimeoutSeconds,
 			BackgroundAsync:  kcStep.BackgroundAsync,
+			TargetNode:       kcStep.TargetNode,
 			HasFlag:          stepHasFlag,
 			FlagPath:         kcStep.FlagPath,
 			// index.json has no step_type; a sidecar may still declare one below.
@@ -419,6 +460,9 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 					Level:   j + 1,
 					Content: part,
 				}
+				if j < len(kcStep.HintPenalties) {
+					hints[j].PenaltyPercent = kcStep.HintPenalties[j]
+				}
 			}
 			step.Hints = hints
 		}
@@ -431,6 +475,8 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 		} else if sidecar != nil {
 			step.StepType = ResolveStepType(sidecar.StepType, step.HasFlag)
 			step.ShowImmediateFeedback = sidecar.ShowImmediateFeedback
+			step.QuizDrawCount = sidecar.QuizDrawCount
+			step.SolutionScript = readFileContent(dirPath, sidecar.Solution)
 			if len(sidecar.Questions) > 0 {
 				questions := make([]models.ScenarioStepQuestion, len(sidecar.Questions))
 				for j, q := range sidecar.Questions {
@@ -442,10 +488,19 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 						CorrectAnswer: q.CorrectAnswer,
 						Explanation:   q.Explanation,
 						Points:        q.Points,
+						Tolerance:     q.Tolerance,
+						Difficulty:    q.Difficulty,
+						Topic:         q.Topic,
+					}
+					if err := ValidateQuizQuestion(&questions[j]); err != nil {
+						return nil, fmt.Errorf("%s question %d: %w", stepDir, j+1, err)
 					}
 				}
 				step.Questions = questions
 			}
+			if len(sidecar.Transitions) > 0 {
+				transitionSpecsByOrder[step.Order] = sidecar.Transitions
+			}
 		}
 
 		steps = append(steps, step)
@@ -456,6 +511,21 @@ func (s *ScenarioImporterService) BuildScenarioFromIndex(index *KillerCodaIndex,
 		return steps[i].Order < steps[j].Order
 	})
 
+	for i := range steps {
+		specs := transitionSpecsByOrder[steps[i].Order]
+		if len(specs) == 0 {
+			continue
+		}
+		transitions, err := transitionsFromSpecs(specs, steps)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transition in step %d extensions.json: %w", i+1, err)
+		}
+		steps[i].Transitions = transitions
+	}
+
+	if err := ValidateStepTargets(nodes, steps); err != nil {
+		return nil, err
+	}
 	scenario.Steps = steps
 
 	return scenario, nil
@@ -469,17 +539,25 @@ type stepExtensions struct {
 	StepType              string                   `json:"step_type,omitempty"`
 	ShowImmediateFeedback bool                     `json:"show_immediate_feedback,omitempty"`
 	Questions             []stepExtensionsQuestion `json:"questions,omitempty"`
+	QuizDrawCount         int                      `json:"quiz_draw_count,omitempty"`
+	Transitions           []dto.StepTransitionSpec `json:"transitions,omitempty"`
+	// Solution is the path of the step's solution script, relative to the
+	// scenario root like the paths of index.json.
+	Solution string `json:"solution,omitempty"`
 }
 
 // stepExtensionsQuestion is the on-disk shape of a quiz question inside extensions.json.
 type stepExtensionsQuestion struct {
-	Order         int    `json:"order"`
-	QuestionText  string `json:"question_text"`
-	QuestionType  string `json:"question_type"`
-	Options       string `json:"options,omitempty"`
-	CorrectAnswer string `json:"correct_answer,omitempty"`
-	Explanation   string `json:"explanation,omitempty"`
-	Points        int    `json:"points,omitempty"`
+	Order         int     `json:"order"`
+	QuestionText  string  `json:"question_text"`
+	QuestionType  string  `json:"question_type"`
+	Options       string  `json:"options,omitempty"`
+	CorrectAnswer string  `json:"correct_answer,omitempty"`
+	Explanation   string  `json:"explanation,omitempty"`
+	Points        int     `json:"points,omitempty"`
+	Tolerance     float64 `json:"tolerance,omitempty"`
+	Difficulty    string  `json:"difficulty,omitempty"`
+	Topic         string  `json:"topic,omitempty"`
 }
 
 // stepDirFor resolves the directory holding a step's sidecar files.
@@ -626,16 +704,17 @@ func collectProjectFileIDs(tx *gorm.DB, scenarioID uuid.UUID) []uuid.UUID {
 // createProjectFilesForScenario creates ProjectFile records for a scenario and its steps,
 // and updates the FKs accordingly. dbScenario is the persisted scenario (with valid ID),
 // srcScenario provides the inline content, and stepRelPaths provides original KillerCoda paths.
-func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, srcScenario *models.Scenario, stepRelPaths []stepRelPathInfo) error {
+// File content is written through store.
+func createProjectFilesForScenario(tx *gorm.DB, store storage.FileStorage, dbScenario *models.Scenario, srcScenario *models.Scenario, stepRelPaths []stepRelPathInfo) error {
 	// Scenario-level setup script (global background)
 	if srcScenario.SetupScript != "" {
-		file := models.ProjectFile{
+		file, err := newProjectFile(store, models.ProjectFile{
 			Name:        "background.sh",
 			RelPath:     "background.sh",
 			ContentType: "script",
-			Content:     srcScenario.SetupScript,
-			StorageType: "database",
-			SizeBytes:   int64(len(srcScenario.SetupScript)),
+		}, srcScenario.SetupScript)
+		if err != nil {
+			return err
 		}
 		if err := tx.Create(&file).Error; err != nil {
 			return fmt.Errorf("failed to create setup script ProjectFile: %w", err)
@@ -647,13 +726,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 
 	// Scenario-level files
 	if srcScenario.IntroText != "" {
-		file := models.ProjectFile{
+		file, err := newProjectFile(store, models.ProjectFile{
 			Name:        "intro.md",
 			RelPath:     "intro.md",
 			ContentType: "markdown",
-			Content:     srcScenario.IntroText,
-			StorageType: "database",
-			SizeBytes:   int64(len(srcScenario.IntroText)),
+		}, srcScenario.IntroText)
+		if err != nil {
+			return err
 		}
 		if err := tx.Create(&file).Error; err != nil {
 			return fmt.Errorf("failed to create intro ProjectFile: %w", err)
@@ -664,13 +743,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 	}
 
 	if srcScenario.FinishText != "" {
-		file := models.ProjectFile{
+		file, err := newProjectFile(store, models.ProjectFile{
 			Name:        "finish.md",
 			RelPath:     "finish.md",
 			ContentType: "markdown",
-			Content:     srcScenario.FinishText,
-			StorageType: "database",
-			SizeBytes:   int64(len(srcScenario.FinishText)),
+		}, srcScenario.FinishText)
+		if err != nil {
+			return err
 		}
 		if err := tx.Create(&file).Error; err != nil {
 			return fmt.Errorf("failed to create finish ProjectFile: %w", err)
@@ -682,7 +761,7 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 
 	// Step-level files — reload steps from DB to get their persisted IDs
 	var dbSteps []models.ScenarioStep
-	tx.Where("scenario_id = ?", dbScenario.ID).Order("\"order\" ASC").Find(&dbSteps)
+	tx.Scopes(models.DraftSteps).Where("scenario_id = ?", dbScenario.ID).Order("\"order\" ASC").Find(&dbSteps)
 
 	for _, dbStep := range dbSteps {
 		// Find matching source step by order
@@ -713,13 +792,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 		}
 
 		if srcStep.VerifyScript != "" {
-			file := models.ProjectFile{
+			file, err := newProjectFile(store, models.ProjectFile{
 				Name:        "verify.sh",
 				RelPath:     relPaths.Verify,
 				ContentType: "script",
-				Content:     srcStep.VerifyScript,
-				StorageType: "database",
-				SizeBytes:   int64(len(srcStep.VerifyScript)),
+			}, srcStep.VerifyScript)
+			if err != nil {
+				return err
 			}
 			if err := tx.Create(&file).Error; err != nil {
 				return fmt.Errorf("failed to create verify ProjectFile: %w", err)
@@ -730,13 +809,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 		}
 
 		if srcStep.BackgroundScript != "" {
-			file := models.ProjectFile{
+			file, err := newProjectFile(store, models.ProjectFile{
 				Name:        "background.sh",
 				RelPath:     relPaths.Background,
 				ContentType: "script",
-				Content:     srcStep.BackgroundScript,
-				StorageType: "database",
-				SizeBytes:   int64(len(srcStep.BackgroundScript)),
+			}, srcStep.BackgroundScript)
+			if err != nil {
+				return err
 			}
 			if err := tx.Create(&file).Error; err != nil {
 				return fmt.Errorf("failed to create background ProjectFile: %w", err)
@@ -747,13 +826,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 		}
 
 		if srcStep.ForegroundScript != "" {
-			file := models.ProjectFile{
+			file, err := newProjectFile(store, models.ProjectFile{
 				Name:        "foreground.sh",
 				RelPath:     relPaths.Foreground,
 				ContentType: "script",
-				Content:     srcStep.ForegroundScript,
-				StorageType: "database",
-				SizeBytes:   int64(len(srcStep.ForegroundScript)),
+			}, srcStep.ForegroundScript)
+			if err != nil {
+				return err
 			}
 			if err := tx.Create(&file).Error; err != nil {
 				return fmt.Errorf("failed to create foreground ProjectFile: %w", err)
@@ -764,13 +843,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 		}
 
 		if srcStep.TextContent != "" {
-			file := models.ProjectFile{
+			file, err := newProjectFile(store, models.ProjectFile{
 				Name:        "text.md",
 				RelPath:     relPaths.Text,
 				ContentType: "markdown",
-				Content:     srcStep.TextContent,
-				StorageType: "database",
-				SizeBytes:   int64(len(srcStep.TextContent)),
+			}, srcStep.TextContent)
+			if err != nil {
+				return err
 			}
 			if err := tx.Create(&file).Error; err != nil {
 				return fmt.Errorf("failed to create text ProjectFile: %w", err)
@@ -781,13 +860,13 @@ func createProjectFilesForScenario(tx *gorm.DB, dbScenario *models.Scenario, src
 		}
 
 		if srcStep.HintContent != "" {
-			file := models.ProjectFile{
+			file, err := newProjectFile(store, models.ProjectFile{
 				Name:        "hint.md",
 				RelPath:     relPaths.Hint,
 				ContentType: "markdown",
-				Content:     srcStep.HintContent,
-				StorageType: "database",
-				SizeBytes:   int64(len(srcStep.HintContent)),
+			}, srcStep.HintContent)
+			if err != nil {
+				return err
 			}
 			if err := tx.Create(&file).Error; err != nil {
 				return fmt.Errorf("failed to create hint ProjectFile: %w", err)
@@ -876,8 +955,9 @@ func ExtractLocalImagePaths(markdown string) []string {
 }
 
 // importScenarioImages scans all markdown content for image references,
-// reads the image files from the directory, and creates ProjectFile records.
-func importScenarioImages(tx *gorm.DB, scenarioID uuid.UUID, dirPath string, index *KillerCodaIndex, srcScenario *models.Scenario) error {
+// reads the image files from the directory, and creates ProjectFile records
+// whose content is written through store.
+func importScenarioImages(tx *gorm.DB, store storage.FileStorage, scenarioID uuid.UUID, dirPath string, index *KillerCodaIndex, srcScenario *models.Scenario) error {
 	// Collect (markdownContent, markdownFileDir) pairs to scan for images
 	type mdSource struct {
 		content string
@@ -940,15 +1020,16 @@ func importScenarioImages(tx *gorm.DB, scenarioID uuid.UUID, dirPath string, ind
 			mimeType = "application/octet-stream"
 		}
 
-		file := models.ProjectFile{
+		file, err := newProjectFile(store, models.ProjectFile{
 			Name:        filepath.Base(relPath),
 			RelPath:     relPath,
 			ContentType: "image",
 			MimeType:    mimeType,
-			Content:     content,
-			StorageType: "database",
 			SizeBytes:   sizeBytes,
 			ScenarioID:  &scenarioID,
+		}, content)
+		if err != nil {
+			return err
 		}
 		if err := tx.Create(&file).Error; err != nil {
 			return fmt.Errorf("failed to create image ProjectFile %s: %w", relPath, err)
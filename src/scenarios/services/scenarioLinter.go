@@ -0,0 +1,389 @@
+package services
+
+// scenarioLinter.go — dry-run validation of a scenario. Import only refuses
+// what it cannot store; a scenario it accepts can still be one learners
+// cannot finish: a flag nobody places, a quiz nobody can pass, a feature no
+// host offers. The linter runs the checks the session engine would otherwise
+// make at play time, and reports them per step instead of failing.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// Lint issue severities.
+const (
+	LintSeverityError   = "error"   // Learners cannot complete the scenario as authored
+	LintSeverityWarning = "warning" // The scenario runs, likely not as intended
+)
+
+// ScenarioLinter validates scenarios without importing them.
+type ScenarioLinter struct {
+	db *gorm.DB
+	// featureCatalog lists the feature keys terminals can be started with.
+	// Nil skips the feature checks.
+	featureCatalog func() ([]string, error)
+}
+
+// NewScenarioLinter creates a linter. db is only needed by LintStoredScenario;
+// featureCatalog may be nil when no catalog is reachable, as in the CLI.
+func NewScenarioLinter(db *gorm.DB, featureCatalog func() ([]string, error)) *ScenarioLinter {
+	return &ScenarioLinter{db: db, featureCatalog: featureCatalog}
+}
+
+// lintReport accumulates the issues of one lint run.
+type lintReport struct {
+	issues []dto.ScenarioLintIssue
+}
+
+func (r *lintReport) add(severity, code string, step *models.ScenarioStep, position int, field, format string, args ...any) {
+	issue := dto.ScenarioLintIssue{
+		Severity: severity,
+		Code:     code,
+		Step:     position,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if step != nil {
+		issue.StepTitle = step.Title
+	}
+	r.issues = append(r.issues, issue)
+}
+
+func (r *lintReport) result() *dto.ScenarioLintReport {
+	report := &dto.ScenarioLintReport{Issues: r.issues}
+	if report.Issues == nil {
+		report.Issues = []dto.ScenarioLintIssue{}
+	}
+	for _, issue := range report.Issues {
+		if issue.Severity == LintSeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+	return report
+}
+
+// LintScenario checks a scenario built in memory, with its steps, hints,
+// questions and transitions, and scripts resolved to their content.
+func (l *ScenarioLinter) LintScenario(scenario *models.Scenario) *dto.ScenarioLintReport {
+	var r lintReport
+	l.lintScenario(&r, scenario)
+	return r.result()
+}
+
+// LintSeedInput checks a scenario in the JSON import format. Question and
+// transition errors, which make the import fail on the first one, are all
+// reported instead.
+func (l *ScenarioLinter) LintSeedInput(input dto.SeedScenarioInput) *dto.ScenarioLintReport {
+	var r lintReport
+	scenario := &models.Scenario{
+		Title:            input.Title,
+		FlagsEnabled:     input.FlagsEnabled,
+		AllowedFlagPaths: input.AllowedFlagPaths,
+		CrashTraps:       input.CrashTraps,
+		SetupScript:      input.SetupScript,
+		Steps:            seedStepsFromInput(input.Steps),
+	}
+	scenario.RequiredFeatures, _ = EncodeRequiredFeatures(input.RequiredFeatures)
+	scenario.BuildFeatures, _ = EncodeRequiredFeatures(input.BuildFeatures)
+
+	for i, st := range input.Steps {
+		step := &scenario.Steps[i]
+		for j, spec := range st.Transitions {
+			field := fmt.Sprintf("transitions[%d]", j+1)
+			if err := ValidateStepTransition(spec.Condition, spec.Threshold, spec.Value); err != nil {
+				r.add(LintSeverityError, "invalid_transition", step, i+1, field, "%v", err)
+				continue
+			}
+			target, err := transitionOrderTarget(scenario.Steps, spec.TargetStep)
+			if err != nil {
+				r.add(LintSeverityError, "transition_target_missing", step, i+1, field, "%v", err)
+				continue
+			}
+			step.Transitions = append(step.Transitions, models.ScenarioStepTransition{
+				Priority:        spec.Priority,
+				Condition:       spec.Condition,
+				Threshold:       spec.Threshold,
+				Value:           spec.Value,
+				TargetStepOrder: target,
+			})
+		}
+	}
+
+	l.lintScenario(&r, scenario)
+	return r.result()
+}
+
+// LintDirectory checks a KillerCoda-compatible scenario directory, as
+// ImportFromDirectory would read it. Files index.json references but the
+// directory lacks are reported; the importer reads them as empty.
+func (l *ScenarioLinter) LintDirectory(dirPath string) (*dto.ScenarioLintReport, error) {
+	data, err := os.ReadFile(filepath.Join(dirPath, "index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index.json: %w", err)
+	}
+	importer := NewScenarioImporterService(l.db)
+	index, err := importer.ParseIndexJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var r lintReport
+	checkFile := func(relPath string, position int, title, field string) {
+		if relPath == "" || lintFileExists(dirPath, relPath) {
+			return
+		}
+		r.issues = append(r.issues, dto.ScenarioLintIssue{
+			Severity:  LintSeverityError,
+			Code:      "missing_file",
+			Step:      position,
+			StepTitle: title,
+			Field:     field,
+			Message:   fmt.Sprintf("%s is referenced by index.json but missing from the scenario", relPath),
+		})
+	}
+	checkFile(index.Intro.Text, 0, "", "intro.text")
+	checkFile(index.Intro.Background, 0, "", "intro.background")
+	checkFile(index.Intro.Foreground, 0, "", "intro.foreground")
+	checkFile(index.Details.Intro.Text, 0, "", "details.intro.text")
+	checkFile(index.Details.Intro.Background, 0, "", "details.intro.background")
+	checkFile(index.Details.Intro.Foreground, 0, "", "details.intro.foreground")
+	checkFile(index.Finish.Text, 0, "", "finish.text")
+	checkFile(index.Details.Finish.Text, 0, "", "details.finish.text")
+	for i, kcStep := range index.Details.Steps {
+		checkFile(kcStep.Text, i+1, kcStep.Title, "text")
+		checkFile(kcStep.Verify, i+1, kcStep.Title, "verify")
+		checkFile(kcStep.Background, i+1, kcStep.Title, "background")
+		checkFile(kcStep.Foreground, i+1, kcStep.Title, "foreground")
+		checkFile(kcStep.Hint, i+1, kcStep.Title, "hint")
+	}
+
+	scenario, err := importer.BuildScenarioFromIndex(index, dirPath, "", nil, "lint")
+	if err != nil {
+		r.add(LintSeverityError, "invalid_scenario", nil, 0, "", "%v", err)
+		return r.result(), nil
+	}
+	l.lintScenario(&r, scenario)
+	return r.result(), nil
+}
+
+// LintStoredScenario checks the draft of a scenario already on the platform —
+// what the next revision would publish.
+func (l *ScenarioLinter) LintStoredScenario(scenario *models.Scenario) (*dto.ScenarioLintReport, error) {
+	setupScript, steps, err := NewScenarioRevisionService(l.db).loadVersion(scenario, nil)
+	if err != nil {
+		return nil, err
+	}
+	draft := *scenario
+	draft.SetupScript = setupScript
+	draft.Steps = steps
+	return l.LintScenario(&draft), nil
+}
+
+// lintFileExists reports whether relPath names a file inside dirPath, with
+// the path traversal protection of readFileContent.
+func lintFileExists(dirPath, relPath string) bool {
+	cleanPath := filepath.Clean(filepath.Join(dirPath, relPath))
+	cleanDir := filepath.Clean(dirPath)
+	if !strings.HasPrefix(cleanPath, cleanDir+string(filepath.Separator)) {
+		return false
+	}
+	info, err := os.Stat(cleanPath)
+	return err == nil && !info.IsDir()
+}
+
+func (l *ScenarioLinter) lintScenario(r *lintReport, scenario *models.Scenario) {
+	if strings.TrimSpace(scenario.Title) == "" {
+		r.add(LintSeverityError, "missing_title", nil, 0, "title", "the scenario has no title")
+	}
+	if len(scenario.Steps) == 0 {
+		r.add(LintSeverityError, "no_steps", nil, 0, "steps", "the scenario has no steps")
+	}
+	lintScript(r, nil, 0, "setup_script", scenario.SetupScript)
+	l.lintFeatures(r, scenario)
+
+	allowedPrefixes := defaultAllowedFlagPaths
+	if scenario.AllowedFlagPaths != "" {
+		allowedPrefixes = parseAllowedFlagPaths(scenario.AllowedFlagPaths)
+	}
+	for i := range scenario.Steps {
+		step := &scenario.Steps[i]
+		position := i + 1
+		if strings.TrimSpace(step.Title) == "" {
+			r.add(LintSeverityWarning, "missing_step_title", step, position, "title", "the step has no title")
+		}
+		lintScript(r, step, position, "verify_script", step.VerifyScript)
+		lintScript(r, step, position, "background_script", step.BackgroundScript)
+		lintFlag(r, scenario, step, position, allowedPrefixes)
+		lintQuiz(r, step, position)
+		lintTransitions(r, scenario.Steps, step, position)
+
+		switch normalizeStepType(step.StepType) {
+		case StepTypeTerminal:
+			if step.VerifyScript == "" && !step.HasFlag {
+				r.add(LintSeverityWarning, "no_verify_script", step, position, "verify_script",
+					"the step has no verify script; it passes without checking anything")
+			}
+		case StepTypeFlag, "quiz", "info":
+		default:
+			r.add(LintSeverityWarning, "unknown_step_type", step, position, "step_type",
+				"step type %q is unknown; the step is played as a terminal step", step.StepType)
+		}
+	}
+}
+
+// lintScript checks the interpreter a script runs with, as parseShebang
+// resolves it.
+func lintScript(r *lintReport, step *models.ScenarioStep, position int, field, script string) {
+	if strings.TrimSpace(script) == "" {
+		return
+	}
+	if !strings.HasPrefix(script, "#!") {
+		r.add(LintSeverityWarning, "missing_shebang", step, position, field,
+			"the script has no shebang; it runs with /bin/sh")
+		return
+	}
+	if interpreter := parseShebang(script); !strings.HasPrefix(interpreter, "/") {
+		r.add(LintSeverityError, "invalid_shebang", step, position, field,
+			"the shebang names %q, which is not an absolute path", interpreter)
+	}
+}
+
+// lintFlag checks that a flag step gets a flag, and that the flag reaches the
+// container: the session engine writes it at FlagPath, or hands it to the
+// background script in OCF_FLAG_CURRENT.
+func lintFlag(r *lintReport, scenario *models.Scenario, step *models.ScenarioStep, position int, allowedPrefixes []string) {
+	generated := scenario.FlagsEnabled && step.HasFlag
+	if !generated {
+		if step.StepType == StepTypeFlag {
+			if !scenario.FlagsEnabled {
+				r.add(LintSeverityError, "flags_disabled", step, position, "step_type",
+					"the step is a flag step but flags are disabled for the scenario; no flag can be submitted")
+			} else {
+				r.add(LintSeverityError, "flag_not_generated", step, position, "has_flag",
+					"the step is a flag step but has no flag; no flag can be submitted")
+			}
+		}
+		if step.FlagPath != "" {
+			r.add(LintSeverityWarning, "flag_path_unused", step, position, "flag_path",
+				"the step has a flag path but no flag is generated for it")
+		}
+		return
+	}
+
+	if step.FlagPath == "" {
+		if !scenario.CrashTraps && !strings.Contains(step.BackgroundScript, ocfFlagCurrentEnv) {
+			r.add(LintSeverityError, "flag_not_placed", step, position, "flag_path",
+				"the step has a flag but no flag path, and its background script does not read %s; the flag is never placed", ocfFlagCurrentEnv)
+		}
+		return
+	}
+	if strings.Contains(step.FlagPath, "..") {
+		r.add(LintSeverityError, "flag_path_traversal", step, position, "flag_path",
+			"flag path %q contains \"..\"; the flag is not deployed", step.FlagPath)
+		return
+	}
+	if !isFlagPathAllowed(step.FlagPath, allowedPrefixes) {
+		r.add(LintSeverityError, "flag_path_not_allowed", step, position, "flag_path",
+			"flag path %q is outside the allowed prefixes %s; the flag is not deployed",
+			step.FlagPath, strings.Join(allowedPrefixes, ", "))
+	}
+}
+
+// lintQuiz checks that a quiz step's questions can be answered.
+func lintQuiz(r *lintReport, step *models.ScenarioStep, position int) {
+	if normalizeStepType(step.StepType) != "quiz" {
+		if len(step.Questions) > 0 {
+			r.add(LintSeverityWarning, "questions_ignored", step, position, "questions",
+				"the step has questions but is not a quiz step; they are never asked")
+		}
+		return
+	}
+	if len(step.Questions) == 0 {
+		r.add(LintSeverityError, "quiz_no_questions", step, position, "questions", "the quiz step has no questions")
+		return
+	}
+	for j := range step.Questions {
+		q := &step.Questions[j]
+		field := fmt.Sprintf("questions[%d]", j+1)
+		if strings.TrimSpace(q.CorrectAnswer) == "" {
+			r.add(LintSeverityError, "quiz_no_correct_answer", step, position, field,
+				"the question has no correct answer; it cannot be passed")
+			continue
+		}
+		if err := ValidateQuizQuestion(q); err != nil {
+			r.add(LintSeverityError, "invalid_question", step, position, field, "%v", err)
+			continue
+		}
+		if q.QuestionType == models.QuestionTypeMultipleChoice && q.Options != "" {
+			if options, err := parseAnswerList(q.Options); err == nil && !slices.Contains(options, q.CorrectAnswer) {
+				r.add(LintSeverityError, "quiz_answer_not_in_options", step, position, field,
+					"the correct answer %q is not one of the options", q.CorrectAnswer)
+			}
+		}
+	}
+	if step.QuizDrawCount > len(step.Questions) {
+		r.add(LintSeverityWarning, "quiz_draw_exceeds_pool", step, position, "quiz_draw_count",
+			"the step draws %d questions from a pool of %d; every question is asked", step.QuizDrawCount, len(step.Questions))
+	}
+}
+
+// lintTransitions checks a step's transitions against the scenario's steps.
+func lintTransitions(r *lintReport, steps []models.ScenarioStep, step *models.ScenarioStep, position int) {
+	for j, t := range step.Transitions {
+		field := fmt.Sprintf("transitions[%d]", j+1)
+		if err := ValidateStepTransition(t.Condition, t.Threshold, t.Value); err != nil {
+			r.add(LintSeverityError, "invalid_transition", step, position, field, "%v", err)
+			continue
+		}
+		if _, ok := transitionPositionTarget(steps, t.TargetStepOrder); !ok {
+			r.add(LintSeverityError, "transition_target_missing", step, position, field,
+				"the transition targets step order %d, which does not exist", *t.TargetStepOrder)
+		}
+	}
+}
+
+// lintFeatures checks the scenario's features against the terminal feature
+// catalog: a feature no host offers makes every launch fail.
+func (l *ScenarioLinter) lintFeatures(r *lintReport, scenario *models.Scenario) {
+	required, err := scenario.GetRequiredFeatures()
+	if err != nil {
+		r.add(LintSeverityError, "invalid_features", nil, 0, "required_features", "%v", err)
+	}
+	build, err := scenario.GetBuildFeatures()
+	if err != nil {
+		r.add(LintSeverityError, "invalid_features", nil, 0, "build_features", "%v", err)
+	}
+	if l.featureCatalog == nil || len(required)+len(build) == 0 {
+		return
+	}
+	catalog, err := l.featureCatalog()
+	if err != nil {
+		r.add(LintSeverityWarning, "feature_catalog_unavailable", nil, 0, "",
+			"features were not checked: the terminal feature catalog is unavailable (%v)", err)
+		return
+	}
+	for _, name := range required {
+		if !slices.Contains(catalog, name) {
+			r.add(LintSeverityError, "unknown_feature", nil, 0, "required_features",
+				"feature %q is not in the terminal feature catalog", name)
+		}
+	}
+	for _, name := range build {
+		if !slices.Contains(catalog, name) {
+			r.add(LintSeverityError, "unknown_feature", nil, 0, "build_features",
+				"feature %q is not in the terminal feature catalog", name)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// ErrRevisionNotFound is returned for a revision number the scenario does not have.
+var ErrRevisionNotFound = errors.New("scenario revision not found")
+
+// ErrNothingToPublish is returned when the draft is identical to the
+// published revision: a new revision would only split its sessions in two.
+var ErrNothingToPublish = errors.New("the draft has no changes since the published revision")
+
+// ErrNoStepsToPublish is returned when publishing a scenario without steps.
+var ErrNoStepsToPublish = errors.New("scenario has no steps to publish")
+
+// draftVersion names the draft where a revision number is expected.
+const draftVersion = "draft"
+
+// ScenarioRevisionService publishes a scenario's draft as immutable revisions,
+// compares versions, and rolls the draft back to a revision. See
+// models.ScenarioRevision.
+type ScenarioRevisionService struct {
+	db *gorm.DB
+}
+
+// NewScenarioRevisionService creates a new revision service.
+func NewScenarioRevisionService(db *gorm.DB) *ScenarioRevisionService {
+	return &ScenarioRevisionService{db: db}
+}
+
+// loadScenarioContent sets scenario.Steps to the steps of a revision, or of
+// the draft when revisionID is nil, in order. A revision also brings the setup
+// script it was published with.
+func loadScenarioContent(db *gorm.DB, scenario *models.Scenario, revisionID *uuid.UUID) error {
+	if err := db.Scopes(models.RevisionSteps(revisionID)).
+		Where("scenario_id = ?", scenario.ID).
+		Order("\"order\" ASC").
+		Find(&scenario.Steps).Error; err != nil {
+		return fmt.Errorf("failed to load scenario steps: %w", err)
+	}
+	if revisionID == nil {
+		return nil
+	}
+	var revision models.ScenarioRevision
+	if err := db.Select("id", "setup_script").First(&revision, "id = ?", *revisionID).Error; err != nil {
+		return fmt.Errorf("failed to load scenario revision: %w", err)
+	}
+	scenario.SetupScript = revision.SetupScript
+	scenario.SetupScriptID = nil
+	return nil
+}
+
+// loadSessionContent sets session.Scenario's steps and setup script to the
+// ones the session runs: its revision's, or the draft's.
+func loadSessionContent(db *gorm.DB, session *models.ScenarioSession) error {
+	return loadScenarioContent(db, &session.Scenario, session.RevisionID)
+}
+
+// ListRevisions returns the scenario's revisions, newest first.
+func (s *ScenarioRevisionService) ListRevisions(scenarioID uuid.UUID) ([]dto.ScenarioRevisionOutput, error) {
+	var scenario models.Scenario
+	if err := s.db.Select("id", "published_revision_id").First(&scenario, "id = ?", scenarioID).Error; err != nil {
+		return nil, err
+	}
+	var revisions []models.ScenarioRevision
+	if err := s.db.Where("scenario_id = ?", scenarioID).Order("number DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load revisions: %w", err)
+	}
+	ids := make([]uuid.UUID, len(revisions))
+	for i, revision := range revisions {
+		ids[i] = revision.ID
+	}
+
+	stepCounts, err := countByRevision(s.db.Model(&models.ScenarioStep{}), ids)
+	if err != nil {
+		return nil, err
+	}
+	sessionCounts, err := countByRevision(s.db.Model(&models.ScenarioSession{}), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]dto.ScenarioRevisionOutput, len(revisions))
+	for i, revision := range revisions {
+		out[i] = dto.ScenarioRevisionOutput{
+			ID:            revision.ID,
+			Number:        revision.Number,
+			Note:          revision.Note,
+			PublishedByID: revision.PublishedByID,
+			PublishedAt:   revision.CreatedAt,
+			StepCount:     stepCounts[revision.ID],
+			SessionCount:  sessionCounts[revision.ID],
+			Current:       scenario.PublishedRevisionID != nil && *scenario.PublishedRevisionID == revision.ID,
+		}
+	}
+	return out, nil
+}
+
+func countByRevision(query *gorm.DB, revisionIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts := make(map[uuid.UUID]int64, len(revisionIDs))
+	if len(revisionIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		RevisionID uuid.UUID
+		Count      int64
+	}
+	if err := query.Select("revision_id, COUNT(*) AS count").
+		Where("revision_id IN ?", revisionIDs).
+		Group("revision_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count revision rows: %w", err)
+	}
+	for _, row := range rows {
+		counts[row.RevisionID] = row.Count
+	}
+	return counts, nil
+}
+
+// Publish freezes the scenario's draft as its next revision and makes it the
+// one new sessions start on. Sessions already running keep their revision.
+func (s *ScenarioRevisionService) Publish(scenarioID uuid.UUID, userID, note string) (*models.ScenarioRevision, error) {
+	var scenario models.Scenario
+	if err := s.db.First(&scenario, "id = ?", scenarioID).Error; err != nil {
+		return nil, err
+	}
+	setupScript, steps, err := s.loadVersion(&scenario, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, ErrNoStepsToPublish
+	}
+	if scenario.PublishedRevisionID != nil {
+		publishedSetup, publishedSteps, err := s.loadVersion(&scenario, scenario.PublishedRevisionID)
+		if err != nil {
+			return nil, err
+		}
+		if publishedSetup == setupScript && len(diffRevisionSteps(publishedSteps, steps)) == 0 {
+			return nil, ErrNothingToPublish
+		}
+	}
+
+	revision := &models.ScenarioRevision{
+		ScenarioID:    scenarioID,
+		Note:          note,
+		PublishedByID: userID,
+		SetupScript:   setupScript,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&models.ScenarioRevision{}).
+			Where("scenario_id = ?", scenarioID).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&last).Error; err != nil {
+			return fmt.Errorf("failed to number revision: %w", err)
+		}
+		revision.Number = last + 1
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create revision: %w", err)
+		}
+		for _, step := range steps {
+			if err := copyStep(tx, step, &revision.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Scenario{}).Where("id = ?", scenarioID).
+			Update("published_revision_id", revision.ID).Error; err != nil {
+			return fmt.Errorf("failed to publish revision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// Rollback replaces the scenario's draft with the content of revision number
+// and makes that revision the one new sessions start on again. No revision is
+// created or changed: the rolled-back one is already published.
+func (s *ScenarioRevisionService) Rollback(scenarioID uuid.UUID, number int) (*models.ScenarioRevision, error) {
+	var revision models.ScenarioRevision
+	if err := s.db.Where("scenario_id = ? AND number = ?", scenarioID, number).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	scenario := models.Scenario{}
+	scenario.ID = scenarioID
+	_, steps, err := s.loadVersion(&scenario, &revision.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		draftStepIDs := tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).
+			Select("id").Where("scenario_id = ?", scenarioID)
+		// Soft-delete won't cascade, so the step's rows go first.
+		if err := tx.Where("step_id IN (?)", draftStepIDs).Delete(&models.ScenarioStepHint{}).Error; err != nil {
+			return fmt.Errorf("failed to delete draft hints: %w", err)
+		}
+		if err := tx.Where("step_id IN (?)", draftStepIDs).Delete(&models.ScenarioStepQuestion{}).Error; err != nil {
+			return fmt.Errorf("failed to delete draft questions: %w", err)
+		}
+		if err := tx.Where("step_id IN (?)", draftStepIDs).Delete(&models.ScenarioStepTransition{}).Error; err != nil {
+			return fmt.Errorf("failed to delete draft transitions: %w", err)
+		}
+		if err := tx.Scopes(models.DraftSteps).Where("scenario_id = ?", scenarioID).
+			Delete(&models.ScenarioStep{}).Error; err != nil {
+			return fmt.Errorf("failed to delete draft steps: %w", err)
+		}
+		for _, step := range steps {
+			if err := copyStep(tx, step, nil); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Scenario{}).Where("id = ?", scenarioID).Updates(map[string]any{
+			"setup_script":          revision.SetupScript,
+			"setup_script_id":       nil,
+			"published_revision_id": revision.ID,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to restore scenario: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Diff compares two versions of the scenario, each a revision number or
+// "draft". from defaults to the published revision and to to the draft, so
+// the zero request answers "what would publishing change?".
+func (s *ScenarioRevisionService) Diff(scenarioID uuid.UUID, from, to string) (*dto.ScenarioRevisionDiff, error) {
+	var scenario models.Scenario
+	if err := s.db.First(&scenario, "id = ?", scenarioID).Error; err != nil {
+		return nil, err
+	}
+	if to == "" {
+		to = draftVersion
+	}
+	if from == "" {
+		if scenario.PublishedRevisionID == nil {
+			return nil, ErrRevisionNotFound
+		}
+		var published models.ScenarioRevision
+		if err := s.db.Select("number").First(&published, "id = ?", *scenario.PublishedRevisionID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load published revision: %w", err)
+		}
+		from = strconv.Itoa(published.Number)
+	}
+
+	fromSetup, fromSteps, err := s.loadNamedVersion(&scenario, from)
+	if err != nil {
+		return nil, err
+	}
+	toSetup, toSteps, err := s.loadNamedVersion(&scenario, to)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ScenarioRevisionDiff{
+		From:               from,
+		To:                 to,
+		SetupScriptChanged: fromSetup != toSetup,
+		Steps:              diffRevisionSteps(fromSteps, toSteps),
+	}, nil
+}
+
+// loadNamedVersion is loadVersion for a revision number or "draft".
+func (s *ScenarioRevisionService) loadNamedVersion(scenario *models.Scenario, version string) (string, []models.ScenarioStep, error) {
+	if version == draftVersion {
+		return s.loadVersion(scenario, nil)
+	}
+	number, err := strconv.Atoi(version)
+	if err != nil {
+		return "", nil, ErrRevisionNotFound
+	}
+	var revision models.ScenarioRevision
+	if err := s.db.Select("id").Where("scenario_id = ? AND number = ?", scenario.ID, number).
+		First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, ErrRevisionNotFound
+		}
+		return "", nil, err
+	}
+	return s.loadVersion(scenario, &revision.ID)
+}
+
+// loadVersion loads a version of the scenario's content — a revision, or the
+// draft when revisionID is nil — with every step's hints, questions and
+// transitions, and its setup script. The draft's project-file references are
+// resolved and inlined, which is the form a revision stores them in, so the
+// two compare and copy alike.
+func (s *ScenarioRevisionService) loadVersion(scenario *models.Scenario, revisionID *uuid.UUID) (string, []models.ScenarioStep, error) {
+	var steps []models.ScenarioStep
+	if err := s.db.Scopes(models.RevisionSteps(revisionID)).
+		Preload("Hints", func(db *gorm.DB) *gorm.DB { return db.Order("level ASC") }).
+		Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }).
+		Preload("Transitions", func(db *gorm.DB) *gorm.DB { return db.Order("priority ASC, created_at ASC") }).
+		Where("scenario_id = ?", scenario.ID).
+		Order("\"order\" ASC").
+		Find(&steps).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to load scenario steps: %w", err)
+	}
+
+	if revisionID != nil {
+		var revision models.ScenarioRevision
+		if err := s.db.Select("setup_script").First(&revision, "id = ?", *revisionID).Error; err != nil {
+			return "", nil, fmt.Errorf("failed to load scenario revision: %w", err)
+		}
+		return revision.SetupScript, steps, nil
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		step.TextContent = ResolveScriptContent(s.db, step.TextFileID, step.TextContent)
+		step.HintContent = ResolveScriptContent(s.db, step.HintFileID, step.HintContent)
+		step.VerifyScript = ResolveScriptContent(s.db, step.VerifyScriptID, step.VerifyScript)
+		step.BackgroundScript = ResolveScriptContent(s.db, step.BackgroundScriptID, step.BackgroundScript)
+		step.ForegroundScript = ResolveScriptContent(s.db, step.ForegroundScriptID, step.ForegroundScript)
+		step.TextFileID, step.HintFileID = nil, nil
+		step.VerifyScriptID, step.BackgroundScriptID, step.ForegroundScriptID = nil, nil, nil
+	}
+	return ResolveScriptContent(s.db, scenario.SetupScriptID, scenario.SetupScript), steps, nil
+}
+
+// copyStep creates a copy of step, with its hints, questions and transitions,
+// in revisionID — or in the draft when revisionID is nil. The whole row is
+// copied rather than field by field, so a column added to a step is carried
+// into revisions without anyone having to remember it.
+func copyStep(tx *gorm.DB, step models.ScenarioStep, revisionID *uuid.UUID) error {
+	hints, questions, transitions := step.Hints, step.Questions, step.Transitions
+	step.ID, step.Model = uuid.Nil, gorm.Model{}
+	step.RevisionID = revisionID
+	step.Hints, step.Questions, step.Transitions = nil, nil, nil
+	if err := tx.Create(&step).Error; err != nil {
+		return fmt.Errorf("failed to copy step: %w", err)
+	}
+	for _, hint := range hints {
+		hint.ID, hint.Model = uuid.Nil, gorm.Model{}
+		hint.StepID = step.ID
+		if err := tx.Create(&hint).Error; err != nil {
+			return fmt.Errorf("failed to copy hint: %w", err)
+		}
+	}
+	for _, question := range questions {
+		question.ID, question.Model = uuid.Nil, gorm.Model{}
+		question.StepID = step.ID
+		if err := tx.Create(&question).Error; err != nil {
+			return fmt.Errorf("failed to copy question: %w", err)
+		}
+	}
+	for _, transition := range transitions {
+		transition.ID, transition.Model = uuid.Nil, gorm.Model{}
+		transition.StepID = step.ID
+		if err := tx.Create(&transition).Error; err != nil {
+			return fmt.Errorf("failed to copy transition: %w", err)
+		}
+	}
+	return nil
+}
+
+// revisionStepFields are the parts of a step a diff reports on, each with the
+// value compared. Hints, questions and transitions are compared by content,
+// without the identity of their rows.
+var revisionStepFields = []struct {
+	name  string
+	value func(step *models.ScenarioStep) any
+}{
+	{"title", func(step *models.ScenarioStep) any { return step.Title }},
+	{"step_type", func(step *models.ScenarioStep) any { return normalizeStepType(step.StepType) }},
+	{"text", func(step *models.ScenarioStep) any { return step.TextContent }},
+	{"hint", func(step *models.ScenarioStep) any { return step.HintContent }},
+	{"verify_script", func(step *models.ScenarioStep) any { return step.VerifyScript }},
+	{"background_script", func(step *models.ScenarioStep) any { return step.BackgroundScript }},
+	{"foreground_script", func(step *models.ScenarioStep) any { return step.ForegroundScript }},
+	{"solution_script", func(step *models.ScenarioStep) any { return step.SolutionScript }},
+	{"provisioning", func(step *models.ScenarioStep) any {
+		return [2]any{step.BackgroundTimeoutSeconds, step.BackgroundAsync}
+	}},
+	{"banners", func(step *models.ScenarioStep) any {
+		return [4]string{step.IntroEffect, step.IntroText, step.OutroEffect, step.OutroText}
+	}},
+	{"flag", func(step *models.ScenarioStep) any { return [3]any{step.HasFlag, step.FlagPath, step.FlagLevel} }},
+	{"target_node", func(step *models.ScenarioStep) any { return step.TargetNode }},
+	{"show_immediate_feedback", func(step *models.ScenarioStep) any { return step.ShowImmediateFeedback }},
+	{"quiz_draw_count", func(step *models.ScenarioStep) any { return step.QuizDrawCount }},
+	{"hints", func(step *models.ScenarioStep) any {
+		return withoutRowIdentity(step.Hints, func(h *models.ScenarioStepHint) (*entityManagementModels.BaseModel, *uuid.UUID) {
+			return &h.BaseModel, &h.StepID
+		})
+	}},
+	{"questions", func(step *models.ScenarioStep) any {
+		return withoutRowIdentity(step.Questions, func(q *models.ScenarioStepQuestion) (*entityManagementModels.BaseModel, *uuid.UUID) {
+			return &q.BaseModel, &q.StepID
+		})
+	}},
+	{"transitions", func(step *models.ScenarioStep) any {
+		return withoutRowIdentity(step.Transitions, func(t *models.ScenarioStepTransition) (*entityManagementModels.BaseModel, *uuid.UUID) {
+			return &t.BaseModel, &t.StepID
+		})
+	}},
+}
+
+// withoutRowIdentity copies rows with their base model and owning step
+// cleared, leaving only what a learner would see differ.
+func withoutRowIdentity[T any](rows []T, identity func(*T) (*entityManagementModels.BaseModel, *uuid.UUID)) []T {
+	out := make([]T, len(rows))
+	for i := range rows {
+		out[i] = rows[i]
+		base, stepID := identity(&out[i])
+		*base = entityManagementModels.BaseModel{}
+		*stepID = uuid.Nil
+	}
+	return out
+}
+
+// diffRevisionSteps lists the steps that differ between two versions, matched
+// by Order, in Order.
+func diffRevisionSteps(from, to []models.ScenarioStep) []dto.ScenarioRevisionStepDiff {
+	fromByOrder := make(map[int]*models.ScenarioStep, len(from))
+	toByOrder := make(map[int]*models.ScenarioStep, len(to))
+	var orders []int
+	for i := range from {
+		fromByOrder[from[i].Order] = &from[i]
+		orders = append(orders, from[i].Order)
+	}
+	for i := range to {
+		toByOrder[to[i].Order] = &to[i]
+		if _, ok := fromByOrder[to[i].Order]; !ok {
+			orders = append(orders, to[i].Order)
+		}
+	}
+	slices.Sort(orders)
+
+	diffs := []dto.ScenarioRevisionStepDiff{}
+	for _, order := range orders {
+		before, after := fromByOrder[order], toByOrder[order]
+		switch {
+		case after == nil:
+			diffs = append(diffs, dto.ScenarioRevisionStepDiff{Order: order, Title: before.Title, Change: "removed"})
+		case before == nil:
+			diffs = append(diffs, dto.ScenarioRevisionStepDiff{Order: order, Title: after.Title, Change: "added"})
+		default:
+			var fields []string
+			for _, field := range revisionStepFields {
+				if !reflect.DeepEqual(field.value(before), field.value(after)) {
+					fields = append(fields, field.name)
+				}
+			}
+			if len(fields) > 0 {
+				diffs = append(diffs, dto.ScenarioRevisionStepDiff{Order: order, Title: after.Title, Change: "changed", Fields: fields})
+			}
+		}
+	}
+	return diffs
+}
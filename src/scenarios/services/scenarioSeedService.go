@@ -10,17 +10,25 @@ import (
 
 	"soli/formations/src/scenarios/dto"
 	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/storage"
 	"soli/formations/src/scenarios/utils"
 )
 
 // ScenarioSeedService handles creating or updating scenarios from JSON input
 type ScenarioSeedService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	store storage.FileStorage
 }
 
-// NewScenarioSeedService creates a new seed service
+// NewScenarioSeedService creates a new seed service writing file content to
+// the configured storage backend
 func NewScenarioSeedService(db *gorm.DB) *ScenarioSeedService {
-	return &ScenarioSeedService{db: db}
+	return &ScenarioSeedService{db: db, store: storage.Active()}
+}
+
+// SetFileStorage overrides the backend seeded file content is written to
+func (s *ScenarioSeedService) SetFileStorage(store storage.FileStorage) {
+	s.store = store
 }
 
 // SeedScenario creates or updates a scenario with all its steps from a SeedScenarioInput.
@@ -68,66 +76,41 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 		return nil, false, buildFeatErr
 	}
 
-	// Build new steps
-	newSteps := make([]models.ScenarioStep, len(input.Steps))
-	for i, st := range input.Steps {
-		newSteps[i] = models.ScenarioStep{
-			Order:                    i,
-			Title:                    st.Title,
-			StepType:                 ResolveStepType(st.StepType, st.HasFlag),
-			ShowImmediateFeedback:    st.ShowImmediateFeedback,
-			TextContent:              st.TextContent,
-			HintContent:              st.HintContent,
-			VerifyScript:             st.VerifyScript,
-			BackgroundScript:         st.BackgroundScript,
-			ForegroundScript:         st.ForegroundScript,
-			IntroEffect:              st.IntroEffect,
-			IntroText:                st.IntroText,
-			OutroEffect:              st.OutroEffect,
-			OutroText:                st.OutroText,
-			BackgroundTimeoutSeconds: st.BackgroundTimeoutSeconds,
-			BackgroundAsync:          st.BackgroundAsync,
-			HasFlag:                  st.HasFlag,
-			FlagPath:                 st.FlagPath,
-		}
-
-		// Build progressive hints from hint content
-		if st.HintContent != "" {
-			parts := SplitHintContent(st.HintContent)
-			hints := make([]models.ScenarioStepHint, len(parts))
-			for j, part := range parts {
-				hints[j] = models.ScenarioStepHint{
-					Level:   j + 1,
-					Content: part,
-				}
+	newSteps := seedStepsFromInput(input.Steps)
+	for i := range newSteps {
+		for j := range newSteps[i].Questions {
+			if err := ValidateQuizQuestion(&newSteps[i].Questions[j]); err != nil {
+				return nil, false, fmt.Errorf("step %d question %d: %w", i+1, j+1, err)
 			}
-			newSteps[i].Hints = hints
 		}
+	}
 
-		// Build quiz questions; GORM cascade-creates them with the step
-		if len(st.Questions) > 0 {
-			questions := make([]models.ScenarioStepQuestion, len(st.Questions))
-			for j, q := range st.Questions {
-				questions[j] = models.ScenarioStepQuestion{
-					Order:         q.Order,
-					QuestionText:  q.QuestionText,
-					QuestionType:  q.QuestionType,
-					Options:       q.Options,
-					CorrectAnswer: q.CorrectAnswer,
-					Explanation:   q.Explanation,
-					Points:        q.Points,
-				}
-			}
-			newSteps[i].Questions = questions
+	// Transitions target steps by position, so they are built once every
+	// step exists; GORM cascade-creates them with their step.
+	for i, st := range input.Steps {
+		transitions, err := transitionsFromSpecs(st.Transitions, newSteps)
+		if err != nil {
+			return nil, false, fmt.Errorf("step %d: %w", i+1, err)
 		}
+		newSteps[i].Transitions = transitions
+	}
+
+	nodes, err := BuildScenarioNodes(input.Nodes)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := ValidateStepTargets(nodes, newSteps); err != nil {
+		return nil, false, err
 	}
 
 	var scenario models.Scenario
 	if isUpdate {
 		// Update existing scenario in a transaction
+		var oldFiles []models.ProjectFile
 		err := s.db.Transaction(func(tx *gorm.DB) error {
 			// Collect old ProjectFile IDs before deleting steps
 			oldFileIDs := collectProjectFileIDs(tx, existing.ID)
+			oldFiles = loadProjectFilesByID(tx, oldFileIDs)
 
 			if err := tx.Model(&existing).Updates(map[string]any{
 				"title":              input.Title,
@@ -155,18 +138,24 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 
 			// Delete old hints before steps (soft-delete won't cascade)
 			if err := tx.Where("step_id IN (?)",
-				tx.Model(&models.ScenarioStep{}).Select("id").Where("scenario_id = ?", existing.ID),
+				tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).Select("id").Where("scenario_id = ?", existing.ID),
 			).Delete(&models.ScenarioStepHint{}).Error; err != nil {
 				return fmt.Errorf("failed to delete old hints: %w", err)
 			}
 			// Delete old quiz questions before steps (soft-delete won't cascade)
 			if err := tx.Where("step_id IN (?)",
-				tx.Model(&models.ScenarioStep{}).Select("id").Where("scenario_id = ?", existing.ID),
+				tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).Select("id").Where("scenario_id = ?", existing.ID),
 			).Delete(&models.ScenarioStepQuestion{}).Error; err != nil {
 				return fmt.Errorf("failed to delete old questions: %w", err)
 			}
+			// Delete old transitions before steps (soft-delete won't cascade)
+			if err := tx.Where("step_id IN (?)",
+				tx.Model(&models.ScenarioStep{}).Scopes(models.DraftSteps).Select("id").Where("scenario_id = ?", existing.ID),
+			).Delete(&models.ScenarioStepTransition{}).Error; err != nil {
+				return fmt.Errorf("failed to delete old transitions: %w", err)
+			}
 			// Delete old steps
-			if err := tx.Where("scenario_id = ?", existing.ID).Delete(&models.ScenarioStep{}).Error; err != nil {
+			if err := tx.Scopes(models.DraftSteps).Where("scenario_id = ?", existing.ID).Delete(&models.ScenarioStep{}).Error; err != nil {
 				return fmt.Errorf("failed to delete old steps: %w", err)
 			}
 			// Replace the image declaration rather than adding to it, so a
@@ -182,6 +171,9 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 					return fmt.Errorf("failed to create instance type: %w", err)
 				}
 			}
+			if err := replaceScenarioNodes(tx, existing.ID, nodes); err != nil {
+				return err
+			}
 			// Delete old ProjectFiles (orphaned from previous imports)
 			if len(oldFileIDs) > 0 {
 				if err := tx.Where("id IN ?", oldFileIDs).Delete(&models.ProjectFile{}).Error; err != nil {
@@ -204,7 +196,7 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 				SetupScript: input.SetupScript,
 				Steps:       newSteps,
 			}
-			if err := createProjectFilesForScenario(tx, &existing, srcScenario, nil); err != nil {
+			if err := createProjectFilesForScenario(tx, s.store, &existing, srcScenario, nil); err != nil {
 				return fmt.Errorf("failed to create project files: %w", err)
 			}
 
@@ -213,10 +205,11 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 		if err != nil {
 			return nil, false, err
 		}
+		releaseProjectFileContent(s.db, oldFiles)
 
 		// Reload with steps and hints
 		if err := s.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("\"order\" ASC")
+			return db.Scopes(models.DraftSteps).Order("\"order\" ASC")
 		}).Preload("Steps.Hints", func(db *gorm.DB) *gorm.DB {
 			return db.Order("level ASC")
 		}).First(&scenario, "id = ?", existing.ID).Error; err != nil {
@@ -248,6 +241,7 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 		}
 		scenario.Steps = newSteps
 		scenario.CompatibleInstanceTypes = compatibleInstanceTypes
+		scenario.Nodes = nodes
 
 		if err := s.db.Create(&scenario).Error; err != nil {
 			return nil, false, fmt.Errorf("failed to create scenario: %w", err)
@@ -260,10 +254,78 @@ func (s *ScenarioSeedService) SeedScenario(input dto.SeedScenarioInput, userID s
 			SetupScript: input.SetupScript,
 			Steps:       newSteps,
 		}
-		if err := createProjectFilesForScenario(s.db, &scenario, srcScenario, nil); err != nil {
+		if err := createProjectFilesForScenario(s.db, s.store, &scenario, srcScenario, nil); err != nil {
 			return nil, false, fmt.Errorf("failed to create project files: %w", err)
 		}
 	}
 
 	return &scenario, isUpdate, nil
 }
+
+// seedStepsFromInput builds the steps of a seeded scenario with their hints
+// and questions. Transitions, which target steps by position, and question
+// validation are left to the caller.
+func seedStepsFromInput(steps []dto.SeedStepInput) []models.ScenarioStep {
+	newSteps := make([]models.ScenarioStep, len(steps))
+	for i, st := range steps {
+		newSteps[i] = models.ScenarioStep{
+			Order:                    i,
+			Title:                    st.Title,
+			StepType:                 ResolveStepType(st.StepType, st.HasFlag),
+			ShowImmediateFeedback:    st.ShowImmediateFeedback,
+			TextContent:              st.TextContent,
+			HintContent:              st.HintContent,
+			VerifyScript:             st.VerifyScript,
+			BackgroundScript:         st.BackgroundScript,
+			ForegroundScript:         st.ForegroundScript,
+			SolutionScript:           st.SolutionScript,
+			IntroEffect:              st.IntroEffect,
+			IntroText:                st.IntroText,
+			OutroEffect:              st.OutroEffect,
+			OutroText:                st.OutroText,
+			BackgroundTimeoutSeconds: st.BackgroundTimeoutSeconds,
+			BackgroundAsync:          st.BackgroundAsync,
+			TargetNode:               st.TargetNode,
+			HasFlag:                  st.HasFlag,
+			FlagPath:                 st.FlagPath,
+			QuizDrawCount:            st.QuizDrawCount,
+		}
+
+		// Build progressive hints from hint content
+		if st.HintContent != "" {
+			parts := SplitHintContent(st.HintContent)
+			hints := make([]models.ScenarioStepHint, len(parts))
+			for j, part := range parts {
+				hints[j] = models.ScenarioStepHint{
+					Level:   j + 1,
+					Content: part,
+				}
+				if j < len(st.HintPenalties) {
+					hints[j].PenaltyPercent = st.HintPenalties[j]
+				}
+			}
+			newSteps[i].Hints = hints
+		}
+
+		// Build quiz questions; GORM cascade-creates them with the step
+		if len(st.Questions) > 0 {
+			questions := make([]models.ScenarioStepQuestion, len(st.Questions))
+			for j, q := range st.Questions {
+				questions[j] = models.ScenarioStepQuestion{
+					Order:         q.Order,
+					QuestionText:  q.QuestionText,
+					QuestionType:  q.QuestionType,
+					Options:       q.Options,
+					CorrectAnswer: q.CorrectAnswer,
+					Explanation:   q.Explanation,
+					Points:        q.Points,
+					Tolerance:     q.Tolerance,
+					Difficulty:    q.Difficulty,
+					Topic:         q.Topic,
+				}
+			}
+			newSteps[i].Questions = questions
+		}
+	}
+	return newSteps
+}
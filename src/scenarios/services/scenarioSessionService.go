@@ -1,19 +1,22 @@
 package services
 
 import (
-	"crypto/subtle"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"runtime/debug"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 
+	"soli/formations/src/liveprogress"
 	"soli/formations/src/observability"
 	"soli/formations/src/scenarios/dto"
 	"soli/formations/src/scenarios/models"
@@ -37,6 +40,14 @@ type VerificationServiceInterface interface {
 	ExecInContainer(sessionID string, command []string, env map[string]string, timeout int) (exitCode int, stdout string, stderr string, err error)
 }
 
+// ExitCodeVerifier is implemented by verification services that can report
+// the verify script's exit code rather than just pass/fail. It is optional:
+// verify_exit_code transitions need it, and without it a failed verification
+// simply never routes.
+type ExitCodeVerifier interface {
+	VerifyStepExitCode(terminalSessionID string, step *models.ScenarioStep) (exitCode int, output string, err error)
+}
+
 // defaultAllowedFlagPaths is the fallback list of allowed path prefixes for flag deployment
 // when a scenario does not define its own AllowedFlagPaths.
 var defaultAllowedFlagPaths = []string{"/tmp/", "/home/", "/var/", "/opt/", "/World/"}
@@ -146,6 +157,14 @@ type TerminalStopFunc func(terminalSessionID string) error
 // controller layer, same reason as TerminalStopFunc: no import cycle).
 type TerminalBuildCompleteFunc func(terminalSessionID string) error
 
+// TerminalSnapshotFunc snapshots a terminal under name, overwriting a snapshot
+// of the same name, and records the scenario step it captured (injected from
+// the controller layer, same reason as TerminalStopFunc).
+type TerminalSnapshotFunc func(terminalSessionID, name string, stepOrder int) error
+
+// TerminalRestoreFunc rolls a terminal back to the snapshot called name.
+type TerminalRestoreFunc func(terminalSessionID, name string) error
+
 // ScenarioSessionService manages the lifecycle of a student's scenario session
 type ScenarioSessionService struct {
 	db                  *gorm.DB
@@ -153,6 +172,8 @@ type ScenarioSessionService struct {
 	verificationService VerificationServiceInterface
 	stopTerminal        TerminalStopFunc
 	buildComplete       TerminalBuildCompleteFunc
+	snapshotTerminal    TerminalSnapshotFunc
+	restoreTerminal     TerminalRestoreFunc
 }
 
 // NewScenarioSessionService creates a new session service with its dependencies
@@ -175,6 +196,14 @@ func (s *ScenarioSessionService) SetTerminalBuildCompleteFunc(fn TerminalBuildCo
 	s.buildComplete = fn
 }
 
+// SetTerminalSnapshotFuncs sets the callbacks that snapshot a learner's
+// terminal after each step's setup and restore it when the step is
+// reprovisioned, for scenarios with SnapshotSteps.
+func (s *ScenarioSessionService) SetTerminalSnapshotFuncs(snapshot TerminalSnapshotFunc, restore TerminalRestoreFunc) {
+	s.snapshotTerminal = snapshot
+	s.restoreTerminal = restore
+}
+
 // finishBuild closes the provisioning window, taking back any feature the
 // container was given only to be built.
 //
@@ -191,33 +220,51 @@ func (s *ScenarioSessionService) finishBuild(sessionID uuid.UUID, terminalSessio
 	if s.buildComplete == nil || terminalSessionID == "" {
 		return
 	}
-	if err := s.buildComplete(terminalSessionID); err != nil {
-		slog.Error("scenario build window left open — session still holds its build-time features",
-			"session_id", sessionID, "terminal_session_id", terminalSessionID, "err", err)
+	for _, id := range s.topologyTerminals(terminalSessionID) {
+		if err := s.buildComplete(id); err != nil {
+			slog.Error("scenario build window left open — session still holds its build-time features",
+				"session_id", sessionID, "terminal_session_id", id, "err", err)
+		}
 	}
 }
 
-// tryStopTerminal stops the linked terminal session (best-effort, logs on failure)
+// tryStopTerminal stops the linked terminal session, and the other nodes of a
+// multi-node scenario with it (best-effort, logs on failure)
 func (s *ScenarioSessionService) tryStopTerminal(terminalSessionID string, sessionID uuid.UUID) {
 	if s.stopTerminal == nil || terminalSessionID == "" {
 		return
 	}
-	if err := s.stopTerminal(terminalSessionID); err != nil {
-		observability.Metrics.TerminalStopOnCleanupFailure.Add(1)
-		slog.Error("failed to stop terminal — container may be orphaned", "terminal_session_id", terminalSessionID, "session_id", sessionID, "err", err)
+	for _, id := range s.topologyTerminals(terminalSessionID) {
+		if err := s.stopTerminal(id); err != nil {
+			observability.Metrics.TerminalStopOnCleanupFailure.Add(1)
+			slog.Error("failed to stop terminal — container may be orphaned", "terminal_session_id", id, "session_id", sessionID, "err", err)
+		}
 	}
 }
 
 // StartScenario creates a new scenario session for a student.
 // It creates the session, step progress records, generates flags, and returns session info.
+// The session runs the scenario's published revision, or its draft if it has
+// never been published.
 func (s *ScenarioSessionService) StartScenario(userID string, scenarioID uuid.UUID, terminalSessionID string) (*models.ScenarioSession, error) {
-	// Load scenario with steps
+	return s.startScenario(userID, scenarioID, terminalSessionID, false, nil)
+}
+
+// startScenario is StartScenario, running the draft instead of the published
+// revision when runDraft is set. nodes is the topology of a multi-node
+// scenario, terminalSessionID being its first node's terminal.
+func (s *ScenarioSessionService) startScenario(userID string, scenarioID uuid.UUID, terminalSessionID string, runDraft bool, nodes []TopologyNode) (*models.ScenarioSession, error) {
 	var scenario models.Scenario
-	if err := s.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).First(&scenario, "id = ?", scenarioID).Error; err != nil {
+	if err := s.db.First(&scenario, "id = ?", scenarioID).Error; err != nil {
 		return nil, fmt.Errorf("scenario not found: %w", err)
 	}
+	revisionID := scenario.PublishedRevisionID
+	if runDraft {
+		revisionID = nil
+	}
+	if err := loadScenarioContent(s.db, &scenario, revisionID); err != nil {
+		return nil, err
+	}
 
 	if len(scenario.Steps) == 0 {
 		return nil, fmt.Errorf("scenario has no steps")
@@ -237,6 +284,7 @@ func (s *ScenarioSessionService) StartScenario(userID string, scenarioID uuid.UU
 		CurrentStep:       firstStepOrder,
 		Status:            "active",
 		StartedAt:         now,
+		RevisionID:        revisionID,
 	}
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
@@ -268,15 +316,47 @@ func (s *ScenarioSessionService) StartScenario(userID string, scenarioID uuid.UU
 				"terminal_session_id", existingSession.TerminalSessionID,
 				"user_id", userID,
 			)
+			if existingSession.ExamMode {
+				// Abandoning it would free the attempt. The expiry job grades
+				// it when its time runs out.
+				return ErrExamAlreadyTaken
+			}
 			if err := tx.Model(existingSession).Update("status", "abandoned").Error; err != nil {
 				return fmt.Errorf("failed to abandon zombie session: %w", err)
 			}
 		}
 
+		exam, examErr := findExamAssignment(tx, userID, scenarioID)
+		if examErr != nil {
+			return examErr
+		}
+		if exam != nil {
+			if err := checkExamStart(tx, exam, userID, now); err != nil {
+				return err
+			}
+			expiresAt := examExpiry(exam, now)
+			session.AssignmentID = &exam.ID
+			session.ExamMode = true
+			session.ExpiresAt = &expiresAt
+		} else if !runDraft {
+			// A public scenario stays open to everyone past the deadline of
+			// an assignment of it; the session is then not the assignment's.
+			assignment, assignmentErr := findOpenAssignment(tx, userID, scenarioID, now)
+			if assignmentErr != nil && !(scenario.IsPublic && isAssignmentWindowError(assignmentErr)) {
+				return assignmentErr
+			}
+			if assignment != nil {
+				session.AssignmentID = &assignment.ID
+			}
+		}
+
 		// Create session
 		if err := tx.Create(session).Error; err != nil {
 			return fmt.Errorf("failed to create session: %w", err)
 		}
+		if err := createSessionNodes(tx, session.ID, nodes); err != nil {
+			return err
+		}
 
 		// Create step progress for each step
 		for i, step := range scenario.Steps {
@@ -370,6 +450,7 @@ func (s *ScenarioSessionService) StartScenario(userID string, scenarioID uuid.UU
 		}
 	}
 
+	s.notifyLiveProgress(session.ID, liveprogress.KindSessionStarted)
 	return session, nil
 }
 
@@ -379,6 +460,7 @@ type PreviewOption func(*previewConfig)
 type previewConfig struct {
 	isOrgManager func(userID string, orgID uuid.UUID) bool
 	isAdmin      bool
+	nodes        []TopologyNode
 }
 
 // WithOrgManagerCheck injects a callback to check if a user is an org manager.
@@ -419,8 +501,9 @@ func (s *ScenarioSessionService) PreviewScenario(userID string, scenarioID uuid.
 		return nil, fmt.Errorf("not authorized to preview this scenario")
 	}
 
-	// Delegate to StartScenario for session creation
-	session, err := s.StartScenario(userID, scenarioID, terminalSessionID)
+	// Delegate to StartScenario for session creation. A preview is how an
+	// author tries their edits, so it runs the draft.
+	session, err := s.startScenario(userID, scenarioID, terminalSessionID, true, cfg.nodes)
 	if err != nil {
 		return nil, err
 	}
@@ -438,6 +521,16 @@ func (s *ScenarioSessionService) PreviewScenario(userID string, scenarioID uuid.
 // the session from "provisioning" to "active" once setup completes, or to
 // "setup_failed" if the script fails.
 func (s *ScenarioSessionService) runStep0Setup(sessionID uuid.UUID, terminalSessionID string, scenario *models.Scenario, flags []models.ScenarioFlag) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindStepProgress)
+	// Runs detached from the launch request, so it is a trace of its own;
+	// the session attributes tie it back to the launch.
+	_, span := observability.StartSpan(context.Background(), "scenario.step0_setup",
+		attribute.String("scenario.session_id", sessionID.String()),
+		attribute.String("scenario.id", scenario.ID.String()),
+		attribute.String("terminal.session_id", terminalSessionID),
+	)
+	defer span.End()
+
 	// Deferred rather than placed at the end: this function returns from a
 	// dozen points — setup failures, an abandoned session, a recovered panic —
 	// and every one of them leaves a container still holding the network its
@@ -521,6 +614,8 @@ func (s *ScenarioSessionService) runStep0Setup(sessionID uuid.UUID, terminalSess
 	// is staged as the MOTD and rendered when the learner's shell starts.
 	s.deliverStepZeroIntro(terminalSessionID, step, sessionID)
 
+	s.snapshotStep(terminalSessionID, scenario, step.Order)
+
 	// Say so once, loudly, if this scenario wants banners on an image that
 	// cannot draw them. The symptom is otherwise just "nothing happens".
 	s.warnIfEffectsUnsupported(terminalSessionID, scenario, sessionID)
@@ -734,10 +829,61 @@ func (s *ScenarioSessionService) runStepProvisioning(terminalSessionID string, s
 		// a failed script, so it counts as a provisioning failure too.
 		return flagErr
 	}
+	// Before the foreground script: it acts on the learner's shell, not the
+	// disk, and restoring the step does not replay it.
+	s.snapshotStep(terminalSessionID, scenario, step.Order)
 	s.runForegroundScript(terminalSessionID, step)
 	return nil
 }
 
+// stepSnapshotName is the snapshot a scenario takes of a step once its setup
+// has run. A terminal serves a single session, so the step order is enough to
+// keep the names apart.
+func stepSnapshotName(stepOrder int) string {
+	return fmt.Sprintf("scenario-step-%d", stepOrder)
+}
+
+// snapshotStep checkpoints the learner's terminal right after a step's setup,
+// when the scenario asks for it, so ReprovisionCurrentStep can bring the step
+// back by restoring rather than re-running its scripts. A step whose setup is
+// run again overwrites its snapshot with the fresh state.
+//
+// Best-effort: a step without a snapshot only loses the shortcut, since
+// reprovisioning falls back to the scripts. Failing the step over it would
+// cost the learner a working level — most often because their plan's storage
+// is full.
+func (s *ScenarioSessionService) snapshotStep(terminalSessionID string, scenario *models.Scenario, stepOrder int) {
+	if scenario == nil || !scenario.SnapshotSteps || s.snapshotTerminal == nil {
+		return
+	}
+	// Every node of a topology is checkpointed: a step's setup may have
+	// changed any of them.
+	for _, id := range s.topologyTerminals(terminalSessionID) {
+		if err := s.snapshotTerminal(id, stepSnapshotName(stepOrder), stepOrder); err != nil {
+			slog.Warn("step snapshot failed; reprovisioning this step will re-run its scripts",
+				"terminal_session_id", id, "step_order", stepOrder, "err", err)
+		}
+	}
+}
+
+// restoreStepSnapshot rolls the learner's terminal back to the snapshot taken
+// after the step's setup, reporting whether it did. Any failure — the scenario
+// does not snapshot, the snapshot was never taken, tt-backend refused — is
+// reported as false for the caller to fall back to the scripts.
+func (s *ScenarioSessionService) restoreStepSnapshot(session *models.ScenarioSession, stepOrder int) bool {
+	if !session.Scenario.SnapshotSteps || s.restoreTerminal == nil || session.TerminalSessionID == nil {
+		return false
+	}
+	for _, id := range s.topologyTerminals(*session.TerminalSessionID) {
+		if err := s.restoreTerminal(id, stepSnapshotName(stepOrder)); err != nil {
+			slog.Warn("step snapshot restore failed; re-running the step's scripts",
+				"session_id", session.ID, "terminal_session_id", id, "step_order", stepOrder, "err", err)
+			return false
+		}
+	}
+	return true
+}
+
 // runForegroundScript types a step's foreground script into the learner's own
 // shell, which is what distinguishes it from the background script: it runs
 // where the learner is looking, in their session, so they see it happen and its
@@ -762,8 +908,13 @@ func (s *ScenarioSessionService) runForegroundScript(terminalSessionID string, s
 	if script == "" {
 		return
 	}
+	terminalSessionID, err := s.stepTerminal(terminalSessionID, step)
+	if err != nil {
+		slog.Warn("skipping foreground script", "step_order", step.Order, "err", err)
+		return
+	}
 
-	err := s.verificationService.WriteToConsole(terminalSessionID, script)
+	err = s.verificationService.WriteToConsole(terminalSessionID, script)
 	switch {
 	case err == nil:
 		slog.Info("foreground script sent to console", "step_order", step.Order)
@@ -811,6 +962,7 @@ func (s *ScenarioSessionService) startAsyncStepProvisioning(session *models.Scen
 // leaves the terminal running. The learner already has a working shell, and
 // killing it would cost them the whole run for one bad level.
 func (s *ScenarioSessionService) runAsyncStepProvisioning(sessionID uuid.UUID, terminalSessionID string, scenario *models.Scenario, flags []models.ScenarioFlag, step *models.ScenarioStep) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindStepProgress)
 	defer func() {
 		if r := recover(); r != nil {
 			observability.Metrics.ScenarioStepProvisioningPanic.Add(1)
@@ -881,7 +1033,8 @@ func (s *ScenarioSessionService) failStepProvisioning(sessionID uuid.UUID) {
 // ReprovisionCurrentStep re-runs the current step's background script against
 // the learner's existing container. It is the recovery path for a failed
 // advance: the advance itself is never rolled back, so the only way back into a
-// playable state is to retry the setup.
+// playable state is to retry the setup. A scenario with SnapshotSteps restores
+// the step's snapshot instead when there is one.
 //
 // force exports FORCE=1 into the script so it redoes work its idempotency
 // markers would otherwise skip.
@@ -896,6 +1049,14 @@ func (s *ScenarioSessionService) ReprovisionCurrentStep(sessionID uuid.UUID, for
 		return nil, fmt.Errorf("current step (order=%d) not found", session.CurrentStep)
 	}
 
+	// A scenario that snapshots its steps gets the step back as its setup left
+	// it, without running anything. force asks for the scripts themselves, so
+	// it skips the snapshot.
+	if !force && s.restoreStepSnapshot(session, step.Order) {
+		s.setSessionRunState(session.ID, statusActive)
+		return &dto.ReprovisionStepResponse{StepOrder: step.Order, Status: statusActive}, nil
+	}
+
 	runnable, err := s.resolveRunnableStep(step, force)
 	if err != nil {
 		return nil, err
@@ -927,6 +1088,7 @@ func (s *ScenarioSessionService) ReprovisionCurrentStep(sessionID uuid.UUID, for
 // produce. The guard restricts it to reprovisionableStatuses, so an abandon
 // racing the retry still wins.
 func (s *ScenarioSessionService) setSessionRunState(sessionID uuid.UUID, status string) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindStepProgress)
 	result := s.db.Model(&models.ScenarioSession{}).
 		Where("id = ? AND status IN ?", sessionID, reprovisionableStatuses).
 		Updates(map[string]any{
@@ -944,11 +1106,12 @@ func (s *ScenarioSessionService) setSessionRunState(sessionID uuid.UUID, status
 // a goroutine on the job; a completed or abandoned one has no run to repair.
 func (s *ScenarioSessionService) loadReprovisionableSession(sessionID uuid.UUID) (*models.ScenarioSession, error) {
 	var session models.ScenarioSession
-	if err := s.db.Preload("Scenario.Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := s.db.Preload("Scenario").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
+	if err := loadSessionContent(s.db, &session); err != nil {
+		return nil, err
+	}
 	if !slices.Contains(reprovisionableStatuses, session.Status) {
 		return nil, fmt.Errorf("session status %q cannot be reprovisioned", session.Status)
 	}
@@ -986,7 +1149,8 @@ func (s *ScenarioSessionService) CurrentStepProvisioningTimeout(session *models.
 		return 0
 	}
 	var step models.ScenarioStep
-	if err := s.db.Where("scenario_id = ? AND \"order\" = ?", session.ScenarioID, session.CurrentStep).
+	if err := s.db.Scopes(models.RevisionSteps(session.RevisionID)).
+		Where("scenario_id = ? AND \"order\" = ?", session.ScenarioID, session.CurrentStep).
 		First(&step).Error; err != nil {
 		return 0
 	}
@@ -1000,11 +1164,12 @@ func (s *ScenarioSessionService) CurrentStepProvisioningTimeout(session *models.
 // report the session's real step order rather than a hardcoded 0.
 func (s *ScenarioSessionService) GetCurrentStep(sessionID uuid.UUID) (*dto.CurrentStepResponse, error) {
 	var session models.ScenarioSession
-	if err := s.db.Preload("Scenario.Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).Preload("StepProgress").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := s.db.Preload("Scenario").Preload("StepProgress").First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
+	if err := loadSessionContent(s.db, &session); err != nil {
+		return nil, err
+	}
 
 	if session.Status == "provisioning" {
 		return &dto.CurrentStepResponse{
@@ -1049,12 +1214,12 @@ func (s *ScenarioSessionService) GetCurrentStep(sessionID uuid.UUID) (*dto.Curre
 	textContent := ResolveScriptContent(s.db, currentStep.TextFileID, currentStep.TextContent)
 	hintContent := ResolveScriptContent(s.db, currentStep.HintFileID, currentStep.HintContent)
 
-	position, stepOrders := stepPositionInfo(session.Scenario.Steps, currentStep.Order)
+	position, stepOrders := stepPositionInfo(session.Scenario.Steps, session.StepProgress, currentStep.Order)
 	response := &dto.CurrentStepResponse{
 		StepOrder:             currentStep.Order,
 		Position:              position,
 		StepOrders:            stepOrders,
-		TotalSteps:            len(session.Scenario.Steps),
+		TotalSteps:            len(stepOrders),
 		Title:                 currentStep.Title,
 		Text:                  textContent,
 		Hint:                  hintContent,
@@ -1067,7 +1232,7 @@ func (s *ScenarioSessionService) GetCurrentStep(sessionID uuid.UUID) (*dto.Curre
 
 	// Quiz steps: populate the sanitized question list (no correct_answer/explanation)
 	if response.StepType == "quiz" {
-		response.Questions = loadSanitizedQuestions(s.db, currentStep.ID)
+		response.Questions = loadSanitizedQuestions(s.db, session.ID, currentStep)
 	}
 
 	// Add progressive hint metadata
@@ -1075,6 +1240,7 @@ func (s *ScenarioSessionService) GetCurrentStep(sessionID uuid.UUID) (*dto.Curre
 	s.db.Model(&models.ScenarioStepHint{}).Where("step_id = ?", currentStep.ID).Count(&totalHints)
 	if totalHints > 0 {
 		response.HintsTotalCount = int(totalHints)
+		response.HintPenalties = s.hintPenalties(currentStep.ID)
 		// Find hints_revealed from step progress
 		for _, sp := range session.StepProgress {
 			if sp.StepOrder == session.CurrentStep {
@@ -1086,6 +1252,8 @@ func (s *ScenarioSessionService) GetCurrentStep(sessionID uuid.UUID) (*dto.Curre
 		response.Hint = ""
 	}
 
+	withholdExamHints(&session, response)
+
 	return response, nil
 }
 
@@ -1107,14 +1275,24 @@ func StepPosition(steps []models.ScenarioStep, order int) int {
 	return 0
 }
 
-// stepPositionInfo returns the step's display position plus the full ordered
-// list of orders, which clients use to map any order to its position locally.
-func stepPositionInfo(steps []models.ScenarioStep, order int) (int, []int) {
-	orders := make([]int, len(steps))
-	for i := range steps {
-		orders[i] = steps[i].Order
-	}
-	return StepPosition(steps, order), orders
+// SessionStepPosition is StepPosition counted along a session's path, so
+// labels agree with the step view once routing has skipped a branch.
+func SessionStepPosition(steps []models.ScenarioStep, progress []models.ScenarioStepProgress, order int) int {
+	return StepPosition(stepsOnPath(steps, progress), order)
+}
+
+// stepPositionInfo returns the step's display position plus the ordered list
+// of orders, which clients use to map any order to its position locally. Both
+// are counted along the session's path: steps its routing skipped are left
+// out, so a branch not taken does not show as a gap. A step that is itself
+// skipped (viewed after the fact) has position 0.
+func stepPositionInfo(steps []models.ScenarioStep, progress []models.ScenarioStepProgress, order int) (int, []int) {
+	onPath := stepsOnPath(steps, progress)
+	orders := make([]int, len(onPath))
+	for i := range onPath {
+		orders[i] = onPath[i].Order
+	}
+	return StepPosition(onPath, order), orders
 }
 
 // normalizeStepType returns the canonical step_type string. Empty values from
@@ -1126,15 +1304,17 @@ func normalizeStepType(stepType string) string {
 	return stepType
 }
 
-// loadSanitizedQuestions fetches the quiz questions for a step and returns them
-// without the CorrectAnswer/Explanation fields (those are only revealed in
-// per-question results after submission).
-func loadSanitizedQuestions(db *gorm.DB, stepID uuid.UUID) []dto.CurrentStepQuestion {
+// loadSanitizedQuestions fetches the quiz questions a session is asked on a
+// step — its draw from the step's pool, options in the session's order — and
+// returns them without the CorrectAnswer/Explanation fields (those are only
+// revealed in per-question results after submission).
+func loadSanitizedQuestions(db *gorm.DB, sessionID uuid.UUID, step *models.ScenarioStep) []dto.CurrentStepQuestion {
 	var questions []models.ScenarioStepQuestion
-	if err := db.Where("step_id = ?", stepID).Order("\"order\" ASC").Find(&questions).Error; err != nil {
-		slog.Warn("failed to load quiz questions", "step_id", stepID, "err", err)
+	if err := db.Where("step_id = ?", step.ID).Order("\"order\" ASC").Find(&questions).Error; err != nil {
+		slog.Warn("failed to load quiz questions", "step_id", step.ID, "err", err)
 		return nil
 	}
+	questions = DrawQuizQuestions(questions, step.QuizDrawCount, sessionID, step.ID)
 	out := make([]dto.CurrentStepQuestion, 0, len(questions))
 	for _, q := range questions {
 		out = append(out, dto.CurrentStepQuestion{
@@ -1142,7 +1322,7 @@ func loadSanitizedQuestions(db *gorm.DB, stepID uuid.UUID) []dto.CurrentStepQues
 			Order:        q.Order,
 			QuestionText: q.QuestionText,
 			QuestionType: q.QuestionType,
-			Options:      q.Options,
+			Options:      ShuffledQuestionOptions(q, sessionID),
 		})
 	}
 	return out
@@ -1152,11 +1332,12 @@ func loadSanitizedQuestions(db *gorm.DB, stepID uuid.UUID) []dto.CurrentStepQues
 // Only completed or active steps can be viewed — locked steps are forbidden.
 func (s *ScenarioSessionService) GetStepByOrder(sessionID uuid.UUID, stepOrder int) (*dto.CurrentStepResponse, error) {
 	var session models.ScenarioSession
-	if err := s.db.Preload("Scenario.Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).Preload("StepProgress").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := s.db.Preload("Scenario").Preload("StepProgress").First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
+	if err := loadSessionContent(s.db, &session); err != nil {
+		return nil, err
+	}
 
 	// Find the step at the given order
 	var targetStep *models.ScenarioStep
@@ -1188,12 +1369,12 @@ func (s *ScenarioSessionService) GetStepByOrder(sessionID uuid.UUID, stepOrder i
 	textContent := ResolveScriptContent(s.db, targetStep.TextFileID, targetStep.TextContent)
 	hintContent := ResolveScriptContent(s.db, targetStep.HintFileID, targetStep.HintContent)
 
-	position, stepOrders := stepPositionInfo(session.Scenario.Steps, targetStep.Order)
+	position, stepOrders := stepPositionInfo(session.Scenario.Steps, session.StepProgress, targetStep.Order)
 	response := &dto.CurrentStepResponse{
 		StepOrder:             targetStep.Order,
 		Position:              position,
 		StepOrders:            stepOrders,
-		TotalSteps:            len(session.Scenario.Steps),
+		TotalSteps:            len(stepOrders),
 		Title:                 targetStep.Title,
 		Text:                  textContent,
 		Hint:                  hintContent,
@@ -1205,7 +1386,7 @@ func (s *ScenarioSessionService) GetStepByOrder(sessionID uuid.UUID, stepOrder i
 	}
 
 	if response.StepType == "quiz" {
-		response.Questions = loadSanitizedQuestions(s.db, targetStep.ID)
+		response.Questions = loadSanitizedQuestions(s.db, session.ID, targetStep)
 	}
 
 	// Add progressive hint metadata
@@ -1213,6 +1394,7 @@ func (s *ScenarioSessionService) GetStepByOrder(sessionID uuid.UUID, stepOrder i
 	s.db.Model(&models.ScenarioStepHint{}).Where("step_id = ?", targetStep.ID).Count(&totalHints)
 	if totalHints > 0 {
 		response.HintsTotalCount = int(totalHints)
+		response.HintPenalties = s.hintPenalties(targetStep.ID)
 		for _, sp := range session.StepProgress {
 			if sp.StepOrder == stepOrder {
 				response.HintsRevealed = sp.HintsRevealed
@@ -1222,21 +1404,28 @@ func (s *ScenarioSessionService) GetStepByOrder(sessionID uuid.UUID, stepOrder i
 		response.Hint = ""
 	}
 
+	withholdExamHints(&session, response)
+
 	return response, nil
 }
 
 // VerifyCurrentStep runs the verify script for the current step.
 func (s *ScenarioSessionService) VerifyCurrentStep(sessionID uuid.UUID) (*dto.VerifyStepResponse, error) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindVerifyAttempt)
 	var session models.ScenarioSession
-	if err := s.db.Preload("Scenario.Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).Preload("StepProgress").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := s.db.Preload("Scenario").Preload("StepProgress").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
+	if err := loadSessionContent(s.db, &session); err != nil {
+		return nil, err
+	}
 
 	if err := requireActiveSession(&session); err != nil {
 		return nil, err
 	}
+	if err := s.enforceExamTimeLimit(&session); err != nil {
+		return nil, err
+	}
 
 	if session.TerminalSessionID == nil {
 		return nil, fmt.Errorf("no terminal session attached")
@@ -1280,11 +1469,22 @@ func (s *ScenarioSessionService) VerifyCurrentStep(sessionID uuid.UUID) (*dto.Ve
 	// Steps without a verify script auto-pass when the user clicks verify
 	var passed bool
 	var output string
+	var exitCode *int
+	verifyTerminal, err := s.stepTerminal(*session.TerminalSessionID, currentStep)
+	if err != nil {
+		return nil, fmt.Errorf("verification failed: %w", err)
+	}
 	if currentStep.VerifyScript == "" {
 		passed = true
+	} else if verifier, ok := s.verificationService.(ExitCodeVerifier); ok {
+		code, out, err := verifier.VerifyStepExitCode(verifyTerminal, currentStep)
+		if err != nil {
+			return nil, fmt.Errorf("verification failed: %w", err)
+		}
+		passed, output, exitCode = code == 0, out, &code
 	} else {
 		var err error
-		passed, output, err = s.verificationService.VerifyStep(*session.TerminalSessionID, currentStep)
+		passed, output, err = s.verificationService.VerifyStep(verifyTerminal, currentStep)
 		if err != nil {
 			return nil, fmt.Errorf("verification failed: %w", err)
 		}
@@ -1295,6 +1495,11 @@ func (s *ScenarioSessionService) VerifyCurrentStep(sessionID uuid.UUID) (*dto.Ve
 		Output: output,
 	}
 
+	// A failed verification normally leaves the learner on the step; an
+	// exit-code transition can instead route them to a remediation step,
+	// leaving this one failed.
+	route := routeForStep(s.db, currentStep, stepOutcome{exitCode: exitCode, failed: !passed})
+
 	// Wrap all DB updates in a transaction for consistency
 	// Captured before the transaction: advanceToNextStep updates current_step
 	// through GORM, which writes the new value back onto this struct, so after
@@ -1308,9 +1513,9 @@ func (s *ScenarioSessionService) VerifyCurrentStep(sessionID uuid.UUID) (*dto.Ve
 			return fmt.Errorf("failed to update verify attempts: %w", err)
 		}
 
-		if passed {
+		if passed || route != nil {
 			now := time.Now()
-			nextStep, err := s.advanceToNextStep(tx, &session, now)
+			nextStep, err := s.advanceToNextStep(tx, &session, now, route, !passed)
 			if err != nil {
 				return err
 			}
@@ -1323,7 +1528,11 @@ func (s *ScenarioSessionService) VerifyCurrentStep(sessionID uuid.UUID) (*dto.Ve
 		return nil, txErr
 	}
 
-	if response.Passed && response.NextStep != nil {
+	if response.NextStep == nil && session.Status == "completed" {
+		s.publishSessionCompleted(session.ID)
+	}
+
+	if response.NextStep != nil {
 		response.StepProvisioningStatus = s.runStepTransition(&session, leftStep, *response.NextStep)
 	}
 
@@ -1336,13 +1545,14 @@ func (s *ScenarioSessionService) VerifyCurrentStep(sessionID uuid.UUID) (*dto.Ve
 func (s *ScenarioSessionService) completeInfoStep(session *models.ScenarioSession) (*dto.VerifyStepResponse, error) {
 	now := time.Now()
 	response := &dto.VerifyStepResponse{Passed: true}
+	route := routeForStep(s.db, findStepByOrder(session.Scenario.Steps, session.CurrentStep), stepOutcome{})
 
 	// Captured before the transaction: advanceToNextStep updates current_step
 	// through GORM, which writes the new value back onto this struct, so after
 	// the transaction session.CurrentStep is already the step just entered.
 	leftStep := session.CurrentStep
 	txErr := s.db.Transaction(func(tx *gorm.DB) error {
-		nextStep, err := s.advanceToNextStep(tx, session, now)
+		nextStep, err := s.advanceToNextStep(tx, session, now, route, false)
 		if err != nil {
 			return err
 		}
@@ -1353,6 +1563,10 @@ func (s *ScenarioSessionService) completeInfoStep(session *models.ScenarioSessio
 		return nil, txErr
 	}
 
+	if response.NextStep == nil && session.Status == "completed" {
+		s.publishSessionCompleted(session.ID)
+	}
+
 	if response.NextStep != nil {
 		response.StepProvisioningStatus = s.runStepTransition(session, leftStep, *response.NextStep)
 	}
@@ -1371,21 +1585,26 @@ func (s *ScenarioSessionService) completeInfoStep(session *models.ScenarioSessio
 // lose answered questions); a separate POST .../quiz-answer endpoint will
 // cover that without changing this final-submission contract.
 func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.SubmitQuizInput) (*dto.SubmitQuizResponse, error) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindQuizSubmitted)
 	// Reject empty/nil submissions early (controller would map this to 400).
 	if len(input.Answers) == 0 {
 		return nil, fmt.Errorf("answers are required")
 	}
 
 	var session models.ScenarioSession
-	if err := s.db.Preload("Scenario.Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).Preload("StepProgress").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := s.db.Preload("Scenario").Preload("StepProgress").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
+	if err := loadSessionContent(s.db, &session); err != nil {
+		return nil, err
+	}
 
 	if err := requireActiveSession(&session); err != nil {
 		return nil, err
 	}
+	if err := s.enforceExamTimeLimit(&session); err != nil {
+		return nil, err
+	}
 
 	// Find the current step
 	currentStep := findStepByOrder(session.Scenario.Steps, session.CurrentStep)
@@ -1397,8 +1616,9 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 		return nil, fmt.Errorf("current step is not a quiz step")
 	}
 
-	// Load every question for this step so we can validate IDs and score
-	// answers against the canonical correct_answer.
+	// Load the questions this session was asked — its draw from the step's
+	// pool — so we can validate IDs and score answers against the canonical
+	// correct_answer.
 	var questions []models.ScenarioStepQuestion
 	if err := s.db.Where("step_id = ?", currentStep.ID).Order("\"order\" ASC").Find(&questions).Error; err != nil {
 		return nil, fmt.Errorf("failed to load questions: %w", err)
@@ -1406,9 +1626,11 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 	if len(questions) == 0 {
 		return nil, fmt.Errorf("quiz step has no questions")
 	}
+	questions = DrawQuizQuestions(questions, currentStep.QuizDrawCount, session.ID, currentStep.ID)
 
 	// Build a lookup so we can reject answers with unknown question IDs and
-	// score with O(1) per submitted answer.
+	// score with O(1) per submitted answer. A question of the pool this
+	// session was not asked is unknown too.
 	byID := make(map[uuid.UUID]models.ScenarioStepQuestion, len(questions))
 	for _, q := range questions {
 		byID[q.ID] = q
@@ -1434,7 +1656,7 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 	}
 	for _, q := range questions {
 		submitted := input.Answers[q.ID]
-		correct := subtle.ConstantTimeCompare([]byte(submitted), []byte(q.CorrectAnswer)) == 1
+		correct := GradeQuizAnswer(q, submitted)
 		if correct {
 			correctCount++
 		}
@@ -1467,6 +1689,7 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 
 	now := time.Now()
 	scoreCopy := score
+	route := routeForStep(s.db, currentStep, stepOutcome{quizScore: &scoreCopy})
 	// Captured before the transaction: advanceToNextStep updates current_step
 	// through GORM, which writes the new value back onto this struct, so after
 	// the transaction session.CurrentStep is already the step just entered.
@@ -1496,7 +1719,7 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 			}
 		}
 
-		nextStep, err := s.advanceToNextStep(tx, &session, now)
+		nextStep, err := s.advanceToNextStep(tx, &session, now, route, false)
 		if err != nil {
 			return err
 		}
@@ -1507,6 +1730,10 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 		return nil, txErr
 	}
 
+	if response.NextStep == nil && session.Status == "completed" {
+		s.publishSessionCompleted(session.ID)
+	}
+
 	if response.NextStep != nil {
 		response.StepProvisioningStatus = s.runStepTransition(&session, leftStep, *response.NextStep)
 	}
@@ -1516,16 +1743,21 @@ func (s *ScenarioSessionService) SubmitQuiz(sessionID uuid.UUID, input dto.Submi
 
 // SubmitFlag validates a flag submission for the current step.
 func (s *ScenarioSessionService) SubmitFlag(sessionID uuid.UUID, submittedFlag string) (*dto.SubmitFlagResponse, error) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindFlagSubmission)
 	var session models.ScenarioSession
-	if err := s.db.Preload("Scenario.Steps", func(db *gorm.DB) *gorm.DB {
-		return db.Order("\"order\" ASC")
-	}).Preload("StepProgress").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := s.db.Preload("Scenario").Preload("StepProgress").Preload("Flags").First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
+	if err := loadSessionContent(s.db, &session); err != nil {
+		return nil, err
+	}
 
 	if err := requireActiveSession(&session); err != nil {
 		return nil, err
 	}
+	if err := s.enforceExamTimeLimit(&session); err != nil {
+		return nil, err
+	}
 
 	flag := findFlagByStepOrder(session.Flags, session.CurrentStep)
 	if flag == nil {
@@ -1541,8 +1773,14 @@ func (s *ScenarioSessionService) SubmitFlag(sessionID uuid.UUID, submittedFlag s
 		}, nil
 	}
 
-	// Validate the flag
+	// Validate the flag. A flag transition's value is accepted too: it is an
+	// alternative answer that routes the learner down another path.
 	isCorrect := s.flagService.ValidateFlag(flag.ExpectedFlag, submittedFlag)
+	route := routeForStep(s.db, findStepByOrder(session.Scenario.Steps, session.CurrentStep),
+		stepOutcome{flag: &submittedFlag, failed: !isCorrect})
+	if route != nil {
+		isCorrect = true
+	}
 
 	now := time.Now()
 
@@ -1559,6 +1797,16 @@ func (s *ScenarioSessionService) SubmitFlag(sessionID uuid.UUID, submittedFlag s
 			"is_correct":     false,
 			"flag_attempts":  gorm.Expr("flag_attempts + 1"),
 		})
+		// The wrong-answer log feeds the teacher's integrity report; losing an
+		// entry weakens that report but must not fail the learner's submission.
+		if err := s.db.Create(&models.ScenarioFlagAttempt{
+			SessionID:     session.ID,
+			StepOrder:     flag.StepOrder,
+			SubmittedFlag: submittedFlag,
+			SubmittedAt:   now,
+		}).Error; err != nil {
+			slog.Warn("failed to log wrong flag submission", "session_id", session.ID, "err", err)
+		}
 		return response, nil
 	}
 
@@ -1582,7 +1830,7 @@ func (s *ScenarioSessionService) SubmitFlag(sessionID uuid.UUID, submittedFlag s
 			return fmt.Errorf("failed to update flag: %w", err)
 		}
 
-		nextStep, err := s.advanceToNextStep(tx, &session, now)
+		nextStep, err := s.advanceToNextStep(tx, &session, now, route, false)
 		if err != nil {
 			return err
 		}
@@ -1594,6 +1842,10 @@ func (s *ScenarioSessionService) SubmitFlag(sessionID uuid.UUID, submittedFlag s
 		return nil, txErr
 	}
 
+	if response.NextStep == nil && session.Status == "completed" {
+		s.publishSessionCompleted(session.ID)
+	}
+
 	if response.NextStep != nil {
 		response.StepProvisioningStatus = s.runStepTransition(&session, leftStep, *response.NextStep)
 	}
@@ -1649,7 +1901,24 @@ func (s *ScenarioSessionService) GetMySessions(userID string) ([]dto.MySessionRe
 // running setup goroutine is safe against this: all its status writes are
 // gated on WHERE status='provisioning', so once we flip to abandoned here
 // it can no longer resurrect the session.
+//
+// An exam cannot be abandoned, only handed in: it is finalised and graded on
+// what the learner had done, so walking away neither frees the attempt nor
+// leaves the trainer without a grade.
 func (s *ScenarioSessionService) AbandonSession(sessionID uuid.UUID) error {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindSessionEnded)
+	var session models.ScenarioSession
+	if err := s.db.Select("id", "exam_mode").First(&session, "id = ?", sessionID).Error; err == nil && session.ExamMode {
+		finalized, err := s.finalizeExam(sessionID, false)
+		if err != nil {
+			return err
+		}
+		if !finalized {
+			return fmt.Errorf("session not found or not abandonable")
+		}
+		return nil
+	}
+
 	result := s.db.Model(&models.ScenarioSession{}).
 		Where("id = ? AND status IN ?", sessionID, []string{"active", "provisioning"}).
 		Update("status", "abandoned")
@@ -1660,6 +1929,7 @@ func (s *ScenarioSessionService) AbandonSession(sessionID uuid.UUID) error {
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("session not found or not abandonable")
 	}
+	s.syncTeamSessionsNow(sessionID)
 
 	return nil
 }
@@ -1670,10 +1940,11 @@ func (s *ScenarioSessionService) AbandonSession(sessionID uuid.UUID) error {
 //
 // Canonical owner of the "which run is this terminal running?" lookup: the
 // by-terminal endpoint the frontend polls and the crash-trap permadeath path
-// must never disagree about which run a reused terminal belongs to.
+// must never disagree about which run a reused terminal belongs to. On a shared
+// terminal that is the team lead's session, never a member's mirror of it.
 func (s *ScenarioSessionService) FindSessionByTerminal(terminalSessionID string) (*models.ScenarioSession, error) {
 	var session models.ScenarioSession
-	err := s.db.Where("terminal_session_id = ?", terminalSessionID).
+	err := s.db.Where("terminal_session_id = ? AND team_lead_session_id IS NULL", terminalSessionID).
 		Order("created_at DESC").
 		First(&session).Error
 	if err != nil {
@@ -1731,6 +2002,7 @@ func (s *ScenarioSessionService) EndCrashTrapRun(terminalSessionID string) {
 // Hints must be revealed sequentially (level 1 before level 2, etc.).
 // Re-reading an already revealed hint is idempotent.
 func (s *ScenarioSessionService) RevealHint(sessionID uuid.UUID, stepOrder int, level int) (*dto.RevealHintResponse, error) {
+	defer s.notifyLiveProgress(sessionID, liveprogress.KindHintRevealed)
 	// 1. Load session, verify it's active
 	var session models.ScenarioSession
 	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
@@ -1739,6 +2011,12 @@ func (s *ScenarioSessionService) RevealHint(sessionID uuid.UUID, stepOrder int,
 	if err := requireActiveSession(&session); err != nil {
 		return nil, err
 	}
+	if err := s.enforceExamTimeLimit(&session); err != nil {
+		return nil, err
+	}
+	if session.ExamMode {
+		return nil, ErrHintsDisabled
+	}
 
 	// 2. Load step progress, verify step is not locked
 	var progress models.ScenarioStepProgress
@@ -1751,7 +2029,8 @@ func (s *ScenarioSessionService) RevealHint(sessionID uuid.UUID, stepOrder int,
 
 	// 3. Find the step model (by scenario_id + order)
 	var step models.ScenarioStep
-	if err := s.db.Where("scenario_id = ? AND \"order\" = ?", session.ScenarioID, stepOrder).First(&step).Error; err != nil {
+	if err := s.db.Scopes(models.RevisionSteps(session.RevisionID)).
+		Where("scenario_id = ? AND \"order\" = ?", session.ScenarioID, stepOrder).First(&step).Error; err != nil {
 		return nil, fmt.Errorf("step not found: %w", err)
 	}
 
@@ -1785,37 +2064,53 @@ func (s *ScenarioSessionService) RevealHint(sessionID uuid.UUID, stepOrder int,
 			Update("hints_revealed", level)
 	}
 
-	return &dto.RevealHintResponse{
-		Level:   level,
-		Content: hint.Content,
-		Total:   int(totalHints),
-	}, nil
+	response := &dto.RevealHintResponse{
+		Level:          level,
+		Content:        hint.Content,
+		Total:          int(totalHints),
+		PenaltyPercent: hint.PenaltyPercent,
+	}
+	s.fillHintScoreOutlook(&session, stepOrder, response)
+	return response, nil
 }
 
 // advanceToNextStep handles step completion and session advancement logic.
 // It marks the current step as completed, calculates time spent, and either
 // completes the session (if last step) or advances to the next step.
 // Returns the next step order (nil if session completed).
-func (s *ScenarioSessionService) advanceToNextStep(tx *gorm.DB, session *models.ScenarioSession, now time.Time) (*int, error) {
-	// Calculate time spent on this step and mark as completed.
+//
+// route is the transition the caller matched for the step being left, nil to
+// advance to the next Order. A route that jumps forward marks the steps it
+// passes over as skipped; one that jumps back reopens its target.
+//
+// failed marks the step as failed instead of completed: a failed attempt a
+// transition routed away from still moves the learner on, but earns no credit.
+func (s *ScenarioSessionService) advanceToNextStep(tx *gorm.DB, session *models.ScenarioSession, now time.Time, route *models.ScenarioStepTransition, failed bool) (*int, error) {
+	leftStatus := "completed"
+	if failed {
+		leftStatus = "failed"
+	}
+
+	// Calculate time spent on this step and mark it as left.
 	// Time is measured from when the student started this step:
-	// - Step 0: from session start
-	// - Other steps: from when the previous step was completed
+	// - First step: from session start
+	// - Other steps: from the most recent time another step was left, which
+	//   on a routed path is not necessarily the previous Order
 	var stepProgress models.ScenarioStepProgress
 	if err := tx.Where("session_id = ? AND step_order = ?", session.ID, session.CurrentStep).First(&stepProgress).Error; err == nil {
 		stepStartTime := session.StartedAt
 		// Find the previous step's completion time
 		var prevStep models.ScenarioStepProgress
-		if err := tx.Where("session_id = ? AND step_order < ? AND status = ?",
-			session.ID, session.CurrentStep, "completed").
-			Order("step_order DESC").First(&prevStep).Error; err == nil && prevStep.CompletedAt != nil {
+		if err := tx.Where("session_id = ? AND step_order <> ? AND status IN ? AND completed_at IS NOT NULL",
+			session.ID, session.CurrentStep, []string{"completed", "failed"}).
+			Order("completed_at DESC").First(&prevStep).Error; err == nil && prevStep.CompletedAt != nil {
 			stepStartTime = *prevStep.CompletedAt
 		}
 		timeSpent := int(now.Sub(stepStartTime).Seconds())
 		if err := tx.Model(&models.ScenarioStepProgress{}).
 			Where("session_id = ? AND step_order = ?", session.ID, session.CurrentStep).
 			Updates(map[string]any{
-				"status":             "completed",
+				"status":             leftStatus,
 				"completed_at":       now,
 				"time_spent_seconds": timeSpent,
 			}).Error; err != nil {
@@ -1826,7 +2121,7 @@ func (s *ScenarioSessionService) advanceToNextStep(tx *gorm.DB, session *models.
 		if err := tx.Model(&models.ScenarioStepProgress{}).
 			Where("session_id = ? AND step_order = ?", session.ID, session.CurrentStep).
 			Updates(map[string]any{
-				"status":       "completed",
+				"status":       leftStatus,
 				"completed_at": now,
 			}).Error; err != nil {
 			return nil, fmt.Errorf("failed to mark step completed: %w", err)
@@ -1845,17 +2140,54 @@ func (s *ScenarioSessionService) advanceToNextStep(tx *gorm.DB, session *models.
 		}
 	}
 
+	if route != nil {
+		if route.TargetStepOrder == nil {
+			isLastStep = true
+		} else if findStepByOrder(session.Scenario.Steps, *route.TargetStepOrder) != nil {
+			isLastStep = false
+			nextStepOrder = *route.TargetStepOrder
+		} else {
+			// The target was deleted after the transition was written. The
+			// linear fallback keeps the learner moving instead of stranding
+			// them on a step they already passed.
+			slog.Warn("transition targets a missing step, advancing linearly",
+				"transition_id", route.ID, "target_step_order", *route.TargetStepOrder)
+		}
+	}
+
+	// Steps passed over are marked skipped, not left locked: they were never
+	// going to be played, and grades and "step N of M" exclude them. A step
+	// reached later by another route is simply reopened below.
+	skipFrom, skipTo := session.CurrentStep, nextStepOrder
+	if isLastStep {
+		skipTo = math.MaxInt
+	}
+	if skipTo > skipFrom {
+		if err := tx.Model(&models.ScenarioStepProgress{}).
+			Where("session_id = ? AND step_order > ? AND step_order < ? AND status = ?",
+				session.ID, skipFrom, skipTo, "locked").
+			Update("status", "skipped").Error; err != nil {
+			return nil, fmt.Errorf("failed to mark skipped steps: %w", err)
+		}
+		for i := range session.StepProgress {
+			p := &session.StepProgress[i]
+			if p.StepOrder > skipFrom && p.StepOrder < skipTo && p.Status == "locked" {
+				p.Status = "skipped"
+			}
+		}
+	}
+
 	if isLastStep {
 		// Calculate weighted grade: each step contributes its own weight.
 		//   terminal/flag/info → 1.0 if completed else 0.0
 		//   quiz               → progress.QuizScore (0 if nil)
-		// Mirror the just-applied "completed" status onto the in-memory
+		// Mirror the just-applied status onto the in-memory
 		// session.StepProgress slice for the current step so the helper
-		// counts it as completed. Quiz scores must be set on the in-memory
+		// grades it. Quiz scores must be set on the in-memory
 		// slice by the caller (SubmitQuiz) before reaching here.
 		for i := range session.StepProgress {
 			if session.StepProgress[i].StepOrder == session.CurrentStep {
-				session.StepProgress[i].Status = "completed"
+				session.StepProgress[i].Status = leftStatus
 				if session.StepProgress[i].CompletedAt == nil {
 					completedAt := now
 					session.StepProgress[i].CompletedAt = &completedAt
@@ -1863,16 +2195,23 @@ func (s *ScenarioSessionService) advanceToNextStep(tx *gorm.DB, session *models.
 				break
 			}
 		}
+		if err := attachStepHints(tx, session.Scenario.Steps); err != nil {
+			return nil, err
+		}
 		grade := ComputeWeightedGradeFromLoaded(session.Scenario.Steps, session.StepProgress, nil)
 
-		// Mark session as completed with grade
-		if err := tx.Model(session).Updates(map[string]any{
-			"status":       "completed",
-			"completed_at": now,
-			"grade":        grade,
-		}).Error; err != nil {
+		// Mark session as completed with grade, less any late penalty
+		updates, err := completionUpdates(tx, session, grade, now)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Model(session).Updates(updates).Error; err != nil {
 			return nil, fmt.Errorf("failed to mark session completed: %w", err)
 		}
+		// The team is graded with its lead: the grade is everyone's.
+		if err := syncTeamSessions(tx, session.ID); err != nil {
+			return nil, err
+		}
 		return nil, nil
 	}
 
@@ -1887,6 +2226,9 @@ func (s *ScenarioSessionService) advanceToNextStep(tx *gorm.DB, session *models.
 		Update("status", "active").Error; err != nil {
 		return nil, fmt.Errorf("failed to unlock next step: %w", err)
 	}
+	if err := syncTeamSessions(tx, session.ID); err != nil {
+		return nil, err
+	}
 
 	return &nextStepOrder, nil
 }
@@ -1946,6 +2288,12 @@ func (s *ScenarioSessionService) deploySingleFlagToContainer(terminalSessionID s
 		return nil
 	}
 
+	// The flag lands on the machine the step is played on.
+	terminalSessionID, err := s.stepTerminal(terminalSessionID, step)
+	if err != nil {
+		return fmt.Errorf("failed to deploy flag for step %d: %w", flag.StepOrder, err)
+	}
+
 	// Push the flag file to the container (with trailing newline for clean cat output)
 	if err := s.verificationService.PushFile(terminalSessionID, flagPath, flag.ExpectedFlag+"\n", "0644"); err != nil {
 		slog.Warn("failed to deploy flag to container", "step_order", flag.StepOrder, "path", flagPath, "err", err)
@@ -2052,12 +2400,15 @@ func (s *ScenarioSessionService) executeBackgroundScript(terminalSessionID strin
 	if s.verificationService == nil {
 		return "", fmt.Errorf("verification service not available")
 	}
+	terminalSessionID, err := s.stepTerminal(terminalSessionID, step)
+	if err != nil {
+		return "", err
+	}
 
 	timeout := effectiveTimeout(step)
 
 	var exitCode int
 	var stderr string
-	var err error
 
 	interpreter := parseShebang(bgScript)
 
@@ -0,0 +1,289 @@
+package services
+
+// scenarioTestRunner.go — "scenario CI". The runner plays a scenario from its
+// first step to its last against any VerificationServiceInterface — tt-backend
+// itself, or a local container through ContainerCLIVerificationService — the
+// way a learner who knows the answers would: each step's background script
+// provisions it, its solution script solves it, its verify script checks it.
+//
+// Provisioning goes through the session engine's own executeBackgroundScript
+// and deploySingleFlagToContainer, so a scenario that passes here provisions
+// the same way in a session. Steps are played in order; transitions are not
+// followed.
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// Scenario test step statuses.
+const (
+	ScenarioTestPassed   = "passed"
+	ScenarioTestFailed   = "failed"
+	ScenarioTestUntested = "untested" // The step has no solution script to play
+	ScenarioTestSkipped  = "skipped"  // Not played: a quiz step, or a step after a failure
+)
+
+// solutionScriptTimeout bounds a step's solution script, in seconds.
+const solutionScriptTimeout = 120
+
+// scenarioTestUserID is the learner flags are generated for.
+const scenarioTestUserID = "scenario-test-runner"
+
+// ScenarioTestRunner plays scenarios end to end.
+type ScenarioTestRunner struct {
+	verification VerificationServiceInterface
+	// sessions runs provisioning with the session engine's code. It has no
+	// database: everything it is handed is already resolved.
+	sessions *ScenarioSessionService
+	flags    *FlagService
+}
+
+// NewScenarioTestRunner creates a runner executing scripts through
+// verification.
+func NewScenarioTestRunner(verification VerificationServiceInterface) *ScenarioTestRunner {
+	return &ScenarioTestRunner{
+		verification: verification,
+		sessions:     NewScenarioSessionService(nil, nil, verification),
+		flags:        NewFlagService(),
+	}
+}
+
+// Run plays scenario in the container terminalSessionID names, which must be
+// freshly started from the scenario's image. Scripts must be resolved to
+// their content.
+func (r *ScenarioTestRunner) Run(scenario *models.Scenario, terminalSessionID string) (*dto.ScenarioTestReport, error) {
+	played := *scenario
+	played.Steps = make([]models.ScenarioStep, len(scenario.Steps))
+	copy(played.Steps, scenario.Steps)
+	sort.SliceStable(played.Steps, func(i, j int) bool { return played.Steps[i].Order < played.Steps[j].Order })
+
+	var flags []models.ScenarioFlag
+	if played.FlagsEnabled {
+		if played.FlagSecret == "" {
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, fmt.Errorf("failed to generate flag secret: %w", err)
+			}
+			played.FlagSecret = hex.EncodeToString(secret)
+		}
+		flags = r.flags.GenerateFlags(&played, uuid.New(), scenarioTestUserID)
+	}
+
+	report := &dto.ScenarioTestReport{Passed: true, Steps: make([]dto.ScenarioTestStepResult, len(played.Steps))}
+	for i := range played.Steps {
+		report.Steps[i] = dto.ScenarioTestStepResult{
+			Step:     i + 1,
+			Title:    played.Steps[i].Title,
+			StepType: normalizeStepType(played.Steps[i].StepType),
+		}
+	}
+	if len(played.Steps) == 0 {
+		return report, nil
+	}
+
+	if played.CrashTraps && len(flags) > 0 {
+		session := &models.ScenarioSession{Flags: flags}
+		if err := r.sessions.deployChallengeConfig(terminalSessionID, &played, session, scenarioTestUserID); err != nil {
+			failStep(report, 0, "setup", nil, "", err.Error())
+			return report, nil
+		}
+	}
+	if played.SetupScript != "" {
+		setupStep := &models.ScenarioStep{Order: -1, BackgroundScript: played.SetupScript}
+		if stdout, err := r.sessions.executeBackgroundScript(terminalSessionID, setupStep, nil); err != nil {
+			failStep(report, 0, "setup", nil, stdout, "setup script failed: "+err.Error())
+			return report, nil
+		}
+	}
+
+	for i := range played.Steps {
+		if !r.playStep(report, i, &played, flags, terminalSessionID) {
+			return report, nil
+		}
+	}
+	return report, nil
+}
+
+// playStep plays one step, reporting false when the run cannot go on.
+func (r *ScenarioTestRunner) playStep(report *dto.ScenarioTestReport, i int, scenario *models.Scenario, flags []models.ScenarioFlag, terminalSessionID string) bool {
+	step := &scenario.Steps[i]
+	result := &report.Steps[i]
+
+	stdout, err := r.sessions.executeBackgroundScript(terminalSessionID, step, stepProvisioningEnv(scenario, flags, step.Order))
+	if err != nil {
+		failStep(report, i, "background", nil, stdout, "background script failed: "+err.Error())
+		return false
+	}
+	if flag := findFlagByStepOrder(flags, step.Order); flag != nil && step.HasFlag {
+		if answer := parseStepAnswer(stdout); answer != "" {
+			flag.ExpectedFlag = answer
+		}
+	}
+	if err := r.sessions.deploySingleFlagToContainer(terminalSessionID, scenario, flags, step.Order); err != nil {
+		failStep(report, i, "flag", nil, "", err.Error())
+		return false
+	}
+
+	stepType := normalizeStepType(step.StepType)
+	isFlagStep := stepType == StepTypeFlag || (stepType == StepTypeTerminal && step.HasFlag)
+	switch {
+	case stepType == "quiz":
+		result.Status = ScenarioTestSkipped
+		result.Message = "quiz steps are not played"
+		return true
+	case stepType == "info":
+		result.Status = ScenarioTestPassed
+		return true
+	}
+
+	if !isFlagStep && step.VerifyScript != "" {
+		if code, _, err := runStepVerify(r.verification, terminalSessionID, step); err == nil && code == 0 {
+			result.VerifyPassedBeforeSolution = true
+		}
+	}
+
+	if step.SolutionScript == "" {
+		result.Status = ScenarioTestUntested
+		result.Message = "the step has no solution script"
+		return true
+	}
+	code, solutionOut, solutionErr, err := r.verification.ExecInContainer(terminalSessionID,
+		[]string{parseShebang(step.SolutionScript), "-c", step.SolutionScript}, nil, solutionScriptTimeout)
+	if err != nil || code != 0 {
+		message := fmt.Sprintf("solution script exited with code %d: %s", code, solutionErr)
+		if err != nil {
+			message = "solution script failed: " + err.Error()
+		}
+		failStep(report, i, "solution", &code, solutionOut, message)
+		return false
+	}
+
+	if isFlagStep {
+		flag := findFlagByStepOrder(flags, step.Order)
+		if flag == nil {
+			failStep(report, i, "flag", nil, solutionOut, "the step has no flag: flags are disabled for the scenario")
+			return false
+		}
+		if !r.flags.ValidateFlag(flag.ExpectedFlag, lastOutputLine(solutionOut)) {
+			failStep(report, i, "solution", nil, solutionOut, "the solution script's last line of output is not the step's flag")
+			return false
+		}
+		result.Status = ScenarioTestPassed
+		return true
+	}
+
+	if step.VerifyScript == "" {
+		result.Status = ScenarioTestPassed
+		result.Message = "the step has no verify script"
+		return true
+	}
+	code, output, err := runStepVerify(r.verification, terminalSessionID, step)
+	if err != nil {
+		failStep(report, i, "verify", nil, "", err.Error())
+		return false
+	}
+	if code != 0 {
+		failStep(report, i, "verify", &code, output, "the verify script failed after the solution ran")
+		return false
+	}
+	result.Status = ScenarioTestPassed
+	result.Output = output
+	return true
+}
+
+// runStepVerify runs a step's verify script, with its exit code when the
+// verification service reports one.
+func runStepVerify(verification VerificationServiceInterface, terminalSessionID string, step *models.ScenarioStep) (int, string, error) {
+	if verifier, ok := verification.(ExitCodeVerifier); ok {
+		return verifier.VerifyStepExitCode(terminalSessionID, step)
+	}
+	passed, output, err := verification.VerifyStep(terminalSessionID, step)
+	if err != nil || passed {
+		return 0, output, err
+	}
+	return 1, output, nil
+}
+
+// failStep marks step i failed and every step after it skipped.
+func failStep(report *dto.ScenarioTestReport, i int, phase string, exitCode *int, output, message string) {
+	report.Passed = false
+	report.Steps[i].Status = ScenarioTestFailed
+	report.Steps[i].Phase = phase
+	report.Steps[i].ExitCode = exitCode
+	report.Steps[i].Output = output
+	report.Steps[i].Message = message
+	for j := i + 1; j < len(report.Steps); j++ {
+		report.Steps[j].Status = ScenarioTestSkipped
+		report.Steps[j].Message = fmt.Sprintf("not played: step %d failed", i+1)
+	}
+}
+
+// lastOutputLine returns the last non-blank line of a script's output.
+func lastOutputLine(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// LoadScenarioSource builds a scenario from a KillerCoda-compatible directory
+// or a JSON file in the /scenarios/import-json format, without storing it.
+func LoadScenarioSource(path string) (*models.Scenario, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		data, err := os.ReadFile(filepath.Join(path, "index.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index.json: %w", err)
+		}
+		importer := NewScenarioImporterService(nil)
+		index, err := importer.ParseIndexJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		return importer.BuildScenarioFromIndex(index, path, "", nil, "test")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var input dto.SeedScenarioInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	scenario := &models.Scenario{
+		Title:            input.Title,
+		InstanceType:     input.InstanceType,
+		FlagsEnabled:     input.FlagsEnabled,
+		AllowedFlagPaths: input.AllowedFlagPaths,
+		CrashTraps:       input.CrashTraps,
+		SetupScript:      input.SetupScript,
+		Steps:            seedStepsFromInput(input.Steps),
+	}
+	for i, st := range input.Steps {
+		transitions, err := transitionsFromSpecs(st.Transitions, scenario.Steps)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		scenario.Steps[i].Transitions = transitions
+	}
+	return scenario, nil
+}
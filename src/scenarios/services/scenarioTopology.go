@@ -0,0 +1,226 @@
+package services
+
+// scenarioTopology.go — multi-node scenarios. A scenario that declares nodes
+// runs on one terminal per node, on a private network of their own. The
+// session stays bound to the primary node's terminal, which is what every
+// single-machine code path already passes around; the other terminals are
+// reached from it through the session's node rows, so only the places that
+// touch a container need to know there may be more than one.
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// ErrUnknownTargetNode is returned when a step targets a node the scenario, or
+// the session running it, does not have.
+var ErrUnknownTargetNode = errors.New("step targets a node the topology does not declare")
+
+// TopologyNode is one machine of a session's topology once its terminal has
+// been started, as the launch path hands it to the session service. The first
+// node of a topology is the primary one.
+type TopologyNode struct {
+	Name              string
+	Hostname          string
+	TerminalSessionID string
+}
+
+// SortNodesByOrder returns the nodes primary first, without reordering the
+// caller's slice.
+func SortNodesByOrder(nodes []models.ScenarioNode) []models.ScenarioNode {
+	sorted := make([]models.ScenarioNode, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	return sorted
+}
+
+// BuildScenarioNodes turns an authored topology into ScenarioNode rows,
+// declaration order becoming the node order. Names are how steps target a
+// node, so a blank or repeated name is refused rather than stored.
+//
+// Shared by the import and seed paths so an authored topology means the same
+// thing however it reaches the platform.
+func BuildScenarioNodes(specs []dto.ScenarioNodeSpec) ([]models.ScenarioNode, error) {
+	nodes := make([]models.ScenarioNode, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		name := strings.TrimSpace(spec.Name)
+		if name == "" {
+			return nil, fmt.Errorf("node %d has no name", i+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("node %q is declared twice", name)
+		}
+		seen[name] = true
+
+		features, err := EncodeRequiredFeatures(spec.RequiredFeatures)
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", name, err)
+		}
+		nodes = append(nodes, models.ScenarioNode{
+			Name:             name,
+			Order:            i,
+			Distribution:     strings.TrimSpace(spec.Distribution),
+			Size:             strings.TrimSpace(spec.Size),
+			Hostname:         strings.TrimSpace(spec.Hostname),
+			RequiredFeatures: features,
+		})
+	}
+	return nodes, nil
+}
+
+// ScenarioNodeSpecs is BuildScenarioNodes in reverse, for export.
+func ScenarioNodeSpecs(nodes []models.ScenarioNode) []dto.ScenarioNodeSpec {
+	if len(nodes) == 0 {
+		return nil
+	}
+	specs := make([]dto.ScenarioNodeSpec, 0, len(nodes))
+	for _, node := range SortNodesByOrder(nodes) {
+		features, _ := node.GetRequiredFeatures()
+		specs = append(specs, dto.ScenarioNodeSpec{
+			Name:             node.Name,
+			Distribution:     node.Distribution,
+			Size:             node.Size,
+			Hostname:         node.Hostname,
+			RequiredFeatures: features,
+		})
+	}
+	return specs
+}
+
+// ValidateStepTargets checks that every step naming a node names one of the
+// scenario's. Caught when the scenario is authored, a typo would otherwise
+// surface as a setup failure in the first learner's run.
+func ValidateStepTargets(nodes []models.ScenarioNode, steps []models.ScenarioStep) error {
+	names := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		names[node.Name] = true
+	}
+	for i, step := range steps {
+		if step.TargetNode != "" && !names[step.TargetNode] {
+			return fmt.Errorf("step %d: %w: %q", i+1, ErrUnknownTargetNode, step.TargetNode)
+		}
+	}
+	return nil
+}
+
+// replaceScenarioNodes swaps a scenario's topology for a new one. As with the
+// declared images, a re-import or re-seed converges on what the scenario now
+// says instead of adding to what it said before.
+func replaceScenarioNodes(tx *gorm.DB, scenarioID uuid.UUID, nodes []models.ScenarioNode) error {
+	if err := tx.Unscoped().Where("scenario_id = ?", scenarioID).Delete(&models.ScenarioNode{}).Error; err != nil {
+		return fmt.Errorf("failed to delete old nodes: %w", err)
+	}
+	for i := range nodes {
+		nodes[i].ScenarioID = scenarioID
+		if err := tx.Create(&nodes[i]).Error; err != nil {
+			return fmt.Errorf("failed to create node: %w", err)
+		}
+	}
+	return nil
+}
+
+// StartScenarioOnTopology is StartScenario for a multi-node scenario: the
+// session is bound to the first node's terminal, and the others are recorded
+// alongside it before any setup script runs, so the first step can already
+// target them.
+func (s *ScenarioSessionService) StartScenarioOnTopology(userID string, scenarioID uuid.UUID, nodes []TopologyNode) (*models.ScenarioSession, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("a topology needs at least one node")
+	}
+	return s.startScenario(userID, scenarioID, nodes[0].TerminalSessionID, false, nodes)
+}
+
+// WithPreviewTopology previews a multi-node scenario on the given node
+// terminals, the first being the primary one.
+func WithPreviewTopology(nodes []TopologyNode) PreviewOption {
+	return func(c *previewConfig) {
+		c.nodes = nodes
+	}
+}
+
+// createSessionNodes records the terminals of a session's topology.
+func createSessionNodes(tx *gorm.DB, sessionID uuid.UUID, nodes []TopologyNode) error {
+	for i, node := range nodes {
+		row := models.ScenarioSessionNode{
+			SessionID:         sessionID,
+			Name:              node.Name,
+			Hostname:          node.Hostname,
+			TerminalSessionID: node.TerminalSessionID,
+			IsPrimary:         i == 0,
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return fmt.Errorf("failed to record node %q: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// SessionNodes lists the machines of a session's topology, primary first.
+// A session of a single-machine scenario has none.
+func (s *ScenarioSessionService) SessionNodes(sessionID uuid.UUID) ([]models.ScenarioSessionNode, error) {
+	var nodes []models.ScenarioSessionNode
+	if err := s.db.Where("session_id = ?", sessionID).
+		Order("is_primary DESC, name ASC").
+		Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// primaryNodeSession selects the session whose topology has terminalSessionID
+// as its primary node.
+func (s *ScenarioSessionService) primaryNodeSession(terminalSessionID string) *gorm.DB {
+	return s.db.Model(&models.ScenarioSessionNode{}).Select("session_id").
+		Where("terminal_session_id = ? AND is_primary = ?", terminalSessionID, true)
+}
+
+// stepTerminal is the terminal a step's scripts and flag go to: the node it
+// targets, found from the session's primary terminal, or that terminal itself
+// for a step that names no node.
+func (s *ScenarioSessionService) stepTerminal(terminalSessionID string, step *models.ScenarioStep) (string, error) {
+	if step == nil || step.TargetNode == "" {
+		return terminalSessionID, nil
+	}
+	var node models.ScenarioSessionNode
+	err := s.db.Where("name = ? AND session_id IN (?)", step.TargetNode, s.primaryNodeSession(terminalSessionID)).
+		First(&node).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownTargetNode, step.TargetNode)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve node %q: %w", step.TargetNode, err)
+	}
+	return node.TerminalSessionID, nil
+}
+
+// topologyTerminals lists every terminal of the topology a primary terminal
+// leads, primary first, or just the terminal itself when it leads none. Used
+// where the session's machines are handled as one: stopping them, closing
+// their build window, snapshotting and restoring a step.
+func (s *ScenarioSessionService) topologyTerminals(terminalSessionID string) []string {
+	var ids []string
+	if err := s.db.Model(&models.ScenarioSessionNode{}).
+		Where("session_id IN (?)", s.primaryNodeSession(terminalSessionID)).
+		Order("is_primary DESC, name ASC").
+		Pluck("terminal_session_id", &ids).Error; err != nil {
+		slog.Warn("failed to list the session's node terminals; handling the primary one only",
+			"terminal_session_id", terminalSessionID, "err", err)
+		return []string{terminalSessionID}
+	}
+	if len(ids) == 0 {
+		return []string{terminalSessionID}
+	}
+	return ids
+}
@@ -21,5 +21,10 @@ func ResolveScriptContent(db *gorm.DB, fileID *uuid.UUID, inlineContent string)
 		return inlineContent
 	}
 
-	return file.Content
+	content, err := LoadProjectFileContent(&file)
+	if err != nil {
+		log.Printf("[ScriptResolver] Failed to load content of ProjectFile %s from %s storage: %v", fileID, file.StorageType, err)
+		return inlineContent
+	}
+	return content
 }
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"soli/formations/src/liveprogress"
+	"soli/formations/src/scenarios/models"
+)
+
+// notifyLiveProgress tells the open teacher streams that a session's learner
+// may have moved. Deferred by the session actions, so it fires whatever the
+// outcome: a rejected flag is activity too. Previews are nobody's class work
+// and are not reported.
+func (s *ScenarioSessionService) notifyLiveProgress(sessionID uuid.UUID, kind string) {
+	if !liveprogress.HasSubscribers() {
+		return
+	}
+	var session models.ScenarioSession
+	if err := s.db.Select("id", "user_id", "scenario_id", "is_preview").
+		First(&session, "id = ?", sessionID).Error; err != nil || session.IsPreview {
+		return
+	}
+	liveprogress.Publish(liveprogress.Event{
+		Kind:       kind,
+		UserID:     session.UserID,
+		ScenarioID: session.ScenarioID,
+		SessionID:  session.ID,
+		At:         time.Now(),
+	})
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	certificateServices "soli/formations/src/certificates/services"
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+// sessionEventData is the "data" of the scenario session webhooks.
+type sessionEventData struct {
+	SessionID    uuid.UUID  `json:"session_id"`
+	ScenarioID   uuid.UUID  `json:"scenario_id"`
+	UserID       string     `json:"user_id"`
+	AssignmentID *uuid.UUID `json:"assignment_id,omitempty"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	Grade        *float64   `json:"grade,omitempty"`
+	ExamMode     bool       `json:"exam_mode"`
+	TimedOut     bool       `json:"timed_out"`
+	// SubmittedLate and LatePenaltyPercent report an assignment completed
+	// after the learner's deadline.
+	SubmittedLate      bool    `json:"submitted_late"`
+	LatePenaltyPercent float64 `json:"late_penalty_percent,omitempty"`
+}
+
+// publishSessionCompleted raises the completion and grade webhooks of a
+// session that just ended, for every organization the learner took it in.
+// Called after the completing transaction commits; previews raise nothing.
+// It also issues the session's certificate of completion, and those of the
+// class groups the session completes. A team run is reported once per member:
+// the team's sessions all carry the grade.
+func (s *ScenarioSessionService) publishSessionCompleted(sessionID uuid.UUID) {
+	var sessions []models.ScenarioSession
+	if err := s.db.Where("id = ? OR team_lead_session_id = ?", sessionID, sessionID).
+		Find(&sessions).Error; err != nil || len(sessions) == 0 {
+		slog.Warn("failed to load session for webhooks", "session_id", sessionID, "err", err)
+		return
+	}
+	for i := range sessions {
+		s.publishCompletionOf(&sessions[i])
+	}
+}
+
+func (s *ScenarioSessionService) publishCompletionOf(session *models.ScenarioSession) {
+	if session.IsPreview {
+		return
+	}
+	data := sessionEventData{
+		SessionID:    session.ID,
+		ScenarioID:   session.ScenarioID,
+		UserID:       session.UserID,
+		AssignmentID: session.AssignmentID,
+		Status:       session.Status,
+		StartedAt:    session.StartedAt,
+		CompletedAt:  session.CompletedAt,
+		Grade:        session.Grade,
+		ExamMode:     session.ExamMode,
+		TimedOut:     session.TimedOut,
+
+		SubmittedLate:      session.SubmittedLate,
+		LatePenaltyPercent: session.LatePenaltyPercent,
+	}
+	for _, orgID := range sessionOrganizationIDs(s.db, session) {
+		webhookServices.Emit(s.db, &orgID, webhookServices.EventScenarioSessionCompleted, data)
+		webhookServices.Emit(s.db, &orgID, webhookServices.EventScenarioSessionGraded, data)
+	}
+	certificateServices.IssueForCompletedSession(s.db, session.ID)
+}
+
+// sessionOrganizationIDs returns the organizations a session is reported to:
+// the one of its exam assignment, or else those that assigned the scenario to
+// the learner (directly or through one of their groups), plus the scenario's
+// own organization when the learner belongs to it.
+func sessionOrganizationIDs(db *gorm.DB, session *models.ScenarioSession) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	var orgIDs []uuid.UUID
+	add := func(id *uuid.UUID) {
+		if id != nil && *id != uuid.Nil && !seen[*id] {
+			seen[*id] = true
+			orgIDs = append(orgIDs, *id)
+		}
+	}
+
+	var assignments []models.ScenarioAssignment
+	if session.ExamMode && session.AssignmentID != nil {
+		db.Where("id = ?", *session.AssignmentID).Find(&assignments)
+	} else {
+		var groupIDs []uuid.UUID
+		db.Model(&groupModels.GroupMember{}).
+			Where("user_id = ? AND is_active = true", session.UserID).
+			Pluck("group_id", &groupIDs)
+		var memberOrgIDs []uuid.UUID
+		db.Model(&orgModels.OrganizationMember{}).
+			Where("user_id = ? AND is_active = true", session.UserID).
+			Pluck("organization_id", &memberOrgIDs)
+		if len(groupIDs) > 0 || len(memberOrgIDs) > 0 {
+			// uuid.Nil keeps both IN lists non-empty.
+			db.Where("scenario_id = ? AND is_active = true", session.ScenarioID).
+				Where("(scope = 'group' AND group_id IN ?) OR (scope = 'org' AND organization_id IN ?)",
+					append(groupIDs, uuid.Nil), append(memberOrgIDs, uuid.Nil)).
+				Find(&assignments)
+		}
+
+		var scenario models.Scenario
+		if err := db.Select("id", "organization_id").First(&scenario, "id = ?", session.ScenarioID).Error; err == nil && scenario.OrganizationID != nil {
+			for _, id := range memberOrgIDs {
+				if id == *scenario.OrganizationID {
+					add(scenario.OrganizationID)
+					break
+				}
+			}
+		}
+	}
+
+	for _, a := range assignments {
+		if a.OrganizationID != nil {
+			add(a.OrganizationID)
+			continue
+		}
+		if a.GroupID != nil {
+			var group groupModels.ClassGroup
+			if err := db.Select("id", "organization_id").First(&group, "id = ?", *a.GroupID).Error; err == nil {
+				add(group.OrganizationID)
+			}
+		}
+	}
+	return orgIDs
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+)
+
+// stepOutcome is what the learner did on the step they are leaving — the facts
+// its transitions are evaluated against. Nil fields do not apply to the step.
+type stepOutcome struct {
+	quizScore *float64
+	flag      *string
+	exitCode  *int
+	// failed marks an attempt that did not pass the step. Only conditions
+	// naming a specific outcome (a flag, an exit code) can route a failed
+	// attempt: "always" or a quiz threshold must not turn a failure into an
+	// advance.
+	failed bool
+}
+
+// IsValidTransitionCondition reports whether condition is one the session
+// engine knows how to evaluate.
+func IsValidTransitionCondition(condition string) bool {
+	switch condition {
+	case models.TransitionAlways, models.TransitionQuizScoreBelow, models.TransitionQuizScoreAtLeast,
+		models.TransitionFlag, models.TransitionVerifyExitCode:
+		return true
+	}
+	return false
+}
+
+// ValidateStepTransition checks the fields a condition depends on. Shared by
+// every path that creates transitions so an archive cannot carry one the
+// editor would have refused.
+func ValidateStepTransition(condition string, threshold float64, value string) error {
+	if !IsValidTransitionCondition(condition) {
+		return fmt.Errorf("unknown transition condition %q", condition)
+	}
+	switch condition {
+	case models.TransitionQuizScoreBelow, models.TransitionQuizScoreAtLeast:
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("%s threshold must be between 0 and 1, got %v", condition, threshold)
+		}
+	case models.TransitionFlag:
+		if value == "" {
+			return fmt.Errorf("%s transition requires a value", condition)
+		}
+	case models.TransitionVerifyExitCode:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s transition requires an integer value, got %q", condition, value)
+		}
+	}
+	return nil
+}
+
+// loadStepTransitions returns a step's transitions in evaluation order.
+func loadStepTransitions(db *gorm.DB, stepID uuid.UUID) []models.ScenarioStepTransition {
+	var transitions []models.ScenarioStepTransition
+	db.Where("step_id = ?", stepID).Order("priority ASC, created_at ASC").Find(&transitions)
+	return transitions
+}
+
+// matchTransition returns the first transition whose condition holds for the
+// outcome, or nil when the step should advance linearly.
+func matchTransition(transitions []models.ScenarioStepTransition, outcome stepOutcome) *models.ScenarioStepTransition {
+	for i := range transitions {
+		if transitionMatches(&transitions[i], outcome) {
+			return &transitions[i]
+		}
+	}
+	return nil
+}
+
+func transitionMatches(t *models.ScenarioStepTransition, outcome stepOutcome) bool {
+	switch t.Condition {
+	case models.TransitionAlways:
+		return !outcome.failed
+	case models.TransitionQuizScoreBelow:
+		return !outcome.failed && outcome.quizScore != nil && *outcome.quizScore < t.Threshold
+	case models.TransitionQuizScoreAtLeast:
+		return !outcome.failed && outcome.quizScore != nil && *outcome.quizScore >= t.Threshold
+	case models.TransitionFlag:
+		return outcome.flag != nil && t.Value != "" &&
+			subtle.ConstantTimeCompare([]byte(*outcome.flag), []byte(t.Value)) == 1
+	case models.TransitionVerifyExitCode:
+		return outcome.exitCode != nil && t.Value == strconv.Itoa(*outcome.exitCode)
+	}
+	return false
+}
+
+// routeForStep loads step's transitions and matches them against outcome.
+func routeForStep(db *gorm.DB, step *models.ScenarioStep, outcome stepOutcome) *models.ScenarioStepTransition {
+	if step == nil {
+		return nil
+	}
+	return matchTransition(loadStepTransitions(db, step.ID), outcome)
+}
+
+// stepsOnPath drops the steps a session's routing skipped, leaving the ones
+// the learner has taken or may still take. Positions are counted over this
+// list, so a learner routed past a remediation branch is not shown a gap in
+// "step N of M".
+func stepsOnPath(steps []models.ScenarioStep, progress []models.ScenarioStepProgress) []models.ScenarioStep {
+	skipped := make(map[int]bool)
+	for _, p := range progress {
+		if p.Status == "skipped" {
+			skipped[p.StepOrder] = true
+		}
+	}
+	if len(skipped) == 0 {
+		return steps
+	}
+	onPath := make([]models.ScenarioStep, 0, len(steps))
+	for _, step := range steps {
+		if !skipped[step.Order] {
+			onPath = append(onPath, step)
+		}
+	}
+	return onPath
+}
+
+// transitionPositionTarget converts a transition's target order into the
+// 1-based step position archives use (0 ends the scenario). Archives address
+// steps by position because orders are not portable: a 1-based editor
+// scenario comes back 0-based from an import. ok is false when the target
+// step no longer exists.
+func transitionPositionTarget(steps []models.ScenarioStep, targetOrder *int) (position int, ok bool) {
+	if targetOrder == nil {
+		return 0, true
+	}
+	position = StepPosition(steps, *targetOrder)
+	return position, position > 0
+}
+
+// transitionOrderTarget is the inverse of transitionPositionTarget.
+func transitionOrderTarget(steps []models.ScenarioStep, position int) (*int, error) {
+	if position == 0 {
+		return nil, nil
+	}
+	if position < 0 || position > len(steps) {
+		return nil, fmt.Errorf("transition targets step %d, scenario has %d steps", position, len(steps))
+	}
+	order := steps[position-1].Order
+	return &order, nil
+}
+
+// transitionSpecs converts a step's transitions to their portable form.
+// Transitions whose target step no longer exists are dropped rather than
+// exported as "end the scenario", which is what a 0 target would mean.
+func transitionSpecs(transitions []models.ScenarioStepTransition, steps []models.ScenarioStep) []dto.StepTransitionSpec {
+	if len(transitions) == 0 {
+		return nil
+	}
+	specs := make([]dto.StepTransitionSpec, 0, len(transitions))
+	for _, t := range transitions {
+		target, ok := transitionPositionTarget(steps, t.TargetStepOrder)
+		if !ok {
+			continue
+		}
+		specs = append(specs, dto.StepTransitionSpec{
+			Priority:   t.Priority,
+			Condition:  t.Condition,
+			Threshold:  t.Threshold,
+			Value:      t.Value,
+			TargetStep: target,
+		})
+	}
+	return specs
+}
+
+// transitionsFromSpecs builds transition rows from their portable form.
+// steps must be the scenario's complete, order-sorted step list, since
+// targets are positions in it.
+func transitionsFromSpecs(specs []dto.StepTransitionSpec, steps []models.ScenarioStep) ([]models.ScenarioStepTransition, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	transitions := make([]models.ScenarioStepTransition, len(specs))
+	for i, spec := range specs {
+		if err := ValidateStepTransition(spec.Condition, spec.Threshold, spec.Value); err != nil {
+			return nil, err
+		}
+		target, err := transitionOrderTarget(steps, spec.TargetStep)
+		if err != nil {
+			return nil, err
+		}
+		transitions[i] = models.ScenarioStepTransition{
+			Priority:        spec.Priority,
+			Condition:       spec.Condition,
+			Threshold:       spec.Threshold,
+			Value:           spec.Value,
+			TargetStepOrder: target,
+		}
+	}
+	return transitions, nil
+}
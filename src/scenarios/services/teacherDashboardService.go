@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,7 +14,9 @@ import (
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
 
+	authModels "soli/formations/src/auth/models"
 	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
 	paymentServices "soli/formations/src/payment/services"
 	"soli/formations/src/scenarios/dto"
 	"soli/formations/src/scenarios/repositories"
@@ -32,6 +33,13 @@ var (
 	ErrSessionNotInGroup            = errors.New("session does not belong to this group")
 	ErrSessionHasNoTerminal         = errors.New("session has no terminal yet")
 	ErrScenarioNotAssignedToGroup   = errors.New("scenario is not assigned to this group")
+	// ErrRecordingConsentMissing is returned when nothing covers a trainer
+	// replaying a learner's recording: neither the group's (or its
+	// organization's) consent policy nor the learner's own acknowledgement.
+	ErrRecordingConsentMissing = errors.New("recording consent is not covered for this learner")
+	// ErrStepNotInSession is returned for a replay seek to a step the session
+	// has no progress for.
+	ErrStepNotInSession = errors.New("step not found in this session")
 )
 
 // GroupActivityItem represents an active session for a group member
@@ -75,6 +83,11 @@ type ScenarioResultItem struct {
 	TotalCorrectPossible int64      `json:"total_correct_possible"`
 	StartedAt            time.Time  `json:"started_at"`
 	CompletedAt          *time.Time `json:"completed_at,omitempty"`
+	// RevisionID and RevisionNumber name the published revision the session
+	// ran and was graded against; both are nil for a session that ran the
+	// draft, before the scenario was first published.
+	RevisionID     *uuid.UUID `json:"revision_id,omitempty"`
+	RevisionNumber *int       `json:"revision_number,omitempty"`
 }
 
 // PaginatedScenarioResults represents paginated scenario results with total count
@@ -99,6 +112,25 @@ type ScenarioAnalytics struct {
 	CompletionRate        float64  `json:"completion_rate"`
 	AvgGrade              *float64 `json:"avg_grade,omitempty"`
 	AvgCompletionTimeSecs *float64 `json:"avg_completion_time_seconds,omitempty"`
+	// Steps is the hint usage of each step, in step order, so authors can see
+	// which steps learners struggle with.
+	Steps []StepHintAnalytics `json:"steps,omitempty"`
+}
+
+// StepHintAnalytics is the hint usage of one step across the sessions that
+// reached it (active or completed on it; locked and skipped rows don't count).
+type StepHintAnalytics struct {
+	Order           int    `json:"order"`
+	Title           string `json:"title"`
+	HintsCount      int    `json:"hints_count"`
+	SessionsReached int64  `json:"sessions_reached"`
+	// HintUsageRate is the PERCENTAGE (0..100) of those sessions that
+	// revealed at least one hint.
+	HintUsageRate    float64 `json:"hint_usage_rate"`
+	AvgHintsRevealed float64 `json:"avg_hints_revealed"`
+	// AvgHintCostPercent is the average share (0..100) of the step's credit
+	// given up to hint penalties. Zero on steps whose hints are all free.
+	AvgHintCostPercent float64 `json:"avg_hint_cost_percent"`
 }
 
 // fetchUserMap loads display name and email for a set of user IDs from Casdoor.
@@ -218,7 +250,8 @@ func (s *TeacherDashboardService) GetGroupActivity(groupID uuid.UUID) ([]GroupAc
 	err := s.db.Raw(`
 		SELECT ss.id as session_id, ss.user_id, ss.current_step, ss.status, ss.started_at, ss.terminal_session_id,
 		       sc.title as scenario_title, sc.id as scenario_id,
-		       (SELECT COUNT(*) FROM scenario_steps WHERE scenario_id = sc.id AND deleted_at IS NULL) as total_steps
+		       `+sessionStepCountExpr+`
+		         - (SELECT COUNT(*) FROM scenario_step_progress WHERE session_id = ss.id AND status = 'skipped') as total_steps
 		FROM scenario_sessions ss
 		JOIN scenarios sc ON sc.id = ss.scenario_id
 		JOIN group_members gm ON gm.user_id = ss.user_id AND gm.group_id = ? AND gm.is_active = true
@@ -287,11 +320,14 @@ func (s *TeacherDashboardService) getScenarioResults(groupID, scenarioID uuid.UU
 	// Build paginated query
 	query := `
 		SELECT ss.id as session_id, ss.user_id, ss.status, ss.grade, ss.started_at, ss.completed_at, ss.current_step,
-		       (SELECT COUNT(*) FROM scenario_steps WHERE scenario_id = ss.scenario_id AND deleted_at IS NULL) as total_steps,
+		       ss.revision_id, sr.number as revision_number,
+		       ` + sessionStepCountExpr + `
+		         - (SELECT COUNT(*) FROM scenario_step_progress WHERE session_id = ss.id AND status = 'skipped') as total_steps,
 		       (SELECT COUNT(*) FROM scenario_step_progress WHERE session_id = ss.id AND status = 'completed') as completed_steps,
 		       ` + sessionHintsUsedExpr + ` as total_hints_used
 		FROM scenario_sessions ss
 		JOIN group_members gm ON gm.user_id = ss.user_id AND gm.group_id = ? AND gm.is_active = true
+		LEFT JOIN scenario_revisions sr ON sr.id = ss.revision_id
 		WHERE ss.scenario_id = ? AND ss.is_preview = false
 		ORDER BY ss.started_at DESC
 	`
@@ -342,7 +378,7 @@ func (s *TeacherDashboardService) getScenarioResults(groupID, scenarioID uuid.UU
 			// Same N+1 shape as the grade enrichment above; same justification.
 			// Analytics callers pass false to skip these extra queries since
 			// the aggregate computation does not read these fields.
-			correctCount, totalCorrectPossible := computeSessionCorrectCounts(s.db, scenarioID, results[i].SessionID)
+			correctCount, totalCorrectPossible := computeSessionCorrectCounts(s.db, scenarioID, results[i].RevisionID, results[i].SessionID)
 			results[i].CorrectCount = correctCount
 			results[i].TotalCorrectPossible = totalCorrectPossible
 		}
@@ -409,9 +445,60 @@ func (s *TeacherDashboardService) GetScenarioAnalytics(groupID, scenarioID uuid.
 		analytics.AvgCompletionTimeSecs = &avgTime
 	}
 
+	steps, err := s.stepHintAnalytics(groupID, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	analytics.Steps = steps
+
 	return analytics, nil
 }
 
+// stepHintAnalytics aggregates, per step of the scenario, the hints revealed
+// in the group's sessions and what they cost under the step's hint penalties.
+func (s *TeacherDashboardService) stepHintAnalytics(groupID, scenarioID uuid.UUID) ([]StepHintAnalytics, error) {
+	var steps []models.ScenarioStep
+	if err := s.db.Scopes(models.PublishedSteps).Preload("Hints").
+		Where("scenario_id = ?", scenarioID).
+		Order("\"order\" ASC").
+		Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scenario steps: %w", err)
+	}
+	usage, err := s.sessionRepo.GetStepHintUsageForGroupScenario(groupID, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]StepHintAnalytics, len(steps))
+	indexByOrder := make(map[int]int, len(steps))
+	for i, step := range steps {
+		stats[i] = StepHintAnalytics{Order: step.Order, Title: step.Title, HintsCount: len(step.Hints)}
+		indexByOrder[step.Order] = i
+	}
+
+	usedHints := make([]int64, len(steps))
+	for _, u := range usage {
+		i, ok := indexByOrder[u.StepOrder]
+		if !ok || (u.Status != "active" && u.Status != "completed") {
+			continue
+		}
+		stats[i].SessionsReached++
+		stats[i].AvgHintsRevealed += float64(u.HintsRevealed)
+		stats[i].AvgHintCostPercent += HintPenaltyPercent(steps[i].Hints, u.HintsRevealed)
+		if u.HintsRevealed > 0 {
+			usedHints[i]++
+		}
+	}
+	for i := range stats {
+		if reached := float64(stats[i].SessionsReached); reached > 0 {
+			stats[i].AvgHintsRevealed /= reached
+			stats[i].AvgHintCostPercent /= reached
+			stats[i].HintUsageRate = float64(usedHints[i]) / reached * 100.0
+		}
+	}
+	return stats, nil
+}
+
 // BulkStartResult represents the result of a bulk start operation
 type BulkStartResult struct {
 	Created    int              `json:"created"`
@@ -460,7 +547,7 @@ func (s *TeacherDashboardService) BulkStartScenario(groupID uuid.UUID, scenarioI
 
 	// Verify scenario exists and has steps
 	var scenario models.Scenario
-	if err := s.db.Preload("Steps").First(&scenario, "id = ?", scenarioID).Error; err != nil {
+	if err := s.db.Preload("Steps", models.PublishedSteps).First(&scenario, "id = ?", scenarioID).Error; err != nil {
 		return nil, fmt.Errorf("scenario not found: %w", err)
 	}
 
@@ -721,6 +808,8 @@ type SessionDetailResponse struct {
 	TrainerID         *string            `json:"trainer_id,omitempty"`
 	ScenarioID        uuid.UUID          `json:"scenario_id"`
 	ScenarioTitle     string             `json:"scenario_title"`
+	// RevisionNumber is the revision the session ran; nil when it ran the draft.
+	RevisionNumber    *int               `json:"revision_number,omitempty"`
 	Status            string             `json:"status"`
 	Grade             *float64           `json:"grade,omitempty"`
 	// CorrectCount mirrors ScenarioResultItem.CorrectCount at the session level
@@ -777,7 +866,7 @@ func (s *TeacherDashboardService) GetSessionDetail(groupID, sessionID uuid.UUID)
 	}
 
 	var stepRows []models.ScenarioStep
-	if err := s.db.Where("scenario_id = ?", session.ScenarioID).Order("\"order\" ASC, id ASC").Find(&stepRows).Error; err != nil {
+	if err := s.db.Scopes(models.RevisionSteps(session.RevisionID)).Where("scenario_id = ?", session.ScenarioID).Order("\"order\" ASC, id ASC").Find(&stepRows).Error; err != nil {
 		return nil, fmt.Errorf("failed to load steps: %w", err)
 	}
 	stepByOrder := make(map[int]models.ScenarioStep, len(stepRows))
@@ -792,19 +881,27 @@ func (s *TeacherDashboardService) GetSessionDetail(groupID, sessionID uuid.UUID)
 
 	// Populate Questions for quiz steps. Single batch query for all questions
 	// across all quiz steps in the session, plus a lookup of QuizAnswers JSON.
-	if err := populateQuizQuestions(s.db, session.ScenarioID, sessionID, steps); err != nil {
+	if err := populateQuizQuestions(s.db, &session, steps); err != nil {
 		return nil, fmt.Errorf("failed to populate quiz questions: %w", err)
 	}
 
 	// Compute absolute correct counts so the modal header can render
 	// "Correct answers: X/Y" next to the percentage.
-	correctCount, totalCorrectPossible := computeSessionCorrectCounts(s.db, session.ScenarioID, session.ID)
+	correctCount, totalCorrectPossible := computeSessionCorrectCounts(s.db, session.ScenarioID, session.RevisionID, session.ID)
+
+	var revisionNumber *int
+	if session.RevisionID != nil {
+		var revision models.ScenarioRevision
+		if err := s.db.Select("number").First(&revision, "id = ?", *session.RevisionID).Error; err == nil {
+			revisionNumber = &revision.Number
+		}
+	}
 
 	// Enrich with user info
 	userMap := fetchUserMap([]string{session.UserID})
 	info := userMap[session.UserID]
 
-	return buildSessionDetailResponse(session, scenario, steps, correctCount, totalCorrectPossible, info), nil
+	return buildSessionDetailResponse(session, scenario, revisionNumber, steps, correctCount, totalCorrectPossible, info), nil
 }
 
 // buildSessionStepDetails merges progress rows with their scenario_step metadata
@@ -840,16 +937,35 @@ func buildSessionStepDetails(session models.ScenarioSession, progress []models.S
 		})
 	}
 
-	// Derive per-step StartedAt:
-	//   step 0 → session.StartedAt
-	//   step N>0 → previous step's CompletedAt (nil if previous incomplete)
+	// Derive per-step StartedAt. A step starts when the learner left the
+	// step they were on before it, which on a linear path is the previous
+	// step's CompletedAt. Transitions make the path non-linear, so "before"
+	// is the latest completion preceding this step's own:
+	//   locked / skipped step → nil (never started)
+	//   no earlier completion → session.StartedAt
+	//   otherwise            → latest earlier CompletedAt
 	for i := range steps {
-		if i == 0 {
-			started := session.StartedAt
-			steps[i].StartedAt = &started
-		} else {
-			steps[i].StartedAt = steps[i-1].CompletedAt
+		if steps[i].Status == "locked" || steps[i].Status == "skipped" {
+			continue
+		}
+		var started *time.Time
+		for j := range steps {
+			prev := steps[j].CompletedAt
+			if j == i || prev == nil {
+				continue
+			}
+			if steps[i].CompletedAt != nil && !prev.Before(*steps[i].CompletedAt) {
+				continue
+			}
+			if started == nil || prev.After(*started) {
+				started = prev
+			}
+		}
+		if started == nil {
+			sessionStart := session.StartedAt
+			started = &sessionStart
 		}
+		steps[i].StartedAt = started
 	}
 	return steps
 }
@@ -860,6 +976,7 @@ func buildSessionStepDetails(session models.ScenarioSession, progress []models.S
 func buildSessionDetailResponse(
 	session models.ScenarioSession,
 	scenario models.Scenario,
+	revisionNumber *int,
 	steps []SessionStepDetail,
 	correctCount, totalCorrectPossible int64,
 	info userInfo,
@@ -870,6 +987,7 @@ func buildSessionDetailResponse(
 		TrainerID:            session.TrainerID,
 		ScenarioID:           session.ScenarioID,
 		ScenarioTitle:        scenario.Title,
+		RevisionNumber:       revisionNumber,
 		Status:               session.Status,
 		Grade:                session.Grade,
 		CorrectCount:         correctCount,
@@ -918,7 +1036,7 @@ func (s *TeacherDashboardService) GetSessionDetails(groupID uuid.UUID, sessionID
 		return nil, err
 	}
 
-	graph, err := s.loadScenarioGraph(scenarioIDs)
+	graph, err := s.loadScenarioGraph(scenarioIDs, revisionIDsFromSessions(sessions))
 	if err != nil {
 		return nil, err
 	}
@@ -951,6 +1069,23 @@ func distinctIDsFromSessions(sessions []models.ScenarioSession) (userIDs []strin
 	return
 }
 
+// revisionIDsFromSessions returns the distinct revisions the sessions ran.
+// Sessions that ran a draft have none.
+func revisionIDsFromSessions(sessions []models.ScenarioSession) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{})
+	revisionIDs := make([]uuid.UUID, 0)
+	for _, ss := range sessions {
+		if ss.RevisionID == nil {
+			continue
+		}
+		if _, ok := seen[*ss.RevisionID]; !ok {
+			seen[*ss.RevisionID] = struct{}{}
+			revisionIDs = append(revisionIDs, *ss.RevisionID)
+		}
+	}
+	return revisionIDs
+}
+
 // loadSessionsForBulkDetails loads every session referenced by sessionIDs in a
 // single query. If any ID is missing, it returns the same "session not found"
 // error a looped GetSessionDetail would have hit at the first miss (walks the
@@ -1006,13 +1141,16 @@ func (s *TeacherDashboardService) verifyGroupAccessForBulkDetails(groupID uuid.U
 
 // scenarioGraph holds all scenario-keyed data needed to render session details:
 // the scenarios themselves, their steps (in both per-order map and full-list
-// views), and quiz questions for any quiz steps.
+// views), and quiz questions for any quiz steps. Steps are keyed by
+// ScenarioStep.VersionKey, so sessions pinned to different revisions of one
+// scenario each find their own.
 type scenarioGraph struct {
 	byID                  map[uuid.UUID]models.Scenario
-	stepByOrder           map[uuid.UUID]map[int]models.ScenarioStep // [scenario_id][order]
-	stepsByScenario       map[uuid.UUID][]models.ScenarioStep
+	stepByOrder           map[uuid.UUID]map[int]models.ScenarioStep // [version key][order]
+	stepsByVersion        map[uuid.UUID][]models.ScenarioStep       // [version key]
 	questionsByStepID     map[uuid.UUID][]models.ScenarioStepQuestion
 	questionCountByStepID map[uuid.UUID]int
+	revisionNumbers       map[uuid.UUID]int
 }
 
 // sessionTracking holds all per-session-keyed data used by the assembler:
@@ -1024,8 +1162,9 @@ type sessionTracking struct {
 
 // loadScenarioGraph runs the scenario-keyed query plan that feeds the assembler:
 // scenarios, scenario steps (two views: per-order map and per-scenario list),
-// and quiz questions (with per-step counts). Independent from per-session data.
-func (s *TeacherDashboardService) loadScenarioGraph(scenarioIDs []uuid.UUID) (*scenarioGraph, error) {
+// and quiz questions (with per-step counts). Steps are loaded for the drafts of
+// scenarioIDs and for revisionIDs, the revisions the sessions ran.
+func (s *TeacherDashboardService) loadScenarioGraph(scenarioIDs, revisionIDs []uuid.UUID) (*scenarioGraph, error) {
 	// Load all referenced scenarios in one query.
 	var scenarios []models.Scenario
 	if err := s.db.Where("id IN ?", scenarioIDs).Find(&scenarios).Error; err != nil {
@@ -1036,28 +1175,35 @@ func (s *TeacherDashboardService) loadScenarioGraph(scenarioIDs []uuid.UUID) (*s
 		byID[sc.ID] = sc
 	}
 
-	// Load all steps for all referenced scenarios in one query.
+	// Load all steps for all referenced scenario versions in one query.
 	// Two views of this data are needed downstream:
-	//   - stepByOrder[scenario_id][order] for the step-merge pass
-	//     (first-win on duplicate (scenario, order) to match GetSessionDetail)
-	//   - stepsByScenario[scenario_id] = []ScenarioStep for
+	//   - stepByOrder[version][order] for the step-merge pass
+	//     (first-win on duplicate (version, order) to match GetSessionDetail)
+	//   - stepsByVersion[version] = []ScenarioStep for
 	//     ComputeCorrectCountsFromLoaded, which expects the raw list.
+	stepQuery := s.db.Where("scenario_id IN ?", scenarioIDs)
+	if len(revisionIDs) > 0 {
+		stepQuery = stepQuery.Where("(revision_id IS NULL OR revision_id IN ?)", revisionIDs)
+	} else {
+		stepQuery = stepQuery.Scopes(models.DraftSteps)
+	}
 	var allSteps []models.ScenarioStep
-	if err := s.db.Where("scenario_id IN ?", scenarioIDs).
+	if err := stepQuery.
 		Order("\"order\" ASC, id ASC").
 		Find(&allSteps).Error; err != nil {
 		return nil, fmt.Errorf("failed to load steps: %w", err)
 	}
 	stepByOrder := make(map[uuid.UUID]map[int]models.ScenarioStep, len(scenarioIDs))
-	stepsByScenario := make(map[uuid.UUID][]models.ScenarioStep, len(scenarioIDs))
+	stepsByVersion := make(map[uuid.UUID][]models.ScenarioStep, len(scenarioIDs))
 	allQuizStepIDs := make([]uuid.UUID, 0)
 	for _, st := range allSteps {
-		stepsByScenario[st.ScenarioID] = append(stepsByScenario[st.ScenarioID], st)
-		if stepByOrder[st.ScenarioID] == nil {
-			stepByOrder[st.ScenarioID] = make(map[int]models.ScenarioStep)
+		key := st.VersionKey()
+		stepsByVersion[key] = append(stepsByVersion[key], st)
+		if stepByOrder[key] == nil {
+			stepByOrder[key] = make(map[int]models.ScenarioStep)
 		}
-		if _, exists := stepByOrder[st.ScenarioID][st.Order]; !exists {
-			stepByOrder[st.ScenarioID][st.Order] = st
+		if _, exists := stepByOrder[key][st.Order]; !exists {
+			stepByOrder[key][st.Order] = st
 		}
 		if normalizeStepType(st.StepType) == "quiz" {
 			allQuizStepIDs = append(allQuizStepIDs, st.ID)
@@ -1082,12 +1228,24 @@ func (s *TeacherDashboardService) loadScenarioGraph(scenarioIDs []uuid.UUID) (*s
 		}
 	}
 
+	revisionNumbers := make(map[uuid.UUID]int, len(revisionIDs))
+	if len(revisionIDs) > 0 {
+		var revisions []models.ScenarioRevision
+		if err := s.db.Select("id, number").Where("id IN ?", revisionIDs).Find(&revisions).Error; err != nil {
+			return nil, fmt.Errorf("failed to load revisions: %w", err)
+		}
+		for _, r := range revisions {
+			revisionNumbers[r.ID] = r.Number
+		}
+	}
+
 	return &scenarioGraph{
 		byID:                  byID,
 		stepByOrder:           stepByOrder,
-		stepsByScenario:       stepsByScenario,
+		stepsByVersion:        stepsByVersion,
 		questionsByStepID:     questionsByStepID,
 		questionCountByStepID: questionCountByStepID,
+		revisionNumbers:       revisionNumbers,
 	}, nil
 }
 
@@ -1155,7 +1313,7 @@ func assembleBulkSessionDetails(
 	for _, id := range sessionIDs {
 		session := sessionByID[id]
 		scenario := graph.byID[session.ScenarioID]
-		stepByOrder := graph.stepByOrder[session.ScenarioID]
+		stepByOrder := graph.stepByOrder[session.VersionKey()]
 		if stepByOrder == nil {
 			stepByOrder = map[int]models.ScenarioStep{}
 		}
@@ -1165,13 +1323,20 @@ func assembleBulkSessionDetails(
 		populateQuizQuestionsFromLoaded(session.ID, steps, stepByOrder, graph.questionsByStepID, sessionProgress)
 
 		correctCount, totalCorrectPossible := ComputeCorrectCountsFromLoaded(
-			graph.stepsByScenario[session.ScenarioID],
+			graph.stepsByVersion[session.VersionKey()],
 			sessionProgress,
 			tracking.flagsBySession[session.ID],
 			graph.questionCountByStepID,
 		)
 
-		details = append(details, buildSessionDetailResponse(session, scenario, steps, correctCount, totalCorrectPossible, userMap[session.UserID]))
+		var revisionNumber *int
+		if session.RevisionID != nil {
+			if number, ok := graph.revisionNumbers[*session.RevisionID]; ok {
+				revisionNumber = &number
+			}
+		}
+
+		details = append(details, buildSessionDetailResponse(session, scenario, revisionNumber, steps, correctCount, totalCorrectPossible, userMap[session.UserID]))
 	}
 	return details
 }
@@ -1204,7 +1369,7 @@ func populateQuizQuestionsFromLoaded(
 		if !ok {
 			continue
 		}
-		questions := questionsByStepID[st.ID]
+		questions := DrawQuizQuestions(questionsByStepID[st.ID], st.QuizDrawCount, sessionID, st.ID)
 		if len(questions) == 0 {
 			continue
 		}
@@ -1223,7 +1388,7 @@ func populateQuizQuestionsFromLoaded(
 			submitted := studentAnswers[q.ID.String()]
 			isCorrect := false
 			if submitted != "" {
-				isCorrect = subtle.ConstantTimeCompare([]byte(submitted), []byte(q.CorrectAnswer)) == 1
+				isCorrect = GradeQuizAnswer(q, submitted)
 			}
 			details = append(details, dto.SessionStepQuestionDetail{
 				ID:            q.ID,
@@ -1236,6 +1401,8 @@ func populateQuizQuestionsFromLoaded(
 				IsCorrect:     isCorrect,
 				Points:        q.Points,
 				Explanation:   q.Explanation,
+				Difficulty:    q.Difficulty,
+				Topic:         q.Topic,
 			})
 		}
 		steps[i].Questions = details
@@ -1251,9 +1418,25 @@ func populateQuizQuestionsFromLoaded(
 // ErrSessionHasNoTerminal) so the controller can map them to 404 responses
 // without leaking session existence.
 func (s *TeacherDashboardService) GetSessionCommands(groupID, sessionID uuid.UUID, limit, offset int) ([]byte, string, error) {
+	session, err := s.loadGroupTerminalSession(groupID, sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, contentType, err := s.terminalService.GetSessionCommandHistoryAdmin(*session.TerminalSessionID, limit, offset)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, contentType, nil
+}
+
+// loadGroupTerminalSession loads a scenario session a trainer of the group may
+// look into the terminal of: its learner is an active member of the group, it
+// runs a scenario assigned to the group, and it has a terminal.
+func (s *TeacherDashboardService) loadGroupTerminalSession(groupID, sessionID uuid.UUID) (*models.ScenarioSession, error) {
 	var session models.ScenarioSession
 	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
-		return nil, "", ErrSessionNotFound
+		return nil, ErrSessionNotFound
 	}
 
 	// Verify the session's user is a member of this group (mirror GetSessionDetail).
@@ -1262,11 +1445,11 @@ func (s *TeacherDashboardService) GetSessionCommands(groupID, sessionID uuid.UUI
 		Where("group_id = ? AND user_id = ? AND is_active = true", groupID, session.UserID).
 		Count(&memberCount)
 	if memberCount == 0 {
-		return nil, "", ErrSessionNotInGroup
+		return nil, ErrSessionNotInGroup
 	}
 
 	if session.TerminalSessionID == nil || *session.TerminalSessionID == "" {
-		return nil, "", ErrSessionHasNoTerminal
+		return nil, ErrSessionHasNoTerminal
 	}
 
 	// Verify the session's scenario is assigned to this group. Without this
@@ -1278,14 +1461,116 @@ func (s *TeacherDashboardService) GetSessionCommands(groupID, sessionID uuid.UUI
 		Where("group_id = ? AND scenario_id = ? AND deleted_at IS NULL", groupID, session.ScenarioID).
 		Count(&assignmentCount)
 	if assignmentCount == 0 {
-		return nil, "", ErrScenarioNotAssignedToGroup
+		return nil, ErrScenarioNotAssignedToGroup
 	}
+	return &session, nil
+}
 
-	body, contentType, err := s.terminalService.GetSessionCommandHistoryAdmin(*session.TerminalSessionID, limit, offset)
+// GetSessionRecording replays the terminal recording of a scenario session as
+// asciicast v2, with a marker at the start of every step so the player can
+// jump between them. stepOrder, when set, cuts the replay to that step: from
+// the moment the step before it was completed — or the session started — to
+// the moment it was.
+//
+// On top of GetSessionCommands' checks, a trainer may only replay what the
+// learner's recording consent covers (see recordingConsentCovers): a replay
+// shows everything the learner typed and saw, not just the commands.
+func (s *TeacherDashboardService) GetSessionRecording(groupID, sessionID uuid.UUID, stepOrder *int) ([]byte, error) {
+	session, err := s.loadGroupTerminalSession(groupID, sessionID)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	return body, contentType, nil
+	if !s.recordingConsentCovers(groupID, session.UserID) {
+		return nil, ErrRecordingConsentMissing
+	}
+
+	var progress []models.ScenarioStepProgress
+	if err := s.db.Where("session_id = ?", session.ID).Order("step_order ASC").Find(&progress).Error; err != nil {
+		return nil, fmt.Errorf("failed to load step progress: %w", err)
+	}
+	var steps []models.ScenarioStep
+	if err := s.db.Scopes(models.RevisionSteps(session.RevisionID)).
+		Where("scenario_id = ?", session.ScenarioID).Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scenario steps: %w", err)
+	}
+	titles := make(map[int]string, len(steps))
+	for _, step := range steps {
+		titles[step.Order] = step.Title
+	}
+
+	opts := ttDto.RecordingReplayOptions{}
+	stepFound := stepOrder == nil
+	for _, window := range stepWindows(session.StartedAt, progress) {
+		opts.Markers = append(opts.Markers, ttDto.RecordingMarker{
+			At:    window.start,
+			Label: fmt.Sprintf("Step %d: %s", window.order+1, titles[window.order]),
+		})
+		if stepOrder != nil && window.order == *stepOrder {
+			stepFound = true
+			since := window.start
+			opts.Since = &since
+			opts.Until = window.end
+		}
+	}
+	if !stepFound {
+		return nil, ErrStepNotInSession
+	}
+
+	return s.terminalService.GetSessionRecording(*session.TerminalSessionID, opts)
+}
+
+// stepWindow is the stretch of a session a step was being worked on. end is
+// nil while the step has not been completed.
+type stepWindow struct {
+	order int
+	start time.Time
+	end   *time.Time
+}
+
+// stepWindows derives when each reached step was worked on from the step
+// progress, which records only completions: a step starts when the last step
+// before it was completed, the first one when the session started. Locked
+// steps were never reached and have no window.
+func stepWindows(startedAt time.Time, progress []models.ScenarioStepProgress) []stepWindow {
+	windows := make([]stepWindow, 0, len(progress))
+	start := startedAt
+	for _, p := range progress {
+		if p.Status == "locked" {
+			continue
+		}
+		windows = append(windows, stepWindow{order: p.StepOrder, start: start, end: p.CompletedAt})
+		if p.CompletedAt != nil {
+			start = *p.CompletedAt
+		}
+	}
+	return windows
+}
+
+// recordingConsentCovers reports whether a trainer of the group may replay the
+// learner's recording. The group's recording consent policy decides when it
+// sets one; a group that inherits takes its organization's. When neither
+// covers it, the learner must have acknowledged recording themselves — the
+// per-session dialog a policy that does not cover consent shows them.
+func (s *TeacherDashboardService) recordingConsentCovers(groupID uuid.UUID, learnerID string) bool {
+	var group groupModels.ClassGroup
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return false
+	}
+	if group.RecordingConsentHandled != nil && *group.RecordingConsentHandled {
+		return true
+	}
+	if group.RecordingConsentHandled == nil && group.OrganizationID != nil {
+		var org orgModels.Organization
+		if err := s.db.First(&org, "id = ?", *group.OrganizationID).Error; err == nil && org.RecordingConsentHandled {
+			return true
+		}
+	}
+
+	var settings authModels.UserSettings
+	if err := s.db.Where("user_id = ?", learnerID).First(&settings).Error; err != nil {
+		return false
+	}
+	return settings.RecordingAcknowledgedAt != nil
 }
 
 // computeSessionCorrectCounts loads the data required by
@@ -1298,11 +1583,14 @@ func (s *TeacherDashboardService) GetSessionCommands(groupID, sessionID uuid.UUI
 // for steps and explicitly via the Where clause for questions (which are
 // scanned into a small struct rather than the full model).
 //
+// The steps are those of revisionID, the revision the session ran, or of the
+// draft when it is nil.
+//
 // On any DB error this returns (0, 0); callers continue rendering the row
 // without the absolute count rather than failing the whole list.
-func computeSessionCorrectCounts(db *gorm.DB, scenarioID, sessionID uuid.UUID) (int64, int64) {
+func computeSessionCorrectCounts(db *gorm.DB, scenarioID uuid.UUID, revisionID *uuid.UUID, sessionID uuid.UUID) (int64, int64) {
 	var steps []models.ScenarioStep
-	if err := db.Where("scenario_id = ?", scenarioID).Find(&steps).Error; err != nil {
+	if err := db.Scopes(models.RevisionSteps(revisionID)).Where("scenario_id = ?", scenarioID).Find(&steps).Error; err != nil {
 		return 0, 0
 	}
 	if len(steps) == 0 {
@@ -1353,7 +1641,8 @@ func computeSessionCorrectCounts(db *gorm.DB, scenarioID, sessionID uuid.UUID) (
 // and the QuizAnswers lookup (one query for the relevant progress rows).
 // Malformed QuizAnswers JSON is logged at warn level but never propagated —
 // the questions metadata is still surfaced so the trainer view doesn't break.
-func populateQuizQuestions(db *gorm.DB, scenarioID, sessionID uuid.UUID, steps []SessionStepDetail) error {
+func populateQuizQuestions(db *gorm.DB, session *models.ScenarioSession, steps []SessionStepDetail) error {
+	sessionID := session.ID
 	// Collect step orders for quiz steps.
 	quizStepOrders := make([]int, 0)
 	for i := range steps {
@@ -1368,13 +1657,14 @@ func populateQuizQuestions(db *gorm.DB, scenarioID, sessionID uuid.UUID, steps [
 	// Load the matching ScenarioStep rows so we can resolve their IDs to
 	// fetch questions. Order is required to map back to step.Order.
 	type stepRow struct {
-		ID    uuid.UUID
-		Order int
+		ID            uuid.UUID
+		Order         int
+		QuizDrawCount int
 	}
 	var stepRows []stepRow
-	if err := db.Table("scenario_steps").
-		Select("id, \"order\"").
-		Where("scenario_id = ? AND \"order\" IN ? AND deleted_at IS NULL", scenarioID, quizStepOrders).
+	if err := db.Table("scenario_steps").Scopes(models.RevisionSteps(session.RevisionID)).
+		Select("id, \"order\", quiz_draw_count").
+		Where("scenario_id = ? AND \"order\" IN ? AND deleted_at IS NULL", session.ScenarioID, quizStepOrders).
 		Scan(&stepRows).Error; err != nil {
 		return fmt.Errorf("failed to load quiz step IDs: %w", err)
 	}
@@ -1382,10 +1672,10 @@ func populateQuizQuestions(db *gorm.DB, scenarioID, sessionID uuid.UUID, steps [
 		return nil
 	}
 
-	stepIDByOrder := make(map[int]uuid.UUID, len(stepRows))
+	stepByOrder := make(map[int]stepRow, len(stepRows))
 	stepIDs := make([]uuid.UUID, 0, len(stepRows))
 	for _, sr := range stepRows {
-		stepIDByOrder[sr.Order] = sr.ID
+		stepByOrder[sr.Order] = sr
 		stepIDs = append(stepIDs, sr.ID)
 	}
 
@@ -1423,11 +1713,11 @@ func populateQuizQuestions(db *gorm.DB, scenarioID, sessionID uuid.UUID, steps [
 		if steps[i].StepType != "quiz" {
 			continue
 		}
-		stepID, ok := stepIDByOrder[steps[i].StepOrder]
+		st, ok := stepByOrder[steps[i].StepOrder]
 		if !ok {
 			continue
 		}
-		questions := questionsByStepID[stepID]
+		questions := DrawQuizQuestions(questionsByStepID[st.ID], st.QuizDrawCount, sessionID, st.ID)
 		if len(questions) == 0 {
 			continue
 		}
@@ -1448,7 +1738,7 @@ func populateQuizQuestions(db *gorm.DB, scenarioID, sessionID uuid.UUID, steps [
 			submitted := studentAnswers[q.ID.String()]
 			isCorrect := false
 			if submitted != "" {
-				isCorrect = subtle.ConstantTimeCompare([]byte(submitted), []byte(q.CorrectAnswer)) == 1
+				isCorrect = GradeQuizAnswer(q, submitted)
 			}
 			details = append(details, dto.SessionStepQuestionDetail{
 				ID:            q.ID,
@@ -1461,6 +1751,8 @@ func populateQuizQuestions(db *gorm.DB, scenarioID, sessionID uuid.UUID, steps [
 				IsCorrect:     isCorrect,
 				Points:        q.Points,
 				Explanation:   q.Explanation,
+				Difficulty:    q.Difficulty,
+				Topic:         q.Topic,
 			})
 		}
 		steps[i].Questions = details
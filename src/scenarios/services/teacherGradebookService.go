@@ -0,0 +1,176 @@
+package services
+
+// teacherGradebookService.go — the class gradebook: one row per learner, one
+// column group per active assignment, in the shape a school's LMS imports.
+//
+// It is the class view (teacherLiveProgressService.go) without the live half:
+// the same learner list, the same assignment list, and the same attempt choice
+// (chooseLearnerAttempts), so the grade a teacher exports is the grade the
+// class view showed them.
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/models"
+)
+
+// Gradebook is a class's results on every active assignment.
+type Gradebook struct {
+	GroupID     uuid.UUID             `json:"group_id"`
+	GroupName   string                `json:"group_name"`
+	GeneratedAt time.Time             `json:"generated_at"`
+	Assignments []GradebookAssignment `json:"assignments"`
+	Learners    []GradebookLearner    `json:"learners"`
+}
+
+// GradebookAssignment is one column group of the gradebook.
+type GradebookAssignment struct {
+	AssignmentID  uuid.UUID  `json:"assignment_id"`
+	ScenarioID    uuid.UUID  `json:"scenario_id"`
+	ScenarioTitle string     `json:"scenario_title"`
+	Deadline      *time.Time `json:"deadline,omitempty"`
+}
+
+// GradebookLearner is one row of the gradebook. Results are in the same order
+// as Gradebook.Assignments.
+type GradebookLearner struct {
+	UserID    string            `json:"user_id"`
+	UserName  string            `json:"user_name,omitempty"`
+	UserEmail string            `json:"user_email,omitempty"`
+	Results   []GradebookResult `json:"results"`
+}
+
+// GradebookResult is a learner's standing on one assignment.
+//
+// Grade is only set for a completed attempt. The results table shows a partial
+// grade for a run in progress; a gradebook is what gets copied into an LMS,
+// where a half-finished run must not read as a final mark.
+type GradebookResult struct {
+	Status      string     `json:"status"` // not_started, in_progress, completed
+	Grade       *float64   `json:"grade,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// TimeSpentSeconds is wall-clock time from start to completion.
+	TimeSpentSeconds *int `json:"time_spent_seconds,omitempty"`
+	HintsUsed        int  `json:"hints_used"`
+	VerifyAttempts   int  `json:"verify_attempts"`
+	// QuizScore is the mean score over the attempt's submitted quiz steps, as a
+	// percentage. Nil when the scenario has no quiz or none was submitted.
+	QuizScore *float64 `json:"quiz_score,omitempty"`
+	// SubmittedLate marks an attempt completed after the learner's deadline;
+	// Grade is then net of any late penalty.
+	SubmittedLate bool `json:"submitted_late,omitempty"`
+}
+
+// GetGroupGradebook assembles the gradebook of groupID. Staff memberships get
+// no row, as in the class view. Like GetGroupLiveProgress, the query count does
+// not grow with class size.
+func (s *TeacherDashboardService) GetGroupGradebook(groupID uuid.UUID) (*Gradebook, error) {
+	var group groupModels.ClassGroup
+	if err := s.db.Where("id = ?", groupID).First(&group).Error; err != nil {
+		return nil, fmt.Errorf("group not found: %w", err)
+	}
+
+	gradebook := &Gradebook{
+		GroupID:     group.ID,
+		GroupName:   group.DisplayName,
+		GeneratedAt: time.Now(),
+		Assignments: []GradebookAssignment{},
+		Learners:    []GradebookLearner{},
+	}
+	if gradebook.GroupName == "" {
+		gradebook.GroupName = group.Name
+	}
+
+	var learnerIDs []string
+	if err := s.db.Model(&groupModels.GroupMember{}).
+		Where("group_id = ? AND is_active = ?", groupID, true).
+		Scopes(groupModels.LearnerRoleScope("group_members")).
+		Order("user_id ASC").
+		Pluck("user_id", &learnerIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load class learners: %w", err)
+	}
+
+	assignmentsByGroup, err := s.activeAssignmentsByGroup([]uuid.UUID{groupID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load class assignments: %w", err)
+	}
+	assignments := assignmentsByGroup[groupID]
+	for _, a := range assignments {
+		gradebook.Assignments = append(gradebook.Assignments, GradebookAssignment{
+			AssignmentID:  a.AssignmentID,
+			ScenarioID:    a.ScenarioID,
+			ScenarioTitle: a.ScenarioTitle,
+			Deadline:      a.Deadline,
+		})
+	}
+
+	attempts, err := s.chooseLearnerAttempts(learnerIDs, assignedScenarioIDs(assignments))
+	if err != nil {
+		return nil, err
+	}
+	sessionIDs := make([]uuid.UUID, 0, len(attempts))
+	for _, attempt := range attempts {
+		sessionIDs = append(sessionIDs, attempt.SessionID)
+	}
+	progressBySession, err := s.stepProgressBySession(sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userID := range learnerIDs {
+		learner := GradebookLearner{UserID: userID, Results: make([]GradebookResult, 0, len(assignments))}
+		for _, a := range assignments {
+			attempt, started := attempts[learnerScenarioKey{userID: userID, scenarioID: a.ScenarioID}]
+			if !started {
+				learner.Results = append(learner.Results, GradebookResult{Status: LearnerStatusNotStarted})
+				continue
+			}
+			learner.Results = append(learner.Results, buildGradebookResult(attempt, progressBySession[attempt.SessionID]))
+		}
+		gradebook.Learners = append(gradebook.Learners, learner)
+	}
+	resolveUserIdentities(gradebook.Learners,
+		func(row GradebookLearner) string { return row.UserID },
+		func(row *GradebookLearner, info userInfo) {
+			row.UserName, row.UserEmail = info.Name, info.Email
+		})
+
+	return gradebook, nil
+}
+
+// buildGradebookResult folds one attempt and its step progress into a cell.
+func buildGradebookResult(attempt learnerSessionRow, progress []models.ScenarioStepProgress) GradebookResult {
+	result := GradebookResult{
+		Status:    LearnerStatusInProgress,
+		HintsUsed: attempt.HintsUsed,
+	}
+	if attempt.Status == sessionStatusCompleted {
+		result.Status = LearnerStatusCompleted
+		result.Grade = attempt.Grade
+		result.CompletedAt = attempt.CompletedAt
+		result.SubmittedLate = attempt.SubmittedLate
+		if attempt.CompletedAt != nil {
+			seconds := int(attempt.CompletedAt.Sub(attempt.StartedAt).Seconds())
+			result.TimeSpentSeconds = &seconds
+		}
+	}
+
+	var quizTotal float64
+	quizCount := 0
+	for _, p := range progress {
+		result.VerifyAttempts += p.VerifyAttempts
+		if p.QuizScore != nil {
+			quizTotal += *p.QuizScore
+			quizCount++
+		}
+	}
+	if quizCount > 0 {
+		score := quizTotal / float64(quizCount) * 100
+		result.QuizScore = &score
+	}
+	return result
+}
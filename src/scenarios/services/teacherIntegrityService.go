@@ -0,0 +1,523 @@
+package services
+
+// teacherIntegrityService.go — the integrity report of one assignment: which
+// learners of a class look like they shared their work. Three signals, from
+// the strongest:
+//
+//   - Flag sharing. A generated flag is an HMAC of the session, the step and the
+//     user (FlagService.computeFlag), so it is valid in exactly one session. A
+//     wrong submission equal to another session's flag was copied from that
+//     session, and the report names both ends.
+//   - Identical typos. Two learners who mistype a command the same way, when the
+//     rest of the class typed it correctly.
+//   - Command sequence similarity. Runs of consecutive commands two learners
+//     have in common and nobody else in the class typed.
+//
+// The scenario's expected solution is in every honest history, so the last two
+// signals only count what a pair shares exclusively. Nothing here is a verdict:
+// the report is for a teacher to read next to the command histories, which is
+// why every finding carries the sessions it was found in.
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/models"
+)
+
+// Suspicion levels of a similar pair.
+const (
+	SuspicionHigh   = "high"
+	SuspicionMedium = "medium"
+)
+
+const (
+	// integrityRunLength is how many consecutive commands make a run.
+	integrityRunLength = 3
+	// integrityMinRuns is the shortest history, in runs, worth comparing: below
+	// it any overlap is too small a sample to mean anything.
+	integrityMinRuns = 5
+	// Similarity thresholds for a pair to be reported as medium / high.
+	integrityMediumSimilarity = 0.25
+	integrityHighSimilarity   = 0.5
+	// integrityMaxTypoLength bounds the commands compared for typos, which keeps
+	// the edit-distance work small; long commands are pasted, not mistyped.
+	integrityMaxTypoLength = 120
+	// integrityHistoryLimit is tt-backend's cap on one JSON history page.
+	integrityHistoryLimit = 1000
+)
+
+// IntegrityReport is the suspicion report of one assignment of a class.
+type IntegrityReport struct {
+	GroupID     uuid.UUID `json:"group_id"`
+	ScenarioID  uuid.UUID `json:"scenario_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// SessionsAnalyzed counts the learners' non-preview sessions on the scenario.
+	SessionsAnalyzed int `json:"sessions_analyzed"`
+	// HistoryUnavailable lists the sessions whose command history could not be
+	// read (tt-backend unreachable, history erased). They are still checked
+	// for flag sharing.
+	HistoryUnavailable []uuid.UUID          `json:"history_unavailable"`
+	FlagSharing        []FlagSharingFinding `json:"flag_sharing"`
+	SimilarPairs       []SimilarPairFinding `json:"similar_pairs"`
+}
+
+// FlagSharingFinding is a submission of a flag generated for another session.
+type FlagSharingFinding struct {
+	UserID      string    `json:"user_id"`
+	UserName    string    `json:"user_name,omitempty"`
+	SessionID   uuid.UUID `json:"session_id"`
+	StepOrder   int       `json:"step_order"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	// SourceUserID is the learner the flag was generated for. It and
+	// SourceSessionID are empty when that learner is not in this class: the
+	// teacher learns that the flag came from outside, not whose it was.
+	SourceUserID    string     `json:"source_user_id,omitempty"`
+	SourceUserName  string     `json:"source_user_name,omitempty"`
+	SourceSessionID *uuid.UUID `json:"source_session_id,omitempty"`
+	SourceInClass   bool       `json:"source_in_class"`
+}
+
+// SimilarPairFinding is two learners whose command histories overlap more than
+// the assignment explains.
+type SimilarPairFinding struct {
+	UserA      string      `json:"user_a"`
+	UserAName  string      `json:"user_a_name,omitempty"`
+	SessionsA  []uuid.UUID `json:"sessions_a"`
+	UserB      string      `json:"user_b"`
+	UserBName  string      `json:"user_b_name,omitempty"`
+	SessionsB  []uuid.UUID `json:"sessions_b"`
+	Similarity float64     `json:"similarity"`
+	// SharedRuns is how many runs of consecutive commands only these two typed.
+	SharedRuns     int      `json:"shared_runs"`
+	IdenticalTypos []string `json:"identical_typos,omitempty"`
+	Suspicion      string   `json:"suspicion"`
+}
+
+// integritySession is a class session under analysis.
+type integritySession struct {
+	ID                uuid.UUID
+	UserID            string
+	TerminalSessionID *string
+}
+
+// GetScenarioIntegrityReport analyses the class's sessions on scenarioID. Only
+// an assignment of the class can be analysed (ErrScenarioNotAssignedToGroup
+// otherwise), and only learners' sessions are: staff memberships are not
+// suspects.
+func (s *TeacherDashboardService) GetScenarioIntegrityReport(groupID, scenarioID uuid.UUID) (*IntegrityReport, error) {
+	var assignmentCount int64
+	if err := s.db.Table("scenario_assignments").
+		Where("group_id = ? AND scenario_id = ? AND deleted_at IS NULL", groupID, scenarioID).
+		Count(&assignmentCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to check scenario assignment: %w", err)
+	}
+	if assignmentCount == 0 {
+		return nil, ErrScenarioNotAssignedToGroup
+	}
+
+	report := &IntegrityReport{
+		GroupID:            groupID,
+		ScenarioID:         scenarioID,
+		GeneratedAt:        time.Now(),
+		HistoryUnavailable: []uuid.UUID{},
+		FlagSharing:        []FlagSharingFinding{},
+		SimilarPairs:       []SimilarPairFinding{},
+	}
+
+	var learnerIDs []string
+	if err := s.db.Model(&groupModels.GroupMember{}).
+		Where("group_id = ? AND is_active = ?", groupID, true).
+		Scopes(groupModels.LearnerRoleScope("group_members")).
+		Pluck("user_id", &learnerIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load class learners: %w", err)
+	}
+	if len(learnerIDs) == 0 {
+		return report, nil
+	}
+
+	var sessions []integritySession
+	if err := s.db.Model(&models.ScenarioSession{}).
+		Select("id, user_id, terminal_session_id").
+		Where("scenario_id = ? AND user_id IN ? AND is_preview = ?", scenarioID, learnerIDs, false).
+		Order("started_at ASC").
+		Scan(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load class sessions: %w", err)
+	}
+	report.SessionsAnalyzed = len(sessions)
+	if len(sessions) == 0 {
+		return report, nil
+	}
+
+	flagSharing, err := s.findFlagSharing(scenarioID, sessions)
+	if err != nil {
+		return nil, err
+	}
+	report.FlagSharing = flagSharing
+
+	histories := make(map[string][][]string)
+	sessionsByUser := make(map[string][]uuid.UUID)
+	for _, session := range sessions {
+		sessionsByUser[session.UserID] = append(sessionsByUser[session.UserID], session.ID)
+		if session.TerminalSessionID == nil || *session.TerminalSessionID == "" {
+			continue
+		}
+		commands, err := s.loadCommandSequence(*session.TerminalSessionID)
+		if err != nil {
+			slog.Warn("integrity report: command history unavailable", "session_id", session.ID, "err", err)
+			report.HistoryUnavailable = append(report.HistoryUnavailable, session.ID)
+			continue
+		}
+		histories[session.UserID] = append(histories[session.UserID], commands)
+	}
+	for _, pair := range CompareCommandHistories(histories) {
+		pair.SessionsA = sessionsByUser[pair.UserA]
+		pair.SessionsB = sessionsByUser[pair.UserB]
+		report.SimilarPairs = append(report.SimilarPairs, pair)
+	}
+
+	s.resolveIntegrityNames(report)
+	return report, nil
+}
+
+// findFlagSharing matches the wrong flag submissions of the class's sessions
+// against the flags generated for every session of the scenario.
+//
+// Submissions come from the wrong-answer log and, for the time before the log
+// existed, from the last submission ScenarioFlag keeps. Only generated flags
+// are considered: a scenario-chosen answer is the same for everyone.
+func (s *TeacherDashboardService) findFlagSharing(scenarioID uuid.UUID, sessions []integritySession) ([]FlagSharingFinding, error) {
+	sessionIDs := make([]uuid.UUID, 0, len(sessions))
+	userBySession := make(map[uuid.UUID]string, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+		userBySession[session.ID] = session.UserID
+	}
+
+	type submission struct {
+		SessionID     uuid.UUID
+		StepOrder     int
+		SubmittedFlag string
+		SubmittedAt   time.Time
+	}
+	var logged []submission
+	if err := s.db.Model(&models.ScenarioFlagAttempt{}).
+		Select("session_id, step_order, submitted_flag, submitted_at").
+		Where("session_id IN ?", sessionIDs).
+		Order("submitted_at ASC").
+		Scan(&logged).Error; err != nil {
+		return nil, fmt.Errorf("failed to load flag submissions: %w", err)
+	}
+	var lastWrong []models.ScenarioFlag
+	if err := s.db.Where("session_id IN ? AND is_correct = ? AND submitted_flag IS NOT NULL", sessionIDs, false).
+		Find(&lastWrong).Error; err != nil {
+		return nil, fmt.Errorf("failed to load flag submissions: %w", err)
+	}
+	for _, flag := range lastWrong {
+		sub := submission{SessionID: flag.SessionID, StepOrder: flag.StepOrder, SubmittedFlag: *flag.SubmittedFlag}
+		if flag.SubmittedAt != nil {
+			sub.SubmittedAt = *flag.SubmittedAt
+		}
+		logged = append(logged, sub)
+	}
+
+	type submissionKey struct {
+		sessionID uuid.UUID
+		stepOrder int
+		value     string
+	}
+	seen := make(map[submissionKey]bool)
+	var candidates []submission
+	var values []string
+	for _, sub := range logged {
+		sub.SubmittedFlag = strings.TrimSpace(sub.SubmittedFlag)
+		if !strings.HasPrefix(sub.SubmittedFlag, generatedFlagPrefix) {
+			continue
+		}
+		key := submissionKey{sub.SessionID, sub.StepOrder, sub.SubmittedFlag}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, sub)
+		values = append(values, sub.SubmittedFlag)
+	}
+	if len(candidates) == 0 {
+		return []FlagSharingFinding{}, nil
+	}
+
+	var owners []struct {
+		SessionID    uuid.UUID
+		UserID       string
+		ExpectedFlag string
+	}
+	if err := s.db.Table("scenario_flags sf").
+		Select("sf.session_id, ss.user_id, sf.expected_flag").
+		Joins("JOIN scenario_sessions ss ON ss.id = sf.session_id").
+		Where("ss.scenario_id = ? AND sf.expected_flag IN ? AND sf.deleted_at IS NULL", scenarioID, values).
+		Scan(&owners).Error; err != nil {
+		return nil, fmt.Errorf("failed to match submitted flags: %w", err)
+	}
+
+	findings := []FlagSharingFinding{}
+	for _, sub := range candidates {
+		submitter := userBySession[sub.SessionID]
+		for _, owner := range owners {
+			// A learner retaking the scenario with their own old flag is
+			// confused, not cheating.
+			if owner.ExpectedFlag != sub.SubmittedFlag || owner.SessionID == sub.SessionID || owner.UserID == submitter {
+				continue
+			}
+			finding := FlagSharingFinding{
+				UserID:      submitter,
+				SessionID:   sub.SessionID,
+				StepOrder:   sub.StepOrder,
+				SubmittedAt: sub.SubmittedAt,
+			}
+			if _, inClass := userBySession[owner.SessionID]; inClass {
+				sourceSession := owner.SessionID
+				finding.SourceUserID = owner.UserID
+				finding.SourceSessionID = &sourceSession
+				finding.SourceInClass = true
+			}
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].SubmittedAt.Before(findings[j].SubmittedAt)
+	})
+	return findings, nil
+}
+
+// loadCommandSequence reads a terminal session's commands, in the order they
+// were typed, through the same tt-backend admin endpoint as the per-session
+// command view.
+func (s *TeacherDashboardService) loadCommandSequence(terminalSessionID string) ([]string, error) {
+	body, _, err := s.terminalService.GetSessionCommandHistoryAdmin(terminalSessionID, integrityHistoryLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+	var history struct {
+		Commands []struct {
+			SequenceNum int    `json:"sequence_num"`
+			CommandText string `json:"command_text"`
+		} `json:"commands"`
+	}
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, fmt.Errorf("invalid command history: %w", err)
+	}
+	sort.SliceStable(history.Commands, func(i, j int) bool {
+		return history.Commands[i].SequenceNum < history.Commands[j].SequenceNum
+	})
+	commands := make([]string, 0, len(history.Commands))
+	for _, cmd := range history.Commands {
+		if normalized := normalizeCommand(cmd.CommandText); normalized != "" {
+			commands = append(commands, normalized)
+		}
+	}
+	return commands, nil
+}
+
+// normalizeCommand collapses whitespace, so "ls  -la" and "ls -la " compare equal.
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// CompareCommandHistories scores every pair of learners on what they alone
+// share. histories maps a learner to their sessions' command sequences; a
+// learner with several sessions is compared on all of them together. Pairs
+// below the medium thresholds are left out. Results are sorted, most
+// suspicious first.
+func CompareCommandHistories(histories map[string][][]string) []SimilarPairFinding {
+	runsByUser := make(map[string]map[string]bool, len(histories))
+	commandsByUser := make(map[string]map[string]bool, len(histories))
+	runUsers := make(map[string][]string)
+	commandUsers := make(map[string][]string)
+
+	users := make([]string, 0, len(histories))
+	for user := range histories {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	for _, user := range users {
+		runs := make(map[string]bool)
+		commands := make(map[string]bool)
+		for _, sequence := range histories[user] {
+			for i, command := range sequence {
+				commands[command] = true
+				if i+integrityRunLength <= len(sequence) {
+					runs[strings.Join(sequence[i:i+integrityRunLength], "\n")] = true
+				}
+			}
+		}
+		runsByUser[user] = runs
+		commandsByUser[user] = commands
+		for run := range runs {
+			runUsers[run] = append(runUsers[run], user)
+		}
+		for command := range commands {
+			commandUsers[command] = append(commandUsers[command], user)
+		}
+	}
+
+	type pairKey struct{ a, b string }
+	sharedRuns := make(map[pairKey]int)
+	for _, holders := range runUsers {
+		if len(holders) == 2 {
+			sharedRuns[pairKey{holders[0], holders[1]}]++
+		}
+	}
+
+	// A typo is a command exactly one pair typed that is a near miss of a
+	// command typed by several learners, someone outside the pair among them.
+	var reference []string
+	for command, holders := range commandUsers {
+		if len(holders) >= 2 && len(command) <= integrityMaxTypoLength {
+			reference = append(reference, command)
+		}
+	}
+	typos := make(map[pairKey][]string)
+	for command, holders := range commandUsers {
+		if len(holders) != 2 || len(command) > integrityMaxTypoLength {
+			continue
+		}
+		for _, ref := range reference {
+			if ref == command || !hasHolderOutside(commandUsers[ref], holders[0], holders[1]) {
+				continue
+			}
+			if isNearMiss(command, ref) {
+				key := pairKey{holders[0], holders[1]}
+				typos[key] = append(typos[key], command)
+				break
+			}
+		}
+	}
+
+	var findings []SimilarPairFinding
+	for i, a := range users {
+		for _, b := range users[i+1:] {
+			key := pairKey{a, b}
+			shorter := min(len(runsByUser[a]), len(runsByUser[b]))
+			similarity := 0.0
+			if shorter >= integrityMinRuns {
+				similarity = float64(sharedRuns[key]) / float64(shorter)
+			}
+			pairTypos := typos[key]
+			sort.Strings(pairTypos)
+
+			suspicion := ""
+			switch {
+			case similarity >= integrityHighSimilarity || len(pairTypos) >= 2:
+				suspicion = SuspicionHigh
+			case similarity >= integrityMediumSimilarity || len(pairTypos) == 1:
+				suspicion = SuspicionMedium
+			default:
+				continue
+			}
+			findings = append(findings, SimilarPairFinding{
+				UserA:          a,
+				UserB:          b,
+				Similarity:     similarity,
+				SharedRuns:     sharedRuns[key],
+				IdenticalTypos: pairTypos,
+				Suspicion:      suspicion,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Suspicion != findings[j].Suspicion {
+			return findings[i].Suspicion == SuspicionHigh
+		}
+		return findings[i].Similarity > findings[j].Similarity
+	})
+	return findings
+}
+
+func hasHolderOutside(holders []string, a, b string) bool {
+	for _, holder := range holders {
+		if holder != a && holder != b {
+			return true
+		}
+	}
+	return false
+}
+
+// isNearMiss reports whether typed is one slip away from intended: one edit for
+// a short command, two for a longer one. Swapped letters count as one edit.
+func isNearMiss(typed, intended string) bool {
+	maxEdits := 1
+	if len(intended) >= 8 {
+		maxEdits = 2
+	}
+	if d := len(typed) - len(intended); d > maxEdits || -d > maxEdits {
+		return false
+	}
+	return editDistance(typed, intended) <= maxEdits
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+// resolveIntegrityNames fills in the learners' display names.
+func (s *TeacherDashboardService) resolveIntegrityNames(report *IntegrityReport) {
+	seen := make(map[string]bool)
+	var userIDs []string
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+	for _, f := range report.FlagSharing {
+		add(f.UserID)
+		add(f.SourceUserID)
+	}
+	for _, p := range report.SimilarPairs {
+		add(p.UserA)
+		add(p.UserB)
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	infos := fetchUserMap(userIDs)
+	for i := range report.FlagSharing {
+		report.FlagSharing[i].UserName = infos[report.FlagSharing[i].UserID].Name
+		report.FlagSharing[i].SourceUserName = infos[report.FlagSharing[i].SourceUserID].Name
+	}
+	for i := range report.SimilarPairs {
+		report.SimilarPairs[i].UserAName = infos[report.SimilarPairs[i].UserA].Name
+		report.SimilarPairs[i].UserBName = infos[report.SimilarPairs[i].UserB].Name
+	}
+}
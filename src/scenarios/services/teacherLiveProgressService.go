@@ -54,6 +54,12 @@ const LearnerIdleThreshold = 10 * time.Minute
 // the scenario_sessions row is aliased `ss`.
 const sessionHintsUsedExpr = `(SELECT COALESCE(SUM(hints_revealed), 0) FROM scenario_step_progress WHERE session_id = ss.id)`
 
+// sessionStepCountExpr counts the steps of the version of its scenario the
+// session runs — its revision, or the draft — the SQL form of
+// models.RevisionSteps. Assumes the scenario_sessions row is aliased `ss`.
+const sessionStepCountExpr = `(SELECT COUNT(*) FROM scenario_steps st WHERE st.scenario_id = ss.scenario_id AND st.deleted_at IS NULL
+		AND COALESCE(st.revision_id, st.scenario_id) = COALESCE(ss.revision_id, ss.scenario_id))`
+
 // LearnerLiveProgress is one row of the class view: who the learner is, whether
 // they are present, and where they stand on each of the class's assignments.
 type LearnerLiveProgress struct {
@@ -131,10 +137,20 @@ type LearnerAssignmentProgress struct {
 // error: the caller has already cleared Layer 2, so "nothing to show" is an
 // answer, not a fault.
 func (s *TeacherDashboardService) GetGroupLiveProgress(groupID uuid.UUID) ([]LearnerLiveProgress, error) {
+	group, learnerIDs, err := s.liveProgressClass(groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.liveProgressRows(group, learnerIDs)
+}
+
+// liveProgressClass loads a class and its active apprenants, in user id order.
+// A group that is gone comes back with no learner.
+func (s *TeacherDashboardService) liveProgressClass(groupID uuid.UUID) (groupModels.ClassGroup, []string, error) {
 	var group groupModels.ClassGroup
 	if err := s.db.Where("id = ?", groupID).First(&group).Error; err != nil {
 		// A well-formed id for a group that is gone lists nothing.
-		return []LearnerLiveProgress{}, nil
+		return group, nil, nil
 	}
 
 	var learnerIDs []string
@@ -143,8 +159,14 @@ func (s *TeacherDashboardService) GetGroupLiveProgress(groupID uuid.UUID) ([]Lea
 		Scopes(groupModels.LearnerRoleScope("group_members")).
 		Order("user_id ASC").
 		Pluck("user_id", &learnerIDs).Error; err != nil {
-		return nil, fmt.Errorf("failed to load class learners: %w", err)
+		return group, nil, fmt.Errorf("failed to load class learners: %w", err)
 	}
+	return group, learnerIDs, nil
+}
+
+// liveProgressRows builds the class view rows of learnerIDs, which must be
+// apprenants of group — the whole class, or the few a live stream refreshes.
+func (s *TeacherDashboardService) liveProgressRows(group groupModels.ClassGroup, learnerIDs []string) ([]LearnerLiveProgress, error) {
 	if len(learnerIDs) == 0 {
 		return []LearnerLiveProgress{}, nil
 	}
@@ -204,6 +226,9 @@ type learnerSessionRow struct {
 	StartedAt   time.Time
 	CompletedAt *time.Time
 	HintsUsed   int
+	// SubmittedLate marks an attempt completed after the learner's
+	// assignment deadline.
+	SubmittedLate bool
 	// LastStepCompletedAt is when the learner finished their most recent step —
 	// i.e. when they entered the one they are on now.
 	LastStepCompletedAt *time.Time
@@ -330,7 +355,7 @@ func (s *TeacherDashboardService) chooseLearnerAttempts(learnerIDs []string, sce
 	var rows []learnerSessionRow
 	err := s.db.Raw(`
 		SELECT ss.id as session_id, ss.user_id, ss.scenario_id, ss.status, ss.grade,
-		       ss.current_step, ss.started_at, ss.completed_at,
+		       ss.current_step, ss.started_at, ss.completed_at, ss.submitted_late,
 		       `+sessionHintsUsedExpr+` as hints_used
 		FROM scenario_sessions ss
 		WHERE ss.user_id IN ? AND ss.scenario_id IN ?
@@ -429,7 +454,9 @@ func attemptRank(status string) int {
 //
 // The duplicate-order rule is the one loadScenarioGraph and GetSessionDetail
 // already follow — nothing enforces (scenario_id, order) as unique, so the first
-// row wins rather than a JOIN multiplying the result set.
+// row wins rather than a JOIN multiplying the result set. The steps indexed
+// are the published ones, which the class's sessions run unless they started
+// on an earlier revision.
 func (s *TeacherDashboardService) stepIndexByScenario(scenarioIDs []uuid.UUID) (map[uuid.UUID]scenarioStepIndex, error) {
 	index := make(map[uuid.UUID]scenarioStepIndex, len(scenarioIDs))
 	if len(scenarioIDs) == 0 {
@@ -438,6 +465,7 @@ func (s *TeacherDashboardService) stepIndexByScenario(scenarioIDs []uuid.UUID) (
 
 	var steps []models.ScenarioStep
 	if err := s.db.Select("id", "scenario_id", `"order"`, "title").
+		Scopes(models.PublishedSteps).
 		Where("scenario_id IN ?", scenarioIDs).
 		Order(`"order" ASC, id ASC`).
 		Find(&steps).Error; err != nil {
@@ -0,0 +1,217 @@
+package services
+
+// teacherLiveProgressStream.go — the push form of GetGroupLiveProgress. A
+// class view polling live-progress recomputes the whole class every few
+// seconds for every trainer watching; the stream computes it once, then only
+// recomputes the learners the liveprogress bus names, and only sends the rows
+// that actually changed.
+//
+// The bus is per process and drops events for a subscriber that falls behind,
+// so the stream also resynchronises the whole class on a timer (and at once
+// after a drop). Presence and the idle flag also move with the clock alone —
+// a terminal expiring, a learner going quiet — and the resync is what catches
+// those.
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/liveprogress"
+)
+
+// Stream event names.
+const (
+	// LiveProgressEventSnapshot carries every row of the class. Always first.
+	LiveProgressEventSnapshot = "snapshot"
+	// LiveProgressEventProgress carries the rows that changed since the last
+	// message and the learners that left the class.
+	LiveProgressEventProgress = "progress"
+	// LiveProgressEventPing carries nothing; it keeps proxies from closing an
+	// idle stream.
+	LiveProgressEventPing = "ping"
+)
+
+const (
+	// liveProgressDebounce groups the events of a burst (a flag submission
+	// advancing a step touches the session twice) into one recompute.
+	liveProgressDebounce = 500 * time.Millisecond
+	// liveProgressResyncInterval bounds how stale a stream can get on what no
+	// event reports.
+	liveProgressResyncInterval = time.Minute
+	// liveProgressPingInterval stays under the usual 60s proxy idle timeout.
+	liveProgressPingInterval = 25 * time.Second
+)
+
+// LiveProgressMessage is the payload of the snapshot and progress events.
+// CurrentStepElapsedSeconds in Rows is as of GeneratedAt: a row is not resent
+// just because that counter moved, so clients add the time since GeneratedAt.
+type LiveProgressMessage struct {
+	Rows        []LearnerLiveProgress `json:"rows"`
+	Removed     []string              `json:"removed,omitempty"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// LiveProgressEmitter writes one stream event. An error ends the stream.
+type LiveProgressEmitter func(event string, payload any) error
+
+// StreamGroupLiveProgress pushes the class view of groupID to emit until ctx
+// is done: a snapshot, then progress deltas. It returns nil when ctx ends and
+// the first error of emit or of the initial snapshot otherwise; a failed
+// recompute later on is logged and retried at the next event or resync.
+func (s *TeacherDashboardService) StreamGroupLiveProgress(ctx context.Context, groupID uuid.UUID, emit LiveProgressEmitter) error {
+	// Subscribe before the snapshot, so nothing that happens while it is
+	// computed is missed.
+	sub := liveprogress.Subscribe()
+	defer sub.Close()
+
+	stream := &liveProgressStream{service: s, groupID: groupID}
+	snapshot, err := stream.resync()
+	if err != nil {
+		return err
+	}
+	if err := emit(LiveProgressEventSnapshot, snapshot); err != nil {
+		return err
+	}
+
+	resync := time.NewTicker(liveProgressResyncInterval)
+	defer resync.Stop()
+	ping := time.NewTicker(liveProgressPingInterval)
+	defer ping.Stop()
+
+	pending := make(map[string]bool)
+	var flush <-chan time.Time
+	for {
+		var message *LiveProgressMessage
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if !stream.learners[event.UserID] {
+				continue
+			}
+			pending[event.UserID] = true
+			if flush == nil {
+				flush = time.After(liveProgressDebounce)
+			}
+			continue
+		case <-flush:
+			flush = nil
+			if sub.TakeLagged() {
+				message, err = stream.resync()
+			} else {
+				message, err = stream.refresh(pending)
+			}
+			pending = make(map[string]bool)
+		case <-resync.C:
+			sub.TakeLagged()
+			message, err = stream.resync()
+		case <-ping.C:
+			if err := emit(LiveProgressEventPing, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err != nil {
+			slog.Warn("failed to refresh live class view", "group_id", groupID, "err", err)
+			continue
+		}
+		if len(message.Rows) == 0 && len(message.Removed) == 0 {
+			continue
+		}
+		if err := emit(LiveProgressEventProgress, message); err != nil {
+			return err
+		}
+	}
+}
+
+// liveProgressStream is the state of one open stream: the class as last
+// loaded and, per learner, the fingerprint of the row last sent.
+type liveProgressStream struct {
+	service  *TeacherDashboardService
+	groupID  uuid.UUID
+	group    groupModels.ClassGroup
+	learners map[string]bool
+	sent     map[string]string
+}
+
+// resync reloads the whole class. The first call returns every row; later
+// ones return the rows that changed and the learners no longer in the class.
+func (st *liveProgressStream) resync() (*LiveProgressMessage, error) {
+	group, learnerIDs, err := st.service.liveProgressClass(st.groupID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.service.liveProgressRows(group, learnerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.sent == nil {
+		st.sent = make(map[string]string, len(rows))
+	}
+	st.group = group
+	st.learners = make(map[string]bool, len(learnerIDs))
+	for _, id := range learnerIDs {
+		st.learners[id] = true
+	}
+
+	message := &LiveProgressMessage{Rows: st.changed(rows), GeneratedAt: time.Now()}
+	for id := range st.sent {
+		if !st.learners[id] {
+			message.Removed = append(message.Removed, id)
+			delete(st.sent, id)
+		}
+	}
+	return message, nil
+}
+
+// refresh recomputes the rows of the given learners and returns those that
+// changed.
+func (st *liveProgressStream) refresh(userIDs map[string]bool) (*LiveProgressMessage, error) {
+	ids := make([]string, 0, len(userIDs))
+	for id := range userIDs {
+		if st.learners[id] {
+			ids = append(ids, id)
+		}
+	}
+	rows, err := st.service.liveProgressRows(st.group, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &LiveProgressMessage{Rows: st.changed(rows), GeneratedAt: time.Now()}, nil
+}
+
+// changed keeps the rows whose fingerprint differs from the one last sent,
+// and records the new fingerprints.
+func (st *liveProgressStream) changed(rows []LearnerLiveProgress) []LearnerLiveProgress {
+	out := make([]LearnerLiveProgress, 0, len(rows))
+	for _, row := range rows {
+		fingerprint := liveProgressFingerprint(row)
+		if st.sent[row.UserID] == fingerprint {
+			continue
+		}
+		st.sent[row.UserID] = fingerprint
+		out = append(out, row)
+	}
+	return out
+}
+
+// liveProgressFingerprint is a row's content minus the elapsed-time counters,
+// which move every second without anything having happened.
+func liveProgressFingerprint(row LearnerLiveProgress) string {
+	row.Assignments = append([]LearnerAssignmentProgress(nil), row.Assignments...)
+	for i := range row.Assignments {
+		row.Assignments[i].CurrentStepElapsedSeconds = nil
+	}
+	encoded, _ := json.Marshal(row)
+	return string(encoded)
+}
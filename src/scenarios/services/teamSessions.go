@@ -0,0 +1,213 @@
+package services
+
+// teamSessions.go — scenario runs on a shared terminal. The team plays through
+// one session, the lead's: the run of the learner whose terminal it is. Every
+// other member gets a session of their own that mirrors the lead's, so the run
+// shows up in their sessions, the trainer's gradebook and the completion
+// webhooks with the team's grade, without the engine having to know about
+// teams anywhere but here.
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"soli/formations/src/scenarios/models"
+)
+
+// ErrTeamLeadNotRunning is returned when a team is formed on a run that has
+// ended, or on a session that is itself a team member's mirror.
+var ErrTeamLeadNotRunning = errors.New("only a learner's own run in progress can be shared with a team")
+
+// ErrTeamMemberBusy is returned when a learner joining a team already has a
+// run of the scenario in progress: they would end up with two.
+var ErrTeamMemberBusy = errors.New("a team member already has a run of this scenario in progress")
+
+// teamSyncedColumns are the columns a member's session takes from the lead's.
+var teamSyncedColumns = []string{
+	"status", "provisioning_phase", "current_step", "completed_at", "grade",
+	"timed_out", "submitted_late", "late_penalty_percent", "grade_before_penalty",
+}
+
+// JoinTeamSession gives each learner a session mirroring the lead session, for
+// the scenario's run on the lead's shared terminal. Learners already in the
+// team keep their session. The member sessions are returned by user.
+func (s *ScenarioSessionService) JoinTeamSession(leadID uuid.UUID, userIDs []string) (map[string]uuid.UUID, error) {
+	members := map[string]uuid.UUID{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var lead models.ScenarioSession
+		if err := tx.Preload("StepProgress").First(&lead, "id = ?", leadID).Error; err != nil {
+			return err
+		}
+		if lead.TeamLeadSessionID != nil || requireActiveSession(&lead) != nil && lead.Status != statusProvisioning {
+			return ErrTeamLeadNotRunning
+		}
+
+		for _, userID := range userIDs {
+			if userID == lead.UserID {
+				continue
+			}
+			var existing models.ScenarioSession
+			err := tx.Where("user_id = ? AND team_lead_session_id = ?", userID, lead.ID).First(&existing).Error
+			if err == nil {
+				members[userID] = existing.ID
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			var busy int64
+			if err := tx.Model(&models.ScenarioSession{}).
+				Where("user_id = ? AND scenario_id = ? AND status IN ?", userID, lead.ScenarioID,
+					[]string{statusActive, statusInProgress, statusProvisioning}).
+				Count(&busy).Error; err != nil {
+				return err
+			}
+			if busy > 0 {
+				return fmt.Errorf("%w: %s", ErrTeamMemberBusy, userID)
+			}
+
+			member := models.ScenarioSession{
+				ScenarioID:        lead.ScenarioID,
+				UserID:            userID,
+				TerminalSessionID: lead.TerminalSessionID,
+				CurrentStep:       lead.CurrentStep,
+				Status:            lead.Status,
+				ProvisioningPhase: lead.ProvisioningPhase,
+				StartedAt:         lead.StartedAt,
+				TrainerID:         lead.TrainerID,
+				AssignmentID:      lead.AssignmentID,
+				ExamMode:          lead.ExamMode,
+				ExpiresAt:         lead.ExpiresAt,
+				RevisionID:        lead.RevisionID,
+				TeamLeadSessionID: &lead.ID,
+			}
+			if err := tx.Omit("StepProgress", "Flags", "Scenario").Create(&member).Error; err != nil {
+				return fmt.Errorf("failed to create the team member's session: %w", err)
+			}
+			if err := copyTeamStepProgress(tx, lead.StepProgress, member.ID); err != nil {
+				return err
+			}
+			members[userID] = member.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// TeamLeadSession returns the session the team plays through for a session:
+// the lead's for a member's mirror, the session itself otherwise.
+func (s *ScenarioSessionService) TeamLeadSession(session *models.ScenarioSession) (*models.ScenarioSession, error) {
+	if session.TeamLeadSessionID == nil {
+		return session, nil
+	}
+	var lead models.ScenarioSession
+	if err := s.db.First(&lead, "id = ?", *session.TeamLeadSessionID).Error; err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+// TeamMemberSession returns userID's mirror of a lead session.
+func (s *ScenarioSessionService) TeamMemberSession(leadID uuid.UUID, userID string) (*models.ScenarioSession, error) {
+	var member models.ScenarioSession
+	if err := s.db.Where("team_lead_session_id = ? AND user_id = ?", leadID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// LeaveTeamSession ends a member's part in a team run: their own session is
+// abandoned, the team's run and its terminal carry on.
+func (s *ScenarioSessionService) LeaveTeamSession(memberID uuid.UUID) error {
+	result := s.db.Model(&models.ScenarioSession{}).
+		Where("id = ? AND team_lead_session_id IS NOT NULL AND status IN ?", memberID,
+			[]string{statusActive, statusInProgress, statusProvisioning}).
+		Update("status", "abandoned")
+	if result.Error != nil {
+		return fmt.Errorf("failed to leave the team run: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("session not found or not abandonable")
+	}
+	return nil
+}
+
+// syncTeamSessions brings the member sessions of a lead up to date with it.
+// Members who left the run are not brought back. The step progress is copied
+// too once the run has ended, so each member's record shows the steps the team
+// completed and the grade breakdown matches the grade.
+func syncTeamSessions(tx *gorm.DB, leadID uuid.UUID) error {
+	var lead models.ScenarioSession
+	if err := tx.Preload("StepProgress").First(&lead, "id = ?", leadID).Error; err != nil {
+		return err
+	}
+	if lead.TeamLeadSessionID != nil {
+		return nil
+	}
+	var memberIDs []uuid.UUID
+	if err := tx.Model(&models.ScenarioSession{}).
+		Where("team_lead_session_id = ? AND status <> ?", lead.ID, "abandoned").
+		Pluck("id", &memberIDs).Error; err != nil {
+		return err
+	}
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	updates := map[string]any{
+		"status":               lead.Status,
+		"provisioning_phase":   lead.ProvisioningPhase,
+		"current_step":         lead.CurrentStep,
+		"completed_at":         lead.CompletedAt,
+		"grade":                lead.Grade,
+		"timed_out":            lead.TimedOut,
+		"submitted_late":       lead.SubmittedLate,
+		"late_penalty_percent": lead.LatePenaltyPercent,
+		"grade_before_penalty": lead.GradeBeforePenalty,
+	}
+	if err := tx.Model(&models.ScenarioSession{}).Where("id IN ?", memberIDs).
+		Select(teamSyncedColumns).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update the team's sessions: %w", err)
+	}
+	if lead.Status != "completed" {
+		return nil
+	}
+	for _, memberID := range memberIDs {
+		if err := tx.Unscoped().Where("session_id = ?", memberID).Delete(&models.ScenarioStepProgress{}).Error; err != nil {
+			return err
+		}
+		if err := copyTeamStepProgress(tx, lead.StepProgress, memberID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncTeamSessionsNow is syncTeamSessions outside a transaction, for the
+// paths that end a run without one. A failure leaves the members' sessions
+// behind the lead's, which the next sync catches up.
+func (s *ScenarioSessionService) syncTeamSessionsNow(leadID uuid.UUID) {
+	if err := syncTeamSessions(s.db, leadID); err != nil {
+		slog.Warn("failed to sync the team's sessions", "session_id", leadID, "err", err)
+	}
+}
+
+func copyTeamStepProgress(tx *gorm.DB, progress []models.ScenarioStepProgress, sessionID uuid.UUID) error {
+	for _, p := range progress {
+		p.BaseModel = entityManagementModels.BaseModel{}
+		p.SessionID = sessionID
+		if err := tx.Create(&p).Error; err != nil {
+			return fmt.Errorf("failed to copy the team's step progress: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,12 +1,16 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"soli/formations/src/observability"
 	"soli/formations/src/scenarios/models"
 	"soli/formations/src/utils"
 )
@@ -50,6 +54,15 @@ type execResponse struct {
 // when there is nothing to set: the request is then byte-identical to one from
 // before this parameter existed.
 func (s *VerificationService) ExecInContainer(sessionID string, command []string, env map[string]string, timeout int) (exitCode int, stdout string, stderr string, err error) {
+	ctx, span := observability.StartSpan(context.Background(), "tt.exec",
+		attribute.String("terminal.session_id", sessionID),
+		attribute.Int("exec.timeout_seconds", timeout),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Int("exec.exit_code", exitCode))
+		observability.EndSpan(span, err)
+	}()
+
 	url := fmt.Sprintf("%s/1.0/exec", s.ttBackendURL)
 
 	payload := map[string]any{
@@ -65,7 +78,7 @@ func (s *VerificationService) ExecInContainer(sessionID string, command []string
 	// HTTP timeout must exceed the exec timeout to avoid killing the connection early
 	httpTimeout := time.Duration(timeout+30) * time.Second
 	opts := utils.DefaultHTTPClientOptions()
-	utils.ApplyOptions(&opts, utils.WithAPIKey(s.ttAPIKey), utils.WithTimeout(httpTimeout))
+	utils.ApplyOptions(&opts, utils.WithAPIKey(s.ttAPIKey), utils.WithTimeout(httpTimeout), utils.WithContext(ctx))
 
 	err = utils.MakeExternalAPIJSONRequest("Terminal Trainer", "POST", url, payload, &result, opts)
 	if err != nil {
@@ -140,22 +153,43 @@ func (s *VerificationService) WriteToConsole(sessionID string, text string) erro
 // so the learner cannot inspect it.
 // Exit code 0 = passed, non-zero = failed. Returns stdout as output.
 func (s *VerificationService) VerifyStep(terminalSessionID string, step *models.ScenarioStep) (passed bool, output string, err error) {
+	exitCode, output, err := s.VerifyStepExitCode(terminalSessionID, step)
+	if err != nil {
+		return false, "", err
+	}
+	return exitCode == 0, output, nil
+}
+
+// VerifyStepExitCode runs the verify script like VerifyStep but reports the
+// script's exit code, which verify_exit_code transitions route on.
+func (s *VerificationService) VerifyStepExitCode(terminalSessionID string, step *models.ScenarioStep) (exitCode int, output string, err error) {
+	return runVerifyScript(s, terminalSessionID, step)
+}
+
+// containerExecutor runs commands in a container; see ExecInContainer.
+type containerExecutor interface {
+	ExecInContainer(sessionID string, command []string, env map[string]string, timeout int) (exitCode int, stdout string, stderr string, err error)
+}
+
+// runVerifyScript executes a step's verify script through executor, the way
+// every verification service runs it.
+func runVerifyScript(executor containerExecutor, terminalSessionID string, step *models.ScenarioStep) (exitCode int, output string, err error) {
 	if step.VerifyScript == "" {
-		return false, "", fmt.Errorf("step %d has no verify script", step.Order)
+		return 0, "", fmt.Errorf("step %d has no verify script", step.Order)
 	}
 
 	// Execute the verify script inline with a 10s timeout.
 	// Parse the shebang to use the correct interpreter (e.g., bash vs sh).
 	// No env: a verify script checks the learner's work, so handing it the
 	// step's flag would only widen the flag's exposure for nothing.
-	exitCode, stdout, stderr, err := s.ExecInContainer(
+	exitCode, stdout, stderr, err := executor.ExecInContainer(
 		terminalSessionID,
 		[]string{parseShebang(step.VerifyScript), "-c", step.VerifyScript},
 		nil,
 		10,
 	)
 	if err != nil {
-		return false, "", fmt.Errorf("failed to execute verify script: %w", err)
+		return 0, "", fmt.Errorf("failed to execute verify script: %w", err)
 	}
 
 	// Surface stdout AND stderr so the learner sees what actually failed when
@@ -169,5 +203,5 @@ func (s *VerificationService) VerifyStep(terminalSessionID string, step *models.
 		output += stderr
 	}
 
-	return exitCode == 0, output, nil
+	return exitCode, output, nil
 }
@@ -18,6 +18,9 @@ import (
 // is "zombie" if its linked terminal is no longer running — i.e. State is
 // StateStopped, StateDeleted or StateRevoked (billing revocation, #388) — or
 // the terminal row has been removed entirely.
+//
+// Exam sessions are left alone: abandoning one would void the learner's only
+// attempt without a grade. FinalizeExpiredExams grades them at their cut-off.
 func CleanupZombieScenarioSessions(db *gorm.DB) (int64, error) {
 	now := time.Now()
 
@@ -32,6 +35,7 @@ func CleanupZombieScenarioSessions(db *gorm.DB) (int64, error) {
 
 	result := db.Model(&models.ScenarioSession{}).
 		Where("status IN ?", []string{"active", "in_progress"}).
+		Where("exam_mode = ?", false).
 		Where("terminal_session_id IS NOT NULL").
 		Where(
 			db.Where("terminal_session_id IN (?)", deadTerminals).
@@ -1,6 +1,20 @@
 package storage
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
 // FileStorage defines the interface for storing and retrieving file content.
+//
+// Implementations other than DatabaseStorage are content-addressed: the ref
+// returned by Store is the SHA-256 of the content, so storing the same script
+// or image twice yields the same ref and one stored object. Because several
+// ProjectFile rows may then share an object, Delete must only be called once
+// no row references the ref any more — the services own that decision, the
+// backend only does what it is told.
 type FileStorage interface {
 	Store(content string) (ref string, err error)
 	Retrieve(ref string) (content string, err error)
@@ -8,6 +22,49 @@ type FileStorage interface {
 	Type() string
 }
 
+// Storage type identifiers, as persisted in ProjectFile.StorageType.
+const (
+	TypeDatabase   = "database"
+	TypeFilesystem = "filesystem"
+	TypeS3         = "s3"
+)
+
+// ErrObjectNotFound is returned by Retrieve when no object exists for a ref.
+var ErrObjectNotFound = errors.New("stored object not found")
+
+// ErrInvalidRef is returned when a ref is not a content hash. Refs end up in
+// filesystem paths and object keys, so anything else is refused outright
+// rather than sanitised.
+var ErrInvalidRef = errors.New("invalid storage ref")
+
+// ContentRef returns the content-addressed ref for a piece of content: the
+// lowercase hex SHA-256 of its bytes.
+func ContentRef(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateRef checks that ref has the shape ContentRef produces.
+func validateRef(ref string) error {
+	if len(ref) != sha256.Size*2 {
+		return fmt.Errorf("%w: %q", ErrInvalidRef, ref)
+	}
+	if _, err := hex.DecodeString(ref); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidRef, ref)
+	}
+	return nil
+}
+
+// verifyContent checks retrieved content against its ref. A mismatch means
+// the object was altered or truncated behind our back; serving it would hand a
+// learner a script nobody authored.
+func verifyContent(ref string, content string) error {
+	if got := ContentRef(content); got != ref {
+		return fmt.Errorf("stored object %s failed integrity check (content hashes to %s)", ref, got)
+	}
+	return nil
+}
+
 // DatabaseStorage is a no-op pass-through implementation of FileStorage.
 // Content lives directly in the ProjectFile model's Content field,
 // so no external storage management is needed.
@@ -33,5 +90,5 @@ func (d *DatabaseStorage) Delete(ref string) error {
 }
 
 func (d *DatabaseStorage) Type() string {
-	return "database"
+	return TypeDatabase
 }
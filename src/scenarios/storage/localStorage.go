@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage keeps content-addressed objects under a root directory, fanned
+// out by the first two hex characters of the ref (root/ab/ab12…) so no single
+// directory ends up holding every asset of every scenario.
+//
+// It is the backend for single-node deployments and the stand-in the tests
+// use; a mounted volume behaves the same way.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates the root directory if needed and returns a backend
+// rooted there.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local storage root is required")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage root %s: %w", root, err)
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+func (l *LocalStorage) objectPath(ref string) string {
+	return filepath.Join(l.root, ref[:2], ref)
+}
+
+// Store writes content under its hash. An object that already exists is left
+// untouched — identical content has the identical ref, so rewriting it would
+// only add a window where a concurrent reader sees a partial file.
+func (l *LocalStorage) Store(content string) (string, error) {
+	ref := ContentRef(content)
+	path := l.objectPath(ref)
+	if _, err := os.Stat(path); err == nil {
+		return ref, nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	// Write to a temp file in the same directory and rename into place, so the
+	// object path only ever holds complete content.
+	tmp, err := os.CreateTemp(dir, ".tmp-"+ref[:8]+"-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp object: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return "", fmt.Errorf("failed to write object %s: %w", ref, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("failed to close object %s: %w", ref, err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("failed to commit object %s: %w", ref, err)
+	}
+	return ref, nil
+}
+
+func (l *LocalStorage) Retrieve(ref string) (string, error) {
+	if err := validateRef(ref); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(l.objectPath(ref))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrObjectNotFound, ref)
+		}
+		return "", fmt.Errorf("failed to read object %s: %w", ref, err)
+	}
+	content := string(data)
+	if err := verifyContent(ref, content); err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// Delete removes the object. A missing object is not an error: the caller
+// wanted it gone and it is.
+func (l *LocalStorage) Delete(ref string) error {
+	if err := validateRef(ref); err != nil {
+		return err
+	}
+	if err := os.Remove(l.objectPath(ref)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object %s: %w", ref, err)
+	}
+	return nil
+}
+
+func (l *LocalStorage) Type() string {
+	return TypeFilesystem
+}
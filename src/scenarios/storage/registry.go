@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Backends are tracked in two roles. The active backend receives new content;
+// the registry resolves existing rows by the StorageType they were written
+// with. The two differ during a migration — rows written before the switch
+// still say "database" and must keep loading — so a row is never read through
+// whatever happens to be configured today.
+var (
+	registryMu sync.RWMutex
+	backends               = map[string]FileStorage{TypeDatabase: NewDatabaseStorage()}
+	active     FileStorage = backends[TypeDatabase]
+)
+
+// Register makes a backend available for reading rows of its Type.
+func Register(fs FileStorage) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	backends[fs.Type()] = fs
+}
+
+// SetActive registers fs and makes it the destination for new content.
+func SetActive(fs FileStorage) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	backends[fs.Type()] = fs
+	active = fs
+}
+
+// Active returns the backend new content is written to.
+func Active() FileStorage {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return active
+}
+
+// ForType returns the backend that reads rows stored with storageType. An
+// empty type is a row written before the column existed, which means the
+// database.
+func ForType(storageType string) (FileStorage, error) {
+	if storageType == "" {
+		storageType = TypeDatabase
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	fs, ok := backends[storageType]
+	if !ok {
+		return nil, fmt.Errorf("no storage backend configured for type %q", storageType)
+	}
+	return fs, nil
+}
+
+// NewFromEnv builds the backend selected by SCENARIO_STORAGE_BACKEND:
+//
+//	database   (default) content stays in project_files.content
+//	filesystem SCENARIO_STORAGE_PATH holds the objects
+//	s3         SCENARIO_S3_ENDPOINT, SCENARIO_S3_BUCKET, SCENARIO_S3_ACCESS_KEY,
+//	           SCENARIO_S3_SECRET_KEY, optional SCENARIO_S3_REGION and SCENARIO_S3_PREFIX
+func NewFromEnv() (FileStorage, error) {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("SCENARIO_STORAGE_BACKEND")))
+	switch backend {
+	case "", TypeDatabase:
+		return NewDatabaseStorage(), nil
+	case TypeFilesystem:
+		return NewLocalStorage(os.Getenv("SCENARIO_STORAGE_PATH"))
+	case TypeS3:
+		return NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("SCENARIO_S3_ENDPOINT"),
+			Bucket:    os.Getenv("SCENARIO_S3_BUCKET"),
+			Region:    os.Getenv("SCENARIO_S3_REGION"),
+			AccessKey: os.Getenv("SCENARIO_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("SCENARIO_S3_SECRET_KEY"),
+			Prefix:    os.Getenv("SCENARIO_S3_PREFIX"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown SCENARIO_STORAGE_BACKEND %q (expected database, filesystem or s3)", backend)
+	}
+}
+
+// InitFromEnv configures the active backend from the environment. A broken
+// configuration falls back to the database so the server still boots, and is
+// logged loudly: content keeps landing in Postgres until someone fixes it,
+// which is the situation before this backend existed, not an outage.
+func InitFromEnv() {
+	fs, err := NewFromEnv()
+	if err != nil {
+		slog.Error("scenario file storage misconfigured, keeping content in the database", "err", err)
+		return
+	}
+	SetActive(fs)
+	slog.Info("scenario file storage configured", "backend", fs.Type())
+}
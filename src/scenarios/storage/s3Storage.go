@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config holds the connection settings for an S3-compatible object store.
+// Requests use path-style addressing (endpoint/bucket/key), which MinIO, Ceph
+// RGW, Garage and AWS itself all accept, so no DNS-per-bucket setup is needed.
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-3.amazonaws.com or http://minio:9000
+	Bucket    string
+	Region    string // defaults to us-east-1, which MinIO expects unless configured otherwise
+	AccessKey string
+	SecretKey string
+	Prefix    string // optional key prefix, e.g. "ocf/project-files"
+}
+
+// S3Storage stores content-addressed objects in an S3-compatible bucket.
+//
+// Requests are signed with AWS Signature V4 directly rather than through an
+// SDK: the backend needs four verbs on single objects, and the signing is the
+// only non-trivial part of that.
+type S3Storage struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Storage validates the configuration and returns a backend for it.
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 storage requires an endpoint and a bucket")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 storage requires an access key and a secret key")
+	}
+	if _, err := url.Parse(cfg.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint %q: %w", cfg.Endpoint, err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	return &S3Storage{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+func (s *S3Storage) objectKey(ref string) string {
+	key := ref[:2] + "/" + ref
+	if s.cfg.Prefix != "" {
+		key = s.cfg.Prefix + "/" + key
+	}
+	return key
+}
+
+func (s *S3Storage) objectURL(ref string) string {
+	return s.cfg.Endpoint + "/" + s.cfg.Bucket + "/" + s.objectKey(ref)
+}
+
+// Store uploads content under its hash. A HEAD first skips the upload when the
+// object is already there — the common case on re-import, where every script
+// of an unchanged scenario hashes to an object stored the first time round.
+func (s *S3Storage) Store(content string) (string, error) {
+	ref := ContentRef(content)
+
+	exists, err := s.exists(ref)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return ref, nil
+	}
+
+	resp, err := s.do(http.MethodPut, ref, []byte(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload object %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to upload object %s: %s", ref, readS3Error(resp))
+	}
+	return ref, nil
+}
+
+func (s *S3Storage) Retrieve(ref string) (string, error) {
+	if err := validateRef(ref); err != nil {
+		return "", err
+	}
+	resp, err := s.do(http.MethodGet, ref, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch object %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s", ErrObjectNotFound, ref)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch object %s: %s", ref, readS3Error(resp))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read object %s: %w", ref, err)
+	}
+	content := string(data)
+	if err := verifyContent(ref, content); err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// Delete removes the object. S3 answers 204 whether or not the key existed,
+// and a 404 from a stricter implementation is treated the same way.
+func (s *S3Storage) Delete(ref string) error {
+	if err := validateRef(ref); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, ref, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("failed to delete object %s: %s", ref, readS3Error(resp))
+	}
+}
+
+func (s *S3Storage) Type() string {
+	return TypeS3
+}
+
+func (s *S3Storage) exists(ref string) (bool, error) {
+	resp, err := s.do(http.MethodHead, ref, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to check object %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check object %s: %s", ref, readS3Error(resp))
+	}
+}
+
+// do builds, signs and sends a request for a single object.
+func (s *S3Storage) do(method string, ref string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, s.objectURL(ref), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	s.sign(req, body)
+	return s.client.Do(req)
+}
+
+// sign adds AWS Signature V4 headers to req.
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func (s *S3Storage) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaderNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headerValues := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		signedHeaderNames = append(signedHeaderNames, "content-type")
+		headerValues["content-type"] = ct
+	}
+	sort.Strings(signedHeaderNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaderNames {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headerValues[name]) + "\n")
+	}
+	signedHeaders := strings.Join(signedHeaderNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := dateStamp + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), dateStamp)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// readS3Error summarises an error response without dumping an unbounded body
+// into the log.
+func readS3Error(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if len(body) == 0 {
+		return resp.Status
+	}
+	return resp.Status + ": " + strings.TrimSpace(string(body))
+}
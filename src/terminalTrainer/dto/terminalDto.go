@@ -499,6 +499,10 @@ type CreateComposedSessionInput struct {
 	// Tier enforcement (free tier cannot request persistent) is applied by
 	// StartComposedSession. The value is forwarded to tt-backend.
 	PersistenceMode string `json:"persistence_mode,omitempty"`
+	// PrivateNetwork names a network the session shares with the other
+	// sessions given the same name, and nothing else — the machines of a
+	// multi-node scenario. Empty keeps the usual isolated container.
+	PrivateNetwork string `json:"private_network,omitempty"`
 	// Set by service layer
 	HistoryRetentionDays   int        `json:"-"`
 	SubscriptionPlanID     *uuid.UUID `json:"-"`
@@ -749,3 +753,85 @@ type MyTerminalUsageResponse struct {
 	UsedMemoryMB              int                      `json:"used_memory_mb"`
 	ActiveSessions            []MyTerminalUsageSession `json:"active_sessions"`
 }
+
+// ScheduleTerminalFleetInput schedules one terminal per active member of a
+// class group, launched at start_at and stopped at stop_at.
+type ScheduleTerminalFleetInput struct {
+	Distribution     string          `binding:"required" json:"distribution"`
+	Size             string          `binding:"required" json:"size"`
+	Features         map[string]bool `json:"features,omitempty"`
+	Terms            string          `binding:"required" json:"terms"`
+	NameTemplate     string          `json:"name_template,omitempty"` // Same placeholders as bulk creation
+	Backend          string          `json:"backend,omitempty"`
+	OrganizationID   string          `json:"organization_id,omitempty"`
+	RecordingEnabled int             `json:"recording_enabled,omitempty"`
+	StartAt          time.Time       `binding:"required" json:"start_at"`
+	StopAt           time.Time       `binding:"required" json:"stop_at"`
+	// StaggerSeconds spaces out the launches so a whole class does not hit
+	// the backend at once. Defaults to 5 seconds when omitted.
+	StaggerSeconds *int `binding:"omitempty,min=0,max=300" json:"stagger_seconds,omitempty"`
+}
+
+// CreateTerminalSnapshotInput names a snapshot of a persistent terminal.
+type CreateTerminalSnapshotInput struct {
+	Name string `binding:"required" json:"name"`
+}
+
+// TTSnapshot is a snapshot as tt-backend reports it on creation.
+type TTSnapshot struct {
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TerminalSnapshotsResponse lists a terminal's snapshots along with the
+// owner's storage: used_bytes counts every snapshot they hold, across all
+// their terminals, against the plan's quota_bytes.
+type TerminalSnapshotsResponse struct {
+	Snapshots  []models.TerminalSnapshot `json:"snapshots"`
+	UsedBytes  int64                     `json:"used_bytes"`
+	QuotaBytes int64                     `json:"quota_bytes"`
+}
+
+// RecordingMarker labels a moment of a terminal recording. Replay writes it
+// into the cast as an asciicast v2 marker event, which players offer as a
+// seek point.
+type RecordingMarker struct {
+	At    time.Time `json:"at"`
+	Label string    `json:"label"`
+}
+
+// RecordingReplayOptions selects the part of a recording to replay. From and
+// To are offsets in seconds from the start of the recording; Since and Until
+// are absolute and, when set, take precedence. Unset bounds leave that end of
+// the recording open.
+type RecordingReplayOptions struct {
+	From    *float64
+	To      *float64
+	Since   *time.Time
+	Until   *time.Time
+	Markers []RecordingMarker
+}
+
+// ShareTerminalInput adds learners to a terminal's team: the active members
+// of a sub-group, learners named one by one, or both. Everyone added joins as
+// a navigator.
+type ShareTerminalInput struct {
+	GroupID *uuid.UUID `json:"group_id,omitempty"`
+	UserIDs []string   `json:"user_ids,omitempty"`
+}
+
+// SetTerminalTeamRoleInput changes a team member's role. Making a member the
+// driver makes the previous driver a navigator.
+type SetTerminalTeamRoleInput struct {
+	Role string `binding:"required,oneof=driver navigator" json:"role"`
+}
+
+// TerminalTeamResponse is a shared terminal's team. Members is empty for a
+// terminal that is not shared.
+type TerminalTeamResponse struct {
+	TerminalID uuid.UUID                   `json:"terminal_id"`
+	SessionID  string                      `json:"session_id"`
+	OwnerID    string                      `json:"owner_id"`
+	Members    []models.TerminalTeamMember `json:"members"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Terminal fleet lifecycle. A fleet is scheduled ahead of a class, launching
+// from start_at while its members' terminals come up, running once every
+// member has been tried, and completed when stop_at has stopped them. It ends
+// failed when it cannot launch at all, and cancelled when a trainer calls it
+// off.
+const (
+	FleetStatusScheduled = "scheduled"
+	FleetStatusLaunching = "launching"
+	FleetStatusRunning   = "running"
+	FleetStatusCompleted = "completed"
+	FleetStatusCancelled = "cancelled"
+	FleetStatusFailed    = "failed"
+)
+
+// FleetActiveStatuses are the statuses of a fleet that still holds its budget
+// reservation.
+var FleetActiveStatuses = []string{FleetStatusScheduled, FleetStatusLaunching, FleetStatusRunning}
+
+// Terminal fleet member lifecycle: pending until its turn in the staggered
+// launch, launching while its session is being created, then running or
+// failed; a running member is stopped with its fleet.
+const (
+	FleetMemberStatusPending   = "pending"
+	FleetMemberStatusLaunching = "launching"
+	FleetMemberStatusRunning   = "running"
+	FleetMemberStatusFailed    = "failed"
+	FleetMemberStatusStopped   = "stopped"
+)
+
+// TerminalFleet is a scheduled pre-provisioning of one terminal per active
+// member of a class group. It is persisted so a schedule, and a launch in
+// progress, survive a restart.
+type TerminalFleet struct {
+	entityManagementModels.BaseModel
+	GroupID uuid.UUID `gorm:"type:uuid;not null;index" json:"group_id"`
+	// CreatedByID is the trainer who scheduled the fleet. Terminals are
+	// launched under their effective plan, resolved again at start_at.
+	CreatedByID    string     `gorm:"type:varchar(255);not null;index" json:"created_by_id"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	// BudgetOrganizationID is the organization whose shared budget the fleet
+	// draws on, nil when each member's terminal counts against their own.
+	// Only fleets sharing a pool reserve budget against each other.
+	BudgetOrganizationID *uuid.UUID      `gorm:"type:uuid;index" json:"budget_organization_id,omitempty"`
+	Distribution         string          `gorm:"type:varchar(100);not null" json:"distribution"`
+	Size                 string          `gorm:"type:varchar(10);not null" json:"size"`
+	Features             map[string]bool `gorm:"serializer:json" json:"features,omitempty"`
+	Terms                string          `gorm:"type:varchar(255)" json:"terms"`
+	NameTemplate         string          `gorm:"type:varchar(255)" json:"name_template,omitempty"`
+	Backend              string          `gorm:"type:varchar(255)" json:"backend,omitempty"`
+	RecordingEnabled     int             `gorm:"default:0" json:"recording_enabled,omitempty"`
+	StartAt              time.Time       `gorm:"not null;index" json:"start_at"`
+	StopAt               time.Time       `gorm:"not null;index" json:"stop_at"`
+	StaggerSeconds       int             `gorm:"default:0" json:"stagger_seconds"`
+	// ReservedCPU and ReservedMemoryMB are the fleet's footprint (members at
+	// scheduling time × size), held against the budget pool between start_at
+	// and stop_at.
+	ReservedCPU      int        `gorm:"default:0" json:"reserved_cpu"`
+	ReservedMemoryMB int        `gorm:"default:0" json:"reserved_memory_mb"`
+	Status           string     `gorm:"type:varchar(20);not null;default:'scheduled';index" json:"status"`
+	LaunchedAt       *time.Time `json:"launched_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+	Error            string     `gorm:"type:text" json:"error,omitempty"`
+
+	Members []TerminalFleetMember `gorm:"foreignKey:FleetID" json:"members,omitempty"`
+}
+
+// TerminalFleetMember is one learner's terminal in a fleet. Members are
+// recorded when the fleet launches, from the group's active members then.
+type TerminalFleetMember struct {
+	entityManagementModels.BaseModel
+	FleetID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_fleet_member" json:"fleet_id"`
+	UserID     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_fleet_member" json:"user_id"`
+	Name       string     `gorm:"type:varchar(255)" json:"name"`
+	Status     string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	SessionID  string     `gorm:"type:varchar(255)" json:"session_id,omitempty"`
+	LaunchedAt *time.Time `json:"launched_at,omitempty"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+)
+
+// TerminalSnapshot is a named checkpoint of a persistent terminal's disk. The
+// snapshot itself lives on tt-backend; this row is the local record of it,
+// kept so its size can be counted against the owner's storage quota without a
+// round trip per terminal. Rows are deleted outright with their snapshot.
+type TerminalSnapshot struct {
+	entityManagementModels.BaseModel
+	TerminalID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_terminal_snapshot_name" json:"terminal_id"`
+	// UserID is the terminal's owner, whose plan storage the snapshot counts
+	// against — not CreatedByID, which may be a trainer resetting a learner.
+	UserID      string `gorm:"type:varchar(255);not null;index" json:"user_id"`
+	Name        string `gorm:"type:varchar(63);not null;uniqueIndex:idx_terminal_snapshot_name" json:"name"`
+	SizeBytes   int64  `gorm:"default:0" json:"size_bytes"`
+	CreatedByID string `gorm:"type:varchar(255)" json:"created_by_id"`
+	// StepOrder is set on the snapshots a scenario takes automatically once a
+	// step's setup has run; nil for snapshots taken by hand.
+	StepOrder *int `json:"step_order,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	entityManagementModels "soli/formations/src/entityManagement/models"
+
+	"github.com/google/uuid"
+)
+
+// Team roles on a shared terminal. The driver types; navigators watch the
+// same console and talk it through. A team has at most one driver at a time.
+const (
+	TeamRoleDriver    = "driver"
+	TeamRoleNavigator = "navigator"
+)
+
+// TerminalTeamMember is one learner attached to a shared terminal. A terminal
+// is shared once it has rows: its owner among them, so the owner can be a
+// navigator too, and the container stays the owner's — launched under their
+// plan and counted against their budget.
+type TerminalTeamMember struct {
+	entityManagementModels.BaseModel
+	TerminalID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_terminal_team_member" json:"terminal_id"`
+	UserID     string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_terminal_team_member;index" json:"user_id"`
+	Role       string    `gorm:"type:varchar(20);not null;default:'navigator'" json:"role"`
+	// GroupID is the sub-group the member was added with, nil for a member
+	// added by hand.
+	GroupID   *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	AddedByID string     `gorm:"type:varchar(255)" json:"added_by_id"`
+}
+
+// AttachmentRole is the tt-backend console attachment role the member's
+// console runs under: interactive for the driver, observer otherwise.
+func (m TerminalTeamMember) AttachmentRole() string {
+	if m.Role == TeamRoleDriver {
+		return "interactive"
+	}
+	return "observer"
+}
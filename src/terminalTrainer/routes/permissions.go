@@ -43,10 +43,19 @@ func RegisterTerminalPermissions(enforcer interfaces.EnforcerInterface) {
 		// registration (Layer 1 + Layer 2 now derived from that declaration).
 		access.RoutePermission{Path: "/api/v1/terminals/:id/start", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Resume a stopped terminal (controller-enforced ownership)"},
 		access.RoutePermission{Path: "/api/v1/terminals/:id", Method: "DELETE", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Permanently delete a terminal session (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/snapshots", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "List a persistent terminal's snapshots and storage use (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/snapshots", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Snapshot a persistent terminal (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/snapshots/:name/restore", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Restore a terminal to one of its snapshots (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/snapshots/:name", Method: "DELETE", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Delete a terminal snapshot (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/team", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Get a shared terminal's team (controller-enforced access)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/team", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Share a terminal with a sub-group or classmates (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/team/:userId", Method: "PATCH", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Change a shared terminal team member's role (controller-enforced access)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/team/:userId", Method: "DELETE", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Remove a member from a shared terminal's team (controller-enforced access)"},
 		access.RoutePermission{Path: "/api/v1/terminals/:id/sync", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Sync terminal session state with backend (controller-enforced ownership)"},
 		access.RoutePermission{Path: "/api/v1/terminals/:id/status", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Get terminal session status (controller-enforced ownership)"},
 		access.RoutePermission{Path: "/api/v1/terminals/:id/history", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Get command history for a terminal session (controller-enforced ownership)"},
 		access.RoutePermission{Path: "/api/v1/terminals/:id/history", Method: "DELETE", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Delete command history for a terminal session (controller-enforced ownership)"},
+		access.RoutePermission{Path: "/api/v1/terminals/:id/recording", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Replay a terminal session's recording as asciicast v2 (controller-enforced ownership)"},
 
 		// Access status (self-scoped - checks own access level)
 		access.RoutePermission{Path: "/api/v1/terminals/:id/access-status", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.SelfScoped}, Description: "Check current user's access level for a terminal"},
@@ -78,6 +87,12 @@ func RegisterTerminalPermissions(enforcer interfaces.EnforcerInterface) {
 		access.RoutePermission{Path: "/api/v1/class-groups/:id/command-history", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "id", MinRole: "manager"}, Description: "Get command history for all group members"},
 		access.RoutePermission{Path: "/api/v1/class-groups/:id/command-history-stats", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "id", MinRole: "manager"}, Description: "Get command history statistics for a group"},
 
+		// Scheduled terminal fleets: pre-provisioned terminals for a class session.
+		access.RoutePermission{Path: "/api/v1/class-groups/:id/terminal-fleets", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "id", MinRole: "manager"}, Description: "Schedule a terminal fleet for all group members"},
+		access.RoutePermission{Path: "/api/v1/class-groups/:id/terminal-fleets", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "id", MinRole: "manager"}, Description: "List a group's terminal fleets"},
+		access.RoutePermission{Path: "/api/v1/class-groups/:id/terminal-fleets/:fleetId", Method: "GET", Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "id", MinRole: "manager"}, Description: "Get a terminal fleet and its members' launch results"},
+		access.RoutePermission{Path: "/api/v1/class-groups/:id/terminal-fleets/:fleetId/cancel", Method: "POST", Role: access.RoleMember, Access: access.AccessRule{Type: access.GroupRole, Param: "id", MinRole: "manager"}, Description: "Cancel a terminal fleet and stop its terminals"},
+
 		// Supervision (#425): group-scoped active session listing (Layer 2 GroupRole
 		// manager+). The supervise WS broker is SelfScoped — the controller derives
 		// the learner's group from the session record and enforces manager+ itself
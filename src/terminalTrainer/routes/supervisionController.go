@@ -517,11 +517,18 @@ var supervisionHTTPClient = &http.Client{Timeout: 30 * time.Second}
 // patchAttachmentRole calls tt-backend's REST role-transition endpoint to promote
 // or demote our attachment, authenticated with the session owner's key.
 func (tc *terminalController) patchAttachmentRole(ttSessionID, attachmentID, role, apiKey string) error {
-	base, err := url.Parse(tc.terminalTrainerURL)
+	return patchConsoleAttachmentRole(tc.terminalTrainerURL, tc.apiVersion, ttSessionID, attachmentID, role, apiKey)
+}
+
+// patchConsoleAttachmentRole moves any console attachment of a session to role
+// ("interactive" or "observer"): the supervisor's on take/release-hand, a
+// team member's when the driver's seat changes hands.
+func patchConsoleAttachmentRole(terminalTrainerURL, apiVersion, ttSessionID, attachmentID, role, apiKey string) error {
+	base, err := url.Parse(terminalTrainerURL)
 	if err != nil {
 		return err
 	}
-	base.Path = fmt.Sprintf("/%s/sessions/%s/console/attachments/%s", tc.apiVersion, ttSessionID, attachmentID)
+	base.Path = fmt.Sprintf("/%s/sessions/%s/console/attachments/%s", apiVersion, ttSessionID, attachmentID)
 	body, _ := json.Marshal(map[string]string{"role": role})
 	req, err := http.NewRequest(http.MethodPatch, base.String(), bytes.NewReader(body))
 	if err != nil {
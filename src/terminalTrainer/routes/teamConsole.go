@@ -0,0 +1,112 @@
+package terminalController
+
+// teamConsole.go — the live side of shared terminals. Each team member's
+// console runs under the attachment role of their team role; when the
+// driver's seat changes hands, the consoles already open are moved to their
+// new role through the same role-transition endpoint supervision's take-hand
+// uses, so nobody has to reconnect.
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"soli/formations/src/terminalTrainer/dto"
+)
+
+// teamConsoleRegistry tracks the tt-backend attachment of every open team
+// console, by session and user: a learner may have the terminal open in more
+// than one tab. Attachments are only known to the process relaying them, so
+// the registry is in-memory, like the supervise broker's.
+type teamConsoleRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]map[string]struct{}
+}
+
+var teamConsoles = &teamConsoleRegistry{sessions: map[string]map[string]map[string]struct{}{}}
+
+func (r *teamConsoleRegistry) add(ttSessionID, userID, attachmentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users, ok := r.sessions[ttSessionID]
+	if !ok {
+		users = map[string]map[string]struct{}{}
+		r.sessions[ttSessionID] = users
+	}
+	if users[userID] == nil {
+		users[userID] = map[string]struct{}{}
+	}
+	users[userID][attachmentID] = struct{}{}
+}
+
+func (r *teamConsoleRegistry) remove(ttSessionID, userID, attachmentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := r.sessions[ttSessionID]
+	delete(users[userID], attachmentID)
+	if len(users[userID]) == 0 {
+		delete(users, userID)
+	}
+	if len(users) == 0 {
+		delete(r.sessions, ttSessionID)
+	}
+}
+
+func (r *teamConsoleRegistry) attachments(ttSessionID, userID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.sessions[ttSessionID][userID]))
+	for id := range r.sessions[ttSessionID][userID] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// teamAttachmentConn is a team member's tt-backend console connection. It
+// learns the console's attachment id from tt-backend's self frame, exactly as
+// the supervise broker does (bindSelfAttachmentID), and keeps it in the
+// registry until closed.
+type teamAttachmentConn struct {
+	consoleRelayConn
+	ttSessionID  string
+	userID       string
+	attachmentID string
+}
+
+func (c *teamAttachmentConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.consoleRelayConn.ReadMessage()
+	if err == nil && messageType == websocket.BinaryMessage && c.attachmentID == "" {
+		if c.attachmentID = bindSelfAttachmentID("", data); c.attachmentID != "" {
+			teamConsoles.add(c.ttSessionID, c.userID, c.attachmentID)
+		}
+	}
+	return messageType, data, err
+}
+
+// release drops the console from the registry once it is closed.
+func (c *teamAttachmentConn) release() {
+	if c.attachmentID != "" {
+		teamConsoles.remove(c.ttSessionID, c.userID, c.attachmentID)
+	}
+}
+
+// applyTeamAttachmentRoles moves the open consoles of the team to the
+// attachment role of each member's current team role. Failures are logged
+// and left: the team's roles are already saved, and a console reopened picks
+// its role up from them.
+func applyTeamAttachmentRoles(terminalTrainerURL, apiVersion string, team *dto.TerminalTeamResponse, ownerAPIKey string) {
+	for _, member := range team.Members {
+		setConsoleAttachmentRoles(terminalTrainerURL, apiVersion, team.SessionID, member.UserID, member.AttachmentRole(), ownerAPIKey)
+	}
+}
+
+// setConsoleAttachmentRoles moves every open console of one user on a session
+// to role.
+func setConsoleAttachmentRoles(terminalTrainerURL, apiVersion, ttSessionID, userID, role, ownerAPIKey string) {
+	for _, attachmentID := range teamConsoles.attachments(ttSessionID, userID) {
+		if err := patchConsoleAttachmentRole(terminalTrainerURL, apiVersion, ttSessionID, attachmentID, role, ownerAPIKey); err != nil {
+			slog.Error("team console role PATCH failed", "session_id", ttSessionID, "user_id", userID, "role", role, "err", err)
+		}
+	}
+}
@@ -78,6 +78,7 @@ type TerminalController interface {
 	GetSessionHistory(ctx *gin.Context)
 	DeleteSessionHistory(ctx *gin.Context)
 	DeleteAllUserHistory(ctx *gin.Context)
+	GetSessionRecording(ctx *gin.Context)
 
 	// Organization session management
 	GetOrganizationTerminalSessions(ctx *gin.Context)
@@ -307,10 +308,20 @@ func (tc *terminalController) ConnectConsole(ctx *gin.Context) {
 		return
 	}
 
+	// A shared terminal's team member consoles in under their team role:
+	// navigators as observers, the driver interactive.
+	teamRole, teamErr := tc.service.GetTerminalTeamRole(sessionID, userId)
+	if teamErr != nil {
+		utils.Warn("console: team role lookup failed for session %s, user %s: %v", sessionID, userId, teamErr)
+	}
+
 	// Construire l'URL WebSocket du Terminal Trainer. Un contexte supervisable
 	// (propriétaire membre d'au moins un class-group) ouvre la console avec
 	// control=1 pour que l'indicateur "sous supervision" du learner s'active.
-	supervisable := SessionSupportsSupervision(tc.db, sessionID)
+	// A team console needs the control frames too: its attachment id, which a
+	// later driver handover addresses, arrives in one — and its teammates are
+	// watching.
+	supervisable := SessionSupportsSupervision(tc.db, sessionID) || teamRole != ""
 	instanceType := terminal.InstanceType
 	if instanceType == "" {
 		instanceType = tc.terminalType
@@ -341,6 +352,9 @@ func (tc *terminalController) ConnectConsole(ctx *gin.Context) {
 	if height := ctx.Query("height"); height != "" {
 		q.Set("height", height)
 	}
+	if teamRole == models.TeamRoleNavigator {
+		q.Set("role", "observer")
+	}
 	terminalTrainerWSURL.RawQuery = q.Encode()
 
 	// Upgrade la connexion cliente vers WebSocket. On failure gorilla has
@@ -404,7 +418,13 @@ func (tc *terminalController) ConnectConsole(ctx *gin.Context) {
 		}
 	}()
 
-	relayTerminalToClient(terminalConn, clientConn, terminal.SessionID)
+	var relayConn consoleRelayConn = terminalConn
+	if teamRole != "" {
+		teamConn := &teamAttachmentConn{consoleRelayConn: terminalConn, ttSessionID: terminal.SessionID, userID: userId}
+		defer teamConn.release()
+		relayConn = teamConn
+	}
+	relayTerminalToClient(relayConn, clientConn, terminal.SessionID)
 }
 
 // consoleRelayConn is the narrow part of *websocket.Conn the console relay
@@ -1577,6 +1597,99 @@ func (tc *terminalController) GetSessionHistory(ctx *gin.Context) {
 	ctx.Data(http.StatusOK, contentType, body)
 }
 
+// GetSessionRecording replays a terminal session's recording
+//
+//	@Summary		Replay a terminal session's recording
+//	@Description	Returns the full terminal I/O of the session as an asciicast v2 file, optionally cut to a window given in seconds from the start of the recording. Output before the window is folded into its first frame, so the replay opens on the screen as it was. Anything older than the plan's command history retention is never returned. Session owner or admin only; trainers replay through the teacher dashboard, which checks the group's recording consent.
+//	@Tags			terminal
+//	@Produce		application/x-asciicast
+//	@Param			id		path	string	true	"Terminal session ID"
+//	@Param			from	query	number	false	"Start of the window, in seconds from the start of the recording"
+//	@Param			to		query	number	false	"End of the window, in seconds from the start of the recording"
+//	@Security		BearerAuth
+//	@Success		200	{string}	string	"asciicast v2 recording"
+//	@Failure		400	{object}	errors.APIError	"Invalid window"
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Session not found"
+//	@Failure		410	{object}	errors.APIError	"Recording past its retention period"
+//	@Failure		500	{object}	errors.APIError	"Internal server error"
+//	@Router			/terminals/{id}/recording [get]
+func (tc *terminalController) GetSessionRecording(ctx *gin.Context) {
+	sessionID := ctx.Param("id")
+
+	terminal, err := tc.service.GetSessionInfo(sessionID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, &errors.APIError{
+			ErrorCode:    http.StatusNotFound,
+			ErrorMessage: "Session not found",
+		})
+		return
+	}
+	if !tc.isSessionOwnerOrAdmin(ctx, terminal) {
+		ctx.JSON(http.StatusForbidden, &errors.APIError{
+			ErrorCode:    http.StatusForbidden,
+			ErrorMessage: "Only the session owner or an admin can replay this recording",
+		})
+		return
+	}
+
+	from, ok := parseRecordingOffset(ctx, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseRecordingOffset(ctx, "to")
+	if !ok {
+		return
+	}
+
+	body, err := tc.service.GetSessionRecording(sessionID, dto.RecordingReplayOptions{From: from, To: to})
+	if err != nil {
+		respondRecordingError(ctx, sessionID, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", `inline; filename="`+sessionID+`.cast"`)
+	ctx.Data(http.StatusOK, "application/x-asciicast", body)
+}
+
+// parseRecordingOffset reads an optional offset, in seconds, from the query,
+// writing the 400 when it is not a non-negative number.
+func parseRecordingOffset(ctx *gin.Context, name string) (*float64, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: "invalid " + name + " parameter",
+		})
+		return nil, false
+	}
+	return &v, true
+}
+
+// respondRecordingError maps a recording failure to its response, with the
+// same reading of tt-backend's errors as GetSessionHistory.
+func respondRecordingError(ctx *gin.Context, sessionID string, err error) {
+	status, message := http.StatusInternalServerError, "Failed to get the recording"
+	switch {
+	case stderrors.Is(err, services.ErrRecordingExpired):
+		status, message = http.StatusGone, err.Error()
+	case strings.Contains(err.Error(), "not found"):
+		status, message = http.StatusNotFound, "Recording not found"
+	case strings.Contains(err.Error(), "returned 403") || strings.Contains(err.Error(), "Recording not enabled"):
+		status, message = http.StatusForbidden, "Recording not enabled for this session"
+	default:
+		utils.Debug("GetSessionRecording failed for session %s: %v", sessionID, err)
+	}
+	ctx.JSON(status, &errors.APIError{
+		ErrorCode:    status,
+		ErrorMessage: message,
+	})
+}
+
 // DeleteSessionHistory deletes command history (RGPD right to erasure)
 //
 //	@Summary		Delete command history for a terminal session
@@ -0,0 +1,236 @@
+package terminalController
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/errors"
+	paymentMiddleware "soli/formations/src/payment/middleware"
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	services "soli/formations/src/terminalTrainer/services"
+)
+
+// terminalFleetController schedules terminal fleets for a class group's
+// sessions. Every route sits under /class-groups/:id and is for the group's
+// managers.
+type terminalFleetController struct {
+	db           *gorm.DB
+	fleetService services.TerminalFleetService
+}
+
+func NewTerminalFleetController(db *gorm.DB, launcher services.FleetLauncher) *terminalFleetController {
+	return &terminalFleetController{
+		db:           db,
+		fleetService: services.NewTerminalFleetService(db, launcher),
+	}
+}
+
+// ScheduleFleet godoc
+//
+//	@Summary		Schedule a terminal fleet for a class session
+//	@Description	Pre-provisions one terminal per active group member at start_at, launched a few seconds apart, and stops them at stop_at. The fleet's footprint is reserved in the plan's budget for its window; server capacity is checked when it launches.
+//	@Tags			terminals
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string							true	"Group ID"
+//	@Param			request	body	dto.ScheduleTerminalFleetInput	true	"Fleet schedule"
+//	@Security		Bearer
+//	@Success		201	{object}	models.TerminalFleet
+//	@Failure		400	{object}	errors.APIError	"Invalid schedule"
+//	@Failure		403	{object}	errors.APIError	"Access denied or budget exhausted"
+//	@Failure		404	{object}	errors.APIError	"Group not found"
+//	@Failure		500	{object}	errors.APIError	"Internal server error"
+//	@Router			/class-groups/{id}/terminal-fleets [post]
+func (fc *terminalFleetController) ScheduleFleet(ctx *gin.Context) {
+	groupID, ok := fc.managedGroupID(ctx)
+	if !ok {
+		return
+	}
+	// Dunning gate: a fleet creates sessions, later but all the same.
+	if paymentMiddleware.GatePastDueBeyondGrace(ctx) {
+		return
+	}
+
+	var input dto.ScheduleTerminalFleetInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	planInterface, _ := ctx.Get("subscription_plan")
+	plan, ok := planInterface.(*paymentModels.SubscriptionPlan)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, &errors.APIError{
+			ErrorCode:    http.StatusForbidden,
+			ErrorMessage: "Active subscription required to schedule terminals",
+		})
+		return
+	}
+
+	fleet, err := fc.fleetService.Schedule(groupID, ctx.GetString("userId"), input, plan, time.Now())
+	if err != nil {
+		fc.respondFleetError(ctx, err, "Failed to schedule the fleet")
+		return
+	}
+	ctx.JSON(http.StatusCreated, fleet)
+}
+
+// ListFleets godoc
+//
+//	@Summary		List a group's terminal fleets
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id	path	string	true	"Group ID"
+//	@Security		Bearer
+//	@Success		200	{array}		models.TerminalFleet
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		500	{object}	errors.APIError	"Internal server error"
+//	@Router			/class-groups/{id}/terminal-fleets [get]
+func (fc *terminalFleetController) ListFleets(ctx *gin.Context) {
+	groupID, ok := fc.managedGroupID(ctx)
+	if !ok {
+		return
+	}
+	fleets, err := fc.fleetService.ListForGroup(groupID)
+	if err != nil {
+		fc.respondFleetError(ctx, err, "Failed to list fleets")
+		return
+	}
+	ctx.JSON(http.StatusOK, fleets)
+}
+
+// GetFleet godoc
+//
+//	@Summary		Get a terminal fleet with its members' launch results
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id		path	string	true	"Group ID"
+//	@Param			fleetId	path	string	true	"Fleet ID"
+//	@Security		Bearer
+//	@Success		200	{object}	models.TerminalFleet
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Fleet not found"
+//	@Router			/class-groups/{id}/terminal-fleets/{fleetId} [get]
+func (fc *terminalFleetController) GetFleet(ctx *gin.Context) {
+	fleet, ok := fc.loadFleet(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, fleet)
+}
+
+// CancelFleet godoc
+//
+//	@Summary		Cancel a terminal fleet
+//	@Description	A scheduled fleet will not launch; the terminals of a launched one are stopped.
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id		path	string	true	"Group ID"
+//	@Param			fleetId	path	string	true	"Fleet ID"
+//	@Security		Bearer
+//	@Success		200	{object}	models.TerminalFleet
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Fleet not found"
+//	@Failure		409	{object}	errors.APIError	"Fleet already finished"
+//	@Router			/class-groups/{id}/terminal-fleets/{fleetId}/cancel [post]
+func (fc *terminalFleetController) CancelFleet(ctx *gin.Context) {
+	fleet, ok := fc.loadFleet(ctx)
+	if !ok {
+		return
+	}
+	cancelled, err := fc.fleetService.Cancel(fleet.ID)
+	if err != nil {
+		fc.respondFleetError(ctx, err, "Failed to cancel the fleet")
+		return
+	}
+	ctx.JSON(http.StatusOK, cancelled)
+}
+
+// managedGroupID parses the group id and checks the caller manages the
+// group, writing the error response when either fails.
+func (fc *terminalFleetController) managedGroupID(ctx *gin.Context) (uuid.UUID, bool) {
+	groupID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: "Invalid group ID",
+		})
+		return uuid.Nil, false
+	}
+	if !access.IsAdmin(ctx.GetStringSlice("userRoles")) && !callerManagesGroup(fc.db, groupID, ctx.GetString("userId")) {
+		ctx.JSON(http.StatusForbidden, &errors.APIError{
+			ErrorCode:    http.StatusForbidden,
+			ErrorMessage: "You are not a manager of this group",
+		})
+		return uuid.Nil, false
+	}
+	return groupID, true
+}
+
+// loadFleet loads the fleet named by the path, which must belong to a group
+// the caller manages.
+func (fc *terminalFleetController) loadFleet(ctx *gin.Context) (*models.TerminalFleet, bool) {
+	groupID, ok := fc.managedGroupID(ctx)
+	if !ok {
+		return nil, false
+	}
+	fleetID, err := uuid.Parse(ctx.Param("fleetId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: "Invalid fleet ID",
+		})
+		return nil, false
+	}
+	fleet, err := fc.fleetService.Get(fleetID)
+	if err == nil && fleet.GroupID != groupID {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		fc.respondFleetError(ctx, err, "Failed to load the fleet")
+		return nil, false
+	}
+	return fleet, true
+}
+
+func (fc *terminalFleetController) respondFleetError(ctx *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+		err = stderrors.New("group or fleet not found")
+	case stderrors.Is(err, services.ErrFleetInvalidWindow),
+		stderrors.Is(err, services.ErrFleetInvalidSize),
+		stderrors.Is(err, services.ErrFleetInvalidOrg),
+		stderrors.Is(err, services.ErrFleetNoMembers):
+		status = http.StatusBadRequest
+	case stderrors.Is(err, services.ErrFleetBudgetExceeded):
+		status = http.StatusForbidden
+	case stderrors.Is(err, services.ErrFleetFinished):
+		status = http.StatusConflict
+	}
+	if status == http.StatusInternalServerError {
+		slog.Error("terminal fleet operation failed", "err", err)
+		ctx.JSON(status, &errors.APIError{
+			ErrorCode:    status,
+			ErrorMessage: message,
+		})
+		return
+	}
+	ctx.JSON(status, &errors.APIError{
+		ErrorCode:    status,
+		ErrorMessage: err.Error(),
+	})
+}
@@ -64,6 +64,17 @@ func TerminalRoutes(router *gin.RouterGroup, config *config.Configuration, db *g
 	)...)
 	groupRoutes.GET("/:id/command-history", middleware.AuthManagement(), terminalController.GetGroupCommandHistory)
 	groupRoutes.GET("/:id/command-history-stats", middleware.AuthManagement(), terminalController.GetGroupCommandHistoryStats)
+	// Scheduled fleets: the plan chain resolves the plan the fleet's budget
+	// reservation is checked against.
+	fleetController := NewTerminalFleetController(db, terminalService)
+	groupRoutes.POST("/:id/terminal-fleets", paymentMiddleware.WithPlanChain(
+		db, entityManagementInterfaces.PlanRequirement{RequirePlan: true}, terminalService,
+		[]gin.HandlerFunc{middleware.AuthManagement()},
+		fleetController.ScheduleFleet,
+	)...)
+	groupRoutes.GET("/:id/terminal-fleets", middleware.AuthManagement(), fleetController.ListFleets)
+	groupRoutes.GET("/:id/terminal-fleets/:fleetId", middleware.AuthManagement(), fleetController.GetFleet)
+	groupRoutes.POST("/:id/terminal-fleets/:fleetId/cancel", middleware.AuthManagement(), fleetController.CancelFleet)
 	// Supervision (#425): a group's active member terminal sessions (manager+).
 	groupRoutes.GET("/:id/terminal-sessions", middleware.AuthManagement(), terminalController.GetGroupTerminalSessions)
 
@@ -99,6 +110,21 @@ func TerminalRoutes(router *gin.RouterGroup, config *config.Configuration, db *g
 	// Permanently delete a session — ownership enforced via Layer 2,
 	// StateStopped allowed.
 	routes.DELETE("/:id", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), terminalController.DeleteSession)
+	// Snapshots of persistent terminals — owner or a trainer of their group
+	// (Layer 2), StateStopped allowed: a stopped persistent disk is exactly
+	// what a trainer resets a learner from.
+	snapshotController := NewTerminalSnapshotController(terminalService)
+	routes.GET("/:id/snapshots", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), snapshotController.ListSnapshots)
+	routes.POST("/:id/snapshots", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), snapshotController.CreateSnapshot)
+	routes.POST("/:id/snapshots/:name/restore", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), snapshotController.RestoreSnapshot)
+	routes.DELETE("/:id/snapshots/:name", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), snapshotController.DeleteSnapshot)
+	// Shared terminals — Layer 2 lets the team itself in; the controller
+	// keeps changes to the team for the owner and trainers.
+	teamController := NewTerminalTeamController(terminalService)
+	routes.GET("/:id/team", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), teamController.GetTeam)
+	routes.POST("/:id/team", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), teamController.ShareTerminal)
+	routes.PATCH("/:id/team/:userId", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), teamController.SetTeamRole)
+	routes.DELETE("/:id/team/:userId", middleware.AuthManagement(), terminalAccessMiddleware.RequireTerminalAccessAllowStopped(), teamController.RemoveTeamMember)
 	routes.GET("/user-sessions", middleware.AuthManagement(), terminalController.GetUserSessions)
 
 	// Sync routes (Layer 2 security checks)
@@ -112,6 +138,7 @@ func TerminalRoutes(router *gin.RouterGroup, config *config.Configuration, db *g
 	routes.DELETE("/my-history", middleware.AuthManagement(), terminalController.DeleteAllUserHistory)
 	routes.GET("/:id/history", middleware.AuthManagement(), terminalController.GetSessionHistory)
 	routes.DELETE("/:id/history", middleware.AuthManagement(), terminalController.DeleteSessionHistory)
+	routes.GET("/:id/recording", middleware.AuthManagement(), terminalController.GetSessionRecording)
 
 	// Consent status (checks org/group-level consent policy)
 	routes.GET("/consent-status", middleware.AuthManagement(), terminalController.GetConsentStatus)
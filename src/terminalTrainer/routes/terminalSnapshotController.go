@@ -0,0 +1,152 @@
+package terminalController
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/terminalTrainer/dto"
+	services "soli/formations/src/terminalTrainer/services"
+)
+
+// terminalSnapshotController manages the named snapshots of a persistent
+// terminal. Access is enforced by the terminal access middleware on every
+// route: the terminal's owner, a trainer of a group they belong to, or an
+// administrator.
+type terminalSnapshotController struct {
+	service services.TerminalTrainerService
+}
+
+func NewTerminalSnapshotController(service services.TerminalTrainerService) *terminalSnapshotController {
+	return &terminalSnapshotController{service: service}
+}
+
+// ListSnapshots godoc
+//
+//	@Summary		List a terminal's snapshots
+//	@Description	Returns the terminal's snapshots, newest first, with the storage the owner's snapshots use against their plan quota.
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id	path	string	true	"Terminal ID or session ID"
+//	@Security		Bearer
+//	@Success		200	{object}	dto.TerminalSnapshotsResponse
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Terminal not found"
+//	@Router			/terminals/{id}/snapshots [get]
+func (sc *terminalSnapshotController) ListSnapshots(ctx *gin.Context) {
+	snapshots, err := sc.service.ListSnapshots(ctx.Param("id"))
+	if err != nil {
+		sc.respondSnapshotError(ctx, err, "Failed to list snapshots")
+		return
+	}
+	ctx.JSON(http.StatusOK, snapshots)
+}
+
+// CreateSnapshot godoc
+//
+//	@Summary		Snapshot a persistent terminal
+//	@Description	Checkpoints the terminal's persistent disk under a name. The snapshot counts against the owner's plan storage (data_persistence_gb).
+//	@Tags			terminals
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string								true	"Terminal ID or session ID"
+//	@Param			request	body	dto.CreateTerminalSnapshotInput	true	"Snapshot name"
+//	@Security		Bearer
+//	@Success		201	{object}	models.TerminalSnapshot
+//	@Failure		400	{object}	errors.APIError	"Invalid name"
+//	@Failure		403	{object}	errors.APIError	"Access denied, not in plan or quota exceeded"
+//	@Failure		404	{object}	errors.APIError	"Terminal not found"
+//	@Failure		409	{object}	errors.APIError	"Terminal not persistent or name taken"
+//	@Router			/terminals/{id}/snapshots [post]
+func (sc *terminalSnapshotController) CreateSnapshot(ctx *gin.Context) {
+	var input dto.CreateTerminalSnapshotInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	snapshot, err := sc.service.CreateSnapshot(ctx.Param("id"), input.Name, ctx.GetString("userId"), nil)
+	if err != nil {
+		sc.respondSnapshotError(ctx, err, "Failed to create the snapshot")
+		return
+	}
+	ctx.JSON(http.StatusCreated, snapshot)
+}
+
+// RestoreSnapshot godoc
+//
+//	@Summary		Restore a terminal to a snapshot
+//	@Description	Rolls the terminal's persistent disk back to the snapshot. The snapshot is kept and can be restored again.
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id		path	string	true	"Terminal ID or session ID"
+//	@Param			name	path	string	true	"Snapshot name"
+//	@Security		Bearer
+//	@Success		200	{object}	map[string]string
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Terminal or snapshot not found"
+//	@Failure		409	{object}	errors.APIError	"Terminal not persistent"
+//	@Router			/terminals/{id}/snapshots/{name}/restore [post]
+func (sc *terminalSnapshotController) RestoreSnapshot(ctx *gin.Context) {
+	if err := sc.service.RestoreSnapshot(ctx.Param("id"), ctx.Param("name")); err != nil {
+		sc.respondSnapshotError(ctx, err, "Failed to restore the snapshot")
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Snapshot restored successfully"})
+}
+
+// DeleteSnapshot godoc
+//
+//	@Summary		Delete a terminal snapshot
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id		path	string	true	"Terminal ID or session ID"
+//	@Param			name	path	string	true	"Snapshot name"
+//	@Security		Bearer
+//	@Success		200	{object}	map[string]string
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Terminal or snapshot not found"
+//	@Router			/terminals/{id}/snapshots/{name} [delete]
+func (sc *terminalSnapshotController) DeleteSnapshot(ctx *gin.Context) {
+	if err := sc.service.DeleteSnapshot(ctx.Param("id"), ctx.Param("name")); err != nil {
+		sc.respondSnapshotError(ctx, err, "Failed to delete the snapshot")
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Snapshot deleted successfully"})
+}
+
+func (sc *terminalSnapshotController) respondSnapshotError(ctx *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+		err = stderrors.New("terminal or snapshot not found")
+	case stderrors.Is(err, services.ErrSnapshotInvalidName):
+		status = http.StatusBadRequest
+	case stderrors.Is(err, services.ErrSnapshotsNotInPlan),
+		stderrors.Is(err, services.ErrSnapshotQuotaExceeded):
+		status = http.StatusForbidden
+	case stderrors.Is(err, services.ErrSnapshotNotPersistent),
+		stderrors.Is(err, services.ErrSnapshotExists):
+		status = http.StatusConflict
+	}
+	if status == http.StatusInternalServerError {
+		slog.Error("terminal snapshot operation failed", "err", err)
+		ctx.JSON(status, &errors.APIError{
+			ErrorCode:    status,
+			ErrorMessage: message,
+		})
+		return
+	}
+	ctx.JSON(status, &errors.APIError{
+		ErrorCode:    status,
+		ErrorMessage: err.Error(),
+	})
+}
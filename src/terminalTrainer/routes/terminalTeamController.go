@@ -0,0 +1,229 @@
+package terminalController
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"soli/formations/src/auth/access"
+	"soli/formations/src/auth/errors"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	services "soli/formations/src/terminalTrainer/services"
+)
+
+// terminalTeamController manages shared terminals. The terminal access
+// middleware on every route lets in its owner, its team, a trainer of a group
+// the owner belongs to, and administrators; within that, changing the team is
+// for the owner, trainers and administrators, while team members may hand
+// over the driver's seat when they hold it, and leave.
+type terminalTeamController struct {
+	service            services.TerminalTrainerService
+	terminalTrainerURL string
+	apiVersion         string
+}
+
+func NewTerminalTeamController(service services.TerminalTrainerService) *terminalTeamController {
+	apiVersion := os.Getenv("TERMINAL_TRAINER_API_VERSION")
+	if apiVersion == "" {
+		apiVersion = "1.0"
+	}
+	return &terminalTeamController{
+		service:            service,
+		terminalTrainerURL: os.Getenv("TERMINAL_TRAINER_URL"),
+		apiVersion:         apiVersion,
+	}
+}
+
+// GetTeam godoc
+//
+//	@Summary		Get a shared terminal's team
+//	@Description	Returns the learners attached to the terminal and their roles. Members is empty when the terminal is not shared.
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id	path	string	true	"Terminal ID or session ID"
+//	@Security		Bearer
+//	@Success		200	{object}	dto.TerminalTeamResponse
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Terminal not found"
+//	@Router			/terminals/{id}/team [get]
+func (tm *terminalTeamController) GetTeam(ctx *gin.Context) {
+	team, err := tm.service.GetTerminalTeam(ctx.Param("id"))
+	if err != nil {
+		tm.respondTeamError(ctx, err, "Failed to get the team")
+		return
+	}
+	ctx.JSON(http.StatusOK, team)
+}
+
+// ShareTerminal godoc
+//
+//	@Summary		Share a terminal with a team
+//	@Description	Attaches learners to the terminal as navigators: the active members of a sub-group its owner belongs to, or classmates of the owner named one by one. The first share makes the owner the driver. The terminal stays the owner's, counted against their plan.
+//	@Tags			terminals
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string					true	"Terminal ID or session ID"
+//	@Param			request	body	dto.ShareTerminalInput	true	"Sub-group and/or learners"
+//	@Security		Bearer
+//	@Success		200	{object}	dto.TerminalTeamResponse
+//	@Failure		400	{object}	errors.APIError	"No learner to add"
+//	@Failure		403	{object}	errors.APIError	"Access denied or learner not a classmate"
+//	@Failure		404	{object}	errors.APIError	"Terminal not found"
+//	@Router			/terminals/{id}/team [post]
+func (tm *terminalTeamController) ShareTerminal(ctx *gin.Context) {
+	if !tm.requireManager(ctx) {
+		return
+	}
+	var input dto.ShareTerminalInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	team, err := tm.service.ShareTerminal(ctx.Param("id"), input, ctx.GetString("userId"))
+	if err != nil {
+		tm.respondTeamError(ctx, err, "Failed to share the terminal")
+		return
+	}
+	ctx.JSON(http.StatusOK, team)
+}
+
+// SetTeamRole godoc
+//
+//	@Summary		Change a team member's role
+//	@Description	Makes a member the driver or a navigator. A new driver takes over from the previous one, and the open consoles of the team switch to their new attachment roles. The current driver may hand over the seat; other changes are for the owner or a trainer.
+//	@Tags			terminals
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string							true	"Terminal ID or session ID"
+//	@Param			userId	path	string							true	"Team member's user ID"
+//	@Param			request	body	dto.SetTerminalTeamRoleInput	true	"New role"
+//	@Security		Bearer
+//	@Success		200	{object}	dto.TerminalTeamResponse
+//	@Failure		400	{object}	errors.APIError	"Invalid role"
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Terminal or team member not found"
+//	@Router			/terminals/{id}/team/{userId} [patch]
+func (tm *terminalTeamController) SetTeamRole(ctx *gin.Context) {
+	var input dto.SetTerminalTeamRoleInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, &errors.APIError{
+			ErrorCode:    http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	callerRole, err := tm.service.GetTerminalTeamRole(ctx.Param("id"), ctx.GetString("userId"))
+	if err != nil {
+		tm.respondTeamError(ctx, err, "Failed to change the role")
+		return
+	}
+	if callerRole != models.TeamRoleDriver && !tm.requireManager(ctx) {
+		return
+	}
+
+	team, err := tm.service.SetTerminalTeamRole(ctx.Param("id"), ctx.Param("userId"), input.Role)
+	if err != nil {
+		tm.respondTeamError(ctx, err, "Failed to change the role")
+		return
+	}
+	if key, err := tm.service.GetUserKey(team.OwnerID); err == nil {
+		applyTeamAttachmentRoles(tm.terminalTrainerURL, tm.apiVersion, team, key.APIKey)
+	} else {
+		slog.Error("team role changed but the owner's key is missing; open consoles keep their role", "session_id", team.SessionID, "err", err)
+	}
+	ctx.JSON(http.StatusOK, team)
+}
+
+// RemoveTeamMember godoc
+//
+//	@Summary		Remove a member from a terminal's team
+//	@Description	Detaches a learner from the shared terminal; a learner may also leave on their own. The owner drives again if the driver left, and the team is dissolved once only the owner is left.
+//	@Tags			terminals
+//	@Produce		json
+//	@Param			id		path	string	true	"Terminal ID or session ID"
+//	@Param			userId	path	string	true	"Team member's user ID"
+//	@Security		Bearer
+//	@Success		200	{object}	dto.TerminalTeamResponse
+//	@Failure		403	{object}	errors.APIError	"Access denied"
+//	@Failure		404	{object}	errors.APIError	"Terminal or team member not found"
+//	@Failure		409	{object}	errors.APIError	"The owner cannot be removed"
+//	@Router			/terminals/{id}/team/{userId} [delete]
+func (tm *terminalTeamController) RemoveTeamMember(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+	if userID != ctx.GetString("userId") && !tm.requireManager(ctx) {
+		return
+	}
+
+	team, err := tm.service.RemoveTerminalTeamMember(ctx.Param("id"), userID)
+	if err != nil {
+		tm.respondTeamError(ctx, err, "Failed to remove the team member")
+		return
+	}
+	// The member's open consoles lose their say at once; with the access gone
+	// they cannot reconnect.
+	if key, err := tm.service.GetUserKey(team.OwnerID); err == nil {
+		setConsoleAttachmentRoles(tm.terminalTrainerURL, tm.apiVersion, team.SessionID, userID, "observer", key.APIKey)
+		applyTeamAttachmentRoles(tm.terminalTrainerURL, tm.apiVersion, team, key.APIKey)
+	}
+	ctx.JSON(http.StatusOK, team)
+}
+
+// requireManager answers 403 and reports false unless the caller may change
+// the team.
+func (tm *terminalTeamController) requireManager(ctx *gin.Context) bool {
+	if access.IsAdmin(ctx.GetStringSlice("userRoles")) {
+		return true
+	}
+	allowed, err := tm.service.CanManageTerminalTeam(ctx.Param("id"), ctx.GetString("userId"))
+	if err != nil {
+		tm.respondTeamError(ctx, err, "Failed to check team access")
+		return false
+	}
+	if !allowed {
+		ctx.JSON(http.StatusForbidden, &errors.APIError{
+			ErrorCode:    http.StatusForbidden,
+			ErrorMessage: "Only the terminal's owner or a trainer can change its team",
+		})
+		return false
+	}
+	return true
+}
+
+func (tm *terminalTeamController) respondTeamError(ctx *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+		err = stderrors.New("terminal or team member not found")
+	case stderrors.Is(err, services.ErrTeamNoMembers),
+		stderrors.Is(err, services.ErrTeamInvalidRole):
+		status = http.StatusBadRequest
+	case stderrors.Is(err, services.ErrTeamNotClassmate),
+		stderrors.Is(err, services.ErrTeamGroupNotOwners):
+		status = http.StatusForbidden
+	case stderrors.Is(err, services.ErrTeamOwnerRemoval):
+		status = http.StatusConflict
+	}
+	if status == http.StatusInternalServerError {
+		slog.Error("terminal team operation failed", "err", err)
+		ctx.JSON(status, &errors.APIError{
+			ErrorCode:    status,
+			ErrorMessage: message,
+		})
+		return
+	}
+	ctx.JSON(status, &errors.APIError{
+		ErrorCode:    status,
+		ErrorMessage: err.Error(),
+	})
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"soli/formations/src/terminalTrainer/dto"
+)
+
+var (
+	// ErrRecordingInvalid is returned when tt-backend's recording is not an
+	// asciicast v2 file.
+	ErrRecordingInvalid = errors.New("recording is not a valid asciicast v2 file")
+	// ErrRecordingExpired is returned when nothing of the recording is left
+	// within its plan's command history retention.
+	ErrRecordingExpired = errors.New("recording is past its plan's retention period")
+)
+
+// asciicast is a parsed asciicast v2 recording: a JSON header line, then one
+// JSON array per event — [time, code, data], time in seconds from the start.
+// Codes are "o" (output), "i" (input), "r" (resize) and "m" (marker).
+//
+// The header is kept as a map so fields this package does not know about
+// (env, theme, title, …) reach the player untouched.
+type asciicast struct {
+	header map[string]any
+	start  time.Time
+	events []castEvent
+}
+
+type castEvent struct {
+	Time float64
+	Code string
+	Data string
+}
+
+// parseAsciicast reads an asciicast v2 recording. fallbackStart dates a
+// recording whose header carries no timestamp.
+func parseAsciicast(body []byte, fallbackStart time.Time) (*asciicast, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	// One output event can hold a whole screen redraw; the default 64KB line
+	// limit would reject a perfectly valid recording.
+	scanner.Buffer(make([]byte, 64*1024), maxRecordingSize)
+
+	if !scanner.Scan() {
+		return nil, ErrRecordingInvalid
+	}
+	var header map[string]any
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, ErrRecordingInvalid
+	}
+	if version, _ := header["version"].(float64); version != 2 {
+		return nil, ErrRecordingInvalid
+	}
+
+	cast := &asciicast{header: header, start: fallbackStart}
+	if ts, ok := header["timestamp"].(float64); ok && ts > 0 {
+		cast.start = time.Unix(int64(ts), 0)
+	}
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var raw []json.RawMessage
+		if err := json.Unmarshal(line, &raw); err != nil || len(raw) != 3 {
+			return nil, ErrRecordingInvalid
+		}
+		var event castEvent
+		if json.Unmarshal(raw[0], &event.Time) != nil ||
+			json.Unmarshal(raw[1], &event.Code) != nil ||
+			json.Unmarshal(raw[2], &event.Data) != nil {
+			return nil, ErrRecordingInvalid
+		}
+		cast.events = append(cast.events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ErrRecordingInvalid
+	}
+	return cast, nil
+}
+
+// offset converts an absolute time to seconds from the start of the recording.
+func (c *asciicast) offset(at time.Time) float64 {
+	return at.Sub(c.start).Seconds()
+}
+
+// replay cuts the recording down to what may be shown and what was asked for,
+// returning a new recording that starts at the window.
+//
+// Events before notBefore are past the plan's retention and are dropped
+// outright. Output between there and the window start is not dropped but
+// folded into one event at the start of the replay, along with the last
+// resize: seeking has to show the screen as it was at that moment, not the
+// fragment written after it.
+func (c *asciicast) replay(notBefore time.Time, opts dto.RecordingReplayOptions) (*asciicast, error) {
+	cutoff := math.Inf(-1)
+	if !notBefore.IsZero() {
+		cutoff = c.offset(notBefore)
+		if len(c.events) > 0 && c.events[len(c.events)-1].Time < cutoff {
+			return nil, ErrRecordingExpired
+		}
+	}
+
+	from, to := 0.0, math.Inf(1)
+	if opts.From != nil {
+		from = *opts.From
+	}
+	if opts.To != nil {
+		to = *opts.To
+	}
+	if opts.Since != nil {
+		from = c.offset(*opts.Since)
+	}
+	if opts.Until != nil {
+		to = c.offset(*opts.Until)
+	}
+	from = math.Max(from, math.Max(cutoff, 0))
+
+	var screen strings.Builder
+	var lastResize *castEvent
+	events := []castEvent{}
+	for i := range c.events {
+		event := c.events[i]
+		switch {
+		case event.Time < cutoff:
+			continue
+		case event.Time < from:
+			if event.Code == "o" {
+				screen.WriteString(event.Data)
+			} else if event.Code == "r" {
+				lastResize = &c.events[i]
+			}
+		case event.Time <= to:
+			event.Time -= from
+			events = append(events, event)
+		}
+	}
+	for _, marker := range opts.Markers {
+		at := c.offset(marker.At)
+		if at >= from && at <= to {
+			events = append(events, castEvent{Time: at - from, Code: "m", Data: marker.Label})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time < events[j].Time })
+
+	var prefix []castEvent
+	if lastResize != nil {
+		prefix = append(prefix, castEvent{Code: "r", Data: lastResize.Data})
+	}
+	if screen.Len() > 0 {
+		prefix = append(prefix, castEvent{Code: "o", Data: screen.String()})
+	}
+
+	header := make(map[string]any, len(c.header))
+	for k, v := range c.header {
+		header[k] = v
+	}
+	start := c.start.Add(time.Duration(from * float64(time.Second)))
+	header["timestamp"] = start.Unix()
+	// The original duration no longer describes the cut; players work it out
+	// from the last event.
+	delete(header, "duration")
+
+	return &asciicast{header: header, start: start, events: append(prefix, events...)}, nil
+}
+
+// encode writes the recording back out as asciicast v2.
+func (c *asciicast) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	line, err := json.Marshal(c.header)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+	for _, event := range c.events {
+		// Microseconds are all the format's players resolve; more digits only
+		// make the file bigger.
+		t := math.Round(event.Time*1e6) / 1e6
+		line, err := json.Marshal([]any{t, event.Code, event.Data})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
@@ -189,3 +189,26 @@ func EnforceLaunchCapacity(ctx *gin.Context, plan *paymentModels.SubscriptionPla
 	})
 	return true
 }
+
+// EvaluateFleetCapacity answers EvaluateLaunchCapacity's question for count
+// sessions of requestedSize launched together: the first count-1 sessions'
+// RAM is taken off the available RAM before the last one is evaluated, so
+// the verdict is the one the server would give the final launch.
+func EvaluateFleetCapacity(plan *paymentModels.SubscriptionPlan, requestedSize string, count int, metrics *dto.ServerMetricsResponse) CapacityResult {
+	if metrics == nil || count <= 1 {
+		return EvaluateLaunchCapacity(plan, requestedSize, metrics)
+	}
+	denom := 1.0 - metrics.RAMPercent/100.0
+	if denom <= 0 {
+		return CapacityResult{Status: CapacityStatusCritical, Reason: "ram_full"}
+	}
+	totalRAM := metrics.RAMAvailableGB / denom
+
+	projected := *metrics
+	projected.RAMAvailableGB -= float64(count-1) * resolveRequiredRAM(plan, requestedSize)
+	if projected.RAMAvailableGB <= 0 || totalRAM <= 0 {
+		return CapacityResult{Status: CapacityStatusCritical, Reason: "insufficient_ram_for_size"}
+	}
+	projected.RAMPercent = 100.0 * (1.0 - projected.RAMAvailableGB/totalRAM)
+	return EvaluateLaunchCapacity(plan, requestedSize, &projected)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	authModels "soli/formations/src/auth/models"
@@ -19,6 +20,7 @@ import (
 	"soli/formations/src/terminalTrainer/models"
 	"soli/formations/src/terminalTrainer/repositories"
 	"soli/formations/src/utils"
+	webhookServices "soli/formations/src/webhooks/services"
 
 	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
 	"github.com/google/uuid"
@@ -34,8 +36,8 @@ import (
 //
 // It was carved out of terminalTrainerService to shrink that god object;
 // terminalTrainerService holds a *terminalComposer and delegates the
-// StartComposedSession and BulkCreateTerminalsForGroup interface methods
-// to it.
+// StartComposedSession, StartComposedTopology and BulkCreateTerminalsForGroup
+// interface methods to it.
 //
 // Collaborators: catalog supplies the session-options used for validation;
 // proxy owns the tt-backend HTTP layer (server metrics, console path,
@@ -411,18 +413,30 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 		return nil, fmt.Errorf("invalid subscription plan type")
 	}
 
+	input, orgID, err := c.prepareComposedSession(input, plan)
+	if err != nil {
+		return nil, err
+	}
+	return c.startComposedSession(userID, orgID, plan, input)
+}
+
+// prepareComposedSession validates a composed-session request against the
+// plan and fills in what the service layer derives from it: the
+// distribution prefix, the size footprint, the backend, the idle window and
+// the plan caps. It returns the completed input and the parsed organization.
+func (c *terminalComposer) prepareComposedSession(input dto.CreateComposedSessionInput, plan *paymentModels.SubscriptionPlan) (dto.CreateComposedSessionInput, *uuid.UUID, error) {
 	// Resolve effective persistence mode (free tier hard-fails; empty defaults to ephemeral).
 	// Done up-front so we never hit tt-backend with a request the plan forbids.
 	effectiveMode, persistErr := resolvePersistenceMode(input.PersistenceMode, plan)
 	if persistErr != nil {
-		return nil, persistErr
+		return input, nil, persistErr
 	}
 	input.PersistenceMode = effectiveMode
 
 	// Compute session options to validate the request
 	options, err := c.catalog.GetSessionOptions(plan, input.Distribution, input.Backend)
 	if err != nil {
-		return nil, err
+		return input, nil, err
 	}
 
 	// Store the distribution prefix for console URL and InstanceType
@@ -434,14 +448,14 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 	for _, s := range options.AllowedSizes {
 		if NormalizeSizeKey(s.Key) == requestedSizeNorm {
 			if !s.Allowed {
-				return nil, fmt.Errorf("size '%s' is not allowed: %s", input.Size, s.Reason)
+				return input, nil, fmt.Errorf("size '%s' is not allowed: %s", input.Size, s.Reason)
 			}
 			sizeAllowed = true
 			break
 		}
 	}
 	if !sizeAllowed {
-		return nil, fmt.Errorf("size '%s' not found in catalog", input.Size)
+		return input, nil, fmt.Errorf("size '%s' not found in catalog", input.Size)
 	}
 
 	// Validate requested features
@@ -456,10 +470,10 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 			}
 			opt, exists := featureAllowedMap[featureKey]
 			if !exists {
-				return nil, fmt.Errorf("feature '%s' not found in catalog", featureKey)
+				return input, nil, fmt.Errorf("feature '%s' not found in catalog", featureKey)
 			}
 			if !opt.Allowed {
-				return nil, fmt.Errorf("feature '%s' is not allowed: %s", featureKey, opt.Reason)
+				return input, nil, fmt.Errorf("feature '%s' is not allowed: %s", featureKey, opt.Reason)
 			}
 		}
 	}
@@ -470,7 +484,7 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 	// network feature. Reject before touching tt-backend so no container boots
 	// with a package install that would silently fail.
 	if len(input.Packages) > 0 && !(plan.NetworkAccessEnabled && input.Features["network"]) {
-		return nil, fmt.Errorf("custom packages require network access, which is not enabled for this session")
+		return input, nil, fmt.Errorf("custom packages require network access, which is not enabled for this session")
 	}
 
 	// Validate backend
@@ -478,7 +492,7 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 	if input.OrganizationID != "" {
 		parsed, err := uuid.Parse(input.OrganizationID)
 		if err != nil {
-			return nil, fmt.Errorf("invalid organization_id: %w", err)
+			return input, nil, fmt.Errorf("invalid organization_id: %w", err)
 		}
 		orgID = &parsed
 	}
@@ -504,7 +518,7 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 
 	validatedBackend, err := c.validateBackendForContext(orgID, plan, input.Backend)
 	if err != nil {
-		return nil, err
+		return input, nil, err
 	}
 	input.Backend = validatedBackend
 
@@ -524,7 +538,7 @@ func (c *terminalComposer) StartComposedSession(userID string, input dto.CreateC
 	input.HistoryRetentionDays = plan.CommandHistoryRetentionDays
 	input.SubscriptionPlanID = &plan.ID
 
-	return c.startComposedSession(userID, orgID, plan, input)
+	return input, orgID, nil
 }
 
 // resolveIdleWindowSeconds returns the org-level idle window override for the
@@ -572,37 +586,27 @@ func composedFeaturesJSON(features map[string]bool) string {
 // itself). The lock is released when the transaction commits, before any
 // container is provisioned.
 //
-// It returns exactly one of: a committed reservation (rejection nil), a
-// *BudgetRejection (reservation nil) when the budget is exhausted, or an
-// error for an infrastructure failure.
+// A multi-node topology reserves all of its nodes at once: the gate is run
+// against their summed footprint and one row is inserted per node, so a
+// topology either fits whole or is refused before any of its containers
+// boots, rather than failing halfway with some nodes already running.
+//
+// It returns exactly one of: committed reservations, one per input and in
+// the same order (rejection nil), a *BudgetRejection (reservations nil) when
+// the budget is exhausted, or an error for an infrastructure failure.
 func (c *terminalComposer) reserveBudget(
 	userID string,
 	orgID *uuid.UUID,
 	plan *paymentModels.SubscriptionPlan,
 	userKey *models.UserTerminalKey,
-	input dto.CreateComposedSessionInput,
-) (*models.Terminal, *BudgetRejection, error) {
-	// Unique placeholder id: Terminal.SessionID is uniquely indexed, so a
-	// shared or empty placeholder would collide between concurrent
-	// reservations and fail on the index instead of the budget gate. The
-	// real tt-backend id replaces this on finalize.
-	reservation := &models.Terminal{
-		SessionID:            models.TerminalReservationSessionIDPrefix + uuid.NewString(),
-		UserID:               userID,
-		Name:                 input.Name,
-		State:                models.StateStarting,
-		PersistenceMode:      input.PersistenceMode,
-		ExpiresAt:            time.Now().Add(reservationTTL),
-		InstanceType:         input.DistributionPrefix,
-		MachineSize:          strings.ToUpper(input.Size),
-		OrganizationID:       orgID,
-		SubscriptionPlanID:   input.SubscriptionPlanID,
-		UserTerminalKeyID:    userKey.ID,
-		ComposedDistribution: input.Distribution,
-		ComposedSize:         input.Size,
-		ComposedFeatures:     composedFeaturesJSON(input.Features),
-		SizeCPU:              input.SizeCPU,
-		SizeMemoryMB:         input.SizeMemoryMB,
+	inputs []dto.CreateComposedSessionInput,
+) ([]*models.Terminal, *BudgetRejection, error) {
+	reservations := make([]*models.Terminal, 0, len(inputs))
+	var requestedCPU, requestedMemMB int
+	for _, input := range inputs {
+		reservations = append(reservations, newReservation(userID, orgID, userKey, input))
+		requestedCPU += input.SizeCPU
+		requestedMemMB += input.SizeMemoryMB
 	}
 
 	// The budget pool comes from the plan that resolves, not from the request's
@@ -616,7 +620,7 @@ func (c *terminalComposer) reserveBudget(
 
 	var rejection *BudgetRejection
 	err := c.db.Transaction(func(tx *gorm.DB) error {
-		enforcement, enforceErr := c.quotaService.EnforceBudgetTx(tx, userID, scopeOrgID, plan, input.SizeCPU, input.SizeMemoryMB)
+		enforcement, enforceErr := c.quotaService.EnforceBudgetTx(tx, userID, scopeOrgID, plan, requestedCPU, requestedMemMB)
 		if enforceErr != nil {
 			return fmt.Errorf("budget check failed: %w", enforceErr)
 		}
@@ -624,7 +628,12 @@ func (c *terminalComposer) reserveBudget(
 			rejection = rejectionFromBudgetCheck(enforcement.BudgetCheck)
 			return nil
 		}
-		return c.repository.CreateReservation(tx, reservation)
+		for _, reservation := range reservations {
+			if err := c.repository.CreateReservation(tx, reservation); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, nil, err
@@ -632,7 +641,34 @@ func (c *terminalComposer) reserveBudget(
 	if rejection != nil {
 		return nil, rejection, nil
 	}
-	return reservation, nil, nil
+	return reservations, nil, nil
+}
+
+// newReservation builds the StateStarting row that holds a composed
+// session's budget while its container is provisioned.
+func newReservation(userID string, orgID *uuid.UUID, userKey *models.UserTerminalKey, input dto.CreateComposedSessionInput) *models.Terminal {
+	// Unique placeholder id: Terminal.SessionID is uniquely indexed, so a
+	// shared or empty placeholder would collide between concurrent
+	// reservations and fail on the index instead of the budget gate. The
+	// real tt-backend id replaces this on finalize.
+	return &models.Terminal{
+		SessionID:            models.TerminalReservationSessionIDPrefix + uuid.NewString(),
+		UserID:               userID,
+		Name:                 input.Name,
+		State:                models.StateStarting,
+		PersistenceMode:      input.PersistenceMode,
+		ExpiresAt:            time.Now().Add(reservationTTL),
+		InstanceType:         input.DistributionPrefix,
+		MachineSize:          strings.ToUpper(input.Size),
+		OrganizationID:       orgID,
+		SubscriptionPlanID:   input.SubscriptionPlanID,
+		UserTerminalKeyID:    userKey.ID,
+		ComposedDistribution: input.Distribution,
+		ComposedSize:         input.Size,
+		ComposedFeatures:     composedFeaturesJSON(input.Features),
+		SizeCPU:              input.SizeCPU,
+		SizeMemoryMB:         input.SizeMemoryMB,
+	}
 }
 
 // releaseReservation hard-deletes a reservation row, returning its budget
@@ -667,20 +703,16 @@ func (c *terminalComposer) startComposedSession(
 	plan *paymentModels.SubscriptionPlan,
 	input dto.CreateComposedSessionInput,
 ) (*dto.TerminalSessionResponse, error) {
-	// Get user key
-	userKey, err := c.repository.GetUserTerminalKeyByUserID(userID, true)
+	userKey, err := c.activeUserKey(userID)
 	if err != nil {
-		return nil, fmt.Errorf("no terminal trainer key found for user: %w", err)
-	}
-	if !userKey.IsActive {
-		return nil, fmt.Errorf("user terminal trainer key is disabled")
+		return nil, err
 	}
 
 	// Reserve budget before provisioning. The reservation row is inserted
 	// in StateStarting inside a locked transaction so the budget sum and
 	// the insert serialise against concurrent starts for the same scope;
 	// the lock is released at commit, well before the tt-backend HTTP call.
-	reservation, rejection, err := c.reserveBudget(userID, orgID, plan, userKey, input)
+	reservations, rejection, err := c.reserveBudget(userID, orgID, plan, userKey, []dto.CreateComposedSessionInput{input})
 	if err != nil {
 		return nil, err
 	}
@@ -688,6 +720,32 @@ func (c *terminalComposer) startComposedSession(
 		return nil, rejection
 	}
 
+	return c.provisionReservation(userID, plan, userKey, reservations[0], input)
+}
+
+// activeUserKey returns the user's terminal trainer key, refusing a
+// disabled one.
+func (c *terminalComposer) activeUserKey(userID string) (*models.UserTerminalKey, error) {
+	userKey, err := c.repository.GetUserTerminalKeyByUserID(userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal trainer key found for user: %w", err)
+	}
+	if !userKey.IsActive {
+		return nil, fmt.Errorf("user terminal trainer key is disabled")
+	}
+	return userKey, nil
+}
+
+// provisionReservation boots the container a committed reservation holds
+// budget for and promotes the row to a running session, or releases it if
+// tt-backend does not deliver one.
+func (c *terminalComposer) provisionReservation(
+	userID string,
+	plan *paymentModels.SubscriptionPlan,
+	userKey *models.UserTerminalKey,
+	reservation *models.Terminal,
+	input dto.CreateComposedSessionInput,
+) (*dto.TerminalSessionResponse, error) {
 	// Compute terms hash
 	hash := sha256.New()
 	io.WriteString(hash, input.Terms)
@@ -731,6 +789,9 @@ func (c *terminalComposer) startComposedSession(
 	if input.IdleWindowSeconds != nil {
 		ttReqBody["idle_window_seconds"] = *input.IdleWindowSeconds
 	}
+	if input.PrivateNetwork != "" {
+		ttReqBody["private_network"] = input.PrivateNetwork
+	}
 	// Forward the plan's storage quota so tt-backend sizes the persistent
 	// volume. Only meaningful for a persistent session; a zero/absent quota
 	// keeps tt-backend's unsized default, so the key is omitted then (same
@@ -813,6 +874,7 @@ func (c *terminalComposer) startComposedSession(
 		c.releaseReservation(reservation.ID)
 		return nil, fmt.Errorf("failed to finalize terminal session: %w", err)
 	}
+	publishTerminalEvent(c.db, reservation, webhookServices.EventTerminalStarted)
 
 	// Build console URL
 	consolePath := c.proxy.buildAPIPath("/console", input.DistributionPrefix)
@@ -826,3 +888,65 @@ func (c *terminalComposer) startComposedSession(
 
 	return response, nil
 }
+
+// StartComposedTopology starts the machines of a multi-node topology, one
+// composed session per input. Every input is validated before anything is
+// reserved, and the budget gate sees the topology's summed footprint, so a
+// topology the plan cannot hold is refused whole. The nodes are then
+// provisioned in parallel — each is a multi-second container build.
+//
+// On failure the responses of the nodes that did start are returned with the
+// error, in input order (nil for the others), so the caller can stop them.
+func (c *terminalComposer) StartComposedTopology(userID string, inputs []dto.CreateComposedSessionInput, planInterface any) ([]*dto.TerminalSessionResponse, error) {
+	plan, ok := planInterface.(*paymentModels.SubscriptionPlan)
+	if !ok {
+		return nil, fmt.Errorf("invalid subscription plan type")
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("a topology needs at least one node")
+	}
+
+	prepared := make([]dto.CreateComposedSessionInput, len(inputs))
+	var orgID *uuid.UUID
+	for i, input := range inputs {
+		p, nodeOrgID, err := c.prepareComposedSession(input, plan)
+		if err != nil {
+			return nil, err
+		}
+		prepared[i] = p
+		if i == 0 {
+			orgID = nodeOrgID
+		}
+	}
+
+	userKey, err := c.activeUserKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	reservations, rejection, err := c.reserveBudget(userID, orgID, plan, userKey, prepared)
+	if err != nil {
+		return nil, err
+	}
+	if rejection != nil {
+		return nil, rejection
+	}
+
+	responses := make([]*dto.TerminalSessionResponse, len(prepared))
+	errs := make([]error, len(prepared))
+	var wg sync.WaitGroup
+	for i := range prepared {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = c.provisionReservation(userID, plan, userKey, reservations[i], prepared[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return responses, err
+		}
+	}
+	return responses, nil
+}
@@ -0,0 +1,568 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/payment/catalog"
+	paymentModels "soli/formations/src/payment/models"
+	paymentServices "soli/formations/src/payment/services"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/utils"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultFleetStaggerSeconds spaces a fleet's launches when the schedule does
+// not say otherwise.
+const DefaultFleetStaggerSeconds = 5
+
+// fleetMemberLaunchTimeout is how long a member may stay launching before it
+// is taken for a launch lost to a restart. It exceeds sessionCreateTimeout so
+// a slow but live launch is never failed under its own feet.
+const fleetMemberLaunchTimeout = 2 * sessionCreateTimeout
+
+var (
+	ErrFleetInvalidWindow  = errors.New("invalid fleet schedule window")
+	ErrFleetInvalidSize    = errors.New("unknown machine size")
+	ErrFleetInvalidOrg     = errors.New("invalid organization_id")
+	ErrFleetNoMembers      = errors.New("the group has no active members")
+	ErrFleetBudgetExceeded = errors.New("not enough budget left for the fleet")
+	ErrFleetFinished       = errors.New("the fleet has already finished")
+)
+
+// FleetLauncher is the part of TerminalTrainerService a fleet launch needs.
+// Kept narrow so tests can launch fleets without a tt-backend.
+type FleetLauncher interface {
+	StartComposedSession(userID string, input dto.CreateComposedSessionInput, planInterface any) (*dto.TerminalSessionResponse, error)
+	StopSession(sessionID string) error
+	GetServerMetrics(nocache bool, backend string) (*dto.ServerMetricsResponse, error)
+	GetUserKey(userID string) (*models.UserTerminalKey, error)
+	CreateUserKey(userID, keyName string) error
+}
+
+// TerminalFleetService schedules terminal fleets for class sessions: one
+// terminal per active group member, launched at a given time with a staggered
+// start and stopped at another. Schedules are rows, so the cron job driving
+// RunDue picks them up again after a restart.
+type TerminalFleetService interface {
+	// Schedule records a fleet for the group after checking its window and
+	// reserving its footprint in the budget pool it draws on.
+	Schedule(groupID uuid.UUID, createdByID string, input dto.ScheduleTerminalFleetInput, plan *paymentModels.SubscriptionPlan, now time.Time) (*models.TerminalFleet, error)
+	ListForGroup(groupID uuid.UUID) ([]models.TerminalFleet, error)
+	Get(fleetID uuid.UUID) (*models.TerminalFleet, error)
+	// Cancel calls a fleet off, stopping the terminals it already launched.
+	Cancel(fleetID uuid.UUID) (*models.TerminalFleet, error)
+	// RunDue launches the fleets whose start time has come and stops those
+	// whose stop time has. Returns the number of fleets launched and stopped.
+	RunDue(now time.Time) (launched int, stopped int, err error)
+}
+
+type terminalFleetService struct {
+	db                   *gorm.DB
+	launcher             FleetLauncher
+	quotaService         paymentServices.QuotaService
+	effectivePlanService paymentServices.EffectivePlanService
+	sleep                func(time.Duration)
+}
+
+// NewTerminalFleetService returns a fleet service launching terminals through
+// launcher, normally the TerminalTrainerService.
+func NewTerminalFleetService(db *gorm.DB, launcher FleetLauncher) TerminalFleetService {
+	eps := paymentServices.NewEffectivePlanService(db)
+	return &terminalFleetService{
+		db:                   db,
+		launcher:             launcher,
+		quotaService:         paymentServices.NewQuotaService(db, eps),
+		effectivePlanService: eps,
+		sleep:                time.Sleep,
+	}
+}
+
+func (s *terminalFleetService) Schedule(groupID uuid.UUID, createdByID string, input dto.ScheduleTerminalFleetInput, plan *paymentModels.SubscriptionPlan, now time.Time) (*models.TerminalFleet, error) {
+	if !input.StartAt.After(now) {
+		return nil, fmt.Errorf("%w: start_at must be in the future", ErrFleetInvalidWindow)
+	}
+	if !input.StopAt.After(input.StartAt) {
+		return nil, fmt.Errorf("%w: stop_at must be after start_at", ErrFleetInvalidWindow)
+	}
+	// The plan caps every session's lifetime; a longer window would see the
+	// terminals expire before the class ends.
+	if maxSeconds := resolvePlanExpirySeconds(plan); maxSeconds > 0 && input.StopAt.Sub(input.StartAt) > time.Duration(maxSeconds)*time.Second {
+		return nil, fmt.Errorf("%w: the plan limits sessions to %d minutes", ErrFleetInvalidWindow, plan.MaxSessionDurationMinutes)
+	}
+	size, ok := catalog.LookupSize(input.Size)
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrFleetInvalidSize, input.Size)
+	}
+
+	var orgID *uuid.UUID
+	if input.OrganizationID != "" {
+		parsed, err := uuid.Parse(input.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFleetInvalidOrg, err)
+		}
+		orgID = &parsed
+	}
+
+	var group groupModels.ClassGroup
+	if err := s.db.Where("id = ?", groupID).First(&group).Error; err != nil {
+		return nil, err
+	}
+	var memberCount int64
+	if err := s.db.Model(&groupModels.GroupMember{}).
+		Where("group_id = ? AND is_active = ?", groupID, true).
+		Count(&memberCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count group members: %w", err)
+	}
+	if memberCount == 0 {
+		return nil, ErrFleetNoMembers
+	}
+
+	stagger := DefaultFleetStaggerSeconds
+	if input.StaggerSeconds != nil {
+		stagger = *input.StaggerSeconds
+	}
+
+	fleet := &models.TerminalFleet{
+		GroupID:              groupID,
+		CreatedByID:          createdByID,
+		OrganizationID:       orgID,
+		BudgetOrganizationID: s.quotaService.BudgetScopeFor(createdByID, orgID),
+		Distribution:         input.Distribution,
+		Size:                 NormalizeSizeKey(input.Size),
+		Features:             input.Features,
+		Terms:                input.Terms,
+		NameTemplate:         input.NameTemplate,
+		Backend:              input.Backend,
+		RecordingEnabled:     input.RecordingEnabled,
+		StartAt:              input.StartAt,
+		StopAt:               input.StopAt,
+		StaggerSeconds:       stagger,
+		ReservedCPU:          int(memberCount) * size.CPU,
+		ReservedMemoryMB:     int(memberCount) * size.MemoryMB,
+		Status:               models.FleetStatusScheduled,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.reserveBudget(tx, fleet, plan, int(memberCount)); err != nil {
+			return err
+		}
+		return tx.Create(fleet).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fleet, nil
+}
+
+// reserveBudget checks that the fleet fits in its budget pool next to the
+// fleets already holding a reservation over an overlapping window. A fleet
+// on personal budgets only needs one terminal of its size to fit a plan: each
+// member's terminal counts against that member alone.
+func (s *terminalFleetService) reserveBudget(tx *gorm.DB, fleet *models.TerminalFleet, plan *paymentModels.SubscriptionPlan, memberCount int) error {
+	needed := 1
+	var reservedCPU, reservedMemMB int
+	if fleet.BudgetOrganizationID != nil {
+		needed = memberCount
+		var reserved struct {
+			CPU   int
+			MemMB int
+		}
+		if err := tx.Model(&models.TerminalFleet{}).
+			Select("COALESCE(SUM(reserved_cpu), 0) AS cpu, COALESCE(SUM(reserved_memory_mb), 0) AS mem_mb").
+			Where("budget_organization_id = ? AND status IN ? AND start_at < ? AND stop_at > ?",
+				*fleet.BudgetOrganizationID, models.FleetActiveStatuses, fleet.StopAt, fleet.StartAt).
+			Scan(&reserved).Error; err != nil {
+			return fmt.Errorf("failed to sum fleet reservations: %w", err)
+		}
+		reservedCPU, reservedMemMB = reserved.CPU, reserved.MemMB
+	}
+
+	for _, remaining := range s.quotaService.ComputeRemainingBySize(plan, reservedCPU, reservedMemMB) {
+		if NormalizeSizeKey(remaining.Key) != fleet.Size {
+			continue
+		}
+		if remaining.RemainingCount < needed {
+			return fmt.Errorf("%w: %d terminals of size %s requested, %d fit", ErrFleetBudgetExceeded, needed, fleet.Size, remaining.RemainingCount)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: '%s'", ErrFleetInvalidSize, fleet.Size)
+}
+
+func (s *terminalFleetService) ListForGroup(groupID uuid.UUID) ([]models.TerminalFleet, error) {
+	var fleets []models.TerminalFleet
+	if err := s.db.Where("group_id = ?", groupID).Order("start_at DESC").Find(&fleets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list fleets: %w", err)
+	}
+	return fleets, nil
+}
+
+func (s *terminalFleetService) Get(fleetID uuid.UUID) (*models.TerminalFleet, error) {
+	var fleet models.TerminalFleet
+	if err := s.db.Preload("Members").Where("id = ?", fleetID).First(&fleet).Error; err != nil {
+		return nil, err
+	}
+	return &fleet, nil
+}
+
+func (s *terminalFleetService) Cancel(fleetID uuid.UUID) (*models.TerminalFleet, error) {
+	fleet, err := s.Get(fleetID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := s.db.Model(&models.TerminalFleet{}).
+		Where("id = ? AND status IN ?", fleetID, models.FleetActiveStatuses).
+		Updates(map[string]any{"status": models.FleetStatusCancelled, "finished_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel fleet: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrFleetFinished
+	}
+	// Members still waiting for their turn will not get one; the launch loop
+	// sees the cancellation before the next launch.
+	if err := s.db.Model(&models.TerminalFleetMember{}).
+		Where("fleet_id = ? AND status = ?", fleetID, models.FleetMemberStatusPending).
+		Updates(map[string]any{"status": models.FleetMemberStatusFailed, "error": "fleet cancelled"}).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel pending members: %w", err)
+	}
+	s.stopMembers(fleet.ID)
+	return s.Get(fleetID)
+}
+
+func (s *terminalFleetService) RunDue(now time.Time) (int, int, error) {
+	// A fleet whose whole window passed while nothing was running (the
+	// server was down) is not launched late: the class is over.
+	if err := s.db.Model(&models.TerminalFleet{}).
+		Where("status = ? AND stop_at <= ?", models.FleetStatusScheduled, now).
+		Updates(map[string]any{"status": models.FleetStatusFailed, "finished_at": now, "error": "the fleet's window passed before it could launch"}).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to expire missed fleets: %w", err)
+	}
+
+	var dueIDs []uuid.UUID
+	if err := s.db.Model(&models.TerminalFleet{}).
+		Where("status = ? AND start_at <= ?", models.FleetStatusScheduled, now).
+		Order("start_at").Pluck("id", &dueIDs).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to load due fleets: %w", err)
+	}
+	launched := 0
+	for _, id := range dueIDs {
+		// The guarded update is the claim: a fleet is launched by one run.
+		claim := s.db.Model(&models.TerminalFleet{}).
+			Where("id = ? AND status = ?", id, models.FleetStatusScheduled).
+			Updates(map[string]any{"status": models.FleetStatusLaunching, "launched_at": now})
+		if claim.Error != nil {
+			return launched, 0, fmt.Errorf("failed to claim fleet: %w", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		if err := s.startFleet(id, now); err != nil {
+			utils.Warn("terminal fleet %s failed to launch: %v", id, err)
+			s.db.Model(&models.TerminalFleet{}).Where("id = ?", id).
+				Updates(map[string]any{"status": models.FleetStatusFailed, "finished_at": time.Now(), "error": err.Error()})
+			continue
+		}
+		launched++
+	}
+
+	// Fleets caught launching by a restart carry on with their pending
+	// members; their pre-flight already passed.
+	var resumingIDs []uuid.UUID
+	if err := s.db.Model(&models.TerminalFleet{}).
+		Where("status = ? AND stop_at > ? AND id NOT IN ?", models.FleetStatusLaunching, now, append(dueIDs, uuid.Nil)).
+		Pluck("id", &resumingIDs).Error; err != nil {
+		return launched, 0, fmt.Errorf("failed to load launching fleets: %w", err)
+	}
+	for _, id := range resumingIDs {
+		if err := s.launchMembers(id); err != nil {
+			utils.Warn("terminal fleet %s: failed to resume launch: %v", id, err)
+		}
+	}
+
+	stopped, err := s.stopDue(now)
+	return launched, stopped, err
+}
+
+// startFleet runs the launch pre-flight of a claimed fleet, records its
+// members and launches them.
+func (s *terminalFleetService) startFleet(fleetID uuid.UUID, now time.Time) error {
+	var fleet models.TerminalFleet
+	if err := s.db.Where("id = ?", fleetID).First(&fleet).Error; err != nil {
+		return err
+	}
+	plan, err := s.fleetPlan(&fleet)
+	if err != nil {
+		return err
+	}
+
+	var memberIDs []string
+	if err := s.db.Model(&groupModels.GroupMember{}).
+		Where("group_id = ? AND is_active = ?", fleet.GroupID, true).
+		Distinct().Pluck("user_id", &memberIDs).Error; err != nil {
+		return fmt.Errorf("failed to load group members: %w", err)
+	}
+	if len(memberIDs) == 0 {
+		return ErrFleetNoMembers
+	}
+
+	// The server must hold the whole fleet; half a class of terminals is no
+	// use to a trainer. Metrics being unavailable does not block, as on every
+	// other launch path.
+	if metrics, err := s.launcher.GetServerMetrics(true, fleet.Backend); err != nil {
+		utils.Warn("terminal fleet %s: could not fetch server metrics, skipping capacity check: %v", fleet.ID, err)
+	} else if capacity := EvaluateFleetCapacity(plan, fleet.Size, len(memberIDs), metrics); capacity.Status == CapacityStatusCritical {
+		return fmt.Errorf("%w: %d terminals of size %s (%s)", ErrBulkInsufficientRAM, len(memberIDs), fleet.Size, capacity.Reason)
+	}
+
+	// Budget pooled in an organization is checked against its live usage
+	// too: sessions started outside fleets may have eaten into it since the
+	// fleet was scheduled.
+	if fleet.BudgetOrganizationID != nil {
+		size, _ := catalog.LookupSize(fleet.Size)
+		check, err := s.quotaService.CheckBudget(fleet.CreatedByID, fleet.BudgetOrganizationID, plan,
+			len(memberIDs)*size.CPU, len(memberIDs)*size.MemoryMB)
+		if err != nil {
+			return fmt.Errorf("budget check failed: %w", err)
+		}
+		if !check.Allowed {
+			return fmt.Errorf("%w: %s", ErrFleetBudgetExceeded, check.Reason)
+		}
+	}
+
+	members := make([]models.TerminalFleetMember, 0, len(memberIDs))
+	for _, userID := range memberIDs {
+		members = append(members, models.TerminalFleetMember{
+			FleetID: fleet.ID,
+			UserID:  userID,
+			Status:  models.FleetMemberStatusPending,
+		})
+	}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error; err != nil {
+		return fmt.Errorf("failed to record fleet members: %w", err)
+	}
+	return s.launchMembers(fleet.ID)
+}
+
+// fleetPlan resolves the plan the fleet launches under: its creator's
+// effective plan at launch time, not the one they held when scheduling.
+func (s *terminalFleetService) fleetPlan(fleet *models.TerminalFleet) (*paymentModels.SubscriptionPlan, error) {
+	result, err := s.effectivePlanService.GetUserEffectivePlan(fleet.CreatedByID, fleet.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the fleet creator's plan: %w", err)
+	}
+	if result == nil || result.Plan == nil {
+		return nil, errors.New("the fleet's creator has no active subscription")
+	}
+	return result.Plan, nil
+}
+
+// launchMembers launches the fleet's pending members one at a time,
+// StaggerSeconds apart, until none is left or the fleet is cancelled.
+func (s *terminalFleetService) launchMembers(fleetID uuid.UUID) error {
+	var fleet models.TerminalFleet
+	if err := s.db.Where("id = ?", fleetID).First(&fleet).Error; err != nil {
+		return err
+	}
+	plan, err := s.fleetPlan(&fleet)
+	if err != nil {
+		return err
+	}
+	var group groupModels.ClassGroup
+	if err := s.db.Where("id = ?", fleet.GroupID).First(&group).Error; err != nil {
+		return err
+	}
+
+	// A member left launching longer than a launch can take was lost to a
+	// restart. Whether its session came up is unknown, so it is not retried.
+	if err := s.db.Model(&models.TerminalFleetMember{}).
+		Where("fleet_id = ? AND status = ? AND launched_at < ?", fleetID, models.FleetMemberStatusLaunching, time.Now().Add(-fleetMemberLaunchTimeout)).
+		Updates(map[string]any{"status": models.FleetMemberStatusFailed, "error": "launch interrupted"}).Error; err != nil {
+		return fmt.Errorf("failed to fail interrupted members: %w", err)
+	}
+
+	var pending []models.TerminalFleetMember
+	if err := s.db.Where("fleet_id = ? AND status = ?", fleetID, models.FleetMemberStatusPending).
+		Order("user_id").Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to load pending members: %w", err)
+	}
+
+	for i := range pending {
+		if i > 0 && fleet.StaggerSeconds > 0 {
+			s.sleep(time.Duration(fleet.StaggerSeconds) * time.Second)
+		}
+		var status string
+		if err := s.db.Model(&models.TerminalFleet{}).Where("id = ?", fleetID).Pluck("status", &status).Error; err != nil {
+			return err
+		}
+		if status != models.FleetStatusLaunching {
+			return nil
+		}
+		s.launchMember(&fleet, &group, plan, &pending[i])
+	}
+	return s.finishLaunch(fleetID)
+}
+
+// launchMember claims one pending member and starts their terminal.
+func (s *terminalFleetService) launchMember(fleet *models.TerminalFleet, group *groupModels.ClassGroup, plan *paymentModels.SubscriptionPlan, member *models.TerminalFleetMember) {
+	now := time.Now()
+	claim := s.db.Model(&models.TerminalFleetMember{}).
+		Where("id = ? AND status = ?", member.ID, models.FleetMemberStatusPending).
+		Updates(map[string]any{"status": models.FleetMemberStatusLaunching, "launched_at": now})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+	fail := func(err error) {
+		s.db.Model(&models.TerminalFleetMember{}).Where("id = ?", member.ID).
+			Updates(map[string]any{"status": models.FleetMemberStatusFailed, "error": err.Error()})
+	}
+
+	// Terminals already launched have changed the server's load since the
+	// fleet's pre-flight.
+	if metrics, err := s.launcher.GetServerMetrics(true, fleet.Backend); err == nil {
+		if capacity := EvaluateLaunchCapacity(plan, fleet.Size, metrics); capacity.Status == CapacityStatusCritical {
+			fail(fmt.Errorf("%w (%s)", ErrBulkInsufficientRAM, capacity.Reason))
+			return
+		}
+	}
+
+	email := member.UserID
+	if user, err := casdoorsdk.GetUserByUserId(member.UserID); err == nil && user != nil && user.Email != "" {
+		email = user.Email
+	}
+	if _, err := s.launcher.GetUserKey(member.UserID); err != nil {
+		if createErr := s.launcher.CreateUserKey(member.UserID, "auto-"+email); createErr != nil {
+			utils.Warn("failed to auto-provision terminal key for user %s: %v", member.UserID, createErr)
+		}
+	}
+
+	name := ApplyNameTemplate(fleet.NameTemplate, group.DisplayName, email, member.UserID, fleet.Size)
+	input := dto.CreateComposedSessionInput{
+		Distribution:     fleet.Distribution,
+		Size:             fleet.Size,
+		Features:         fleet.Features,
+		Terms:            fleet.Terms,
+		Name:             name,
+		Expiry:           int(fleet.StopAt.Sub(now).Seconds()),
+		Backend:          fleet.Backend,
+		RecordingEnabled: fleet.RecordingEnabled,
+		ExternalRef:      "fleet:" + fleet.ID.String(),
+	}
+	if fleet.OrganizationID != nil {
+		input.OrganizationID = fleet.OrganizationID.String()
+	}
+	session, err := s.launcher.StartComposedSession(member.UserID, input, plan)
+	if err != nil {
+		fail(err)
+		return
+	}
+	s.db.Model(&models.TerminalFleetMember{}).Where("id = ?", member.ID).
+		Updates(map[string]any{"status": models.FleetMemberStatusRunning, "session_id": session.SessionID, "name": name})
+}
+
+// finishLaunch moves a fleet whose members have all been tried to running,
+// or to failed when none of them got a terminal.
+func (s *terminalFleetService) finishLaunch(fleetID uuid.UUID) error {
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	if err := s.db.Model(&models.TerminalFleetMember{}).
+		Select("status, COUNT(*) AS count").
+		Where("fleet_id = ?", fleetID).
+		Group("status").Scan(&counts).Error; err != nil {
+		return fmt.Errorf("failed to count fleet members: %w", err)
+	}
+	running := 0
+	for _, c := range counts {
+		switch c.Status {
+		case models.FleetMemberStatusPending, models.FleetMemberStatusLaunching:
+			return nil
+		case models.FleetMemberStatusRunning:
+			running += c.Count
+		}
+	}
+	updates := map[string]any{"status": models.FleetStatusRunning}
+	if running == 0 {
+		updates = map[string]any{"status": models.FleetStatusFailed, "finished_at": time.Now(), "error": "no terminal could be launched"}
+	}
+	return s.db.Model(&models.TerminalFleet{}).
+		Where("id = ? AND status = ?", fleetID, models.FleetStatusLaunching).
+		Updates(updates).Error
+}
+
+// stopDue stops the fleets whose stop time has come, and the terminals of
+// cancelled fleets a launch brought up after their cancellation.
+func (s *terminalFleetService) stopDue(now time.Time) (int, error) {
+	var fleetIDs []uuid.UUID
+	if err := s.db.Model(&models.TerminalFleet{}).
+		Where("status IN ? AND stop_at <= ?", []string{models.FleetStatusLaunching, models.FleetStatusRunning}, now).
+		Pluck("id", &fleetIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to load fleets to stop: %w", err)
+	}
+	stopped := 0
+	for _, id := range fleetIDs {
+		claim := s.db.Model(&models.TerminalFleet{}).
+			Where("id = ? AND status IN ?", id, []string{models.FleetStatusLaunching, models.FleetStatusRunning}).
+			Updates(map[string]any{"status": models.FleetStatusCompleted, "finished_at": now})
+		if claim.Error != nil {
+			return stopped, fmt.Errorf("failed to complete fleet: %w", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		s.db.Model(&models.TerminalFleetMember{}).
+			Where("fleet_id = ? AND status = ?", id, models.FleetMemberStatusPending).
+			Updates(map[string]any{"status": models.FleetMemberStatusFailed, "error": "the fleet's window ended before launch"})
+		s.stopMembers(id)
+		stopped++
+	}
+
+	// Terminals still running in a finished fleet: a stop that failed, or a
+	// launch that raced a cancellation.
+	var strayFleetIDs []uuid.UUID
+	if err := s.db.Model(&models.TerminalFleetMember{}).
+		Joins("JOIN terminal_fleets ON terminal_fleets.id = terminal_fleet_members.fleet_id").
+		Where("terminal_fleet_members.status = ? AND terminal_fleets.status IN ?", models.FleetMemberStatusRunning,
+			[]string{models.FleetStatusCompleted, models.FleetStatusCancelled, models.FleetStatusFailed}).
+		Distinct().Pluck("terminal_fleet_members.fleet_id", &strayFleetIDs).Error; err != nil {
+		return stopped, fmt.Errorf("failed to load stray fleet terminals: %w", err)
+	}
+	for _, id := range strayFleetIDs {
+		s.stopMembers(id)
+	}
+	return stopped, nil
+}
+
+// stopMembers stops the running terminals of a fleet. A member is claimed
+// before its session is stopped, and released for the next run to retry if
+// the stop fails.
+func (s *terminalFleetService) stopMembers(fleetID uuid.UUID) {
+	var running []models.TerminalFleetMember
+	if err := s.db.Where("fleet_id = ? AND status = ?", fleetID, models.FleetMemberStatusRunning).Find(&running).Error; err != nil {
+		utils.Warn("terminal fleet %s: failed to load running members: %v", fleetID, err)
+		return
+	}
+	for _, member := range running {
+		claim := s.db.Model(&models.TerminalFleetMember{}).
+			Where("id = ? AND status = ?", member.ID, models.FleetMemberStatusRunning).
+			Update("status", models.FleetMemberStatusStopped)
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+		if err := s.launcher.StopSession(member.SessionID); err != nil {
+			utils.Warn("terminal fleet %s: failed to stop session %s: %v", fleetID, member.SessionID, err)
+			s.db.Model(&models.TerminalFleetMember{}).Where("id = ?", member.ID).
+				Updates(map[string]any{"status": models.FleetMemberStatusRunning, "error": err.Error()})
+		}
+	}
+}
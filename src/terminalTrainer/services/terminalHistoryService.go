@@ -10,6 +10,8 @@ import (
 	"time"
 
 	groupModels "soli/formations/src/groups/models"
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/dto"
 	"soli/formations/src/terminalTrainer/models"
 	"soli/formations/src/terminalTrainer/repositories"
 	"soli/formations/src/utils"
@@ -154,6 +156,62 @@ func (h *terminalHistoryService) GetSessionCommandHistoryAdmin(sessionUUID strin
 	return resp.Body, contentType, nil
 }
 
+// maxRecordingSize caps a recording fetched from tt-backend. Far above the
+// history cap: a recording carries every byte the terminal printed, and a
+// long session with a chatty build produces tens of megabytes of it.
+const maxRecordingSize = 64 * 1024 * 1024
+
+// GetSessionRecording retrieves a session's full terminal I/O recording from
+// tt-backend and returns it as asciicast v2, cut to the requested window with
+// the requested markers written in.
+//
+// The recording honours the command history retention of the plan the session
+// ran under: whatever is older than CommandHistoryRetentionDays is never
+// replayed, even if tt-backend still holds it. A plan with no history
+// retention has nothing to replay at all. Sessions from before the plan was
+// recorded on the terminal are left to tt-backend's own retention.
+func (h *terminalHistoryService) GetSessionRecording(sessionID string, opts dto.RecordingReplayOptions) ([]byte, error) {
+	terminal, err := h.repository.GetTerminalSessionByID(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("session not found: %w", err)
+	}
+
+	var notBefore time.Time
+	if terminal.SubscriptionPlanID != nil {
+		var plan paymentModels.SubscriptionPlan
+		if err := h.db.First(&plan, "id = ?", *terminal.SubscriptionPlanID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load the session's plan: %w", err)
+		}
+		if plan.CommandHistoryRetentionDays <= 0 {
+			return nil, ErrRecordingExpired
+		}
+		notBefore = time.Now().AddDate(0, 0, -plan.CommandHistoryRetentionDays)
+	}
+
+	url := fmt.Sprintf("%s/%s/sessions/%s/recording", h.baseURL, h.apiVersion, neturl.PathEscape(sessionID))
+
+	httpOpts := utils.DefaultHTTPClientOptions()
+	utils.ApplyOptions(&httpOpts, utils.WithAPIKey(terminal.UserTerminalKey.APIKey))
+
+	resp, err := utils.MakeExternalAPIRequest("Terminal Trainer", "GET", url, nil, httpOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Body) > maxRecordingSize {
+		return nil, fmt.Errorf("recording exceeds maximum allowed size (%d bytes > %d bytes)", len(resp.Body), maxRecordingSize)
+	}
+
+	cast, err := parseAsciicast(resp.Body, terminal.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	replay, err := cast.replay(notBefore, opts)
+	if err != nil {
+		return nil, err
+	}
+	return replay.encode()
+}
+
 // DeleteSessionCommandHistory deletes command history (RGPD right to erasure)
 func (h *terminalHistoryService) DeleteSessionCommandHistory(sessionID string) error {
 	terminal, err := h.repository.GetTerminalSessionByID(sessionID)
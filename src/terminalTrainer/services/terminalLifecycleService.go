@@ -10,6 +10,7 @@ import (
 	"soli/formations/src/utils"
 
 	paymentModels "soli/formations/src/payment/models"
+	webhookServices "soli/formations/src/webhooks/services"
 
 	"gorm.io/gorm"
 )
@@ -123,6 +124,7 @@ func (l *terminalLifecycleService) StopSession(sessionID string) error {
 		utils.Error("Failed to update session %s state: %v", sessionID, err)
 		return err
 	}
+	publishTerminalEvent(l.db, terminal, webhookServices.EventTerminalStopped)
 
 	// 3. Auto-abandon any active scenario sessions linked to this terminal
 	result := l.db.Model(&struct{}{}).Table("scenario_sessions").
@@ -206,6 +208,7 @@ func (l *terminalLifecycleService) StartSession(sessionID string) error {
 		utils.Error("Failed to update session %s after start: %v", sessionID, err)
 		return err
 	}
+	publishTerminalEvent(l.db, terminal, webhookServices.EventTerminalStarted)
 
 	return nil
 }
@@ -235,11 +238,15 @@ func (l *terminalLifecycleService) DeleteSession(sessionID string) error {
 		utils.Warn("failed to delete session in Terminal Trainer API: %v", err)
 	}
 
+	wasRunning := terminal.State == models.StateRunning
 	terminal.State = models.StateDeleted
 	if err := l.repository.UpdateTerminalSession(terminal); err != nil {
 		utils.Error("Failed to update session %s after delete: %v", sessionID, err)
 		return err
 	}
+	if wasRunning {
+		publishTerminalEvent(l.db, terminal, webhookServices.EventTerminalStopped)
+	}
 
 	// Auto-abandon any active scenario sessions linked to this terminal
 	result := l.db.Model(&struct{}{}).Table("scenario_sessions").
@@ -255,7 +262,8 @@ func (l *terminalLifecycleService) DeleteSession(sessionID string) error {
 }
 
 // HasTerminalAccess checks if a user has access to a terminal.
-// Only terminal owners and group owners of the owner's group have access.
+// Only terminal owners, members of its team when it is shared, and group
+// owners of the owner's group have access.
 func (l *terminalLifecycleService) HasTerminalAccess(terminalIDOrSessionID, userID string) (bool, error) {
 	// Try to get terminal by UUID first (most common case from API)
 	terminal, err := l.repository.GetTerminalByUUID(terminalIDOrSessionID)
@@ -275,6 +283,14 @@ func (l *terminalLifecycleService) HasTerminalAccess(terminalIDOrSessionID, user
 		return true, nil
 	}
 
+	// A shared terminal's team reaches it like its owner does
+	var teamCount int64
+	if err := l.db.Model(&models.TerminalTeamMember{}).
+		Where("terminal_id = ? AND user_id = ?", terminal.ID, userID).
+		Count(&teamCount).Error; err == nil && teamCount > 0 {
+		return true, nil
+	}
+
 	// Check if requesting user is a group owner with the terminal owner as member
 	isGroupOwner, err := l.checkGroupOwnerAccess(terminal.UserID, userID)
 	if err == nil && isGroupOwner {
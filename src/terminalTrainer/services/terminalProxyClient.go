@@ -579,3 +579,50 @@ func (p *terminalProxyClient) buildCompleteInAPI(sessionID, userAPIKey string) (
 	}
 	return resp.DroppedProfiles, nil
 }
+
+// createSnapshotInAPI calls POST /sessions/{id}/snapshots on tt-backend, which
+// checkpoints the session's persistent disk under the given name. The returned
+// size is what the snapshot costs against the owner's storage quota.
+func (p *terminalProxyClient) createSnapshotInAPI(sessionID, name, userAPIKey string) (*dto.TTSnapshot, error) {
+	url := fmt.Sprintf("%s/%s/sessions/%s/snapshots", p.baseURL, p.apiVersion, sessionID)
+
+	utils.Debug("createSnapshotInAPI - calling %s", url)
+
+	opts := utils.DefaultHTTPClientOptions()
+	utils.ApplyOptions(&opts, utils.WithAPIKey(userAPIKey))
+
+	var snapshot dto.TTSnapshot
+	body := map[string]string{"name": name}
+	if err := utils.MakeExternalAPIJSONRequest("Terminal Trainer", "POST", url, body, &snapshot, opts); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// restoreSnapshotInAPI calls POST /sessions/{id}/snapshots/{name}/restore on
+// tt-backend, which rolls the session's disk back to the snapshot. The
+// snapshot survives the restore and can be restored again.
+func (p *terminalProxyClient) restoreSnapshotInAPI(sessionID, name, userAPIKey string) error {
+	url := fmt.Sprintf("%s/%s/sessions/%s/snapshots/%s/restore", p.baseURL, p.apiVersion, sessionID, name)
+
+	utils.Debug("restoreSnapshotInAPI - calling %s", url)
+
+	opts := utils.DefaultHTTPClientOptions()
+	utils.ApplyOptions(&opts, utils.WithAPIKey(userAPIKey))
+
+	_, err := utils.MakeExternalAPIRequest("Terminal Trainer", "POST", url, nil, opts)
+	return err
+}
+
+// deleteSnapshotInAPI calls DELETE /sessions/{id}/snapshots/{name} on tt-backend.
+func (p *terminalProxyClient) deleteSnapshotInAPI(sessionID, name, userAPIKey string) error {
+	url := fmt.Sprintf("%s/%s/sessions/%s/snapshots/%s", p.baseURL, p.apiVersion, sessionID, name)
+
+	utils.Debug("deleteSnapshotInAPI - calling %s", url)
+
+	opts := utils.DefaultHTTPClientOptions()
+	utils.ApplyOptions(&opts, utils.WithAPIKey(userAPIKey))
+
+	_, err := utils.MakeExternalAPIRequest("Terminal Trainer", "DELETE", url, nil, opts)
+	return err
+}
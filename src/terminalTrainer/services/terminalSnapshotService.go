@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/repositories"
+	"soli/formations/src/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSnapshotInvalidName is returned for a name tt-backend could not use
+	// as a path segment.
+	ErrSnapshotInvalidName = errors.New("snapshot name must be 1-63 letters, digits, '-' or '_', starting with a letter or digit")
+	// ErrSnapshotNotPersistent is returned for an ephemeral terminal: there is
+	// no disk that outlives the container to checkpoint.
+	ErrSnapshotNotPersistent = errors.New("snapshots require a persistent terminal")
+	// ErrSnapshotsNotInPlan is returned when the terminal's plan grants no
+	// persistent storage to hold snapshots in.
+	ErrSnapshotsNotInPlan = errors.New("snapshots are not available on this terminal's plan")
+	// ErrSnapshotQuotaExceeded is returned when the owner's snapshots already
+	// fill, or the new one would overflow, their plan's storage.
+	ErrSnapshotQuotaExceeded = errors.New("snapshot storage quota exceeded")
+	// ErrSnapshotExists is returned when the terminal already has a snapshot
+	// of that name.
+	ErrSnapshotExists = errors.New("a snapshot with this name already exists")
+)
+
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,62}$`)
+
+const bytesPerGB = int64(1024 * 1024 * 1024)
+
+// terminalSnapshotService owns named snapshots of persistent terminals:
+// creating, listing, restoring and deleting them on tt-backend, and keeping
+// the local TerminalSnapshot rows their storage is counted from.
+//
+// Snapshots are counted against the owner's plan storage
+// (SubscriptionPlan.DataPersistenceGB), read from the plan the terminal was
+// launched under. The count covers every snapshot the owner holds on a
+// terminal that still exists, so a trainer snapshotting a learner's terminal
+// spends the learner's storage, not their own. tt-backend only reports a
+// snapshot's size once it has taken it, so the quota is checked twice: before,
+// against what is already held, and after, against the real size — a snapshot
+// that overflows it is deleted again.
+type terminalSnapshotService struct {
+	proxy      *terminalProxyClient
+	repository repositories.TerminalRepository
+	db         *gorm.DB
+}
+
+// newTerminalSnapshotService returns a snapshot service calling tt-backend
+// through the supplied proxy. The proxy, repository and db are shared with the
+// facade.
+func newTerminalSnapshotService(proxy *terminalProxyClient, repository repositories.TerminalRepository, db *gorm.DB) *terminalSnapshotService {
+	return &terminalSnapshotService{
+		proxy:      proxy,
+		repository: repository,
+		db:         db,
+	}
+}
+
+// ListSnapshots returns a terminal's snapshots, newest first, with the owner's
+// storage use and quota.
+func (sn *terminalSnapshotService) ListSnapshots(terminalIDOrSessionID string) (*dto.TerminalSnapshotsResponse, error) {
+	terminal, err := sn.resolveTerminal(terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []models.TerminalSnapshot{}
+	if err := sn.db.Where("terminal_id = ?", terminal.ID).Order("created_at DESC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	used, err := sn.usedBytes(terminal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.TerminalSnapshotsResponse{Snapshots: snapshots, UsedBytes: used}
+	if plan, err := sn.terminalPlan(terminal); err == nil && plan != nil {
+		response.QuotaBytes = int64(plan.DataPersistenceGB) * bytesPerGB
+	}
+	return response, nil
+}
+
+// CreateSnapshot checkpoints a persistent terminal under name. createdByID is
+// who asked for it; empty means the terminal's owner. stepOrder marks the
+// snapshots a scenario takes of its steps.
+func (sn *terminalSnapshotService) CreateSnapshot(terminalIDOrSessionID, name, createdByID string, stepOrder *int) (*models.TerminalSnapshot, error) {
+	return sn.create(terminalIDOrSessionID, name, createdByID, stepOrder, false)
+}
+
+// ReplaceSnapshot is CreateSnapshot for a name that may already be taken: the
+// existing snapshot is deleted first, so its storage is free for the new one.
+func (sn *terminalSnapshotService) ReplaceSnapshot(terminalIDOrSessionID, name, createdByID string, stepOrder *int) (*models.TerminalSnapshot, error) {
+	return sn.create(terminalIDOrSessionID, name, createdByID, stepOrder, true)
+}
+
+func (sn *terminalSnapshotService) create(terminalIDOrSessionID, name, createdByID string, stepOrder *int, replace bool) (*models.TerminalSnapshot, error) {
+	if !snapshotNamePattern.MatchString(name) {
+		return nil, ErrSnapshotInvalidName
+	}
+	terminal, err := sn.resolveSnapshottable(terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := sn.terminalPlan(terminal)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || plan.DataPersistenceGB <= 0 {
+		return nil, ErrSnapshotsNotInPlan
+	}
+
+	var existing models.TerminalSnapshot
+	err = sn.db.Where("terminal_id = ? AND name = ?", terminal.ID, name).First(&existing).Error
+	switch {
+	case err == nil && !replace:
+		return nil, ErrSnapshotExists
+	case err == nil:
+		if err := sn.remove(terminal, &existing); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to look up snapshot: %w", err)
+	}
+
+	quota := int64(plan.DataPersistenceGB) * bytesPerGB
+	used, err := sn.usedBytes(terminal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if used >= quota {
+		return nil, ErrSnapshotQuotaExceeded
+	}
+
+	taken, err := sn.proxy.createSnapshotInAPI(terminal.SessionID, name, terminal.UserTerminalKey.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot failed for session %s: %w", terminal.SessionID, err)
+	}
+	if used+taken.SizeBytes > quota {
+		if err := sn.proxy.deleteSnapshotInAPI(terminal.SessionID, name, terminal.UserTerminalKey.APIKey); err != nil {
+			utils.Warn("snapshot %s of session %s overflowed the quota and could not be deleted: %v", name, terminal.SessionID, err)
+		}
+		return nil, ErrSnapshotQuotaExceeded
+	}
+
+	if createdByID == "" {
+		createdByID = terminal.UserID
+	}
+	snapshot := &models.TerminalSnapshot{
+		TerminalID:  terminal.ID,
+		UserID:      terminal.UserID,
+		Name:        name,
+		SizeBytes:   taken.SizeBytes,
+		CreatedByID: createdByID,
+		StepOrder:   stepOrder,
+	}
+	if err := sn.db.Create(snapshot).Error; err != nil {
+		return nil, fmt.Errorf("failed to record snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// RestoreSnapshot rolls a persistent terminal back to one of its snapshots.
+// Returns gorm.ErrRecordNotFound when the terminal has no snapshot of that
+// name.
+func (sn *terminalSnapshotService) RestoreSnapshot(terminalIDOrSessionID, name string) error {
+	terminal, err := sn.resolveSnapshottable(terminalIDOrSessionID)
+	if err != nil {
+		return err
+	}
+	var snapshot models.TerminalSnapshot
+	if err := sn.db.Where("terminal_id = ? AND name = ?", terminal.ID, name).First(&snapshot).Error; err != nil {
+		return err
+	}
+	if err := sn.proxy.restoreSnapshotInAPI(terminal.SessionID, name, terminal.UserTerminalKey.APIKey); err != nil {
+		return fmt.Errorf("restore failed for session %s: %w", terminal.SessionID, err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes one of a terminal's snapshots, freeing its storage.
+// Returns gorm.ErrRecordNotFound when the terminal has no snapshot of that
+// name.
+func (sn *terminalSnapshotService) DeleteSnapshot(terminalIDOrSessionID, name string) error {
+	terminal, err := sn.resolveTerminal(terminalIDOrSessionID)
+	if err != nil {
+		return err
+	}
+	var snapshot models.TerminalSnapshot
+	if err := sn.db.Where("terminal_id = ? AND name = ?", terminal.ID, name).First(&snapshot).Error; err != nil {
+		return err
+	}
+	return sn.remove(terminal, &snapshot)
+}
+
+// remove deletes a snapshot on tt-backend, then its row. The row goes outright
+// rather than soft-deleted: it is what the quota is counted from, and a
+// tombstone would keep the name taken.
+func (sn *terminalSnapshotService) remove(terminal *models.Terminal, snapshot *models.TerminalSnapshot) error {
+	if err := sn.proxy.deleteSnapshotInAPI(terminal.SessionID, snapshot.Name, terminal.UserTerminalKey.APIKey); err != nil {
+		return fmt.Errorf("snapshot delete failed for session %s: %w", terminal.SessionID, err)
+	}
+	if err := sn.db.Unscoped().Delete(snapshot).Error; err != nil {
+		return fmt.Errorf("failed to delete snapshot record: %w", err)
+	}
+	return nil
+}
+
+// resolveTerminal loads a terminal by its UUID or its tt-backend session id.
+func (sn *terminalSnapshotService) resolveTerminal(terminalIDOrSessionID string) (*models.Terminal, error) {
+	return lookupTerminal(sn.repository, terminalIDOrSessionID)
+}
+
+// resolveSnapshottable loads a terminal that snapshots can be taken of or
+// restored onto.
+func (sn *terminalSnapshotService) resolveSnapshottable(terminalIDOrSessionID string) (*models.Terminal, error) {
+	terminal, err := sn.resolveTerminal(terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+	if terminal.PersistenceMode != PersistenceModePersistent {
+		return nil, ErrSnapshotNotPersistent
+	}
+	return terminal, nil
+}
+
+// terminalPlan loads the plan the terminal was launched under, nil for a row
+// that predates the column.
+func (sn *terminalSnapshotService) terminalPlan(terminal *models.Terminal) (*paymentModels.SubscriptionPlan, error) {
+	if terminal.SubscriptionPlanID == nil {
+		return nil, nil
+	}
+	var plan paymentModels.SubscriptionPlan
+	if err := sn.db.First(&plan, "id = ?", *terminal.SubscriptionPlanID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load the terminal's plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// usedBytes sums the snapshots a user holds on terminals that still exist.
+// A deleted terminal takes its snapshots with it on tt-backend, so they stop
+// counting even before their rows are cleaned up.
+func (sn *terminalSnapshotService) usedBytes(userID string) (int64, error) {
+	var used int64
+	err := sn.db.Model(&models.TerminalSnapshot{}).
+		Joins("JOIN terminals ON terminals.id = terminal_snapshots.terminal_id").
+		Where("terminal_snapshots.user_id = ? AND terminals.state <> ? AND terminals.deleted_at IS NULL", userID, models.StateDeleted).
+		Select("COALESCE(SUM(terminal_snapshots.size_bytes), 0)").
+		Scan(&used).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute snapshot storage: %w", err)
+	}
+	return used, nil
+}
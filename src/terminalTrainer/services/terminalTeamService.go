@@ -0,0 +1,331 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTeamNoMembers is returned when sharing would leave the terminal's
+	// owner alone in its team.
+	ErrTeamNoMembers = errors.New("a team needs at least one learner besides the terminal's owner")
+	// ErrTeamNotClassmate is returned for a learner added by hand who shares
+	// no class group with the terminal's owner.
+	ErrTeamNotClassmate = errors.New("team members must share a class group with the terminal's owner")
+	// ErrTeamGroupNotOwners is returned for a sub-group the terminal's owner
+	// is not an active member of.
+	ErrTeamGroupNotOwners = errors.New("the terminal's owner is not a member of this group")
+	// ErrTeamOwnerRemoval is returned when removing the terminal's owner from
+	// its team: the container is theirs, so the team goes with them or not
+	// at all.
+	ErrTeamOwnerRemoval = errors.New("the terminal's owner cannot be removed from its team")
+	// ErrTeamInvalidRole is returned for a role other than driver or navigator.
+	ErrTeamInvalidRole = errors.New("team role must be driver or navigator")
+)
+
+// terminalTeamService owns shared terminals: the team of learners attached to
+// one container, and who of them drives.
+//
+// Sharing does not change whose terminal it is. The container stays the
+// owner's, launched under their plan and counted against their budget, and
+// tt-backend is still reached with their key; the team only widens who
+// HasTerminalAccess lets onto it. Every member consoles in under the
+// attachment role their team role maps to (TerminalTeamMember.AttachmentRole),
+// so tt-backend, not ocf-core, is what keeps navigators from typing.
+//
+// Members are the owner's classmates: the active members of a sub-group the
+// owner belongs to, or learners named one by one who share a class group with
+// them.
+type terminalTeamService struct {
+	repository repositories.TerminalRepository
+	lifecycle  *terminalLifecycleService
+	db         *gorm.DB
+}
+
+// newTerminalTeamService returns a team service. The repository, lifecycle
+// and db are shared with the facade.
+func newTerminalTeamService(repository repositories.TerminalRepository, lifecycle *terminalLifecycleService, db *gorm.DB) *terminalTeamService {
+	return &terminalTeamService{
+		repository: repository,
+		lifecycle:  lifecycle,
+		db:         db,
+	}
+}
+
+// GetTeam returns a terminal's team, empty when it is not shared.
+func (ts *terminalTeamService) GetTeam(terminalIDOrSessionID string) (*dto.TerminalTeamResponse, error) {
+	terminal, err := lookupTerminal(ts.repository, terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+	return ts.team(ts.db, terminal)
+}
+
+// ShareTerminal adds learners to a terminal's team as navigators. The first
+// share also adds the owner, as the driver. Learners already in the team keep
+// their role.
+func (ts *terminalTeamService) ShareTerminal(terminalIDOrSessionID string, input dto.ShareTerminalInput, addedByID string) (*dto.TerminalTeamResponse, error) {
+	terminal, err := lookupTerminal(ts.repository, terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	learners := map[string]*uuid.UUID{}
+	if input.GroupID != nil {
+		members, err := ts.subGroupMembers(*input.GroupID, terminal.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range members {
+			learners[userID] = input.GroupID
+		}
+	}
+	for _, userID := range input.UserIDs {
+		if userID == terminal.UserID {
+			continue
+		}
+		if _, ok := learners[userID]; ok {
+			continue
+		}
+		classmates, err := ts.areClassmates(terminal.UserID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !classmates {
+			return nil, ErrTeamNotClassmate
+		}
+		learners[userID] = nil
+	}
+	delete(learners, terminal.UserID)
+	if len(learners) == 0 {
+		return nil, ErrTeamNoMembers
+	}
+
+	var team *dto.TerminalTeamResponse
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.TerminalTeamMember{}).Where("terminal_id = ?", terminal.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			owner := &models.TerminalTeamMember{TerminalID: terminal.ID, UserID: terminal.UserID, Role: models.TeamRoleDriver, AddedByID: addedByID}
+			if err := tx.Create(owner).Error; err != nil {
+				return fmt.Errorf("failed to add the terminal's owner to its team: %w", err)
+			}
+		}
+		for userID, groupID := range learners {
+			var existing int64
+			if err := tx.Model(&models.TerminalTeamMember{}).Where("terminal_id = ? AND user_id = ?", terminal.ID, userID).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+			member := &models.TerminalTeamMember{TerminalID: terminal.ID, UserID: userID, Role: models.TeamRoleNavigator, GroupID: groupID, AddedByID: addedByID}
+			if err := tx.Create(member).Error; err != nil {
+				return fmt.Errorf("failed to add %s to the team: %w", userID, err)
+			}
+		}
+		var err error
+		team, err = ts.team(tx, terminal)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// SetTeamRole changes a member's role. A new driver takes over from the
+// previous one, who becomes a navigator. Returns gorm.ErrRecordNotFound when
+// the user is not in the team.
+func (ts *terminalTeamService) SetTeamRole(terminalIDOrSessionID, userID, role string) (*dto.TerminalTeamResponse, error) {
+	if role != models.TeamRoleDriver && role != models.TeamRoleNavigator {
+		return nil, ErrTeamInvalidRole
+	}
+	terminal, err := lookupTerminal(ts.repository, terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var team *dto.TerminalTeamResponse
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		var member models.TerminalTeamMember
+		if err := tx.Where("terminal_id = ? AND user_id = ?", terminal.ID, userID).First(&member).Error; err != nil {
+			return err
+		}
+		if role == models.TeamRoleDriver {
+			if err := tx.Model(&models.TerminalTeamMember{}).
+				Where("terminal_id = ? AND role = ? AND user_id <> ?", terminal.ID, models.TeamRoleDriver, userID).
+				Update("role", models.TeamRoleNavigator).Error; err != nil {
+				return fmt.Errorf("failed to hand over the driver's seat: %w", err)
+			}
+		}
+		if err := tx.Model(&member).Update("role", role).Error; err != nil {
+			return fmt.Errorf("failed to set team role: %w", err)
+		}
+		var err error
+		team, err = ts.team(tx, terminal)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// RemoveTeamMember takes a learner off a terminal's team. A team left without
+// a driver is driven by the owner again; one left with the owner alone is
+// dissolved. Returns gorm.ErrRecordNotFound when the user is not in the team.
+func (ts *terminalTeamService) RemoveTeamMember(terminalIDOrSessionID, userID string) (*dto.TerminalTeamResponse, error) {
+	terminal, err := lookupTerminal(ts.repository, terminalIDOrSessionID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == terminal.UserID {
+		return nil, ErrTeamOwnerRemoval
+	}
+
+	var team *dto.TerminalTeamResponse
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		var member models.TerminalTeamMember
+		if err := tx.Where("terminal_id = ? AND user_id = ?", terminal.ID, userID).First(&member).Error; err != nil {
+			return err
+		}
+		// Rows go outright: the unique index would otherwise keep a learner
+		// who left from ever being added back.
+		if err := tx.Unscoped().Delete(&member).Error; err != nil {
+			return fmt.Errorf("failed to remove team member: %w", err)
+		}
+
+		var others int64
+		if err := tx.Model(&models.TerminalTeamMember{}).
+			Where("terminal_id = ? AND user_id <> ?", terminal.ID, terminal.UserID).Count(&others).Error; err != nil {
+			return err
+		}
+		if others == 0 {
+			if err := tx.Unscoped().Where("terminal_id = ?", terminal.ID).Delete(&models.TerminalTeamMember{}).Error; err != nil {
+				return fmt.Errorf("failed to dissolve the team: %w", err)
+			}
+		} else if member.Role == models.TeamRoleDriver {
+			if err := tx.Model(&models.TerminalTeamMember{}).
+				Where("terminal_id = ? AND user_id = ?", terminal.ID, terminal.UserID).
+				Update("role", models.TeamRoleDriver).Error; err != nil {
+				return fmt.Errorf("failed to hand the driver's seat back to the owner: %w", err)
+			}
+		}
+		var err error
+		team, err = ts.team(tx, terminal)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// TeamRole returns the user's role on a shared terminal, "" when the terminal
+// is not shared or the user is not in its team.
+func (ts *terminalTeamService) TeamRole(terminalIDOrSessionID, userID string) (string, error) {
+	terminal, err := lookupTerminal(ts.repository, terminalIDOrSessionID)
+	if err != nil {
+		return "", err
+	}
+	var member models.TerminalTeamMember
+	err = ts.db.Where("terminal_id = ? AND user_id = ?", terminal.ID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up team role: %w", err)
+	}
+	return member.Role, nil
+}
+
+// CanManageTeam reports whether the user may add and remove team members and
+// reassign roles: the terminal's owner, or the owner of a group they are in.
+// Team members themselves only hand over the driver's seat and leave.
+func (ts *terminalTeamService) CanManageTeam(terminalIDOrSessionID, userID string) (bool, error) {
+	terminal, err := lookupTerminal(ts.repository, terminalIDOrSessionID)
+	if err != nil {
+		return false, err
+	}
+	if terminal.UserID == userID {
+		return true, nil
+	}
+	return ts.lifecycle.checkGroupOwnerAccess(terminal.UserID, userID)
+}
+
+func (ts *terminalTeamService) team(db *gorm.DB, terminal *models.Terminal) (*dto.TerminalTeamResponse, error) {
+	members := []models.TerminalTeamMember{}
+	if err := db.Where("terminal_id = ?", terminal.ID).Order("created_at ASC").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to load the team: %w", err)
+	}
+	return &dto.TerminalTeamResponse{
+		TerminalID: terminal.ID,
+		SessionID:  terminal.SessionID,
+		OwnerID:    terminal.UserID,
+		Members:    members,
+	}, nil
+}
+
+// subGroupMembers returns the active members of an active group the owner is
+// an active member of.
+func (ts *terminalTeamService) subGroupMembers(groupID uuid.UUID, ownerID string) ([]string, error) {
+	var ownerCount int64
+	err := ts.db.Table("group_members").
+		Joins("JOIN class_groups ON class_groups.id = group_members.group_id").
+		Where("group_members.group_id = ? AND group_members.user_id = ?", groupID, ownerID).
+		Where("group_members.is_active = ? AND class_groups.is_active = ?", true, true).
+		Count(&ownerCount).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check group membership: %w", err)
+	}
+	if ownerCount == 0 {
+		return nil, ErrTeamGroupNotOwners
+	}
+
+	var userIDs []string
+	if err := ts.db.Table("group_members").
+		Where("group_id = ? AND is_active = ?", groupID, true).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load group members: %w", err)
+	}
+	return userIDs, nil
+}
+
+// areClassmates reports whether two users are active members of a common
+// active group.
+func (ts *terminalTeamService) areClassmates(ownerID, userID string) (bool, error) {
+	var count int64
+	err := ts.db.Table("group_members AS owner_membership").
+		Joins("JOIN group_members AS member_membership ON member_membership.group_id = owner_membership.group_id").
+		Joins("JOIN class_groups ON class_groups.id = owner_membership.group_id").
+		Where("owner_membership.user_id = ? AND member_membership.user_id = ?", ownerID, userID).
+		Where("owner_membership.is_active = ? AND member_membership.is_active = ? AND class_groups.is_active = ?", true, true, true).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check group membership: %w", err)
+	}
+	return count > 0, nil
+}
+
+// lookupTerminal loads a terminal by its UUID or its tt-backend session id,
+// as the /terminals/:id routes accept either.
+func lookupTerminal(repository repositories.TerminalRepository, terminalIDOrSessionID string) (*models.Terminal, error) {
+	terminal, err := repository.GetTerminalByUUID(terminalIDOrSessionID)
+	if err != nil {
+		terminal, err = repository.GetTerminalSessionByID(terminalIDOrSessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("terminal not found: %w", err)
+	}
+	return terminal, nil
+}
@@ -74,6 +74,9 @@ type TerminalTrainerService interface {
 	GetSessionCommandHistoryAdmin(sessionUUID string, limit, offset int) ([]byte, string, error)
 	DeleteSessionCommandHistory(sessionID string) error
 	DeleteAllUserCommandHistory(apiKey string) (int64, error)
+	// GetSessionRecording returns the session's terminal I/O as asciicast v2,
+	// cut to opts and to the plan's command history retention.
+	GetSessionRecording(sessionID string, opts dto.RecordingReplayOptions) ([]byte, error)
 
 	// Organization session management
 	GetOrganizationTerminalSessions(orgID uuid.UUID) (*[]models.Terminal, error)
@@ -122,9 +125,32 @@ type TerminalTrainerService interface {
 	// refuses (#457). nil means the budget is the user's own.
 	EnrichSessionOptionsBudget(opts *dto.SessionOptionsResponse, plan *paymentModels.SubscriptionPlan, userID string, budgetScopeOrgID *uuid.UUID)
 	StartComposedSession(userID string, input dto.CreateComposedSessionInput, planInterface any) (*dto.TerminalSessionResponse, error)
+	// StartComposedTopology starts one composed session per node of a
+	// multi-node topology, budgeted as a whole. The responses follow the
+	// inputs' order. On failure the nodes that did start are returned with the
+	// error (nil for the others); the caller stops them.
+	StartComposedTopology(userID string, inputs []dto.CreateComposedSessionInput, planInterface any) ([]*dto.TerminalSessionResponse, error)
 	// BuildComplete removes the features a session held only while it was
 	// being provisioned. Called once the scenario's setup has run.
 	BuildComplete(sessionID string) error
+
+	// Snapshots of persistent terminals, counted against the owner's plan
+	// storage. ReplaceSnapshot overwrites a snapshot of the same name.
+	ListSnapshots(terminalIDOrSessionID string) (*dto.TerminalSnapshotsResponse, error)
+	CreateSnapshot(terminalIDOrSessionID, name, createdByID string, stepOrder *int) (*models.TerminalSnapshot, error)
+	ReplaceSnapshot(terminalIDOrSessionID, name, createdByID string, stepOrder *int) (*models.TerminalSnapshot, error)
+	RestoreSnapshot(terminalIDOrSessionID, name string) error
+	DeleteSnapshot(terminalIDOrSessionID, name string) error
+
+	// Shared terminals: the team of learners attached to one container, one
+	// of them driving. HasTerminalAccess lets team members in;
+	// CanManageTerminalTeam is who may change the team beyond that.
+	GetTerminalTeam(terminalIDOrSessionID string) (*dto.TerminalTeamResponse, error)
+	ShareTerminal(terminalIDOrSessionID string, input dto.ShareTerminalInput, addedByID string) (*dto.TerminalTeamResponse, error)
+	SetTerminalTeamRole(terminalIDOrSessionID, userID, role string) (*dto.TerminalTeamResponse, error)
+	RemoveTerminalTeamMember(terminalIDOrSessionID, userID string) (*dto.TerminalTeamResponse, error)
+	GetTerminalTeamRole(terminalIDOrSessionID, userID string) (string, error)
+	CanManageTerminalTeam(terminalIDOrSessionID, userID string) (bool, error)
 }
 
 type terminalTrainerService struct {
@@ -143,6 +169,8 @@ type terminalTrainerService struct {
 	lifecycle              *terminalLifecycleService
 	composer               *terminalComposer
 	history                *terminalHistoryService
+	snapshots              *terminalSnapshotService
+	teams                  *terminalTeamService
 }
 
 func NewTerminalTrainerService(db *gorm.DB) TerminalTrainerService {
@@ -166,6 +194,7 @@ func NewTerminalTrainerService(db *gorm.DB) TerminalTrainerService {
 	catalog := newTerminalCatalogService(proxy, baseURL, apiVersion, adminKey)
 	quotaService := paymentServices.NewQuotaService(db, eps)
 	enumService := NewTerminalTrainerEnumService(baseURL, apiVersion)
+	lifecycle := newTerminalLifecycleService(proxy, sync, repository, db)
 
 	tts := &terminalTrainerService{
 		adminKey:               adminKey,
@@ -180,8 +209,10 @@ func NewTerminalTrainerService(db *gorm.DB) TerminalTrainerService {
 		proxy:                  proxy,
 		catalog:                catalog,
 		sync:                   sync,
-		lifecycle:              newTerminalLifecycleService(proxy, sync, repository, db),
+		lifecycle:              lifecycle,
 		history:                newTerminalHistoryService(proxy, repository, db, baseURL, apiVersion, adminKey),
+		snapshots:              newTerminalSnapshotService(proxy, repository, db),
+		teams:                  newTerminalTeamService(repository, lifecycle, db),
 	}
 
 	// Constructed last: the composer takes the facade's CreateUserKey as a
@@ -366,6 +397,56 @@ func (tts *terminalTrainerService) BuildComplete(sessionID string) error {
 	return tts.lifecycle.BuildComplete(sessionID)
 }
 
+// The following methods delegate to terminalSnapshotService, which owns the
+// snapshots of persistent terminals and the storage they are counted against.
+
+func (tts *terminalTrainerService) ListSnapshots(terminalIDOrSessionID string) (*dto.TerminalSnapshotsResponse, error) {
+	return tts.snapshots.ListSnapshots(terminalIDOrSessionID)
+}
+
+func (tts *terminalTrainerService) CreateSnapshot(terminalIDOrSessionID, name, createdByID string, stepOrder *int) (*models.TerminalSnapshot, error) {
+	return tts.snapshots.CreateSnapshot(terminalIDOrSessionID, name, createdByID, stepOrder)
+}
+
+func (tts *terminalTrainerService) ReplaceSnapshot(terminalIDOrSessionID, name, createdByID string, stepOrder *int) (*models.TerminalSnapshot, error) {
+	return tts.snapshots.ReplaceSnapshot(terminalIDOrSessionID, name, createdByID, stepOrder)
+}
+
+func (tts *terminalTrainerService) RestoreSnapshot(terminalIDOrSessionID, name string) error {
+	return tts.snapshots.RestoreSnapshot(terminalIDOrSessionID, name)
+}
+
+func (tts *terminalTrainerService) DeleteSnapshot(terminalIDOrSessionID, name string) error {
+	return tts.snapshots.DeleteSnapshot(terminalIDOrSessionID, name)
+}
+
+// The following methods delegate to terminalTeamService, which owns shared
+// terminals and their teams.
+
+func (tts *terminalTrainerService) GetTerminalTeam(terminalIDOrSessionID string) (*dto.TerminalTeamResponse, error) {
+	return tts.teams.GetTeam(terminalIDOrSessionID)
+}
+
+func (tts *terminalTrainerService) ShareTerminal(terminalIDOrSessionID string, input dto.ShareTerminalInput, addedByID string) (*dto.TerminalTeamResponse, error) {
+	return tts.teams.ShareTerminal(terminalIDOrSessionID, input, addedByID)
+}
+
+func (tts *terminalTrainerService) SetTerminalTeamRole(terminalIDOrSessionID, userID, role string) (*dto.TerminalTeamResponse, error) {
+	return tts.teams.SetTeamRole(terminalIDOrSessionID, userID, role)
+}
+
+func (tts *terminalTrainerService) RemoveTerminalTeamMember(terminalIDOrSessionID, userID string) (*dto.TerminalTeamResponse, error) {
+	return tts.teams.RemoveTeamMember(terminalIDOrSessionID, userID)
+}
+
+func (tts *terminalTrainerService) GetTerminalTeamRole(terminalIDOrSessionID, userID string) (string, error) {
+	return tts.teams.TeamRole(terminalIDOrSessionID, userID)
+}
+
+func (tts *terminalTrainerService) CanManageTerminalTeam(terminalIDOrSessionID, userID string) (bool, error) {
+	return tts.teams.CanManageTeam(terminalIDOrSessionID, userID)
+}
+
 // The following methods delegate to terminalSyncService, which owns the
 // tt-backend session reconciliation (treating the API session list as the
 // source of truth, creating/updating/soft-deleting local rows). The shared
@@ -579,6 +660,10 @@ func (tts *terminalTrainerService) DeleteAllUserCommandHistory(apiKey string) (i
 	return tts.history.DeleteAllUserCommandHistory(apiKey)
 }
 
+func (tts *terminalTrainerService) GetSessionRecording(sessionID string, opts dto.RecordingReplayOptions) ([]byte, error) {
+	return tts.history.GetSessionRecording(sessionID, opts)
+}
+
 func (tts *terminalTrainerService) GetOrganizationTerminalSessions(orgID uuid.UUID) (*[]models.Terminal, error) {
 	return tts.repository.GetTerminalSessionsByOrganizationID(orgID)
 }
@@ -1163,6 +1248,12 @@ func (tts *terminalTrainerService) EnrichSessionOptionsBudget(
 func (tts *terminalTrainerService) StartComposedSession(userID string, input dto.CreateComposedSessionInput, planInterface any) (*dto.TerminalSessionResponse, error) {
 	return tts.composer.StartComposedSession(userID, input, planInterface)
 }
+
+// StartComposedTopology delegates to terminalComposer.
+func (tts *terminalTrainerService) StartComposedTopology(userID string, inputs []dto.CreateComposedSessionInput, planInterface any) ([]*dto.TerminalSessionResponse, error) {
+	return tts.composer.StartComposedTopology(userID, inputs, planInterface)
+}
+
 // BudgetRejection is the structured error surfaced by StartComposedSession
 // when CheckBudget rejects a request in budget-mode. Carries enough context
 // for the controller to translate into a 403 with body
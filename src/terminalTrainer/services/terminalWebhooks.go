@@ -0,0 +1,42 @@
+package services
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"soli/formations/src/liveprogress"
+	"soli/formations/src/terminalTrainer/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+// terminalEventData is the "data" of the terminal webhooks.
+type terminalEventData struct {
+	SessionID       string    `json:"session_id"`
+	TerminalID      string    `json:"terminal_id"`
+	UserID          string    `json:"user_id"`
+	State           string    `json:"state"`
+	Backend         string    `json:"backend,omitempty"`
+	InstanceType    string    `json:"instance_type,omitempty"`
+	MachineSize     string    `json:"machine_size,omitempty"`
+	PersistenceMode string    `json:"persistence_mode,omitempty"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// publishTerminalEvent raises a terminal webhook for the terminal's
+// organization (terminals started outside one raise nothing) and tells the
+// live class views that the learner's presence changed.
+func publishTerminalEvent(db *gorm.DB, terminal *models.Terminal, eventType string) {
+	liveprogress.Publish(liveprogress.Event{Kind: liveprogress.KindTerminalPresence, UserID: terminal.UserID})
+	webhookServices.Emit(db, terminal.OrganizationID, eventType, terminalEventData{
+		SessionID:       terminal.SessionID,
+		TerminalID:      terminal.ID.String(),
+		UserID:          terminal.UserID,
+		State:           string(terminal.State),
+		Backend:         terminal.Backend,
+		InstanceType:    terminal.InstanceType,
+		MachineSize:     terminal.MachineSize,
+		PersistenceMode: terminal.PersistenceMode,
+		ExpiresAt:       terminal.ExpiresAt,
+	})
+}
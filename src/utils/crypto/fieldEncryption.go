@@ -107,6 +107,32 @@ func Decrypt(value string) (string, error) {
 	return string(plaintext), nil
 }
 
+// EncryptField encrypts *field in place for a model's BeforeSave hook. Empty
+// and already encrypted values are left unchanged, so saving a model loaded
+// without its AfterFind hook does not encrypt it twice.
+func EncryptField(field *string) error {
+	if *field == "" || strings.HasPrefix(*field, prefix) {
+		return nil
+	}
+	encrypted, err := Encrypt(*field)
+	if err != nil {
+		return err
+	}
+	*field = encrypted
+	return nil
+}
+
+// DecryptField decrypts *field in place for a model's AfterFind hook, so
+// callers always work with plaintext. Legacy plaintext is left unchanged.
+func DecryptField(field *string) error {
+	decrypted, err := Decrypt(*field)
+	if err != nil {
+		return err
+	}
+	*field = decrypted
+	return nil
+}
+
 // deriveKey returns a 32-byte AES-256 key derived from the given secret via SHA-256.
 func deriveKey(secret string) []byte {
 	sum := sha256.Sum256([]byte(secret))
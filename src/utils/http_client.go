@@ -2,11 +2,14 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	"soli/formations/src/observability"
 )
 
 // HTTPClientOptions configures HTTP client behavior
@@ -15,6 +18,12 @@ type HTTPClientOptions struct {
 	Headers      map[string]string
 	RetryCount   int
 	RetryDelayMS int
+	// Context parents the request's trace span and cancels the request.
+	// Nil means context.Background().
+	Context context.Context
+	// ServiceName names the callee in trace spans; set by the
+	// MakeExternalAPI* helpers.
+	ServiceName string
 }
 
 // DefaultHTTPClientOptions returns sensible defaults
@@ -90,8 +99,17 @@ func MakeHTTPRequest(method, url string, body any, opts HTTPClientOptions) (*HTT
 		bodyReader = bytes.NewBuffer(jsonBytes)
 	}
 
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = "HTTP"
+	}
+
 	// Create HTTP request
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -113,7 +131,8 @@ func MakeHTTPRequest(method, url string, body any, opts HTTPClientOptions) (*HTT
 
 	// Create HTTP client with timeout
 	client := &http.Client{
-		Timeout: opts.Timeout,
+		Timeout:   opts.Timeout,
+		Transport: observability.NewTracingTransport(nil, serviceName),
 	}
 
 	// Execute request
@@ -238,6 +257,7 @@ func MakeJSONRequestWithRetry(method, url string, body any, result any, opts HTT
 //
 //	resp, err := MakeExternalAPIRequest("Terminal Trainer", "POST", url, payload, opts)
 func MakeExternalAPIRequest(serviceName, method, url string, body any, opts HTTPClientOptions) (*HTTPResponse, error) {
+	opts.ServiceName = serviceName
 	resp, err := MakeHTTPRequest(method, url, body, opts)
 	if err != nil {
 		return nil, ExternalAPIError(serviceName, fmt.Sprintf("%s %s", method, url), err)
@@ -284,6 +304,13 @@ func WithTimeout(timeout time.Duration) func(*HTTPClientOptions) {
 	}
 }
 
+// WithContext sets the context the request is made under
+func WithContext(ctx context.Context) func(*HTTPClientOptions) {
+	return func(opts *HTTPClientOptions) {
+		opts.Context = ctx
+	}
+}
+
 // WithHeader adds a header to the HTTP request
 func WithHeader(key, value string) func(*HTTPClientOptions) {
 	return func(opts *HTTPClientOptions) {
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"soli/formations/src/webhooks/models"
+)
+
+// CreateWebhookInput registers an endpoint for some of an organization's events.
+type CreateWebhookInput struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" binding:"required,min=1"`
+}
+
+// UpdateWebhookInput changes a subscription; omitted fields are kept.
+type UpdateWebhookInput struct {
+	URL         *string   `json:"url,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Events      *[]string `json:"events,omitempty"`
+	IsActive    *bool     `json:"is_active,omitempty"`
+}
+
+// WebhookSubscriptionWithSecret is the creation response: the only time the
+// signing secret is shown.
+type WebhookSubscriptionWithSecret struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	entityManagementModels "soli/formations/src/entityManagement/models"
+	"soli/formations/src/utils/crypto"
+)
+
+// WebhookDelivery state values — lifecycle of one event sent to one endpoint.
+const (
+	WebhookDeliveryStatePending   = "pending"   // Queued, or waiting for its next retry
+	WebhookDeliveryStateSucceeded = "succeeded" // Terminal: the endpoint answered 2xx
+	WebhookDeliveryStateDead      = "dead"      // Terminal: gave up after WebhookDeliveryMaxAttempts
+)
+
+// WebhookDeliveryMaxAttempts caps attempts before a delivery is dead-lettered.
+// With the worker's exponential backoff (30s doubling, capped at 6h) the last
+// attempt happens a little over 1h after the event, so an endpoint down for
+// a deploy or a short outage still receives everything.
+const WebhookDeliveryMaxAttempts = 8
+
+// WebhookSubscription is an HTTPS endpoint an organization registered to
+// receive some of its events. Secret signs every delivery (see
+// services.SignPayload); it is returned once, when the subscription is
+// created, never serialized afterwards, and encrypted at rest.
+type WebhookSubscription struct {
+	entityManagementModels.BaseModel
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id"`
+	URL            string    `gorm:"type:varchar(2048);not null" json:"url"`
+	Description    string    `gorm:"type:varchar(255)" json:"description,omitempty"`
+	Events         []string  `gorm:"serializer:json" json:"events"`
+	Secret         string    `gorm:"type:varchar(255);not null" json:"-"`
+	IsActive       bool      `gorm:"default:true" json:"is_active"`
+	CreatedByID    string    `gorm:"type:varchar(255);not null" json:"created_by_id"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// BeforeSave encrypts Secret before writing to the database.
+func (s *WebhookSubscription) BeforeSave(tx *gorm.DB) error {
+	return crypto.EncryptField(&s.Secret)
+}
+
+// AfterFind decrypts Secret after loading from the database.
+func (s *WebhookSubscription) AfterFind(tx *gorm.DB) error {
+	return crypto.DecryptField(&s.Secret)
+}
+
+// Subscribes reports whether the subscription asked for eventType.
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is both the durable queue row and the delivery log of one
+// event for one subscription. Payload is the exact body sent, so a
+// redelivery carries the same event id and data as the original.
+//
+// Like StripeSync, the ID has no database-side default: the queue assigns
+// uuid.New() before insert so AutoMigrate works on SQLite too.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	EventID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"event_id"`
+	EventType      string     `gorm:"type:varchar(100);not null" json:"event_type"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	State          string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"state"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	// RedeliveryOf is the delivery this one was manually replayed from.
+	RedeliveryOf *uuid.UUID `gorm:"type:uuid" json:"redelivery_of,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	Subscription *WebhookSubscription     `gorm:"foreignKey:SubscriptionID" json:"-"`
+	AttemptLog   []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE" json:"attempt_log,omitempty"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryAttempt records one HTTP attempt of a delivery: what the
+// endpoint answered (or why it could not be reached) and how long it took.
+type WebhookDeliveryAttempt struct {
+	ID           uint      `gorm:"primarykey" json:"-"`
+	DeliveryID   uuid.UUID `gorm:"type:uuid;not null;index" json:"delivery_id"`
+	Attempt      int       `gorm:"not null" json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `gorm:"type:text" json:"error,omitempty"`
+	ResponseBody string    `gorm:"type:text" json:"response_body,omitempty"` // truncated
+	DurationMs   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `gorm:"not null" json:"attempted_at"`
+}
+
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
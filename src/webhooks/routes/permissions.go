@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"log"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/interfaces"
+)
+
+// RegisterPermissions registers the Casbin policies and RouteRegistry entries
+// of the organization webhook endpoints. Webhooks expose the organization's
+// activity to an outside system, so every route requires an organization
+// manager.
+func RegisterPermissions(enforcer interfaces.EnforcerInterface) {
+	log.Println("=== Registering webhook permissions ===")
+
+	managerOf := access.AccessRule{Type: access.OrgRole, Param: "id", MinRole: "manager"}
+	access.RegisterEnforced(enforcer, "Webhooks",
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks/event-types", Method: "GET",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "List the event types a webhook can subscribe to",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks", Method: "GET",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "List an organization's webhooks",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks", Method: "POST",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "Register a webhook for an organization's events",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks/:webhookId", Method: "PATCH",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "Update an organization webhook",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks/:webhookId", Method: "DELETE",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "Delete an organization webhook",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks/:webhookId/deliveries", Method: "GET",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "List a webhook's deliveries",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks/:webhookId/deliveries/:deliveryId", Method: "GET",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "View a webhook delivery and its attempt log",
+		},
+		access.RoutePermission{
+			Path: "/api/v1/organizations/:id/webhooks/:webhookId/deliveries/:deliveryId/redeliver", Method: "POST",
+			Role: access.RoleMember, Access: managerOf,
+			Description: "Redeliver a webhook event",
+		},
+	)
+
+	log.Println("=== Webhook permissions registered ===")
+}
@@ -0,0 +1,276 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"soli/formations/src/webhooks/dto"
+	"soli/formations/src/webhooks/models"
+	"soli/formations/src/webhooks/services"
+)
+
+// WebhookController serves an organization's webhook subscriptions and
+// delivery logs.
+type WebhookController struct {
+	service *services.WebhookService
+}
+
+// NewWebhookController creates a new webhook controller.
+func NewWebhookController(service *services.WebhookService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+// ListEventTypes godoc
+// @Summary List webhook event types
+// @Description Returns the event types a webhook subscription can ask for.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} string
+// @Router /organizations/{id}/webhooks/event-types [get]
+// @Security BearerAuth
+func (wc *WebhookController) ListEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, services.EventTypes)
+}
+
+// ListWebhooks godoc
+// @Summary List an organization's webhooks
+// @Description Returns the organization's webhook subscriptions. Signing secrets are not included.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /organizations/{id}/webhooks [get]
+// @Security BearerAuth
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	orgID, ok := parseID(c, "id", "invalid organization ID")
+	if !ok {
+		return
+	}
+	subscriptions, err := wc.service.ListSubscriptions(orgID)
+	if err != nil {
+		slog.Error("failed to list webhook subscriptions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Subscribes an HTTPS endpoint to some of the organization's events. The response
+// @Description carries the signing secret; it is not shown again. Every delivery is signed in the
+// @Description X-OCF-Signature header as "t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param body body dto.CreateWebhookInput true "Endpoint and events"
+// @Success 201 {object} dto.WebhookSubscriptionWithSecret
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /organizations/{id}/webhooks [post]
+// @Security BearerAuth
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	orgID, ok := parseID(c, "id", "invalid organization ID")
+	if !ok {
+		return
+	}
+	var input dto.CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	subscription, err := wc.service.CreateSubscription(orgID, c.GetString("userId"), input)
+	if err != nil {
+		writeWebhookError(c, err, "failed to create webhook")
+		return
+	}
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Changes a subscription's URL, description or events, or disables it.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param webhookId path string true "Webhook ID"
+// @Param body body dto.UpdateWebhookInput true "Fields to change"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /organizations/{id}/webhooks/{webhookId} [patch]
+// @Security BearerAuth
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	orgID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+	var input dto.UpdateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	subscription, err := wc.service.UpdateSubscription(orgID, webhookID, input)
+	if err != nil {
+		writeWebhookError(c, err, "failed to update webhook")
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Removes a subscription. Its pending deliveries are dead-lettered; the delivery log is kept.
+// @Tags webhooks
+// @Param id path string true "Organization ID"
+// @Param webhookId path string true "Webhook ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /organizations/{id}/webhooks/{webhookId} [delete]
+// @Security BearerAuth
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	orgID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+	if err := wc.service.DeleteSubscription(orgID, webhookID); err != nil {
+		writeWebhookError(c, err, "failed to delete webhook")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List a webhook's deliveries
+// @Description Returns the 100 most recent deliveries of a subscription, optionally filtered by state.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param webhookId path string true "Webhook ID"
+// @Param state query string false "pending, succeeded or dead"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /organizations/{id}/webhooks/{webhookId}/deliveries [get]
+// @Security BearerAuth
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	orgID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+	state := c.Query("state")
+	switch state {
+	case "", models.WebhookDeliveryStatePending, models.WebhookDeliveryStateSucceeded, models.WebhookDeliveryStateDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state must be pending, succeeded or dead"})
+		return
+	}
+	deliveries, err := wc.service.ListDeliveries(orgID, webhookID, state)
+	if err != nil {
+		writeWebhookError(c, err, "failed to list deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery godoc
+// @Summary Get a webhook delivery
+// @Description Returns a delivery with the payload sent and the log of every attempt.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /organizations/{id}/webhooks/{webhookId}/deliveries/{deliveryId} [get]
+// @Security BearerAuth
+func (wc *WebhookController) GetDelivery(c *gin.Context) {
+	orgID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "deliveryId", "invalid delivery ID")
+	if !ok {
+		return
+	}
+	delivery, err := wc.service.GetDelivery(orgID, webhookID, deliveryID)
+	if err != nil {
+		writeWebhookError(c, err, "failed to load delivery")
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook event
+// @Description Queues the delivery's event again for the subscription, with the same event id,
+// @Description whatever the original outcome. The original delivery and its log are kept.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /organizations/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver [post]
+// @Security BearerAuth
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	orgID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "deliveryId", "invalid delivery ID")
+	if !ok {
+		return
+	}
+	delivery, err := wc.service.Redeliver(orgID, webhookID, deliveryID)
+	if err != nil {
+		writeWebhookError(c, err, "failed to redeliver")
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func parseID(c *gin.Context, param, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func parseWebhookIDs(c *gin.Context) (orgID, webhookID uuid.UUID, ok bool) {
+	if orgID, ok = parseID(c, "id", "invalid organization ID"); !ok {
+		return
+	}
+	webhookID, ok = parseID(c, "webhookId", "invalid webhook ID")
+	return
+}
+
+func writeWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+	default:
+		slog.Error(message, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
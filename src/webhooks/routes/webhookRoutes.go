@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	auth "soli/formations/src/auth"
+	"soli/formations/src/webhooks/services"
+)
+
+// RegisterRoutes wires an organization's webhook management endpoints.
+func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	mw := auth.NewAuthMiddleware(db)
+	controller := NewWebhookController(services.NewWebhookService(db))
+
+	webhooks := router.Group("/organizations/:id/webhooks")
+	webhooks.GET("/event-types", mw.AuthManagement(), controller.ListEventTypes)
+	webhooks.GET("", mw.AuthManagement(), controller.ListWebhooks)
+	webhooks.POST("", mw.AuthManagement(), controller.CreateWebhook)
+	webhooks.PATCH("/:webhookId", mw.AuthManagement(), controller.UpdateWebhook)
+	webhooks.DELETE("/:webhookId", mw.AuthManagement(), controller.DeleteWebhook)
+	webhooks.GET("/:webhookId/deliveries", mw.AuthManagement(), controller.ListDeliveries)
+	webhooks.GET("/:webhookId/deliveries/:deliveryId", mw.AuthManagement(), controller.GetDelivery)
+	webhooks.POST("/:webhookId/deliveries/:deliveryId/redeliver", mw.AuthManagement(), controller.Redeliver)
+}
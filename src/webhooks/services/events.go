@@ -0,0 +1,112 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/webhooks/models"
+)
+
+// Event types an organization can subscribe to.
+const (
+	EventScenarioSessionCompleted = "scenario_session.completed"
+	EventScenarioSessionGraded    = "scenario_session.graded"
+	EventOrganizationMemberAdded  = "organization_member.added"
+	EventLicenseAssigned          = "license.assigned"
+	EventTerminalStarted          = "terminal.started"
+	EventTerminalStopped          = "terminal.stopped"
+	EventCertificateIssued        = "certificate.issued"
+	EventCertificateRevoked       = "certificate.revoked"
+)
+
+// EventTypes lists every event type, in the order the API documents them.
+var EventTypes = []string{
+	EventScenarioSessionCompleted,
+	EventScenarioSessionGraded,
+	EventOrganizationMemberAdded,
+	EventLicenseAssigned,
+	EventTerminalStarted,
+	EventTerminalStopped,
+	EventCertificateIssued,
+	EventCertificateRevoked,
+}
+
+// IsKnownEvent reports whether eventType is one of EventTypes.
+func IsKnownEvent(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the JSON body of every delivery. ID identifies the event, not the
+// delivery: it is the same for every subscription the event goes to and for
+// a redelivery, so receivers can deduplicate on it.
+type Event struct {
+	ID             uuid.UUID `json:"id"`
+	Type           string    `json:"type"`
+	CreatedAt      time.Time `json:"created_at"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Data           any       `json:"data"`
+}
+
+// Publish queues eventType for every active subscription of orgID that asked
+// for it, and returns how many deliveries were queued. It only writes queue
+// rows; the WebhookDeliveryWorker sends them.
+func Publish(db *gorm.DB, orgID uuid.UUID, eventType string, data any) (int, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := db.Where("organization_id = ? AND is_active = ?", orgID, true).Find(&subscriptions).Error; err != nil {
+		return 0, fmt.Errorf("load webhook subscriptions: %w", err)
+	}
+
+	var targets []models.WebhookSubscription
+	for _, sub := range subscriptions {
+		if sub.Subscribes(eventType) {
+			targets = append(targets, sub)
+		}
+	}
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	event := Event{
+		ID:             uuid.New(),
+		Type:           eventType,
+		CreatedAt:      time.Now().UTC(),
+		OrganizationID: orgID,
+		Data:           data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("marshal webhook event: %w", err)
+	}
+
+	queue := NewWebhookDeliveryQueue(db)
+	queued := 0
+	for i := range targets {
+		if err := queue.Enqueue(&targets[i], event.ID, eventType, payload); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// Emit is Publish for the modules raising events: an event outside any
+// organization is dropped, and a failure is logged rather than returned,
+// because a webhook must never fail the action it reports.
+func Emit(db *gorm.DB, orgID *uuid.UUID, eventType string, data any) {
+	if orgID == nil || *orgID == uuid.Nil {
+		return
+	}
+	if _, err := Publish(db, *orgID, eventType, data); err != nil {
+		slog.Warn("failed to queue webhook event", "event", eventType, "organization_id", *orgID, "err", err)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/webhooks/models"
+)
+
+// webhookRetryBaseDelay and webhookRetryMaxDelay bound the exponential
+// backoff between two attempts of a delivery.
+const (
+	webhookRetryBaseDelay = 30 * time.Second
+	webhookRetryMaxDelay  = 6 * time.Hour
+)
+
+// RetryDelay is how long a delivery waits after its attempts-th failed
+// attempt: 30s, 1m, 2m, ... capped at 6h.
+func RetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		return webhookRetryBaseDelay
+	}
+	delay := webhookRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMaxDelay {
+			return webhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// WebhookDeliveryQueue is the durable queue of outbound webhook deliveries,
+// shaped like StripeSyncQueue: Publish enqueues (no HTTP call), the
+// WebhookDeliveryWorker polls ListDue and records each attempt through
+// MarkSuccess or MarkFailure. Unlike Stripe syncs, a failed delivery is not
+// retried on the next poll but after RetryDelay, and dead-letters after
+// models.WebhookDeliveryMaxAttempts.
+type WebhookDeliveryQueue interface {
+	Enqueue(subscription *models.WebhookSubscription, eventID uuid.UUID, eventType string, payload []byte) error
+	ListDue(now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	ListByID(id uuid.UUID) ([]*models.WebhookDelivery, error)
+	MarkSuccess(id uuid.UUID, attempt models.WebhookDeliveryAttempt) error
+	MarkFailure(id uuid.UUID, attempt models.WebhookDeliveryAttempt) error
+	Redeliver(id uuid.UUID) (*models.WebhookDelivery, error)
+	CountPending() (int64, error)
+}
+
+type webhookDeliveryQueue struct {
+	db *gorm.DB
+}
+
+// NewWebhookDeliveryQueue constructs the production queue.
+func NewWebhookDeliveryQueue(db *gorm.DB) WebhookDeliveryQueue {
+	return &webhookDeliveryQueue{db: db}
+}
+
+// Enqueue stores a pending delivery of the event payload to subscription,
+// due now.
+func (q *webhookDeliveryQueue) Enqueue(subscription *models.WebhookSubscription, eventID uuid.UUID, eventType string, payload []byte) error {
+	row := models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscription.ID,
+		OrganizationID: subscription.OrganizationID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        string(payload),
+		State:          models.WebhookDeliveryStatePending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := q.db.Create(&row).Error; err != nil {
+		return fmt.Errorf("insert webhook_delivery row: %w", err)
+	}
+	return nil
+}
+
+// ListDue returns up to limit pending deliveries whose next attempt is due,
+// oldest first, with their subscription loaded (nil once it was deleted).
+func (q *webhookDeliveryQueue) ListDue(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	var rows []*models.WebhookDelivery
+	err := q.db.
+		Preload("Subscription").
+		Where("state = ? AND next_attempt_at <= ?", models.WebhookDeliveryStatePending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("query due webhook deliveries: %w", err)
+	}
+	return rows, nil
+}
+
+// ListByID returns the delivery with the given id (in a slice for
+// consistency with ListDue).
+func (q *webhookDeliveryQueue) ListByID(id uuid.UUID) ([]*models.WebhookDelivery, error) {
+	var rows []*models.WebhookDelivery
+	if err := q.db.Where("id = ?", id).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("query webhook_delivery by id: %w", err)
+	}
+	return rows, nil
+}
+
+// MarkSuccess logs attempt and transitions the delivery to succeeded. Terminal.
+func (q *webhookDeliveryQueue) MarkSuccess(id uuid.UUID, attempt models.WebhookDeliveryAttempt) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		row, err := logAttempt(tx, id, &attempt)
+		if err != nil {
+			return err
+		}
+		row.State = models.WebhookDeliveryStateSucceeded
+		row.DeliveredAt = &attempt.AttemptedAt
+		row.LastError = ""
+		if err := tx.Save(row).Error; err != nil {
+			return fmt.Errorf("save webhook_delivery row: %w", err)
+		}
+		return nil
+	})
+}
+
+// MarkFailure logs attempt and either schedules the next one after
+// RetryDelay or, once attempts reaches WebhookDeliveryMaxAttempts,
+// dead-letters the delivery.
+func (q *webhookDeliveryQueue) MarkFailure(id uuid.UUID, attempt models.WebhookDeliveryAttempt) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		row, err := logAttempt(tx, id, &attempt)
+		if err != nil {
+			return err
+		}
+		row.LastError = attempt.Error
+		if row.LastError == "" {
+			row.LastError = fmt.Sprintf("endpoint answered HTTP %d", attempt.StatusCode)
+		}
+		if row.Attempts >= models.WebhookDeliveryMaxAttempts {
+			row.State = models.WebhookDeliveryStateDead
+		} else {
+			row.State = models.WebhookDeliveryStatePending
+			row.NextAttemptAt = attempt.AttemptedAt.Add(RetryDelay(row.Attempts))
+		}
+		if err := tx.Save(row).Error; err != nil {
+			return fmt.Errorf("save webhook_delivery row: %w", err)
+		}
+		return nil
+	})
+}
+
+// logAttempt inserts the attempt log row and returns the delivery with its
+// attempt counter and last-attempt fields updated, for the caller to save.
+func logAttempt(tx *gorm.DB, id uuid.UUID, attempt *models.WebhookDeliveryAttempt) (*models.WebhookDelivery, error) {
+	var row models.WebhookDelivery
+	if err := tx.First(&row, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("load webhook_delivery row: %w", err)
+	}
+	row.Attempts++
+	attempt.DeliveryID = id
+	attempt.Attempt = row.Attempts
+	if err := tx.Create(attempt).Error; err != nil {
+		return nil, fmt.Errorf("insert webhook_delivery_attempt row: %w", err)
+	}
+	row.LastAttemptAt = &attempt.AttemptedAt
+	row.LastStatusCode = attempt.StatusCode
+	return &row, nil
+}
+
+// Redeliver queues a fresh delivery of the same event to the same
+// subscription, due now. The original row and its attempt log are kept as
+// they are; the copy points back to it through RedeliveryOf.
+func (q *webhookDeliveryQueue) Redeliver(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var original models.WebhookDelivery
+	if err := q.db.First(&original, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("load webhook_delivery row: %w", err)
+	}
+	row := models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: original.SubscriptionID,
+		OrganizationID: original.OrganizationID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		State:          models.WebhookDeliveryStatePending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOf:   &original.ID,
+	}
+	if err := q.db.Create(&row).Error; err != nil {
+		return nil, fmt.Errorf("insert webhook_delivery row: %w", err)
+	}
+	return &row, nil
+}
+
+// CountPending returns the number of deliveries not yet succeeded or dead,
+// due or waiting for a retry.
+func (q *webhookDeliveryQueue) CountPending() (int64, error) {
+	var count int64
+	if err := q.db.Model(&models.WebhookDelivery{}).
+		Where("state = ?", models.WebhookDeliveryStatePending).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count pending webhook deliveries: %w", err)
+	}
+	return count, nil
+}
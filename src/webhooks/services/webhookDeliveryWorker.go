@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"soli/formations/src/observability"
+	"soli/formations/src/webhooks/models"
+)
+
+// webhookWorkerPollInterval controls how often the worker looks for due
+// deliveries. Shorter than the Stripe worker's: a webhook is expected within
+// seconds of its event, and a pass with nothing due is a single query.
+const webhookWorkerPollInterval = 5 * time.Second
+
+// webhookWorkerBatchSize caps the number of deliveries sent per pass.
+const webhookWorkerBatchSize = 50
+
+// webhookRequestTimeout bounds one delivery attempt, connection included.
+const webhookRequestTimeout = 10 * time.Second
+
+// webhookResponseLogLimit caps the response body kept in the attempt log.
+const webhookResponseLogLimit = 1024
+
+// errBlockedWebhookTarget is returned when a webhook URL resolves to an
+// address inside ocf-core's own network.
+var errBlockedWebhookTarget = errors.New("webhook target resolves to a private or loopback address")
+
+// WebhookDeliveryWorker drains the WebhookDeliveryQueue: polls due
+// deliveries on a fixed interval, POSTs each signed payload to its
+// subscription's URL and records the attempt. Survives process restarts via
+// the durable queue.
+//
+// Concurrency model: a single goroutine, like StripeSyncWorker. Deliveries
+// are sent one after the other; a slow endpoint delays the others by at most
+// webhookRequestTimeout.
+type WebhookDeliveryWorker struct {
+	queue  WebhookDeliveryQueue
+	client *http.Client
+
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	shutOnce sync.Once
+}
+
+// NewWebhookDeliveryWorker constructs a worker sending with client
+// (NewWebhookHTTPClient() when nil). Call Start(ctx) to begin polling and
+// Shutdown(timeout) to stop gracefully.
+func NewWebhookDeliveryWorker(queue WebhookDeliveryQueue, client *http.Client) *WebhookDeliveryWorker {
+	if client == nil {
+		client = NewWebhookHTTPClient()
+	}
+	return &WebhookDeliveryWorker{queue: queue, client: client}
+}
+
+// NewWebhookHTTPClient returns the client deliveries are sent with. Webhook
+// URLs are chosen by organization managers, not operators, so the client
+// refuses to connect to loopback, private and link-local addresses (checked
+// on the resolved address, so DNS cannot point a public name inward) and
+// does not follow redirects. WEBHOOK_ALLOW_PRIVATE_TARGETS=true lifts the
+// address check, for development against a local receiver.
+func NewWebhookHTTPClient() *http.Client {
+	allowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS") == "true"
+	dialer := &net.Dialer{
+		Timeout: webhookRequestTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isBlockedWebhookIP(ip) {
+				return errBlockedWebhookTarget
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: webhookRequestTimeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   webhookRequestTimeout,
+		Transport: observability.NewTracingTransport(transport, "Webhook"),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// carrierGradeNAT is 100.64.0.0/10, shared address space net.IP.IsPrivate
+// does not cover.
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isBlockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() ||
+		carrierGradeNAT.Contains(ip)
+}
+
+// Start spawns the polling goroutine. Returns immediately.
+func (w *WebhookDeliveryWorker) Start(parent context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(webhookWorkerPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.ProcessOnce(ctx); err != nil {
+					slog.Error("webhook delivery worker pass error", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// Shutdown cancels the polling goroutine and waits for it to exit, up to
+// timeout. Idempotent.
+func (w *WebhookDeliveryWorker) Shutdown(timeout time.Duration) {
+	w.shutOnce.Do(func() {
+		if w.cancel != nil {
+			w.cancel()
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("webhook delivery worker shutdown timed out", "timeout", timeout)
+	}
+}
+
+// ProcessOnce sends the current batch of due deliveries synchronously. Used
+// by tests and by Start's polling loop. Returns the first DB-level error
+// encountered; a failed delivery is recorded through MarkFailure and does
+// NOT cause ProcessOnce to return an error.
+func (w *WebhookDeliveryWorker) ProcessOnce(ctx context.Context) error {
+	rows, err := w.queue.ListDue(time.Now(), webhookWorkerBatchSize)
+	if err != nil {
+		return fmt.Errorf("list due webhook deliveries: %w", err)
+	}
+
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.deliver(ctx, row)
+	}
+
+	if pending, err := w.queue.CountPending(); err == nil {
+		observability.Metrics.WebhookQueuePendingDepth.Store(uint64(pending))
+	}
+	return nil
+}
+
+// deliver makes one attempt at row and records its outcome.
+func (w *WebhookDeliveryWorker) deliver(ctx context.Context, row *models.WebhookDelivery) {
+	attempt := models.WebhookDeliveryAttempt{AttemptedAt: time.Now()}
+
+	switch {
+	case row.Subscription == nil:
+		attempt.Error = "subscription was deleted"
+	case !row.Subscription.IsActive:
+		attempt.Error = "subscription is disabled"
+	default:
+		w.send(ctx, row, &attempt)
+	}
+	attempt.DurationMs = time.Since(attempt.AttemptedAt).Milliseconds()
+
+	if attempt.Error == "" && attempt.StatusCode >= 200 && attempt.StatusCode < 300 {
+		if err := w.queue.MarkSuccess(row.ID, attempt); err != nil {
+			slog.Error("mark webhook delivery succeeded failed", "delivery_id", row.ID, "err", err)
+			return
+		}
+		observability.Metrics.WebhookDeliverySuccess.Add(1)
+		return
+	}
+	w.markFailure(row.ID, attempt)
+}
+
+// send POSTs the signed payload and fills attempt with the response.
+func (w *WebhookDeliveryWorker) send(ctx context.Context, row *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) {
+	body := []byte(row.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, row.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("build request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OCF-Webhooks/1.0")
+	req.Header.Set(EventHeader, row.EventType)
+	req.Header.Set(DeliveryHeader, row.ID.String())
+	req.Header.Set(SignatureHeader, SignPayload(row.Subscription.Secret, attempt.AttemptedAt, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	attempt.StatusCode = resp.StatusCode
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLogLimit))
+	attempt.ResponseBody = string(excerpt)
+}
+
+// markFailure records a failed attempt and bumps the observability counters,
+// including WebhookDeliveryDead when the delivery just ran out of attempts.
+func (w *WebhookDeliveryWorker) markFailure(id uuid.UUID, attempt models.WebhookDeliveryAttempt) {
+	if err := w.queue.MarkFailure(id, attempt); err != nil {
+		slog.Error("mark webhook delivery failed failed", "delivery_id", id, "attempt_err", attempt.Error, "mark_err", err)
+		return
+	}
+	observability.Metrics.WebhookDeliveryFailure.Add(1)
+
+	if rows, err := w.queue.ListByID(id); err == nil && len(rows) > 0 && rows[0].State == models.WebhookDeliveryStateDead {
+		observability.Metrics.WebhookDeliveryDead.Add(1)
+		slog.Warn("webhook delivery dead-lettered", "delivery_id", id, "subscription_id", rows[0].SubscriptionID, "last_error", rows[0].LastError)
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"soli/formations/src/webhooks/dto"
+	"soli/formations/src/webhooks/models"
+)
+
+// ErrWebhookNotFound is returned for a subscription that does not exist in
+// the organization.
+var ErrWebhookNotFound = errors.New("webhook subscription not found")
+
+// ErrWebhookDeliveryNotFound is returned for a delivery that does not belong
+// to the subscription.
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// ErrInvalidWebhook wraps the validation failures of a subscription input.
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// webhookDeliveryListLimit caps the delivery log page size.
+const webhookDeliveryListLimit = 100
+
+// WebhookService manages an organization's webhook subscriptions and exposes
+// their delivery logs.
+type WebhookService struct {
+	db    *gorm.DB
+	queue WebhookDeliveryQueue
+}
+
+// NewWebhookService creates a webhook service.
+func NewWebhookService(db *gorm.DB) *WebhookService {
+	return &WebhookService{db: db, queue: NewWebhookDeliveryQueue(db)}
+}
+
+// ListSubscriptions returns the organization's subscriptions, newest first.
+func (s *WebhookService) ListSubscriptions(orgID uuid.UUID) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := s.db.Where("organization_id = ?", orgID).Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// CreateSubscription registers an endpoint with a fresh signing secret,
+// returned in the response and never again.
+func (s *WebhookService) CreateSubscription(orgID uuid.UUID, userID string, input dto.CreateWebhookInput) (*dto.WebhookSubscriptionWithSecret, error) {
+	if err := validateWebhookURL(input.URL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEvents(input.Events); err != nil {
+		return nil, err
+	}
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	subscription := models.WebhookSubscription{
+		OrganizationID: orgID,
+		URL:            input.URL,
+		Description:    input.Description,
+		Events:         input.Events,
+		Secret:         secret,
+		IsActive:       true,
+		CreatedByID:    userID,
+	}
+	if err := s.db.Create(&subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	// BeforeSave encrypted the field in place.
+	subscription.Secret = secret
+	return &dto.WebhookSubscriptionWithSecret{WebhookSubscription: subscription, Secret: secret}, nil
+}
+
+// UpdateSubscription applies the non-nil fields of input. Re-enabling a
+// subscription does not resend what was dead-lettered while it was disabled;
+// use Redeliver for that.
+func (s *WebhookService) UpdateSubscription(orgID, id uuid.UUID, input dto.UpdateWebhookInput) (*models.WebhookSubscription, error) {
+	subscription, err := s.getSubscription(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if input.URL != nil {
+		if err := validateWebhookURL(*input.URL); err != nil {
+			return nil, err
+		}
+		subscription.URL = *input.URL
+	}
+	if input.Events != nil {
+		if err := validateWebhookEvents(*input.Events); err != nil {
+			return nil, err
+		}
+		subscription.Events = *input.Events
+	}
+	if input.Description != nil {
+		subscription.Description = *input.Description
+	}
+	if input.IsActive != nil {
+		subscription.IsActive = *input.IsActive
+	}
+	if err := s.db.Save(subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription removes a subscription and dead-letters its pending
+// deliveries. The delivery log is kept.
+func (s *WebhookService) DeleteSubscription(orgID, id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.WebhookSubscription{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete webhook subscription: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		if err := tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND state = ?", id, models.WebhookDeliveryStatePending).
+			Updates(map[string]any{
+				"state":      models.WebhookDeliveryStateDead,
+				"last_error": "subscription was deleted",
+			}).Error; err != nil {
+			return fmt.Errorf("failed to cancel pending webhook deliveries: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription,
+// optionally filtered by state, without their attempt logs.
+func (s *WebhookService) ListDeliveries(orgID, subscriptionID uuid.UUID, state string) ([]models.WebhookDelivery, error) {
+	if _, err := s.getSubscription(orgID, subscriptionID); err != nil {
+		return nil, err
+	}
+	query := s.db.Where("subscription_id = ?", subscriptionID)
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(webhookDeliveryListLimit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetDelivery returns one delivery with its attempt log, oldest attempt first.
+func (s *WebhookService) GetDelivery(orgID, subscriptionID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := s.getSubscription(orgID, subscriptionID); err != nil {
+		return nil, err
+	}
+	var delivery models.WebhookDelivery
+	err := s.db.Preload("AttemptLog", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt ASC")
+	}).Where("id = ? AND subscription_id = ?", deliveryID, subscriptionID).First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// Redeliver queues the delivery's event again for the subscription, whatever
+// the outcome of the original. The new delivery is sent on the worker's next
+// pass with a fresh signature.
+func (s *WebhookService) Redeliver(orgID, subscriptionID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	subscription, err := s.getSubscription(orgID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.IsActive {
+		return nil, fmt.Errorf("%w: subscription is disabled", ErrInvalidWebhook)
+	}
+	if _, err := s.GetDelivery(orgID, subscriptionID, deliveryID); err != nil {
+		return nil, err
+	}
+	return s.queue.Redeliver(deliveryID)
+}
+
+func (s *WebhookService) getSubscription(orgID, id uuid.UUID) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := s.db.Where("id = ? AND organization_id = ?", id, orgID).First(&subscription).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to load webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// validateWebhookURL accepts absolute https URLs without credentials. A
+// literal private or loopback address is refused here already; host names
+// are checked when the worker connects (see NewWebhookHTTPClient).
+func validateWebhookURL(raw string) error {
+	if len(raw) > 2048 {
+		return fmt.Errorf("%w: url is too long", ErrInvalidWebhook)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: url must be absolute", ErrInvalidWebhook)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: url must not carry credentials", ErrInvalidWebhook)
+	}
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS") != "true" {
+		host := u.Hostname()
+		if strings.EqualFold(host, "localhost") {
+			return fmt.Errorf("%w: url must not point to localhost", ErrInvalidWebhook)
+		}
+		if ip := net.ParseIP(host); ip != nil && isBlockedWebhookIP(ip) {
+			return fmt.Errorf("%w: url must not point to a private address", ErrInvalidWebhook)
+		}
+	}
+	return nil
+}
+
+func validateWebhookEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for _, e := range events {
+		if !IsKnownEvent(e) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, e)
+		}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 
 	config "soli/formations/src/configuration"
 	"soli/formations/src/courses/models"
+	"soli/formations/src/observability"
 )
 
 // WorkerJobStatus représente le statut d'un job dans le worker
@@ -62,7 +63,8 @@ func NewWorkerService(cfg *config.WorkerConfig) WorkerService {
 	return &workerService{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
+			Timeout:   cfg.Timeout,
+			Transport: observability.NewTracingTransport(nil, "OCF Worker"),
 		},
 	}
 }
@@ -120,6 +120,7 @@ func setupDeleteMyAccountDB(t *testing.T) *gorm.DB {
 		&scenarioModels.ScenarioSession{},
 		&scenarioModels.ScenarioStepProgress{},
 		&scenarioModels.ScenarioFlag{},
+		&scenarioModels.ScenarioFlagAttempt{},
 		&scenarioModels.ScenarioAssignment{},
 		&organizationModels.Organization{},
 		&organizationModels.OrganizationMember{},
@@ -219,6 +220,13 @@ func seedScenarioSessionWithProgress(t *testing.T, db *gorm.DB, userID string, s
 		SessionID: sessionID,
 		StepOrder: 1,
 	}).Error)
+	require.NoError(t, db.Create(&scenarioModels.ScenarioFlagAttempt{
+		BaseModel:     entityManagementModels.BaseModel{ID: uuid.New()},
+		SessionID:     sessionID,
+		StepOrder:     1,
+		SubmittedFlag: "FLAG{0000000000000000}",
+		SubmittedAt:   time.Now(),
+	}).Error)
 	return sessionID
 }
 
@@ -460,14 +468,16 @@ func TestDeleteMyAccount_DeletesScenarioSessions_CascadesStepProgressAndFlags(t
 
 	require.NoError(t, svc.DeleteMyAccount(userID))
 
-	var sessionCount, progressCount, flagCount int64
+	var sessionCount, progressCount, flagCount, attemptCount int64
 	db.Model(&scenarioModels.ScenarioSession{}).Where("id = ?", sessionID).Count(&sessionCount)
 	db.Model(&scenarioModels.ScenarioStepProgress{}).Where("session_id = ?", sessionID).Count(&progressCount)
 	db.Model(&scenarioModels.ScenarioFlag{}).Where("session_id = ?", sessionID).Count(&flagCount)
+	db.Model(&scenarioModels.ScenarioFlagAttempt{}).Where("session_id = ?", sessionID).Count(&attemptCount)
 
 	assert.Equal(t, int64(0), sessionCount, "scenario session must be deleted")
 	assert.Equal(t, int64(0), progressCount, "step progress must cascade-delete with the session")
 	assert.Equal(t, int64(0), flagCount, "flags must cascade-delete with the session")
+	assert.Equal(t, int64(0), attemptCount, "wrong flag submissions must cascade-delete with the session")
 }
 
 // Test 6: audit logs are anonymized — actor_id, actor_email AND actor_ip must
@@ -846,6 +856,7 @@ func TestDeleteMyAccount_CascadeFailsAfterCasdoor_RollsBack(t *testing.T) {
 		&scenarioModels.ScenarioSession{},
 		&scenarioModels.ScenarioStepProgress{},
 		&scenarioModels.ScenarioFlag{},
+		&scenarioModels.ScenarioFlagAttempt{},
 		&scenarioModels.ScenarioAssignment{},
 		&organizationModels.Organization{},
 		&organizationModels.OrganizationMember{},
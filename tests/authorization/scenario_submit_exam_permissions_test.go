@@ -0,0 +1,98 @@
+package authorization_tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authController "soli/formations/src/auth"
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/casdoor"
+	scenarioModels "soli/formations/src/scenarios/models"
+	scenarioController "soli/formations/src/scenarios/routes"
+)
+
+// setupSubmitExamRouter mounts POST /scenario-sessions/:id/submit-exam behind
+// both layers as production declares them: the Casbin policies come from
+// RegisterScenarioPermissions on a real enforcer, and ownership is checked by
+// the real GormEntityLoader. The handler only answers 200, so any 403 comes
+// from the permission setup.
+func setupSubmitExamRouter(t *testing.T, userID string) (*gin.Engine, uuid.UUID) {
+	t.Helper()
+
+	db := setupCasbinEnforcer(t)
+	require.NoError(t, db.AutoMigrate(&scenarioModels.ScenarioSession{}))
+
+	access.RouteRegistry.Reset()
+	access.ResetEnforcers()
+	t.Cleanup(func() {
+		access.RouteRegistry.Reset()
+		access.ResetEnforcers()
+	})
+	scenarioController.RegisterScenarioPermissions(casdoor.Enforcer)
+	access.RegisterBuiltinEnforcers(access.NewGormEntityLoader(db), access.NewGormMembershipChecker(db))
+
+	session := scenarioModels.ScenarioSession{
+		ScenarioID: uuid.New(),
+		UserID:     "learner-submit-exam",
+		Status:     "active",
+		StartedAt:  time.Now(),
+	}
+	require.NoError(t, db.Create(&session).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	// Same role check as AuthManagement, minus the token parsing.
+	api.Use(func(ctx *gin.Context) {
+		roles := []string{access.RoleMember}
+		authorized := false
+		for _, role := range roles {
+			ok, err := authController.NewPermissionService().HasPermission(role, ctx.Request.URL.Path, ctx.Request.Method)
+			require.NoError(t, err)
+			authorized = authorized || ok
+		}
+		if !authorized {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "You are not authorized"})
+			return
+		}
+		ctx.Set("userId", userID)
+		ctx.Set("userRoles", roles)
+		ctx.Next()
+	})
+	api.Use(access.Layer2Enforcement())
+	api.POST("/scenario-sessions/:id/submit-exam", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return r, session.ID
+}
+
+// TestSubmitExam_LearnerPassesPermissionSetup guards the route every exam
+// taker hands in through: without its RoutePermission, Layer 1 has no policy
+// for it and AuthManagement answers 403 to every learner.
+func TestSubmitExam_LearnerPassesPermissionSetup(t *testing.T) {
+	router, sessionID := setupSubmitExamRouter(t, "learner-submit-exam")
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scenario-sessions/"+sessionID.String()+"/submit-exam", nil)
+	router.ServeHTTP(w, req)
+
+	assert.NotEqual(t, http.StatusForbidden, w.Code, "a learner must be able to hand in their own exam: %s", w.Body.String())
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSubmitExam_OtherLearnerDenied(t *testing.T) {
+	router, sessionID := setupSubmitExamRouter(t, "learner-someone-else")
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scenario-sessions/"+sessionID.String()+"/submit-exam", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, "only the session's owner may hand it in")
+}
@@ -0,0 +1,130 @@
+package certificates_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/mocks"
+	"soli/formations/src/certificates/dto"
+	certificateController "soli/formations/src/certificates/routes"
+	"soli/formations/src/certificates/services"
+	orgModels "soli/formations/src/organizations/models"
+)
+
+// The certificate endpoints behind Layer 2 enforcement: the recipient and
+// the organization's managers see a certificate, anyone can verify it.
+
+func setupCertificateRouter(t *testing.T, db *gorm.DB, userID string) *gin.Engine {
+	t.Helper()
+	access.RouteRegistry.Reset()
+	access.ResetEnforcers()
+	t.Cleanup(func() {
+		access.RouteRegistry.Reset()
+		access.ResetEnforcers()
+	})
+
+	certificateController.RegisterPermissions(mocks.NewMockEnforcer())
+	access.RegisterBuiltinEnforcers(nil, access.NewGormMembershipChecker(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set("userId", userID)
+			c.Set("userRoles", []string{"member"})
+		}
+		c.Next()
+	})
+	api.Use(access.Layer2Enforcement())
+
+	controller := certificateController.NewCertificateController(services.NewCertificateService(db, testIssuer))
+	api.GET("/certificates/:id", controller.GetCertificate)
+	api.GET("/certificates/:id/pdf", controller.DownloadCertificatePDF)
+	api.GET("/certificates/:id/verify", controller.VerifyCertificate)
+	api.GET("/certificates/:id/credential", controller.GetCredential)
+	api.POST("/certificates/:id/revoke", controller.RevokeCertificate)
+	api.GET("/organizations/:id/certificates", controller.ListOrganizationCertificates)
+	return r
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCertificateEndpoints_Access(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "classmate", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "manager", orgModels.OrgRoleManager)
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	session := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(70))
+	cert, err := services.NewCertificateService(db, testIssuer).IssueForSession(session.ID)
+	require.NoError(t, err)
+	certPath := "/api/v1/certificates/" + cert.ID.String()
+
+	t.Run("recipient downloads the PDF", func(t *testing.T) {
+		w := serve(setupCertificateRouter(t, db, "learner"), http.MethodGet, certPath+"/pdf", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+	})
+
+	t.Run("another learner cannot see it", func(t *testing.T) {
+		w := serve(setupCertificateRouter(t, db, "classmate"), http.MethodGet, certPath, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("anyone verifies it", func(t *testing.T) {
+		w := serve(setupCertificateRouter(t, db, ""), http.MethodGet, certPath+"/verify", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var verification dto.CertificateVerification
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &verification))
+		assert.Equal(t, services.VerificationValid, verification.Status)
+
+		w = serve(setupCertificateRouter(t, db, ""), http.MethodGet, certPath+"/credential", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, cert.Credential, w.Body.String())
+	})
+
+	t.Run("only managers list the organization's certificates", func(t *testing.T) {
+		path := "/api/v1/organizations/" + orgID.String() + "/certificates"
+		w := serve(setupCertificateRouter(t, db, "learner"), http.MethodGet, path, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(setupCertificateRouter(t, db, "manager"), http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), cert.ID.String())
+	})
+
+	t.Run("revocation requires a reason and a manager", func(t *testing.T) {
+		w := serve(setupCertificateRouter(t, db, "manager"), http.MethodPost, certPath+"/revoke", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = serve(setupCertificateRouter(t, db, "learner"), http.MethodPost, certPath+"/revoke", `{"reason":"mine"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(setupCertificateRouter(t, db, "manager"), http.MethodPost, certPath+"/revoke", `{"reason":"Plagiarism"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = serve(setupCertificateRouter(t, db, ""), http.MethodGet, certPath+"/verify", "")
+		assert.Contains(t, w.Body.String(), `"status":"revoked"`)
+	})
+}
@@ -0,0 +1,346 @@
+package certificates_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/certificates/models"
+	"soli/formations/src/certificates/services"
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+// Certificates of completion: issued and signed when a session completes (or
+// a class group's assignments all are), verifiable publicly, and revocable by
+// the organization that issued them.
+
+var testIssuer = services.Issuer{BaseURL: "https://ocf.example.org", Name: "OCF"}
+
+func stubRecipients(t *testing.T) {
+	t.Helper()
+	original := services.LookupCasdoorUser
+	services.LookupCasdoorUser = func(userID string) (*casdoorsdk.User, error) {
+		if userID == "unknown" {
+			return nil, errors.New("user not found")
+		}
+		return &casdoorsdk.User{Name: userID, DisplayName: "Ada " + userID, Email: userID + "@example.org"}, nil
+	}
+	t.Cleanup(func() { services.LookupCasdoorUser = original })
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func createOrg(t *testing.T, db *gorm.DB, name string) uuid.UUID {
+	t.Helper()
+	org := &orgModels.Organization{Name: name, DisplayName: "Acme Training", OwnerUserID: "owner-" + name, IsActive: true}
+	require.NoError(t, db.Omit("Metadata").Create(org).Error)
+	return org.ID
+}
+
+func addOrgMember(t *testing.T, db *gorm.DB, orgID uuid.UUID, userID string, role orgModels.OrganizationMemberRole) {
+	t.Helper()
+	require.NoError(t, db.Omit("Metadata").Create(&orgModels.OrganizationMember{
+		OrganizationID: orgID, UserID: userID, Role: role, JoinedAt: time.Now(), IsActive: true,
+	}).Error)
+}
+
+func createScenario(t *testing.T, db *gorm.DB, title string, orgID *uuid.UUID) scenarioModels.Scenario {
+	t.Helper()
+	scenario := scenarioModels.Scenario{
+		Name: "scen-" + uuid.NewString()[:8], Title: title, InstanceType: "ubuntu:22.04",
+		CreatedByID: "author", OrganizationID: orgID,
+	}
+	require.NoError(t, db.Create(&scenario).Error)
+	return scenario
+}
+
+func createSession(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, userID, status string, grade *float64) scenarioModels.ScenarioSession {
+	t.Helper()
+	now := time.Now()
+	session := scenarioModels.ScenarioSession{
+		ScenarioID: scenarioID, UserID: userID, Status: status, Grade: grade,
+		StartedAt: now.Add(-time.Hour),
+	}
+	if status == "completed" {
+		session.CompletedAt = &now
+	}
+	require.NoError(t, db.Create(&session).Error)
+	return session
+}
+
+func createGroupWithMember(t *testing.T, db *gorm.DB, orgID *uuid.UUID, userID string) groupModels.ClassGroup {
+	t.Helper()
+	group := groupModels.ClassGroup{
+		Name: "group-" + uuid.NewString()[:8], DisplayName: "DevOps Bootcamp",
+		OwnerUserID: "trainer", OrganizationID: orgID, IsActive: true,
+	}
+	require.NoError(t, db.Omit("Metadata").Create(&group).Error)
+	require.NoError(t, db.Omit("Metadata").Create(&groupModels.GroupMember{
+		GroupID: group.ID, UserID: userID, Role: "member", JoinedAt: time.Now(), IsActive: true,
+	}).Error)
+	return group
+}
+
+func assignToGroup(t *testing.T, db *gorm.DB, scenarioID, groupID uuid.UUID) {
+	t.Helper()
+	require.NoError(t, db.Create(&scenarioModels.ScenarioAssignment{
+		ScenarioID: scenarioID, GroupID: &groupID, Scope: "group", CreatedByID: "trainer", IsActive: true,
+	}).Error)
+}
+
+func TestIssueForSession_IssuesSignedOpenBadgeCredential(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	session := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(87.5))
+
+	svc := services.NewCertificateService(db, testIssuer)
+	cert, err := svc.IssueForSession(session.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.CertificateKindScenario, cert.Kind)
+	assert.Equal(t, "learner", cert.UserID)
+	assert.Equal(t, "Ada learner", cert.RecipientName)
+	assert.Equal(t, "Linux Basics", cert.Title)
+	assert.Equal(t, "Acme Training", cert.OrganizationName)
+	require.NotNil(t, cert.OrganizationID)
+	assert.Equal(t, orgID, *cert.OrganizationID)
+	require.NotNil(t, cert.Grade)
+	assert.Equal(t, 87.5, *cert.Grade)
+
+	var credential map[string]any
+	require.NoError(t, json.Unmarshal([]byte(cert.Credential), &credential))
+	assert.Equal(t, testIssuer.CredentialURL(cert.ID.String()), credential["id"])
+	assert.Equal(t, []any{"VerifiableCredential", "OpenBadgeCredential"}, credential["type"])
+	proof, ok := credential["proof"].(map[string]any)
+	require.True(t, ok, "credential must carry a proof")
+	assert.Equal(t, "DataIntegrityProof", proof["type"])
+	assert.Equal(t, "eddsa-jcs-2022", proof["cryptosuite"])
+	subject := credential["credentialSubject"].(map[string]any)
+	result := subject["result"].([]any)[0].(map[string]any)
+	assert.Equal(t, "87.5", result["value"])
+	assert.NotContains(t, cert.Credential, "learner@example.org", "the email is only published hashed")
+
+	verification, err := svc.Verify(cert.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.VerificationValid, verification.Status)
+	assert.True(t, verification.SignatureValid)
+	assert.Equal(t, "Ada learner", verification.RecipientName)
+
+	// The issuer profile publishes the key the proof points to.
+	profile, err := svc.IssuerProfile()
+	require.NoError(t, err)
+	methods := profile["verificationMethod"].([]any)
+	require.Len(t, methods, 1)
+	assert.Equal(t, proof["verificationMethod"], methods[0].(map[string]any)["id"])
+}
+
+func TestIssueForSession_IsIdempotent(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	scenario := createScenario(t, db, "Linux Basics", nil)
+	session := createSession(t, db, scenario.ID, "learner", "completed", nil)
+
+	svc := services.NewCertificateService(db, testIssuer)
+	first, err := svc.IssueForSession(session.ID)
+	require.NoError(t, err)
+	second, err := svc.IssueForSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Nil(t, first.OrganizationID, "a learner training on their own gets an unbranded certificate")
+
+	var count int64
+	db.Model(&models.Certificate{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestIssueForSession_RequiresACompletedNonPreviewSession(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	scenario := createScenario(t, db, "Linux Basics", nil)
+	active := createSession(t, db, scenario.ID, "learner", "active", nil)
+	preview := createSession(t, db, scenario.ID, "author", "completed", nil)
+	require.NoError(t, db.Model(&preview).Update("is_preview", true).Error)
+
+	svc := services.NewCertificateService(db, testIssuer)
+	_, err := svc.IssueForSession(active.ID)
+	assert.ErrorIs(t, err, services.ErrNotEligible)
+	_, err = svc.IssueForSession(preview.ID)
+	assert.ErrorIs(t, err, services.ErrNotEligible)
+	_, err = svc.IssueForSession(uuid.New())
+	assert.ErrorIs(t, err, services.ErrNotEligible)
+}
+
+func TestClaimSessionCertificate_OnlyForOwnSession(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	scenario := createScenario(t, db, "Linux Basics", nil)
+	session := createSession(t, db, scenario.ID, "learner", "completed", nil)
+
+	svc := services.NewCertificateService(db, testIssuer)
+	_, err := svc.ClaimSessionCertificate(session.ID, "someone-else")
+	assert.ErrorIs(t, err, services.ErrNotEligible)
+	cert, err := svc.ClaimSessionCertificate(session.ID, "learner")
+	require.NoError(t, err)
+	assert.Equal(t, "learner", cert.UserID)
+}
+
+func TestVerify_DetectsTamperedCredential(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	scenario := createScenario(t, db, "Linux Basics", nil)
+	session := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(40))
+
+	svc := services.NewCertificateService(db, testIssuer)
+	cert, err := svc.IssueForSession(session.ID)
+	require.NoError(t, err)
+
+	tampered := bytes.Replace([]byte(cert.Credential), []byte(`"value":"40"`), []byte(`"value":"100"`), 1)
+	require.NotEqual(t, cert.Credential, string(tampered))
+	require.NoError(t, db.Model(&models.Certificate{}).Where("id = ?", cert.ID).Update("credential", string(tampered)).Error)
+
+	verification, err := svc.Verify(cert.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.SignatureValid)
+	assert.Equal(t, services.VerificationInvalid, verification.Status)
+}
+
+func TestSigningKey_StoredEncrypted(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	scenario := createScenario(t, db, "Linux Basics", nil)
+	session := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(90))
+
+	cert, err := services.NewCertificateService(db, testIssuer).IssueForSession(session.ID)
+	require.NoError(t, err)
+
+	var raw string
+	require.NoError(t, db.Raw("SELECT private_key_seed FROM certificate_signing_keys").Scan(&raw).Error)
+	assert.True(t, strings.HasPrefix(raw, "enc::v1:"), "the signing seed must not be stored in clear")
+
+	var stored models.CertificateSigningKey
+	require.NoError(t, db.First(&stored).Error)
+	assert.NotEqual(t, stored.PrivateKeySeed, raw)
+
+	// A restarted service decrypts the key and still verifies what it signed.
+	verification, err := services.NewCertificateService(db, testIssuer).Verify(cert.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.SignatureValid)
+}
+
+func TestIssueForGroup_RequiresEveryAssignmentCompleted(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	orgID := createOrg(t, db, "acme")
+	group := createGroupWithMember(t, db, &orgID, "learner")
+	first := createScenario(t, db, "Linux Basics", &orgID)
+	second := createScenario(t, db, "Docker Basics", &orgID)
+	assignToGroup(t, db, first.ID, group.ID)
+	assignToGroup(t, db, second.ID, group.ID)
+
+	svc := services.NewCertificateService(db, testIssuer)
+	createSession(t, db, first.ID, "learner", "completed", floatPtr(60))
+	createSession(t, db, first.ID, "learner", "completed", floatPtr(80))
+	_, err := svc.IssueForGroup(group.ID, "learner")
+	assert.ErrorIs(t, err, services.ErrNotEligible, "one assignment is left")
+
+	createSession(t, db, second.ID, "learner", "completed", floatPtr(90))
+	cert, err := svc.IssueForGroup(group.ID, "learner")
+	require.NoError(t, err)
+	assert.Equal(t, models.CertificateKindGroup, cert.Kind)
+	assert.Equal(t, "DevOps Bootcamp", cert.Title)
+	require.NotNil(t, cert.Grade)
+	assert.Equal(t, 85.0, *cert.Grade, "average of the best grade on each scenario")
+
+	_, err = svc.IssueForGroup(group.ID, "outsider")
+	assert.ErrorIs(t, err, services.ErrNotEligible, "only members of the group")
+}
+
+func TestIssueForCompletedSession_IssuesSessionAndGroupCertificates(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	orgID := createOrg(t, db, "acme")
+	group := createGroupWithMember(t, db, &orgID, "learner")
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	assignToGroup(t, db, scenario.ID, group.ID)
+	session := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(75))
+
+	services.IssueForCompletedSession(db, session.ID)
+
+	var certs []models.Certificate
+	require.NoError(t, db.Order("kind").Find(&certs).Error)
+	require.Len(t, certs, 2)
+	assert.Equal(t, models.CertificateKindGroup, certs[0].Kind)
+	assert.Equal(t, models.CertificateKindScenario, certs[1].Kind)
+	for _, cert := range certs {
+		require.NotNil(t, cert.OrganizationID, "the assigning organization brands the certificate")
+		assert.Equal(t, orgID, *cert.OrganizationID)
+	}
+}
+
+func TestRevoke_OnlyManagersAndListedAsRevoked(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "manager", orgModels.OrgRoleManager)
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	session := createSession(t, db, scenario.ID, "learner", "completed", nil)
+
+	svc := services.NewCertificateService(db, testIssuer)
+	cert, err := svc.IssueForSession(session.ID)
+	require.NoError(t, err)
+
+	_, err = svc.Revoke(cert.ID, "learner", false, "self-revoke")
+	assert.ErrorIs(t, err, services.ErrCertificateAccessDenied, "the recipient cannot revoke")
+
+	revoked, err := svc.Revoke(cert.ID, "manager", false, "Academic misconduct")
+	require.NoError(t, err)
+	assert.True(t, revoked.IsRevoked())
+
+	_, err = svc.Revoke(cert.ID, "manager", false, "again")
+	assert.ErrorIs(t, err, services.ErrCertificateAlreadyRevoked)
+
+	verification, err := svc.Verify(cert.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.VerificationRevoked, verification.Status)
+	assert.True(t, verification.SignatureValid)
+	assert.Equal(t, "Academic misconduct", verification.RevocationReason)
+
+	list, err := svc.RevocationList()
+	require.NoError(t, err)
+	require.Len(t, list.RevokedCredentials, 1)
+	assert.Equal(t, testIssuer.CredentialURL(cert.ID.String()), list.RevokedCredentials[0].ID)
+	assert.Equal(t, "Academic misconduct", list.RevokedCredentials[0].RevocationReason)
+}
+
+func TestRenderPDF_ProducesADocument(t *testing.T) {
+	db := freshTestDB(t)
+	stubRecipients(t)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	scenario := createScenario(t, db, "Réseaux & Linux", &orgID)
+	session := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(92))
+
+	svc := services.NewCertificateService(db, testIssuer)
+	cert, err := svc.IssueForSession(session.ID)
+	require.NoError(t, err)
+
+	pdf, err := svc.RenderPDF(cert)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")), "output must be a PDF")
+	assert.Greater(t, len(pdf), 1000)
+}
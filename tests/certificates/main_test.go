@@ -0,0 +1,67 @@
+package certificates_test
+
+import (
+	"os"
+	"testing"
+
+	"soli/formations/src/certificates/models"
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+	webhookModels "soli/formations/src/webhooks/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var sharedTestDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to open shared test DB: " + err.Error())
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(
+		&models.Certificate{},
+		&models.CertificateSigningKey{},
+		&scenarioModels.Scenario{},
+		&scenarioModels.ScenarioSession{},
+		&scenarioModels.ScenarioAssignment{},
+		&scenarioModels.ScenarioAssignmentExtension{},
+		&groupModels.ClassGroup{},
+		&groupModels.GroupMember{},
+		&orgModels.Organization{},
+		&orgModels.OrganizationMember{},
+		&webhookModels.WebhookSubscription{},
+		&webhookModels.WebhookDelivery{},
+		&webhookModels.WebhookDeliveryAttempt{},
+	)
+	if err != nil {
+		panic("failed to migrate shared test DB: " + err.Error())
+	}
+
+	sharedTestDB = db
+	// Signing keys are stored encrypted.
+	os.Setenv("FIELD_ENCRYPTION_SECRET", "certificates-test-secret")
+	os.Exit(m.Run())
+}
+
+func freshTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	for _, table := range []string{
+		"certificates", "certificate_signing_keys",
+		"scenario_sessions", "scenario_assignment_extensions", "scenario_assignments", "scenarios",
+		"group_members", "class_groups", "organization_members", "organizations",
+		"webhook_delivery_attempts", "webhook_deliveries", "webhook_subscriptions",
+	} {
+		sharedTestDB.Exec("DELETE FROM " + table)
+	}
+	return sharedTestDB
+}
@@ -23,7 +23,7 @@ func TestInitDefaultTemplates_CreatesExpectedTemplates(t *testing.T) {
 	err := db.Find(&templates).Error
 	require.NoError(t, err)
 
-	assert.Len(t, templates, 3)
+	assert.Len(t, templates, 4)
 
 	// Collect names
 	names := make(map[string]bool)
@@ -34,6 +34,7 @@ func TestInitDefaultTemplates_CreatesExpectedTemplates(t *testing.T) {
 	assert.True(t, names["password_reset"], "password_reset template should exist")
 	assert.True(t, names["welcome"], "welcome template should exist")
 	assert.True(t, names["email_verification"], "email_verification template should exist")
+	assert.True(t, names["assignment_deadline_reminder"], "assignment_deadline_reminder template should exist")
 }
 
 func TestInitDefaultTemplates_Idempotent(t *testing.T) {
@@ -51,8 +52,8 @@ func TestInitDefaultTemplates_Idempotent(t *testing.T) {
 	err := db.Model(&emailModels.EmailTemplate{}).Count(&count).Error
 	require.NoError(t, err)
 
-	// Should still have exactly 3 templates, not 6
-	assert.Equal(t, int64(3), count)
+	// Should still have exactly 4 templates, not 8
+	assert.Equal(t, int64(4), count)
 }
 
 func TestInitDefaultTemplates_RequiredFields(t *testing.T) {
@@ -0,0 +1,133 @@
+package learningPaths_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/mocks"
+	"soli/formations/src/learningPaths/dto"
+	"soli/formations/src/learningPaths/models"
+	learningPathController "soli/formations/src/learningPaths/routes"
+	"soli/formations/src/learningPaths/services"
+	orgModels "soli/formations/src/organizations/models"
+)
+
+// The learning path endpoints behind Layer 2 enforcement: managers build
+// paths, learners follow their own progress.
+
+func setupLearningPathRouter(t *testing.T, db *gorm.DB, userID string) *gin.Engine {
+	t.Helper()
+	access.RouteRegistry.Reset()
+	access.ResetEnforcers()
+	t.Cleanup(func() {
+		access.RouteRegistry.Reset()
+		access.ResetEnforcers()
+	})
+
+	learningPathController.RegisterPermissions(mocks.NewMockEnforcer())
+	access.RegisterBuiltinEnforcers(nil, access.NewGormMembershipChecker(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("userId", userID)
+		c.Set("userRoles", []string{"member"})
+		c.Next()
+	})
+	api.Use(access.Layer2Enforcement())
+
+	controller := learningPathController.NewLearningPathController(services.NewLearningPathService(db))
+	api.GET("/learning-paths", controller.ListMyPaths)
+	api.GET("/learning-paths/:id", controller.GetPath)
+	api.GET("/learning-paths/:id/progress", controller.GetMyProgress)
+	api.GET("/learning-paths/:id/learners/:userId/progress", controller.GetLearnerProgress)
+	api.POST("/learning-paths/:id/items/:itemId/complete", controller.CompleteChapter)
+	api.GET("/learner/dashboard", controller.GetMyDashboard)
+	api.GET("/learner/deadlines", controller.ListMyDeadlines)
+	api.POST("/organizations/:id/learning-paths", controller.CreatePath)
+	return r
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLearningPathEndpoints_Access(t *testing.T) {
+	db := freshTestDB(t)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "manager", orgModels.OrgRoleManager)
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	chapter := createChapter(t, db, "Permissions")
+	body := `{"title":"Linux admin","items":[` +
+		`{"kind":"scenario","scenario_id":"` + scenario.ID.String() + `","min_grade":70},` +
+		`{"kind":"chapter","chapter_id":"` + chapter.ID.String() + `"}]}`
+	createPath := "/api/v1/organizations/" + orgID.String() + "/learning-paths"
+
+	w := serve(setupLearningPathRouter(t, db, "learner"), http.MethodPost, createPath, body)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(setupLearningPathRouter(t, db, "manager"), http.MethodPost, createPath, `{"title":"Empty","items":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(setupLearningPathRouter(t, db, "manager"), http.MethodPost, createPath, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var path models.LearningPath
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &path))
+	require.Len(t, path.Items, 2)
+	pathURL := "/api/v1/learning-paths/" + path.ID.String()
+
+	t.Run("learner follows their progress", func(t *testing.T) {
+		w := serve(setupLearningPathRouter(t, db, "learner"), http.MethodGet, pathURL+"/progress", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var progress dto.LearningPathProgress
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+		assert.Equal(t, dto.ItemStatusLocked, progress.Items[1].Status)
+
+		w = serve(setupLearningPathRouter(t, db, "learner"), http.MethodPost, pathURL+"/items/"+path.Items[1].ID.String()+"/complete", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = serve(setupLearningPathRouter(t, db, "learner"), http.MethodGet, "/api/v1/learner/dashboard", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), path.ID.String())
+	})
+
+	t.Run("only managers see another learner's progress", func(t *testing.T) {
+		w := serve(setupLearningPathRouter(t, db, "learner"), http.MethodGet, pathURL+"/learners/manager/progress", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(setupLearningPathRouter(t, db, "manager"), http.MethodGet, pathURL+"/learners/learner/progress", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"user_id":"learner"`)
+	})
+
+	t.Run("outsiders cannot see the path", func(t *testing.T) {
+		w := serve(setupLearningPathRouter(t, db, "stranger"), http.MethodGet, pathURL, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("deadline window is bounded", func(t *testing.T) {
+		w := serve(setupLearningPathRouter(t, db, "learner"), http.MethodGet, "/api/v1/learner/deadlines?days=1000", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = serve(setupLearningPathRouter(t, db, "learner"), http.MethodGet, "/api/v1/learner/deadlines?days=7", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+}
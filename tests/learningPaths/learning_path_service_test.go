@@ -0,0 +1,380 @@
+package learningPaths_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	courseModels "soli/formations/src/courses/models"
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/learningPaths/dto"
+	"soli/formations/src/learningPaths/models"
+	"soli/formations/src/learningPaths/services"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+// Learning paths: ordered scenarios and chapters unlocking one after the
+// other, the learner's progress on them, and their upcoming deadlines.
+
+func floatPtr(v float64) *float64 { return &v }
+
+func createOrg(t *testing.T, db *gorm.DB, name string) uuid.UUID {
+	t.Helper()
+	org := &orgModels.Organization{Name: name, DisplayName: "Acme Training", OwnerUserID: "owner-" + name, IsActive: true}
+	require.NoError(t, db.Omit("Metadata").Create(org).Error)
+	return org.ID
+}
+
+func addOrgMember(t *testing.T, db *gorm.DB, orgID uuid.UUID, userID string, role orgModels.OrganizationMemberRole) {
+	t.Helper()
+	require.NoError(t, db.Omit("Metadata").Create(&orgModels.OrganizationMember{
+		OrganizationID: orgID, UserID: userID, Role: role, JoinedAt: time.Now(), IsActive: true,
+	}).Error)
+}
+
+func createGroupWithMember(t *testing.T, db *gorm.DB, orgID uuid.UUID, userID string) groupModels.ClassGroup {
+	t.Helper()
+	group := groupModels.ClassGroup{
+		Name: "group-" + uuid.NewString()[:8], DisplayName: "DevOps Bootcamp",
+		OwnerUserID: "trainer", OrganizationID: &orgID, IsActive: true,
+	}
+	require.NoError(t, db.Omit("Metadata").Create(&group).Error)
+	require.NoError(t, db.Omit("Metadata").Create(&groupModels.GroupMember{
+		GroupID: group.ID, UserID: userID, Role: "member", JoinedAt: time.Now(), IsActive: true,
+	}).Error)
+	return group
+}
+
+func createScenario(t *testing.T, db *gorm.DB, title string, orgID *uuid.UUID) scenarioModels.Scenario {
+	t.Helper()
+	scenario := scenarioModels.Scenario{
+		Name: "scen-" + uuid.NewString()[:8], Title: title, InstanceType: "ubuntu:22.04",
+		CreatedByID: "author", OrganizationID: orgID,
+	}
+	require.NoError(t, db.Create(&scenario).Error)
+	return scenario
+}
+
+func createChapter(t *testing.T, db *gorm.DB, title string) courseModels.Chapter {
+	t.Helper()
+	chapter := courseModels.Chapter{Title: title}
+	require.NoError(t, db.Create(&chapter).Error)
+	return chapter
+}
+
+func createSession(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, userID, status string, grade *float64) scenarioModels.ScenarioSession {
+	t.Helper()
+	now := time.Now()
+	session := scenarioModels.ScenarioSession{
+		ScenarioID: scenarioID, UserID: userID, Status: status, Grade: grade,
+		StartedAt: now.Add(-time.Hour),
+	}
+	if status == "completed" {
+		session.CompletedAt = &now
+	}
+	require.NoError(t, db.Create(&session).Error)
+	return session
+}
+
+func scenarioItem(id uuid.UUID, minGrade *float64) dto.LearningPathItemInput {
+	return dto.LearningPathItemInput{Kind: models.ItemKindScenario, ScenarioID: &id, MinGrade: minGrade}
+}
+
+func chapterItem(id uuid.UUID) dto.LearningPathItemInput {
+	return dto.LearningPathItemInput{Kind: models.ItemKindChapter, ChapterID: &id}
+}
+
+func statuses(progress *dto.LearningPathProgress) []string {
+	var out []string
+	for _, item := range progress.Items {
+		out = append(out, item.Status)
+	}
+	return out
+}
+
+func TestProgress_UnlocksItemsInOrder(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	basics := createScenario(t, db, "Linux Basics", &orgID)
+	networking := createScenario(t, db, "Networking", &orgID)
+	chapter := createChapter(t, db, "Permissions")
+
+	path, err := svc.Create(orgID, "manager", dto.LearningPathInput{
+		Title: "Linux admin",
+		Items: []dto.LearningPathItemInput{
+			scenarioItem(basics.ID, floatPtr(70)),
+			chapterItem(chapter.ID),
+			scenarioItem(networking.ID, nil),
+		},
+	})
+	require.NoError(t, err)
+	chapterItemID := path.Items[1].ID
+
+	progress, err := svc.Progress(path.ID, "learner", "learner", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{dto.ItemStatusAvailable, dto.ItemStatusLocked, dto.ItemStatusLocked}, statuses(progress))
+	assert.Equal(t, "Linux Basics", progress.Items[0].Title)
+	assert.Equal(t, "Permissions", progress.Items[1].Title)
+	require.NotNil(t, progress.NextItemID)
+	assert.Equal(t, path.Items[0].ID, *progress.NextItemID)
+
+	// A completed session below the grade does not unlock the next item.
+	createSession(t, db, basics.ID, "learner", "completed", floatPtr(60))
+	progress, err = svc.Progress(path.ID, "learner", "learner", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{dto.ItemStatusInProgress, dto.ItemStatusLocked, dto.ItemStatusLocked}, statuses(progress))
+	assert.InDelta(t, 60, *progress.Items[0].BestGrade, 0.001)
+
+	_, err = svc.CompleteChapter(path.ID, chapterItemID, "learner")
+	assert.ErrorIs(t, err, services.ErrItemLocked)
+
+	createSession(t, db, basics.ID, "learner", "completed", floatPtr(85))
+	progress, err = svc.Progress(path.ID, "learner", "learner", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{dto.ItemStatusCompleted, dto.ItemStatusAvailable, dto.ItemStatusLocked}, statuses(progress))
+	assert.Equal(t, 2, progress.Items[0].Attempts)
+	assert.InDelta(t, 85, *progress.Items[0].BestGrade, 0.001)
+	assert.InDelta(t, 2*3600, progress.Items[0].TimeSpentSeconds, 5)
+
+	progress, err = svc.CompleteChapter(path.ID, chapterItemID, "learner")
+	require.NoError(t, err)
+	assert.Equal(t, []string{dto.ItemStatusCompleted, dto.ItemStatusCompleted, dto.ItemStatusAvailable}, statuses(progress))
+
+	running := createSession(t, db, networking.ID, "learner", "active", nil)
+	progress, err = svc.Progress(path.ID, "learner", "learner", false)
+	require.NoError(t, err)
+	assert.Equal(t, dto.ItemStatusInProgress, progress.Items[2].Status)
+	require.NotNil(t, progress.Items[2].ActiveSessionID)
+	assert.Equal(t, running.ID, *progress.Items[2].ActiveSessionID)
+	assert.Equal(t, 2, progress.CompletedItems)
+	assert.InDelta(t, 66.67, progress.Percent, 0.01)
+}
+
+func TestProgress_PreviewSessionsDoNotCount(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	path, err := svc.Create(orgID, "manager", dto.LearningPathInput{
+		Title: "Linux", Items: []dto.LearningPathItemInput{scenarioItem(scenario.ID, nil)},
+	})
+	require.NoError(t, err)
+
+	preview := createSession(t, db, scenario.ID, "learner", "completed", floatPtr(100))
+	require.NoError(t, db.Model(&preview).Update("is_preview", true).Error)
+
+	progress, err := svc.Progress(path.ID, "learner", "learner", false)
+	require.NoError(t, err)
+	assert.Equal(t, dto.ItemStatusAvailable, progress.Items[0].Status)
+	assert.Zero(t, progress.Items[0].Attempts)
+}
+
+func TestCompleteChapter_OnlyChapterItems(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	path, err := svc.Create(orgID, "manager", dto.LearningPathInput{
+		Title: "Linux", Items: []dto.LearningPathItemInput{scenarioItem(scenario.ID, nil)},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.CompleteChapter(path.ID, path.Items[0].ID, "learner")
+	assert.ErrorIs(t, err, services.ErrItemNotChapter)
+	_, err = svc.CompleteChapter(path.ID, uuid.New(), "learner")
+	assert.ErrorIs(t, err, services.ErrItemNotFound)
+	_, err = svc.CompleteChapter(path.ID, path.Items[0].ID, "stranger")
+	assert.ErrorIs(t, err, services.ErrPathAccessDenied)
+}
+
+func TestPaths_FollowedByTheirGroupOrOrganization(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "classmate", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "manager", orgModels.OrgRoleManager)
+	group := createGroupWithMember(t, db, orgID, "learner")
+	scenario := createScenario(t, db, "Linux Basics", &orgID)
+	items := []dto.LearningPathItemInput{scenarioItem(scenario.ID, nil)}
+
+	orgPath, err := svc.Create(orgID, "manager", dto.LearningPathInput{Title: "Everyone", Items: items})
+	require.NoError(t, err)
+	groupPath, err := svc.Create(orgID, "manager", dto.LearningPathInput{Title: "Bootcamp", GroupID: &group.ID, Items: items})
+	require.NoError(t, err)
+
+	mine, err := svc.LearnerProgress("learner")
+	require.NoError(t, err)
+	require.Len(t, mine, 2)
+	assert.Equal(t, "Bootcamp", mine[0].Title)
+
+	theirs, err := svc.LearnerProgress("classmate")
+	require.NoError(t, err)
+	require.Len(t, theirs, 1)
+	assert.Equal(t, orgPath.ID, theirs[0].PathID)
+
+	_, err = svc.Get(groupPath.ID, "classmate", false)
+	assert.ErrorIs(t, err, services.ErrPathAccessDenied)
+
+	_, err = svc.Progress(groupPath.ID, "learner", "classmate", false)
+	assert.ErrorIs(t, err, services.ErrPathAccessDenied)
+	progress, err := svc.Progress(groupPath.ID, "learner", "manager", false)
+	require.NoError(t, err)
+	assert.Equal(t, "learner", progress.UserID)
+}
+
+func TestCreate_ValidatesItems(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	otherOrgID := createOrg(t, db, "globex")
+	foreign := createScenario(t, db, "Globex only", &otherOrgID)
+	public := createScenario(t, db, "Public", &otherOrgID)
+	require.NoError(t, db.Model(&public).Update("is_public", true).Error)
+	chapter := createChapter(t, db, "Permissions")
+	otherGroup := createGroupWithMember(t, db, otherOrgID, "someone")
+
+	cases := map[string]dto.LearningPathInput{
+		"scenario of another organization": {Title: "x", Items: []dto.LearningPathItemInput{scenarioItem(foreign.ID, nil)}},
+		"unknown scenario":                 {Title: "x", Items: []dto.LearningPathItemInput{scenarioItem(uuid.New(), nil)}},
+		"unknown chapter":                  {Title: "x", Items: []dto.LearningPathItemInput{chapterItem(uuid.New())}},
+		"chapter with a grade": {Title: "x", Items: []dto.LearningPathItemInput{
+			{Kind: models.ItemKindChapter, ChapterID: &chapter.ID, MinGrade: floatPtr(50)},
+		}},
+		"group of another organization": {Title: "x", GroupID: &otherGroup.ID, Items: []dto.LearningPathItemInput{chapterItem(chapter.ID)}},
+	}
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Create(orgID, "manager", input)
+			assert.ErrorIs(t, err, services.ErrInvalidPath)
+		})
+	}
+
+	path, err := svc.Create(orgID, "manager", dto.LearningPathInput{
+		Title: "Mixed", Items: []dto.LearningPathItemInput{scenarioItem(public.ID, nil), chapterItem(chapter.ID)},
+	})
+	require.NoError(t, err)
+	assert.Len(t, path.Items, 2)
+}
+
+func TestUpdate_KeepsChapterCompletions(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	addOrgMember(t, db, orgID, "manager", orgModels.OrgRoleManager)
+	first := createChapter(t, db, "Shells")
+	second := createChapter(t, db, "Permissions")
+	path, err := svc.Create(orgID, "manager", dto.LearningPathInput{
+		Title: "Reading", Items: []dto.LearningPathItemInput{chapterItem(first.ID)},
+	})
+	require.NoError(t, err)
+	_, err = svc.CompleteChapter(path.ID, path.Items[0].ID, "learner")
+	require.NoError(t, err)
+
+	_, err = svc.Update(path.ID, "learner", false, dto.LearningPathInput{Title: "Mine", Items: []dto.LearningPathItemInput{chapterItem(second.ID)}})
+	assert.ErrorIs(t, err, services.ErrPathAccessDenied)
+
+	updated, err := svc.Update(path.ID, "manager", false, dto.LearningPathInput{
+		Title: "Reading list", Items: []dto.LearningPathItemInput{chapterItem(second.ID), chapterItem(first.ID)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Reading list", updated.Title)
+	require.Len(t, updated.Items, 2)
+
+	progress, err := svc.Progress(path.ID, "learner", "learner", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{dto.ItemStatusAvailable, dto.ItemStatusCompleted}, statuses(progress))
+}
+
+func TestUpcomingDeadlines_ListsUncompletedAssignmentsSoonestFirst(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	group := createGroupWithMember(t, db, orgID, "learner")
+	now := time.Now()
+
+	assign := func(title string, deadline time.Time, scope string, active bool) scenarioModels.Scenario {
+		scenario := createScenario(t, db, title, &orgID)
+		assignment := &scenarioModels.ScenarioAssignment{
+			ScenarioID: scenario.ID, Scope: scope, CreatedByID: "trainer", Deadline: &deadline, IsActive: true,
+		}
+		if scope == "group" {
+			assignment.GroupID = &group.ID
+		} else {
+			assignment.OrganizationID = &orgID
+		}
+		require.NoError(t, db.Create(assignment).Error)
+		if !active {
+			require.NoError(t, db.Model(assignment).Update("is_active", false).Error)
+		}
+		return scenario
+	}
+	soon := assign("Due soon", now.Add(3*24*time.Hour), "group", true)
+	later := assign("Due later", now.Add(10*24*time.Hour), "org", true)
+	done := assign("Done", now.Add(5*24*time.Hour), "group", true)
+	missed := assign("Missed", now.Add(-2*24*time.Hour), "org", true)
+	assign("Far away", now.Add(60*24*time.Hour), "group", true)
+	assign("Withdrawn", now.Add(4*24*time.Hour), "group", false)
+	createSession(t, db, done.ID, "learner", "completed", floatPtr(90))
+	createSession(t, db, later.ID, "learner", "active", nil)
+
+	deadlines, err := svc.UpcomingDeadlines("learner", 0)
+	require.NoError(t, err)
+	require.Len(t, deadlines, 3)
+	assert.Equal(t, missed.ID, deadlines[0].ScenarioID)
+	assert.True(t, deadlines[0].Overdue)
+	assert.Equal(t, soon.ID, deadlines[1].ScenarioID)
+	assert.Equal(t, dto.DeadlineStatusNotStarted, deadlines[1].Status)
+	assert.False(t, deadlines[1].Overdue)
+	assert.Equal(t, later.ID, deadlines[2].ScenarioID)
+	assert.Equal(t, dto.DeadlineStatusInProgress, deadlines[2].Status)
+
+	deadlines, err = svc.UpcomingDeadlines("learner", 90)
+	require.NoError(t, err)
+	assert.Len(t, deadlines, 4)
+
+	dashboard, err := svc.Dashboard("learner")
+	require.NoError(t, err)
+	assert.Len(t, dashboard.Deadlines, 3)
+	assert.Empty(t, dashboard.Paths)
+}
+
+func TestUpcomingDeadlines_UsesLearnersExtension(t *testing.T) {
+	db := freshTestDB(t)
+	svc := services.NewLearningPathService(db)
+	orgID := createOrg(t, db, "acme")
+	addOrgMember(t, db, orgID, "learner", orgModels.OrgRoleMember)
+	now := time.Now()
+
+	scenario := createScenario(t, db, "Extended", &orgID)
+	deadline := now.Add(-2 * 24 * time.Hour)
+	assignment := &scenarioModels.ScenarioAssignment{
+		ScenarioID: scenario.ID, Scope: "org", OrganizationID: &orgID, CreatedByID: "trainer", Deadline: &deadline, IsActive: true,
+	}
+	require.NoError(t, db.Create(assignment).Error)
+	extended := now.Add(45 * 24 * time.Hour)
+	require.NoError(t, db.Create(&scenarioModels.ScenarioAssignmentExtension{
+		AssignmentID: assignment.ID, UserID: "learner", Deadline: extended, GrantedByID: "trainer",
+	}).Error)
+
+	deadlines, err := svc.UpcomingDeadlines("learner", 0)
+	require.NoError(t, err)
+	assert.Empty(t, deadlines, "the learner's deadline is beyond the window")
+
+	deadlines, err = svc.UpcomingDeadlines("learner", 60)
+	require.NoError(t, err)
+	require.Len(t, deadlines, 1)
+	assert.WithinDuration(t, extended, deadlines[0].Deadline, time.Second)
+	assert.False(t, deadlines[0].Overdue)
+}
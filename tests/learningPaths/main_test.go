@@ -0,0 +1,65 @@
+package learningPaths_test
+
+import (
+	"os"
+	"testing"
+
+	courseModels "soli/formations/src/courses/models"
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/learningPaths/models"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var sharedTestDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to open shared test DB: " + err.Error())
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(
+		&models.LearningPath{},
+		&models.LearningPathItem{},
+		&models.ChapterCompletion{},
+		&courseModels.Course{},
+		&courseModels.Chapter{},
+		&scenarioModels.Scenario{},
+		&scenarioModels.ScenarioSession{},
+		&scenarioModels.ScenarioAssignment{},
+		&scenarioModels.ScenarioAssignmentExtension{},
+		&groupModels.ClassGroup{},
+		&groupModels.GroupMember{},
+		&orgModels.Organization{},
+		&orgModels.OrganizationMember{},
+	)
+	if err != nil {
+		panic("failed to migrate shared test DB: " + err.Error())
+	}
+
+	sharedTestDB = db
+	os.Exit(m.Run())
+}
+
+func freshTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	for _, table := range []string{
+		"learning_path_items", "learning_paths", "chapter_completions",
+		"course_chapters", "chapters", "courses",
+		"scenario_sessions", "scenario_assignment_extensions", "scenario_assignments", "scenarios",
+		"group_members", "class_groups", "organization_members", "organizations",
+	} {
+		sharedTestDB.Exec("DELETE FROM " + table)
+	}
+	return sharedTestDB
+}
@@ -0,0 +1,53 @@
+package liveprogress_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"soli/formations/src/liveprogress"
+)
+
+func TestBus_FansOutToEverySubscriber(t *testing.T) {
+	bus := liveprogress.NewBus()
+	first := bus.Subscribe(4)
+	second := bus.Subscribe(4)
+	defer first.Close()
+	defer second.Close()
+
+	bus.Publish(liveprogress.Event{Kind: liveprogress.KindFlagSubmission, UserID: "learner-1"})
+
+	for _, sub := range []*liveprogress.Subscription{first, second} {
+		event := <-sub.C
+		assert.Equal(t, "learner-1", event.UserID)
+		assert.False(t, event.At.IsZero(), "Publish stamps events that carry no time")
+	}
+}
+
+func TestBus_SlowSubscriberIsMarkedLaggedNotBlocking(t *testing.T) {
+	bus := liveprogress.NewBus()
+	sub := bus.Subscribe(1)
+	defer sub.Close()
+
+	bus.Publish(liveprogress.Event{UserID: "a"})
+	bus.Publish(liveprogress.Event{UserID: "b"}) // buffer full: dropped, not blocked
+
+	assert.True(t, sub.TakeLagged())
+	assert.False(t, sub.TakeLagged(), "the mark clears once taken")
+	assert.Equal(t, "a", (<-sub.C).UserID)
+}
+
+func TestBus_CloseUnsubscribes(t *testing.T) {
+	bus := liveprogress.NewBus()
+	sub := bus.Subscribe(1)
+	require.Equal(t, 1, bus.SubscriberCount())
+
+	sub.Close()
+	sub.Close()
+	assert.Equal(t, 0, bus.SubscriberCount())
+	_, open := <-sub.C
+	assert.False(t, open)
+
+	bus.Publish(liveprogress.Event{UserID: "after-close"}) // must not panic
+}
@@ -0,0 +1,248 @@
+package lti_tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/lti/dto"
+	ltiModels "soli/formations/src/lti/models"
+	"soli/formations/src/lti/services"
+)
+
+// fakePlatform_test.go — a minimal LTI 1.3 platform (an LMS such as Moodle or
+// Canvas) served by httptest: it publishes a JWKS, signs launch id_tokens with
+// its own key, issues AGS access tokens and records the scores it receives.
+
+const (
+	fakeClientID     = "ocf-tool"
+	fakeDeploymentID = "deployment-1"
+	fakeAGSToken     = "fake-ags-token"
+	roleInstructor   = "http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"
+	roleLearner      = "http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"
+	scopeScore       = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+)
+
+type receivedScore struct {
+	Authorization string
+	ContentType   string
+	Body          map[string]any
+}
+
+type fakePlatform struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu              sync.Mutex
+	tokenRequests   []url.Values
+	scores          []receivedScore
+	scoreStatusCode int
+}
+
+func newFakePlatform(t *testing.T) *fakePlatform {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	fp := &fakePlatform{key: key, kid: "platform-key-1", scoreStatusCode: http.StatusOK}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(services.JWKS{Keys: []services.JWK{services.PublicJWK(fp.kid, &fp.key.PublicKey)}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fp.mu.Lock()
+		fp.tokenRequests = append(fp.tokenRequests, r.PostForm)
+		fp.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"access_token": fakeAGSToken, "token_type": "Bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("/lineitem/scores", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var score map[string]any
+		json.Unmarshal(body, &score)
+		fp.mu.Lock()
+		defer fp.mu.Unlock()
+		fp.scores = append(fp.scores, receivedScore{
+			Authorization: r.Header.Get("Authorization"),
+			ContentType:   r.Header.Get("Content-Type"),
+			Body:          score,
+		})
+		w.WriteHeader(fp.scoreStatusCode)
+	})
+	fp.server = httptest.NewServer(mux)
+	t.Cleanup(fp.server.Close)
+	return fp
+}
+
+func (fp *fakePlatform) issuer() string      { return fp.server.URL }
+func (fp *fakePlatform) lineItemURL() string { return fp.server.URL + "/lineitem" }
+
+// register adds the fake platform to OCF, the way an administrator would.
+func (fp *fakePlatform) register(t *testing.T, svc *services.LtiService, orgID *uuid.UUID) *dto.PlatformOutput {
+	t.Helper()
+	platform, err := svc.CreatePlatform(dto.CreatePlatformInput{
+		Name:           "Fake Moodle",
+		Issuer:         fp.issuer(),
+		ClientID:       fakeClientID,
+		DeploymentID:   fakeDeploymentID,
+		AuthLoginURL:   fp.server.URL + "/auth",
+		AuthTokenURL:   fp.server.URL + "/token",
+		KeySetURL:      fp.server.URL + "/jwks",
+		OrganizationID: orgID,
+	}, "platform-admin")
+	require.NoError(t, err)
+	return platform
+}
+
+// sign returns claims as an id_token signed with the platform key.
+func (fp *fakePlatform) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	return signWith(t, fp.key, fp.kid, claims)
+}
+
+func signWith(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// resourceLinkClaims is a learner's launch of resourceLinkID in course-101.
+func (fp *fakePlatform) resourceLinkClaims(subject, resourceLinkID string, scenarioID uuid.UUID, roles ...string) jwt.MapClaims {
+	if len(roles) == 0 {
+		roles = []string{roleLearner}
+	}
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   fp.issuer(),
+		"aud":   fakeClientID,
+		"sub":   subject,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"name":  "Ada Lovelace",
+		"email": subject + "@school.example",
+		"https://purl.imsglobal.org/spec/lti/claim/message_type":  services.MessageTypeResourceLink,
+		"https://purl.imsglobal.org/spec/lti/claim/version":       "1.3.0",
+		"https://purl.imsglobal.org/spec/lti/claim/deployment_id": fakeDeploymentID,
+		"https://purl.imsglobal.org/spec/lti/claim/roles":         roles,
+		"https://purl.imsglobal.org/spec/lti/claim/resource_link": map[string]any{"id": resourceLinkID, "title": "Lab 1"},
+		"https://purl.imsglobal.org/spec/lti/claim/context":       map[string]any{"id": "course-101", "label": "NET101", "title": "Networking 101"},
+		"https://purl.imsglobal.org/spec/lti/claim/custom":        map[string]any{"scenario_id": scenarioID.String()},
+		"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint": map[string]any{
+			"scope":    []string{scopeScore},
+			"lineitem": fp.lineItemURL(),
+		},
+	}
+}
+
+// deepLinkingClaims is an instructor's deep linking request from course-101.
+func (fp *fakePlatform) deepLinkingClaims(subject string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": fp.issuer(),
+		"aud": fakeClientID,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"https://purl.imsglobal.org/spec/lti/claim/message_type":  services.MessageTypeDeepLinkingRequest,
+		"https://purl.imsglobal.org/spec/lti/claim/version":       "1.3.0",
+		"https://purl.imsglobal.org/spec/lti/claim/deployment_id": fakeDeploymentID,
+		"https://purl.imsglobal.org/spec/lti/claim/roles":         []string{roleInstructor},
+		"https://purl.imsglobal.org/spec/lti/claim/context":       map[string]any{"id": "course-101", "title": "Networking 101"},
+		"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings": map[string]any{
+			"deep_link_return_url": fp.server.URL + "/deep-link-return",
+			"accept_types":         []string{"ltiResourceLink"},
+			"data":                 "opaque-platform-data",
+		},
+	}
+}
+
+// login runs OIDC login initiation and returns the state and nonce the
+// platform receives in the authorization redirect.
+func (fp *fakePlatform) login(t *testing.T, svc *services.LtiService) (string, string) {
+	t.Helper()
+	redirect, err := svc.InitiateLogin(services.LoginRequest{
+		Issuer: fp.issuer(), LoginHint: "hint", ClientID: fakeClientID,
+	})
+	require.NoError(t, err)
+	u, err := url.Parse(redirect)
+	require.NoError(t, err)
+	return u.Query().Get("state"), u.Query().Get("nonce")
+}
+
+// launch runs a full login + launch with claims, filling in the nonce.
+func (fp *fakePlatform) launch(t *testing.T, svc *services.LtiService, claims jwt.MapClaims) (*services.LaunchResult, error) {
+	t.Helper()
+	state, nonce := fp.login(t, svc)
+	claims["nonce"] = nonce
+	return svc.Launch(state, fp.sign(t, claims))
+}
+
+func (fp *fakePlatform) receivedScores() []receivedScore {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return append([]receivedScore(nil), fp.scores...)
+}
+
+// stubAccounts stands in for Casdoor: one account per platform user.
+type stubAccounts struct {
+	mu       sync.Mutex
+	created  []services.LaunchIdentity
+	issuedTo []string
+}
+
+func (s *stubAccounts) CreateAccount(_ *ltiModels.LtiPlatform, identity services.LaunchIdentity) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created = append(s.created, identity)
+	return "lti-user-" + identity.Subject, nil
+}
+
+func (s *stubAccounts) IssueAccessToken(userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issuedTo = append(s.issuedTo, userID)
+	return "ocf-token-" + userID, nil
+}
+
+func newTestService(t *testing.T, db *gorm.DB) (*services.LtiService, *stubAccounts) {
+	t.Helper()
+	accounts := &stubAccounts{}
+	return services.NewLtiService(db, accounts, "https://ocf.example"), accounts
+}
+
+// toolPublicKey decodes the key the tool publishes, as a platform would.
+func toolPublicKey(t *testing.T, svc *services.LtiService, kid string) *rsa.PublicKey {
+	t.Helper()
+	jwks, err := svc.ToolJWKS()
+	require.NoError(t, err)
+	for _, k := range jwks.Keys {
+		if k.Kid != kid {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		require.NoError(t, err)
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		require.NoError(t, err)
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	t.Fatalf("tool JWKS has no key %q", kid)
+	return nil
+}
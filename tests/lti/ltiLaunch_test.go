@@ -0,0 +1,505 @@
+package lti_tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/lti/dto"
+	ltiModels "soli/formations/src/lti/models"
+	ltiController "soli/formations/src/lti/routes"
+	"soli/formations/src/lti/services"
+	scenarioModels "soli/formations/src/scenarios/models"
+)
+
+// ltiLaunch_test.go — LTI 1.3 launches from a fake platform: OIDC login,
+// id_token validation, mapping of courses to class-groups and of platform users
+// to OCF accounts, deep linking and grade passback.
+
+func seedScenario(t *testing.T, db *gorm.DB, title, createdBy string, public bool) scenarioModels.Scenario {
+	t.Helper()
+	scenario := scenarioModels.Scenario{
+		Name: title, Title: title, InstanceType: "ubuntu:22.04", CreatedByID: createdBy, IsPublic: public,
+	}
+	require.NoError(t, db.Create(&scenario).Error)
+	return scenario
+}
+
+func groupMember(t *testing.T, db *gorm.DB, groupID uuid.UUID, userID string) groupModels.GroupMember {
+	t.Helper()
+	var member groupModels.GroupMember
+	require.NoError(t, db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error)
+	return member
+}
+
+func TestLtiLogin_RedirectsToPlatformWithStateAndNonce(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	platform := fp.register(t, svc, nil)
+
+	redirect, err := svc.InitiateLogin(services.LoginRequest{
+		Issuer: fp.issuer(), LoginHint: "user-42", LtiMessageHint: "msg-7", ClientID: fakeClientID,
+	})
+	require.NoError(t, err)
+
+	u, err := url.Parse(redirect)
+	require.NoError(t, err)
+	assert.Equal(t, fp.server.URL+"/auth", u.Scheme+"://"+u.Host+u.Path)
+	q := u.Query()
+	assert.Equal(t, "openid", q.Get("scope"))
+	assert.Equal(t, "id_token", q.Get("response_type"))
+	assert.Equal(t, "form_post", q.Get("response_mode"))
+	assert.Equal(t, "none", q.Get("prompt"))
+	assert.Equal(t, fakeClientID, q.Get("client_id"))
+	assert.Equal(t, "https://ocf.example/api/v1/lti/launch", q.Get("redirect_uri"))
+	assert.Equal(t, "user-42", q.Get("login_hint"))
+	assert.Equal(t, "msg-7", q.Get("lti_message_hint"))
+	require.NotEmpty(t, q.Get("state"))
+	require.NotEmpty(t, q.Get("nonce"))
+
+	var state ltiModels.LtiLaunchState
+	require.NoError(t, db.Where("state = ?", q.Get("state")).First(&state).Error)
+	assert.Equal(t, q.Get("nonce"), state.Nonce)
+	assert.Equal(t, platform.ID, state.PlatformID)
+}
+
+func TestLtiLogin_UnknownOrInactivePlatformRejected(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	platform := fp.register(t, svc, nil)
+
+	_, err := svc.InitiateLogin(services.LoginRequest{Issuer: "https://other.example", LoginHint: "x"})
+	assert.ErrorIs(t, err, services.ErrUnknownPlatform)
+
+	inactive := false
+	_, err = svc.UpdatePlatform(platform.ID, dto.UpdatePlatformInput{IsActive: &inactive})
+	require.NoError(t, err)
+	_, err = svc.InitiateLogin(services.LoginRequest{Issuer: fp.issuer(), LoginHint: "x", ClientID: fakeClientID})
+	assert.ErrorIs(t, err, services.ErrUnknownPlatform)
+}
+
+func TestLtiLaunch_ResourceLinkMapsCourseToClassGroup(t *testing.T) {
+	db := freshTestDB(t)
+	svc, accounts := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Linux basics", "author", true)
+
+	result, err := fp.launch(t, svc, fp.resourceLinkClaims("learner-1", "link-1", scenario.ID))
+	require.NoError(t, err)
+	assert.Equal(t, services.MessageTypeResourceLink, result.MessageType)
+	assert.Equal(t, "lti-user-learner-1", result.UserID)
+	assert.Equal(t, "ocf-token-lti-user-learner-1", result.AccessToken)
+	require.NotNil(t, result.ScenarioID)
+	assert.Equal(t, scenario.ID, *result.ScenarioID)
+	require.NotNil(t, result.GroupID)
+
+	var group groupModels.ClassGroup
+	require.NoError(t, db.Where("id = ?", *result.GroupID).First(&group).Error)
+	assert.Equal(t, "Networking 101", group.DisplayName)
+	assert.Equal(t, "platform-admin", group.OwnerUserID)
+	assert.Equal(t, groupModels.GroupMemberRoleMember, groupMember(t, db, group.ID, result.UserID).Role)
+
+	var assignments []scenarioModels.ScenarioAssignment
+	require.NoError(t, db.Where("scenario_id = ? AND group_id = ?", scenario.ID, group.ID).Find(&assignments).Error)
+	require.Len(t, assignments, 1, "the placed scenario is assigned to the course's class-group")
+
+	var gradeLinks []ltiModels.LtiGradeLink
+	require.NoError(t, db.Where("user_id = ?", result.UserID).Find(&gradeLinks).Error)
+	require.Len(t, gradeLinks, 1, "a learner on a graded placement is registered for passback")
+	assert.Equal(t, "learner-1", gradeLinks[0].Subject)
+
+	// Second launch of the same user: same account, same group, no duplicates.
+	again, err := fp.launch(t, svc, fp.resourceLinkClaims("learner-1", "link-1", scenario.ID))
+	require.NoError(t, err)
+	assert.Equal(t, result.UserID, again.UserID)
+	assert.Equal(t, *result.GroupID, *again.GroupID)
+	assert.Len(t, accounts.created, 1, "an account is created on the first launch only")
+
+	// The course's instructor lands in the same group as a manager.
+	teacher, err := fp.launch(t, svc, fp.resourceLinkClaims("teacher-1", "link-1", scenario.ID, roleInstructor))
+	require.NoError(t, err)
+	assert.Equal(t, *result.GroupID, *teacher.GroupID)
+	assert.Equal(t, groupModels.GroupMemberRoleManager, groupMember(t, db, group.ID, teacher.UserID).Role)
+
+	var count int64
+	db.Model(&scenarioModels.ScenarioAssignment{}).Where("group_id = ?", group.ID).Count(&count)
+	assert.EqualValues(t, 1, count)
+	db.Model(&ltiModels.LtiGradeLink{}).Where("user_id = ?", teacher.UserID).Count(&count)
+	assert.EqualValues(t, 0, count, "instructors are not graded")
+}
+
+func TestLtiLaunch_LearnerPromotedToInstructorBecomesManager(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Linux basics", "author", true)
+
+	first, err := fp.launch(t, svc, fp.resourceLinkClaims("ta-1", "link-1", scenario.ID))
+	require.NoError(t, err)
+	_, err = fp.launch(t, svc, fp.resourceLinkClaims("ta-1", "link-1", scenario.ID, roleInstructor))
+	require.NoError(t, err)
+	assert.Equal(t, groupModels.GroupMemberRoleManager, groupMember(t, db, *first.GroupID, first.UserID).Role)
+
+	// A later learner launch does not demote them.
+	_, err = fp.launch(t, svc, fp.resourceLinkClaims("ta-1", "link-1", scenario.ID))
+	require.NoError(t, err)
+	assert.Equal(t, groupModels.GroupMemberRoleManager, groupMember(t, db, *first.GroupID, first.UserID).Role)
+}
+
+func TestLtiLaunch_RejectsInvalidTokens(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Linux basics", "author", true)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		mutate func(claims jwt.MapClaims)
+		sign   func(claims jwt.MapClaims) string
+	}{
+		{name: "signed with another key", sign: func(c jwt.MapClaims) string { return signWith(t, otherKey, fp.kid, c) }},
+		{name: "wrong nonce", mutate: func(c jwt.MapClaims) { c["nonce"] = "forged" }},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "another-tool" }},
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://other.example" }},
+		{name: "unknown deployment", mutate: func(c jwt.MapClaims) {
+			c["https://purl.imsglobal.org/spec/lti/claim/deployment_id"] = "deployment-2"
+		}},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "missing exp", mutate: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "missing iat", mutate: func(c jwt.MapClaims) { delete(c, "iat") }},
+		{name: "issued long ago", mutate: func(c jwt.MapClaims) { c["iat"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "wrong version", mutate: func(c jwt.MapClaims) { c["https://purl.imsglobal.org/spec/lti/claim/version"] = "1.1" }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, nonce := fp.login(t, svc)
+			claims := fp.resourceLinkClaims("learner-1", "link-1", scenario.ID)
+			claims["nonce"] = nonce
+			if tc.mutate != nil {
+				tc.mutate(claims)
+			}
+			token := fp.sign(t, claims)
+			if tc.sign != nil {
+				token = tc.sign(claims)
+			}
+			_, err := svc.Launch(state, token)
+			assert.ErrorIs(t, err, services.ErrInvalidLaunch)
+		})
+	}
+
+	t.Run("replayed state", func(t *testing.T) {
+		state, nonce := fp.login(t, svc)
+		claims := fp.resourceLinkClaims("learner-1", "link-1", scenario.ID)
+		claims["nonce"] = nonce
+		token := fp.sign(t, claims)
+		_, err := svc.Launch(state, token)
+		require.NoError(t, err)
+		_, err = svc.Launch(state, token)
+		assert.ErrorIs(t, err, services.ErrInvalidLaunch)
+	})
+
+	var links int64
+	db.Model(&ltiModels.LtiUserLink{}).Count(&links)
+	assert.EqualValues(t, 1, links, "only the valid launch created an account")
+}
+
+func TestLtiLaunch_ArchivedOrMissingScenarioRejected(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Retired", "author", true)
+	now := time.Now()
+	require.NoError(t, db.Model(&scenario).Update("archived_at", &now).Error)
+
+	_, err := fp.launch(t, svc, fp.resourceLinkClaims("learner-1", "link-1", scenario.ID))
+	assert.ErrorIs(t, err, services.ErrInvalidLaunch)
+
+	_, err = fp.launch(t, svc, fp.resourceLinkClaims("learner-1", "link-2", uuid.New()))
+	assert.ErrorIs(t, err, services.ErrInvalidLaunch)
+}
+
+// TestLtiLaunch_ScenarioOutsidePlatformScopeRejected: the scenario_id custom
+// parameter is editable in the LMS, so a first launch may only place what
+// deep linking would have offered.
+func TestLtiLaunch_ScenarioOutsidePlatformScopeRejected(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	platformOrg := uuid.New()
+	fp.register(t, svc, &platformOrg)
+
+	otherOrg := uuid.New()
+	foreign := seedScenario(t, db, "Other org's exam", "other-author", false)
+	require.NoError(t, db.Model(&foreign).Update("organization_id", otherOrg).Error)
+	own := seedScenario(t, db, "Platform org's lab", "org-author", false)
+	require.NoError(t, db.Model(&own).Update("organization_id", platformOrg).Error)
+
+	_, err := fp.launch(t, svc, fp.resourceLinkClaims("teacher-1", "link-1", foreign.ID, roleInstructor))
+	assert.ErrorIs(t, err, services.ErrInvalidLaunch)
+	var count int64
+	db.Model(&ltiModels.LtiResourceLink{}).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&scenarioModels.ScenarioAssignment{}).Where("scenario_id = ?", foreign.ID).Count(&count)
+	assert.Zero(t, count, "another organization's private scenario must not be assigned to the course")
+
+	result, err := fp.launch(t, svc, fp.resourceLinkClaims("teacher-1", "link-2", own.ID, roleInstructor))
+	require.NoError(t, err)
+	assert.Equal(t, own.ID, *result.ScenarioID)
+}
+
+func TestLtiDeepLinking_ReturnsSignedResourceLink(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	platform := fp.register(t, svc, nil)
+	public := seedScenario(t, db, "Public lab", "author", true)
+	seedScenario(t, db, "Private lab", "someone-else", false)
+
+	result, err := fp.launch(t, svc, fp.deepLinkingClaims("teacher-1"))
+	require.NoError(t, err)
+	assert.Equal(t, services.MessageTypeDeepLinkingRequest, result.MessageType)
+	require.NotNil(t, result.DeepLinkID)
+	require.NotNil(t, result.GroupID)
+	own := seedScenario(t, db, "Own lab", result.UserID, false)
+
+	scenarios, err := svc.ListDeepLinkScenarios(*result.DeepLinkID, result.UserID)
+	require.NoError(t, err)
+	var titles []string
+	for _, sc := range scenarios {
+		titles = append(titles, sc.Title)
+	}
+	assert.ElementsMatch(t, []string{public.Title, own.Title}, titles)
+
+	_, err = svc.CompleteDeepLink(*result.DeepLinkID, "intruder", public.ID)
+	assert.ErrorIs(t, err, services.ErrDeepLinkNotFound, "a deep linking request belongs to its instructor")
+
+	response, err := svc.CompleteDeepLink(*result.DeepLinkID, result.UserID, public.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fp.server.URL+"/deep-link-return", response.ReturnURL)
+
+	// The platform verifies the response against the tool's published keys.
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(response.JWT, claims, func(token *jwt.Token) (any, error) {
+		return toolPublicKey(t, svc, token.Header["kid"].(string)), nil
+	})
+	require.NoError(t, err)
+	assert.True(t, token.Valid)
+	assert.Equal(t, fakeClientID, claims["iss"])
+	assert.True(t, claims.VerifyAudience(platform.Issuer, true))
+	assert.Equal(t, services.MessageTypeDeepLinkingResponse, claims["https://purl.imsglobal.org/spec/lti/claim/message_type"])
+	assert.Equal(t, fakeDeploymentID, claims["https://purl.imsglobal.org/spec/lti/claim/deployment_id"])
+	assert.Equal(t, "opaque-platform-data", claims["https://purl.imsglobal.org/spec/lti-dl/claim/data"])
+
+	items := claims["https://purl.imsglobal.org/spec/lti-dl/claim/content_items"].([]any)
+	require.Len(t, items, 1)
+	item := items[0].(map[string]any)
+	assert.Equal(t, "ltiResourceLink", item["type"])
+	assert.Equal(t, "https://ocf.example/api/v1/lti/launch", item["url"])
+	assert.Equal(t, public.ID.String(), item["custom"].(map[string]any)["scenario_id"])
+	assert.EqualValues(t, 100, item["lineItem"].(map[string]any)["scoreMaximum"])
+
+	_, err = svc.CompleteDeepLink(*result.DeepLinkID, result.UserID, public.ID)
+	assert.ErrorIs(t, err, services.ErrDeepLinkNotFound, "a deep linking request is single use")
+}
+
+func TestLtiDeepLinking_LearnerRejected(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+
+	claims := fp.deepLinkingClaims("learner-1")
+	claims["https://purl.imsglobal.org/spec/lti/claim/roles"] = []string{roleLearner}
+	_, err := fp.launch(t, svc, claims)
+	assert.ErrorIs(t, err, services.ErrInvalidLaunch)
+}
+
+func completeSession(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, userID string, grade float64, completedAt time.Time) scenarioModels.ScenarioSession {
+	t.Helper()
+	session := scenarioModels.ScenarioSession{
+		ScenarioID: scenarioID, UserID: userID, Status: "completed",
+		StartedAt: completedAt.Add(-30 * time.Minute), CompletedAt: &completedAt, Grade: &grade,
+	}
+	require.NoError(t, db.Create(&session).Error)
+	return session
+}
+
+func TestLtiGradePassback_PostsLatestGradeOnce(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	platform := fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Linux basics", "author", true)
+
+	result, err := fp.launch(t, svc, fp.resourceLinkClaims("learner-1", "link-1", scenario.ID))
+	require.NoError(t, err)
+
+	sent, err := svc.SyncGrades()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "nothing to send before the scenario is completed")
+
+	completeSession(t, db, scenario.ID, result.UserID, 80, time.Now().Add(-time.Hour))
+	sent, err = svc.SyncGrades()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	scores := fp.receivedScores()
+	require.Len(t, scores, 1)
+	assert.Equal(t, "Bearer "+fakeAGSToken, scores[0].Authorization)
+	assert.Equal(t, "application/vnd.ims.lis.v1.score+json", scores[0].ContentType)
+	assert.Equal(t, "learner-1", scores[0].Body["userId"])
+	assert.EqualValues(t, 80, scores[0].Body["scoreGiven"])
+	assert.EqualValues(t, 100, scores[0].Body["scoreMaximum"])
+	assert.Equal(t, "Completed", scores[0].Body["activityProgress"])
+	assert.Equal(t, "FullyGraded", scores[0].Body["gradingProgress"])
+
+	// The AGS token was obtained with a client assertion signed by the tool.
+	require.Len(t, fp.tokenRequests, 1)
+	form := fp.tokenRequests[0]
+	assert.Equal(t, "client_credentials", form.Get("grant_type"))
+	assert.Equal(t, scopeScore, form.Get("scope"))
+	assertion := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(form.Get("client_assertion"), assertion, func(token *jwt.Token) (any, error) {
+		return toolPublicKey(t, svc, token.Header["kid"].(string)), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fakeClientID, assertion["iss"])
+	assert.True(t, assertion.VerifyAudience(platform.AuthTokenURL, true))
+
+	sent, err = svc.SyncGrades()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "an already passed back grade is not resent")
+
+	// A retake replaces the grade in the LMS, reusing the cached token.
+	completeSession(t, db, scenario.ID, result.UserID, 95, time.Now())
+	sent, err = svc.SyncGrades()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	scores = fp.receivedScores()
+	require.Len(t, scores, 2)
+	assert.EqualValues(t, 95, scores[1].Body["scoreGiven"])
+	assert.Len(t, fp.tokenRequests, 1)
+}
+
+func TestLtiGradePassback_RetriesAfterFailure(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Linux basics", "author", true)
+
+	result, err := fp.launch(t, svc, fp.resourceLinkClaims("learner-1", "link-1", scenario.ID))
+	require.NoError(t, err)
+	completeSession(t, db, scenario.ID, result.UserID, 70, time.Now())
+
+	fp.mu.Lock()
+	fp.scoreStatusCode = http.StatusInternalServerError
+	fp.mu.Unlock()
+	sent, err := svc.SyncGrades()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	var link ltiModels.LtiGradeLink
+	require.NoError(t, db.Where("user_id = ?", result.UserID).First(&link).Error)
+	assert.Contains(t, link.LastError, "500")
+	assert.Nil(t, link.SyncedSessionID)
+
+	fp.mu.Lock()
+	fp.scoreStatusCode = http.StatusOK
+	fp.mu.Unlock()
+	sent, err = svc.SyncGrades()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	var synced ltiModels.LtiGradeLink
+	require.NoError(t, db.Where("user_id = ?", result.UserID).First(&synced).Error)
+	assert.Empty(t, synced.LastError)
+	require.NotNil(t, synced.SyncedSessionID)
+}
+
+func TestLtiLaunchEndpoint_RedirectsToFrontendWithTokenInFragment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("FRONTEND_URL", "https://app.ocf.example/")
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	fp := newFakePlatform(t)
+	fp.register(t, svc, nil)
+	scenario := seedScenario(t, db, "Linux basics", "author", true)
+
+	controller := ltiController.NewLtiController(svc)
+	router := gin.New()
+	router.GET("/api/v1/lti/login", controller.Login)
+	router.POST("/api/v1/lti/launch", controller.Launch)
+
+	w := httptest.NewRecorder()
+	loginURL := "/api/v1/lti/login?" + url.Values{"iss": {fp.issuer()}, "login_hint": {"hint"}, "client_id": {fakeClientID}}.Encode()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, loginURL, nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	authRedirect, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+
+	claims := fp.resourceLinkClaims("learner-1", "link-1", scenario.ID)
+	claims["nonce"] = authRedirect.Query().Get("nonce")
+	form := url.Values{"state": {authRedirect.Query().Get("state")}, "id_token": {fp.sign(t, claims)}}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/lti/launch", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusSeeOther, w.Code)
+
+	location := w.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, "https://app.ocf.example/lti/launch#"), location)
+	fragment, err := url.ParseQuery(location[strings.Index(location, "#")+1:])
+	require.NoError(t, err)
+	assert.Equal(t, "ocf-token-lti-user-learner-1", fragment.Get("access_token"))
+	assert.Equal(t, scenario.ID.String(), fragment.Get("scenario_id"))
+	assert.NotEmpty(t, fragment.Get("group_id"))
+
+	// Replaying the same form post is refused.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/lti/launch", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLtiToolKey_StoredEncrypted(t *testing.T) {
+	db := freshTestDB(t)
+	svc, _ := newTestService(t, db)
+	published, err := svc.ToolJWKS()
+	require.NoError(t, err)
+	require.Len(t, published.Keys, 1)
+
+	var raw string
+	require.NoError(t, db.Raw("SELECT private_key_pem FROM lti_tool_keys").Scan(&raw).Error)
+	assert.True(t, strings.HasPrefix(raw, "enc::v1:"), "the tool's private key must not be stored in clear")
+	assert.NotContains(t, raw, "PRIVATE KEY")
+
+	restarted, _ := newTestService(t, db)
+	republished, err := restarted.ToolJWKS()
+	require.NoError(t, err)
+	assert.Equal(t, published.Keys, republished.Keys, "a restarted tool must decrypt and keep publishing the same key")
+}
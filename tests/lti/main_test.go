@@ -0,0 +1,69 @@
+package lti_tests
+
+import (
+	"os"
+	"testing"
+
+	groupModels "soli/formations/src/groups/models"
+	ltiModels "soli/formations/src/lti/models"
+	orgModels "soli/formations/src/organizations/models"
+	scenarioModels "soli/formations/src/scenarios/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var sharedTestDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to open shared test DB: " + err.Error())
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(
+		&ltiModels.LtiPlatform{},
+		&ltiModels.LtiToolKey{},
+		&ltiModels.LtiLaunchState{},
+		&ltiModels.LtiUserLink{},
+		&ltiModels.LtiContextLink{},
+		&ltiModels.LtiResourceLink{},
+		&ltiModels.LtiGradeLink{},
+		&ltiModels.LtiDeepLinkRequest{},
+		&scenarioModels.Scenario{},
+		&scenarioModels.ScenarioSession{},
+		&scenarioModels.ScenarioAssignment{},
+		&scenarioModels.ScenarioAssignmentExtension{},
+		&groupModels.ClassGroup{},
+		&groupModels.GroupMember{},
+		&orgModels.Organization{},
+		&orgModels.OrganizationMember{},
+	)
+	if err != nil {
+		panic("failed to migrate shared test DB: " + err.Error())
+	}
+
+	sharedTestDB = db
+	// The tool key is stored encrypted.
+	os.Setenv("FIELD_ENCRYPTION_SECRET", "lti-test-secret")
+	os.Exit(m.Run())
+}
+
+func freshTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	for _, table := range []string{
+		"lti_grade_links", "lti_resource_links", "lti_context_links", "lti_user_links",
+		"lti_deep_link_requests", "lti_launch_states", "lti_tool_keys", "lti_platforms",
+		"scenario_sessions", "scenario_assignment_extensions", "scenario_assignments", "scenarios",
+		"group_members", "class_groups", "organization_members", "organizations",
+	} {
+		sharedTestDB.Exec("DELETE FROM " + table)
+	}
+	return sharedTestDB
+}
@@ -0,0 +1,201 @@
+// tests/observability/prometheus_endpoint_test.go
+//
+// Tests for the Prometheus /metrics endpoint: bearer-token authentication,
+// exposition of the observability counters, the entity-route and hook
+// latency histograms, and the gauges read from the database at scrape time.
+package observability_tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"soli/formations/src/entityManagement/hooks"
+	"soli/formations/src/observability"
+	observabilityRoutes "soli/formations/src/observability/routes"
+	scenarioModels "soli/formations/src/scenarios/models"
+	terminalModels "soli/formations/src/terminalTrainer/models"
+)
+
+const testMetricsToken = "scrape-token"
+
+func setupMetricsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&terminalModels.Terminal{}, &scenarioModels.ScenarioSession{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func scrape(t *testing.T, db *gorm.DB, authorization string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", observabilityRoutes.NewPrometheusHandler(db, testMetricsToken))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func mustContainLine(t *testing.T, body, line string) {
+	t.Helper()
+	for _, l := range strings.Split(body, "\n") {
+		if l == line {
+			return
+		}
+	}
+	t.Errorf("exposition is missing line %q\n--- body ---\n%s", line, body)
+}
+
+func TestPrometheusMetrics_RequiresToken(t *testing.T) {
+	db := setupMetricsDB(t)
+	for _, tc := range []struct {
+		name          string
+		authorization string
+		wantCode      int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"token without scheme", testMetricsToken, http.StatusUnauthorized},
+		{"valid token", "Bearer " + testMetricsToken, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := scrape(t, db, tc.authorization)
+			if w.Code != tc.wantCode {
+				t.Errorf("expected %d, got %d (body: %s)", tc.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrometheusMetrics_ExposesCounters(t *testing.T) {
+	t.Cleanup(resetObservabilityMetrics)
+	observability.Metrics.StripeCreateFailure.Add(2)
+	observability.Metrics.StripeQueuePendingDepth.Store(7)
+	t.Cleanup(func() { observability.Metrics.StripeQueuePendingDepth.Store(0) })
+	observability.Metrics.ScenarioSetupFailed.Add(1)
+
+	w := scrape(t, setupMetricsDB(t), "Bearer "+testMetricsToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != observability.PrometheusContentType {
+		t.Errorf("Content-Type: expected %q, got %q", observability.PrometheusContentType, ct)
+	}
+
+	body := w.Body.String()
+	mustContainLine(t, body, "# TYPE ocf_stripe_sync_operations_total counter")
+	mustContainLine(t, body, `ocf_stripe_sync_operations_total{operation="create",result="failure"} 2`)
+	mustContainLine(t, body, `ocf_stripe_sync_operations_total{operation="create",result="success"} 0`)
+	mustContainLine(t, body, "# TYPE ocf_stripe_sync_queue_depth gauge")
+	mustContainLine(t, body, "ocf_stripe_sync_queue_depth 7")
+	mustContainLine(t, body, "ocf_scenario_setup_failures_total 1")
+}
+
+func TestPrometheusMetrics_EntityRouteLatencyHistogram(t *testing.T) {
+	observability.HTTPRequestDuration.Reset()
+	t.Cleanup(observability.HTTPRequestDuration.Reset)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	courses := r.Group("/api/v1/courses", observability.InstrumentRoute("Course"))
+	courses.GET("/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id := uuid.NewString()
+		ids = append(ids, id)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/courses/"+id, nil))
+	}
+
+	body := scrape(t, setupMetricsDB(t), "Bearer "+testMetricsToken).Body.String()
+	labels := `entity="Course",method="GET",route="/api/v1/courses/:id",status="404"`
+	mustContainLine(t, body, "# TYPE ocf_http_request_duration_seconds histogram")
+	mustContainLine(t, body, `ocf_http_request_duration_seconds_bucket{`+labels+`,le="+Inf"} 3`)
+	mustContainLine(t, body, `ocf_http_request_duration_seconds_count{`+labels+`} 3`)
+	for _, id := range ids {
+		if strings.Contains(body, id) {
+			t.Errorf("route label must be the route template, never the raw path:\n%s", body)
+		}
+	}
+}
+
+func TestPrometheusMetrics_HookDurationHistogram(t *testing.T) {
+	const hookName = "prometheus-test-failing-hook"
+	const entityName = "PrometheusTestEntity"
+	observability.HookDuration.Reset()
+	t.Cleanup(observability.HookDuration.Reset)
+
+	failing := &localFailingHook{name: hookName, entityName: entityName, hookTypes: []hooks.HookType{hooks.AfterCreate}}
+	if err := hooks.GlobalHookRegistry.RegisterHook(failing); err != nil {
+		t.Fatalf("registering hook: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hooks.GlobalHookRegistry.UnregisterHook(hookName)
+		hooks.GlobalHookRegistry.ClearErrors()
+	})
+	_ = hooks.GlobalHookRegistry.ExecuteHooks(&hooks.HookContext{
+		HookType: hooks.AfterCreate, EntityName: entityName, EntityID: "e1",
+	})
+
+	body := scrape(t, setupMetricsDB(t), "Bearer "+testMetricsToken).Body.String()
+	mustContainLine(t, body, `ocf_hook_duration_seconds_count{hook="`+hookName+`",entity="`+entityName+`",hook_type="after_create",result="error"} 1`)
+}
+
+func TestPrometheusMetrics_DatabaseGauges(t *testing.T) {
+	db := setupMetricsDB(t)
+	future := time.Now().Add(time.Hour)
+	for i, tc := range []struct {
+		backend string
+		state   terminalModels.TerminalState
+		expires time.Time
+	}{
+		{"eu-1", terminalModels.StateRunning, future},
+		{"eu-1", terminalModels.StateRunning, future},
+		{"us-1", terminalModels.StateRunning, future},
+		{"", terminalModels.StateRunning, future},
+		{"us-1", terminalModels.StateStopped, future},                     // not running
+		{"us-1", terminalModels.StateRunning, time.Now().Add(-time.Hour)}, // zombie past expiry
+	} {
+		if err := db.Create(&terminalModels.Terminal{
+			SessionID: uuid.NewString(), UserID: "u" + string(rune('a'+i)), State: tc.state,
+			Backend: tc.backend, ExpiresAt: tc.expires, UserTerminalKeyID: uuid.New(),
+		}).Error; err != nil {
+			t.Fatalf("seed terminal: %v", err)
+		}
+	}
+	for i, status := range []string{"active", "active", "completed", "setup_failed"} {
+		if err := db.Create(&scenarioModels.ScenarioSession{
+			ScenarioID: uuid.New(), UserID: "learner-" + string(rune('a'+i)), Status: status, StartedAt: time.Now(),
+		}).Error; err != nil {
+			t.Fatalf("seed session: %v", err)
+		}
+	}
+
+	body := scrape(t, db, "Bearer "+testMetricsToken).Body.String()
+	mustContainLine(t, body, "# TYPE ocf_terminals_active gauge")
+	mustContainLine(t, body, `ocf_terminals_active{backend="eu-1"} 2`)
+	mustContainLine(t, body, `ocf_terminals_active{backend="us-1"} 1`)
+	mustContainLine(t, body, `ocf_terminals_active{backend="default"} 1`)
+	mustContainLine(t, body, "# TYPE ocf_scenario_sessions gauge")
+	mustContainLine(t, body, `ocf_scenario_sessions{status="active"} 2`)
+	mustContainLine(t, body, `ocf_scenario_sessions{status="completed"} 1`)
+	mustContainLine(t, body, `ocf_scenario_sessions{status="setup_failed"} 1`)
+}
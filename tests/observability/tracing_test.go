@@ -0,0 +1,232 @@
+// tests/observability/tracing_test.go
+//
+// Tests for the OpenTelemetry instrumentation, recorded with an in-memory
+// exporter: the gin server span, the outgoing HTTP client spans (generic
+// helper, tt-backend exec), GORM statement spans and hook spans, and how they
+// nest.
+package observability_tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"soli/formations/src/entityManagement/hooks"
+	"soli/formations/src/observability"
+	scenarioModels "soli/formations/src/scenarios/models"
+	scenarioServices "soli/formations/src/scenarios/services"
+	"soli/formations/src/utils"
+)
+
+// setupTracing installs a tracer provider exporting synchronously to memory,
+// and restores the previous global provider afterwards.
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	previous := otel.GetTracerProvider()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := observability.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	observability.InstallTracerProvider(tp)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+	return exporter
+}
+
+func spanNamed(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range exporter.GetSpans() {
+		if s.Name == name {
+			return s
+		}
+	}
+	var names []string
+	for _, s := range exporter.GetSpans() {
+		names = append(names, s.Name)
+	}
+	t.Fatalf("no span named %q; recorded: %v", name, names)
+	return tracetest.SpanStub{}
+}
+
+func attr(s tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracing_GinMiddlewareContinuesCallerTrace(t *testing.T) {
+	exporter := setupTracing(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(observability.TracingMiddleware())
+	var handlerSpan trace.SpanContext
+	r.GET("/api/v1/things/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/things/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := spanNamed(t, exporter, "GET /api/v1/things/:id")
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind: expected server, got %v", span.SpanKind)
+	}
+	if got := span.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("trace id: expected the caller's %s, got %s", traceID, got)
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("handlers must see the request span in c.Request.Context()")
+	}
+	if v, _ := attr(span, "http.response.status_code"); v.AsInt64() != 500 {
+		t.Errorf("http.response.status_code: expected 500, got %v", v.Emit())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("a 5xx must mark the span as an error, got %v", span.Status.Code)
+	}
+}
+
+func TestTracing_ExternalAPIRequestIsAChildSpanAndPropagates(t *testing.T) {
+	exporter := setupTracing(t)
+
+	var receivedTraceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedTraceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	ctx, parent := observability.StartSpan(context.Background(), "launch")
+	opts := utils.DefaultHTTPClientOptions()
+	utils.ApplyOptions(&opts, utils.WithContext(ctx))
+	if _, err := utils.MakeExternalAPIRequest("Terminal Trainer", "POST", server.URL+"/1.0/sessions?token=secret", nil, opts); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	parent.End()
+
+	span := spanNamed(t, exporter, "Terminal Trainer POST")
+	if span.SpanKind != trace.SpanKindClient {
+		t.Errorf("span kind: expected client, got %v", span.SpanKind)
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("the HTTP span must be a child of the caller's span")
+	}
+	if v, _ := attr(span, "url.path"); v.AsString() != "/1.0/sessions" {
+		t.Errorf("url.path: expected /1.0/sessions without the query, got %q", v.AsString())
+	}
+	if receivedTraceparent == "" {
+		t.Errorf("tt-backend must receive a traceparent header")
+	}
+}
+
+func TestTracing_ExecInContainer(t *testing.T) {
+	exporter := setupTracing(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"exit_code": 3, "stdout": "", "stderr": "nope"})
+	}))
+	defer server.Close()
+
+	svc := scenarioServices.NewVerificationServiceWithConfig(server.URL, "key")
+	exitCode, _, _, err := svc.ExecInContainer("term-1", []string{"false"}, nil, 5)
+	if err != nil || exitCode != 3 {
+		t.Fatalf("exec: exit %d, err %v", exitCode, err)
+	}
+
+	exec := spanNamed(t, exporter, "tt.exec")
+	if v, _ := attr(exec, "terminal.session_id"); v.AsString() != "term-1" {
+		t.Errorf("terminal.session_id: expected term-1, got %q", v.AsString())
+	}
+	if v, _ := attr(exec, "exec.exit_code"); v.AsInt64() != 3 {
+		t.Errorf("exec.exit_code: expected 3, got %v", v.Emit())
+	}
+	httpSpan := spanNamed(t, exporter, "Terminal Trainer POST")
+	if httpSpan.Parent.SpanID() != exec.SpanContext.SpanID() {
+		t.Errorf("the tt-backend call must nest under tt.exec")
+	}
+}
+
+func TestTracing_GormStatementsUnderATracedContext(t *testing.T) {
+	exporter := setupTracing(t)
+	db := setupMetricsDB(t)
+	if err := observability.RegisterGormTracing(db); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	// Outside any trace: nothing is recorded.
+	var count int64
+	db.Model(&scenarioModels.ScenarioSession{}).Count(&count)
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("untraced statements must not start traces, got %d spans", n)
+	}
+
+	ctx, parent := observability.StartSpan(context.Background(), "request")
+	var sessions []scenarioModels.ScenarioSession
+	db.WithContext(ctx).Where("user_id = ?", "secret-user").Find(&sessions)
+	parent.End()
+
+	span := spanNamed(t, exporter, "gorm.query scenario_sessions")
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("the statement span must be a child of the request span")
+	}
+	if v, _ := attr(span, "db.query.text"); v.AsString() == "" {
+		t.Errorf("db.query.text must carry the SQL")
+	} else if strings.Contains(v.AsString(), "secret-user") {
+		t.Errorf("bound values must not be recorded: %q", v.AsString())
+	}
+}
+
+type tracedHook struct{ err error }
+
+func (h *tracedHook) GetName() string                { return "tracing-test-hook" }
+func (h *tracedHook) GetEntityName() string          { return "TracingTestEntity" }
+func (h *tracedHook) GetHookTypes() []hooks.HookType { return []hooks.HookType{hooks.AfterUpdate} }
+func (h *tracedHook) IsEnabled() bool                { return true }
+func (h *tracedHook) GetPriority() int               { return 50 }
+func (h *tracedHook) Execute(ctx *hooks.HookContext) error {
+	return h.err
+}
+
+func TestTracing_HookSpans(t *testing.T) {
+	exporter := setupTracing(t)
+	hook := &tracedHook{err: errors.New("hook failed")}
+	if err := hooks.GlobalHookRegistry.RegisterHook(hook); err != nil {
+		t.Fatalf("register hook: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hooks.GlobalHookRegistry.UnregisterHook(hook.GetName())
+		hooks.GlobalHookRegistry.ClearErrors()
+	})
+
+	ctx, parent := observability.StartSpan(context.Background(), "edit")
+	_ = hooks.GlobalHookRegistry.ExecuteHooks(&hooks.HookContext{
+		HookType: hooks.AfterUpdate, EntityName: "TracingTestEntity", Context: ctx,
+	})
+	parent.End()
+
+	span := spanNamed(t, exporter, "hook tracing-test-hook")
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("the hook span must be a child of HookContext.Context's span")
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("a failing hook must mark its span as an error")
+	}
+}
@@ -0,0 +1,366 @@
+package scenarios_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+const deadlineTrainerID = "trainer-deadline"
+
+// deadlineFixture creates a one-step scenario, a group owned by
+// deadlineTrainerID holding userID, and an assignment of the scenario to the
+// group opened a week ago with the given deadline and late policy.
+func deadlineFixture(t *testing.T, db *gorm.DB, userID string, deadline time.Time, policy string, penalty float64) (*models.Scenario, *models.ScenarioAssignment) {
+	t.Helper()
+
+	scenario := &models.Scenario{
+		Name:         "deadline-" + userID,
+		Title:        "Deadline Scenario",
+		InstanceType: "ubuntu:22.04",
+		CreatedByID:  deadlineTrainerID,
+	}
+	require.NoError(t, db.Create(scenario).Error)
+	require.NoError(t, db.Create(&models.ScenarioStep{
+		ScenarioID: scenario.ID, Order: 0, Title: "Only step", TextContent: "Do the task",
+	}).Error)
+
+	group := groupModels.ClassGroup{
+		Name: "deadline-group-" + userID, DisplayName: "Deadline Group",
+		OwnerUserID: deadlineTrainerID, IsActive: true,
+	}
+	require.NoError(t, db.Omit("Metadata").Create(&group).Error)
+	require.NoError(t, db.Omit("Metadata").Create(&groupModels.GroupMember{
+		GroupID: group.ID, UserID: userID, Role: "member", IsActive: true,
+	}).Error)
+
+	start := time.Now().Add(-7 * 24 * time.Hour)
+	assignment := &models.ScenarioAssignment{
+		ScenarioID:               scenario.ID,
+		GroupID:                  &group.ID,
+		Scope:                    "group",
+		CreatedByID:              deadlineTrainerID,
+		IsActive:                 true,
+		StartDate:                &start,
+		Deadline:                 &deadline,
+		LatePolicy:               policy,
+		LatePenaltyPercentPerDay: penalty,
+	}
+	require.NoError(t, db.Create(assignment).Error)
+	return scenario, assignment
+}
+
+func newDeadlineSessionService(db *gorm.DB) *services.ScenarioSessionService {
+	return services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{passed: true})
+}
+
+func TestValidateLatePolicy(t *testing.T) {
+	assert.NoError(t, services.ValidateLatePolicy(&models.ScenarioAssignment{}),
+		"assignments saved before late policies existed block")
+	assert.NoError(t, services.ValidateLatePolicy(&models.ScenarioAssignment{LatePolicy: models.LatePolicyFlag}))
+	assert.NoError(t, services.ValidateLatePolicy(&models.ScenarioAssignment{
+		LatePolicy: models.LatePolicyPenalty, LatePenaltyPercentPerDay: 10,
+	}))
+	assert.Error(t, services.ValidateLatePolicy(&models.ScenarioAssignment{LatePolicy: models.LatePolicyPenalty}),
+		"a penalty policy needs a percentage")
+	assert.Error(t, services.ValidateLatePolicy(&models.ScenarioAssignment{
+		LatePolicy: models.LatePolicyPenalty, LatePenaltyPercentPerDay: 150,
+	}))
+	assert.Error(t, services.ValidateLatePolicy(&models.ScenarioAssignment{LatePolicy: "forgive"}))
+}
+
+func TestLatePenaltyPercent(t *testing.T) {
+	deadline := time.Now()
+	a := &models.ScenarioAssignment{LatePolicy: models.LatePolicyPenalty, LatePenaltyPercentPerDay: 15}
+
+	assert.Zero(t, services.LatePenaltyPercent(a, deadline, deadline.Add(-time.Minute)))
+	assert.Equal(t, 15.0, services.LatePenaltyPercent(a, deadline, deadline.Add(time.Minute)),
+		"any part of a day late counts as a day")
+	assert.Equal(t, 30.0, services.LatePenaltyPercent(a, deadline, deadline.Add(36*time.Hour)))
+	assert.Equal(t, 100.0, services.LatePenaltyPercent(a, deadline, deadline.Add(30*24*time.Hour)),
+		"a grade cannot go below zero")
+	assert.Zero(t, services.LatePenaltyPercent(&models.ScenarioAssignment{LatePolicy: models.LatePolicyFlag},
+		deadline, deadline.Add(48*time.Hour)))
+}
+
+func TestAssignmentDeadline_StartBeforeStartDateRefused(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-early"
+	scenario, assignment := deadlineFixture(t, db, userID, time.Now().Add(48*time.Hour), models.LatePolicyBlock, 0)
+	future := time.Now().Add(24 * time.Hour)
+	require.NoError(t, db.Model(assignment).Update("start_date", future).Error)
+	svc := newDeadlineSessionService(db)
+
+	assert.ErrorIs(t, svc.CheckAssignmentWindow(userID, scenario.ID), services.ErrAssignmentNotStarted)
+	_, err := svc.StartScenario(userID, scenario.ID, "deadline-term-early")
+	assert.ErrorIs(t, err, services.ErrAssignmentNotStarted)
+}
+
+func TestAssignmentDeadline_BlockPolicyRefusesLateStart(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-block"
+	scenario, _ := deadlineFixture(t, db, userID, time.Now().Add(-time.Hour), models.LatePolicyBlock, 0)
+	svc := newDeadlineSessionService(db)
+
+	assert.ErrorIs(t, svc.CheckAssignmentWindow(userID, scenario.ID), services.ErrAssignmentClosed)
+	_, err := svc.StartScenario(userID, scenario.ID, "deadline-term-block")
+	assert.ErrorIs(t, err, services.ErrAssignmentClosed)
+}
+
+func TestAssignmentDeadline_OpenAssignmentRecordedOnSession(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-open"
+	scenario, assignment := deadlineFixture(t, db, userID, time.Now().Add(48*time.Hour), models.LatePolicyBlock, 0)
+	svc := newDeadlineSessionService(db)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "deadline-term-open")
+	require.NoError(t, err)
+	require.NotNil(t, session.AssignmentID)
+	assert.Equal(t, assignment.ID, *session.AssignmentID)
+	assert.False(t, session.ExamMode)
+
+	_, err = svc.VerifyCurrentStep(session.ID)
+	require.NoError(t, err)
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	assert.False(t, stored.SubmittedLate)
+	require.NotNil(t, stored.Grade)
+	assert.InDelta(t, 100.0, *stored.Grade, 0.01)
+}
+
+func TestAssignmentDeadline_PublicScenarioStaysOpen(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-public"
+	scenario, _ := deadlineFixture(t, db, userID, time.Now().Add(-time.Hour), models.LatePolicyBlock, 0)
+	require.NoError(t, db.Model(scenario).Update("is_public", true).Error)
+	svc := newDeadlineSessionService(db)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "deadline-term-public")
+	require.NoError(t, err)
+	assert.Nil(t, session.AssignmentID, "a session past the deadline is not the assignment's")
+}
+
+func TestAssignmentDeadline_PenaltyPolicyReducesGrade(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-penalty"
+	// 60 hours late: three started days at 10% each.
+	scenario, _ := deadlineFixture(t, db, userID, time.Now().Add(-60*time.Hour), models.LatePolicyPenalty, 10)
+	svc := newDeadlineSessionService(db)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "deadline-term-penalty")
+	require.NoError(t, err)
+	_, err = svc.VerifyCurrentStep(session.ID)
+	require.NoError(t, err)
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	assert.True(t, stored.SubmittedLate)
+	assert.Equal(t, 30.0, stored.LatePenaltyPercent)
+	require.NotNil(t, stored.GradeBeforePenalty)
+	assert.InDelta(t, 100.0, *stored.GradeBeforePenalty, 0.01)
+	require.NotNil(t, stored.Grade)
+	assert.InDelta(t, 70.0, *stored.Grade, 0.01)
+}
+
+func TestAssignmentDeadline_FlagPolicyKeepsGrade(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-flag"
+	scenario, _ := deadlineFixture(t, db, userID, time.Now().Add(-time.Hour), models.LatePolicyFlag, 0)
+	svc := newDeadlineSessionService(db)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "deadline-term-flag")
+	require.NoError(t, err)
+	_, err = svc.VerifyCurrentStep(session.ID)
+	require.NoError(t, err)
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.True(t, stored.SubmittedLate)
+	assert.Zero(t, stored.LatePenaltyPercent)
+	assert.Nil(t, stored.GradeBeforePenalty)
+	require.NotNil(t, stored.Grade)
+	assert.InDelta(t, 100.0, *stored.Grade, 0.01)
+}
+
+func TestAssignmentExtension_ReopensAssignmentForLearner(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-extended"
+	scenario, assignment := deadlineFixture(t, db, userID, time.Now().Add(-time.Hour), models.LatePolicyBlock, 0)
+	svc := newDeadlineSessionService(db)
+	extensions := services.NewAssignmentExtensionService(db)
+
+	extension, err := extensions.Grant(assignment.ID, userID, time.Now().Add(24*time.Hour), "Medical leave", deadlineTrainerID, false)
+	require.NoError(t, err)
+	assert.Equal(t, deadlineTrainerID, extension.GrantedByID)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "deadline-term-extended")
+	require.NoError(t, err)
+	require.NotNil(t, session.AssignmentID)
+	_, err = svc.VerifyCurrentStep(session.ID)
+	require.NoError(t, err)
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.False(t, stored.SubmittedLate, "the learner's own deadline has not passed")
+
+	listed, err := extensions.List(assignment.ID, deadlineTrainerID, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "Medical leave", listed[0].Reason)
+}
+
+func TestAssignmentExtension_RegrantReplacesAndRevokeRestores(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-regrant"
+	scenario, assignment := deadlineFixture(t, db, userID, time.Now().Add(-time.Hour), models.LatePolicyBlock, 0)
+	svc := newDeadlineSessionService(db)
+	extensions := services.NewAssignmentExtensionService(db)
+
+	_, err := extensions.Grant(assignment.ID, userID, time.Now().Add(24*time.Hour), "", deadlineTrainerID, false)
+	require.NoError(t, err)
+	_, err = extensions.Grant(assignment.ID, userID, time.Now().Add(-time.Minute), "Shortened", deadlineTrainerID, false)
+	require.NoError(t, err)
+
+	listed, err := extensions.List(assignment.ID, deadlineTrainerID, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1, "a learner has one extension at most")
+	assert.ErrorIs(t, svc.CheckAssignmentWindow(userID, scenario.ID), services.ErrAssignmentClosed,
+		"an extension replaces the deadline, even with an earlier one")
+
+	require.NoError(t, extensions.Revoke(assignment.ID, userID, deadlineTrainerID, false))
+	assert.ErrorIs(t, extensions.Revoke(assignment.ID, userID, deadlineTrainerID, false), services.ErrExtensionNotFound)
+}
+
+func TestAssignmentExtension_OnlyManagersGrant(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "deadline-student-self"
+	_, assignment := deadlineFixture(t, db, userID, time.Now().Add(-time.Hour), models.LatePolicyBlock, 0)
+	extensions := services.NewAssignmentExtensionService(db)
+
+	_, err := extensions.Grant(assignment.ID, userID, time.Now().Add(24*time.Hour), "", userID, false)
+	assert.ErrorIs(t, err, services.ErrExtensionNotAllowed, "learners cannot extend their own deadline")
+	_, err = extensions.List(assignment.ID, userID, false)
+	assert.ErrorIs(t, err, services.ErrExtensionNotAllowed)
+
+	_, err = extensions.Grant(assignment.ID, userID, time.Now().Add(24*time.Hour), "", "platform-admin", true)
+	assert.NoError(t, err, "admins manage every assignment")
+}
+
+// recordingEmailService captures the templated emails the reminder job sends.
+type recordingEmailService struct {
+	sent []recordedEmail
+	err  error
+}
+
+type recordedEmail struct {
+	to, template string
+	vars         map[string]interface{}
+}
+
+func (r *recordingEmailService) SendEmail(to, subject, body string) error { return nil }
+func (r *recordingEmailService) SendEmailWithAttachment(to, subject, body, attachmentName, attachmentBase64 string) error {
+	return nil
+}
+func (r *recordingEmailService) SendPasswordResetEmail(to, resetToken, resetURL string) error {
+	return nil
+}
+func (r *recordingEmailService) SendTemplatedEmail(to, templateName string, variables map[string]interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, recordedEmail{to: to, template: templateName, vars: variables})
+	return nil
+}
+
+func stubReminderRecipients(t *testing.T) {
+	t.Helper()
+	previous := services.LookupReminderRecipient
+	services.LookupReminderRecipient = func(userID string) (*casdoorsdk.User, error) {
+		return &casdoorsdk.User{Id: userID, DisplayName: "Learner " + userID, Email: userID + "@example.com"}, nil
+	}
+	t.Cleanup(func() { services.LookupReminderRecipient = previous })
+}
+
+func TestAssignmentReminders_SentOncePerDeadline(t *testing.T) {
+	db := freshTestDB(t)
+	stubReminderRecipients(t)
+	userID := "deadline-student-remind"
+	_, assignment := deadlineFixture(t, db, userID, time.Now().Add(10*time.Hour), models.LatePolicyFlag, 0)
+	require.NoError(t, db.Model(assignment).Update("reminder_hours_before", 24).Error)
+	email := &recordingEmailService{}
+	reminders := services.NewAssignmentReminderService(db, email)
+
+	sent, err := reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, userID+"@example.com", email.sent[0].to)
+	assert.Equal(t, services.AssignmentReminderTemplate, email.sent[0].template)
+	assert.Equal(t, "Deadline Scenario", email.sent[0].vars["ScenarioTitle"])
+	assert.Equal(t, "10", email.sent[0].vars["HoursLeft"])
+
+	sent, err = reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, sent, "a deadline is announced once")
+
+	// Moving the learner's deadline announces the new one.
+	_, err = services.NewAssignmentExtensionService(db).
+		Grant(assignment.ID, userID, time.Now().Add(20*time.Hour), "", deadlineTrainerID, false)
+	require.NoError(t, err)
+	sent, err = reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestAssignmentReminders_SkipsOutsideWindowAndCompleted(t *testing.T) {
+	db := freshTestDB(t)
+	stubReminderRecipients(t)
+	userID := "deadline-student-quiet"
+	scenario, assignment := deadlineFixture(t, db, userID, time.Now().Add(48*time.Hour), models.LatePolicyBlock, 0)
+	require.NoError(t, db.Model(assignment).Update("reminder_hours_before", 24).Error)
+	email := &recordingEmailService{}
+	reminders := services.NewAssignmentReminderService(db, email)
+
+	sent, err := reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, sent, "the deadline is further away than the reminder")
+
+	require.NoError(t, db.Model(assignment).Update("deadline", time.Now().Add(2*time.Hour)).Error)
+	require.NoError(t, db.Create(&models.ScenarioSession{
+		ScenarioID: scenario.ID, UserID: userID, Status: "completed", StartedAt: time.Now(),
+	}).Error)
+	sent, err = reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, sent, "learners who completed the scenario are not reminded")
+	assert.Empty(t, email.sent)
+}
+
+func TestAssignmentReminders_FailedSendRetried(t *testing.T) {
+	db := freshTestDB(t)
+	stubReminderRecipients(t)
+	userID := "deadline-student-retry"
+	_, assignment := deadlineFixture(t, db, userID, time.Now().Add(5*time.Hour), models.LatePolicyBlock, 0)
+	require.NoError(t, db.Model(assignment).Update("reminder_hours_before", 12).Error)
+	email := &recordingEmailService{err: errors.New("smtp down")}
+	reminders := services.NewAssignmentReminderService(db, email)
+
+	sent, err := reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	email.err = nil
+	sent, err = reminders.SendDueReminders(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent, "a reminder that failed to send is sent on the next run")
+}
@@ -159,6 +159,10 @@ func (m *capturingTTService) StartComposedSession(userID string, input ttDto.Cre
 	return &ttDto.TerminalSessionResponse{SessionID: "terminal-" + userID, Status: "running"}, nil
 }
 
+func (m *capturingTTService) StartComposedTopology(string, []ttDto.CreateComposedSessionInput, any) ([]*ttDto.TerminalSessionResponse, error) {
+	return nil, nil
+}
+
 // --- Test ---
 
 // TestBulkStartScenario_PassesOrganizationID verifies that when a scenario has an
@@ -272,3 +276,38 @@ func TestBulkStartScenario_PassesOrganizationID(t *testing.T) {
 // BuildComplete satisfies TerminalTrainerService. These tests never exercise
 // the provisioning window, so the stub reports success without recording.
 func (m *capturingTTService) BuildComplete(sessionID string) error { return nil }
+
+// The snapshot methods satisfy TerminalTrainerService; these tests never
+// snapshot a terminal.
+func (m *capturingTTService) ListSnapshots(string) (*ttDto.TerminalSnapshotsResponse, error) {
+	return nil, nil
+}
+func (m *capturingTTService) CreateSnapshot(string, string, string, *int) (*ttModels.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *capturingTTService) ReplaceSnapshot(string, string, string, *int) (*ttModels.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *capturingTTService) RestoreSnapshot(string, string) error { return nil }
+func (m *capturingTTService) DeleteSnapshot(string, string) error  { return nil }
+
+// GetSessionRecording satisfies TerminalTrainerService; these tests never
+// replay a recording.
+func (m *capturingTTService) GetSessionRecording(string, ttDto.RecordingReplayOptions) ([]byte, error) {
+	return nil, nil
+}
+
+// The team methods satisfy TerminalTrainerService; these tests never share
+// a terminal.
+func (m *capturingTTService) GetTerminalTeam(string) (*ttDto.TerminalTeamResponse, error) { return nil, nil }
+func (m *capturingTTService) ShareTerminal(string, ttDto.ShareTerminalInput, string) (*ttDto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *capturingTTService) SetTerminalTeamRole(string, string, string) (*ttDto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *capturingTTService) RemoveTerminalTeamMember(string, string) (*ttDto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *capturingTTService) GetTerminalTeamRole(string, string) (string, error)    { return "", nil }
+func (m *capturingTTService) CanManageTerminalTeam(string, string) (bool, error) { return false, nil }
@@ -177,6 +177,10 @@ func (m *mockTTService) StartComposedSession(userID string, _ ttDto.CreateCompos
 	return &ttDto.TerminalSessionResponse{SessionID: "terminal-" + userID, Status: "running"}, nil
 }
 
+func (m *mockTTService) StartComposedTopology(string, []ttDto.CreateComposedSessionInput, any) ([]*ttDto.TerminalSessionResponse, error) {
+	return nil, nil
+}
+
 // --- Tests ---
 
 // TestBulkStartScenario_ReplacesExistingActiveSessions verifies that when members
@@ -329,3 +333,38 @@ func TestBulkStartScenario_ReplacesInProgressSessions(t *testing.T) {
 // BuildComplete satisfies TerminalTrainerService. These tests never exercise
 // the provisioning window, so the stub reports success without recording.
 func (m *mockTTService) BuildComplete(sessionID string) error { return nil }
+
+// The snapshot methods satisfy TerminalTrainerService; these tests never
+// snapshot a terminal.
+func (m *mockTTService) ListSnapshots(string) (*ttDto.TerminalSnapshotsResponse, error) {
+	return nil, nil
+}
+func (m *mockTTService) CreateSnapshot(string, string, string, *int) (*ttModels.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *mockTTService) ReplaceSnapshot(string, string, string, *int) (*ttModels.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *mockTTService) RestoreSnapshot(string, string) error { return nil }
+func (m *mockTTService) DeleteSnapshot(string, string) error  { return nil }
+
+// GetSessionRecording satisfies TerminalTrainerService; these tests never
+// replay a recording.
+func (m *mockTTService) GetSessionRecording(string, ttDto.RecordingReplayOptions) ([]byte, error) {
+	return nil, nil
+}
+
+// The team methods satisfy TerminalTrainerService; these tests never share
+// a terminal.
+func (m *mockTTService) GetTerminalTeam(string) (*ttDto.TerminalTeamResponse, error) { return nil, nil }
+func (m *mockTTService) ShareTerminal(string, ttDto.ShareTerminalInput, string) (*ttDto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTTService) SetTerminalTeamRole(string, string, string) (*ttDto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTTService) RemoveTerminalTeamMember(string, string) (*ttDto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTTService) GetTerminalTeamRole(string, string) (string, error) { return "", nil }
+func (m *mockTTService) CanManageTerminalTeam(string, string) (bool, error) { return false, nil }
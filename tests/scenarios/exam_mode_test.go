@@ -0,0 +1,286 @@
+package scenarios_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/entityManagement/hooks"
+	groupModels "soli/formations/src/groups/models"
+	scenarioHooks "soli/formations/src/scenarios/hooks"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// examFixture creates a two-step scenario, a group holding userID, and an exam
+// assignment of the scenario to the group opened an hour ago.
+func examFixture(t *testing.T, db *gorm.DB, userID string, timeLimit int) (*models.Scenario, *models.ScenarioAssignment) {
+	t.Helper()
+
+	scenario := &models.Scenario{
+		Name:         "exam-" + userID,
+		Title:        "Exam Scenario",
+		InstanceType: "ubuntu:22.04",
+		CreatedByID:  "trainer-exam",
+	}
+	require.NoError(t, db.Create(scenario).Error)
+	for i := 0; i < 2; i++ {
+		step := models.ScenarioStep{
+			ScenarioID:  scenario.ID,
+			Order:       i,
+			Title:       "Exam step",
+			TextContent: "Do the task",
+			HintContent: "The answer is in /etc",
+		}
+		require.NoError(t, db.Create(&step).Error)
+		require.NoError(t, db.Create(&models.ScenarioStepHint{StepID: step.ID, Level: 1, Content: "Look in /etc"}).Error)
+	}
+
+	group := groupModels.ClassGroup{
+		Name: "exam-group-" + userID, DisplayName: "Exam Group",
+		OwnerUserID: "trainer-exam", IsActive: true,
+	}
+	require.NoError(t, db.Omit("Metadata").Create(&group).Error)
+	require.NoError(t, db.Omit("Metadata").Create(&groupModels.GroupMember{
+		GroupID: group.ID, UserID: userID, Role: "member", IsActive: true,
+	}).Error)
+
+	start := time.Now().Add(-1 * time.Hour)
+	assignment := &models.ScenarioAssignment{
+		ScenarioID:       scenario.ID,
+		GroupID:          &group.ID,
+		Scope:            "group",
+		CreatedByID:      "trainer-exam",
+		IsActive:         true,
+		StartDate:        &start,
+		ExamMode:         true,
+		TimeLimitMinutes: timeLimit,
+	}
+	require.NoError(t, db.Create(assignment).Error)
+
+	return scenario, assignment
+}
+
+func newExamSessionService(db *gorm.DB, tracker *terminalStopTracker) *services.ScenarioSessionService {
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{passed: true})
+	svc.SetTerminalStopFunc(tracker.StopFunc())
+	return svc
+}
+
+// expireSession moves an exam session's cut-off into the past.
+func expireSession(t *testing.T, db *gorm.DB, session *models.ScenarioSession) {
+	t.Helper()
+	past := time.Now().Add(-1 * time.Minute)
+	require.NoError(t, db.Model(session).Update("expires_at", past).Error)
+}
+
+func TestValidateExamAssignment(t *testing.T) {
+	start := time.Now()
+	before := start.Add(-time.Hour)
+
+	assert.NoError(t, services.ValidateExamAssignment(&models.ScenarioAssignment{}),
+		"an ordinary assignment needs no exam settings")
+	assert.Error(t, services.ValidateExamAssignment(&models.ScenarioAssignment{ExamMode: true, StartDate: &start}),
+		"an exam without a time limit would never be finalised")
+	assert.Error(t, services.ValidateExamAssignment(&models.ScenarioAssignment{ExamMode: true, TimeLimitMinutes: 60}),
+		"an exam without a start date has no window")
+	assert.Error(t, services.ValidateExamAssignment(&models.ScenarioAssignment{
+		ExamMode: true, TimeLimitMinutes: 60, StartDate: &start, StartWindowEnd: &before,
+	}))
+	assert.NoError(t, services.ValidateExamAssignment(&models.ScenarioAssignment{
+		ExamMode: true, TimeLimitMinutes: 60, StartDate: &start,
+	}))
+}
+
+func TestExamMode_StartScenario_SetsExpiry(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-start"
+	scenario, assignment := examFixture(t, db, userID, 90)
+	svc := newExamSessionService(db, &terminalStopTracker{})
+
+	session, err := svc.StartScenario(userID, scenario.ID, "exam-term-start")
+	require.NoError(t, err)
+
+	assert.True(t, session.ExamMode)
+	require.NotNil(t, session.AssignmentID)
+	assert.Equal(t, assignment.ID, *session.AssignmentID)
+	require.NotNil(t, session.ExpiresAt)
+	assert.WithinDuration(t, session.StartedAt.Add(90*time.Minute), *session.ExpiresAt, time.Second)
+}
+
+func TestExamMode_StartScenario_ExpiryCappedAtDeadline(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-deadline"
+	scenario, assignment := examFixture(t, db, userID, 90)
+	deadline := time.Now().Add(30 * time.Minute)
+	require.NoError(t, db.Model(assignment).Update("deadline", deadline).Error)
+	svc := newExamSessionService(db, &terminalStopTracker{})
+
+	session, err := svc.StartScenario(userID, scenario.ID, "exam-term-deadline")
+	require.NoError(t, err)
+
+	require.NotNil(t, session.ExpiresAt)
+	assert.WithinDuration(t, deadline, *session.ExpiresAt, time.Second,
+		"an exam must not run past the assignment deadline")
+}
+
+func TestExamMode_StartScenario_OutsideWindow(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-window"
+	scenario, assignment := examFixture(t, db, userID, 60)
+	windowEnd := time.Now().Add(-time.Minute)
+	require.NoError(t, db.Model(assignment).Update("start_window_end", windowEnd).Error)
+	svc := newExamSessionService(db, &terminalStopTracker{})
+
+	assert.ErrorIs(t, svc.CheckExamEligibility(userID, scenario.ID), services.ErrExamNotOpen)
+	_, err := svc.StartScenario(userID, scenario.ID, "exam-term-window")
+	assert.ErrorIs(t, err, services.ErrExamNotOpen)
+}
+
+func TestExamMode_SubmitExam_GradesStopsAndForbidsRetry(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-submit"
+	scenario, _ := examFixture(t, db, userID, 60)
+	tracker := &terminalStopTracker{}
+	svc := newExamSessionService(db, tracker)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "exam-term-submit")
+	require.NoError(t, err)
+	_, err = svc.VerifyCurrentStep(session.ID)
+	require.NoError(t, err)
+
+	result, err := svc.SubmitExam(session.ID)
+	require.NoError(t, err)
+	require.NotNil(t, result.Grade)
+	assert.InDelta(t, 50.0, *result.Grade, 0.01, "one of two steps completed")
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	assert.False(t, stored.TimedOut)
+	assert.Equal(t, []string{"exam-term-submit"}, tracker.CalledWith())
+
+	assert.ErrorIs(t, svc.CheckExamEligibility(userID, scenario.ID), services.ErrExamAlreadyTaken)
+	_, err = svc.StartScenario(userID, scenario.ID, "exam-term-retry")
+	assert.ErrorIs(t, err, services.ErrExamAlreadyTaken)
+}
+
+func TestExamMode_AbandonSubmitsTheExam(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-abandon"
+	scenario, _ := examFixture(t, db, userID, 60)
+	svc := newExamSessionService(db, &terminalStopTracker{})
+
+	session, err := svc.StartScenario(userID, scenario.ID, "exam-term-abandon")
+	require.NoError(t, err)
+	require.NoError(t, svc.AbandonSession(session.ID))
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	require.NotNil(t, stored.Grade)
+	assert.InDelta(t, 0.0, *stored.Grade, 0.01)
+
+	_, err = svc.StartScenario(userID, scenario.ID, "exam-term-abandon-retry")
+	assert.ErrorIs(t, err, services.ErrExamAlreadyTaken)
+}
+
+func TestExamMode_HintsWithheld(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-hints"
+	scenario, _ := examFixture(t, db, userID, 60)
+	svc := newExamSessionService(db, &terminalStopTracker{})
+
+	session, err := svc.StartScenario(userID, scenario.ID, "exam-term-hints")
+	require.NoError(t, err)
+
+	step, err := svc.GetCurrentStep(session.ID)
+	require.NoError(t, err)
+	assert.Empty(t, step.Hint)
+	assert.Zero(t, step.HintsTotalCount)
+	assert.True(t, step.ExamMode)
+	assert.NotNil(t, step.ExpiresAt)
+
+	_, err = svc.RevealHint(session.ID, 0, 1)
+	assert.ErrorIs(t, err, services.ErrHintsDisabled)
+}
+
+func TestExamMode_ActionAfterTimeLimit_FinalizesSession(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "exam-student-late"
+	scenario, _ := examFixture(t, db, userID, 60)
+	tracker := &terminalStopTracker{}
+	svc := newExamSessionService(db, tracker)
+
+	session, err := svc.StartScenario(userID, scenario.ID, "exam-term-late")
+	require.NoError(t, err)
+	expireSession(t, db, session)
+
+	_, err = svc.VerifyCurrentStep(session.ID)
+	assert.ErrorIs(t, err, services.ErrExamTimeUp)
+	assert.True(t, errors.Is(err, services.ErrSessionNotActive),
+		"controllers answer 409 for a session whose time is up")
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", session.ID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	assert.True(t, stored.TimedOut)
+	require.NotNil(t, stored.Grade)
+	assert.Equal(t, []string{"exam-term-late"}, tracker.CalledWith())
+}
+
+func TestExamMode_FinalizeExpiredExams(t *testing.T) {
+	db := freshTestDB(t)
+	tracker := &terminalStopTracker{}
+	svc := newExamSessionService(db, tracker)
+
+	expiredScenario, _ := examFixture(t, db, "exam-student-expired", 60)
+	expired, err := svc.StartScenario("exam-student-expired", expiredScenario.ID, "exam-term-expired")
+	require.NoError(t, err)
+	expireSession(t, db, expired)
+
+	runningScenario, _ := examFixture(t, db, "exam-student-running", 60)
+	running, err := svc.StartScenario("exam-student-running", runningScenario.ID, "exam-term-running")
+	require.NoError(t, err)
+
+	count, err := svc.FinalizeExpiredExams()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var stored models.ScenarioSession
+	require.NoError(t, db.First(&stored, "id = ?", expired.ID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	assert.True(t, stored.TimedOut)
+	var stillRunning models.ScenarioSession
+	require.NoError(t, db.First(&stillRunning, "id = ?", running.ID).Error)
+	assert.Equal(t, running.Status, stillRunning.Status, "an exam still inside its time limit keeps running")
+	assert.Equal(t, []string{"exam-term-expired"}, tracker.CalledWith())
+
+	count, err = svc.FinalizeExpiredExams()
+	require.NoError(t, err)
+	assert.Zero(t, count, "a finalised exam is not graded twice")
+}
+
+func TestScenarioAssignmentHook_RejectsIncompleteExamUpdate(t *testing.T) {
+	db := freshTestDB(t)
+	hook := scenarioHooks.NewScenarioAssignmentAuthorizationHook(db)
+
+	assignment := &models.ScenarioAssignment{Scope: "group", IsActive: true}
+	ctx := &hooks.HookContext{
+		EntityName: "ScenarioAssignment",
+		HookType:   hooks.BeforeUpdate,
+		OldEntity:  assignment,
+		NewEntity:  map[string]any{"exam_mode": true},
+		UserID:     "admin-exam",
+		UserRoles:  []string{"administrator"},
+	}
+	assert.Error(t, hook.Execute(ctx), "turning on exam mode without a start date and time limit must be refused, even for an admin")
+
+	start := time.Now()
+	ctx.NewEntity = map[string]any{"exam_mode": true, "start_date": start, "time_limit_minutes": 45}
+	assert.NoError(t, hook.Execute(ctx))
+}
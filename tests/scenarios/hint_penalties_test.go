@@ -0,0 +1,197 @@
+package scenarios_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// hint_penalties_test.go — hints with a PenaltyPercent cost the learner a
+// share of their step's credit once revealed. The grade applies it, the
+// learner sees it before and after revealing, and teachers see it per step.
+
+// addPricedHints gives a step one hint per penalty, levels from 1.
+func addPricedHints(t *testing.T, db *gorm.DB, stepID uuid.UUID, penalties ...float64) {
+	t.Helper()
+	for i, penalty := range penalties {
+		require.NoError(t, db.Create(&models.ScenarioStepHint{
+			StepID: stepID, Level: i + 1, Content: "hint", PenaltyPercent: penalty,
+		}).Error)
+	}
+}
+
+func stepByOrder(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, order int) models.ScenarioStep {
+	t.Helper()
+	var step models.ScenarioStep
+	require.NoError(t, db.Where("scenario_id = ? AND \"order\" = ?", scenarioID, order).First(&step).Error)
+	return step
+}
+
+func TestComputeWeightedGradeFromLoaded_HintPenalties(t *testing.T) {
+	steps := []models.ScenarioStep{
+		{Order: 0, StepType: "terminal", Hints: []models.ScenarioStepHint{
+			{Level: 1, PenaltyPercent: 10}, {Level: 2, PenaltyPercent: 20},
+		}},
+		{Order: 1, StepType: "terminal"},
+	}
+
+	t.Run("revealed hints reduce the step's credit", func(t *testing.T) {
+		progress := []models.ScenarioStepProgress{
+			{StepOrder: 0, Status: "completed", HintsRevealed: 2},
+			{StepOrder: 1, Status: "completed"},
+		}
+		assert.InDelta(t, 85.0, services.ComputeWeightedGradeFromLoaded(steps, progress, nil), 0.001)
+	})
+
+	t.Run("unrevealed hints cost nothing", func(t *testing.T) {
+		progress := []models.ScenarioStepProgress{
+			{StepOrder: 0, Status: "completed", HintsRevealed: 1},
+			{StepOrder: 1, Status: "completed"},
+		}
+		assert.InDelta(t, 95.0, services.ComputeWeightedGradeFromLoaded(steps, progress, nil), 0.001)
+	})
+
+	t.Run("quiz scores are scaled too", func(t *testing.T) {
+		score := 0.5
+		quiz := []models.ScenarioStep{{Order: 0, StepType: "quiz", Hints: []models.ScenarioStepHint{{Level: 1, PenaltyPercent: 50}}}}
+		progress := []models.ScenarioStepProgress{{StepOrder: 0, Status: "completed", QuizScore: &score, HintsRevealed: 1}}
+		assert.InDelta(t, 25.0, services.ComputeWeightedGradeFromLoaded(quiz, progress, nil), 0.001)
+	})
+}
+
+func TestHintPenaltyPercent_CappedAtFullCredit(t *testing.T) {
+	hints := []models.ScenarioStepHint{{Level: 1, PenaltyPercent: 60}, {Level: 2, PenaltyPercent: 60}}
+	assert.Equal(t, 60.0, services.HintPenaltyPercent(hints, 1))
+	assert.Equal(t, 100.0, services.HintPenaltyPercent(hints, 2))
+	assert.Equal(t, 0.0, services.HintCreditFactor(hints, 2))
+}
+
+func TestRevealHint_ReportsPenaltyAndScoreOutlook(t *testing.T) {
+	db := setupTestDB(t)
+	session := flaggedTwoStepSession(t, db, "priced-hints", models.ScenarioStep{})
+	addPricedHints(t, db, stepByOrder(t, db, session.ScenarioID, 0).ID, 25, 50)
+
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: true}, &mockVerificationService{})
+
+	current, err := sessionSvc.GetCurrentStep(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{25, 50}, current.HintPenalties, "the learner sees the cost before revealing")
+
+	resp, err := sessionSvc.RevealHint(session.ID, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, resp.PenaltyPercent)
+	assert.InDelta(t, 75.0, resp.StepCreditRemaining, 0.001)
+	assert.InDelta(t, 87.5, resp.MaxAchievableGrade, 0.001)
+
+	resp, err = sessionSvc.RevealHint(session.ID, 0, 2)
+	require.NoError(t, err)
+	assert.InDelta(t, 25.0, resp.StepCreditRemaining, 0.001)
+	assert.InDelta(t, 62.5, resp.MaxAchievableGrade, 0.001)
+}
+
+func TestGetCurrentStep_FreeHintsOmitPenalties(t *testing.T) {
+	db := setupTestDB(t)
+	session := flaggedTwoStepSession(t, db, "free-hints", models.ScenarioStep{})
+	addPricedHints(t, db, stepByOrder(t, db, session.ScenarioID, 0).ID, 0, 0)
+
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: true}, &mockVerificationService{})
+	current, err := sessionSvc.GetCurrentStep(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, current.HintsTotalCount)
+	assert.Nil(t, current.HintPenalties)
+}
+
+func TestCompletedSession_GradeAppliesHintPenalties(t *testing.T) {
+	db := setupTestDB(t)
+	session := flaggedTwoStepSession(t, db, "graded-hints", models.ScenarioStep{})
+	addPricedHints(t, db, stepByOrder(t, db, session.ScenarioID, 0).ID, 30)
+
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: true}, &mockVerificationService{})
+	_, err := sessionSvc.RevealHint(session.ID, 0, 1)
+	require.NoError(t, err)
+	_, err = sessionSvc.SubmitFlag(session.ID, "flag{step-zero}")
+	require.NoError(t, err)
+	_, err = sessionSvc.SubmitFlag(session.ID, "flag{step-one}")
+	require.NoError(t, err)
+
+	var finished models.ScenarioSession
+	require.NoError(t, db.First(&finished, "id = ?", session.ID).Error)
+	assert.Equal(t, "completed", finished.Status)
+	require.NotNil(t, finished.Grade)
+	assert.InDelta(t, 85.0, *finished.Grade, 0.001)
+}
+
+func TestSeedAndExport_RoundTripHintPenalties(t *testing.T) {
+	db := setupTestDB(t)
+	scenario, _, err := services.NewScenarioSeedService(db).SeedScenario(dto.SeedScenarioInput{
+		Title:        "Seeded priced hints",
+		InstanceType: "ubuntu:22.04",
+		Steps: []dto.SeedStepInput{{
+			Title:         "Two hints",
+			HintContent:   "### Hint 1\nLook around\n### Hint 2\nRun ls",
+			HintPenalties: []float64{10, 40},
+		}},
+	}, "creator-1", nil)
+	require.NoError(t, err)
+
+	var hints []models.ScenarioStepHint
+	require.NoError(t, db.Where("step_id = ?", stepByOrder(t, db, scenario.ID, 0).ID).Order("level ASC").Find(&hints).Error)
+	require.Len(t, hints, 2)
+	assert.Equal(t, 10.0, hints[0].PenaltyPercent)
+	assert.Equal(t, 40.0, hints[1].PenaltyPercent)
+
+	exported, err := services.NewScenarioExportService(db).ExportAsJSON(scenario.ID)
+	require.NoError(t, err)
+	require.Len(t, exported.Steps, 1)
+	assert.Equal(t, []float64{10, 40}, exported.Steps[0].HintPenalties)
+}
+
+func TestGetScenarioAnalytics_StepHintCost(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "hint-analytics", "Easy", "Hard")
+	hard := stepByOrder(t, db, scenario.ID, 1)
+	addPricedHints(t, db, hard.ID, 20, 30)
+
+	group := createClassGroup(t, db, "hint-analytics-class", "teacher-hints", nil)
+	revealedOnHard := map[string]int{"student-a": 2, "student-b": 0, "student-c": 1}
+	for userID, revealed := range revealedOnHard {
+		addGroupMember(t, db, group.ID, userID, groupModels.GroupMemberRoleMember)
+		session := models.ScenarioSession{
+			ScenarioID: scenario.ID, UserID: userID, Status: "completed", StartedAt: db.NowFunc(),
+		}
+		require.NoError(t, db.Create(&session).Error)
+		seedStepProgress(t, db, session.ID, 0, "completed", 0, nil)
+		seedStepProgress(t, db, session.ID, 1, "completed", revealed, nil)
+	}
+	// A session that never got past the first step does not count for the second.
+	addGroupMember(t, db, group.ID, "student-d", groupModels.GroupMemberRoleMember)
+	stuck := models.ScenarioSession{ScenarioID: scenario.ID, UserID: "student-d", Status: "completed", StartedAt: db.NowFunc()}
+	require.NoError(t, db.Create(&stuck).Error)
+	seedStepProgress(t, db, stuck.ID, 0, "active", 0, nil)
+	seedStepProgress(t, db, stuck.ID, 1, "locked", 0, nil)
+
+	analytics, err := services.NewTeacherDashboardService(db, nil, nil).GetScenarioAnalytics(group.ID, scenario.ID)
+	require.NoError(t, err)
+	require.Len(t, analytics.Steps, 2)
+
+	easy := analytics.Steps[0]
+	assert.Equal(t, "Easy", easy.Title)
+	assert.Equal(t, int64(4), easy.SessionsReached)
+	assert.Zero(t, easy.AvgHintCostPercent)
+
+	stats := analytics.Steps[1]
+	assert.Equal(t, 2, stats.HintsCount)
+	assert.Equal(t, int64(3), stats.SessionsReached)
+	assert.InDelta(t, 1.0, stats.AvgHintsRevealed, 0.001)
+	assert.InDelta(t, 200.0/3, stats.HintUsageRate, 0.001)
+	// (50 + 0 + 20) / 3
+	assert.InDelta(t, 70.0/3, stats.AvgHintCostPercent, 0.001)
+}
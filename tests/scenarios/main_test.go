@@ -4,11 +4,13 @@ import (
 	"os"
 	"testing"
 
+	authModels "soli/formations/src/auth/models"
 	groupModels "soli/formations/src/groups/models"
 	orgModels "soli/formations/src/organizations/models"
 	paymentModels "soli/formations/src/payment/models"
 	"soli/formations/src/scenarios/models"
 	terminalModels "soli/formations/src/terminalTrainer/models"
+	webhookModels "soli/formations/src/webhooks/models"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -34,11 +36,21 @@ func TestMain(m *testing.M) {
 		&models.ScenarioStep{},
 		&models.ScenarioStepHint{},
 		&models.ScenarioStepQuestion{},
+		&models.ScenarioStepTransition{},
+		&models.ScenarioRevision{},
+		&models.ScenarioGitSource{},
+		&models.ScenarioGitSync{},
+		&models.ScenarioReview{},
 		&models.ScenarioSession{},
 		&models.ScenarioStepProgress{},
 		&models.ScenarioFlag{},
+		&models.ScenarioFlagAttempt{},
 		&models.ScenarioAssignment{},
+		&models.ScenarioAssignmentExtension{},
+		&models.ScenarioAssignmentReminder{},
 		&models.ScenarioInstanceType{},
+		&models.ScenarioNode{},
+		&models.ScenarioSessionNode{},
 		&groupModels.ClassGroup{},
 		&groupModels.GroupMember{},
 		&terminalModels.Terminal{},
@@ -49,6 +61,10 @@ func TestMain(m *testing.M) {
 		&paymentModels.UserSubscription{},
 		&paymentModels.OrganizationSubscription{},
 		&paymentModels.OrganizationRolePlan{},
+		&webhookModels.WebhookSubscription{},
+		&webhookModels.WebhookDelivery{},
+		&webhookModels.WebhookDeliveryAttempt{},
+		&authModels.UserSettings{},
 	)
 	if err != nil {
 		panic("failed to migrate shared test DB: " + err.Error())
@@ -58,6 +74,8 @@ func TestMain(m *testing.M) {
 	models.MigrateUniqueActiveSessionIndex(db)
 
 	sharedTestDB = db
+	// Webhook subscription and Git source secrets are stored encrypted.
+	os.Setenv("FIELD_ENCRYPTION_SECRET", "scenarios-test-secret")
 	os.Exit(m.Run())
 }
 
@@ -66,12 +84,22 @@ func freshTestDB(t *testing.T) *gorm.DB {
 	// Delete in reverse-dependency order to avoid FK issues
 	sharedTestDB.Exec("DELETE FROM scenario_step_progress")
 	sharedTestDB.Exec("DELETE FROM scenario_flags")
+	sharedTestDB.Exec("DELETE FROM scenario_flag_attempts")
+	sharedTestDB.Exec("DELETE FROM scenario_reviews")
 	sharedTestDB.Exec("DELETE FROM scenario_sessions")
+	sharedTestDB.Exec("DELETE FROM scenario_assignment_reminders")
+	sharedTestDB.Exec("DELETE FROM scenario_assignment_extensions")
 	sharedTestDB.Exec("DELETE FROM scenario_assignments")
 	sharedTestDB.Exec("DELETE FROM scenario_instance_types")
+	sharedTestDB.Exec("DELETE FROM scenario_nodes")
+	sharedTestDB.Exec("DELETE FROM scenario_session_nodes")
 	sharedTestDB.Exec("DELETE FROM scenario_step_questions")
+	sharedTestDB.Exec("DELETE FROM scenario_step_transitions")
 	sharedTestDB.Exec("DELETE FROM scenario_step_hints")
 	sharedTestDB.Exec("DELETE FROM scenario_steps")
+	sharedTestDB.Exec("DELETE FROM scenario_revisions")
+	sharedTestDB.Exec("DELETE FROM scenario_git_syncs")
+	sharedTestDB.Exec("DELETE FROM scenario_git_sources")
 	sharedTestDB.Exec("DELETE FROM scenarios")
 	sharedTestDB.Exec("DELETE FROM project_files")
 	sharedTestDB.Exec("DELETE FROM group_members")
@@ -84,5 +112,9 @@ func freshTestDB(t *testing.T) *gorm.DB {
 	sharedTestDB.Exec("DELETE FROM organization_subscriptions")
 	sharedTestDB.Exec("DELETE FROM organization_role_plans")
 	sharedTestDB.Exec("DELETE FROM subscription_plans")
+	sharedTestDB.Exec("DELETE FROM webhook_delivery_attempts")
+	sharedTestDB.Exec("DELETE FROM webhook_deliveries")
+	sharedTestDB.Exec("DELETE FROM webhook_subscriptions")
+	sharedTestDB.Exec("DELETE FROM user_settings")
 	return sharedTestDB
 }
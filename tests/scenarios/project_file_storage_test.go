@@ -0,0 +1,403 @@
+package scenarios_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+	"soli/formations/src/scenarios/storage"
+)
+
+// newTestLocalStorage returns a filesystem backend rooted in a temp dir and
+// registers it so rows it writes can be read back through the registry.
+func newTestLocalStorage(t *testing.T) *storage.LocalStorage {
+	t.Helper()
+	ls, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	storage.Register(ls)
+	return ls
+}
+
+// writeImageScenario lays out a KillerCoda directory with one step, one
+// script and one image referenced from the step markdown.
+func writeImageScenario(t *testing.T, dir string, pngData []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "step1", "assets"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "step1", "assets", "diagram.png"), pngData, 0644))
+	writeTestFile(t, dir, "step1/text.md", "# Step 1\n\n![Diagram](./assets/diagram.png)")
+	writeTestFile(t, dir, "step1/verify.sh", "#!/bin/bash\ntest -f /tmp/done")
+	writeTestFile(t, dir, "index.json", `{
+		"title": "Storage Lab",
+		"details": {
+			"steps": [{"title": "Step 1", "text": "step1/text.md", "verify": "step1/verify.sh"}]
+		},
+		"backend": {"imageid": "ubuntu"}
+	}`)
+}
+
+func countObjects(t *testing.T, root string) int {
+	t.Helper()
+	n := 0
+	require.NoError(t, filepath.Walk(root, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			n++
+		}
+		return err
+	}))
+	return n
+}
+
+func TestLocalStorage_StoreIsContentAddressed(t *testing.T) {
+	root := t.TempDir()
+	ls, err := storage.NewLocalStorage(root)
+	require.NoError(t, err)
+
+	ref1, err := ls.Store("#!/bin/bash\necho hi")
+	require.NoError(t, err)
+	ref2, err := ls.Store("#!/bin/bash\necho hi")
+	require.NoError(t, err)
+
+	assert.Equal(t, ref1, ref2)
+	assert.Equal(t, storage.ContentRef("#!/bin/bash\necho hi"), ref1)
+	assert.Equal(t, 1, countObjects(t, root), "identical content must be stored once")
+
+	content, err := ls.Retrieve(ref1)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/bash\necho hi", content)
+}
+
+func TestLocalStorage_RetrieveDetectsCorruption(t *testing.T) {
+	root := t.TempDir()
+	ls, err := storage.NewLocalStorage(root)
+	require.NoError(t, err)
+
+	ref, err := ls.Store("original")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, ref[:2], ref), []byte("tampered"), 0644))
+
+	_, err = ls.Retrieve(ref)
+	assert.Error(t, err)
+}
+
+func TestLocalStorage_RejectsInvalidRef(t *testing.T) {
+	ls, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = ls.Retrieve("../../etc/passwd")
+	assert.ErrorIs(t, err, storage.ErrInvalidRef)
+}
+
+func TestLocalStorage_RetrieveMissing(t *testing.T) {
+	ls, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = ls.Retrieve(storage.ContentRef("never stored"))
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+	assert.NoError(t, ls.Delete(storage.ContentRef("never stored")))
+}
+
+// fakeS3 is a minimal in-memory S3 endpoint: it records requests and
+// rejects any that are not SigV4-signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		r.Header.Get("X-Amz-Content-Sha256") == "" || r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+		f.puts++
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage_RoundTrip(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s3, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint: srv.URL, Bucket: "ocf", Prefix: "project-files",
+		AccessKey: "AKID", SecretKey: "secret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, storage.TypeS3, s3.Type())
+
+	ref, err := s3.Store("hello world")
+	require.NoError(t, err)
+	_, err = s3.Store("hello world")
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.puts, "an existing object must not be uploaded again")
+
+	_, ok := fake.objects["/ocf/project-files/"+ref[:2]+"/"+ref]
+	assert.True(t, ok, "object should be stored under prefix/ab/ref")
+
+	content, err := s3.Retrieve(ref)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", content)
+
+	require.NoError(t, s3.Delete(ref))
+	_, err = s3.Retrieve(ref)
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+}
+
+func TestS3Storage_RequiresCredentials(t *testing.T) {
+	_, err := storage.NewS3Storage(storage.S3Config{Endpoint: "http://minio:9000", Bucket: "ocf"})
+	assert.Error(t, err)
+}
+
+func TestImporter_ExternalStorage_KeepsContentOutOfDatabase(t *testing.T) {
+	db := freshTestDB(t)
+	ls := newTestLocalStorage(t)
+
+	importer := services.NewScenarioImporterService(db)
+	importer.SetFileStorage(ls)
+
+	pngData := []byte{0x89, 0x50, 0x4e, 0x47, 0x01, 0x02}
+	dir := t.TempDir()
+	writeImageScenario(t, dir, pngData)
+
+	scenario, err := importer.ImportFromDirectory(dir, "user-1", nil, "upload")
+	require.NoError(t, err)
+
+	var files []models.ProjectFile
+	require.NoError(t, db.Find(&files).Error)
+	require.NotEmpty(t, files)
+	for _, f := range files {
+		assert.Empty(t, f.Content, "%s should not be held in the database", f.RelPath)
+		assert.Equal(t, storage.TypeFilesystem, f.StorageType)
+		assert.Len(t, f.StorageRef, 64)
+		assert.NotZero(t, f.SizeBytes)
+	}
+
+	// Scripts still resolve through the ProjectFile reference
+	var step models.ScenarioStep
+	require.NoError(t, db.Where("scenario_id = ?", scenario.ID).First(&step).Error)
+	assert.Equal(t, "#!/bin/bash\ntest -f /tmp/done",
+		services.ResolveScriptContent(db, step.VerifyScriptID, ""))
+
+	// Export rebuilds the archive, image included, from the object store
+	zipBytes, _, err := services.NewScenarioExportService(db).ExportAsArchive(scenario.ID)
+	require.NoError(t, err)
+	r, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err)
+	found := false
+	for _, zf := range r.File {
+		if zf.Name != "step1/assets/diagram.png" {
+			continue
+		}
+		rc, err := zf.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		assert.Equal(t, pngData, data)
+		found = true
+	}
+	assert.True(t, found, "archive should contain the scenario image")
+}
+
+func TestImporter_ExternalStorage_ReimportDeduplicates(t *testing.T) {
+	db := freshTestDB(t)
+	root := t.TempDir()
+	ls, err := storage.NewLocalStorage(root)
+	require.NoError(t, err)
+	storage.Register(ls)
+
+	importer := services.NewScenarioImporterService(db)
+	importer.SetFileStorage(ls)
+
+	dir := t.TempDir()
+	writeImageScenario(t, dir, []byte{0x89, 0x50, 0x4e, 0x47})
+
+	_, err = importer.ImportFromDirectory(dir, "user-1", nil, "upload")
+	require.NoError(t, err)
+	before := countObjects(t, root)
+
+	// Re-importing unchanged content must not grow or empty the store
+	scenario, err := importer.ImportFromDirectory(dir, "user-1", nil, "upload")
+	require.NoError(t, err)
+	assert.Equal(t, before, countObjects(t, root))
+
+	var step models.ScenarioStep
+	require.NoError(t, db.Where("scenario_id = ?", scenario.ID).First(&step).Error)
+	assert.Equal(t, "#!/bin/bash\ntest -f /tmp/done",
+		services.ResolveScriptContent(db, step.VerifyScriptID, ""))
+}
+
+func TestDuplicate_ExternalStorage_SharesObjects(t *testing.T) {
+	db := freshTestDB(t)
+	root := t.TempDir()
+	ls, err := storage.NewLocalStorage(root)
+	require.NoError(t, err)
+	storage.Register(ls)
+
+	importer := services.NewScenarioImporterService(db)
+	importer.SetFileStorage(ls)
+	dir := t.TempDir()
+	writeImageScenario(t, dir, []byte{0x89, 0x50, 0x4e, 0x47})
+	source, err := importer.ImportFromDirectory(dir, "user-1", nil, "upload")
+	require.NoError(t, err)
+	objects := countObjects(t, root)
+
+	dupService := services.NewScenarioDuplicateService(db)
+	dupService.SetFileStorage(ls)
+	dup, err := dupService.DuplicateScenario(source.ID, "user-2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, objects, countObjects(t, root), "a copy must reference the same objects")
+
+	// Re-importing the source releases its old rows; the copy still needs the
+	// objects, so they must survive.
+	_, err = importer.ImportFromDirectory(dir, "user-1", nil, "upload")
+	require.NoError(t, err)
+	require.NoError(t, db.Where("scenario_id = ?", source.ID).Delete(&models.ProjectFile{}).Error)
+
+	var step models.ScenarioStep
+	require.NoError(t, db.Where("scenario_id = ?", dup.ID).First(&step).Error)
+	assert.Equal(t, "#!/bin/bash\ntest -f /tmp/done",
+		services.ResolveScriptContent(db, step.VerifyScriptID, ""))
+}
+
+func TestDuplicate_DatabaseContent_MovesToActiveStore(t *testing.T) {
+	db := freshTestDB(t)
+	ls := newTestLocalStorage(t)
+	source := createFullSourceScenario(t, db, nil)
+
+	dupService := services.NewScenarioDuplicateService(db)
+	dupService.SetFileStorage(ls)
+	dup, err := dupService.DuplicateScenario(source.ID, "user-2", nil)
+	require.NoError(t, err)
+
+	var reloaded models.Scenario
+	require.NoError(t, db.First(&reloaded, "id = ?", dup.ID).Error)
+	require.NotNil(t, reloaded.IntroFileID)
+
+	var intro models.ProjectFile
+	require.NoError(t, db.First(&intro, "id = ?", *reloaded.IntroFileID).Error)
+	assert.Equal(t, storage.TypeFilesystem, intro.StorageType)
+	assert.Empty(t, intro.Content)
+
+	content, err := services.LoadProjectFileContent(&intro)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome to the test", content)
+}
+
+func seedDatabaseProjectFiles(t *testing.T, db *gorm.DB) []models.ProjectFile {
+	t.Helper()
+	files := []models.ProjectFile{
+		{Name: "verify.sh", RelPath: "step1/verify.sh", ContentType: "script", Content: "#!/bin/bash\nexit 0", StorageType: storage.TypeDatabase},
+		{Name: "text.md", RelPath: "step1/text.md", ContentType: "markdown", Content: "# Hello", StorageType: storage.TypeDatabase},
+		{Name: "logo.png", RelPath: "logo.png", ContentType: "image", Content: base64.StdEncoding.EncodeToString([]byte{1, 2, 3}), StorageType: storage.TypeDatabase},
+	}
+	for i := range files {
+		require.NoError(t, db.Create(&files[i]).Error)
+	}
+	return files
+}
+
+func TestMigrateProjectFileStorage_DryRunChangesNothing(t *testing.T) {
+	db := freshTestDB(t)
+	root := t.TempDir()
+	ls, err := storage.NewLocalStorage(root)
+	require.NoError(t, err)
+	seedDatabaseProjectFiles(t, db)
+
+	report, err := services.MigrateProjectFileStorage(db, ls, services.ProjectFileMigrationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.WouldMigrate)
+	assert.Equal(t, 0, report.Migrated)
+	assert.Equal(t, 0, countObjects(t, root))
+
+	var stillInDB int64
+	db.Model(&models.ProjectFile{}).Where("storage_type = ?", storage.TypeDatabase).Count(&stillInDB)
+	assert.Equal(t, int64(3), stillInDB)
+}
+
+func TestMigrateProjectFileStorage_Apply(t *testing.T) {
+	db := freshTestDB(t)
+	ls := newTestLocalStorage(t)
+	files := seedDatabaseProjectFiles(t, db)
+
+	report, err := services.MigrateProjectFileStorage(db, ls, services.ProjectFileMigrationOptions{
+		Apply:     true,
+		BatchSize: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Migrated)
+	assert.Empty(t, report.Failures)
+
+	for _, orig := range files {
+		var f models.ProjectFile
+		require.NoError(t, db.First(&f, "id = ?", orig.ID).Error)
+		assert.Empty(t, f.Content)
+		assert.Equal(t, storage.TypeFilesystem, f.StorageType)
+		assert.Equal(t, int64(len(orig.Content)), f.SizeBytes)
+
+		content, err := services.LoadProjectFileContent(&f)
+		require.NoError(t, err)
+		assert.Equal(t, orig.Content, content)
+	}
+
+	// A second run finds nothing left to move
+	report, err = services.MigrateProjectFileStorage(db, ls, services.ProjectFileMigrationOptions{Apply: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Total)
+}
+
+func TestMigrateProjectFileStorage_ContentTypeFilter(t *testing.T) {
+	db := freshTestDB(t)
+	ls := newTestLocalStorage(t)
+	seedDatabaseProjectFiles(t, db)
+
+	report, err := services.MigrateProjectFileStorage(db, ls, services.ProjectFileMigrationOptions{
+		Apply:        true,
+		ContentTypes: []string{"image"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Migrated)
+
+	var remaining int64
+	db.Model(&models.ProjectFile{}).Where("storage_type = ?", storage.TypeDatabase).Count(&remaining)
+	assert.Equal(t, int64(2), remaining)
+}
+
+func TestMigrateProjectFileStorage_RejectsDatabaseTarget(t *testing.T) {
+	db := freshTestDB(t)
+	_, err := services.MigrateProjectFileStorage(db, storage.NewDatabaseStorage(), services.ProjectFileMigrationOptions{Apply: true})
+	assert.Error(t, err)
+}
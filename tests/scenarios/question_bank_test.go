@@ -0,0 +1,263 @@
+package scenarios_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// question_bank_test.go — a quiz step's questions are a pool each session
+// draws from, choice options are shuffled per session, and the newer question
+// types (multi_select, ordering, numeric, regex) are graded by their own rules.
+
+func TestGradeQuizAnswer_QuestionTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		question  models.ScenarioStepQuestion
+		submitted string
+		want      bool
+	}{
+		{"exact match", models.ScenarioStepQuestion{QuestionType: "multiple_choice", CorrectAnswer: "ls"}, "ls", true},
+		{"exact mismatch", models.ScenarioStepQuestion{QuestionType: "free_text", CorrectAnswer: "/root"}, "/root/", false},
+		{"multi select in any order", models.ScenarioStepQuestion{QuestionType: "multi_select", CorrectAnswer: `["ls","dir"]`}, `["dir","ls"]`, true},
+		{"multi select missing one", models.ScenarioStepQuestion{QuestionType: "multi_select", CorrectAnswer: `["ls","dir"]`}, `["ls"]`, false},
+		{"multi select with an extra", models.ScenarioStepQuestion{QuestionType: "multi_select", CorrectAnswer: `["ls"]`}, `["ls","rm"]`, false},
+		{"multi select not JSON", models.ScenarioStepQuestion{QuestionType: "multi_select", CorrectAnswer: `["ls"]`}, "ls", false},
+		{"ordering in order", models.ScenarioStepQuestion{QuestionType: "ordering", CorrectAnswer: `["mkdir","cd","touch"]`}, `["mkdir","cd","touch"]`, true},
+		{"ordering out of order", models.ScenarioStepQuestion{QuestionType: "ordering", CorrectAnswer: `["mkdir","cd","touch"]`}, `["cd","mkdir","touch"]`, false},
+		{"numeric exact", models.ScenarioStepQuestion{QuestionType: "numeric", CorrectAnswer: "22"}, "22", true},
+		{"numeric within tolerance", models.ScenarioStepQuestion{QuestionType: "numeric", CorrectAnswer: "3.14", Tolerance: 0.01}, "3,141", true},
+		{"numeric outside tolerance", models.ScenarioStepQuestion{QuestionType: "numeric", CorrectAnswer: "3.14", Tolerance: 0.01}, "3.2", false},
+		{"numeric not a number", models.ScenarioStepQuestion{QuestionType: "numeric", CorrectAnswer: "22"}, "twenty-two", false},
+		{"regex full match", models.ScenarioStepQuestion{QuestionType: "regex", CorrectAnswer: `(?i)chmod\s+755\s+\S+`}, " CHMOD 755 run.sh ", true},
+		{"regex is anchored", models.ScenarioStepQuestion{QuestionType: "regex", CorrectAnswer: `chmod 755`}, "sudo chmod 755 run.sh", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, services.GradeQuizAnswer(tt.question, tt.submitted))
+		})
+	}
+}
+
+func TestValidateQuizQuestion(t *testing.T) {
+	valid := []models.ScenarioStepQuestion{
+		{QuestionType: "multiple_choice", Options: `["a","b"]`, CorrectAnswer: "a"},
+		{QuestionType: "multi_select", Options: `["a","b","c"]`, CorrectAnswer: `["a","c"]`},
+		{QuestionType: "ordering", CorrectAnswer: `["first","second"]`},
+		{QuestionType: "numeric", CorrectAnswer: "42", Tolerance: 0.5},
+		{QuestionType: "regex", CorrectAnswer: `\d+`},
+	}
+	for _, q := range valid {
+		assert.NoError(t, services.ValidateQuizQuestion(&q), q.QuestionType)
+	}
+
+	invalid := []models.ScenarioStepQuestion{
+		{QuestionType: "multi_select", Options: `["a","b"]`, CorrectAnswer: "a"},
+		{QuestionType: "multi_select", Options: `["a","b"]`, CorrectAnswer: `["z"]`},
+		{QuestionType: "multi_select", CorrectAnswer: `["a"]`},
+		{QuestionType: "ordering", Options: `["a","b","c"]`, CorrectAnswer: `["a","b"]`},
+		{QuestionType: "numeric", CorrectAnswer: "many"},
+		{QuestionType: "numeric", CorrectAnswer: "1", Tolerance: -1},
+		{QuestionType: "regex", CorrectAnswer: `(unclosed`},
+	}
+	for _, q := range invalid {
+		assert.Error(t, services.ValidateQuizQuestion(&q), "%s %s", q.QuestionType, q.CorrectAnswer)
+	}
+}
+
+func questionPool(n int, topic func(i int) string) []models.ScenarioStepQuestion {
+	pool := make([]models.ScenarioStepQuestion, n)
+	for i := range pool {
+		pool[i] = models.ScenarioStepQuestion{
+			Order: i, QuestionText: fmt.Sprintf("Q%d", i), QuestionType: "free_text", Topic: topic(i),
+		}
+		pool[i].ID = uuid.New()
+	}
+	return pool
+}
+
+func TestDrawQuizQuestions_StablePerSessionAndSpreadAcrossTopics(t *testing.T) {
+	topics := []string{"files", "users", "network"}
+	pool := questionPool(12, func(i int) string { return topics[i%3] })
+	stepID := uuid.New()
+	sessionID := uuid.New()
+
+	drawn := services.DrawQuizQuestions(pool, 3, sessionID, stepID)
+	require.Len(t, drawn, 3)
+	assert.Equal(t, drawn, services.DrawQuizQuestions(pool, 3, sessionID, stepID), "a session always gets the same draw")
+
+	covered := map[string]bool{}
+	for i, q := range drawn {
+		covered[q.Topic] = true
+		if i > 0 {
+			assert.Less(t, drawn[i-1].Order, q.Order, "drawn questions keep pool order")
+		}
+	}
+	assert.Len(t, covered, 3, "a draw of 3 from 3 topics asks one of each")
+
+	differs := false
+	for i := 0; i < 10 && !differs; i++ {
+		other := services.DrawQuizQuestions(pool, 3, uuid.New(), stepID)
+		differs = fmt.Sprint(other) != fmt.Sprint(drawn)
+	}
+	assert.True(t, differs, "sessions do not all get the same questions")
+
+	assert.Len(t, services.DrawQuizQuestions(pool, 0, sessionID, stepID), 12, "0 asks the whole pool")
+	assert.Len(t, services.DrawQuizQuestions(pool, 50, sessionID, stepID), 12, "a draw larger than the pool asks it all")
+}
+
+func TestShuffledQuestionOptions(t *testing.T) {
+	sessionID := uuid.New()
+	choice := models.ScenarioStepQuestion{QuestionType: "multiple_choice", Options: `["a","b","c","d","e"]`}
+	choice.ID = uuid.New()
+
+	shuffled := services.ShuffledQuestionOptions(choice, sessionID)
+	assert.Equal(t, shuffled, services.ShuffledQuestionOptions(choice, sessionID))
+	var options []string
+	require.NoError(t, json.Unmarshal([]byte(shuffled), &options))
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, options)
+
+	ordering := models.ScenarioStepQuestion{QuestionType: "ordering", CorrectAnswer: `["one","two"]`}
+	ordering.ID = uuid.New()
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, `["two","one"]`, services.ShuffledQuestionOptions(ordering, uuid.New()),
+			"an ordering question is never shown already in order")
+	}
+
+	text := models.ScenarioStepQuestion{QuestionType: "free_text", Options: `["x","y"]`}
+	assert.Equal(t, `["x","y"]`, services.ShuffledQuestionOptions(text, sessionID))
+}
+
+// drawnQuizSession creates a quiz step with a pool of poolSize numeric
+// questions ("what is i+1?") drawing drawCount of them, and an active session
+// on it.
+func drawnQuizSession(t *testing.T, db *gorm.DB, poolSize, drawCount int) (models.ScenarioSession, map[uuid.UUID]models.ScenarioStepQuestion) {
+	t.Helper()
+	scenario := models.Scenario{Name: "quiz-pool", Title: "Quiz Pool", InstanceType: "ubuntu:22.04", CreatedByID: "creator-1"}
+	require.NoError(t, db.Create(&scenario).Error)
+	step := models.ScenarioStep{
+		ScenarioID: scenario.ID, Order: 0, Title: "Pool", StepType: "quiz",
+		ShowImmediateFeedback: true, QuizDrawCount: drawCount,
+	}
+	require.NoError(t, db.Create(&step).Error)
+
+	pool := make(map[uuid.UUID]models.ScenarioStepQuestion, poolSize)
+	for i := 0; i < poolSize; i++ {
+		q := models.ScenarioStepQuestion{
+			StepID: step.ID, Order: i, QuestionText: fmt.Sprintf("What is %d+1?", i),
+			QuestionType: "numeric", CorrectAnswer: fmt.Sprint(i + 1), Points: 1,
+		}
+		require.NoError(t, db.Create(&q).Error)
+		pool[q.ID] = q
+	}
+
+	session := models.ScenarioSession{
+		ScenarioID: scenario.ID, UserID: "student-pool", CurrentStep: 0, Status: "active", StartedAt: time.Now(),
+	}
+	require.NoError(t, db.Create(&session).Error)
+	require.NoError(t, db.Create(&models.ScenarioStepProgress{SessionID: session.ID, StepOrder: 0, Status: "active"}).Error)
+	return session, pool
+}
+
+func TestSubmitQuiz_GradesTheDrawnSubset(t *testing.T) {
+	db := setupTestDB(t)
+	session, pool := drawnQuizSession(t, db, 6, 2)
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+
+	current, err := svc.GetCurrentStep(session.ID)
+	require.NoError(t, err)
+	require.Len(t, current.Questions, 2, "the session is asked its draw, not the pool")
+
+	answers := make(map[uuid.UUID]string, 2)
+	for _, q := range current.Questions {
+		answers[q.ID] = pool[q.ID].CorrectAnswer
+	}
+	result, err := svc.SubmitQuiz(session.ID, dto.SubmitQuizInput{Answers: answers})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 2, result.CorrectCount)
+	assert.InDelta(t, 1.0, result.Score, 0.001)
+	assert.Len(t, result.PerQuestionResults, 2)
+}
+
+func TestSubmitQuiz_RejectsQuestionsOutsideTheDraw(t *testing.T) {
+	db := setupTestDB(t)
+	session, pool := drawnQuizSession(t, db, 6, 2)
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+
+	current, err := svc.GetCurrentStep(session.ID)
+	require.NoError(t, err)
+	asked := map[uuid.UUID]bool{}
+	for _, q := range current.Questions {
+		asked[q.ID] = true
+	}
+	for id, q := range pool {
+		if asked[id] {
+			continue
+		}
+		_, err := svc.SubmitQuiz(session.ID, dto.SubmitQuizInput{Answers: map[uuid.UUID]string{id: q.CorrectAnswer}})
+		assert.ErrorContains(t, err, "unknown question id")
+		return
+	}
+	t.Fatal("every pool question was drawn")
+}
+
+func TestComputeCorrectCountsFromLoaded_CountsTheDraw(t *testing.T) {
+	score := 0.5
+	steps := []models.ScenarioStep{{Order: 0, StepType: "quiz", QuizDrawCount: 4}}
+	steps[0].ID = uuid.New()
+	progress := []models.ScenarioStepProgress{{StepOrder: 0, Status: "completed", QuizScore: &score}}
+
+	correct, total := services.ComputeCorrectCountsFromLoaded(steps, progress, nil, map[uuid.UUID]int{steps[0].ID: 10})
+	assert.Equal(t, int64(4), total, "the denominator is the draw, not the pool")
+	assert.Equal(t, int64(2), correct)
+}
+
+func TestSeedScenario_RejectsUngradableQuestion(t *testing.T) {
+	db := setupTestDB(t)
+	_, _, err := services.NewScenarioSeedService(db).SeedScenario(dto.SeedScenarioInput{
+		Title:        "Bad numeric",
+		InstanceType: "ubuntu:22.04",
+		Steps: []dto.SeedStepInput{{
+			Title: "Quiz", StepType: "quiz", QuizDrawCount: 1,
+			Questions: []dto.SeedQuestionInput{{QuestionText: "How many?", QuestionType: "numeric", CorrectAnswer: "several"}},
+		}},
+	}, "creator-1", nil)
+	assert.ErrorContains(t, err, "step 1 question 1")
+}
+
+func TestSeedAndExport_RoundTripQuestionBank(t *testing.T) {
+	db := setupTestDB(t)
+	scenario, _, err := services.NewScenarioSeedService(db).SeedScenario(dto.SeedScenarioInput{
+		Title:        "Seeded pool",
+		InstanceType: "ubuntu:22.04",
+		Steps: []dto.SeedStepInput{{
+			Title: "Quiz", StepType: "quiz", QuizDrawCount: 1,
+			Questions: []dto.SeedQuestionInput{
+				{Order: 0, QuestionText: "SSH port?", QuestionType: "numeric", CorrectAnswer: "22", Topic: "network", Difficulty: "easy"},
+				{Order: 1, QuestionText: "Pi?", QuestionType: "numeric", CorrectAnswer: "3.14", Tolerance: 0.01, Topic: "math", Difficulty: "hard"},
+			},
+		}},
+	}, "creator-1", nil)
+	require.NoError(t, err)
+
+	exported, err := services.NewScenarioExportService(db).ExportAsJSON(scenario.ID)
+	require.NoError(t, err)
+	require.Len(t, exported.Steps, 1)
+	step := exported.Steps[0]
+	assert.Equal(t, 1, step.QuizDrawCount)
+	require.Len(t, step.Questions, 2)
+	assert.Equal(t, "network", step.Questions[0].Topic)
+	assert.Equal(t, "easy", step.Questions[0].Difficulty)
+	assert.Equal(t, 0.01, step.Questions[1].Tolerance)
+}
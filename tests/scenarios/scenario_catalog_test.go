@@ -0,0 +1,298 @@
+package scenarios_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/mocks"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	scenarioController "soli/formations/src/scenarios/routes"
+	"soli/formations/src/scenarios/services"
+)
+
+// The public scenario catalog: search with filters, ratings and reviews from
+// learners who completed a scenario, usage statistics, and forks into an
+// organization that keep a link to their upstream scenario.
+
+func createCatalogScenario(t *testing.T, db *gorm.DB, scenario models.Scenario) models.Scenario {
+	t.Helper()
+	if scenario.Name == "" {
+		scenario.Name = "catalog-" + uuid.NewString()[:8]
+	}
+	scenario.InstanceType = "ubuntu:22.04"
+	scenario.CreatedByID = "author"
+	isPublic := scenario.IsPublic
+	require.NoError(t, db.Create(&scenario).Error)
+	require.NoError(t, db.Model(&scenario).Update("is_public", isPublic).Error)
+	return scenario
+}
+
+func completeCatalogScenario(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, userID string, grade float64) {
+	t.Helper()
+	now := time.Now()
+	require.NoError(t, db.Create(&models.ScenarioSession{
+		ScenarioID: scenarioID, UserID: userID, Status: "completed",
+		Grade: floatPtr(grade), StartedAt: now.Add(-time.Hour), CompletedAt: &now,
+	}).Error)
+}
+
+func catalogTitles(page *dto.CatalogPage) []string {
+	titles := make([]string, 0, len(page.Items))
+	for _, item := range page.Items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func TestCatalogSearch_OnlyPublicScenariosMatchingFilters(t *testing.T) {
+	db := freshTestDB(t)
+	createCatalogScenario(t, db, models.Scenario{
+		Title: "Linux Basics", Description: "Navigate the filesystem", Difficulty: "beginner",
+		EstimatedTime: "30m", OsType: "deb", IsPublic: true,
+	})
+	createCatalogScenario(t, db, models.Scenario{
+		Title: "Containers 101", Description: "Build and run Docker images", Objectives: "Write a Dockerfile",
+		Difficulty: "intermediate", EstimatedTime: "1h30", OsType: "deb",
+		RequiredFeatures: `["network"]`, IsPublic: true,
+	})
+	createCatalogScenario(t, db, models.Scenario{
+		Title: "Docker Internals", Difficulty: "advanced", EstimatedTime: "2 hours", OsType: "rpm", IsPublic: true,
+	})
+	createCatalogScenario(t, db, models.Scenario{Title: "Private Docker Lab", Description: "docker", IsPublic: false})
+	archived := createCatalogScenario(t, db, models.Scenario{Title: "Old Docker Lab", IsPublic: true})
+	require.NoError(t, db.Model(&archived).Update("archived_at", time.Now()).Error)
+
+	svc := services.NewScenarioCatalogService(db)
+
+	page, err := svc.Search(dto.CatalogSearchInput{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total, "private and archived scenarios are not in the catalog")
+
+	page, err = svc.Search(dto.CatalogSearchInput{Query: "docker"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Docker Internals", "Containers 101"}, catalogTitles(page),
+		"matches on title, description and objectives; title matches first")
+
+	page, err = svc.Search(dto.CatalogSearchInput{Difficulty: "Beginner"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Linux Basics"}, catalogTitles(page))
+
+	page, err = svc.Search(dto.CatalogSearchInput{OsType: "rpm"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Docker Internals"}, catalogTitles(page))
+
+	page, err = svc.Search(dto.CatalogSearchInput{MaxMinutes: 90, Sort: dto.CatalogSortRecent})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Containers 101", "Linux Basics"}, catalogTitles(page))
+	require.NotNil(t, page.Items[0].EstimatedMinutes)
+	assert.Equal(t, 90, *page.Items[0].EstimatedMinutes)
+
+	page, err = svc.Search(dto.CatalogSearchInput{Features: "network"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Containers 101"}, catalogTitles(page))
+
+	none := ""
+	page, err = svc.Search(dto.CatalogSearchInput{SupportedFeatures: &none})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total, "scenarios requiring a feature the plan lacks are left out")
+
+	page, err = svc.Search(dto.CatalogSearchInput{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	assert.Len(t, page.Items, 1)
+}
+
+func TestCatalogSearch_SortsByRatingAndPopularity(t *testing.T) {
+	db := freshTestDB(t)
+	liked := createCatalogScenario(t, db, models.Scenario{Title: "Liked", IsPublic: true})
+	popular := createCatalogScenario(t, db, models.Scenario{Title: "Popular", IsPublic: true})
+	createCatalogScenario(t, db, models.Scenario{Title: "Unrated", IsPublic: true})
+
+	svc := services.NewScenarioCatalogService(db)
+	completeCatalogScenario(t, db, liked.ID, "ann", 90)
+	_, err := svc.UpsertReview(liked.ID, "ann", dto.UpsertScenarioReviewInput{Rating: 5})
+	require.NoError(t, err)
+	for _, learner := range []string{"bob", "cid", "dan"} {
+		completeCatalogScenario(t, db, popular.ID, learner, 60)
+		_, err := svc.UpsertReview(popular.ID, learner, dto.UpsertScenarioReviewInput{Rating: 3})
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Create(&models.ScenarioSession{
+		ScenarioID: popular.ID, UserID: "eve", Status: "abandoned", StartedAt: time.Now(),
+	}).Error)
+
+	page, err := svc.Search(dto.CatalogSearchInput{Sort: dto.CatalogSortRating})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Liked", "Popular", "Unrated"}, catalogTitles(page))
+
+	page, err = svc.Search(dto.CatalogSearchInput{Sort: dto.CatalogSortPopular})
+	require.NoError(t, err)
+	assert.Equal(t, "Popular", page.Items[0].Title)
+	usage := page.Items[0].Usage
+	assert.Equal(t, 4, usage.Sessions)
+	assert.Equal(t, 4, usage.Learners)
+	assert.Equal(t, 3, usage.Completions)
+	assert.Equal(t, 75.0, usage.CompletionRate)
+	require.NotNil(t, usage.AverageGrade)
+	assert.Equal(t, 60.0, *usage.AverageGrade)
+	require.NotNil(t, page.Items[0].Rating.Average)
+	assert.Equal(t, 3.0, *page.Items[0].Rating.Average)
+	assert.Equal(t, 3, page.Items[0].Rating.Count)
+}
+
+func TestCatalogReviews_OnlyFromLearnersWhoCompleted(t *testing.T) {
+	db := freshTestDB(t)
+	scenario := createCatalogScenario(t, db, models.Scenario{Title: "Linux Basics", IsPublic: true})
+	svc := services.NewScenarioCatalogService(db)
+
+	_, err := svc.UpsertReview(scenario.ID, "ann", dto.UpsertScenarioReviewInput{Rating: 4})
+	assert.ErrorIs(t, err, services.ErrReviewNotAllowed)
+
+	completeCatalogScenario(t, db, scenario.ID, "ann", 80)
+	first, err := svc.UpsertReview(scenario.ID, "ann", dto.UpsertScenarioReviewInput{Rating: 4, Comment: "Good"})
+	require.NoError(t, err)
+	second, err := svc.UpsertReview(scenario.ID, "ann", dto.UpsertScenarioReviewInput{Rating: 2, Comment: "Changed my mind"})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID, "reviewing again replaces the review")
+
+	detail, err := svc.Get(scenario.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, detail.Rating.Count)
+	assert.Equal(t, []int{0, 1, 0, 0, 0}, detail.Rating.Distribution)
+	require.Len(t, detail.RecentReviews, 1)
+	assert.Equal(t, "Changed my mind", detail.RecentReviews[0].Comment)
+
+	assert.ErrorIs(t, svc.DeleteReview(scenario.ID, second.ID, "bob", false), services.ErrReviewNotFound,
+		"only the author or an admin deletes a review")
+	require.NoError(t, svc.DeleteReview(scenario.ID, second.ID, "ann", false))
+	_, err = svc.UpsertReview(scenario.ID, "ann", dto.UpsertScenarioReviewInput{Rating: 5})
+	require.NoError(t, err, "a deleted review can be written again")
+
+	private := createCatalogScenario(t, db, models.Scenario{Title: "Private", IsPublic: false})
+	completeCatalogScenario(t, db, private.ID, "ann", 80)
+	_, err = svc.UpsertReview(private.ID, "ann", dto.UpsertScenarioReviewInput{Rating: 5})
+	assert.ErrorIs(t, err, services.ErrCatalogScenarioNotFound)
+}
+
+func TestCatalogFork_CopiesIntoOrganizationWithProvenance(t *testing.T) {
+	db := freshTestDB(t)
+	orgID := createTestOrg(t, db, "fork-owner")
+	upstream := createCatalogScenario(t, db, models.Scenario{
+		Title: "Containers 101", Description: "Docker", RequiredFeatures: `["network"]`, IsPublic: true,
+	})
+	require.NoError(t, db.Create(&models.ScenarioStep{ScenarioID: upstream.ID, Order: 0, Title: "Build"}).Error)
+
+	svc := services.NewScenarioCatalogService(db)
+	fork, err := svc.Fork(upstream.ID, orgID, "fork-owner")
+	require.NoError(t, err)
+
+	var stored models.Scenario
+	require.NoError(t, db.Preload("Steps").First(&stored, "id = ?", fork.ID).Error)
+	assert.Equal(t, "Containers 101", stored.Title)
+	assert.False(t, stored.IsPublic, "a fork is private to its organization")
+	require.NotNil(t, stored.OrganizationID)
+	assert.Equal(t, orgID, *stored.OrganizationID)
+	require.NotNil(t, stored.ForkedFromID)
+	assert.Equal(t, upstream.ID, *stored.ForkedFromID)
+	assert.Equal(t, `["network"]`, stored.RequiredFeatures)
+	assert.Len(t, stored.Steps, 1)
+
+	detail, err := svc.Get(upstream.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, detail.Usage.Forks)
+
+	_, err = svc.Fork(fork.ID, orgID, "fork-owner")
+	assert.ErrorIs(t, err, services.ErrCatalogScenarioNotFound, "only catalog scenarios can be forked")
+}
+
+func TestParseEstimatedMinutes(t *testing.T) {
+	for estimate, want := range map[string]int{
+		"30m": 30, "45 min": 45, "1h": 60, "1h30": 90, "1h 30min": 90,
+		"1.5 hours": 90, "2 heures": 120, "90": 90,
+	} {
+		got, ok := services.ParseEstimatedMinutes(estimate)
+		assert.True(t, ok, estimate)
+		assert.Equal(t, want, got, estimate)
+	}
+	_, ok := services.ParseEstimatedMinutes("a while")
+	assert.False(t, ok)
+}
+
+func setupCatalogRouter(t *testing.T, db *gorm.DB, userID string) *gin.Engine {
+	t.Helper()
+	access.RouteRegistry.Reset()
+	access.ResetEnforcers()
+	t.Cleanup(func() {
+		access.RouteRegistry.Reset()
+		access.ResetEnforcers()
+	})
+
+	scenarioController.RegisterScenarioPermissions(mocks.NewMockEnforcer())
+	access.RegisterBuiltinEnforcers(nil, access.NewGormMembershipChecker(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("userId", userID)
+		c.Set("userRoles", []string{"member"})
+		c.Next()
+	})
+	api.Use(access.Layer2Enforcement())
+
+	controller := scenarioController.NewScenarioCatalogController(db)
+	api.GET("/catalog/scenarios", controller.SearchCatalog)
+	api.POST("/organizations/:id/scenario-forks", controller.ForkScenario)
+	return r
+}
+
+func TestCatalogEndpoints(t *testing.T) {
+	db := freshTestDB(t)
+	orgID := createTestOrg(t, db, "catalog-owner")
+	addOrgMember(t, db, orgID, "catalog-manager", orgModels.OrgRoleManager)
+	addOrgMember(t, db, orgID, "catalog-member", orgModels.OrgRoleMember)
+	upstream := createCatalogScenario(t, db, models.Scenario{Title: "Linux Basics", IsPublic: true})
+
+	w := httptest.NewRecorder()
+	setupCatalogRouter(t, db, "catalog-member").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/catalog/scenarios?q=linux", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var page dto.CatalogPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, 1, page.Total)
+
+	w = httptest.NewRecorder()
+	setupCatalogRouter(t, db, "catalog-member").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/catalog/scenarios?sort=stars", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	body, _ := json.Marshal(dto.ForkScenarioInput{ScenarioID: upstream.ID})
+	forkPath := "/api/v1/organizations/" + orgID.String() + "/scenario-forks"
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, forkPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	setupCatalogRouter(t, db, "catalog-member").ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code, "only organization managers fork into it")
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, forkPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	setupCatalogRouter(t, db, "catalog-manager").ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var output dto.ScenarioOutput
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &output))
+	require.NotNil(t, output.ForkedFromID)
+	assert.Equal(t, upstream.ID, *output.ForkedFromID)
+}
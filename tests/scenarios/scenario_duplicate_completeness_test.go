@@ -31,9 +31,10 @@ import (
 // handled by isProjectFileRef below, which asserts re-pointing rather than
 // equality.
 var stepFieldsNotCopiedVerbatim = map[string]string{
-	"ScenarioID": "points at the new scenario by definition",
-	"Hints":      "separate rows with their own IDs — compared by content below",
-	"Questions":  "separate rows with their own IDs — compared by content below",
+	"ScenarioID":  "points at the new scenario by definition",
+	"Hints":       "separate rows with their own IDs — compared by content below",
+	"Questions":   "separate rows with their own IDs — compared by content below",
+	"Transitions": "separate rows with their own IDs — compared by content below",
 }
 
 // isProjectFileRef reports whether a field is a reference to a ProjectFile.
@@ -139,13 +140,14 @@ func loadStepsWithRelations(t *testing.T, db *gorm.DB, scenarioID uuid.UUID) []m
 	require.NoError(t, db.
 		Preload("Hints", func(db *gorm.DB) *gorm.DB { return db.Order("level ASC") }).
 		Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }).
+		Preload("Transitions", func(db *gorm.DB) *gorm.DB { return db.Order("priority ASC") }).
 		Where("scenario_id = ?", scenarioID).
 		Order("\"order\" ASC").
 		Find(&steps).Error)
 	return steps
 }
 
-// The two associations excluded from the field sweep still have to survive
+// The associations excluded from the field sweep still have to survive
 // duplication — they are just compared by content, since their rows carry new
 // IDs and a new parent.
 func TestDuplicateScenario_CopiesStepHintsAndQuestions(t *testing.T) {
@@ -165,6 +167,13 @@ func TestDuplicateScenario_CopiesStepHintsAndQuestions(t *testing.T) {
 		Explanation:   "ls lists directory contents",
 		Points:        2,
 	}).Error)
+	remediation := source.Steps[1].Order
+	require.NoError(t, db.Create(&models.ScenarioStepTransition{
+		StepID:          source.Steps[0].ID,
+		Condition:       models.TransitionQuizScoreBelow,
+		Threshold:       0.5,
+		TargetStepOrder: &remediation,
+	}).Error)
 
 	copied, err := services.NewScenarioDuplicateService(db).DuplicateScenario(source.ID, "duplicating-user", nil)
 	require.NoError(t, err)
@@ -195,6 +204,16 @@ func TestDuplicateScenario_CopiesStepHintsAndQuestions(t *testing.T) {
 			assert.Equal(t, src.Questions[q].Points, dst.Questions[q].Points)
 			assert.Equal(t, dst.ID, dst.Questions[q].StepID, "question must belong to the copied step")
 		}
+
+		require.Len(t, dst.Transitions, len(src.Transitions),
+			"step %d lost transitions — the copy would run linearly", src.Order)
+		for tr := range src.Transitions {
+			assert.Equal(t, src.Transitions[tr].Condition, dst.Transitions[tr].Condition)
+			assert.Equal(t, src.Transitions[tr].Threshold, dst.Transitions[tr].Threshold)
+			assert.Equal(t, src.Transitions[tr].Value, dst.Transitions[tr].Value)
+			assert.Equal(t, src.Transitions[tr].TargetStepOrder, dst.Transitions[tr].TargetStepOrder)
+			assert.Equal(t, dst.ID, dst.Transitions[tr].StepID, "transition must belong to the copied step")
+		}
 	}
 }
 
@@ -0,0 +1,483 @@
+package scenarios_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/auth/access"
+	"soli/formations/src/auth/mocks"
+	authModels "soli/formations/src/auth/models"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	scenarioController "soli/formations/src/scenarios/routes"
+	"soli/formations/src/scenarios/services"
+)
+
+// scenario_git_sync_test.go — an organization registers a Git repository of
+// scenarios; syncing it, on demand or from a push webhook, imports every
+// scenario directory and publishes the ones that changed as new revisions,
+// reporting the ones that fail validation.
+
+// gitTestRepo is a local bare repository playing the Git host, fed from a
+// working clone.
+type gitTestRepo struct {
+	t    *testing.T
+	bare string
+	work string
+	repo *git.Repository
+}
+
+func newGitTestRepo(t *testing.T) *gitTestRepo {
+	t.Helper()
+	main := git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")}
+	bare := t.TempDir()
+	_, err := git.PlainInitWithOptions(bare, &git.PlainInitOptions{Bare: true, InitOptions: main})
+	require.NoError(t, err)
+
+	work := t.TempDir()
+	repo, err := git.PlainInitWithOptions(work, &git.PlainInitOptions{InitOptions: main})
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}})
+	require.NoError(t, err)
+	return &gitTestRepo{t: t, bare: bare, work: work, repo: repo}
+}
+
+// writeScenario writes a one-file-per-step KillerCoda scenario in dir.
+func (r *gitTestRepo) writeScenario(dir, title string, steps ...string) {
+	r.t.Helper()
+	var entries []string
+	for i, text := range steps {
+		file := fmt.Sprintf("step%d.md", i+1)
+		entries = append(entries, fmt.Sprintf(`{"title": "Step %d", "text": %q}`, i+1, file))
+		r.writeFile(filepath.Join(dir, file), text)
+	}
+	r.writeFile(filepath.Join(dir, "index.json"), fmt.Sprintf(
+		`{"title": %q, "details": {"steps": [%s]}, "backend": {"imageid": "debian:12"}}`,
+		title, strings.Join(entries, ", ")))
+}
+
+func (r *gitTestRepo) writeFile(rel, content string) {
+	r.t.Helper()
+	path := filepath.Join(r.work, rel)
+	require.NoError(r.t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(r.t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// push commits the working tree and pushes it to the bare repository,
+// returning the commit SHA.
+func (r *gitTestRepo) push(message string) string {
+	r.t.Helper()
+	wt, err := r.repo.Worktree()
+	require.NoError(r.t, err)
+	require.NoError(r.t, wt.AddWithOptions(&git.AddOptions{All: true}))
+	hash, err := wt.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Author", Email: "author@example.com", When: time.Now()},
+	})
+	require.NoError(r.t, err)
+	require.NoError(r.t, r.repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"refs/heads/main:refs/heads/main"},
+	}))
+	return hash.String()
+}
+
+func registerGitSource(t *testing.T, db *gorm.DB, orgID uuid.UUID, repoURL, sourcePath string) *models.ScenarioGitSource {
+	t.Helper()
+	source, err := services.NewScenarioGitSyncService(db).RegisterSource(orgID, "git-author", dto.CreateScenarioGitSourceInput{
+		RepositoryURL: repoURL,
+		SourcePath:    sourcePath,
+	})
+	require.NoError(t, err)
+	return source
+}
+
+func gitSyncResultFor(t *testing.T, run *models.ScenarioGitSync, path string) models.ScenarioGitSyncResult {
+	t.Helper()
+	for _, r := range run.Results {
+		if r.Path == path {
+			return r
+		}
+	}
+	t.Fatalf("no sync result for %s in %+v", path, run.Results)
+	return models.ScenarioGitSyncResult{}
+}
+
+func loadScenarioByName(t *testing.T, db *gorm.DB, name string) models.Scenario {
+	t.Helper()
+	var scenario models.Scenario
+	require.NoError(t, db.Where("name = ?", name).First(&scenario).Error)
+	return scenario
+}
+
+func TestGitSync_ImportsAndPublishesEveryScenario(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "git-author")
+	repo := newGitTestRepo(t)
+	repo.writeScenario("labs/linux", "Linux Basics", "List files", "Read a file")
+	repo.writeScenario("labs/network", "Networking", "Ping a host")
+	repo.writeFile("README.md", "Not a scenario")
+	sha := repo.push("Add labs")
+
+	source := registerGitSource(t, db, orgID, repo.bare, "labs")
+	run, err := services.NewScenarioGitSyncService(db).Sync(source.ID, models.GitSyncTriggerManual)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.GitSyncStatusSucceeded, run.Status)
+	assert.Equal(t, sha, run.CommitSHA)
+	require.Len(t, run.Results, 2)
+	linux := gitSyncResultFor(t, run, "labs/linux")
+	assert.Equal(t, models.GitSyncScenarioImported, linux.Status)
+	require.NotNil(t, linux.RevisionNumber)
+	assert.Equal(t, 1, *linux.RevisionNumber)
+
+	scenario := loadScenarioByName(t, db, linux.Name)
+	assert.Equal(t, *linux.ScenarioID, scenario.ID)
+	assert.Equal(t, "git", scenario.SourceType)
+	assert.Equal(t, sha, scenario.GitCommitSHA)
+	assert.Equal(t, "labs/linux", scenario.SourcePath)
+	require.NotNil(t, scenario.GitSourceID)
+	assert.Equal(t, source.ID, *scenario.GitSourceID)
+	require.NotNil(t, scenario.OrganizationID)
+	assert.Equal(t, orgID, *scenario.OrganizationID)
+	require.NotNil(t, scenario.PublishedRevisionID, "the synced content must be what new sessions start on")
+
+	var reloaded models.ScenarioGitSource
+	require.NoError(t, db.First(&reloaded, "id = ?", source.ID).Error)
+	assert.Equal(t, sha, reloaded.LastCommitSHA)
+	assert.Equal(t, models.GitSyncStatusSucceeded, reloaded.LastSyncStatus)
+	assert.NotNil(t, reloaded.LastSyncedAt)
+}
+
+func TestGitSync_PublishesOnlyChangedScenarios(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "git-author")
+	repo := newGitTestRepo(t)
+	repo.writeScenario("linux", "Linux Basics", "List files")
+	repo.writeScenario("network", "Networking", "Ping a host")
+	repo.push("Add labs")
+
+	source := registerGitSource(t, db, orgID, repo.bare, "")
+	syncSvc := services.NewScenarioGitSyncService(db)
+	_, err := syncSvc.Sync(source.ID, models.GitSyncTriggerManual)
+	require.NoError(t, err)
+
+	repo.writeFile("linux/step1.md", "List files, hidden ones too")
+	sha := repo.push("Reword the first step")
+	run, err := syncSvc.Sync(source.ID, models.GitSyncTriggerManual)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.GitSyncStatusSucceeded, run.Status)
+	linux := gitSyncResultFor(t, run, "linux")
+	assert.Equal(t, models.GitSyncScenarioImported, linux.Status)
+	require.NotNil(t, linux.RevisionNumber)
+	assert.Equal(t, 2, *linux.RevisionNumber)
+	network := gitSyncResultFor(t, run, "network")
+	assert.Equal(t, models.GitSyncScenarioUnchanged, network.Status)
+	require.NotNil(t, network.RevisionNumber)
+	assert.Equal(t, 1, *network.RevisionNumber)
+
+	// Both now come from the new commit, changed or not.
+	assert.Equal(t, sha, loadScenarioByName(t, db, linux.Name).GitCommitSHA)
+	assert.Equal(t, sha, loadScenarioByName(t, db, network.Name).GitCommitSHA)
+
+	step := draftStep(t, db, *linux.ScenarioID, 0)
+	assert.Equal(t, "List files, hidden ones too", step.TextContent)
+
+	syncs, err := syncSvc.ListSyncs(source.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, syncs, 2)
+}
+
+func TestGitSync_ReportsInvalidScenariosAndSyncsTheOthers(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "git-author")
+	createTestScenarioForOrg(t, db, orgID, "hand-made")
+	repo := newGitTestRepo(t)
+	repo.writeScenario("good", "Good Lab", "Do something")
+	repo.writeFile("broken/index.json", `{"title": "Broken",`)
+	repo.writeScenario("untitled", "", "Do something")
+	repo.writeScenario("twin", "Good Lab", "Same name as good")
+	repo.writeScenario("clash", "Hand Made", "Same name as a scenario made in the editor")
+	repo.push("Add labs")
+
+	source := registerGitSource(t, db, orgID, repo.bare, "")
+	run, err := services.NewScenarioGitSyncService(db).Sync(source.ID, models.GitSyncTriggerManual)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.GitSyncStatusPartial, run.Status)
+	assert.Contains(t, run.Error, "4 of 5")
+	assert.Equal(t, models.GitSyncScenarioImported, gitSyncResultFor(t, run, "good").Status)
+	for path, message := range map[string]string{
+		"broken":   "index.json",
+		"untitled": "title is required",
+		"twin":     "also declared in good",
+		"clash":    "not synced from this repository",
+	} {
+		result := gitSyncResultFor(t, run, path)
+		assert.Equal(t, models.GitSyncScenarioFailed, result.Status, path)
+		assert.Contains(t, result.Error, message, path)
+	}
+
+	handMade := loadScenarioByName(t, db, "hand-made")
+	assert.Equal(t, "seed", handMade.SourceType, "a scenario not synced from the repository must be left alone")
+	assert.Nil(t, handMade.GitSourceID)
+}
+
+func TestGitSync_UnreachableRepositoryIsRecorded(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "git-author")
+	source := registerGitSource(t, db, orgID, filepath.Join(t.TempDir(), "missing"), "")
+
+	run, err := services.NewScenarioGitSyncService(db).Sync(source.ID, models.GitSyncTriggerManual)
+	require.NoError(t, err)
+	assert.Equal(t, models.GitSyncStatusFailed, run.Status)
+	assert.NotEmpty(t, run.Error)
+	assert.Empty(t, run.Results)
+
+	var reloaded models.ScenarioGitSource
+	require.NoError(t, db.First(&reloaded, "id = ?", source.ID).Error)
+	assert.Equal(t, models.GitSyncStatusFailed, reloaded.LastSyncStatus)
+	assert.Equal(t, run.Error, reloaded.LastSyncError)
+}
+
+func TestGitSync_SymlinksAreNotFollowed(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "git-author")
+
+	// Enough ".." to reach / from wherever the repository is checked out.
+	target := strings.Repeat("../", 32) + "etc/passwd"
+	if _, err := os.Stat("/etc/passwd"); err != nil {
+		t.Skip("needs /etc/passwd")
+	}
+	repo := newGitTestRepo(t)
+	repo.writeScenario("lab", "Symlink Lab", "placeholder")
+	require.NoError(t, os.Remove(filepath.Join(repo.work, "lab", "step1.md")))
+	require.NoError(t, os.Symlink(target, filepath.Join(repo.work, "lab", "step1.md")))
+	repo.push("Point a step at a server file")
+
+	source := registerGitSource(t, db, orgID, repo.bare, "")
+	run, err := services.NewScenarioGitSyncService(db).Sync(source.ID, models.GitSyncTriggerManual)
+	require.NoError(t, err)
+	result := gitSyncResultFor(t, run, "lab")
+	require.NotNil(t, result.ScenarioID)
+
+	step := draftStep(t, db, *result.ScenarioID, 0)
+	assert.NotContains(t, step.TextContent, "root:")
+}
+
+func TestValidateRepositoryURL(t *testing.T) {
+	for url, valid := range map[string]bool{
+		"https://github.com/org/labs.git":    true,
+		"ssh://git@github.com/org/labs.git":  true,
+		"git@github.com:org/labs.git":        true,
+		"http://github.com/org/labs.git":     false,
+		"file:///etc":                        false,
+		"/var/lib/ocf":                       false,
+		"../labs":                            false,
+		"https:///no-host":                   false,
+		"git@github.com:/etc/passwd-lookout": false,
+	} {
+		err := services.ValidateRepositoryURL(url)
+		if valid {
+			assert.NoError(t, err, url)
+		} else {
+			assert.ErrorIs(t, err, services.ErrInvalidRepositoryURL, url)
+		}
+	}
+}
+
+func TestRegisterGitSource_SSHKeyMustBeTheCallers(t *testing.T) {
+	db := setupTestDB(t)
+	t.Setenv("FIELD_ENCRYPTION_SECRET", "git-sync-test-secret")
+	require.NoError(t, db.AutoMigrate(&authModels.SshKey{}))
+	orgID := createTestOrg(t, db, "git-author")
+
+	key := &authModels.SshKey{KeyName: "deploy", PrivateKey: "not a real key"}
+	key.OwnerIDs = append(key.OwnerIDs, "someone-else")
+	require.NoError(t, db.Create(key).Error)
+	t.Cleanup(func() { db.Unscoped().Delete(key) })
+
+	syncSvc := services.NewScenarioGitSyncService(db)
+	_, err := syncSvc.RegisterSource(orgID, "git-author", dto.CreateScenarioGitSourceInput{
+		RepositoryURL: "git@github.com:org/labs.git",
+	})
+	assert.ErrorIs(t, err, services.ErrSSHKeyRequired)
+
+	_, err = syncSvc.RegisterSource(orgID, "git-author", dto.CreateScenarioGitSourceInput{
+		RepositoryURL: "git@github.com:org/labs.git",
+		SshKeyID:      &key.ID,
+	})
+	assert.ErrorIs(t, err, services.ErrSSHKeyNotFound)
+
+	source, err := syncSvc.RegisterSource(orgID, "someone-else", dto.CreateScenarioGitSourceInput{
+		RepositoryURL: "git@github.com:org/labs.git",
+		SshKeyID:      &key.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "main", source.Branch)
+}
+
+// setupGitSourceRouter serves the git source routes with their declared
+// permissions, as userID.
+func setupGitSourceRouter(t *testing.T, db *gorm.DB, userID string) *gin.Engine {
+	t.Helper()
+	access.RouteRegistry.Reset()
+	access.ResetEnforcers()
+	t.Cleanup(func() {
+		access.RouteRegistry.Reset()
+		access.ResetEnforcers()
+	})
+	scenarioController.RegisterScenarioPermissions(mocks.NewMockEnforcer())
+	access.RegisterBuiltinEnforcers(nil, access.NewGormMembershipChecker(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ctrl := scenarioController.NewScenarioGitSourceController(db)
+	r.POST("/api/v1/scenario-git-sources/:id/webhook", ctrl.HandlePushWebhook)
+
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("userId", userID)
+		c.Set("userRoles", []string{"Member"})
+		c.Next()
+	})
+	api.Use(access.Layer2Enforcement())
+	sources := api.Group("/organizations/:id/scenario-git-sources")
+	sources.POST("", ctrl.CreateGitSource)
+	sources.POST("/:sourceId/sync", ctrl.SyncGitSource)
+	sources.GET("/:sourceId/syncs", ctrl.ListGitSyncs)
+	return r
+}
+
+func TestGitSourceRoutes_OrgManagersOnly(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "org-owner")
+	addOrgMember(t, db, orgID, "org-manager", orgModels.OrgRoleManager)
+	addOrgMember(t, db, orgID, "org-member", orgModels.OrgRoleMember)
+
+	register := func(userID, repoURL string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"repository_url": repoURL})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/organizations/"+orgID.String()+"/scenario-git-sources", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		setupGitSourceRouter(t, db, userID).ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, register("org-member", "https://github.com/org/labs.git").Code)
+	assert.Equal(t, http.StatusBadRequest, register("org-manager", "/etc").Code)
+
+	w := register("org-manager", "https://github.com/org/labs.git")
+	require.Equal(t, http.StatusCreated, w.Code, "body: %s", w.Body.String())
+	var created dto.ScenarioGitSourceWithSecret
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.WebhookSecret)
+	assert.Equal(t, "/api/v1/scenario-git-sources/"+created.ID.String()+"/webhook", created.WebhookPath)
+
+	// The secret is shown once.
+	listed, err := json.Marshal(created.ScenarioGitSource)
+	require.NoError(t, err)
+	assert.NotContains(t, string(listed), created.WebhookSecret)
+
+	// and is not stored in clear.
+	var raw string
+	require.NoError(t, db.Raw("SELECT webhook_secret FROM scenario_git_sources WHERE id = ?", created.ID).Scan(&raw).Error)
+	assert.True(t, strings.HasPrefix(raw, "enc::v1:"), "the webhook secret must not be stored in clear")
+	assert.NotContains(t, raw, created.WebhookSecret)
+}
+
+// waitForWebhookSync waits for a sync triggered by the push webhook to finish.
+func waitForWebhookSync(t *testing.T, db *gorm.DB, sourceID uuid.UUID) models.ScenarioGitSync {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var run models.ScenarioGitSync
+		err := db.Where("source_id = ? AND trigger = ? AND status <> ?",
+			sourceID, models.GitSyncTriggerWebhook, models.GitSyncStatusRunning).First(&run).Error
+		if err == nil {
+			return run
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the webhook sync")
+	return models.ScenarioGitSync{}
+}
+
+func TestGitSourceWebhook_SyncsOnSignedPushToTheBranch(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := createTestOrg(t, db, "git-author")
+	repo := newGitTestRepo(t)
+	repo.writeScenario("lab", "Webhook Lab", "Do something")
+	sha := repo.push("Add lab")
+	source := registerGitSource(t, db, orgID, repo.bare, "")
+	router := setupGitSourceRouter(t, db, "")
+
+	deliver := func(payload string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/scenario-git-sources/"+source.ID.String()+"/webhook", strings.NewReader(payload))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	sign := func(payload string) string {
+		mac := hmac.New(sha256.New, []byte(source.WebhookSecret))
+		mac.Write([]byte(payload))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	push := `{"ref": "refs/heads/main"}`
+	assert.Equal(t, http.StatusUnauthorized, deliver(push, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, deliver(push, map[string]string{
+		services.GitHubSignatureHeader: "sha256=" + sign(`{"ref": "refs/heads/other"}`),
+	}).Code)
+	assert.Equal(t, http.StatusUnauthorized, deliver(push, map[string]string{
+		services.GitLabTokenHeader: "wrong",
+	}).Code)
+
+	for _, ignored := range []string{`{"ref": "refs/heads/feature"}`, `{"ref": "refs/tags/v1"}`} {
+		w := deliver(ignored, map[string]string{services.GiteaSignatureHeader: sign(ignored)})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), "ignored")
+	}
+	var count int64
+	db.Model(&models.ScenarioGitSync{}).Where("source_id = ?", source.ID).Count(&count)
+	assert.Zero(t, count, "pushes to other refs must not sync")
+
+	w := deliver(push, map[string]string{services.GitHubSignatureHeader: "sha256=" + sign(push)})
+	require.Equal(t, http.StatusAccepted, w.Code, "body: %s", w.Body.String())
+	run := waitForWebhookSync(t, db, source.ID)
+	assert.Equal(t, models.GitSyncStatusSucceeded, run.Status)
+	assert.Equal(t, sha, run.CommitSHA)
+	assert.Equal(t, sha, loadScenarioByName(t, db, run.Results[0].Name).GitCommitSHA)
+}
+
+func TestVerifyPushWebhook_GitLabToken(t *testing.T) {
+	header := http.Header{}
+	header.Set(services.GitLabTokenHeader, "whsec_gitlab")
+	assert.NoError(t, services.VerifyPushWebhook("whsec_gitlab", header, []byte(`{}`)))
+	assert.ErrorIs(t, services.VerifyPushWebhook("whsec_other", header, []byte(`{}`)), services.ErrInvalidPushSignature)
+}
@@ -0,0 +1,244 @@
+package scenarios_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	access "soli/formations/src/auth/access"
+	"soli/formations/src/auth/mocks"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	scenarioController "soli/formations/src/scenarios/routes"
+	"soli/formations/src/scenarios/services"
+)
+
+// scenario_linter_test.go — the linter reports, per step, what would make a
+// scenario fail for learners although import accepts it, and what import
+// would refuse, all at once instead of the first error.
+
+// lintCodes returns the issue codes of a report by step position, e.g.
+// "2:flag_not_placed"; scenario-level issues are at 0.
+func lintCodes(report *dto.ScenarioLintReport) []string {
+	codes := make([]string, len(report.Issues))
+	for i, issue := range report.Issues {
+		codes[i] = fmt.Sprintf("%d:%s", issue.Step, issue.Code)
+	}
+	return codes
+}
+
+func TestLintSeedInput_CleanScenarioIsValid(t *testing.T) {
+	linter := services.NewScenarioLinter(nil, nil)
+	report := linter.LintSeedInput(dto.SeedScenarioInput{
+		Title:        "Clean",
+		FlagsEnabled: true,
+		SetupScript:  "#!/bin/bash\napt-get update",
+		Steps: []dto.SeedStepInput{
+			{Title: "Create a file", VerifyScript: "#!/bin/sh\ntest -f /tmp/x"},
+			{Title: "Find the flag", HasFlag: true, FlagPath: "/home/student/flag.txt"},
+			{Title: "Placed by setup", HasFlag: true, BackgroundScript: "#!/bin/bash\necho \"$OCF_FLAG_CURRENT\" > /root/.f"},
+			{Title: "Quiz", StepType: "quiz", Questions: []dto.SeedQuestionInput{
+				{QuestionText: "2+2?", QuestionType: models.QuestionTypeNumeric, CorrectAnswer: "4"},
+			}},
+		},
+	})
+
+	assert.True(t, report.Valid, "issues: %v", lintCodes(report))
+	assert.Empty(t, report.Issues)
+}
+
+func TestLintSeedInput_ReportsEveryBrokenStep(t *testing.T) {
+	linter := services.NewScenarioLinter(nil, nil)
+	report := linter.LintSeedInput(dto.SeedScenarioInput{
+		Title:            "Broken",
+		FlagsEnabled:     true,
+		AllowedFlagPaths: "/opt/",
+		Steps: []dto.SeedStepInput{
+			{Title: "No shebang", VerifyScript: "test -f /tmp/x"},
+			{Title: "Flag nobody places", HasFlag: true},
+			{Title: "Flag outside the allowed paths", HasFlag: true, FlagPath: "/tmp/flag"},
+			{Title: "Quiz without an answer", StepType: "quiz", QuizDrawCount: 3, Questions: []dto.SeedQuestionInput{
+				{QuestionText: "Which?", QuestionType: models.QuestionTypeMultipleChoice, Options: `["a","b"]`},
+				{QuestionText: "Which again?", QuestionType: models.QuestionTypeMultipleChoice, Options: `["a","b"]`, CorrectAnswer: "c"},
+			}},
+			{Title: "Dangling transition", Transitions: []dto.StepTransitionSpec{
+				{Condition: models.TransitionAlways, TargetStep: 9},
+			}},
+		},
+	})
+
+	assert.False(t, report.Valid)
+	assert.ElementsMatch(t, []string{
+		"1:missing_shebang",
+		"2:flag_not_placed",
+		"3:flag_path_not_allowed",
+		"4:quiz_no_correct_answer",
+		"4:quiz_answer_not_in_options",
+		"4:quiz_draw_exceeds_pool",
+		"5:transition_target_missing",
+		"5:no_verify_script",
+	}, lintCodes(report))
+	assert.Equal(t, 5, report.Errors)
+	assert.Equal(t, 3, report.Warnings)
+	for _, issue := range report.Issues {
+		if issue.Code == "flag_path_not_allowed" {
+			assert.Equal(t, "Flag outside the allowed paths", issue.StepTitle)
+			assert.Equal(t, "flag_path", issue.Field)
+		}
+	}
+}
+
+func TestLintSeedInput_FlagStepWithoutFlags(t *testing.T) {
+	linter := services.NewScenarioLinter(nil, nil)
+	report := linter.LintSeedInput(dto.SeedScenarioInput{
+		Title: "Flags off",
+		Steps: []dto.SeedStepInput{
+			{Title: "Flag", StepType: "flag", FlagPath: "/tmp/flag"},
+		},
+	})
+
+	assert.ElementsMatch(t, []string{"1:flags_disabled", "1:flag_path_unused"}, lintCodes(report))
+}
+
+func TestLintSeedInput_ChecksFeaturesAgainstCatalog(t *testing.T) {
+	catalog := func() ([]string, error) { return []string{"network"}, nil }
+	input := dto.SeedScenarioInput{
+		Title:            "Features",
+		RequiredFeatures: []string{"network"},
+		BuildFeatures:    []string{"gpu"},
+		Steps:            []dto.SeedStepInput{{Title: "Step", VerifyScript: "#!/bin/sh\ntrue"}},
+	}
+
+	report := services.NewScenarioLinter(nil, catalog).LintSeedInput(input)
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, "unknown_feature", report.Issues[0].Code)
+	assert.Equal(t, "build_features", report.Issues[0].Field)
+	assert.Contains(t, report.Issues[0].Message, `"gpu"`)
+
+	unreachable := func() ([]string, error) { return nil, errors.New("tt-backend down") }
+	report = services.NewScenarioLinter(nil, unreachable).LintSeedInput(input)
+	assert.True(t, report.Valid, "an unreachable catalog must not fail the scenario")
+	assert.Equal(t, []string{"0:feature_catalog_unavailable"}, lintCodes(report))
+
+	report = services.NewScenarioLinter(nil, nil).LintSeedInput(input)
+	assert.Empty(t, report.Issues, "features are not checked without a catalog")
+}
+
+func TestLintDirectory_ReportsMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "step1"), 0755))
+	writeTestFile(t, dir, "index.json", `{
+		"title": "Missing files",
+		"details": {
+			"intro": {"text": "intro.md"},
+			"steps": [{"title": "First", "text": "step1/text.md", "verify": "step1/verify.sh", "hint": "step1/hint.md"}]
+		}
+	}`)
+	writeTestFile(t, dir, "intro.md", "# Intro")
+	writeTestFile(t, dir, "step1/text.md", "Do it")
+
+	report, err := services.NewScenarioLinter(nil, nil).LintDirectory(dir)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"1:missing_file",
+		"1:missing_file",
+		"1:no_verify_script",
+	}, lintCodes(report))
+	var fields []string
+	for _, issue := range report.Issues {
+		if issue.Code == "missing_file" {
+			fields = append(fields, issue.Field)
+			assert.Equal(t, "First", issue.StepTitle)
+		}
+	}
+	assert.ElementsMatch(t, []string{"verify", "hint"}, fields)
+}
+
+func TestLintDirectory_ReportsWhatImportRefuses(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "step1"), 0755))
+	writeTestFile(t, dir, "index.json", `{
+		"title": "Bad sidecar",
+		"details": {"steps": [{"title": "Quiz", "text": "step1/text.md"}]}
+	}`)
+	writeTestFile(t, dir, "step1/text.md", "Answer")
+	writeTestFile(t, dir, "step1/extensions.json", `{
+		"step_type": "quiz",
+		"questions": [{"question_text": "How many?", "question_type": "numeric", "correct_answer": "many"}]
+	}`)
+
+	report, err := services.NewScenarioLinter(nil, nil).LintDirectory(dir)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{"0:invalid_scenario"}, lintCodes(report))
+
+	_, err = services.NewScenarioLinter(nil, nil).LintDirectory(t.TempDir())
+	assert.Error(t, err, "a directory without index.json is not a scenario")
+}
+
+// setupLintRouter wires the lint routes as production registers them, behind
+// the same Layer 2 enforcement.
+func setupLintRouter(t *testing.T, db *gorm.DB, userID string) *gin.Engine {
+	t.Helper()
+	access.RouteRegistry.Reset()
+	access.ResetEnforcers()
+	t.Cleanup(func() {
+		access.RouteRegistry.Reset()
+		access.ResetEnforcers()
+	})
+
+	scenarioController.RegisterScenarioPermissions(mocks.NewMockEnforcer())
+	access.RegisterBuiltinEnforcers(nil, access.NewGormMembershipChecker(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("userId", userID)
+		c.Set("userRoles", []string{"member"})
+		c.Next()
+	})
+	api.Use(access.Layer2Enforcement())
+
+	controller := scenarioController.NewScenarioController(db)
+	api.GET("/scenarios/:id/lint", controller.LintScenario)
+	return r
+}
+
+func TestLintScenarioEndpoint_LintsTheDraft(t *testing.T) {
+	db := freshTestDB(t)
+	orgID := createTestOrg(t, db, "lint-owner")
+	addOrgMember(t, db, orgID, "lint-manager", orgModels.OrgRoleManager)
+	scenario := createTestScenarioForOrg(t, db, orgID, "lint-draft")
+	require.NoError(t, db.Create(&models.ScenarioStep{
+		ScenarioID: scenario.ID, Order: 1, Title: "Quiz", StepType: "quiz",
+	}).Error)
+
+	lint := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/scenarios/"+scenario.ID.String()+"/lint", nil)
+		setupLintRouter(t, db, userID).ServeHTTP(w, req)
+		return w
+	}
+
+	w := lint("lint-manager")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report dto.ScenarioLintReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{"1:no_verify_script", "2:quiz_no_questions"}, lintCodes(&report))
+
+	assert.Equal(t, http.StatusForbidden, lint("lint-stranger").Code)
+}
@@ -0,0 +1,275 @@
+package scenarios_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/entityManagement/hooks"
+	groupModels "soli/formations/src/groups/models"
+	scenarioHooks "soli/formations/src/scenarios/hooks"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// scenario_revisions_test.go — publishing freezes a scenario's draft as an
+// immutable revision. Sessions run the revision they started on whatever
+// happens to the draft afterwards; authors can compare versions and roll the
+// draft back, and teachers see which revision each result was graded against.
+
+// draftStep returns the draft step of a scenario at order.
+func draftStep(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, order int) models.ScenarioStep {
+	t.Helper()
+	var step models.ScenarioStep
+	require.NoError(t, db.Scopes(models.DraftSteps).
+		Where("scenario_id = ? AND \"order\" = ?", scenarioID, order).First(&step).Error)
+	return step
+}
+
+func renameDraftStep(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, order int, title string) {
+	t.Helper()
+	step := draftStep(t, db, scenarioID, order)
+	require.NoError(t, db.Model(&step).Update("title", title).Error)
+}
+
+// startRevisionSession starts a session for userID and waits for its setup.
+func startRevisionSession(t *testing.T, db *gorm.DB, userID string, scenarioID uuid.UUID) *models.ScenarioSession {
+	t.Helper()
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	session, err := sessionSvc.StartScenario(userID, scenarioID, "terminal-"+userID)
+	require.NoError(t, err)
+	waitForSetupDone(t, db, session.ID)
+	return session
+}
+
+func TestPublishRevision_SessionsKeepTheirRevision(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-pinning", "Old first", "Old second")
+	revisionSvc := services.NewScenarioRevisionService(db)
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+
+	first, err := revisionSvc.Publish(scenario.ID, "teacher-lp", "initial")
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Number)
+
+	early := startRevisionSession(t, db, "early-learner", scenario.ID)
+	require.NotNil(t, early.RevisionID)
+	assert.Equal(t, first.ID, *early.RevisionID)
+
+	renameDraftStep(t, db, scenario.ID, 0, "New first")
+
+	current, err := sessionSvc.GetCurrentStep(early.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Old first", current.Title, "a draft edit must not reach a running session")
+	assert.Equal(t, 2, current.TotalSteps)
+
+	second, err := revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Number)
+
+	current, err = sessionSvc.GetCurrentStep(early.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Old first", current.Title, "publishing must not move a running session either")
+
+	late := startRevisionSession(t, db, "late-learner", scenario.ID)
+	require.NotNil(t, late.RevisionID)
+	assert.Equal(t, second.ID, *late.RevisionID)
+	current, err = sessionSvc.GetCurrentStep(late.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "New first", current.Title)
+}
+
+func TestPreviewScenario_RunsTheDraft(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-preview", "Published")
+	_, err := services.NewScenarioRevisionService(db).Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+	renameDraftStep(t, db, scenario.ID, 0, "Work in progress")
+
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	preview, err := sessionSvc.PreviewScenario("teacher-lp", scenario.ID, "terminal-preview-revision")
+	require.NoError(t, err)
+	waitForSetupDone(t, db, preview.ID)
+	assert.Nil(t, preview.RevisionID)
+
+	current, err := sessionSvc.GetCurrentStep(preview.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Work in progress", current.Title)
+}
+
+func TestPublishRevision_Refusals(t *testing.T) {
+	db := setupTestDB(t)
+	revisionSvc := services.NewScenarioRevisionService(db)
+
+	empty := seedScenarioWithSteps(t, db, "revision-empty")
+	_, err := revisionSvc.Publish(empty.ID, "teacher-lp", "")
+	assert.ErrorIs(t, err, services.ErrNoStepsToPublish)
+
+	scenario := seedScenarioWithSteps(t, db, "revision-unchanged", "Only step")
+	_, err = revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+	_, err = revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	assert.ErrorIs(t, err, services.ErrNothingToPublish)
+
+	require.NoError(t, db.Create(&models.ScenarioStepHint{
+		StepID: draftStep(t, db, scenario.ID, 0).ID, Level: 1, Content: "look closer",
+	}).Error)
+	_, err = revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	assert.NoError(t, err, "a new hint is a change")
+}
+
+func TestDiffRevisions_ReportsStepChanges(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-diff", "Kept", "Edited", "Dropped")
+	revisionSvc := services.NewScenarioRevisionService(db)
+	_, err := revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+
+	edited := draftStep(t, db, scenario.ID, 1)
+	require.NoError(t, db.Model(&edited).Update("text_content", "new instructions").Error)
+	dropped := draftStep(t, db, scenario.ID, 2)
+	require.NoError(t, db.Delete(&dropped).Error)
+	require.NoError(t, db.Create(&models.ScenarioStep{
+		ScenarioID: scenario.ID, Order: 3, Title: "Added", StepType: "terminal",
+	}).Error)
+
+	diff, err := revisionSvc.Diff(scenario.ID, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1", diff.From)
+	assert.Equal(t, "draft", diff.To)
+	assert.False(t, diff.SetupScriptChanged)
+	require.Len(t, diff.Steps, 3)
+	assert.Equal(t, 1, diff.Steps[0].Order)
+	assert.Equal(t, "changed", diff.Steps[0].Change)
+	assert.Equal(t, []string{"text"}, diff.Steps[0].Fields)
+	assert.Equal(t, 2, diff.Steps[1].Order)
+	assert.Equal(t, "removed", diff.Steps[1].Change)
+	assert.Equal(t, 3, diff.Steps[2].Order)
+	assert.Equal(t, "added", diff.Steps[2].Change)
+
+	_, err = revisionSvc.Diff(scenario.ID, "7", "draft")
+	assert.ErrorIs(t, err, services.ErrRevisionNotFound)
+}
+
+func TestRollbackRevision_RestoresDraftAndCurrentRevision(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-rollback", "Original")
+	revisionSvc := services.NewScenarioRevisionService(db)
+	first, err := revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+	renameDraftStep(t, db, scenario.ID, 0, "Regrettable")
+	_, err = revisionSvc.Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+
+	rolledBack, err := revisionSvc.Rollback(scenario.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, rolledBack.ID)
+	assert.Equal(t, "Original", draftStep(t, db, scenario.ID, 0).Title)
+
+	var reloaded models.Scenario
+	require.NoError(t, db.First(&reloaded, "id = ?", scenario.ID).Error)
+	require.NotNil(t, reloaded.PublishedRevisionID)
+	assert.Equal(t, first.ID, *reloaded.PublishedRevisionID)
+
+	revisions, err := revisionSvc.ListRevisions(scenario.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2, "rolling back deletes no revision")
+	assert.Equal(t, 2, revisions[0].Number)
+	assert.False(t, revisions[0].Current)
+	assert.True(t, revisions[1].Current)
+	assert.Equal(t, int64(1), revisions[1].StepCount)
+
+	_, err = revisionSvc.Rollback(scenario.ID, 9)
+	assert.ErrorIs(t, err, services.ErrRevisionNotFound)
+}
+
+func TestTeacherResults_ShowGradingRevision(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-results", "One", "Two")
+	group := createClassGroup(t, db, "revision-class", "teacher-lp", nil)
+	addGroupMember(t, db, group.ID, "revision-student", groupModels.GroupMemberRoleMember)
+	createScenarioAssignment(t, db, scenario.ID, &group.ID, nil, "group")
+
+	_, err := services.NewScenarioRevisionService(db).Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+	session := startRevisionSession(t, db, "revision-student", scenario.ID)
+	// A third draft step must not count against a session on revision 1.
+	require.NoError(t, db.Create(&models.ScenarioStep{
+		ScenarioID: scenario.ID, Order: 2, Title: "Three", StepType: "terminal",
+	}).Error)
+
+	dashboardSvc := services.NewTeacherDashboardService(db, nil, nil)
+	results, err := dashboardSvc.GetScenarioResults(group.ID, scenario.ID, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, results.Items, 1)
+	require.NotNil(t, results.Items[0].RevisionNumber)
+	assert.Equal(t, 1, *results.Items[0].RevisionNumber)
+	assert.Equal(t, int64(2), results.Items[0].TotalSteps)
+
+	detail, err := dashboardSvc.GetSessionDetail(group.ID, session.ID)
+	require.NoError(t, err)
+	require.NotNil(t, detail.RevisionNumber)
+	assert.Equal(t, 1, *detail.RevisionNumber)
+	require.Len(t, detail.Steps, 2)
+	assert.Equal(t, "One", detail.Steps[0].StepTitle)
+
+	bulk, err := dashboardSvc.GetSessionDetails(group.ID, []uuid.UUID{session.ID})
+	require.NoError(t, err)
+	require.Len(t, bulk, 1)
+	assert.Equal(t, detail, bulk[0])
+}
+
+func TestExportScenario_LeavesRevisionsOut(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-export", "One", "Two")
+	_, err := services.NewScenarioRevisionService(db).Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+
+	export, err := services.NewScenarioExportService(db).ExportAsJSON(scenario.ID)
+	require.NoError(t, err)
+	assert.Len(t, export.Steps, 2)
+}
+
+func TestRevisionImmutabilityHooks_RefuseRevisionContent(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := seedScenarioWithSteps(t, db, "revision-immutable", "Frozen")
+	revision, err := services.NewScenarioRevisionService(db).Publish(scenario.ID, "teacher-lp", "")
+	require.NoError(t, err)
+	var frozen models.ScenarioStep
+	require.NoError(t, db.Where("revision_id = ?", revision.ID).First(&frozen).Error)
+	draft := draftStep(t, db, scenario.ID, 0)
+
+	byEntity := make(map[string]hooks.Hook)
+	for _, hook := range scenarioHooks.NewRevisionImmutabilityHooks(db) {
+		byEntity[hook.GetEntityName()] = hook
+	}
+
+	// Admins included: nothing may change a revision.
+	update := func(step *models.ScenarioStep) error {
+		return byEntity["ScenarioStep"].Execute(&hooks.HookContext{
+			EntityName: "ScenarioStep", HookType: hooks.BeforeUpdate,
+			OldEntity: step, NewEntity: map[string]any{"title": "x"},
+			UserID: "admin", UserRoles: []string{"administrator"},
+		})
+	}
+	assert.Error(t, update(&frozen))
+	assert.NoError(t, update(&draft))
+
+	addQuestion := func(stepID uuid.UUID) error {
+		return byEntity["ScenarioStepQuestion"].Execute(&hooks.HookContext{
+			EntityName: "ScenarioStepQuestion", HookType: hooks.BeforeCreate,
+			NewEntity: &models.ScenarioStepQuestion{StepID: stepID, QuestionText: "?"},
+			UserID:    "teacher-lp",
+		})
+	}
+	assert.Error(t, addQuestion(frozen.ID))
+	assert.NoError(t, addQuestion(draft.ID))
+
+	assert.Error(t, byEntity["ScenarioStepHint"].Execute(&hooks.HookContext{
+		EntityName: "ScenarioStepHint", HookType: hooks.BeforeDelete,
+		NewEntity: &models.ScenarioStepHint{StepID: frozen.ID, Level: 1},
+	}))
+}
@@ -0,0 +1,236 @@
+package scenarios_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// scenario_test_runner_test.go — the runner plays each step's background,
+// solution and verify scripts through ContainerCLIVerificationService. A fake
+// container runtime executes `exec` commands on the host, so the scenarios
+// below work in a temporary directory instead of a container.
+
+// fakeRuntime writes a stand-in for the docker CLI that drops the
+// `exec -i [-e NAME]... <container>` prefix and runs the command locally.
+func fakeRuntime(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-docker")
+	script := `#!/bin/sh
+shift 2
+while [ "$1" = "-e" ]; do shift 2; done
+shift
+exec "$@"
+`
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func runScenarioTest(t *testing.T, scenario *models.Scenario) *dto.ScenarioTestReport {
+	t.Helper()
+	runner := services.NewScenarioTestRunner(services.NewContainerCLIVerificationService(fakeRuntime(t)))
+	report, err := runner.Run(scenario, "test-container")
+	require.NoError(t, err)
+	return report
+}
+
+func stepStatuses(report *dto.ScenarioTestReport) []string {
+	statuses := make([]string, len(report.Steps))
+	for i, step := range report.Steps {
+		statuses[i] = step.Status
+	}
+	return statuses
+}
+
+func TestScenarioTestRunner_PlaysSolutionsThroughVerify(t *testing.T) {
+	work := t.TempDir()
+	scenario := &models.Scenario{
+		Title:       "Files",
+		SetupScript: fmt.Sprintf("#!/bin/sh\nmkdir -p %s/home", work),
+		Steps: []models.ScenarioStep{
+			{
+				Order:            0,
+				Title:            "Create a file",
+				BackgroundScript: fmt.Sprintf("#!/bin/sh\ntest -d %s/home", work),
+				SolutionScript:   fmt.Sprintf("#!/bin/sh\ntouch %s/home/hello", work),
+				VerifyScript:     fmt.Sprintf("#!/bin/sh\ntest -f %s/home/hello", work),
+			},
+			{Order: 1, Title: "Read on", StepType: "info"},
+			{Order: 2, Title: "Quiz", StepType: "quiz"},
+			{Order: 3, Title: "No solution yet", VerifyScript: "#!/bin/sh\nfalse"},
+		},
+	}
+
+	report := runScenarioTest(t, scenario)
+
+	assert.True(t, report.Passed)
+	assert.Equal(t, []string{
+		services.ScenarioTestPassed,
+		services.ScenarioTestPassed,
+		services.ScenarioTestSkipped,
+		services.ScenarioTestUntested,
+	}, stepStatuses(report))
+	assert.False(t, report.Steps[0].VerifyPassedBeforeSolution)
+}
+
+func TestScenarioTestRunner_StopsAtTheFirstFailure(t *testing.T) {
+	work := t.TempDir()
+	scenario := &models.Scenario{
+		Title: "Broken",
+		Steps: []models.ScenarioStep{
+			{
+				Order:          0,
+				Title:          "Solution misses the mark",
+				SolutionScript: fmt.Sprintf("#!/bin/sh\ntouch %s/wrong", work),
+				VerifyScript:   fmt.Sprintf("#!/bin/sh\ntest -f %s/right || { echo 'right is missing' >&2; exit 3; }", work),
+			},
+			{Order: 1, Title: "Never played", SolutionScript: "#!/bin/sh\ntrue", VerifyScript: "#!/bin/sh\ntrue"},
+		},
+	}
+
+	report := runScenarioTest(t, scenario)
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, []string{services.ScenarioTestFailed, services.ScenarioTestSkipped}, stepStatuses(report))
+	failed := report.Steps[0]
+	assert.Equal(t, "verify", failed.Phase)
+	require.NotNil(t, failed.ExitCode)
+	assert.Equal(t, 3, *failed.ExitCode)
+	assert.Contains(t, failed.Output, "right is missing")
+}
+
+func TestScenarioTestRunner_ReportsFailingScripts(t *testing.T) {
+	t.Run("background", func(t *testing.T) {
+		report := runScenarioTest(t, &models.Scenario{Steps: []models.ScenarioStep{
+			{Order: 0, Title: "Broken provisioning", BackgroundScript: "#!/bin/sh\nfalse\ntrue"},
+		}})
+		assert.False(t, report.Passed)
+		assert.Equal(t, "background", report.Steps[0].Phase, "set -e is injected as in a session")
+	})
+
+	t.Run("setup", func(t *testing.T) {
+		report := runScenarioTest(t, &models.Scenario{
+			SetupScript: "#!/bin/sh\nexit 1",
+			Steps:       []models.ScenarioStep{{Order: 0, Title: "First"}, {Order: 1, Title: "Second"}},
+		})
+		assert.False(t, report.Passed)
+		assert.Equal(t, "setup", report.Steps[0].Phase)
+		assert.Equal(t, []string{services.ScenarioTestFailed, services.ScenarioTestSkipped}, stepStatuses(report))
+	})
+
+	t.Run("solution", func(t *testing.T) {
+		report := runScenarioTest(t, &models.Scenario{Steps: []models.ScenarioStep{
+			{Order: 0, Title: "Bad solution", SolutionScript: "#!/bin/sh\necho oops >&2\nexit 2", VerifyScript: "#!/bin/sh\ntrue"},
+		}})
+		assert.False(t, report.Passed)
+		assert.Equal(t, "solution", report.Steps[0].Phase)
+		assert.Contains(t, report.Steps[0].Message, "oops")
+	})
+}
+
+func TestScenarioTestRunner_FlagStepsSubmitTheSolutionOutput(t *testing.T) {
+	work := t.TempDir()
+	flagPath := filepath.Join(work, "flag.txt")
+	scenario := &models.Scenario{
+		Title:            "Find the flag",
+		FlagsEnabled:     true,
+		AllowedFlagPaths: work + "/",
+		Steps: []models.ScenarioStep{
+			{
+				Order:          0,
+				Title:          "Deployed flag",
+				StepType:       "flag",
+				HasFlag:        true,
+				FlagPath:       flagPath,
+				SolutionScript: "#!/bin/sh\necho looking...\ncat " + flagPath,
+			},
+			{
+				Order:            1,
+				Title:            "Flag placed by the background script",
+				HasFlag:          true,
+				BackgroundScript: fmt.Sprintf("#!/bin/sh\necho \"$OCF_FLAG_CURRENT\" > %s/hidden", work),
+				SolutionScript:   fmt.Sprintf("#!/bin/sh\ncat %s/hidden", work),
+			},
+			{
+				Order:          2,
+				Title:          "Wrong answer",
+				StepType:       "flag",
+				HasFlag:        true,
+				SolutionScript: "#!/bin/sh\necho FLAG{nope}",
+			},
+		},
+	}
+
+	report := runScenarioTest(t, scenario)
+
+	assert.Equal(t, []string{
+		services.ScenarioTestPassed,
+		services.ScenarioTestPassed,
+		services.ScenarioTestFailed,
+	}, stepStatuses(report))
+	assert.False(t, report.Passed)
+	assert.Empty(t, scenario.FlagSecret, "the scenario given to the runner is not modified")
+}
+
+func TestScenarioTestRunner_FlagsVerifyScriptsThatCheckNothing(t *testing.T) {
+	report := runScenarioTest(t, &models.Scenario{Steps: []models.ScenarioStep{
+		{Order: 0, Title: "Always passes", SolutionScript: "#!/bin/sh\ntrue", VerifyScript: "#!/bin/sh\nexit 0"},
+	}})
+
+	assert.True(t, report.Passed)
+	assert.True(t, report.Steps[0].VerifyPassedBeforeSolution)
+}
+
+func TestSolutionScript_RoundTripsThroughTheArchive(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := models.Scenario{
+		Name:         "archive-solution",
+		Title:        "Archive solution",
+		InstanceType: "ubuntu:24.04",
+		CreatedByID:  "creator-1",
+		Steps: []models.ScenarioStep{
+			{Order: 0, Title: "Solved", TextContent: "do it", VerifyScript: "#!/bin/sh\ntest -f /tmp/x", SolutionScript: "#!/bin/sh\ntouch /tmp/x"},
+			{Order: 1, Title: "Unsolved", TextContent: "then this"},
+		},
+	}
+	require.NoError(t, db.Create(&scenario).Error)
+
+	archive, _, err := services.NewScenarioExportService(db).ExportAsArchive(scenario.ID)
+	require.NoError(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(f.Name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), content, 0o644))
+	}
+
+	loaded, err := services.LoadScenarioSource(dir)
+	require.NoError(t, err)
+	require.Len(t, loaded.Steps, 2)
+	assert.Equal(t, "#!/bin/sh\ntouch /tmp/x", loaded.Steps[0].SolutionScript)
+	assert.Empty(t, loaded.Steps[1].SolutionScript)
+
+	out, err := services.NewScenarioExportService(db).ExportAsJSON(scenario.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\ntouch /tmp/x", out.Steps[0].SolutionScript)
+}
@@ -0,0 +1,98 @@
+package scenarios_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+	webhookModels "soli/formations/src/webhooks/models"
+	webhookServices "soli/formations/src/webhooks/services"
+)
+
+// session_webhooks_test.go — finishing a scenario raises the
+// scenario_session.completed and .graded webhooks for the organizations that
+// assigned it, and for no one else.
+
+func subscribeOrg(t *testing.T, db *gorm.DB, orgID uuid.UUID) {
+	t.Helper()
+	require.NoError(t, db.Create(&webhookModels.WebhookSubscription{
+		OrganizationID: orgID,
+		URL:            "https://hooks.example.com/ocf",
+		Events:         []string{webhookServices.EventScenarioSessionCompleted, webhookServices.EventScenarioSessionGraded},
+		Secret:         "whsec_test",
+		IsActive:       true,
+		CreatedByID:    "manager-1",
+	}).Error)
+}
+
+func queuedDeliveries(t *testing.T, db *gorm.DB, orgID uuid.UUID) []webhookModels.WebhookDelivery {
+	t.Helper()
+	var deliveries []webhookModels.WebhookDelivery
+	require.NoError(t, db.Where("organization_id = ?", orgID).Order("event_type").Find(&deliveries).Error)
+	return deliveries
+}
+
+func TestSessionCompletion_PublishesToAssigningOrganization(t *testing.T) {
+	db := freshTestDB(t)
+	session := flaggedTwoStepSession(t, db, "webhook-complete", models.ScenarioStep{})
+
+	orgID := uuid.New()
+	otherOrgID := uuid.New()
+	group := createClassGroup(t, db, "webhook-class", "teacher-1", &orgID)
+	addGroupMember(t, db, group.ID, session.UserID, groupModels.GroupMemberRoleMember)
+	createScenarioAssignment(t, db, session.ScenarioID, &group.ID, nil, "group")
+	subscribeOrg(t, db, orgID)
+	subscribeOrg(t, db, otherOrgID)
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: true}, &mockVerificationService{})
+	_, err := svc.SubmitFlag(session.ID, "flag{step-zero}")
+	require.NoError(t, err)
+	assert.Empty(t, queuedDeliveries(t, db, orgID), "an intermediate step raises nothing")
+
+	_, err = svc.SubmitFlag(session.ID, "flag{step-one}")
+	require.NoError(t, err)
+
+	deliveries := queuedDeliveries(t, db, orgID)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, webhookServices.EventScenarioSessionCompleted, deliveries[0].EventType)
+	assert.Equal(t, webhookServices.EventScenarioSessionGraded, deliveries[1].EventType)
+
+	var event struct {
+		Data struct {
+			SessionID uuid.UUID `json:"session_id"`
+			UserID    string    `json:"user_id"`
+			Status    string    `json:"status"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &event))
+	assert.Equal(t, session.ID, event.Data.SessionID)
+	assert.Equal(t, session.UserID, event.Data.UserID)
+	assert.Equal(t, "completed", event.Data.Status)
+
+	assert.Empty(t, queuedDeliveries(t, db, otherOrgID), "an organization that did not assign the scenario hears nothing")
+}
+
+func TestSessionCompletion_PreviewPublishesNothing(t *testing.T) {
+	db := freshTestDB(t)
+	session := flaggedTwoStepSession(t, db, "webhook-preview", models.ScenarioStep{})
+	require.NoError(t, db.Model(&models.ScenarioSession{}).Where("id = ?", session.ID).Update("is_preview", true).Error)
+
+	orgID := uuid.New()
+	createScenarioAssignment(t, db, session.ScenarioID, nil, &orgID, "org")
+	subscribeOrg(t, db, orgID)
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: true}, &mockVerificationService{})
+	_, err := svc.SubmitFlag(session.ID, "flag{step-zero}")
+	require.NoError(t, err)
+	_, err = svc.SubmitFlag(session.ID, "flag{step-one}")
+	require.NoError(t, err)
+
+	assert.Empty(t, queuedDeliveries(t, db, orgID))
+}
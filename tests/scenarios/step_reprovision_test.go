@@ -152,3 +152,84 @@ func TestReprovisionCurrentStep_RejectsSessionsWithNothingToRepair(t *testing.T)
 		assert.Len(t, verifySvc.execCalls, 0)
 	})
 }
+
+// snapshotRecorder stands in for the terminal snapshot callbacks.
+type snapshotRecorder struct {
+	restoreErr error
+	restored   []string
+	taken      []string
+}
+
+func (r *snapshotRecorder) wire(svc *services.ScenarioSessionService) {
+	svc.SetTerminalSnapshotFuncs(
+		func(terminalSessionID, name string, stepOrder int) error {
+			r.taken = append(r.taken, fmt.Sprintf("%s@%d", name, stepOrder))
+			return nil
+		},
+		func(terminalSessionID, name string) error {
+			r.restored = append(r.restored, name)
+			return r.restoreErr
+		},
+	)
+}
+
+func snapshottingSession(t *testing.T, name string, snapshotSteps bool) (*models.ScenarioSession, *services.ScenarioSessionService, *bgTrackingVerificationService, *snapshotRecorder) {
+	t.Helper()
+	db := setupTestDB(t)
+	session := twoStepSession(t, db, name, models.ScenarioStep{
+		BackgroundScript:         "echo setup level",
+		BackgroundTimeoutSeconds: 5,
+	})
+	require.NoError(t, db.Model(&models.ScenarioSession{}).
+		Where("id = ?", session.ID).Update("current_step", 1).Error)
+	require.NoError(t, db.Model(&models.Scenario{}).
+		Where("id = ?", session.ScenarioID).Update("snapshot_steps", snapshotSteps).Error)
+
+	verifySvc := &bgTrackingVerificationService{}
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, verifySvc)
+	snapshots := &snapshotRecorder{}
+	snapshots.wire(sessionSvc)
+	return session, sessionSvc, verifySvc, snapshots
+}
+
+func TestReprovisionCurrentStep_SnapshotSteps_RestoresInsteadOfRerunning(t *testing.T) {
+	session, sessionSvc, verifySvc, snapshots := snapshottingSession(t, "reprovision-restore", true)
+
+	result, err := sessionSvc.ReprovisionCurrentStep(session.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "active", result.Status)
+	assert.Equal(t, []string{"scenario-step-1"}, snapshots.restored)
+	assert.Empty(t, verifySvc.execCalls, "a restored step runs none of its scripts")
+}
+
+func TestReprovisionCurrentStep_SnapshotSteps_FallsBackToScriptsAndSnapshots(t *testing.T) {
+	session, sessionSvc, verifySvc, snapshots := snapshottingSession(t, "reprovision-restore-fails", true)
+	snapshots.restoreErr = fmt.Errorf("snapshot not found")
+
+	result, err := sessionSvc.ReprovisionCurrentStep(session.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "active", result.Status)
+	require.Len(t, verifySvc.execCalls, 1, "without a snapshot the step is set up from its scripts")
+	assert.Equal(t, []string{"scenario-step-1@1"}, snapshots.taken,
+		"the re-run setup is snapshotted so the next reprovision can restore it")
+}
+
+func TestReprovisionCurrentStep_SnapshotSteps_ForceSkipsTheSnapshot(t *testing.T) {
+	session, sessionSvc, verifySvc, snapshots := snapshottingSession(t, "reprovision-restore-force", true)
+
+	_, err := sessionSvc.ReprovisionCurrentStep(session.ID, true)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots.restored)
+	require.Len(t, verifySvc.execCalls, 1)
+	assert.Equal(t, []string{"scenario-step-1@1"}, snapshots.taken)
+}
+
+func TestReprovisionCurrentStep_WithoutSnapshotSteps_NeverSnapshots(t *testing.T) {
+	session, sessionSvc, verifySvc, snapshots := snapshottingSession(t, "reprovision-no-snapshots", false)
+
+	_, err := sessionSvc.ReprovisionCurrentStep(session.ID, false)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots.restored)
+	assert.Empty(t, snapshots.taken)
+	require.Len(t, verifySvc.execCalls, 1)
+}
@@ -0,0 +1,410 @@
+package scenarios_test
+
+// Conditional step transitions: a step can route the learner somewhere other
+// than the next Order — a failed diagnostic to its remediation, a decoy flag
+// down another branch, a finished main path straight to the end. Steps passed
+// over are marked "skipped" so grades and "step N of M" ignore them.
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/entityManagement/hooks"
+	"soli/formations/src/scenarios/dto"
+	scenarioHooks "soli/formations/src/scenarios/hooks"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// exitCodeVerificationService reports a fixed exit code, as the real
+// VerificationService does through VerifyStepExitCode.
+type exitCodeVerificationService struct {
+	mockVerificationService
+	exitCode int
+}
+
+func (m *exitCodeVerificationService) VerifyStepExitCode(terminalSessionID string, step *models.ScenarioStep) (int, string, error) {
+	return m.exitCode, "", nil
+}
+
+// transitionFixture creates a scenario with one step per entry of stepTypes
+// (orders 0..n-1) and an active session on step 0 with every other step
+// locked. Returns the scenario, its steps and the session ID.
+func transitionFixture(t *testing.T, db *gorm.DB, stepTypes ...string) (models.Scenario, []models.ScenarioStep, uuid.UUID) {
+	t.Helper()
+
+	scenario := models.Scenario{
+		Name: "transitions-" + uuid.NewString()[:8], Title: "Transitions", InstanceType: "ubuntu:22.04", CreatedByID: "creator-1",
+	}
+	require.NoError(t, db.Create(&scenario).Error)
+
+	steps := make([]models.ScenarioStep, len(stepTypes))
+	for i, stepType := range stepTypes {
+		steps[i] = models.ScenarioStep{
+			ScenarioID: scenario.ID, Order: i, Title: "Step", StepType: stepType,
+		}
+		if stepType == "terminal" {
+			steps[i].VerifyScript = "#!/bin/bash\ntest -f /tmp/done"
+		}
+		require.NoError(t, db.Create(&steps[i]).Error)
+	}
+
+	terminalID := "terminal-" + scenario.Name
+	session := models.ScenarioSession{
+		ScenarioID: scenario.ID, UserID: "student-1", CurrentStep: 0,
+		Status: "active", StartedAt: time.Now(), TerminalSessionID: &terminalID,
+	}
+	require.NoError(t, db.Create(&session).Error)
+	for i := range steps {
+		status := "locked"
+		if i == 0 {
+			status = "active"
+		}
+		require.NoError(t, db.Create(&models.ScenarioStepProgress{
+			SessionID: session.ID, StepOrder: i, Status: status, StepType: stepTypes[i],
+		}).Error)
+	}
+	return scenario, steps, session.ID
+}
+
+func addTransition(t *testing.T, db *gorm.DB, step models.ScenarioStep, priority int, condition string, threshold float64, value string, target *int) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.ScenarioStepTransition{
+		StepID: step.ID, Priority: priority, Condition: condition,
+		Threshold: threshold, Value: value, TargetStepOrder: target,
+	}).Error)
+}
+
+func progressStatuses(t *testing.T, db *gorm.DB, sessionID uuid.UUID) map[int]string {
+	t.Helper()
+	var progress []models.ScenarioStepProgress
+	require.NoError(t, db.Where("session_id = ?", sessionID).Find(&progress).Error)
+	statuses := make(map[int]string, len(progress))
+	for _, p := range progress {
+		statuses[p.StepOrder] = p.Status
+	}
+	return statuses
+}
+
+// quizStepWithOneQuestion adds a single free-text question ("answer") to step.
+func quizStepWithOneQuestion(t *testing.T, db *gorm.DB, step models.ScenarioStep) uuid.UUID {
+	t.Helper()
+	q := models.ScenarioStepQuestion{
+		StepID: step.ID, Order: 1, QuestionText: "Q?", QuestionType: "free_text", CorrectAnswer: "answer", Points: 1,
+	}
+	require.NoError(t, db.Create(&q).Error)
+	return q.ID
+}
+
+func intPtr(v int) *int { return &v }
+
+// A failed diagnostic quiz routes to remediation; the step in between is
+// skipped, not left locked.
+func TestTransitions_QuizScoreBelow_RoutesToRemediation(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "quiz", "info", "info")
+	questionID := quizStepWithOneQuestion(t, db, steps[0])
+	addTransition(t, db, steps[0], 0, models.TransitionQuizScoreBelow, 0.5, "", intPtr(2))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	result, err := svc.SubmitQuiz(sessionID, dto.SubmitQuizInput{Answers: map[uuid.UUID]string{questionID: "wrong"}})
+	require.NoError(t, err)
+	require.NotNil(t, result.NextStep)
+	assert.Equal(t, 2, *result.NextStep, "a score below the threshold must route to the remediation step")
+
+	statuses := progressStatuses(t, db, sessionID)
+	assert.Equal(t, "completed", statuses[0])
+	assert.Equal(t, "skipped", statuses[1])
+	assert.Equal(t, "active", statuses[2])
+}
+
+// A passing score does not match quiz_score_below and falls through to the
+// next Order.
+func TestTransitions_QuizScoreBelow_PassingScoreAdvancesLinearly(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "quiz", "info", "info")
+	questionID := quizStepWithOneQuestion(t, db, steps[0])
+	addTransition(t, db, steps[0], 0, models.TransitionQuizScoreBelow, 0.5, "", intPtr(2))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	result, err := svc.SubmitQuiz(sessionID, dto.SubmitQuizInput{Answers: map[uuid.UUID]string{questionID: "answer"}})
+	require.NoError(t, err)
+	require.NotNil(t, result.NextStep)
+	assert.Equal(t, 1, *result.NextStep)
+	assert.Equal(t, "locked", progressStatuses(t, db, sessionID)[2])
+}
+
+// The main path ends the scenario before the remediation step placed after
+// it; the grade ignores the skipped remediation.
+func TestTransitions_EndTarget_CompletesAndExcludesSkippedFromGrade(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "info", "info")
+	addTransition(t, db, steps[0], 0, models.TransitionAlways, 0, "", nil)
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	result, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+	assert.Nil(t, result.NextStep, "a nil target ends the scenario")
+
+	var session models.ScenarioSession
+	require.NoError(t, db.First(&session, "id = ?", sessionID).Error)
+	assert.Equal(t, "completed", session.Status)
+	require.NotNil(t, session.Grade)
+	assert.InDelta(t, 100.0, *session.Grade, 0.001, "a skipped step must not count against the grade")
+	assert.Equal(t, "skipped", progressStatuses(t, db, sessionID)[1])
+}
+
+// A remediation step sends the learner back to the diagnostic, which reopens.
+func TestTransitions_Always_RoutesBackAndReopensTarget(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "info", "info", "info")
+	addTransition(t, db, steps[0], 0, models.TransitionAlways, 0, "", intPtr(2))
+	addTransition(t, db, steps[2], 0, models.TransitionAlways, 0, "", intPtr(0))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	_, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+	result, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+	require.NotNil(t, result.NextStep)
+	assert.Equal(t, 0, *result.NextStep)
+	assert.Equal(t, "active", progressStatuses(t, db, sessionID)[0])
+}
+
+// A flag transition's value is accepted as an alternative answer and routes
+// the learner elsewhere.
+func TestTransitions_Flag_AlternativeFlagRoutes(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "flag", "info", "info")
+	require.NoError(t, db.Create(&models.ScenarioFlag{
+		SessionID: sessionID, StepOrder: 0, ExpectedFlag: "FLAG{main}",
+	}).Error)
+	addTransition(t, db, steps[0], 0, models.TransitionFlag, 0, "FLAG{decoy}", intPtr(2))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: false}, &mockVerificationService{})
+	result, err := svc.SubmitFlag(sessionID, "FLAG{decoy}")
+	require.NoError(t, err)
+	assert.True(t, result.Correct)
+	require.NotNil(t, result.NextStep)
+	assert.Equal(t, 2, *result.NextStep)
+
+	// Any other wrong flag is still wrong.
+	_, steps2, session2 := transitionFixture(t, db, "flag", "info", "info")
+	require.NoError(t, db.Create(&models.ScenarioFlag{
+		SessionID: session2, StepOrder: 0, ExpectedFlag: "FLAG{main}",
+	}).Error)
+	addTransition(t, db, steps2[0], 0, models.TransitionFlag, 0, "FLAG{decoy}", intPtr(2))
+	result, err = svc.SubmitFlag(session2, "FLAG{other}")
+	require.NoError(t, err)
+	assert.False(t, result.Correct)
+	assert.Nil(t, result.NextStep)
+}
+
+// A failed verification with a matching exit code routes to the remediation
+// for that mistake; an unmatched failure keeps the learner on the step.
+func TestTransitions_VerifyExitCode_RoutesFailedVerification(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "terminal", "info", "info")
+	addTransition(t, db, steps[0], 0, models.TransitionVerifyExitCode, 0, "3", intPtr(2))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &exitCodeVerificationService{exitCode: 3})
+	result, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	require.NotNil(t, result.NextStep)
+	assert.Equal(t, 2, *result.NextStep)
+
+	_, steps2, session2 := transitionFixture(t, db, "terminal", "info", "info")
+	addTransition(t, db, steps2[0], 0, models.TransitionVerifyExitCode, 0, "3", intPtr(2))
+	svc = services.NewScenarioSessionService(db, &mockFlagService{}, &exitCodeVerificationService{exitCode: 1})
+	result, err = svc.VerifyCurrentStep(session2)
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.Nil(t, result.NextStep)
+}
+
+// A failed verification the transition routed away from is recorded as
+// failed: the learner moves on, but the step earns no credit.
+func TestTransitions_VerifyExitCode_RoutedFailureEarnsNoCredit(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "terminal", "info", "info")
+	addTransition(t, db, steps[0], 0, models.TransitionVerifyExitCode, 0, "3", intPtr(2))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &exitCodeVerificationService{exitCode: 3})
+	_, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", progressStatuses(t, db, sessionID)[0])
+
+	result, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+	assert.Nil(t, result.NextStep)
+
+	var session models.ScenarioSession
+	require.NoError(t, db.First(&session, "id = ?", sessionID).Error)
+	assert.Equal(t, "completed", session.Status)
+	require.NotNil(t, session.Grade)
+	assert.InDelta(t, 50.0, *session.Grade, 0.001, "the failed step counts as 0, the skipped one not at all")
+}
+
+// "Step N of M" is counted along the path taken.
+func TestTransitions_CurrentStepPositionExcludesSkipped(t *testing.T) {
+	db := freshTestDB(t)
+	_, steps, sessionID := transitionFixture(t, db, "info", "info", "info", "info")
+	addTransition(t, db, steps[0], 0, models.TransitionAlways, 0, "", intPtr(2))
+
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	_, err := svc.VerifyCurrentStep(sessionID)
+	require.NoError(t, err)
+
+	current, err := svc.GetCurrentStep(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, current.Position)
+	assert.Equal(t, 3, current.TotalSteps)
+	assert.Equal(t, []int{0, 2, 3}, current.StepOrders)
+}
+
+// Transitions survive an archive round trip, targets included, and seeding
+// builds them from step positions.
+func TestTransitions_ArchiveRoundTripAndSeed(t *testing.T) {
+	db := freshTestDB(t)
+
+	seeded, _, err := services.NewScenarioSeedService(db).SeedScenario(dto.SeedScenarioInput{
+		Title:        "Branching",
+		InstanceType: "ubuntu:22.04",
+		Steps: []dto.SeedStepInput{
+			{Title: "Diagnostic", Transitions: []dto.StepTransitionSpec{
+				{Priority: 1, Condition: models.TransitionVerifyExitCode, Value: "2", TargetStep: 3},
+				{Priority: 2, Condition: models.TransitionAlways, TargetStep: 0},
+			}},
+			{Title: "Main"},
+			{Title: "Remediation", Transitions: []dto.StepTransitionSpec{
+				{Condition: models.TransitionAlways, TargetStep: 1},
+			}},
+		},
+	}, "user-1", nil)
+	require.NoError(t, err)
+
+	assertBranching := func(scenarioID uuid.UUID) {
+		t.Helper()
+		var loaded models.Scenario
+		require.NoError(t, db.Preload("Steps", func(d *gorm.DB) *gorm.DB {
+			return d.Order("\"order\" ASC")
+		}).Preload("Steps.Transitions", func(d *gorm.DB) *gorm.DB {
+			return d.Order("priority ASC")
+		}).First(&loaded, "id = ?", scenarioID).Error)
+		require.Len(t, loaded.Steps, 3)
+
+		diag := loaded.Steps[0].Transitions
+		require.Len(t, diag, 2)
+		assert.Equal(t, models.TransitionVerifyExitCode, diag[0].Condition)
+		assert.Equal(t, "2", diag[0].Value)
+		require.NotNil(t, diag[0].TargetStepOrder)
+		assert.Equal(t, loaded.Steps[2].Order, *diag[0].TargetStepOrder)
+		assert.Nil(t, diag[1].TargetStepOrder, "target 0 ends the scenario")
+
+		assert.Empty(t, loaded.Steps[1].Transitions)
+		require.Len(t, loaded.Steps[2].Transitions, 1)
+		require.NotNil(t, loaded.Steps[2].Transitions[0].TargetStepOrder)
+		assert.Equal(t, loaded.Steps[0].Order, *loaded.Steps[2].Transitions[0].TargetStepOrder)
+	}
+	assertBranching(seeded.ID)
+
+	zipBytes, _, err := services.NewScenarioExportService(db).ExportAsArchive(seeded.ID)
+	require.NoError(t, err)
+	r, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		target := filepath.Join(dir, f.Name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+		require.NoError(t, os.WriteFile(target, data, 0o644))
+	}
+
+	imported, err := services.NewScenarioImporterService(db).ImportFromDirectory(dir, "user-2", nil, "upload")
+	require.NoError(t, err)
+	assertBranching(imported.ID)
+}
+
+// An archive whose transition targets a step that does not exist is refused
+// rather than imported with a route that can never fire.
+func TestTransitions_SeedRejectsOutOfRangeTarget(t *testing.T) {
+	db := freshTestDB(t)
+	_, _, err := services.NewScenarioSeedService(db).SeedScenario(dto.SeedScenarioInput{
+		Title:        "Broken",
+		InstanceType: "ubuntu:22.04",
+		Steps: []dto.SeedStepInput{
+			{Title: "Only", Transitions: []dto.StepTransitionSpec{
+				{Condition: models.TransitionAlways, TargetStep: 5},
+			}},
+		},
+	}, "user-1", nil)
+	assert.Error(t, err)
+}
+
+// The transition hook authorizes through the parent scenario and validates
+// what is written, so the editor cannot save a transition that never fires.
+func TestScenarioStepTransitionHook_ValidatesAndAuthorizes(t *testing.T) {
+	db := freshTestDB(t)
+	hook := scenarioHooks.NewScenarioStepTransitionAuthorizationHook(db)
+	scenario, steps, _ := transitionFixture(t, db, "terminal", "info")
+
+	create := func(userID string, transition *models.ScenarioStepTransition) error {
+		return hook.Execute(&hooks.HookContext{
+			EntityName: "ScenarioStepTransition",
+			HookType:   hooks.BeforeCreate,
+			NewEntity:  transition,
+			UserID:     userID,
+			UserRoles:  []string{"Member"},
+		})
+	}
+
+	assert.NoError(t, create(scenario.CreatedByID, &models.ScenarioStepTransition{
+		StepID: steps[0].ID, Condition: models.TransitionVerifyExitCode, Value: "2", TargetStepOrder: intPtr(1),
+	}))
+
+	err := create("unrelated-member", &models.ScenarioStepTransition{
+		StepID: steps[0].ID, Condition: models.TransitionAlways, TargetStepOrder: intPtr(1),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permission")
+
+	assert.Error(t, create(scenario.CreatedByID, &models.ScenarioStepTransition{
+		StepID: steps[0].ID, Condition: models.TransitionAlways, TargetStepOrder: intPtr(7),
+	}), "a target outside the scenario must be refused")
+	assert.Error(t, create(scenario.CreatedByID, &models.ScenarioStepTransition{
+		StepID: steps[0].ID, Condition: models.TransitionVerifyExitCode, Value: "not-a-number",
+	}))
+	assert.Error(t, create(scenario.CreatedByID, &models.ScenarioStepTransition{
+		StepID: steps[0].ID, Condition: models.TransitionQuizScoreBelow, Threshold: 50,
+	}), "quiz thresholds are fractions, not percentages")
+
+	existing := &models.ScenarioStepTransition{
+		StepID: steps[0].ID, Condition: models.TransitionAlways, TargetStepOrder: intPtr(1),
+	}
+	assert.Error(t, hook.Execute(&hooks.HookContext{
+		EntityName: "ScenarioStepTransition",
+		HookType:   hooks.BeforeUpdate,
+		OldEntity:  existing,
+		NewEntity:  map[string]any{"condition": models.TransitionFlag},
+		UserID:     scenario.CreatedByID,
+		UserRoles:  []string{"Member"},
+	}), "switching to a flag condition without a value must be refused")
+}
@@ -548,8 +548,11 @@ func setupRealTeacherRouter(t *testing.T, db *gorm.DB, userID string, roles []st
 	teacher.GET("/groups", ctrl.GetMyGroups)
 	teacher.GET("/groups/:groupId/activity", ctrl.GetGroupActivity)
 	teacher.GET("/groups/:groupId/live-progress", ctrl.GetGroupLiveProgress)
+	teacher.GET("/groups/:groupId/live-progress/stream", ctrl.StreamGroupLiveProgress)
+	teacher.GET("/groups/:groupId/gradebook", ctrl.GetGroupGradebook)
 	teacher.GET("/groups/:groupId/scenarios/:scenarioId/results", ctrl.GetScenarioResults)
 	teacher.GET("/groups/:groupId/scenarios/:scenarioId/analytics", ctrl.GetScenarioAnalytics)
+	teacher.GET("/groups/:groupId/scenarios/:scenarioId/integrity", ctrl.GetScenarioIntegrityReport)
 	teacher.POST("/groups/:groupId/scenarios/:scenarioId/bulk-start", ctrl.BulkStartScenario)
 	teacher.POST("/groups/:groupId/scenarios/:scenarioId/reset-sessions", ctrl.ResetGroupScenarioSessions)
 	teacher.POST("/groups/:groupId/sessions/details", ctrl.GetSessionDetailsBulk)
@@ -0,0 +1,319 @@
+package scenarios_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// teacher_gradebook_test.go — GET /teacher/groups/:groupId/gradebook, the class
+// gradebook a teacher pastes into their school's LMS: one row per learner, one
+// column group per active assignment, as JSON, CSV, XLSX or Moodle grade import.
+
+// seedGradebookClass builds a class of three learners on two assignments:
+// student-done completed the first (with a quiz and failed verifies),
+// student-busy is half way through it, student-idle has not started anything.
+func seedGradebookClass(t *testing.T, db *gorm.DB) (groupModels.ClassGroup, models.Scenario, models.Scenario) {
+	t.Helper()
+	group := createClassGroup(t, db, "gradebook-class", "teacher-gb", nil)
+	addGroupMember(t, db, group.ID, "teacher-gb-manager", groupModels.GroupMemberRoleManager)
+	for _, learner := range []string{"student-done", "student-busy", "student-idle"} {
+		addGroupMember(t, db, group.ID, learner, groupModels.GroupMemberRoleMember)
+	}
+
+	linux := seedScenarioWithSteps(t, db, "Linux basics", "Boot", "Quiz")
+	network := seedScenarioWithSteps(t, db, "Networking", "Ping")
+	createScenarioAssignment(t, db, linux.ID, &group.ID, nil, "group")
+	createScenarioAssignment(t, db, network.ID, &group.ID, nil, "group")
+
+	startedAt := time.Now().Add(-90 * time.Minute)
+	completedAt := startedAt.Add(45 * time.Minute)
+	grade := 87.5
+	done := models.ScenarioSession{
+		ScenarioID: linux.ID, UserID: "student-done", Status: "completed",
+		CurrentStep: 1, StartedAt: startedAt, CompletedAt: &completedAt, Grade: &grade,
+	}
+	require.NoError(t, db.Create(&done).Error)
+	quizScore := 0.75
+	require.NoError(t, db.Create(&models.ScenarioStepProgress{
+		SessionID: done.ID, StepOrder: 0, Status: "completed", HintsRevealed: 1,
+		VerifyAttempts: 3, CompletedAt: &completedAt,
+	}).Error)
+	require.NoError(t, db.Create(&models.ScenarioStepProgress{
+		SessionID: done.ID, StepOrder: 1, Status: "completed",
+		QuizScore: &quizScore, CompletedAt: &completedAt,
+	}).Error)
+
+	partialGrade := 40.0
+	busy := models.ScenarioSession{
+		ScenarioID: linux.ID, UserID: "student-busy", Status: "active",
+		CurrentStep: 1, StartedAt: time.Now().Add(-10 * time.Minute), Grade: &partialGrade,
+	}
+	require.NoError(t, db.Create(&busy).Error)
+	require.NoError(t, db.Create(&models.ScenarioStepProgress{
+		SessionID: busy.ID, StepOrder: 0, Status: "completed", HintsRevealed: 2, VerifyAttempts: 1,
+	}).Error)
+
+	return group, linux, network
+}
+
+func gradebookLearnerByID(g *services.Gradebook) map[string]services.GradebookLearner {
+	byUser := make(map[string]services.GradebookLearner, len(g.Learners))
+	for _, learner := range g.Learners {
+		byUser[learner.UserID] = learner
+	}
+	return byUser
+}
+
+// --- service tests ---------------------------------------------------------
+
+// TestGetGroupGradebook_OneRowPerLearnerOneResultPerAssignment covers the shape
+// and every figure of a completed cell.
+func TestGetGroupGradebook_OneRowPerLearnerOneResultPerAssignment(t *testing.T) {
+	db := setupTestDB(t)
+	group, linux, network := seedGradebookClass(t, db)
+
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	gradebook, err := svc.GetGroupGradebook(group.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, "gradebook-class", gradebook.GroupName)
+	require.Len(t, gradebook.Assignments, 2)
+	assignmentIndex := map[uuid.UUID]int{}
+	for i, a := range gradebook.Assignments {
+		assignmentIndex[a.ScenarioID] = i
+	}
+	require.Contains(t, assignmentIndex, linux.ID)
+	require.Contains(t, assignmentIndex, network.ID)
+
+	require.Len(t, gradebook.Learners, 3, "learners only — the manager gets no row")
+	byUser := gradebookLearnerByID(gradebook)
+	for _, learner := range gradebook.Learners {
+		assert.Len(t, learner.Results, 2, "every learner has a cell for every assignment")
+	}
+
+	done := byUser["student-done"].Results[assignmentIndex[linux.ID]]
+	assert.Equal(t, services.LearnerStatusCompleted, done.Status)
+	require.NotNil(t, done.Grade)
+	assert.InDelta(t, 87.5, *done.Grade, 0.001)
+	require.NotNil(t, done.CompletedAt)
+	require.NotNil(t, done.TimeSpentSeconds)
+	assert.InDelta(t, 45*60, *done.TimeSpentSeconds, 1)
+	assert.Equal(t, 1, done.HintsUsed)
+	assert.Equal(t, 3, done.VerifyAttempts)
+	require.NotNil(t, done.QuizScore)
+	assert.InDelta(t, 75, *done.QuizScore, 0.001)
+
+	assert.Equal(t, services.LearnerStatusNotStarted, byUser["student-done"].Results[assignmentIndex[network.ID]].Status)
+	assert.Equal(t, services.LearnerStatusNotStarted, byUser["student-idle"].Results[assignmentIndex[linux.ID]].Status)
+}
+
+// TestGetGroupGradebook_InProgressHasNoGrade — a partial grade must never reach
+// an LMS as though it were final.
+func TestGetGroupGradebook_InProgressHasNoGrade(t *testing.T) {
+	db := setupTestDB(t)
+	group, linux, _ := seedGradebookClass(t, db)
+
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	gradebook, err := svc.GetGroupGradebook(group.ID)
+	require.NoError(t, err)
+
+	var busy services.GradebookResult
+	for i, a := range gradebook.Assignments {
+		if a.ScenarioID == linux.ID {
+			busy = gradebookLearnerByID(gradebook)["student-busy"].Results[i]
+		}
+	}
+	assert.Equal(t, services.LearnerStatusInProgress, busy.Status)
+	assert.Nil(t, busy.Grade)
+	assert.Nil(t, busy.CompletedAt)
+	assert.Equal(t, 2, busy.HintsUsed)
+	assert.Equal(t, 1, busy.VerifyAttempts)
+	assert.Nil(t, busy.QuizScore, "no quiz submitted yet")
+}
+
+func TestGetGroupGradebook_UnknownGroup(t *testing.T) {
+	db := setupTestDB(t)
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	_, err := svc.GetGroupGradebook(uuid.New())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+// --- export tests ----------------------------------------------------------
+
+func TestExportGroupGradebook_CSV(t *testing.T) {
+	db := setupTestDB(t)
+	group, _, _ := seedGradebookClass(t, db)
+
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	body, contentType, filename, err := svc.ExportGroupGradebook(group.ID, services.GradebookFormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", contentType)
+	assert.Regexp(t, `^gradebook-gradebook-class-\d{4}-\d{2}-\d{2}\.csv$`, filename)
+
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4, "header + one row per learner")
+	assert.Equal(t, []string{"User ID", "Name", "Email"}, records[0][:3])
+	assert.Len(t, records[0], 3+2*7, "seven columns per assignment")
+	assert.Contains(t, records[0], "Linux basics - Grade (%)")
+	assert.Contains(t, records[0], "Networking - Quiz score (%)")
+
+	gradeColumn := -1
+	for i, name := range records[0] {
+		if name == "Linux basics - Grade (%)" {
+			gradeColumn = i
+		}
+	}
+	for _, row := range records[1:] {
+		switch row[0] {
+		case "student-done":
+			assert.Equal(t, "87.5", row[gradeColumn])
+		default:
+			assert.Empty(t, row[gradeColumn])
+		}
+	}
+}
+
+func TestExportGroupGradebook_XLSXIsAWorkbook(t *testing.T) {
+	db := setupTestDB(t)
+	group, _, _ := seedGradebookClass(t, db)
+
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	body, contentType, filename, err := svc.ExportGroupGradebook(group.ID, services.GradebookFormatXLSX)
+	require.NoError(t, err)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", contentType)
+	assert.Regexp(t, `\.xlsx$`, filename)
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	parts := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		assert.Contains(t, parts, name)
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<t>Linux basics - Grade (%)</t>`)
+	assert.Contains(t, sheet, `<v>87.5</v>`, "grades are stored as numbers")
+	assert.Contains(t, sheet, `<t>student-idle</t>`)
+}
+
+// TestExportGroupGradebook_MoodleSkipsLearnersWithoutEmail — Moodle matches rows
+// on the email address; a row it cannot match fails the import. No identity
+// provider runs in tests, so no learner has an email and only the header is left.
+func TestExportGroupGradebook_MoodleSkipsLearnersWithoutEmail(t *testing.T) {
+	db := setupTestDB(t)
+	group, _, _ := seedGradebookClass(t, db)
+
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	body, contentType, filename, err := svc.ExportGroupGradebook(group.ID, services.GradebookFormatMoodle)
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", contentType)
+	assert.Regexp(t, `-moodle\.csv$`, filename)
+
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Email address", records[0][0])
+	assert.ElementsMatch(t, []string{"Linux basics", "Networking"}, records[0][1:])
+}
+
+func TestExportGroupGradebook_UnsupportedFormat(t *testing.T) {
+	db := setupTestDB(t)
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	_, _, _, err := svc.ExportGroupGradebook(uuid.New(), "pdf")
+	assert.ErrorIs(t, err, services.ErrUnsupportedGradebookFormat)
+}
+
+// TestExportGroupGradebook_CSVNeutralisesFormulas — a learner-typed title
+// starting with "=" must not run as a formula when the teacher opens the file.
+func TestExportGroupGradebook_CSVNeutralisesFormulas(t *testing.T) {
+	db := setupTestDB(t)
+	group := createClassGroup(t, db, "formula-class", "teacher-gb", nil)
+	scenario := seedScenarioWithSteps(t, db, "=HYPERLINK(\"http://evil\")", "Step")
+	createScenarioAssignment(t, db, scenario.ID, &group.ID, nil, "group")
+
+	svc := services.NewTeacherDashboardService(db, nil, nil)
+	body, _, _, err := svc.ExportGroupGradebook(group.ID, services.GradebookFormatCSV)
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\") - Status", records[0][3])
+}
+
+// --- API tests -------------------------------------------------------------
+
+func TestGetGroupGradebookAPI_Manager(t *testing.T) {
+	db := setupTestDB(t)
+	group, _, _ := seedGradebookClass(t, db)
+	router := setupRealTeacherRouter(t, db, "teacher-gb-manager", []string{"member"})
+	base := "/api/v1/teacher/groups/" + group.ID.String() + "/gradebook"
+
+	t.Run("json by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", base, nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var gradebook services.Gradebook
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gradebook))
+		assert.Len(t, gradebook.Learners, 3)
+		assert.Len(t, gradebook.Assignments, 2)
+	})
+
+	t.Run("csv download", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", base+"?format=csv", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Regexp(t, `^attachment; filename="gradebook-gradebook-class-.*\.csv"$`, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", base+"?format=pdf", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// TestGetGroupGradebookAPI_PlainMember_Returns403 — a learner must not read
+// their classmates' grades.
+func TestGetGroupGradebookAPI_PlainMember_Returns403(t *testing.T) {
+	db := setupTestDB(t)
+	group, _, _ := seedGradebookClass(t, db)
+	router := setupRealTeacherRouter(t, db, "student-done", []string{"member"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/teacher/groups/"+group.ID.String()+"/gradebook?format=csv", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
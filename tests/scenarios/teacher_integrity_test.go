@@ -0,0 +1,296 @@
+package scenarios_test
+
+// Integrity report of an assignment:
+//   GET /api/v1/teacher/groups/:groupId/scenarios/:scenarioId/integrity
+//
+// Flag sharing is detected from the wrong submissions of the class: a generated
+// flag is valid in exactly one session, so a submission equal to another
+// session's flag was copied. Command similarity and identical typos only count
+// what a pair of learners shares and the rest of the class does not — the
+// scenario's solution is in every honest history.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// historyTTService serves canned command histories per terminal session.
+// A terminal without a history answers with an error, like an unreachable
+// tt-backend.
+type historyTTService struct {
+	*mockTTService
+	histories map[string][]string
+}
+
+func (m *historyTTService) GetSessionCommandHistoryAdmin(sessionUUID string, limit, offset int) ([]byte, string, error) {
+	commands, ok := m.histories[sessionUUID]
+	if !ok {
+		return nil, "", errors.New("tt-backend unavailable")
+	}
+	var entries []string
+	for i, command := range commands {
+		entries = append(entries, fmt.Sprintf(`{"session_uuid":%q,"sequence_num":%d,"command_text":%q,"executed_at":1700000000}`, sessionUUID, i+1, command))
+	}
+	body := fmt.Sprintf(`{"commands":[%s],"total":%d,"limit":%d,"offset":%d}`, strings.Join(entries, ","), len(commands), limit, offset)
+	return []byte(body), "application/json", nil
+}
+
+// integrityClass is a class with one assigned scenario and its learners.
+type integrityClass struct {
+	groupID  uuid.UUID
+	scenario models.Scenario
+}
+
+func seedIntegrityClass(t *testing.T, db *gorm.DB, name string, learners ...string) integrityClass {
+	t.Helper()
+	group := createClassGroup(t, db, name, "teacher-"+name, nil)
+	addGroupMember(t, db, group.ID, "teacher-"+name, groupModels.GroupMemberRoleManager)
+	for _, learner := range learners {
+		addGroupMember(t, db, group.ID, learner, groupModels.GroupMemberRoleMember)
+	}
+	scenario := seedScenarioWithSteps(t, db, name, "Find the flag", "Find the second flag")
+	createScenarioAssignment(t, db, scenario.ID, &group.ID, nil, "group")
+	return integrityClass{groupID: group.ID, scenario: scenario}
+}
+
+// seedIntegritySession creates a session of userID with a generated flag per
+// step, FLAG{<userID>-<n>-<step>} standing in for the HMAC.
+func seedIntegritySession(t *testing.T, db *gorm.DB, scenarioID uuid.UUID, userID string, n int) models.ScenarioSession {
+	t.Helper()
+	terminalID := fmt.Sprintf("terminal-%s-%d", userID, n)
+	session := models.ScenarioSession{
+		ScenarioID: scenarioID, UserID: userID, Status: "completed",
+		StartedAt: time.Now().Add(time.Duration(n) * time.Minute), TerminalSessionID: &terminalID,
+	}
+	require.NoError(t, db.Create(&session).Error)
+	for step := 0; step < 2; step++ {
+		require.NoError(t, db.Create(&models.ScenarioFlag{
+			SessionID: session.ID, StepOrder: step, ExpectedFlag: integrityFlag(userID, n, step),
+		}).Error)
+	}
+	return session
+}
+
+func integrityFlag(userID string, n, step int) string {
+	return fmt.Sprintf("FLAG{%s-%d-%d}", userID, n, step)
+}
+
+func logWrongFlag(t *testing.T, db *gorm.DB, sessionID uuid.UUID, step int, value string) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.ScenarioFlagAttempt{
+		SessionID: sessionID, StepOrder: step, SubmittedFlag: value, SubmittedAt: time.Now(),
+	}).Error)
+}
+
+func newIntegrityService(db *gorm.DB, histories map[string][]string) *services.TeacherDashboardService {
+	return services.NewTeacherDashboardService(db, &historyTTService{mockTTService: newMockTTService(), histories: histories}, nil)
+}
+
+func TestIntegrityReport_FlagSharing(t *testing.T) {
+	db := freshTestDB(t)
+	class := seedIntegrityClass(t, db, "integrity-flags", "alice", "bob", "carol")
+
+	alice := seedIntegritySession(t, db, class.scenario.ID, "alice", 1)
+	bob := seedIntegritySession(t, db, class.scenario.ID, "bob", 1)
+	carol := seedIntegritySession(t, db, class.scenario.ID, "carol", 1)
+	aliceRetake := seedIntegritySession(t, db, class.scenario.ID, "alice", 2)
+	seedIntegritySession(t, db, class.scenario.ID, "someone-else", 1)
+
+	// bob pastes alice's flag; carol got hers from a learner of another class.
+	logWrongFlag(t, db, bob.ID, 0, " "+integrityFlag("alice", 1, 0)+"\n")
+	logWrongFlag(t, db, carol.ID, 0, integrityFlag("someone-else", 1, 0))
+	// alice submitting her previous run's flag is a mistake, not sharing.
+	logWrongFlag(t, db, aliceRetake.ID, 0, integrityFlag("alice", 1, 0))
+	// Guesses in the flag format match no session.
+	logWrongFlag(t, db, carol.ID, 0, "FLAG{guess}")
+	// A submission from before the attempt log existed, kept on the flag row.
+	legacy := integrityFlag("carol", 1, 1)
+	require.NoError(t, db.Model(&models.ScenarioFlag{}).
+		Where("session_id = ? AND step_order = ?", bob.ID, 1).
+		Updates(map[string]any{"submitted_flag": legacy, "is_correct": false}).Error)
+
+	report, err := newIntegrityService(db, nil).GetScenarioIntegrityReport(class.groupID, class.scenario.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.SessionsAnalyzed, "the outsider's session is not the class's")
+	require.Len(t, report.FlagSharing, 3)
+	byStep := map[string]services.FlagSharingFinding{}
+	for _, f := range report.FlagSharing {
+		byStep[fmt.Sprintf("%s/%d", f.UserID, f.StepOrder)] = f
+	}
+
+	fromAlice := byStep["bob/0"]
+	assert.Equal(t, bob.ID, fromAlice.SessionID)
+	assert.Equal(t, "alice", fromAlice.SourceUserID)
+	require.NotNil(t, fromAlice.SourceSessionID)
+	assert.Equal(t, alice.ID, *fromAlice.SourceSessionID)
+	assert.True(t, fromAlice.SourceInClass)
+
+	fromCarol := byStep["bob/1"]
+	assert.Equal(t, "carol", fromCarol.SourceUserID, "the last submission kept on the flag row is checked too")
+
+	fromOutside := byStep["carol/0"]
+	assert.False(t, fromOutside.SourceInClass)
+	assert.Empty(t, fromOutside.SourceUserID, "a learner of another class is not named")
+	assert.Nil(t, fromOutside.SourceSessionID)
+}
+
+func TestIntegrityReport_SimilarHistories(t *testing.T) {
+	db := freshTestDB(t)
+	class := seedIntegrityClass(t, db, "integrity-similar", "alice", "bob", "carol", "dave")
+
+	// Everyone types the expected solution; alice and bob also share a long
+	// detour nobody else took.
+	solution := []string{"cd /var/log", "ls -la", "grep -i error syslog", "cat /etc/hosts", "systemctl status nginx", "systemctl restart nginx"}
+	detour := []string{"find / -name '*.conf' 2>/dev/null", "less /etc/nginx/nginx.conf", "nano /etc/nginx/sites-enabled/default", "nginx -t", "tail -n 50 /var/log/nginx/error.log", "curl localhost:8080", "ss -tlnp", "ps aux | grep nginx"}
+	histories := map[string][]string{}
+	for _, learner := range []string{"alice", "bob", "carol", "dave"} {
+		session := seedIntegritySession(t, db, class.scenario.ID, learner, 1)
+		history := append([]string{}, solution...)
+		switch learner {
+		case "alice", "bob":
+			history = append(history, detour...)
+		case "carol":
+			history = append(history, "df -h", "free -m", "uptime", "whoami", "id", "hostname")
+		}
+		histories[*session.TerminalSessionID] = history
+	}
+	// eve has a session, but tt-backend lost its history.
+	addGroupMember(t, db, class.groupID, "eve", groupModels.GroupMemberRoleMember)
+	eve := seedIntegritySession(t, db, class.scenario.ID, "eve", 1)
+
+	report, err := newIntegrityService(db, histories).GetScenarioIntegrityReport(class.groupID, class.scenario.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []uuid.UUID{eve.ID}, report.HistoryUnavailable)
+	require.Len(t, report.SimilarPairs, 1, "the solution everyone typed must not make a pair")
+	pair := report.SimilarPairs[0]
+	assert.Equal(t, "alice", pair.UserA)
+	assert.Equal(t, "bob", pair.UserB)
+	assert.Equal(t, services.SuspicionHigh, pair.Suspicion)
+	assert.Greater(t, pair.SharedRuns, 0)
+	assert.Len(t, pair.SessionsA, 1)
+}
+
+func TestIntegrityReport_IdenticalTypos(t *testing.T) {
+	db := freshTestDB(t)
+	class := seedIntegrityClass(t, db, "integrity-typos", "alice", "bob", "carol", "dave")
+
+	histories := map[string][]string{}
+	for _, learner := range []string{"alice", "bob", "carol", "dave"} {
+		session := seedIntegritySession(t, db, class.scenario.ID, learner, 1)
+		history := []string{"cat /etc/hosts", "systemctl restart nginx"}
+		if learner == "alice" || learner == "bob" {
+			history = []string{"cat /etc/hots", "cat /etc/hosts", "systemctl restrat nginx", "systemctl restart nginx"}
+		}
+		if learner == "dave" {
+			// A typo of his own is nobody else's business.
+			history = append([]string{"cat /etc/hostss"}, history...)
+		}
+		histories[*session.TerminalSessionID] = history
+	}
+
+	report, err := newIntegrityService(db, histories).GetScenarioIntegrityReport(class.groupID, class.scenario.ID)
+	require.NoError(t, err)
+
+	require.Len(t, report.SimilarPairs, 1)
+	pair := report.SimilarPairs[0]
+	assert.Equal(t, []string{"cat /etc/hots", "systemctl restrat nginx"}, pair.IdenticalTypos)
+	assert.Equal(t, services.SuspicionHigh, pair.Suspicion, "two identical typos are enough on their own")
+}
+
+func TestCompareCommandHistories_OnlyCommonSolution_NoFindings(t *testing.T) {
+	solution := []string{"cd /srv", "ls", "cat README", "make", "make test", "./run.sh", "echo done"}
+	histories := map[string][][]string{
+		"alice": {solution},
+		"bob":   {solution},
+		"carol": {solution},
+	}
+	assert.Empty(t, services.CompareCommandHistories(histories))
+}
+
+func TestCompareCommandHistories_WhitespaceIsNormalized(t *testing.T) {
+	db := freshTestDB(t)
+	class := seedIntegrityClass(t, db, "integrity-spaces", "alice", "bob", "carol")
+
+	shared := []string{"a1", "a2", "a3", "a4", "a5", "a6", "a7"}
+	histories := map[string][]string{}
+	for _, learner := range []string{"alice", "bob", "carol"} {
+		session := seedIntegritySession(t, db, class.scenario.ID, learner, 1)
+		switch learner {
+		case "alice":
+			histories[*session.TerminalSessionID] = shared
+		case "bob":
+			spaced := make([]string, len(shared))
+			for i, c := range shared {
+				spaced[i] = "  " + c + " "
+			}
+			histories[*session.TerminalSessionID] = spaced
+		default:
+			histories[*session.TerminalSessionID] = []string{"b1", "b2", "b3", "b4", "b5", "b6", "b7"}
+		}
+	}
+
+	report, err := newIntegrityService(db, histories).GetScenarioIntegrityReport(class.groupID, class.scenario.ID)
+	require.NoError(t, err)
+	require.Len(t, report.SimilarPairs, 1)
+	assert.InDelta(t, 1.0, report.SimilarPairs[0].Similarity, 0.001)
+}
+
+func TestIntegrityReport_UnassignedScenario(t *testing.T) {
+	db := freshTestDB(t)
+	class := seedIntegrityClass(t, db, "integrity-unassigned", "alice")
+	other := seedScenarioWithSteps(t, db, "integrity-other", "Step")
+
+	_, err := newIntegrityService(db, nil).GetScenarioIntegrityReport(class.groupID, other.ID)
+	assert.ErrorIs(t, err, services.ErrScenarioNotAssignedToGroup)
+
+	router := setupRealTeacherRouter(t, db, "teacher-integrity-unassigned", []string{"member"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/teacher/groups/"+class.groupID.String()+"/scenarios/"+other.ID.String()+"/integrity", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestIntegrityReport_LearnerForbidden(t *testing.T) {
+	db := freshTestDB(t)
+	class := seedIntegrityClass(t, db, "integrity-forbidden", "alice")
+
+	router := setupRealTeacherRouter(t, db, "alice", []string{"member"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/teacher/groups/"+class.groupID.String()+"/scenarios/"+class.scenario.ID.String()+"/integrity", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestSubmitFlag_WrongSubmissionIsLogged(t *testing.T) {
+	db := setupTestDB(t)
+	session := flaggedTwoStepSession(t, db, "flag-attempt-log", models.ScenarioStep{})
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: false}, &mockVerificationService{})
+
+	for _, value := range []string{"FLAG{first-try}", "FLAG{second-try}"} {
+		resp, err := sessionSvc.SubmitFlag(session.ID, value)
+		require.NoError(t, err)
+		require.False(t, resp.Correct)
+	}
+
+	var attempts []models.ScenarioFlagAttempt
+	require.NoError(t, db.Where("session_id = ?", session.ID).Order("submitted_at ASC").Find(&attempts).Error)
+	require.Len(t, attempts, 2, "every wrong submission is kept, not only the last one")
+	assert.Equal(t, "FLAG{first-try}", attempts[0].SubmittedFlag)
+	assert.Equal(t, 0, attempts[0].StepOrder)
+}
@@ -0,0 +1,148 @@
+package scenarios_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/liveprogress"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// teacher_live_progress_stream_test.go — GET
+// /teacher/groups/:groupId/live-progress/stream, the Server-Sent Events form
+// of the class view. It must open with the whole class, then push only the
+// learners the bus names, and only when their row changed.
+
+type streamedEvent struct {
+	name    string
+	payload any
+}
+
+// openLiveProgressStream runs StreamGroupLiveProgress in the background and
+// hands its events over a channel. The stream ends with the test.
+func openLiveProgressStream(t *testing.T, svc *services.TeacherDashboardService, groupID uuid.UUID) <-chan streamedEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan streamedEvent, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = svc.StreamGroupLiveProgress(ctx, groupID, func(name string, payload any) error {
+			events <- streamedEvent{name: name, payload: payload}
+			return nil
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return events
+}
+
+func nextStreamEvent(t *testing.T, events <-chan streamedEvent) streamedEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(3 * time.Second):
+		t.Fatal("no stream event within 3s")
+		return streamedEvent{}
+	}
+}
+
+func TestStreamGroupLiveProgress_SnapshotThenChangedRowsOnly(t *testing.T) {
+	db := setupTestDB(t)
+	orgID := uuid.New()
+	group := createClassGroup(t, db, "stream-class", "teacher-stream", &orgID)
+	session := flaggedTwoStepSession(t, db, "stream-flags", models.ScenarioStep{})
+	addGroupMember(t, db, group.ID, session.UserID, groupModels.GroupMemberRoleMember)
+	addGroupMember(t, db, group.ID, "student-quiet", groupModels.GroupMemberRoleMember)
+	createScenarioAssignment(t, db, session.ScenarioID, &group.ID, nil, "group")
+
+	events := openLiveProgressStream(t, services.NewTeacherDashboardService(db, nil, nil), group.ID)
+
+	snapshot := nextStreamEvent(t, events)
+	require.Equal(t, services.LiveProgressEventSnapshot, snapshot.name)
+	rows := snapshot.payload.(*services.LiveProgressMessage).Rows
+	require.Len(t, rows, 2, "the snapshot lists the whole class")
+	assert.Equal(t, 0, liveProgressByUserID(rows)[session.UserID].Assignments[0].CurrentStep)
+
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{validateRes: true}, &mockVerificationService{})
+	_, err := sessionSvc.SubmitFlag(session.ID, "flag{step-zero}")
+	require.NoError(t, err)
+
+	progress := nextStreamEvent(t, events)
+	require.Equal(t, services.LiveProgressEventProgress, progress.name)
+	message := progress.payload.(*services.LiveProgressMessage)
+	require.Len(t, message.Rows, 1, "only the learner who moved is sent")
+	assert.Equal(t, session.UserID, message.Rows[0].UserID)
+	assert.Equal(t, 1, message.Rows[0].Assignments[0].CurrentStep)
+	assert.Empty(t, message.Removed)
+}
+
+func TestStreamGroupLiveProgress_IgnoresOtherClassesAndUnchangedRows(t *testing.T) {
+	db := setupTestDB(t)
+	group := createClassGroup(t, db, "quiet-class", "teacher-stream", nil)
+	addGroupMember(t, db, group.ID, "student-still", groupModels.GroupMemberRoleMember)
+	scenario := seedScenarioWithSteps(t, db, "quiet-scenario", "Only step")
+	createScenarioAssignment(t, db, scenario.ID, &group.ID, nil, "group")
+
+	events := openLiveProgressStream(t, services.NewTeacherDashboardService(db, nil, nil), group.ID)
+	require.Equal(t, services.LiveProgressEventSnapshot, nextStreamEvent(t, events).name)
+
+	// A stranger's activity, and an event for a member whose row did not move.
+	liveprogress.Publish(liveprogress.Event{Kind: liveprogress.KindVerifyAttempt, UserID: "student-elsewhere"})
+	liveprogress.Publish(liveprogress.Event{Kind: liveprogress.KindTerminalPresence, UserID: "student-still"})
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected %q event: nothing about the class changed", event.name)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestStreamGroupLiveProgressAPI_ManagerGetsEventStream(t *testing.T) {
+	db := setupTestDB(t)
+	group := createClassGroup(t, db, "sse-class", "teacher-manager", nil)
+	addGroupMember(t, db, group.ID, "teacher-manager", groupModels.GroupMemberRoleManager)
+	addGroupMember(t, db, group.ID, "student-sse", groupModels.GroupMemberRoleMember)
+
+	router := setupRealTeacherRouter(t, db, "teacher-manager", []string{"member"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/api/v1/teacher/groups/"+group.ID.String()+"/live-progress/stream", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "event: snapshot\ndata: {\"rows\":["), body)
+	assert.Contains(t, body, `"user_id":"student-sse"`)
+	assert.NotContains(t, body, `"user_id":"teacher-manager"`, "staff are not invigilated")
+}
+
+func TestStreamGroupLiveProgressAPI_Student_Returns403(t *testing.T) {
+	db := setupTestDB(t)
+	group := createClassGroup(t, db, "sse-denied", "other-teacher", nil)
+	addGroupMember(t, db, group.ID, "student-nosy", groupModels.GroupMemberRoleMember)
+
+	router := setupRealTeacherRouter(t, db, "student-nosy", []string{"member"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/teacher/groups/"+group.ID.String()+"/live-progress/stream", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,179 @@
+package scenarios_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	authModels "soli/formations/src/auth/models"
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+	ttDto "soli/formations/src/terminalTrainer/dto"
+)
+
+// recordingCapturingTTService records the replay options the teacher
+// dashboard asks tt-backend's recording for.
+type recordingCapturingTTService struct {
+	*mockTTService
+	calls           int
+	lastSessionUUID string
+	lastOpts        ttDto.RecordingReplayOptions
+}
+
+func (m *recordingCapturingTTService) GetSessionRecording(sessionUUID string, opts ttDto.RecordingReplayOptions) ([]byte, error) {
+	m.calls++
+	m.lastSessionUUID = sessionUUID
+	m.lastOpts = opts
+	return []byte("{\"version\":2}\n"), nil
+}
+
+type recordingFixture struct {
+	db        *gorm.DB
+	group     *groupModels.ClassGroup
+	session   *models.ScenarioSession
+	tt        *recordingCapturingTTService
+	dashboard *services.TeacherDashboardService
+	started   time.Time
+}
+
+// setupRecordingFixture seeds a learner in a group running an assigned
+// three-step scenario: step 0 completed, step 1 in progress, step 2 locked.
+func setupRecordingFixture(t *testing.T, consent *bool) *recordingFixture {
+	t.Helper()
+	db := setupTestDB(t)
+
+	group := &groupModels.ClassGroup{Name: "rec-group", DisplayName: "Recording", OwnerUserID: "trainer-1", RecordingConsentHandled: consent}
+	require.NoError(t, db.Omit("Metadata").Create(group).Error)
+	studentID := "student-rec-" + uuid.NewString()
+	require.NoError(t, db.Omit("Metadata").Create(&groupModels.GroupMember{
+		GroupID: group.ID, UserID: studentID, Role: groupModels.GroupMemberRoleMember,
+		JoinedAt: time.Now(), IsActive: true,
+	}).Error)
+
+	scenario := models.Scenario{Name: "rec", Title: "Recording", InstanceType: "ubuntu:22.04", CreatedByID: "c1"}
+	require.NoError(t, db.Create(&scenario).Error)
+	for i, title := range []string{"Setup", "Files", "Cleanup"} {
+		require.NoError(t, db.Create(&models.ScenarioStep{ScenarioID: scenario.ID, Order: i, Title: title}).Error)
+	}
+	require.NoError(t, db.Create(&models.ScenarioAssignment{
+		ScenarioID: scenario.ID, GroupID: &group.ID, Scope: "group", CreatedByID: "c1", IsActive: true,
+	}).Error)
+
+	started := time.Now().Add(-time.Hour).Truncate(time.Second)
+	completed := started.Add(10 * time.Minute)
+	terminalUUID := "tt-session-rec-" + uuid.NewString()
+	session := &models.ScenarioSession{
+		ScenarioID: scenario.ID, UserID: studentID, Status: "active",
+		StartedAt: started, TerminalSessionID: &terminalUUID,
+	}
+	require.NoError(t, db.Create(session).Error)
+	for _, p := range []models.ScenarioStepProgress{
+		{SessionID: session.ID, StepOrder: 0, Status: "completed", CompletedAt: &completed},
+		{SessionID: session.ID, StepOrder: 1, Status: "active"},
+		{SessionID: session.ID, StepOrder: 2, Status: "locked"},
+	} {
+		require.NoError(t, db.Create(&p).Error)
+	}
+
+	tt := &recordingCapturingTTService{mockTTService: newMockTTService()}
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	return &recordingFixture{
+		db:        db,
+		group:     group,
+		session:   session,
+		tt:        tt,
+		dashboard: services.NewTeacherDashboardService(db, tt, sessionSvc),
+		started:   started,
+	}
+}
+
+func TestGetSessionRecording_MarksEachReachedStep(t *testing.T) {
+	handled := true
+	f := setupRecordingFixture(t, &handled)
+
+	_, err := f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, *f.session.TerminalSessionID, f.tt.lastSessionUUID)
+	assert.Nil(t, f.tt.lastOpts.Since)
+	assert.Nil(t, f.tt.lastOpts.Until)
+	require.Len(t, f.tt.lastOpts.Markers, 2, "the locked step was never reached")
+	assert.Equal(t, "Step 1: Setup", f.tt.lastOpts.Markers[0].Label)
+	assert.True(t, f.tt.lastOpts.Markers[0].At.Equal(f.started))
+	assert.Equal(t, "Step 2: Files", f.tt.lastOpts.Markers[1].Label)
+	assert.True(t, f.tt.lastOpts.Markers[1].At.Equal(f.started.Add(10*time.Minute)))
+}
+
+// TestGetSessionRecording_StepWindow: a step runs from the completion of the
+// one before it to its own, open-ended while it is still in progress.
+func TestGetSessionRecording_StepWindow(t *testing.T) {
+	handled := true
+	f := setupRecordingFixture(t, &handled)
+
+	first := 0
+	_, err := f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, &first)
+	require.NoError(t, err)
+	require.NotNil(t, f.tt.lastOpts.Since)
+	require.NotNil(t, f.tt.lastOpts.Until)
+	assert.True(t, f.tt.lastOpts.Since.Equal(f.started))
+	assert.True(t, f.tt.lastOpts.Until.Equal(f.started.Add(10*time.Minute)))
+
+	second := 1
+	_, err = f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, &second)
+	require.NoError(t, err)
+	require.NotNil(t, f.tt.lastOpts.Since)
+	assert.True(t, f.tt.lastOpts.Since.Equal(f.started.Add(10*time.Minute)))
+	assert.Nil(t, f.tt.lastOpts.Until)
+
+	locked := 2
+	_, err = f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, &locked)
+	assert.ErrorIs(t, err, services.ErrStepNotInSession)
+}
+
+func TestGetSessionRecording_Consent(t *testing.T) {
+	t.Run("group opted out, learner has not acknowledged", func(t *testing.T) {
+		notHandled := false
+		f := setupRecordingFixture(t, &notHandled)
+		_, err := f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, nil)
+		assert.ErrorIs(t, err, services.ErrRecordingConsentMissing)
+		assert.Zero(t, f.tt.calls, "nothing is fetched without consent")
+	})
+
+	t.Run("group opted out, learner acknowledged", func(t *testing.T) {
+		notHandled := false
+		f := setupRecordingFixture(t, &notHandled)
+		now := time.Now()
+		require.NoError(t, f.db.Create(&authModels.UserSettings{UserID: f.session.UserID, RecordingAcknowledgedAt: &now}).Error)
+		_, err := f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("group inherits its organization's policy", func(t *testing.T) {
+		f := setupRecordingFixture(t, nil)
+		org := &orgModels.Organization{Name: "rec-org", DisplayName: "Recording Org", OwnerUserID: "owner-1", RecordingConsentHandled: true}
+		require.NoError(t, f.db.Omit("Metadata").Create(org).Error)
+		require.NoError(t, f.db.Model(f.group).Update("organization_id", org.ID).Error)
+
+		_, err := f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, nil)
+		assert.NoError(t, err)
+
+		require.NoError(t, f.db.Model(org).Update("recording_consent_handled", false).Error)
+		_, err = f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, nil)
+		assert.ErrorIs(t, err, services.ErrRecordingConsentMissing)
+	})
+}
+
+func TestGetSessionRecording_ScenarioNotAssignedToGroup(t *testing.T) {
+	handled := true
+	f := setupRecordingFixture(t, &handled)
+	require.NoError(t, f.db.Where("group_id = ?", f.group.ID).Delete(&models.ScenarioAssignment{}).Error)
+
+	_, err := f.dashboard.GetSessionRecording(f.group.ID, f.session.ID, nil)
+	assert.ErrorIs(t, err, services.ErrScenarioNotAssignedToGroup)
+	assert.Zero(t, f.tt.calls)
+}
@@ -0,0 +1,136 @@
+package scenarios_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+// teamLeadSession seeds a two-step info scenario and the lead's run of it on
+// step 0, on a terminal about to be shared.
+func teamLeadSession(t *testing.T, db *gorm.DB, name string) *models.ScenarioSession {
+	t.Helper()
+
+	scenario := models.Scenario{Name: name, Title: name, InstanceType: "ubuntu:22.04", CreatedByID: "creator-1"}
+	require.NoError(t, db.Create(&scenario).Error)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&models.ScenarioStep{ScenarioID: scenario.ID, Order: i, Title: "Step", StepType: "info"}).Error)
+	}
+
+	terminalID := "terminal-" + name
+	session := models.ScenarioSession{
+		ScenarioID:        scenario.ID,
+		UserID:            "lead-" + name,
+		Status:            "active",
+		StartedAt:         time.Now(),
+		TerminalSessionID: &terminalID,
+	}
+	require.NoError(t, db.Create(&session).Error)
+	require.NoError(t, db.Create(&models.ScenarioStepProgress{SessionID: session.ID, StepOrder: 0, Status: "active"}).Error)
+	require.NoError(t, db.Create(&models.ScenarioStepProgress{SessionID: session.ID, StepOrder: 1, Status: "locked"}).Error)
+	return &session
+}
+
+func loadSession(t *testing.T, db *gorm.DB, id uuid.UUID) models.ScenarioSession {
+	t.Helper()
+	var session models.ScenarioSession
+	require.NoError(t, db.Preload("StepProgress").First(&session, "id = ?", id).Error)
+	return session
+}
+
+func TestTeamSession_MembersShareTheLeadsProgressAndGrade(t *testing.T) {
+	db := setupTestDB(t)
+	lead := teamLeadSession(t, db, "team-grade")
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+
+	members, err := svc.JoinTeamSession(lead.ID, []string{lead.UserID, "alice", "bob"})
+	require.NoError(t, err)
+	require.Len(t, members, 2, "the lead plays through their own session")
+
+	alice := loadSession(t, db, members["alice"])
+	require.NotNil(t, alice.TeamLeadSessionID)
+	assert.Equal(t, lead.ID, *alice.TeamLeadSessionID)
+	assert.Equal(t, *lead.TerminalSessionID, *alice.TerminalSessionID)
+	assert.Equal(t, "active", alice.Status)
+	assert.Len(t, alice.StepProgress, 2)
+
+	again, err := svc.JoinTeamSession(lead.ID, []string{"alice"})
+	require.NoError(t, err)
+	assert.Equal(t, members["alice"], again["alice"], "joining twice keeps the session")
+
+	_, err = svc.VerifyCurrentStep(lead.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loadSession(t, db, members["bob"]).CurrentStep, "members follow the lead's step")
+
+	_, err = svc.VerifyCurrentStep(lead.ID)
+	require.NoError(t, err)
+
+	completedLead := loadSession(t, db, lead.ID)
+	require.Equal(t, "completed", completedLead.Status)
+	require.NotNil(t, completedLead.Grade)
+	for _, userID := range []string{"alice", "bob"} {
+		member := loadSession(t, db, members[userID])
+		assert.Equal(t, "completed", member.Status, userID)
+		require.NotNil(t, member.Grade, userID)
+		assert.Equal(t, *completedLead.Grade, *member.Grade, "the grade is every member's")
+		require.Len(t, member.StepProgress, 2, userID)
+		for _, p := range member.StepProgress {
+			assert.Equal(t, "completed", p.Status, userID)
+		}
+	}
+
+	found, err := svc.FindSessionByTerminal(*lead.TerminalSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, lead.ID, found.ID, "the terminal runs the lead's session, not a mirror")
+}
+
+func TestTeamSession_MemberLeavesWithoutEndingTheRun(t *testing.T) {
+	db := setupTestDB(t)
+	lead := teamLeadSession(t, db, "team-leave")
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+	members, err := svc.JoinTeamSession(lead.ID, []string{"alice", "bob"})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.LeaveTeamSession(members["alice"]))
+	assert.Error(t, svc.LeaveTeamSession(lead.ID), "the lead abandons the run, not leaves it")
+	assert.Equal(t, "active", loadSession(t, db, lead.ID).Status)
+
+	_, err = svc.VerifyCurrentStep(lead.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "abandoned", loadSession(t, db, members["alice"]).Status, "a member who left stays out")
+
+	require.NoError(t, svc.AbandonSession(lead.ID))
+	assert.Equal(t, "abandoned", loadSession(t, db, members["bob"]).Status, "the run ends for the whole team")
+}
+
+func TestTeamSession_Rejections(t *testing.T) {
+	db := setupTestDB(t)
+	lead := teamLeadSession(t, db, "team-reject")
+	svc := services.NewScenarioSessionService(db, &mockFlagService{}, &mockVerificationService{})
+
+	// bob already has a run of the scenario of their own.
+	require.NoError(t, db.Create(&models.ScenarioSession{
+		ScenarioID: lead.ScenarioID, UserID: "bob", Status: "active", StartedAt: time.Now(),
+	}).Error)
+	_, err := svc.JoinTeamSession(lead.ID, []string{"alice", "bob"})
+	assert.ErrorIs(t, err, services.ErrTeamMemberBusy)
+	var mirrors int64
+	require.NoError(t, db.Model(&models.ScenarioSession{}).Where("team_lead_session_id = ?", lead.ID).Count(&mirrors).Error)
+	assert.Zero(t, mirrors, "a rejected team creates no session")
+
+	members, err := svc.JoinTeamSession(lead.ID, []string{"alice"})
+	require.NoError(t, err)
+	_, err = svc.JoinTeamSession(members["alice"], []string{"carol"})
+	assert.ErrorIs(t, err, services.ErrTeamLeadNotRunning, "a mirror cannot lead a team")
+
+	require.NoError(t, db.Model(&models.ScenarioSession{}).Where("id = ?", lead.ID).Update("status", "completed").Error)
+	_, err = svc.JoinTeamSession(lead.ID, []string{"carol"})
+	assert.ErrorIs(t, err, services.ErrTeamLeadNotRunning)
+}
@@ -0,0 +1,295 @@
+package scenarios_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"soli/formations/src/scenarios/dto"
+	"soli/formations/src/scenarios/models"
+	"soli/formations/src/scenarios/services"
+)
+
+func TestBuildScenarioNodes_KeepsDeclarationOrder(t *testing.T) {
+	nodes, err := services.BuildScenarioNodes([]dto.ScenarioNodeSpec{
+		{Name: "client", Distribution: "debian", Size: "S"},
+		{Name: "server", Hostname: "web", RequiredFeatures: []string{"network"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	assert.Equal(t, "client", nodes[0].Name)
+	assert.Equal(t, 0, nodes[0].Order)
+	assert.Equal(t, "client", nodes[0].EffectiveHostname(), "a node without a hostname is reached by its name")
+	assert.Equal(t, 1, nodes[1].Order)
+	assert.Equal(t, "web", nodes[1].EffectiveHostname())
+	features, err := nodes[1].GetRequiredFeatures()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"network"}, features)
+}
+
+func TestBuildScenarioNodes_RejectsBlankAndRepeatedNames(t *testing.T) {
+	_, err := services.BuildScenarioNodes([]dto.ScenarioNodeSpec{{Name: "  "}})
+	assert.Error(t, err, "steps target nodes by name, so a nameless node cannot be targeted")
+
+	_, err = services.BuildScenarioNodes([]dto.ScenarioNodeSpec{{Name: "a"}, {Name: "a"}})
+	assert.Error(t, err, "a repeated name would make a step's target ambiguous")
+}
+
+func TestSeedScenario_StoresAndReplacesTopology(t *testing.T) {
+	db := setupTestDB(t)
+	seeder := services.NewScenarioSeedService(db)
+
+	input := dto.SeedScenarioInput{
+		Title:  "Seeded Topology",
+		OsType: "deb",
+		Nodes: []dto.ScenarioNodeSpec{
+			{Name: "client"},
+			{Name: "server", Size: "M"},
+		},
+		Steps: []dto.SeedStepInput{
+			{Title: "Start the server", TargetNode: "server"},
+			{Title: "Reach it"},
+		},
+	}
+	scenario, _, err := seeder.SeedScenario(input, "topology-seed-user", nil)
+	require.NoError(t, err)
+
+	var nodes []models.ScenarioNode
+	require.NoError(t, db.Where("scenario_id = ?", scenario.ID).Order("\"order\" ASC").Find(&nodes).Error)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "client", nodes[0].Name)
+	assert.Equal(t, "server", nodes[1].Name)
+	assert.Equal(t, "M", nodes[1].Size)
+
+	input.Nodes = []dto.ScenarioNodeSpec{{Name: "server"}}
+	_, isUpdate, err := seeder.SeedScenario(input, "topology-seed-user", nil)
+	require.NoError(t, err)
+	require.True(t, isUpdate)
+
+	nodes = nil
+	require.NoError(t, db.Where("scenario_id = ?", scenario.ID).Find(&nodes).Error)
+	require.Len(t, nodes, 1, "re-seeding must replace the topology, not add to it")
+	assert.Equal(t, "server", nodes[0].Name)
+}
+
+func TestSeedScenario_RejectsStepTargetingUndeclaredNode(t *testing.T) {
+	db := setupTestDB(t)
+	seeder := services.NewScenarioSeedService(db)
+
+	_, _, err := seeder.SeedScenario(dto.SeedScenarioInput{
+		Title: "Topology With Typo",
+		Nodes: []dto.ScenarioNodeSpec{{Name: "server"}},
+		Steps: []dto.SeedStepInput{{Title: "Level 0", TargetNode: "sever"}},
+	}, "topology-typo-user", nil)
+	assert.ErrorIs(t, err, services.ErrUnknownTargetNode)
+}
+
+func TestExportScenario_CarriesTopology(t *testing.T) {
+	db := setupTestDB(t)
+	seeder := services.NewScenarioSeedService(db)
+
+	scenario, _, err := seeder.SeedScenario(dto.SeedScenarioInput{
+		Title: "Exported Topology",
+		Nodes: []dto.ScenarioNodeSpec{{Name: "attacker", Distribution: "kali"}, {Name: "target"}},
+		Steps: []dto.SeedStepInput{{Title: "Scan", TargetNode: "attacker"}},
+	}, "topology-export-user", nil)
+	require.NoError(t, err)
+
+	out, err := services.NewScenarioExportService(db).ExportAsJSON(scenario.ID)
+	require.NoError(t, err)
+	require.Len(t, out.Nodes, 2)
+	assert.Equal(t, "attacker", out.Nodes[0].Name)
+	assert.Equal(t, "kali", out.Nodes[0].Distribution)
+	assert.Equal(t, "target", out.Nodes[1].Name)
+	require.Len(t, out.Steps, 1)
+	assert.Equal(t, "attacker", out.Steps[0].TargetNode)
+}
+
+// twoNodeScenario creates a scenario whose first step sets up the server
+// node while the learner sits on the client.
+func twoNodeScenario(t *testing.T, db *gorm.DB, name string) models.Scenario {
+	t.Helper()
+	scenario := models.Scenario{
+		Name:        name,
+		Title:       name,
+		CreatedByID: "creator-1",
+		Nodes: []models.ScenarioNode{
+			{Name: "client", Order: 0},
+			{Name: "server", Order: 1},
+		},
+	}
+	require.NoError(t, db.Create(&scenario).Error)
+	require.NoError(t, db.Create(&models.ScenarioStep{
+		ScenarioID:       scenario.ID,
+		Order:            0,
+		Title:            "Start the server",
+		BackgroundScript: "systemctl start nginx",
+		TargetNode:       "server",
+	}).Error)
+	return scenario
+}
+
+func topologyNodes() []services.TopologyNode {
+	return []services.TopologyNode{
+		{Name: "client", Hostname: "client", TerminalSessionID: "term-client"},
+		{Name: "server", Hostname: "server", TerminalSessionID: "term-server"},
+	}
+}
+
+func TestStartScenarioOnTopology_RunsTargetedStepOnItsNode(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := twoNodeScenario(t, db, "topology-targeted-step")
+
+	verifySvc := &bgTrackingVerificationService{}
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, verifySvc)
+
+	session, err := sessionSvc.StartScenarioOnTopology("student-topo-1", scenario.ID, topologyNodes())
+	require.NoError(t, err)
+	require.NotNil(t, session.TerminalSessionID)
+	assert.Equal(t, "term-client", *session.TerminalSessionID, "the session is bound to the primary node")
+
+	assert.Equal(t, "active", waitForSetupDone(t, db, session.ID))
+	require.Len(t, verifySvc.execCalls, 1)
+	assert.Equal(t, "term-server", verifySvc.execCalls[0].sessionID,
+		"a step targeting a node must run its setup there, not on the learner's console")
+
+	nodes, err := sessionSvc.SessionNodes(session.ID)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "client", nodes[0].Name)
+	assert.True(t, nodes[0].IsPrimary)
+	assert.Equal(t, "term-server", nodes[1].TerminalSessionID)
+	assert.False(t, nodes[1].IsPrimary)
+}
+
+func TestStartScenarioOnTopology_SetupFailureStopsEveryNode(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := twoNodeScenario(t, db, "topology-setup-failure")
+
+	verifySvc := &bgTrackingVerificationService{execErr: assert.AnError}
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, verifySvc)
+	tracker := &terminalStopTracker{}
+	sessionSvc.SetTerminalStopFunc(tracker.StopFunc())
+
+	session, err := sessionSvc.StartScenarioOnTopology("student-topo-2", scenario.ID, topologyNodes())
+	require.NoError(t, err)
+	assert.Equal(t, "setup_failed", waitForSetupDone(t, db, session.ID))
+
+	require.Eventually(t, func() bool { return tracker.CallCount() == 2 }, time.Second, 10*time.Millisecond,
+		"a failed setup must stop every node, or the others keep running with nothing referencing them")
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	assert.ElementsMatch(t, []string{"term-client", "term-server"}, tracker.calls)
+}
+
+// nodeFailureTTBackend fronts newPersistenceTTBackend: provisioning the
+// "server" node fails, every other node starts, and stop calls are recorded.
+type nodeFailureTTBackend struct {
+	*httptest.Server
+	mu      sync.Mutex
+	started []string
+	stopped []string
+}
+
+func newNodeFailureTTBackend(t *testing.T) *nodeFailureTTBackend {
+	t.Helper()
+	upstream, _ := newPersistenceTTBackend(t)
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	b := &nodeFailureTTBackend{}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/1.0/sessions":
+			raw, _ := io.ReadAll(r.Body)
+			var body map[string]any
+			_ = json.Unmarshal(raw, &body)
+			if body["hostname"] == "server" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"id": "", "status": 1})
+				return
+			}
+			id := "node-" + uuid.NewString()
+			b.mu.Lock()
+			b.started = append(b.started, id)
+			b.mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":         id,
+				"status":     0,
+				"expires_at": time.Now().Add(time.Hour).Unix(),
+				"backend":    "local",
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/stop"):
+			b.mu.Lock()
+			b.stopped = append(b.stopped, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/1.0/sessions/"), "/stop"))
+			b.mu.Unlock()
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			proxy.ServeHTTP(w, r)
+		}
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// TestLaunchTopology_NodeFailureStopsStartedNodes: when one node cannot be
+// provisioned the launch is refused, and the nodes that did start must be
+// stopped — no session references them, so they would otherwise run, and
+// hold budget, until they expire.
+func TestLaunchTopology_NodeFailureStopsStartedNodes(t *testing.T) {
+	db := freshTestDB(t)
+	userID := "launch-topology-" + uuid.NewString()
+	seedPersistencePlan(t, db, userID, false)
+	seedPersistenceUserKey(t, db, userID)
+
+	scenario := seedPersistenceScenario(t, db, userID, false)
+	require.NoError(t, db.Create(&[]models.ScenarioNode{
+		{ScenarioID: scenario.ID, Name: "client", Order: 0},
+		{ScenarioID: scenario.ID, Name: "server", Order: 1},
+	}).Error)
+
+	ttSrv := newNodeFailureTTBackend(t)
+	configureTTServerForPersistence(t, ttSrv.URL)
+
+	router := setupPersistenceRouter(t, db, userID)
+	w := launchScenarioForTest(t, router, scenario.ID)
+	require.NotEqual(t, http.StatusOK, w.Code, "a topology missing a node cannot be played: %s", w.Body.String())
+
+	ttSrv.mu.Lock()
+	defer ttSrv.mu.Unlock()
+	require.Len(t, ttSrv.started, 1, "the client node must have started for this test to mean anything")
+	assert.Equal(t, ttSrv.started, ttSrv.stopped, "the node that did start must be stopped")
+
+	var sessions int64
+	require.NoError(t, db.Model(&models.ScenarioSession{}).Where("user_id = ?", userID).Count(&sessions).Error)
+	assert.Zero(t, sessions)
+}
+
+func TestSessionNodes_EmptyForSingleMachineRun(t *testing.T) {
+	db := setupTestDB(t)
+	scenario := models.Scenario{Name: "topology-none", Title: "No Topology", CreatedByID: "creator-1"}
+	require.NoError(t, db.Create(&scenario).Error)
+	require.NoError(t, db.Create(&models.ScenarioStep{ScenarioID: scenario.ID, Order: 0, Title: "Level 0"}).Error)
+
+	sessionSvc := services.NewScenarioSessionService(db, &mockFlagService{}, &bgTrackingVerificationService{})
+	session, err := sessionSvc.StartScenario("student-topo-3", scenario.ID, "term-single")
+	require.NoError(t, err)
+
+	nodes, err := sessionSvc.SessionNodes(session.ID)
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+}
@@ -164,6 +164,10 @@ func (m *metricsAwareMockService) StartComposedSession(userID string, input dto.
 	panic("not implemented")
 }
 
+func (m *metricsAwareMockService) StartComposedTopology(string, []dto.CreateComposedSessionInput, any) ([]*dto.TerminalSessionResponse, error) {
+	panic("not implemented")
+}
+
 // --- Helper: build a router with optional auth simulation -----------------
 
 // setupCapacityRouter wires the GET /terminals/capacity-check endpoint with
@@ -400,3 +404,42 @@ func TestCheckRAMAvailability_NoBodyFallsBackToPlanMax(t *testing.T) {
 // BuildComplete satisfies TerminalTrainerService. These tests never exercise
 // the provisioning window, so the stub reports success without recording.
 func (m *metricsAwareMockService) BuildComplete(sessionID string) error { return nil }
+
+// The snapshot methods satisfy TerminalTrainerService; these tests never
+// snapshot a terminal.
+func (m *metricsAwareMockService) ListSnapshots(string) (*dto.TerminalSnapshotsResponse, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) CreateSnapshot(string, string, string, *int) (*models.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) ReplaceSnapshot(string, string, string, *int) (*models.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) RestoreSnapshot(string, string) error { return nil }
+func (m *metricsAwareMockService) DeleteSnapshot(string, string) error  { return nil }
+
+// GetSessionRecording satisfies TerminalTrainerService; these tests never
+// replay a recording.
+func (m *metricsAwareMockService) GetSessionRecording(string, dto.RecordingReplayOptions) ([]byte, error) {
+	return nil, nil
+}
+
+// The team methods satisfy TerminalTrainerService; these tests never share
+// a terminal.
+func (m *metricsAwareMockService) GetTerminalTeam(string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) ShareTerminal(string, dto.ShareTerminalInput, string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) SetTerminalTeamRole(string, string, string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) RemoveTerminalTeamMember(string, string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *metricsAwareMockService) GetTerminalTeamRole(string, string) (string, error) { return "", nil }
+func (m *metricsAwareMockService) CanManageTerminalTeam(string, string) (bool, error) {
+	return false, nil
+}
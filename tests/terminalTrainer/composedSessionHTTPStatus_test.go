@@ -40,6 +40,10 @@ func (m *mockTerminalTrainerService) StartComposedSession(userID string, input d
 	return args.Get(0).(*dto.TerminalSessionResponse), args.Error(1)
 }
 
+func (m *mockTerminalTrainerService) StartComposedTopology(string, []dto.CreateComposedSessionInput, any) ([]*dto.TerminalSessionResponse, error) {
+	panic("not implemented")
+}
+
 // --- Stubs for remaining interface methods (not exercised by these tests) ---
 
 func (m *mockTerminalTrainerService) CreateUserKey(userID, userName string) error {
@@ -340,3 +344,44 @@ func TestStartComposedSession_ServerError_ShouldReturn500(t *testing.T) {
 // BuildComplete satisfies TerminalTrainerService. These tests never exercise
 // the provisioning window, so the stub reports success without recording.
 func (m *mockTerminalTrainerService) BuildComplete(sessionID string) error { return nil }
+
+// The snapshot methods satisfy TerminalTrainerService; these tests never
+// snapshot a terminal.
+func (m *mockTerminalTrainerService) ListSnapshots(string) (*dto.TerminalSnapshotsResponse, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) CreateSnapshot(string, string, string, *int) (*models.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) ReplaceSnapshot(string, string, string, *int) (*models.TerminalSnapshot, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) RestoreSnapshot(string, string) error { return nil }
+func (m *mockTerminalTrainerService) DeleteSnapshot(string, string) error  { return nil }
+
+// GetSessionRecording satisfies TerminalTrainerService; these tests never
+// replay a recording.
+func (m *mockTerminalTrainerService) GetSessionRecording(string, dto.RecordingReplayOptions) ([]byte, error) {
+	return nil, nil
+}
+
+// The team methods satisfy TerminalTrainerService; these tests never share
+// a terminal.
+func (m *mockTerminalTrainerService) GetTerminalTeam(string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) ShareTerminal(string, dto.ShareTerminalInput, string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) SetTerminalTeamRole(string, string, string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) RemoveTerminalTeamMember(string, string) (*dto.TerminalTeamResponse, error) {
+	return nil, nil
+}
+func (m *mockTerminalTrainerService) GetTerminalTeamRole(string, string) (string, error) {
+	return "", nil
+}
+func (m *mockTerminalTrainerService) CanManageTerminalTeam(string, string) (bool, error) {
+	return false, nil
+}
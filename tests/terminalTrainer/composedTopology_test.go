@@ -0,0 +1,160 @@
+// Budget accounting for multi-node topologies (StartComposedTopology): the
+// gate sees the summed footprint of every node, so a topology is admitted or
+// refused whole, and each node still lands as its own Terminal row.
+package terminalTrainer_tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/dto"
+	terminalModels "soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/services"
+)
+
+// recordingTTBackend fronts failingComposeTTBackend (in its succeeding mode)
+// and keeps the body of every session POST it forwards.
+type recordingTTBackend struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []map[string]any
+}
+
+func newRecordingTTBackend(t *testing.T) *recordingTTBackend {
+	t.Helper()
+	fail := false
+	upstream := failingComposeTTBackend(t, &fail)
+	t.Cleanup(upstream.Close)
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	rec := &recordingTTBackend{}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/sessions") {
+			raw, _ := io.ReadAll(r.Body)
+			var body map[string]any
+			_ = json.Unmarshal(raw, &body)
+			rec.mu.Lock()
+			rec.bodies = append(rec.bodies, body)
+			rec.mu.Unlock()
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (r *recordingTTBackend) postCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+func topologyInputs(network string) []dto.CreateComposedSessionInput {
+	return []dto.CreateComposedSessionInput{
+		{Distribution: "ubuntu-24.04", Size: "L", Terms: "accepted", Hostname: "client", PrivateNetwork: network},
+		{Distribution: "ubuntu-24.04", Size: "L", Terms: "accepted", Hostname: "server", PrivateNetwork: network},
+	}
+}
+
+func setupTopologyUser(t *testing.T, srv *recordingTTBackend, maxCPU, maxMemMB int) (string, *paymentModels.SubscriptionPlan) {
+	t.Helper()
+	t.Setenv("TERMINAL_TRAINER_URL", srv.URL)
+	t.Setenv("TERMINAL_TRAINER_ADMIN_KEY", "test-admin-key")
+	t.Setenv("TERMINAL_TRAINER_API_VERSION", "1.0")
+
+	freshTestDB(t)
+	userID := "topology-user-" + uuid.New().String()[:8]
+	planID := seedBudgetPlanForUser(t, userID, maxCPU, maxMemMB)
+	_, err := createTestUserKey(sharedTestDB, userID)
+	require.NoError(t, err)
+
+	plan := &paymentModels.SubscriptionPlan{}
+	require.NoError(t, sharedTestDB.First(plan, "id = ?", planID).Error)
+	return userID, plan
+}
+
+// TestComposedTopology_BudgetSumsAllNodes pins the reason the topology is
+// reserved as one: each L node fits the one-L budget on its own, so gating
+// them one at a time would boot the first and strand it when the second is
+// refused.
+func TestComposedTopology_BudgetSumsAllNodes(t *testing.T) {
+	srv := newRecordingTTBackend(t)
+	userID, plan := setupTopologyUser(t, srv, lSizeCPU, lSizeMemMB)
+
+	svc := services.NewTerminalTrainerService(sharedTestDB)
+	resp, err := svc.StartComposedTopology(userID, topologyInputs("scn-test"), plan)
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	var rejection *services.BudgetRejection
+	require.ErrorAs(t, err, &rejection, "a topology over budget is a budget rejection, got %T", err)
+
+	assert.Zero(t, srv.postCount(), "no node may be provisioned when the topology as a whole does not fit")
+	cpu, mem := productionBudgetUsage(t, userID)
+	assert.Zero(t, cpu, "a refused topology must not hold any reservation")
+	assert.Zero(t, mem)
+}
+
+func TestComposedTopology_StartsEveryNodeOnItsNetwork(t *testing.T) {
+	srv := newRecordingTTBackend(t)
+	userID, plan := setupTopologyUser(t, srv, 2*lSizeCPU, 2*lSizeMemMB)
+
+	svc := services.NewTerminalTrainerService(sharedTestDB)
+	resp, err := svc.StartComposedTopology(userID, topologyInputs("scn-test"), plan)
+	require.NoError(t, err)
+	require.Len(t, resp, 2)
+	assert.NotEqual(t, resp[0].SessionID, resp[1].SessionID)
+
+	var occupied int64
+	require.NoError(t, sharedTestDB.Model(&terminalModels.Terminal{}).
+		Scopes(terminalModels.OccupiesSlotScope).
+		Where("terminals.user_id = ?", userID).
+		Count(&occupied).Error)
+	assert.EqualValues(t, 2, occupied, "each node is its own terminal")
+
+	cpu, mem := productionBudgetUsage(t, userID)
+	assert.Equal(t, 2*lSizeCPU, cpu)
+	assert.Equal(t, 2*lSizeMemMB, mem)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	require.Len(t, srv.bodies, 2)
+	hostnames := make([]any, 0, 2)
+	for _, body := range srv.bodies {
+		assert.Equal(t, "scn-test", body["private_network"], "every node must join the topology's network")
+		hostnames = append(hostnames, body["hostname"])
+	}
+	assert.ElementsMatch(t, []any{"client", "server"}, hostnames)
+}
+
+func TestComposedSession_OmitsPrivateNetworkWhenUnset(t *testing.T) {
+	srv := newRecordingTTBackend(t)
+	userID, plan := setupTopologyUser(t, srv, lSizeCPU, lSizeMemMB)
+
+	svc := services.NewTerminalTrainerService(sharedTestDB)
+	_, err := svc.StartComposedSession(userID, dto.CreateComposedSessionInput{
+		Distribution: "ubuntu-24.04", Size: "L", Terms: "accepted",
+	}, plan)
+	require.NoError(t, err)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	require.Len(t, srv.bodies, 1)
+	_, sent := srv.bodies[0]["private_network"]
+	assert.False(t, sent, "a single-machine session keeps the request it always sent")
+}
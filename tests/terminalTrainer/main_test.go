@@ -27,6 +27,10 @@ var sharedTestDB *gorm.DB
 var terminalTestModels = []any{
 	&models.UserTerminalKey{},
 	&models.Terminal{},
+	&models.TerminalFleet{},
+	&models.TerminalFleetMember{},
+	&models.TerminalSnapshot{},
+	&models.TerminalTeamMember{},
 	&groupModels.ClassGroup{},
 	&groupModels.GroupMember{},
 	&orgModels.Organization{},
@@ -113,6 +117,9 @@ func truncateAllPGTables(t *testing.T, db *gorm.DB) {
 	t.Helper()
 	if err := db.Exec(`TRUNCATE TABLE
 		terminals,
+		terminal_fleet_members,
+		terminal_fleets,
+		terminal_snapshots,
 		user_terminal_keys,
 		group_members,
 		class_groups,
@@ -134,6 +141,10 @@ func freshTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 	// Delete in dependency order to respect foreign keys
 	sharedTestDB.Exec("DELETE FROM terminals")
+	sharedTestDB.Exec("DELETE FROM terminal_fleet_members")
+	sharedTestDB.Exec("DELETE FROM terminal_fleets")
+	sharedTestDB.Exec("DELETE FROM terminal_snapshots")
+	sharedTestDB.Exec("DELETE FROM terminal_team_members")
 	sharedTestDB.Exec("DELETE FROM user_terminal_keys")
 	sharedTestDB.Exec("DELETE FROM group_members")
 	sharedTestDB.Exec("DELETE FROM class_groups")
@@ -0,0 +1,350 @@
+package terminalTrainer_tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	orgModels "soli/formations/src/organizations/models"
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/services"
+)
+
+// fakeFleetLauncher stands in for the TerminalTrainerService: it records the
+// sessions a fleet starts and stops instead of calling tt-backend.
+type fakeFleetLauncher struct {
+	metrics  *dto.ServerMetricsResponse
+	started  []dto.CreateComposedSessionInput
+	starters []string
+	stopped  []string
+}
+
+func (f *fakeFleetLauncher) StartComposedSession(userID string, input dto.CreateComposedSessionInput, _ any) (*dto.TerminalSessionResponse, error) {
+	f.started = append(f.started, input)
+	f.starters = append(f.starters, userID)
+	return &dto.TerminalSessionResponse{SessionID: "fleet-session-" + userID, Status: "running"}, nil
+}
+
+func (f *fakeFleetLauncher) StopSession(sessionID string) error {
+	f.stopped = append(f.stopped, sessionID)
+	return nil
+}
+
+func (f *fakeFleetLauncher) GetServerMetrics(bool, string) (*dto.ServerMetricsResponse, error) {
+	if f.metrics == nil {
+		return nil, errors.New("metrics unavailable")
+	}
+	return f.metrics, nil
+}
+
+func (f *fakeFleetLauncher) GetUserKey(userID string) (*models.UserTerminalKey, error) {
+	return &models.UserTerminalKey{UserID: userID, IsActive: true}, nil
+}
+
+func (f *fakeFleetLauncher) CreateUserKey(string, string) error { return nil }
+
+// fleetPlan creates a plan and subscribes userID to it.
+func fleetPlan(t *testing.T, db *gorm.DB, userID string, maxCPU, maxMemMB int) *paymentModels.SubscriptionPlan {
+	t.Helper()
+	plan := &paymentModels.SubscriptionPlan{
+		Name:                      "FleetPlan-" + uuid.NewString()[:8],
+		IsActive:                  true,
+		MaxCPU:                    maxCPU,
+		MaxMemoryMB:               maxMemMB,
+		MaxSessionDurationMinutes: 240,
+	}
+	require.NoError(t, db.Create(plan).Error)
+	require.NoError(t, db.Exec(
+		`INSERT INTO user_subscriptions (id, created_at, updated_at, user_id, subscription_plan_id, status) VALUES (?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), time.Now(), time.Now(), userID, plan.ID.String(), "active",
+	).Error)
+	return plan
+}
+
+func fleetGroup(t *testing.T, db *gorm.DB, ownerID string, members int) *groupModels.ClassGroup {
+	t.Helper()
+	group := createTestGroup(t, db, ownerID)
+	for i := 0; i < members; i++ {
+		addActiveGroupMember(t, db, group.ID, "fleet-learner-"+uuid.NewString(), groupModels.GroupMemberRoleMember)
+	}
+	return group
+}
+
+func fleetInput(startAt time.Time, duration time.Duration) dto.ScheduleTerminalFleetInput {
+	noStagger := 0
+	return dto.ScheduleTerminalFleetInput{
+		Distribution:   "debian-12",
+		Size:           "m",
+		Features:       map[string]bool{"network": true},
+		Terms:          "accepted",
+		StartAt:        startAt,
+		StopAt:         startAt.Add(duration),
+		StaggerSeconds: &noStagger,
+	}
+}
+
+func roomyMetrics() *dto.ServerMetricsResponse {
+	return &dto.ServerMetricsResponse{RAMPercent: 10, RAMAvailableGB: 90}
+}
+
+func TestTerminalFleet_Schedule_RejectsBadWindows(t *testing.T) {
+	db := freshTestDB(t)
+	plan := fleetPlan(t, db, "fleet-trainer", 0, 0)
+	group := fleetGroup(t, db, "fleet-trainer", 1)
+	svc := services.NewTerminalFleetService(db, &fakeFleetLauncher{})
+	now := time.Now()
+
+	_, err := svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(-time.Minute), time.Hour), plan, now)
+	assert.ErrorIs(t, err, services.ErrFleetInvalidWindow, "start in the past")
+
+	_, err = svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(time.Hour), -time.Minute), plan, now)
+	assert.ErrorIs(t, err, services.ErrFleetInvalidWindow, "stop before start")
+
+	_, err = svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(time.Hour), 5*time.Hour), plan, now)
+	assert.ErrorIs(t, err, services.ErrFleetInvalidWindow, "window longer than the plan's sessions")
+
+	input := fleetInput(now.Add(time.Hour), time.Hour)
+	input.Size = "xxl"
+	_, err = svc.Schedule(group.ID, "fleet-trainer", input, plan, now)
+	assert.ErrorIs(t, err, services.ErrFleetInvalidSize)
+}
+
+func TestTerminalFleet_Schedule_ReservesOrganizationBudget(t *testing.T) {
+	db := freshTestDB(t)
+	// Room for four M terminals (2000 mCPU / 1024 MiB each).
+	plan := &paymentModels.SubscriptionPlan{
+		Name: "FleetOrgPlan", IsActive: true, MaxCPU: 8000, MaxMemoryMB: 4096, MaxSessionDurationMinutes: 240,
+	}
+	require.NoError(t, db.Create(plan).Error)
+	org := createTestOrgForHistory(t, db, "fleet-trainer")
+	createTestOrgMember(t, db, org.ID, "fleet-trainer", orgModels.OrgRoleOwner)
+	require.NoError(t, db.Create(&paymentModels.OrganizationSubscription{
+		OrganizationID:     org.ID,
+		SubscriptionPlanID: plan.ID,
+		StripeCustomerID:   "cus_test_" + uuid.NewString()[:8],
+		Status:             "active",
+		CurrentPeriodStart: time.Now(),
+		CurrentPeriodEnd:   time.Now().AddDate(1, 0, 0),
+	}).Error)
+	group := fleetGroup(t, db, "fleet-trainer", 3)
+
+	svc := services.NewTerminalFleetService(db, &fakeFleetLauncher{})
+	now := time.Now()
+	start := now.Add(24 * time.Hour)
+	input := fleetInput(start, 3*time.Hour)
+	input.OrganizationID = org.ID.String()
+
+	first, err := svc.Schedule(group.ID, "fleet-trainer", input, plan, now)
+	require.NoError(t, err)
+	require.NotNil(t, first.BudgetOrganizationID)
+	assert.Equal(t, org.ID, *first.BudgetOrganizationID)
+	assert.Equal(t, 6000, first.ReservedCPU)
+	assert.Equal(t, 3072, first.ReservedMemoryMB)
+
+	// Overlapping the first fleet, three more M terminals do not fit.
+	overlapping := input
+	overlapping.StartAt = start.Add(time.Hour)
+	overlapping.StopAt = start.Add(4 * time.Hour)
+	_, err = svc.Schedule(group.ID, "fleet-trainer", overlapping, plan, now)
+	assert.ErrorIs(t, err, services.ErrFleetBudgetExceeded)
+
+	// After the first fleet's window the budget is free again.
+	later := input
+	later.StartAt = start.Add(3 * time.Hour)
+	later.StopAt = start.Add(5 * time.Hour)
+	_, err = svc.Schedule(group.ID, "fleet-trainer", later, plan, now)
+	assert.NoError(t, err)
+
+	// A cancelled fleet gives its reservation back.
+	_, err = svc.Cancel(first.ID)
+	require.NoError(t, err)
+	_, err = svc.Schedule(group.ID, "fleet-trainer", overlapping, plan, now)
+	assert.ErrorIs(t, err, services.ErrFleetBudgetExceeded, "still overlaps the later fleet")
+	overlapping.StopAt = start.Add(2 * time.Hour)
+	_, err = svc.Schedule(group.ID, "fleet-trainer", overlapping, plan, now)
+	assert.NoError(t, err)
+}
+
+func TestTerminalFleet_RunDue_LaunchesAndStops(t *testing.T) {
+	db := freshTestDB(t)
+	plan := fleetPlan(t, db, "fleet-trainer", 0, 0)
+	group := fleetGroup(t, db, "fleet-trainer", 2)
+	launcher := &fakeFleetLauncher{metrics: roomyMetrics()}
+	svc := services.NewTerminalFleetService(db, launcher)
+
+	now := time.Now()
+	fleet, err := svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(time.Hour), 3*time.Hour+30*time.Minute), plan, now)
+	require.NoError(t, err)
+
+	// Before start_at nothing happens.
+	launched, stopped, err := svc.RunDue(now.Add(30 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	assert.Equal(t, 0, stopped)
+	assert.Empty(t, launcher.started)
+
+	launched, _, err = svc.RunDue(now.Add(time.Hour + time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, launched)
+	require.Len(t, launcher.started, 2)
+	for _, input := range launcher.started {
+		assert.Equal(t, "debian-12", input.Distribution)
+		assert.Equal(t, "M", input.Size)
+		assert.True(t, input.Features["network"])
+		assert.Equal(t, "fleet:"+fleet.ID.String(), input.ExternalRef)
+		// The sessions are meant to last until the fleet stops them.
+		assert.Greater(t, input.Expiry, 0)
+		assert.LessOrEqual(t, input.Expiry, int((4*time.Hour + 30*time.Minute).Seconds()))
+	}
+
+	got, err := svc.Get(fleet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusRunning, got.Status)
+	require.Len(t, got.Members, 2)
+	for _, m := range got.Members {
+		assert.Equal(t, models.FleetMemberStatusRunning, m.Status)
+		assert.Equal(t, "fleet-session-"+m.UserID, m.SessionID)
+	}
+
+	// A second run does not launch the fleet again.
+	launched, _, err = svc.RunDue(now.Add(time.Hour + time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	assert.Len(t, launcher.started, 2)
+
+	_, stopped, err = svc.RunDue(now.Add(4*time.Hour + 30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stopped)
+	assert.ElementsMatch(t, []string{"fleet-session-" + got.Members[0].UserID, "fleet-session-" + got.Members[1].UserID}, launcher.stopped)
+
+	got, err = svc.Get(fleet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusCompleted, got.Status)
+	for _, m := range got.Members {
+		assert.Equal(t, models.FleetMemberStatusStopped, m.Status)
+	}
+}
+
+func TestTerminalFleet_RunDue_InsufficientCapacityFailsFleet(t *testing.T) {
+	db := freshTestDB(t)
+	plan := fleetPlan(t, db, "fleet-trainer", 0, 0)
+	group := fleetGroup(t, db, "fleet-trainer", 4)
+	// 3 GB free: one M terminal fits, four do not.
+	launcher := &fakeFleetLauncher{metrics: &dto.ServerMetricsResponse{RAMPercent: 70, RAMAvailableGB: 3}}
+	svc := services.NewTerminalFleetService(db, launcher)
+
+	now := time.Now()
+	fleet, err := svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(time.Hour), time.Hour), plan, now)
+	require.NoError(t, err)
+
+	launched, _, err := svc.RunDue(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	assert.Empty(t, launcher.started, "no terminal is launched for a fleet the server cannot hold")
+
+	got, err := svc.Get(fleet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusFailed, got.Status)
+	assert.Contains(t, got.Error, "insufficient RAM")
+}
+
+func TestTerminalFleet_RunDue_ResumesInterruptedLaunch(t *testing.T) {
+	db := freshTestDB(t)
+	fleetPlan(t, db, "fleet-trainer", 0, 0)
+	group := fleetGroup(t, db, "fleet-trainer", 0)
+	launcher := &fakeFleetLauncher{metrics: roomyMetrics()}
+	svc := services.NewTerminalFleetService(db, launcher)
+
+	// A fleet a restart caught halfway: one member launched, one waiting.
+	now := time.Now()
+	launchedAt := now.Add(-time.Minute)
+	fleet := &models.TerminalFleet{
+		GroupID: group.ID, CreatedByID: "fleet-trainer", Distribution: "debian-12", Size: "M", Terms: "accepted",
+		StartAt: launchedAt, StopAt: now.Add(time.Hour), Status: models.FleetStatusLaunching, LaunchedAt: &launchedAt,
+	}
+	require.NoError(t, db.Create(fleet).Error)
+	require.NoError(t, db.Create(&[]models.TerminalFleetMember{
+		{FleetID: fleet.ID, UserID: "learner-done", Status: models.FleetMemberStatusRunning, SessionID: "fleet-session-learner-done"},
+		{FleetID: fleet.ID, UserID: "learner-waiting", Status: models.FleetMemberStatusPending},
+	}).Error)
+
+	_, _, err := svc.RunDue(now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"learner-waiting"}, launcher.starters)
+
+	got, err := svc.Get(fleet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusRunning, got.Status)
+}
+
+func TestTerminalFleet_RunDue_MissedWindowIsNotLaunched(t *testing.T) {
+	db := freshTestDB(t)
+	plan := fleetPlan(t, db, "fleet-trainer", 0, 0)
+	group := fleetGroup(t, db, "fleet-trainer", 1)
+	launcher := &fakeFleetLauncher{metrics: roomyMetrics()}
+	svc := services.NewTerminalFleetService(db, launcher)
+
+	now := time.Now()
+	fleet, err := svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(time.Hour), time.Hour), plan, now)
+	require.NoError(t, err)
+
+	_, _, err = svc.RunDue(now.Add(3 * time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, launcher.started)
+
+	got, err := svc.Get(fleet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusFailed, got.Status)
+}
+
+func TestTerminalFleet_Cancel(t *testing.T) {
+	db := freshTestDB(t)
+	plan := fleetPlan(t, db, "fleet-trainer", 0, 0)
+	group := fleetGroup(t, db, "fleet-trainer", 2)
+	launcher := &fakeFleetLauncher{metrics: roomyMetrics()}
+	svc := services.NewTerminalFleetService(db, launcher)
+	now := time.Now()
+
+	scheduled, err := svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(time.Hour), time.Hour), plan, now)
+	require.NoError(t, err)
+	cancelled, err := svc.Cancel(scheduled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusCancelled, cancelled.Status)
+	_, err = svc.Cancel(scheduled.ID)
+	assert.ErrorIs(t, err, services.ErrFleetFinished)
+
+	_, _, err = svc.RunDue(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, launcher.started, "a cancelled fleet never launches")
+
+	// Cancelling a running fleet stops its terminals.
+	running, err := svc.Schedule(group.ID, "fleet-trainer", fleetInput(now.Add(2*time.Hour), time.Hour), plan, now)
+	require.NoError(t, err)
+	_, _, err = svc.RunDue(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, launcher.started, 2)
+
+	cancelled, err = svc.Cancel(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FleetStatusCancelled, cancelled.Status)
+	assert.Len(t, launcher.stopped, 2)
+}
+
+func TestEvaluateFleetCapacity(t *testing.T) {
+	// 16 GB total, 8 GB free. An M terminal needs 1 GB; the reserve is 0.8 GB.
+	metrics := &dto.ServerMetricsResponse{RAMPercent: 50, RAMAvailableGB: 8}
+
+	assert.Equal(t, services.CapacityStatusOK, services.EvaluateFleetCapacity(nil, "m", 1, metrics).Status)
+	assert.Equal(t, services.CapacityStatusOK, services.EvaluateFleetCapacity(nil, "m", 5, metrics).Status)
+	assert.Equal(t, services.CapacityStatusCritical, services.EvaluateFleetCapacity(nil, "m", 8, metrics).Status)
+	assert.Equal(t, services.CapacityStatusCritical, services.EvaluateFleetCapacity(nil, "m", 20, metrics).Status)
+	assert.Equal(t, services.CapacityStatusUnknown, services.EvaluateFleetCapacity(nil, "m", 5, nil).Status)
+}
@@ -0,0 +1,209 @@
+package terminalTrainer_tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/services"
+)
+
+// testCast builds an asciicast v2 recording starting at start. Each event is
+// [seconds, code, data].
+func testCast(start time.Time, events ...[]any) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"version":2,"width":80,"height":24,"timestamp":%d,"duration":999}`+"\n", start.Unix())
+	for _, event := range events {
+		line, _ := json.Marshal(event)
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parsedCast is a replay read back: its header and events.
+type parsedCast struct {
+	header map[string]any
+	events [][]any
+}
+
+func parseTestCast(t *testing.T, body []byte) parsedCast {
+	t.Helper()
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	require.True(t, scanner.Scan())
+	var cast parsedCast
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &cast.header))
+	for scanner.Scan() {
+		var event []any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		cast.events = append(cast.events, event)
+	}
+	return cast
+}
+
+// setupRecordingTest points the service at a fake tt-backend serving cast as
+// the recording of a terminal launched under a plan retaining history for
+// retentionDays.
+func setupRecordingTest(t *testing.T, cast string, retentionDays int) (services.TerminalTrainerService, *models.Terminal, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	ttServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r.Method, r.URL.Path)
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/recording") {
+			w.Header().Set("Content-Type", "application/x-asciicast")
+			_, _ = w.Write([]byte(cast))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(ttServer.Close)
+
+	t.Setenv("TERMINAL_TRAINER_URL", ttServer.URL)
+	t.Setenv("TERMINAL_TRAINER_ADMIN_KEY", "test-admin-key")
+	t.Setenv("TERMINAL_TRAINER_API_VERSION", "1.0")
+
+	db := freshTestDB(t)
+	plan := &paymentModels.SubscriptionPlan{
+		Name:                        "recording-plan",
+		Currency:                    "EUR",
+		BillingInterval:             "monthly",
+		IsActive:                    true,
+		CommandHistoryRetentionDays: retentionDays,
+	}
+	require.NoError(t, db.Create(plan).Error)
+
+	terminal := createPlanTerminal(t, db, plan)
+	return services.NewTerminalTrainerService(db), terminal, rec
+}
+
+func createPlanTerminal(t *testing.T, db *gorm.DB, plan *paymentModels.SubscriptionPlan) *models.Terminal {
+	t.Helper()
+	terminal, err := createTestTerminal(db, "recording-owner", "running", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	terminal.SubscriptionPlanID = &plan.ID
+	require.NoError(t, db.Save(terminal).Error)
+	return terminal
+}
+
+func TestSessionRecording_FullReplay(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cast := testCast(start, []any{0.5, "o", "$ "}, []any{1.0, "i", "ls\r"}, []any{1.2, "o", "file.txt\r\n"})
+	svc, terminal, rec := setupRecordingTest(t, cast, 30)
+
+	body, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{})
+	require.NoError(t, err)
+	assert.True(t, rec.sawCall(http.MethodGet, "/1.0/sessions/"+terminal.SessionID+"/recording"))
+
+	replay := parseTestCast(t, body)
+	assert.Equal(t, float64(2), replay.header["version"])
+	assert.Equal(t, float64(80), replay.header["width"], "header fields pass through")
+	assert.NotContains(t, replay.header, "duration")
+	require.Len(t, replay.events, 3)
+	assert.Equal(t, []any{0.5, "o", "$ "}, replay.events[0])
+}
+
+// TestSessionRecording_SeekFoldsEarlierOutput: seeking must show the screen as
+// it was at that moment, so the output before it is folded into one event at
+// the start, after the last resize.
+func TestSessionRecording_SeekFoldsEarlierOutput(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cast := testCast(start,
+		[]any{0.5, "o", "$ "},
+		[]any{1.0, "r", "120x40"},
+		[]any{2.0, "o", "hello\r\n"},
+		[]any{10.0, "o", "world\r\n"},
+		[]any{20.0, "o", "late\r\n"},
+	)
+	svc, terminal, _ := setupRecordingTest(t, cast, 30)
+
+	from, to := 5.0, 15.0
+	body, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{From: &from, To: &to})
+	require.NoError(t, err)
+
+	replay := parseTestCast(t, body)
+	assert.Equal(t, float64(start.Unix()+5), replay.header["timestamp"])
+	require.Len(t, replay.events, 3)
+	assert.Equal(t, []any{0.0, "r", "120x40"}, replay.events[0])
+	assert.Equal(t, []any{0.0, "o", "$ hello\r\n"}, replay.events[1])
+	assert.Equal(t, []any{5.0, "o", "world\r\n"}, replay.events[2])
+}
+
+// TestSessionRecording_StepWindowAndMarkers: absolute bounds win over offsets
+// and markers inside the window are inserted in order.
+func TestSessionRecording_StepWindowAndMarkers(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cast := testCast(start, []any{1.0, "o", "a"}, []any{12.0, "o", "b"}, []any{30.0, "o", "c"})
+	svc, terminal, _ := setupRecordingTest(t, cast, 30)
+
+	since, until := start.Add(10*time.Second), start.Add(25*time.Second)
+	from := 0.0
+	body, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{
+		From:  &from,
+		Since: &since,
+		Until: &until,
+		Markers: []dto.RecordingMarker{
+			{At: start, Label: "Step 1: Setup"},
+			{At: since, Label: "Step 2: Files"},
+			{At: until, Label: "Step 3: Cleanup"},
+		},
+	})
+	require.NoError(t, err)
+
+	replay := parseTestCast(t, body)
+	require.Len(t, replay.events, 4)
+	assert.Equal(t, []any{0.0, "o", "a"}, replay.events[0])
+	assert.Equal(t, []any{0.0, "m", "Step 2: Files"}, replay.events[1])
+	assert.Equal(t, []any{2.0, "o", "b"}, replay.events[2])
+	assert.Equal(t, []any{15.0, "m", "Step 3: Cleanup"}, replay.events[3])
+}
+
+// TestSessionRecording_RetentionDropsOldEvents: what is past the plan's
+// retention is not shown at all — not even folded into the seek prefix.
+func TestSessionRecording_RetentionDropsOldEvents(t *testing.T) {
+	start := time.Now().Add(-50 * time.Hour).Truncate(time.Second)
+	recent := (49 * time.Hour).Seconds()
+	cast := testCast(start, []any{1.0, "o", "secret"}, []any{recent, "o", "kept"})
+	svc, terminal, _ := setupRecordingTest(t, cast, 2)
+
+	body, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "secret")
+	assert.Contains(t, string(body), "kept")
+}
+
+func TestSessionRecording_Expired(t *testing.T) {
+	start := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	cast := testCast(start, []any{1.0, "o", "old"})
+
+	t.Run("past retention", func(t *testing.T) {
+		svc, terminal, _ := setupRecordingTest(t, cast, 1)
+		_, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{})
+		assert.ErrorIs(t, err, services.ErrRecordingExpired)
+	})
+
+	t.Run("plan without retention", func(t *testing.T) {
+		svc, terminal, rec := setupRecordingTest(t, cast, 0)
+		_, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{})
+		assert.ErrorIs(t, err, services.ErrRecordingExpired)
+		assert.False(t, rec.sawCall(http.MethodGet, "/recording"), "nothing is fetched for a plan keeping no history")
+	})
+}
+
+func TestSessionRecording_RejectsInvalidCast(t *testing.T) {
+	svc, terminal, _ := setupRecordingTest(t, `{"version":1}`+"\n", 30)
+	_, err := svc.GetSessionRecording(terminal.SessionID, dto.RecordingReplayOptions{})
+	assert.ErrorIs(t, err, services.ErrRecordingInvalid)
+}
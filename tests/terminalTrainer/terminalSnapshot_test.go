@@ -0,0 +1,205 @@
+package terminalTrainer_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	paymentModels "soli/formations/src/payment/models"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/services"
+)
+
+const oneGB = int64(1024 * 1024 * 1024)
+
+// startSnapshotTTServer spins a fake tt-backend answering the snapshot
+// endpoints. Every snapshot it takes reports *sizeBytes.
+func startSnapshotTTServer(t *testing.T, sizeBytes *atomic.Int64) (*httptest.Server, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r.Method, r.URL.Path)
+
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/snapshots"):
+			var body struct {
+				Name string `json:"name"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name":       body.Name,
+				"size_bytes": sizeBytes.Load(),
+				"created_at": time.Now().UTC().Format(time.RFC3339),
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/restore"):
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "restored"})
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/snapshots/"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return srv, rec
+}
+
+// setupSnapshotTest points the service at a fake tt-backend and seeds a
+// persistent running terminal launched under a plan with quotaGB of storage.
+func setupSnapshotTest(t *testing.T, quotaGB int) (*gorm.DB, services.TerminalTrainerService, *models.Terminal, *recorder, *atomic.Int64) {
+	t.Helper()
+	sizeBytes := &atomic.Int64{}
+	sizeBytes.Store(100 * 1024 * 1024)
+	ttServer, rec := startSnapshotTTServer(t, sizeBytes)
+	t.Cleanup(ttServer.Close)
+
+	t.Setenv("TERMINAL_TRAINER_URL", ttServer.URL)
+	t.Setenv("TERMINAL_TRAINER_ADMIN_KEY", "test-admin-key")
+	t.Setenv("TERMINAL_TRAINER_API_VERSION", "1.0")
+
+	db := freshTestDB(t)
+	plan := &paymentModels.SubscriptionPlan{
+		Name:                   "snapshot-plan",
+		Currency:               "EUR",
+		BillingInterval:        "monthly",
+		IsActive:               true,
+		DataPersistenceEnabled: quotaGB > 0,
+		DataPersistenceGB:      quotaGB,
+	}
+	require.NoError(t, db.Create(plan).Error)
+
+	terminal := createPersistentTerminal(t, db, "snapshot-owner-"+uuid.New().String(), plan)
+	return db, services.NewTerminalTrainerService(db), terminal, rec, sizeBytes
+}
+
+func createPersistentTerminal(t *testing.T, db *gorm.DB, userID string, plan *paymentModels.SubscriptionPlan) *models.Terminal {
+	t.Helper()
+	terminal, err := createTestTerminal(db, userID, "running", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	terminal.PersistenceMode = services.PersistenceModePersistent
+	terminal.SubscriptionPlanID = &plan.ID
+	require.NoError(t, db.Save(terminal).Error)
+	return terminal
+}
+
+func TestTerminalSnapshot_CreateListRestoreDelete(t *testing.T) {
+	_, svc, terminal, rec, _ := setupSnapshotTest(t, 1)
+
+	snapshot, err := svc.CreateSnapshot(terminal.ID.String(), "before-lab", "trainer-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, terminal.UserID, snapshot.UserID, "a snapshot counts against the terminal owner, whoever took it")
+	assert.Equal(t, "trainer-1", snapshot.CreatedByID)
+	assert.True(t, rec.sawCall(http.MethodPost, "/1.0/sessions/"+terminal.SessionID+"/snapshots"))
+
+	list, err := svc.ListSnapshots(terminal.SessionID)
+	require.NoError(t, err)
+	require.Len(t, list.Snapshots, 1)
+	assert.Equal(t, int64(100*1024*1024), list.UsedBytes)
+	assert.Equal(t, oneGB, list.QuotaBytes)
+
+	require.NoError(t, svc.RestoreSnapshot(terminal.ID.String(), "before-lab"))
+	assert.True(t, rec.sawCall(http.MethodPost, "/sessions/"+terminal.SessionID+"/snapshots/before-lab/restore"))
+
+	require.NoError(t, svc.DeleteSnapshot(terminal.ID.String(), "before-lab"))
+	assert.True(t, rec.sawCall(http.MethodDelete, "/sessions/"+terminal.SessionID+"/snapshots/before-lab"))
+	list, err = svc.ListSnapshots(terminal.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, list.Snapshots)
+	assert.Zero(t, list.UsedBytes)
+}
+
+func TestTerminalSnapshot_RequiresPersistentTerminal(t *testing.T) {
+	db, svc, terminal, rec, _ := setupSnapshotTest(t, 1)
+	require.NoError(t, db.Model(terminal).Update("persistence_mode", services.PersistenceModeEphemeral).Error)
+
+	_, err := svc.CreateSnapshot(terminal.ID.String(), "nope", "", nil)
+	assert.ErrorIs(t, err, services.ErrSnapshotNotPersistent)
+	assert.False(t, rec.sawCall(http.MethodPost, "/snapshots"))
+}
+
+func TestTerminalSnapshot_RequiresPlanStorage(t *testing.T) {
+	_, svc, terminal, rec, _ := setupSnapshotTest(t, 0)
+
+	_, err := svc.CreateSnapshot(terminal.ID.String(), "nope", "", nil)
+	assert.ErrorIs(t, err, services.ErrSnapshotsNotInPlan)
+	assert.False(t, rec.sawCall(http.MethodPost, "/snapshots"))
+}
+
+func TestTerminalSnapshot_RejectsInvalidAndDuplicateNames(t *testing.T) {
+	_, svc, terminal, _, _ := setupSnapshotTest(t, 1)
+
+	_, err := svc.CreateSnapshot(terminal.ID.String(), "../escape", "", nil)
+	assert.ErrorIs(t, err, services.ErrSnapshotInvalidName)
+
+	_, err = svc.CreateSnapshot(terminal.ID.String(), "step-1", "", nil)
+	require.NoError(t, err)
+	_, err = svc.CreateSnapshot(terminal.ID.String(), "step-1", "", nil)
+	assert.ErrorIs(t, err, services.ErrSnapshotExists)
+}
+
+// TestTerminalSnapshot_ReplaceFreesTheOldSnapshot: replacing a snapshot must
+// not count the one it replaces, or a learner near their quota could never
+// refresh a checkpoint.
+func TestTerminalSnapshot_ReplaceFreesTheOldSnapshot(t *testing.T) {
+	_, svc, terminal, rec, sizeBytes := setupSnapshotTest(t, 1)
+	sizeBytes.Store(700 * 1024 * 1024)
+
+	order := 2
+	_, err := svc.ReplaceSnapshot(terminal.SessionID, "scenario-step-2", "", &order)
+	require.NoError(t, err)
+	replaced, err := svc.ReplaceSnapshot(terminal.SessionID, "scenario-step-2", "", &order)
+	require.NoError(t, err, "700MB replacing 700MB fits a 1GB quota")
+	require.NotNil(t, replaced.StepOrder)
+	assert.Equal(t, 2, *replaced.StepOrder)
+	assert.True(t, rec.sawCall(http.MethodDelete, "/snapshots/scenario-step-2"))
+
+	list, err := svc.ListSnapshots(terminal.SessionID)
+	require.NoError(t, err)
+	assert.Len(t, list.Snapshots, 1)
+	assert.Equal(t, int64(700*1024*1024), list.UsedBytes)
+}
+
+// TestTerminalSnapshot_QuotaCountsAllTheOwnersTerminals: the quota is the
+// owner's storage, so a snapshot on one terminal limits the next on another —
+// and one that turns out too big once taken is deleted again.
+func TestTerminalSnapshot_QuotaCountsAllTheOwnersTerminals(t *testing.T) {
+	db, svc, terminal, rec, sizeBytes := setupSnapshotTest(t, 1)
+	var plan paymentModels.SubscriptionPlan
+	require.NoError(t, db.First(&plan, "id = ?", *terminal.SubscriptionPlanID).Error)
+	other := createPersistentTerminal(t, db, terminal.UserID, &plan)
+
+	sizeBytes.Store(600 * 1024 * 1024)
+	_, err := svc.CreateSnapshot(terminal.ID.String(), "first", "", nil)
+	require.NoError(t, err)
+
+	_, err = svc.CreateSnapshot(other.ID.String(), "second", "", nil)
+	assert.ErrorIs(t, err, services.ErrSnapshotQuotaExceeded)
+	assert.True(t, rec.sawCall(http.MethodDelete, "/sessions/"+other.SessionID+"/snapshots/second"),
+		"a snapshot over quota must not be left on tt-backend")
+	var count int64
+	db.Model(&models.TerminalSnapshot{}).Where("terminal_id = ?", other.ID).Count(&count)
+	assert.Zero(t, count)
+
+	// Once the first terminal is gone its snapshots go with it.
+	require.NoError(t, db.Model(terminal).Update("state", models.StateDeleted).Error)
+	_, err = svc.CreateSnapshot(other.ID.String(), "second", "", nil)
+	assert.NoError(t, err)
+}
+
+func TestTerminalSnapshot_RestoreUnknownName(t *testing.T) {
+	_, svc, terminal, rec, _ := setupSnapshotTest(t, 1)
+
+	err := svc.RestoreSnapshot(terminal.ID.String(), "never-taken")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.False(t, rec.sawCall(http.MethodPost, "/restore"))
+}
@@ -0,0 +1,165 @@
+package terminalTrainer_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	groupModels "soli/formations/src/groups/models"
+	"soli/formations/src/terminalTrainer/dto"
+	"soli/formations/src/terminalTrainer/models"
+	"soli/formations/src/terminalTrainer/services"
+)
+
+// setupTeamTest seeds a terminal owned by "owner" and a class group with the
+// owner and two classmates. "outsider" has a terminal key but no group.
+func setupTeamTest(t *testing.T) (*gorm.DB, services.TerminalTrainerService, *models.Terminal, *groupModels.ClassGroup) {
+	t.Helper()
+	db := freshTestDB(t)
+
+	userKey, err := createTestUserKey(db, "owner")
+	require.NoError(t, err)
+	terminal, err := createTestTerminal(db, "owner", "active", userKey.ID)
+	require.NoError(t, err)
+
+	group := &groupModels.ClassGroup{
+		Name:        "pair-class",
+		DisplayName: "Pair Class",
+		OwnerUserID: "trainer",
+		IsActive:    true,
+		MaxMembers:  50,
+	}
+	require.NoError(t, db.Omit("Metadata").Create(group).Error)
+	for _, userID := range []string{"owner", "alice", "bob"} {
+		createTestGroupMember(t, db, group.ID, userID, groupModels.GroupMemberRoleMember)
+	}
+
+	return db, services.NewTerminalTrainerService(db), terminal, group
+}
+
+func roles(team *dto.TerminalTeamResponse) map[string]string {
+	byUser := map[string]string{}
+	for _, member := range team.Members {
+		byUser[member.UserID] = member.Role
+	}
+	return byUser
+}
+
+func TestShareTerminal_OwnerDrivesClassmatesNavigate(t *testing.T) {
+	_, svc, terminal, _ := setupTeamTest(t)
+
+	team, err := svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"alice"}}, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, "owner", team.OwnerID)
+	assert.Equal(t, map[string]string{"owner": models.TeamRoleDriver, "alice": models.TeamRoleNavigator}, roles(team))
+
+	hasAccess, err := svc.HasTerminalAccess(terminal.SessionID, "alice")
+	require.NoError(t, err)
+	assert.True(t, hasAccess, "a team member reaches the shared terminal")
+
+	hasAccess, err = svc.HasTerminalAccess(terminal.SessionID, "bob")
+	require.NoError(t, err)
+	assert.False(t, hasAccess, "a classmate outside the team does not")
+}
+
+func TestShareTerminal_SubGroup(t *testing.T) {
+	_, svc, terminal, group := setupTeamTest(t)
+
+	team, err := svc.ShareTerminal(terminal.ID.String(), dto.ShareTerminalInput{GroupID: &group.ID}, "owner")
+	require.NoError(t, err)
+	assert.Len(t, team.Members, 3)
+	for _, member := range team.Members {
+		if member.UserID != "owner" {
+			require.NotNil(t, member.GroupID)
+			assert.Equal(t, group.ID, *member.GroupID)
+		}
+	}
+}
+
+func TestShareTerminal_RejectsNonClassmates(t *testing.T) {
+	db, svc, terminal, _ := setupTeamTest(t)
+
+	_, err := svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"outsider"}}, "owner")
+	assert.ErrorIs(t, err, services.ErrTeamNotClassmate)
+
+	_, err = svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"owner"}}, "owner")
+	assert.ErrorIs(t, err, services.ErrTeamNoMembers)
+
+	other := &groupModels.ClassGroup{Name: "other", DisplayName: "Other", OwnerUserID: "trainer", IsActive: true, MaxMembers: 50}
+	require.NoError(t, db.Omit("Metadata").Create(other).Error)
+	createTestGroupMember(t, db, other.ID, "outsider", groupModels.GroupMemberRoleMember)
+	_, err = svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{GroupID: &other.ID}, "owner")
+	assert.ErrorIs(t, err, services.ErrTeamGroupNotOwners)
+
+	var count int64
+	require.NoError(t, db.Model(&models.TerminalTeamMember{}).Count(&count).Error)
+	assert.Zero(t, count, "a rejected share leaves no team behind")
+}
+
+func TestSetTerminalTeamRole_HandsOverTheDriversSeat(t *testing.T) {
+	_, svc, terminal, _ := setupTeamTest(t)
+	_, err := svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"alice", "bob"}}, "owner")
+	require.NoError(t, err)
+
+	team, err := svc.SetTerminalTeamRole(terminal.SessionID, "alice", models.TeamRoleDriver)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"owner": models.TeamRoleNavigator,
+		"alice": models.TeamRoleDriver,
+		"bob":   models.TeamRoleNavigator,
+	}, roles(team), "one driver at a time")
+
+	role, err := svc.GetTerminalTeamRole(terminal.SessionID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.TeamRoleDriver, role)
+
+	_, err = svc.SetTerminalTeamRole(terminal.SessionID, "alice", "pilot")
+	assert.ErrorIs(t, err, services.ErrTeamInvalidRole)
+	_, err = svc.SetTerminalTeamRole(terminal.SessionID, "outsider", models.TeamRoleDriver)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestRemoveTerminalTeamMember(t *testing.T) {
+	_, svc, terminal, _ := setupTeamTest(t)
+	_, err := svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"alice", "bob"}}, "owner")
+	require.NoError(t, err)
+	_, err = svc.SetTerminalTeamRole(terminal.SessionID, "alice", models.TeamRoleDriver)
+	require.NoError(t, err)
+
+	_, err = svc.RemoveTerminalTeamMember(terminal.SessionID, "owner")
+	assert.ErrorIs(t, err, services.ErrTeamOwnerRemoval)
+
+	team, err := svc.RemoveTerminalTeamMember(terminal.SessionID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": models.TeamRoleDriver, "bob": models.TeamRoleNavigator}, roles(team),
+		"the owner drives again when the driver leaves")
+	hasAccess, err := svc.HasTerminalAccess(terminal.SessionID, "alice")
+	require.NoError(t, err)
+	assert.False(t, hasAccess)
+
+	team, err = svc.RemoveTerminalTeamMember(terminal.SessionID, "bob")
+	require.NoError(t, err)
+	assert.Empty(t, team.Members, "the owner alone is no team")
+	role, err := svc.GetTerminalTeamRole(terminal.SessionID, "owner")
+	require.NoError(t, err)
+	assert.Empty(t, role)
+
+	// A learner who left can be added back.
+	team, err = svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"alice"}}, "owner")
+	require.NoError(t, err)
+	assert.Len(t, team.Members, 2)
+}
+
+func TestCanManageTerminalTeam(t *testing.T) {
+	_, svc, terminal, _ := setupTeamTest(t)
+	_, err := svc.ShareTerminal(terminal.SessionID, dto.ShareTerminalInput{UserIDs: []string{"alice"}}, "owner")
+	require.NoError(t, err)
+
+	for userID, want := range map[string]bool{"owner": true, "trainer": true, "alice": false} {
+		allowed, err := svc.CanManageTerminalTeam(terminal.SessionID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, want, allowed, userID)
+	}
+}
@@ -65,3 +65,25 @@ func TestFieldEncryption_Encrypt_MissingSecret_Fails(t *testing.T) {
 	_, err := crypto.Encrypt("x")
 	assert.Error(t, err, "Encrypt must fail when FIELD_ENCRYPTION_SECRET is empty")
 }
+
+// TestFieldEncryption_EncryptField_Idempotent verifies the helper behind the
+// models' BeforeSave hooks: a second save must not encrypt the ciphertext,
+// and DecryptField restores the plaintext.
+func TestFieldEncryption_EncryptField_Idempotent(t *testing.T) {
+	t.Setenv("FIELD_ENCRYPTION_SECRET", "a-very-secret-key-for-testing-ok")
+
+	field := testPrivateKey
+	require.NoError(t, crypto.EncryptField(&field))
+	assert.True(t, strings.HasPrefix(field, "enc::v1:"), "EncryptField must encrypt plaintext")
+
+	encrypted := field
+	require.NoError(t, crypto.EncryptField(&field))
+	assert.Equal(t, encrypted, field, "EncryptField must leave an encrypted value unchanged")
+
+	require.NoError(t, crypto.DecryptField(&field))
+	assert.Equal(t, testPrivateKey, field)
+
+	empty := ""
+	require.NoError(t, crypto.EncryptField(&empty))
+	assert.Empty(t, empty)
+}
@@ -0,0 +1,48 @@
+package webhooks_tests
+
+import (
+	"os"
+	"testing"
+
+	"soli/formations/src/webhooks/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var sharedTestDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to open shared test DB: " + err.Error())
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookDeliveryAttempt{},
+	)
+	if err != nil {
+		panic("failed to migrate shared test DB: " + err.Error())
+	}
+
+	sharedTestDB = db
+	// Subscription secrets are stored encrypted.
+	os.Setenv("FIELD_ENCRYPTION_SECRET", "webhooks-test-secret")
+	os.Exit(m.Run())
+}
+
+func freshTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	for _, table := range []string{"webhook_delivery_attempts", "webhook_deliveries", "webhook_subscriptions"} {
+		sharedTestDB.Exec("DELETE FROM " + table)
+	}
+	return sharedTestDB
+}